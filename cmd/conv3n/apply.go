@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/conv3n/conv3n/internal/api"
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// cmdApply implements `conv3n apply <bundle>`: it diffs the workflows and
+// triggers of a bundle directory (see engine.Bundle) against the database or
+// --server, prints the plan and, unless --dry-run is set, applies it. CI
+// pipelines run it with --dry-run on review and without it to promote.
+func cmdApply(args []string) error {
+	fs, opts := newFlagSet("apply")
+	dryRun := fs.Bool("dry-run", false, "print the plan without applying it")
+	prune := fs.Bool("prune", false, "delete the workflows and triggers missing from the bundle")
+	positional, err := parseFlags(fs, opts, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: conv3n apply <bundle-dir> [--dry-run] [--prune]")
+	}
+
+	bundle, err := engine.LoadBundle(positional[0], engine.NewBunRunner(opts.BlocksDir).ConfigSchema)
+	if err != nil {
+		return fmt.Errorf("invalid bundle %s:\n%w", positional[0], err)
+	}
+
+	b, err := opts.openBackend()
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	ctx := context.Background()
+
+	workflows, triggers, err := deployedState(ctx, b)
+	if err != nil {
+		return err
+	}
+	plan := bundle.Plan(workflows, triggers, *prune)
+
+	if opts.Format == "json" {
+		if err := opts.render(os.Stdout, plan, nil, nil); err != nil {
+			return err
+		}
+	} else if len(plan) == 0 {
+		fmt.Println("No changes.")
+	} else {
+		rows := make([][]string, len(plan))
+		counts := make(map[string]int)
+		for i, c := range plan {
+			rows[i] = []string{c.Action, c.Kind, c.ID, orDash(strings.Join(c.Fields, ", "))}
+			counts[c.Action]++
+		}
+		if err := opts.render(os.Stdout, plan, []string{"ACTION", "KIND", "ID", "CHANGES"}, rows); err != nil {
+			return err
+		}
+		fmt.Printf("\nPlan: %d to create, %d to update, %d to delete.\n",
+			counts[engine.ChangeCreate], counts[engine.ChangeUpdate], counts[engine.ChangeDelete])
+	}
+	if *dryRun || len(plan) == 0 {
+		return nil
+	}
+
+	for i, c := range plan {
+		if err := applyChange(ctx, b, bundle, c); err != nil {
+			return fmt.Errorf("failed to %s %s %s after applying %d of %d changes: %w", c.Action, c.Kind, c.ID, i, len(plan), err)
+		}
+	}
+	if opts.Format != "json" {
+		fmt.Printf("Applied %d change(s).\n", len(plan))
+	}
+	return nil
+}
+
+// deployedState returns the workflows and triggers a bundle is diffed against.
+// Workflows synced from a workflows directory are managed there and left out.
+func deployedState(ctx context.Context, b backend) ([]*engine.Workflow, []*engine.BundleTrigger, error) {
+	list, err := b.ListWorkflows(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var workflows []*engine.Workflow
+	for _, item := range list {
+		if item.Source != "" {
+			continue
+		}
+		wf, err := b.GetWorkflow(ctx, item.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		workflows = append(workflows, wf)
+	}
+
+	stored, err := b.ListTriggers(ctx, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	triggers := make([]*engine.BundleTrigger, len(stored))
+	for i, tr := range stored {
+		triggers[i] = bundleTrigger(tr)
+	}
+	return workflows, triggers, nil
+}
+
+func bundleTrigger(tr *storage.Trigger) *engine.BundleTrigger {
+	bt := &engine.BundleTrigger{ID: tr.ID, WorkflowID: tr.WorkflowID, Type: tr.Type, Enabled: tr.Enabled, FilePath: tr.FilePath}
+	json.Unmarshal(tr.Config, &bt.Config)
+	return bt
+}
+
+// applyChange makes one change of a bundle's plan.
+func applyChange(ctx context.Context, b backend, bundle *engine.Bundle, c engine.BundleChange) error {
+	switch {
+	case c.Kind == engine.ChangeKindWorkflow && c.Action == engine.ChangeDelete:
+		return b.DeleteWorkflow(ctx, c.ID)
+	case c.Kind == engine.ChangeKindTrigger && c.Action == engine.ChangeDelete:
+		return b.DeleteTrigger(ctx, c.ID)
+	case c.Kind == engine.ChangeKindWorkflow:
+		for _, wf := range bundle.Workflows {
+			if wf.ID != c.ID {
+				continue
+			}
+			if c.Action == engine.ChangeCreate {
+				return b.CreateWorkflow(ctx, wf)
+			}
+			return b.UpdateWorkflow(ctx, wf)
+		}
+	case c.Kind == engine.ChangeKindTrigger:
+		for _, tr := range bundle.Triggers {
+			if tr.ID != c.ID {
+				continue
+			}
+			req := api.CreateTriggerRequest{ID: tr.ID, WorkflowID: tr.WorkflowID, Type: tr.Type, Config: tr.Config, Enabled: tr.Enabled, FilePath: tr.FilePath}
+			if c.Action == engine.ChangeCreate {
+				return b.CreateTrigger(ctx, req)
+			}
+			return b.UpdateTrigger(ctx, c.ID, req)
+		}
+	}
+	return fmt.Errorf("%s %s is not in the bundle", c.Kind, c.ID)
+}
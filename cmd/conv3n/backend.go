@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/api"
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// backend is what the inspection commands talk to: either the local database
+// or a running server's HTTP API (--server). Both return the API's response types
+// so --format json output is identical in either mode.
+type backend interface {
+	ListWorkflows(ctx context.Context) ([]api.WorkflowListItem, error)
+	GetWorkflow(ctx context.Context, id string) (*engine.Workflow, error)
+	WorkflowCoverage(ctx context.Context, id string, limit int) (*engine.Coverage, error)
+	ListTriggers(ctx context.Context, workflowID string) ([]*storage.Trigger, error)
+	GetTrigger(ctx context.Context, id string) (*storage.Trigger, error)
+	FireTrigger(ctx context.Context, id string, payload map[string]interface{}) error
+	ListExecutions(ctx context.Context, workflowID string, limit int) ([]api.ExecutionResponse, error)
+	GetExecution(ctx context.Context, id string) (*api.ExecutionDetailResponse, error)
+	ExecutionLogs(ctx context.Context, id string) (*api.ExecutionLogsResponse, error)
+	StopExecution(ctx context.Context, id string) error
+	ListBlocks(ctx context.Context) ([]engine.RegisteredBlock, error)
+	ListBlockPackages(ctx context.Context) ([]engine.BlockPackage, error)
+	InstallBlockPackage(ctx context.Context, req api.InstallBlockPackageRequest) (*engine.BlockPackage, error)
+	// Used by apply to deploy bundles
+	CreateWorkflow(ctx context.Context, wf *engine.Workflow) error
+	UpdateWorkflow(ctx context.Context, wf *engine.Workflow) error
+	DeleteWorkflow(ctx context.Context, id string) error
+	CreateTrigger(ctx context.Context, req api.CreateTriggerRequest) error
+	UpdateTrigger(ctx context.Context, id string, req api.CreateTriggerRequest) error
+	DeleteTrigger(ctx context.Context, id string) error
+	Close() error
+}
+
+// errNeedsServer is returned by the local backend for operations that only make
+// sense inside the server process that owns the running executions and triggers.
+var errNeedsServer = errors.New("this command needs a running server; pass --server")
+
+// openBackend returns a remote backend when --server is set, otherwise the local database.
+func (o *cliOptions) openBackend() (backend, error) {
+	if o.Server != "" {
+		return newRemoteBackend(o.Server, o.APIKey), nil
+	}
+	store, err := o.openStore()
+	if err != nil {
+		return nil, err
+	}
+	return &localBackend{store: store, blocksDir: o.BlocksDir}, nil
+}
+
+// --- local ---
+
+type localBackend struct {
+	store     storage.Storage
+	blocksDir string
+}
+
+func (b *localBackend) ListWorkflows(ctx context.Context) ([]api.WorkflowListItem, error) {
+	workflows, err := b.store.ListWorkflows(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflows: %w", err)
+	}
+	list := make([]api.WorkflowListItem, len(workflows))
+	for i, wf := range workflows {
+		list[i] = api.WorkflowListItem{ID: wf.ID, Name: wf.Name, CreatedAt: wf.CreatedAt, UpdatedAt: wf.UpdatedAt, Source: wf.Source, Revision: wf.Revision}
+	}
+	return list, nil
+}
+
+func (b *localBackend) GetWorkflow(ctx context.Context, id string) (*engine.Workflow, error) {
+	stored, err := b.store.GetWorkflow(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("workflow not found: %w", err)
+	}
+	var workflow engine.Workflow
+	if err := json.Unmarshal(stored.Definition, &workflow); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow definition: %w", err)
+	}
+	return &workflow, nil
+}
+
+func (b *localBackend) WorkflowCoverage(ctx context.Context, id string, limit int) (*engine.Coverage, error) {
+	workflow, err := b.GetWorkflow(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return engine.WorkflowCoverage(ctx, b.store, b.blocksDir, *workflow, limit)
+}
+
+func (b *localBackend) ListTriggers(ctx context.Context, workflowID string) ([]*storage.Trigger, error) {
+	var triggers []*storage.Trigger
+	var err error
+	if workflowID != "" {
+		triggers, err = b.store.ListTriggers(ctx, workflowID)
+	} else {
+		triggers, err = b.store.ListAllTriggers(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list triggers: %w", err)
+	}
+	if triggers == nil {
+		triggers = []*storage.Trigger{}
+	}
+	return triggers, nil
+}
+
+func (b *localBackend) GetTrigger(ctx context.Context, id string) (*storage.Trigger, error) {
+	tr, err := b.store.GetTrigger(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("trigger not found: %w", err)
+	}
+	return tr, nil
+}
+
+func (b *localBackend) FireTrigger(ctx context.Context, id string, payload map[string]interface{}) error {
+	return errNeedsServer
+}
+
+func (b *localBackend) ListExecutions(ctx context.Context, workflowID string, limit int) ([]api.ExecutionResponse, error) {
+	execs, err := b.store.ListExecutions(ctx, workflowID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list executions: %w", err)
+	}
+	resp := make([]api.ExecutionResponse, len(execs))
+	for i, e := range execs {
+		resp[i] = api.NewExecutionResponse(e)
+	}
+	return resp, nil
+}
+
+func (b *localBackend) GetExecution(ctx context.Context, id string) (*api.ExecutionDetailResponse, error) {
+	e, err := b.store.GetExecution(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("execution not found: %w", err)
+	}
+	resp := &api.ExecutionDetailResponse{
+		ExecutionResponse: api.NewExecutionResponse(e),
+		State:             json.RawMessage(e.State),
+		TriggerData:       json.RawMessage(e.TriggerData),
+	}
+	if len(e.State) == 0 {
+		resp.State = json.RawMessage("null")
+	}
+	return resp, nil
+}
+
+func (b *localBackend) ExecutionLogs(ctx context.Context, id string) (*api.ExecutionLogsResponse, error) {
+	e, err := b.store.GetExecution(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("execution not found: %w", err)
+	}
+	results, err := b.store.ListNodeResults(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list node results: %w", err)
+	}
+	resp := &api.ExecutionLogsResponse{
+		ExecutionResponse: api.NewExecutionResponse(e),
+		Entries:           make([]api.ExecutionLogEntry, len(results)),
+	}
+	for i, nr := range results {
+		resp.Entries[i] = api.ExecutionLogEntry{NodeID: nr.NodeID, Time: nr.CreatedAt, Result: nr.Result}
+	}
+	return resp, nil
+}
+
+func (b *localBackend) StopExecution(ctx context.Context, id string) error {
+	return errNeedsServer
+}
+
+func (b *localBackend) ListBlocks(ctx context.Context) ([]engine.RegisteredBlock, error) {
+	return engine.BlockRegistryFor(b.blocksDir).Types()
+}
+
+func (b *localBackend) ListBlockPackages(ctx context.Context) ([]engine.BlockPackage, error) {
+	return engine.BlockRegistryFor(b.blocksDir).Packages()
+}
+
+func (b *localBackend) InstallBlockPackage(ctx context.Context, req api.InstallBlockPackageRequest) (*engine.BlockPackage, error) {
+	return engine.BlockRegistryFor(b.blocksDir).Install(ctx, req.Source, engine.BlockInstallOptions{Checksum: req.Checksum, Force: req.Force})
+}
+
+func (b *localBackend) CreateWorkflow(ctx context.Context, wf *engine.Workflow) error {
+	definition, err := json.Marshal(wf)
+	if err != nil {
+		return err
+	}
+	return b.store.CreateWorkflow(ctx, &storage.Workflow{ID: wf.ID, Name: wf.Name, Definition: definition})
+}
+
+func (b *localBackend) UpdateWorkflow(ctx context.Context, wf *engine.Workflow) error {
+	definition, err := json.Marshal(wf)
+	if err != nil {
+		return err
+	}
+	return b.store.UpdateWorkflow(ctx, &storage.Workflow{ID: wf.ID, Name: wf.Name, Definition: definition})
+}
+
+func (b *localBackend) DeleteWorkflow(ctx context.Context, id string) error {
+	return b.store.DeleteWorkflow(ctx, id)
+}
+
+// CreateTrigger saves the trigger; the server starts it when it next loads its triggers.
+func (b *localBackend) CreateTrigger(ctx context.Context, req api.CreateTriggerRequest) error {
+	config, err := json.Marshal(req.Config)
+	if err != nil {
+		return err
+	}
+	id := req.ID
+	if id == "" {
+		id = storage.NewID()
+	}
+	return b.store.CreateTrigger(ctx, &storage.Trigger{ID: id, WorkflowID: req.WorkflowID, Type: req.Type, Config: config, Enabled: req.Enabled, FilePath: req.FilePath})
+}
+
+func (b *localBackend) UpdateTrigger(ctx context.Context, id string, req api.CreateTriggerRequest) error {
+	config, err := json.Marshal(req.Config)
+	if err != nil {
+		return err
+	}
+	return b.store.UpdateTrigger(ctx, &storage.Trigger{ID: id, WorkflowID: req.WorkflowID, Type: req.Type, Config: config, Enabled: req.Enabled, FilePath: req.FilePath})
+}
+
+func (b *localBackend) DeleteTrigger(ctx context.Context, id string) error {
+	return b.store.DeleteTrigger(ctx, id)
+}
+
+func (b *localBackend) Close() error {
+	return b.store.Close()
+}
+
+// --- remote ---
+
+type remoteBackend struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+func newRemoteBackend(server, apiKey string) *remoteBackend {
+	return &remoteBackend{
+		baseURL: strings.TrimRight(server, "/"),
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// do sends a request to the server and decodes a JSON response into out (if non-nil).
+func (b *remoteBackend) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if b.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+b.apiKey)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", b.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		msg := strings.TrimSpace(string(raw))
+		var apiErr api.ErrorResponse
+		if json.Unmarshal(raw, &apiErr) == nil && apiErr.Error.Message != "" {
+			msg = apiErr.Error.Message
+		}
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, msg)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", path, err)
+	}
+	return nil
+}
+
+func (b *remoteBackend) ListWorkflows(ctx context.Context) ([]api.WorkflowListItem, error) {
+	var list []api.WorkflowListItem
+	if err := b.do(ctx, http.MethodGet, "/api/workflows", nil, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (b *remoteBackend) GetWorkflow(ctx context.Context, id string) (*engine.Workflow, error) {
+	var wf engine.Workflow
+	if err := b.do(ctx, http.MethodGet, "/api/workflows/"+url.PathEscape(id), nil, &wf); err != nil {
+		return nil, err
+	}
+	return &wf, nil
+}
+
+func (b *remoteBackend) WorkflowCoverage(ctx context.Context, id string, limit int) (*engine.Coverage, error) {
+	var cov engine.Coverage
+	path := "/api/workflows/" + url.PathEscape(id) + "/coverage?limit=" + strconv.Itoa(limit)
+	if err := b.do(ctx, http.MethodGet, path, nil, &cov); err != nil {
+		return nil, err
+	}
+	return &cov, nil
+}
+
+func (b *remoteBackend) ListTriggers(ctx context.Context, workflowID string) ([]*storage.Trigger, error) {
+	path := "/api/triggers"
+	if workflowID != "" {
+		path += "?workflow_id=" + url.QueryEscape(workflowID)
+	}
+	triggers := []*storage.Trigger{}
+	if err := b.do(ctx, http.MethodGet, path, nil, &triggers); err != nil {
+		return nil, err
+	}
+	return triggers, nil
+}
+
+func (b *remoteBackend) GetTrigger(ctx context.Context, id string) (*storage.Trigger, error) {
+	var tr storage.Trigger
+	if err := b.do(ctx, http.MethodGet, "/api/triggers/"+url.PathEscape(id), nil, &tr); err != nil {
+		return nil, err
+	}
+	return &tr, nil
+}
+
+func (b *remoteBackend) FireTrigger(ctx context.Context, id string, payload map[string]interface{}) error {
+	return b.do(ctx, http.MethodPost, "/api/triggers/"+url.PathEscape(id)+"/fire", payload, nil)
+}
+
+func (b *remoteBackend) ListExecutions(ctx context.Context, workflowID string, limit int) ([]api.ExecutionResponse, error) {
+	path := "/api/workflows/" + url.PathEscape(workflowID) + "/executions?limit=" + strconv.Itoa(limit)
+	var execs []api.ExecutionResponse
+	if err := b.do(ctx, http.MethodGet, path, nil, &execs); err != nil {
+		return nil, err
+	}
+	return execs, nil
+}
+
+func (b *remoteBackend) GetExecution(ctx context.Context, id string) (*api.ExecutionDetailResponse, error) {
+	var e api.ExecutionDetailResponse
+	if err := b.do(ctx, http.MethodGet, "/api/executions/"+url.PathEscape(id), nil, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (b *remoteBackend) ExecutionLogs(ctx context.Context, id string) (*api.ExecutionLogsResponse, error) {
+	var logs api.ExecutionLogsResponse
+	if err := b.do(ctx, http.MethodGet, "/api/executions/"+url.PathEscape(id)+"/logs", nil, &logs); err != nil {
+		return nil, err
+	}
+	return &logs, nil
+}
+
+func (b *remoteBackend) StopExecution(ctx context.Context, id string) error {
+	return b.do(ctx, http.MethodPost, "/api/executions/"+url.PathEscape(id)+"/stop", nil, nil)
+}
+
+func (b *remoteBackend) ListBlocks(ctx context.Context) ([]engine.RegisteredBlock, error) {
+	var blocks []engine.RegisteredBlock
+	if err := b.do(ctx, http.MethodGet, "/api/blocks", nil, &blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+func (b *remoteBackend) ListBlockPackages(ctx context.Context) ([]engine.BlockPackage, error) {
+	var packages []engine.BlockPackage
+	if err := b.do(ctx, http.MethodGet, "/api/blocks/packages", nil, &packages); err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
+
+func (b *remoteBackend) InstallBlockPackage(ctx context.Context, req api.InstallBlockPackageRequest) (*engine.BlockPackage, error) {
+	var pkg engine.BlockPackage
+	if err := b.do(ctx, http.MethodPost, "/api/blocks/install", req, &pkg); err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+func (b *remoteBackend) CreateWorkflow(ctx context.Context, wf *engine.Workflow) error {
+	return b.do(ctx, http.MethodPost, "/api/workflows", wf, nil)
+}
+
+func (b *remoteBackend) UpdateWorkflow(ctx context.Context, wf *engine.Workflow) error {
+	return b.do(ctx, http.MethodPut, "/api/workflows/"+url.PathEscape(wf.ID), wf, nil)
+}
+
+func (b *remoteBackend) DeleteWorkflow(ctx context.Context, id string) error {
+	return b.do(ctx, http.MethodDelete, "/api/workflows/"+url.PathEscape(id), nil, nil)
+}
+
+func (b *remoteBackend) CreateTrigger(ctx context.Context, req api.CreateTriggerRequest) error {
+	return b.do(ctx, http.MethodPost, "/api/triggers", req, nil)
+}
+
+func (b *remoteBackend) UpdateTrigger(ctx context.Context, id string, req api.CreateTriggerRequest) error {
+	return b.do(ctx, http.MethodPut, "/api/triggers/"+url.PathEscape(id), req, nil)
+}
+
+func (b *remoteBackend) DeleteTrigger(ctx context.Context, id string) error {
+	return b.do(ctx, http.MethodDelete, "/api/triggers/"+url.PathEscape(id), nil, nil)
+}
+
+func (b *remoteBackend) Close() error {
+	return nil
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/conv3n/conv3n/internal/awsauth"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// Exit codes. Scripts and CI jobs can rely on these staying stable.
+const (
+	exitError            = 1 // usage, configuration or I/O error
+	exitExecutionFailed  = 2 // the workflow ran and failed
+	exitTimeout          = 3 // the workflow was stopped by --timeout or settings.timeout
+	exitSnapshotMismatch = 4 // `test` found the run diverging from its snapshot
+)
+
+// exitCodeError carries a specific process exit code up to main.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string { return e.err.Error() }
+func (e *exitCodeError) Unwrap() error { return e.err }
+
+// exitCode returns the process exit code for an error returned by a command.
+func exitCode(err error) int {
+	var ec *exitCodeError
+	if errors.As(err, &ec) {
+		return ec.code
+	}
+	return exitError
+}
+
+// command is a single CLI subcommand.
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string) error
+}
+
+// commandList returns all subcommands in the order they appear in the usage text.
+func commandList() []command {
+	return []command{
+		{"serve", "serve [--addr :8080] [--grpc-addr :9090]", "Start the API server", cmdServe},
+		{"run", "run <workflow.json> [--input f] [--var k=v] [--env name] [--mocks f] [--output json] [--quiet] [--timeout d]", "Run a workflow file once", cmdRun},
+		{"validate", "validate <workflow.json>", "Check a workflow file for structural errors", cmdValidate},
+		{"test", "test <workflow.json> [--snapshot f] [--update [--input f]]", "Run a workflow file and compare it to its recorded snapshot", cmdTest},
+		{"workflows", "workflows [list | get <id> | coverage <id> [--limit n]]", "List or show stored workflows, or what their test runs cover", cmdWorkflows},
+		{"triggers", "triggers [list [--workflow <id>] | get <id> | fire <id>]", "List, show or fire triggers", cmdTriggers},
+		{"executions", "executions list <workflow-id> | get <id> | logs <id> | stop <id>", "Inspect, show logs of or stop executions", cmdExecutions},
+		{"blocks", "blocks [list | packages | install <git-url|npm-package> [--checksum sha256:...] [--force]]", "List block types or install a block package", cmdBlocks},
+		{"apply", "apply <bundle-dir> [--dry-run] [--prune]", "Diff a bundle of workflow and trigger files against the server and apply it", cmdApply},
+		{"new", "new block|trigger <namespace/name>", "Scaffold a block or trigger with a manifest and test", cmdNew},
+		{"migrate", "migrate", "Create or upgrade the database schema", cmdMigrate},
+		{"doctor", "doctor", "Check which script runtimes are installed and usable", cmdDoctor},
+	}
+}
+
+func findCommand(name string) (command, bool) {
+	// "server" is kept as an alias for scripts written against the old CLI
+	if name == "server" {
+		name = "serve"
+	}
+	for _, c := range commandList() {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+func printUsage() {
+	w := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "Usage: conv3n <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commandList() {
+		fmt.Fprintf(w, "  %s\t%s\n", c.usage, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Global flags:")
+	fmt.Fprintln(w, "  --db <dsn>\tdatabase: SQLite path or DSN such as sqlite://conv3n.db or memory:// (env CONV3N_DB, default conv3n.db)")
+	fmt.Fprintln(w, "  --blocks-dir <dir>\tBlocks directory (env CONV3N_BLOCKS_DIR, default ./pkg/blocks)")
+	fmt.Fprintln(w, "  --runtime <name>\tScript runtime: bun, node, tsx, deno or auto (env CONV3N_RUNTIME, default bun)")
+	fmt.Fprintln(w, "  --config <file>\tJSON config file with db, blocks_dir, runtime, format, addr, grpc_addr, server, api_key and scrub keys")
+	fmt.Fprintln(w, "  --format json|table\tOutput format (default table)")
+	fmt.Fprintln(w, "  --server <url>\tUse a running server's API instead of the database (env CONV3N_SERVER)")
+	fmt.Fprintln(w, "  --api-key <key>\tAPI key for --server (env CONV3N_API_KEY)")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Exit codes:")
+	fmt.Fprintln(w, "  0\tsuccess")
+	fmt.Fprintln(w, "  1\tusage, configuration or I/O error")
+	fmt.Fprintln(w, "  2\tworkflow execution failed")
+	fmt.Fprintln(w, "  3\tworkflow timed out")
+	fmt.Fprintln(w, "  4\tworkflow diverged from its snapshot (test)")
+	w.Flush()
+}
+
+// cliOptions holds settings shared by every subcommand.
+// Precedence: explicit flag > config file > environment > built-in default.
+type cliOptions struct {
+	DBPath       string `json:"db"`
+	BlocksDir    string `json:"blocks_dir"`
+	Runtime      string `json:"runtime"`
+	Format       string `json:"format"`
+	Addr         string `json:"addr"`
+	GRPCAddr     string `json:"grpc_addr"`
+	UIDir        string `json:"ui_dir"`
+	WorkflowsDir string `json:"workflows_dir"`
+	Server       string `json:"server"`
+	APIKey       string `json:"api_key"`
+	// Scrub are the rules applied to the data stored (see storage.ScrubRule);
+	// env CONV3N_SCRUB_RULES holds them as JSON when the config file doesn't
+	Scrub []storage.ScrubRule `json:"scrub"`
+
+	configPath string
+}
+
+// newFlagSet creates a flag set for a subcommand with the global flags registered.
+func newFlagSet(name string) (*flag.FlagSet, *cliOptions) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	opts := &cliOptions{}
+
+	defaultDB := os.Getenv("CONV3N_DB")
+	if defaultDB == "" {
+		defaultDB = "conv3n.db"
+	}
+	defaultRuntime := os.Getenv("CONV3N_RUNTIME")
+	if defaultRuntime == "" {
+		defaultRuntime = "bun"
+	}
+	defaultBlocks := os.Getenv("CONV3N_BLOCKS_DIR")
+	if defaultBlocks == "" {
+		cwd, _ := os.Getwd()
+		defaultBlocks = filepath.Join(cwd, "pkg", "blocks")
+	}
+
+	fs.StringVar(&opts.DBPath, "db", defaultDB, "database DSN (e.g. sqlite://conv3n.db) or SQLite database path")
+	fs.StringVar(&opts.BlocksDir, "blocks-dir", defaultBlocks, "blocks directory")
+	fs.StringVar(&opts.Runtime, "runtime", defaultRuntime, "script runtime: bun, node, tsx, deno or auto")
+	fs.StringVar(&opts.configPath, "config", "", "JSON config file")
+	fs.StringVar(&opts.Format, "format", "table", "output format: json or table")
+	fs.StringVar(&opts.Server, "server", os.Getenv("CONV3N_SERVER"), "talk to a running server at this URL instead of the local database")
+	fs.StringVar(&opts.APIKey, "api-key", os.Getenv("CONV3N_API_KEY"), "API key for --server")
+	return fs, opts
+}
+
+// parseFlags parses args, allowing flags after positional arguments
+// (e.g. `conv3n run wf.json --db other.db`), and applies the config file.
+func parseFlags(fs *flag.FlagSet, opts *cliOptions, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	if err := opts.applyConfig(fs); err != nil {
+		return nil, err
+	}
+	if opts.Format != "json" && opts.Format != "table" {
+		return nil, fmt.Errorf("invalid --format %q (want json or table)", opts.Format)
+	}
+	return positional, nil
+}
+
+// applyConfig fills in values from the --config file for flags not given explicitly.
+func (o *cliOptions) applyConfig(fs *flag.FlagSet) error {
+	if o.configPath == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(o.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	var fileOpts cliOptions
+	if err := json.Unmarshal(data, &fileOpts); err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", o.configPath, err)
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	if !set["db"] && fileOpts.DBPath != "" {
+		o.DBPath = fileOpts.DBPath
+	}
+	if !set["blocks-dir"] && fileOpts.BlocksDir != "" {
+		o.BlocksDir = fileOpts.BlocksDir
+	}
+	if !set["runtime"] && fileOpts.Runtime != "" {
+		o.Runtime = fileOpts.Runtime
+	}
+	if !set["format"] && fileOpts.Format != "" {
+		o.Format = fileOpts.Format
+	}
+	if !set["addr"] && fileOpts.Addr != "" {
+		o.Addr = fileOpts.Addr
+	}
+	if !set["grpc-addr"] && fileOpts.GRPCAddr != "" {
+		o.GRPCAddr = fileOpts.GRPCAddr
+	}
+	if !set["ui-dir"] && fileOpts.UIDir != "" {
+		o.UIDir = fileOpts.UIDir
+	}
+	if !set["workflows-dir"] && fileOpts.WorkflowsDir != "" {
+		o.WorkflowsDir = fileOpts.WorkflowsDir
+	}
+	if !set["server"] && fileOpts.Server != "" {
+		o.Server = fileOpts.Server
+	}
+	if !set["api-key"] && fileOpts.APIKey != "" {
+		o.APIKey = fileOpts.APIKey
+	}
+	if len(fileOpts.Scrub) > 0 {
+		o.Scrub = fileOpts.Scrub
+	}
+	return nil
+}
+
+// keyValueFlag collects repeated key=value flags. Values that parse as JSON
+// (numbers, booleans, objects) are stored decoded; anything else is kept as a string.
+type keyValueFlag map[string]interface{}
+
+func (f keyValueFlag) String() string {
+	return fmt.Sprint(map[string]interface{}(f))
+}
+
+func (f keyValueFlag) Set(s string) error {
+	key, raw, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", s)
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		value = raw
+	}
+	f[key] = value
+	return nil
+}
+
+// openStore opens (and migrates) the configured database; see storage.Open.
+// Payloads are scrubbed of personal data by the scrub rules, hashing under the
+// secret CONV3N_HASH_KEY, and encrypted at rest when a key is configured (see
+// encryptionKeys).
+func (o *cliOptions) openStore() (storage.Storage, error) {
+	keys, err := encryptionKeys()
+	if err != nil {
+		return nil, err
+	}
+	rules := o.Scrub
+	if raw := os.Getenv("CONV3N_SCRUB_RULES"); len(rules) == 0 && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+			return nil, fmt.Errorf("invalid CONV3N_SCRUB_RULES: %w", err)
+		}
+	}
+	store, err := storage.Open(o.DBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	if keys != nil {
+		store = storage.NewEncryptedStorage(store, keys)
+	}
+	if len(rules) > 0 {
+		scrubbing, err := storage.NewScrubbingStorage(store, rules, []byte(os.Getenv("CONV3N_HASH_KEY")))
+		if err != nil {
+			store.Close()
+			return nil, fmt.Errorf("invalid scrub rules: %w", err)
+		}
+		store = scrubbing
+	}
+	return store, nil
+}
+
+// encryptionKeys returns the key payloads are encrypted under at rest, nil when
+// encryption is off: the AWS KMS key CONV3N_KMS_KEY_ID (in CONV3N_KMS_REGION or
+// AWS_REGION, with the AWS_* credentials, reached at CONV3N_KMS_ENDPOINT if
+// set), or else the base64-encoded 32-byte key CONV3N_ENCRYPTION_KEY.
+func encryptionKeys() (storage.KeyProvider, error) {
+	if keyID := os.Getenv("CONV3N_KMS_KEY_ID"); keyID != "" {
+		region := envFirst("CONV3N_KMS_REGION", "AWS_REGION")
+		if region == "" {
+			return nil, fmt.Errorf("CONV3N_KMS_KEY_ID requires CONV3N_KMS_REGION or AWS_REGION")
+		}
+		return &storage.AWSKMS{
+			KeyID:  keyID,
+			Region: region,
+			Credentials: awsauth.Credentials{
+				AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+				SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+				SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			},
+			Endpoint: os.Getenv("CONV3N_KMS_ENDPOINT"),
+		}, nil
+	}
+	if raw := os.Getenv("CONV3N_ENCRYPTION_KEY"); raw != "" {
+		key, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CONV3N_ENCRYPTION_KEY: %w", err)
+		}
+		local, err := storage.NewLocalKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CONV3N_ENCRYPTION_KEY: %w", err)
+		}
+		return local, nil
+	}
+	return nil, nil
+}
+
+// render writes v as indented JSON or rows as an aligned table, depending on --format.
+func (o *cliOptions) render(w io.Writer, v interface{}, headers []string, rows [][]string) error {
+	if o.Format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// writeWorkflowFile writes a workflow definition to a file and returns its path.
+func writeWorkflowFile(t *testing.T, def string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "workflow.json")
+	if err := os.WriteFile(path, []byte(def), 0o644); err != nil {
+		t.Fatalf("failed to write workflow: %v", err)
+	}
+	return path
+}
+
+// captureStdout returns what fn prints to stdout, and its error.
+func captureStdout(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	done := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- string(out)
+	}()
+	err = fn()
+	w.Close()
+	return <-done, err
+}
+
+func TestRunCLI_ExitCodes(t *testing.T) {
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	tests := []struct {
+		name     string
+		workflow string // Empty runs a missing file
+		timeout  time.Duration
+		wantCode int
+		wantErr  string
+	}{
+		{
+			name:     "success",
+			workflow: `{"id":"wf-ok","name":"OK","nodes":{"mark":{"id":"mark","type":"std/set_var","config":{"name":"seen","value":true}}}}`,
+		},
+		{
+			name:     "missing workflow file",
+			wantCode: exitError,
+			wantErr:  "no such file",
+		},
+		{
+			name:     "failed node",
+			workflow: `{"id":"wf-fail","name":"Fail","nodes":{"check":{"id":"check","type":"std/condition","config":{"expression":" "}}}}`,
+			wantCode: exitExecutionFailed,
+			wantErr:  "workflow execution failed",
+		},
+		{
+			name:     "settings.timeout",
+			workflow: `{"id":"wf-slow","name":"Slow","settings":{"timeout":"50ms"},"nodes":{"wait":{"id":"wait","type":"std/delay","config":{"duration":2,"unit":"s"}}}}`,
+			wantCode: exitTimeout,
+			wantErr:  "run exceeded its 50ms timeout (settings.timeout)",
+		},
+		{
+			name:     "--timeout",
+			workflow: `{"id":"wf-slow","name":"Slow","settings":{"timeout":"1m"},"nodes":{"wait":{"id":"wait","type":"std/delay","config":{"duration":2,"unit":"s"}}}}`,
+			timeout:  50 * time.Millisecond,
+			wantCode: exitTimeout,
+			wantErr:  "run exceeded its 50ms timeout (--timeout)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "missing.json")
+			if tt.workflow != "" {
+				path = writeWorkflowFile(t, tt.workflow)
+			}
+			store, _ := storage.Open("memory://")
+			defer store.Close()
+			_, err := captureStdout(t, func() error {
+				return runCLI(path, t.TempDir(), store, runInput{}, runOutput{Format: "json", Quiet: true, Timeout: tt.timeout})
+			})
+			if tt.wantCode == 0 {
+				if err != nil {
+					t.Fatalf("expected success, got %v", err)
+				}
+				return
+			}
+			if err == nil || exitCode(err) != tt.wantCode || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected exit code %d with %q, got %d: %v", tt.wantCode, tt.wantErr, exitCode(err), err)
+			}
+			if strings.Contains(err.Error(), "%!") {
+				t.Errorf("expected a well-formed error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRunCLI_JSONOutput(t *testing.T) {
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	store, _ := storage.Open("memory://")
+	defer store.Close()
+	path := writeWorkflowFile(t, `{"id":"wf-json","name":"JSON","nodes":{
+		"mark":{"id":"mark","type":"std/set_var","config":{"name":"seen","value":true}},
+		"check":{"id":"check","type":"std/condition","config":{"expression":"{{ $trigger.n }} > 1"}}},
+		"edges":[{"source":"mark","target":"check"}]}`)
+
+	tests := []struct {
+		name       string
+		input      runInput
+		wantStatus string
+		wantCode   int
+	}{
+		{"completed", runInput{TriggerData: map[string]interface{}{"n": 2}}, "completed", 0},
+		{"failed", runInput{TriggerData: map[string]interface{}{"n": "'"}}, "failed", exitExecutionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := captureStdout(t, func() error {
+				return runCLI(path, t.TempDir(), store, tt.input, runOutput{Format: "json"})
+			})
+			if code := exitCode(err); err != nil && code != tt.wantCode || err == nil && tt.wantCode != 0 {
+				t.Fatalf("expected exit code %d, got %v", tt.wantCode, err)
+			}
+
+			var got map[string]interface{}
+			if err := json.Unmarshal([]byte(out), &got); err != nil {
+				t.Fatalf("expected only JSON on stdout, got %q: %v", out, err)
+			}
+			for _, key := range []string{"workflow_id", "execution_id", "status", "duration_ms", "results", "variables"} {
+				if _, ok := got[key]; !ok {
+					t.Errorf("expected %q in the output, got %v", key, got)
+				}
+			}
+			if got["workflow_id"] != "wf-json" || got["status"] != tt.wantStatus {
+				t.Errorf("expected a %s run of wf-json, got %v", tt.wantStatus, got)
+			}
+			if _, hasErr := got["error"]; hasErr != (tt.wantCode != 0) {
+				t.Errorf("expected an error field only for a failed run, got %v", got["error"])
+			}
+			if vars, _ := got["variables"].(map[string]interface{}); vars["seen"] != true {
+				t.Errorf("expected the variables the run set, got %v", got["variables"])
+			}
+		})
+	}
+}
+
+func TestCmdTest_SnapshotMismatch(t *testing.T) {
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	path := writeWorkflowFile(t, `{"id":"wf-snap","name":"Snap","nodes":{"mark":{"id":"mark","type":"std/set_var","config":{"name":"seen","value":"v1"}}}}`)
+	args := []string{path, "--db", "memory://", "--blocks-dir", t.TempDir()}
+
+	if _, err := captureStdout(t, func() error { return cmdTest(append(args, "--update")) }); err != nil {
+		t.Fatalf("failed to record the snapshot: %v", err)
+	}
+	if _, err := captureStdout(t, func() error { return cmdTest(args) }); err != nil {
+		t.Errorf("expected the unchanged workflow to match its snapshot, got %v", err)
+	}
+
+	os.WriteFile(path, []byte(`{"id":"wf-snap","name":"Snap","nodes":{"mark":{"id":"mark","type":"std/set_var","config":{"name":"seen","value":"v2"}}}}`), 0o644)
+	_, err := captureStdout(t, func() error { return cmdTest(args) })
+	if exitCode(err) != exitSnapshotMismatch {
+		t.Errorf("expected exit code %d for a diverging run, got %d: %v", exitSnapshotMismatch, exitCode(err), err)
+	}
+}
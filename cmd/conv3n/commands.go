@@ -0,0 +1,671 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/api"
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+const timeLayout = "2006-01-02 15:04:05"
+
+// loadWorkflowFile reads and parses a workflow definition from disk.
+func loadWorkflowFile(path string) (*engine.Workflow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var workflow engine.Workflow
+	if err := json.Unmarshal(data, &workflow); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow JSON: %w", err)
+	}
+	return &workflow, nil
+}
+
+// --- run ---
+
+// runInput is the data seeded into the execution context before a CLI run.
+type runInput struct {
+	TriggerData map[string]interface{}
+	Variables   map[string]interface{}
+	Environment string        // selects the $globals overrides
+	Mocks       *engine.Mocks // runs the workflow in mock mode if set
+}
+
+// runOutput controls what a CLI run prints and how long it may take.
+type runOutput struct {
+	Format string // text or json
+	Quiet  bool   // print nothing on success (for cron)
+	// Timeout bounds the run; 0 falls back to the workflow's settings.timeout, else no limit.
+	Timeout time.Duration
+}
+
+func cmdRun(args []string) error {
+	fs, opts := newFlagSet("run")
+	inputFile := fs.String("input", "", "JSON file used as the trigger payload ($trigger.*), - for stdin")
+	triggerData := fs.String("trigger-data", "", "inline JSON object merged into the trigger payload")
+	vars := keyValueFlag{}
+	fs.Var(vars, "var", "set a variable ($vars.key) as key=value; repeatable, JSON values are decoded")
+	output := fs.String("output", "text", "result output: text or json (full results and variables)")
+	quiet := fs.Bool("quiet", false, "print nothing on success; failures still go to stderr")
+	env := fs.String("env", "", "environment whose global variables ($globals) override the defaults, e.g. staging")
+	timeout := fs.Duration("timeout", 0, "maximum run time, e.g. 30s or 10m (default: the workflow's settings.timeout, else unlimited)")
+	mocksFile := fs.String("mocks", "", "JSON file of mock responses; network calls are answered by them instead (mock mode)")
+	positional, err := parseFlags(fs, opts, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: conv3n run <workflow.json>")
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (want text or json)", *output)
+	}
+	if *timeout < 0 {
+		return fmt.Errorf("invalid --timeout %s: must not be negative", *timeout)
+	}
+
+	input := runInput{TriggerData: map[string]interface{}{}, Variables: vars, Environment: *env}
+	if *inputFile != "" {
+		if err := readJSONObject(*inputFile, input.TriggerData); err != nil {
+			return fmt.Errorf("--input: %w", err)
+		}
+	}
+	if *triggerData != "" {
+		if err := json.Unmarshal([]byte(*triggerData), &input.TriggerData); err != nil {
+			return fmt.Errorf("--trigger-data must be a JSON object: %w", err)
+		}
+	}
+	if *mocksFile != "" {
+		if input.Mocks, err = readMocksFile(*mocksFile); err != nil {
+			return fmt.Errorf("--mocks: %w", err)
+		}
+	}
+
+	applyResourceLimits()
+	applySecretProviders()
+	if err := applyRuntime(opts.Runtime); err != nil {
+		return err
+	}
+
+	store, err := opts.openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	return runCLI(positional[0], opts.BlocksDir, store, input, runOutput{Format: *output, Quiet: *quiet, Timeout: *timeout})
+}
+
+// readMocksFile reads the mock responses of a mock-mode run (see engine.Mocks).
+func readMocksFile(path string) (*engine.Mocks, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var mocks engine.Mocks
+	if err := json.Unmarshal(data, &mocks); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := mocks.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid mocks in %s: %w", path, err)
+	}
+	return &mocks, nil
+}
+
+// readJSONObject decodes the JSON object in path ("-" for stdin) into dst.
+func readJSONObject(path string, dst map[string]interface{}) error {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &dst); err != nil {
+		return fmt.Errorf("%s is not a JSON object: %w", path, err)
+	}
+	return nil
+}
+
+// --- validate ---
+
+func cmdValidate(args []string) error {
+	fs, opts := newFlagSet("validate")
+	positional, err := parseFlags(fs, opts, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		return fmt.Errorf("usage: conv3n validate <workflow.json>...")
+	}
+
+	// Edges leaving through a named port must use one the block declares
+	runner := engine.NewBunRunner(opts.BlocksDir)
+	failed := 0
+	for _, path := range positional {
+		workflow, err := loadWorkflowFile(path)
+		if err == nil {
+			err = errors.Join(workflow.Validate(), workflow.ValidatePorts(runner.DeclaredPorts))
+		}
+		if err != nil {
+			failed++
+			fmt.Printf("%s: INVALID\n%v\n", path, err)
+			continue
+		}
+		fmt.Printf("%s: OK (%d nodes, %d edges)\n", path, len(workflow.Nodes), len(workflow.Edges))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d workflow(s) invalid", failed, len(positional))
+	}
+	return nil
+}
+
+// --- test ---
+
+func cmdTest(args []string) error {
+	fs, opts := newFlagSet("test")
+	snapshotFile := fs.String("snapshot", "", "snapshot file (default: the workflow file's name with .snapshot.json)")
+	update := fs.Bool("update", false, "record the run as the snapshot instead of comparing it")
+	inputFile := fs.String("input", "", "JSON file used as the trigger payload ($trigger.*) of the recorded run, - for stdin")
+	positional, err := parseFlags(fs, opts, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: conv3n test <workflow.json>")
+	}
+	if *inputFile != "" && !*update {
+		return fmt.Errorf("--input sets the trigger payload of a recorded run; use it with --update")
+	}
+	path := positional[0]
+	if *snapshotFile == "" {
+		*snapshotFile = strings.TrimSuffix(path, filepath.Ext(path)) + ".snapshot.json"
+	}
+
+	workflow, err := loadWorkflowFile(path)
+	if err != nil {
+		return err
+	}
+	// The options and trigger payload of a snapshot are kept when it is re-recorded
+	snap, err := readSnapshotFile(*snapshotFile)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && *update:
+		snap = &engine.Snapshot{WorkflowID: workflow.ID}
+	case errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("no snapshot at %s; record one with --update", *snapshotFile)
+	case err != nil:
+		return err
+	}
+	if *inputFile != "" {
+		snap.TriggerData = map[string]interface{}{}
+		if err := readJSONObject(*inputFile, snap.TriggerData); err != nil {
+			return fmt.Errorf("--input: %w", err)
+		}
+	}
+
+	applyResourceLimits()
+	applySecretProviders()
+	if err := applyRuntime(opts.Runtime); err != nil {
+		return err
+	}
+	store, err := opts.openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	// The report is the output; the engine's progress logs would bury it
+	log.SetOutput(io.Discard)
+	ctx := context.Background()
+	report, err := engine.RunSnapshotTest(ctx, store, opts.BlocksDir, *workflow, snap)
+	if err != nil {
+		return err
+	}
+
+	if *update {
+		recorded, err := engine.NewSnapshot(ctx, store, report.ExecutionID, snap.SnapshotOptions)
+		if err != nil {
+			return err
+		}
+		if err := writeSnapshotFile(*snapshotFile, recorded); err != nil {
+			return err
+		}
+		fmt.Printf("Recorded snapshot of %d node(s) to %s (run %s)\n", len(recorded.Nodes), *snapshotFile, recorded.Status)
+		return nil
+	}
+
+	var rows [][]string
+	for _, n := range report.Nodes {
+		if n.Status == engine.DiffUnchanged {
+			continue
+		}
+		if len(n.Changes) == 0 {
+			rows = append(rows, []string{n.NodeID, n.Status, "", "", ""})
+		}
+		for _, c := range n.Changes {
+			rows = append(rows, []string{n.NodeID, c.Change, c.Path, string(c.A), string(c.B)})
+		}
+	}
+	if report.Status != report.ExpectedStatus {
+		rows = append(rows, []string{"", "status", "", report.ExpectedStatus, report.Status})
+	}
+	if report.Passed && opts.Format != "json" {
+		fmt.Printf("%s: OK (%d nodes match %s)\n", path, len(report.Nodes), *snapshotFile)
+		return nil
+	}
+	if err := opts.render(os.Stdout, report, []string{"NODE", "CHANGE", "FIELD", "SNAPSHOT", "RUN"}, rows); err != nil {
+		return err
+	}
+	if !report.Passed {
+		return &exitCodeError{code: exitSnapshotMismatch, err: fmt.Errorf("%s diverged from its snapshot %s", path, *snapshotFile)}
+	}
+	return nil
+}
+
+// readSnapshotFile reads the snapshot a workflow file is tested against.
+func readSnapshotFile(path string) (*engine.Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snap engine.Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", path, err)
+	}
+	if err := snap.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+	return &snap, nil
+}
+
+// writeSnapshotFile writes snap to path as indented JSON, to be committed and
+// reviewed next to the workflow file.
+func writeSnapshotFile(path string, snap *engine.Snapshot) error {
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// --- workflows ---
+
+func cmdWorkflows(args []string) error {
+	fs, opts := newFlagSet("workflows")
+	limit := fs.Int("limit", 20, "maximum number of test runs counted (coverage)")
+	positional, err := parseFlags(fs, opts, args)
+	if err != nil {
+		return err
+	}
+
+	b, err := opts.openBackend()
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	ctx := context.Background()
+
+	sub, rest := splitSubcommand(positional, "list")
+	switch sub {
+	case "list":
+		workflows, err := b.ListWorkflows(ctx)
+		if err != nil {
+			return err
+		}
+		rows := make([][]string, len(workflows))
+		for i, wf := range workflows {
+			rows[i] = []string{wf.ID, wf.Name, wf.UpdatedAt.Local().Format(timeLayout)}
+		}
+		return opts.render(os.Stdout, workflows, []string{"ID", "NAME", "UPDATED"}, rows)
+
+	case "get":
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: conv3n workflows get <id>")
+		}
+		workflow, err := b.GetWorkflow(ctx, rest[0])
+		if err != nil {
+			return err
+		}
+		rows := make([][]string, 0, len(workflow.Nodes))
+		for id, node := range workflow.Nodes {
+			rows = append(rows, []string{id, string(node.Type)})
+		}
+		return opts.render(os.Stdout, workflow, []string{"NODE", "TYPE"}, rows)
+
+	case "coverage":
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: conv3n workflows coverage <id> [--limit n]")
+		}
+		cov, err := b.WorkflowCoverage(ctx, rest[0], *limit)
+		if err != nil {
+			return err
+		}
+		rows := make([][]string, len(cov.Nodes))
+		for i, n := range cov.Nodes {
+			ports := make([]string, 0, len(n.Ports))
+			for _, port := range slices.Sorted(maps.Keys(n.Ports)) {
+				ports = append(ports, fmt.Sprintf("%s=%d", port, n.Ports[port]))
+			}
+			rows[i] = []string{n.NodeID, string(n.Type), strconv.Itoa(n.Runs), strconv.Itoa(n.Failed), strings.Join(ports, " ")}
+		}
+		if err := opts.render(os.Stdout, cov, []string{"NODE", "TYPE", "RUNS", "FAILED", "PORTS"}, rows); err != nil {
+			return err
+		}
+		if opts.Format != "json" {
+			fmt.Printf("\n%d/%d nodes reached, %d/%d edges traversed by %d test run(s)\n", cov.NodesReached, len(cov.Nodes), cov.EdgesTraversed, len(cov.Edges), cov.Runs)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown workflows subcommand %q (want list, get or coverage)", sub)
+	}
+}
+
+// --- triggers ---
+
+func cmdTriggers(args []string) error {
+	fs, opts := newFlagSet("triggers")
+	workflowID := fs.String("workflow", "", "only list triggers of this workflow")
+	payload := fs.String("payload", "", "JSON object sent as the trigger payload (fire)")
+	positional, err := parseFlags(fs, opts, args)
+	if err != nil {
+		return err
+	}
+
+	b, err := opts.openBackend()
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	ctx := context.Background()
+
+	sub, rest := splitSubcommand(positional, "list")
+	switch sub {
+	case "list":
+		triggers, err := b.ListTriggers(ctx, *workflowID)
+		if err != nil {
+			return err
+		}
+		rows := make([][]string, len(triggers))
+		for i, tr := range triggers {
+			rows[i] = []string{tr.ID, tr.WorkflowID, tr.Type, strconv.FormatBool(tr.Enabled), string(tr.RuntimeStatus)}
+		}
+		return opts.render(os.Stdout, triggers, []string{"ID", "WORKFLOW", "TYPE", "ENABLED", "STATUS"}, rows)
+
+	case "get":
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: conv3n triggers get <id>")
+		}
+		tr, err := b.GetTrigger(ctx, rest[0])
+		if err != nil {
+			return err
+		}
+		rows := [][]string{
+			{"id", tr.ID},
+			{"workflow", tr.WorkflowID},
+			{"type", tr.Type},
+			{"enabled", strconv.FormatBool(tr.Enabled)},
+			{"status", string(tr.RuntimeStatus)},
+			{"config", string(tr.Config)},
+		}
+		if tr.RuntimeError != nil {
+			rows = append(rows, []string{"error", *tr.RuntimeError})
+		}
+		return opts.render(os.Stdout, tr, []string{"FIELD", "VALUE"}, rows)
+
+	case "fire":
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: conv3n triggers fire <id> [--payload '{...}']")
+		}
+		var data map[string]interface{}
+		if *payload != "" {
+			if err := json.Unmarshal([]byte(*payload), &data); err != nil {
+				return fmt.Errorf("--payload must be a JSON object: %w", err)
+			}
+		}
+		if err := b.FireTrigger(ctx, rest[0], data); err != nil {
+			return err
+		}
+		fmt.Printf("Trigger %s fired\n", rest[0])
+		return nil
+
+	default:
+		return fmt.Errorf("unknown triggers subcommand %q (want list, get or fire)", sub)
+	}
+}
+
+// --- executions ---
+
+func cmdExecutions(args []string) error {
+	fs, opts := newFlagSet("executions")
+	limit := fs.Int("limit", 20, "maximum number of executions to list")
+	positional, err := parseFlags(fs, opts, args)
+	if err != nil {
+		return err
+	}
+
+	b, err := opts.openBackend()
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	ctx := context.Background()
+
+	sub, rest := splitSubcommand(positional, "")
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: conv3n executions list <workflow-id> | get <id> | logs <id> | stop <id>")
+	}
+
+	switch sub {
+	case "list":
+		execs, err := b.ListExecutions(ctx, rest[0], *limit)
+		if err != nil {
+			return err
+		}
+		rows := make([][]string, len(execs))
+		for i, e := range execs {
+			rows[i] = []string{e.ID, string(e.Status), e.StartedAt.Local().Format(timeLayout), executionDuration(e)}
+		}
+		return opts.render(os.Stdout, execs, []string{"ID", "STATUS", "STARTED", "DURATION"}, rows)
+
+	case "get":
+		e, err := b.GetExecution(ctx, rest[0])
+		if err != nil {
+			return err
+		}
+		rows := [][]string{
+			{"id", e.ID},
+			{"workflow", e.WorkflowID},
+			{"status", string(e.Status)},
+			{"started", e.StartedAt.Local().Format(timeLayout)},
+			{"duration", executionDuration(e.ExecutionResponse)},
+		}
+		if e.HeartbeatAt != nil {
+			rows = append(rows, []string{"heartbeat", e.HeartbeatAt.Local().Format(timeLayout)})
+		}
+		if e.RequestID != "" {
+			rows = append(rows, []string{"request", e.RequestID})
+		}
+		if e.Revision != "" {
+			rows = append(rows, []string{"revision", e.Revision})
+		}
+		if e.CorrelationID != "" {
+			rows = append(rows, []string{"correlation", e.CorrelationID})
+		}
+		for _, key := range slices.Sorted(maps.Keys(e.Labels)) {
+			rows = append(rows, []string{"label " + key, e.Labels[key]})
+		}
+		rows = append(rows, []string{"usage", fmt.Sprintf("%d nodes, %d ms CPU, %d bytes stored, %d HTTP calls",
+			e.Usage.Nodes, e.Usage.CPUTimeMS, e.Usage.BytesStored, e.Usage.HTTPCalls)})
+		if e.Error != nil {
+			rows = append(rows, []string{"error", *e.Error})
+		}
+		return opts.render(os.Stdout, e, []string{"FIELD", "VALUE"}, rows)
+
+	case "logs":
+		logs, err := b.ExecutionLogs(ctx, rest[0])
+		if err != nil {
+			return err
+		}
+		rows := make([][]string, 0, len(logs.Entries)+1)
+		for _, entry := range logs.Entries {
+			rows = append(rows, []string{entry.Time.Local().Format(timeLayout), entry.NodeID, truncate(string(entry.Result), 100)})
+		}
+		// Close with the execution's outcome so a failed run shows why it stopped
+		outcome := string(logs.Status)
+		if logs.Error != nil {
+			outcome += ": " + *logs.Error
+		}
+		end := "-"
+		if logs.CompletedAt != nil {
+			end = logs.CompletedAt.Local().Format(timeLayout)
+		}
+		rows = append(rows, []string{end, "(execution)", outcome})
+		return opts.render(os.Stdout, logs, []string{"TIME", "NODE", "OUTPUT"}, rows)
+
+	case "stop":
+		if err := b.StopExecution(ctx, rest[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Execution %s stopped\n", rest[0])
+		return nil
+
+	default:
+		return fmt.Errorf("unknown executions subcommand %q (want list, get, logs or stop)", sub)
+	}
+}
+
+// truncate shortens s to at most n runes for table output.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-3]) + "..."
+}
+
+// executionDuration formats how long an execution ran (or has been running).
+func executionDuration(e api.ExecutionResponse) string {
+	end := time.Now()
+	if e.CompletedAt != nil {
+		end = *e.CompletedAt
+	}
+	return end.Sub(e.StartedAt).Round(time.Millisecond).String()
+}
+
+// --- blocks ---
+
+func cmdBlocks(args []string) error {
+	fs, opts := newFlagSet("blocks")
+	checksum := fs.String("checksum", "", "expected sha256:<hex> checksum of the package contents (install)")
+	force := fs.Bool("force", false, "replace an installed package with the same namespace (install)")
+	positional, err := parseFlags(fs, opts, args)
+	if err != nil {
+		return err
+	}
+
+	b, err := opts.openBackend()
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	ctx := context.Background()
+
+	sub, rest := splitSubcommand(positional, "list")
+	switch sub {
+	case "list":
+		blocks, err := b.ListBlocks(ctx)
+		if err != nil {
+			return err
+		}
+		rows := make([][]string, len(blocks))
+		for i, block := range blocks {
+			version, description := orDash(block.Version), ""
+			if block.Yanked {
+				version += " (yanked)"
+			}
+			if block.Manifest != nil {
+				description = block.Manifest.Description
+			}
+			rows[i] = []string{string(block.Type), version, truncate(description, 40), displayPath(block.Script)}
+		}
+		return opts.render(os.Stdout, blocks, []string{"TYPE", "VERSION", "DESCRIPTION", "SCRIPT"}, rows)
+
+	case "packages":
+		packages, err := b.ListBlockPackages(ctx)
+		if err != nil {
+			return err
+		}
+		rows := make([][]string, len(packages))
+		for i, pkg := range packages {
+			rows[i] = []string{pkg.Namespace, orDash(pkg.Version), pkg.Source, strconv.Itoa(len(pkg.Blocks)), pkg.InstalledAt.Local().Format(timeLayout)}
+		}
+		return opts.render(os.Stdout, packages, []string{"NAMESPACE", "VERSION", "SOURCE", "BLOCKS", "INSTALLED"}, rows)
+
+	case "install":
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: conv3n blocks install <git-url|npm-package> [--checksum sha256:...] [--force]")
+		}
+		pkg, err := b.InstallBlockPackage(ctx, api.InstallBlockPackageRequest{Source: rest[0], Checksum: *checksum, Force: *force})
+		if err != nil {
+			return err
+		}
+		if opts.Format == "json" {
+			return opts.render(os.Stdout, pkg, nil, nil)
+		}
+		fmt.Printf("Installed %s %s (%s)\n", pkg.Namespace, orDash(pkg.Version), pkg.Checksum)
+		for _, t := range pkg.Blocks {
+			fmt.Printf("  %s\n", t)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown blocks subcommand %q (want list, packages or install)", sub)
+	}
+}
+
+// --- migrate ---
+
+func cmdMigrate(args []string) error {
+	fs, opts := newFlagSet("migrate")
+	if _, err := parseFlags(fs, opts, args); err != nil {
+		return err
+	}
+
+	// Opening the database applies the schema idempotently
+	store, err := opts.openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	fmt.Printf("Database %s is up to date\n", opts.DBPath)
+	return nil
+}
+
+// splitSubcommand returns the first positional argument as a subcommand name
+// (or def when there is none) and the remaining arguments.
+func splitSubcommand(positional []string, def string) (string, []string) {
+	if len(positional) == 0 {
+		return def, nil
+	}
+	return positional[0], positional[1:]
+}
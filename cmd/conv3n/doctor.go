@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// runtimeReport is what doctor found out about one script runtime.
+type runtimeReport struct {
+	Name     string `json:"name"`
+	Path     string `json:"path,omitempty"`
+	Version  string `json:"version,omitempty"`
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+	Selected bool   `json:"selected"`
+	// Unsupported lists the standard blocks that need Bun, for other runtimes
+	Unsupported []string `json:"unsupported_blocks,omitempty"`
+}
+
+// cmdDoctor implements `conv3n doctor`: it looks for every supported runtime,
+// marks the one --runtime selects (for auto, the first usable one) and fails
+// when that one can't run scripts. Runtimes other than Bun are reported with
+// the standard blocks they can't run.
+func cmdDoctor(args []string) error {
+	fs, opts := newFlagSet("doctor")
+	if _, err := parseFlags(fs, opts, args); err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	reports := make([]runtimeReport, 0, len(engine.RuntimeNames))
+	selected := -1
+	for _, name := range engine.RuntimeNames {
+		rt, _ := engine.RuntimeByName(ctx, name)
+		report := runtimeReport{Name: name}
+		if rt.Name() != "bun" {
+			for nodeType := range engine.BunOnlyBlocks {
+				report.Unsupported = append(report.Unsupported, string(nodeType))
+			}
+			sort.Strings(report.Unsupported)
+		}
+		report.Path, _ = exec.LookPath(rt.Executable())
+		version, err := rt.Check(ctx)
+		report.Version = version
+		if err != nil {
+			report.Error = err.Error()
+		} else {
+			report.OK = true
+		}
+		if selected < 0 && (name == opts.Runtime || (opts.Runtime == "auto" && report.OK)) {
+			selected = len(reports)
+			report.Selected = true
+		}
+		reports = append(reports, report)
+	}
+
+	rows := make([][]string, len(reports))
+	for i, r := range reports {
+		mark, status := "", "ok"
+		if r.Selected {
+			mark = "*"
+		}
+		switch {
+		case !r.OK:
+			status = r.Error
+		case len(r.Unsupported) > 0:
+			status = "ok, except " + strings.Join(r.Unsupported, ", ") + " (need bun)"
+		}
+		rows[i] = []string{mark, r.Name, orDash(r.Path), orDash(r.Version), status}
+	}
+	if err := opts.render(os.Stdout, reports, []string{"", "RUNTIME", "PATH", "VERSION", "STATUS"}, rows); err != nil {
+		return err
+	}
+
+	switch {
+	case selected < 0 && opts.Runtime == "auto":
+		return fmt.Errorf("no supported runtime is installed")
+	case selected < 0:
+		_, err := engine.RuntimeByName(ctx, opts.Runtime)
+		return err
+	case !reports[selected].OK:
+		return fmt.Errorf("runtime %s is not usable: %s", reports[selected].Name, reports[selected].Error)
+	}
+	return nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
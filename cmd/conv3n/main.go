@@ -3,16 +3,28 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/conv3n/conv3n/internal/api"
 	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/eventsink"
+	"github.com/conv3n/conv3n/internal/grpcapi"
 	"github.com/conv3n/conv3n/internal/storage"
+	"github.com/conv3n/conv3n/internal/telemetry"
+	"github.com/conv3n/conv3n/web"
 )
 
 // Server holds the server configuration
@@ -20,6 +32,8 @@ type Server struct {
 	BlocksDir string
 	Store     storage.Storage
 	Registry  *engine.ExecutionRegistry
+	Events    *engine.EventBus
+	Quotas    *engine.Quotas
 }
 
 func main() {
@@ -28,203 +42,703 @@ func main() {
 		os.Exit(1)
 	}
 
-	command := os.Args[1]
+	cmd, ok := findCommand(os.Args[1])
+	if !ok {
+		printUsage()
+		os.Exit(1)
+	}
 
-	// Determine blocks directory
-	blocksDir := os.Getenv("CONV3N_BLOCKS_DIR")
-	if blocksDir == "" {
-		cwd, _ := os.Getwd()
-		blocksDir = filepath.Join(cwd, "pkg", "blocks")
+	if err := cmd.run(os.Args[2:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCode(err))
 	}
+}
 
-	// Initialize Storage
-	store, err := storage.NewSQLite("conv3n.db")
-	if err != nil {
-		log.Fatalf("Failed to initialize storage: %v", err)
+// applyResourceLimits applies per-process limits for Bun blocks from the environment.
+func applyResourceLimits() {
+	engine.SetResourceLimits(engine.ResourceLimits{
+		MaxMemoryMB:  envInt("CONV3N_BUN_MAX_MEMORY_MB", 0),
+		MaxWallTime:  envDuration("CONV3N_BUN_MAX_WALL_TIME", 0),
+		MaxProcesses: envInt("CONV3N_BUN_MAX_PROCESSES", 0),
+	})
+}
+
+// applySecretProviders registers the secret providers configured in the
+// environment, for {{ $credentials.vault:... }} and {{ $credentials.aws:... }}:
+// Vault with CONV3N_VAULT_ADDR and CONV3N_VAULT_TOKEN (or VAULT_ADDR,
+// VAULT_TOKEN and VAULT_NAMESPACE), AWS Secrets Manager with
+// CONV3N_AWS_SECRETS_REGION (or AWS_REGION) and the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN credentials, reached at
+// CONV3N_AWS_SECRETS_ENDPOINT if set.
+func applySecretProviders() {
+	if addr := envFirst("CONV3N_VAULT_ADDR", "VAULT_ADDR"); addr != "" {
+		engine.RegisterSecretProvider("vault", &engine.VaultSecrets{
+			Addr:      addr,
+			Token:     envFirst("CONV3N_VAULT_TOKEN", "VAULT_TOKEN"),
+			Namespace: os.Getenv("VAULT_NAMESPACE"),
+		})
 	}
-	defer store.Close()
+	if region := envFirst("CONV3N_AWS_SECRETS_REGION", "AWS_REGION"); region != "" && os.Getenv("AWS_ACCESS_KEY_ID") != "" {
+		engine.RegisterSecretProvider("aws", &engine.AWSSecrets{
+			Region:          region,
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			Endpoint:        os.Getenv("CONV3N_AWS_SECRETS_ENDPOINT"),
+		})
+	}
+}
 
-	switch command {
-	case "server":
-		runServer(blocksDir, store)
-	case "run":
-		if len(os.Args) < 3 {
-			fmt.Println("Usage: conv3n run <workflow_file.json>")
-			os.Exit(1)
+// envFirst returns the first of keys set in the environment, empty if none is.
+func envFirst(keys ...string) string {
+	for _, key := range keys {
+		if v := os.Getenv(key); v != "" {
+			return v
 		}
-		runCLI(os.Args[2], blocksDir, store)
-	default:
-		printUsage()
-		os.Exit(1)
 	}
+	return ""
 }
 
-func printUsage() {
-	fmt.Println("Usage:")
-	fmt.Println("  conv3n server               Start the API server")
-	fmt.Println("  conv3n run <workflow.json>  Run a workflow file once (CLI mode)")
+// applyRuntime selects the runtime that runs blocks and TS triggers.
+func applyRuntime(name string) error {
+	rt, err := engine.RuntimeByName(context.Background(), name)
+	if err != nil {
+		return fmt.Errorf("invalid --runtime: %w", err)
+	}
+	engine.SetRuntime(rt)
+	return nil
+}
+
+// envInt reads an integer environment variable, falling back to def when unset or invalid.
+func envInt(key string, def int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using %d", key, raw, def)
+		return def
+	}
+	return v
+}
+
+// envDuration reads a duration environment variable (e.g. "90s"), falling back to def.
+func envDuration(key string, def time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	v, err := time.ParseDuration(raw)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using %s", key, raw, def)
+		return def
+	}
+	return v
+}
+
+// envList reads a comma-separated environment variable, nil when unset.
+func envList(key string) []string {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return nil
+	}
+	var list []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
 }
 
 // --- Server Mode ---
 
-func runServer(blocksDir string, store storage.Storage) {
+func cmdServe(args []string) error {
+	fs, opts := newFlagSet("serve")
+	fs.StringVar(&opts.Addr, "addr", ":8080", "listen address")
+	fs.StringVar(&opts.GRPCAddr, "grpc-addr", os.Getenv("CONV3N_GRPC_ADDR"), "gRPC listen address (gRPC API disabled when empty)")
+	fs.StringVar(&opts.UIDir, "ui-dir", os.Getenv("CONV3N_UI_DIR"), "serve the editor UI from this build directory (default: the embedded build, if any)")
+	fs.StringVar(&opts.WorkflowsDir, "workflows-dir", os.Getenv("CONV3N_WORKFLOWS_DIR"), "sync workflows from the JSON/YAML files of this directory, e.g. a git checkout; they are read-only through the API")
+	if _, err := parseFlags(fs, opts, args); err != nil {
+		return err
+	}
+
+	applyResourceLimits()
+	applySecretProviders()
+	if err := applyRuntime(opts.Runtime); err != nil {
+		return err
+	}
+
+	store, err := opts.openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	return runServer(opts.Addr, opts.GRPCAddr, opts.BlocksDir, opts.UIDir, opts.WorkflowsDir, store)
+}
+
+func runServer(addr, grpcAddr, blocksDir, uiDir, workflowsDir string, store storage.Storage) error {
 	fmt.Println("Starting Conv3n API Server...")
 
+	// Export traces when an OTLP endpoint is configured
+	if cfg, ok := telemetry.ConfigFromEnv(); ok {
+		exporter, err := telemetry.NewExporter(context.Background(), cfg)
+		if err != nil {
+			return fmt.Errorf("failed to set up trace export: %w", err)
+		}
+		tracer := telemetry.NewTracer(exporter, cfg.ServiceName)
+		telemetry.SetTracer(tracer)
+		defer tracer.Shutdown(context.Background())
+		fmt.Printf("Exporting traces to %s\n", cfg.Endpoint)
+	}
+
 	// Initialize execution registry for lifecycle management
 	registry := engine.NewExecutionRegistry()
 
 	// Initialize worker pool (limit to 20 concurrent workflows)
 	workerPool := engine.NewWorkerPool(20)
 
+	// Initialize event bus shared by all runners and subscribers
+	events := engine.NewEventBus()
+
+	// Publish execution events to Kafka or NATS when CONV3N_EVENT_SINK is set
+	if cfg, ok := eventsink.ConfigFromEnv(); ok {
+		sink, err := eventsink.Start(cfg, events)
+		if err != nil {
+			return fmt.Errorf("failed to start event sink: %w", err)
+		}
+		defer sink.Close()
+		fmt.Printf("Publishing events to %s\n", cfg)
+	}
+
+	// Publish daily or weekly workflow health reports when CONV3N_REPORTS is set
+	if period := os.Getenv("CONV3N_REPORTS"); period != "" {
+		reports, err := engine.NewReportScheduler(period, store, events)
+		if err != nil {
+			return fmt.Errorf("invalid CONV3N_REPORTS: %w", err)
+		}
+		reports.Start()
+		defer reports.Stop()
+		fmt.Printf("Publishing %s workflow reports\n", period)
+	}
+
+	// Resume executions parked in long delay and enqueue nodes, including those waiting before a restart
+	delays := engine.NewDelayScheduler(store, blocksDir, registry, workerPool)
+	delays.SetEventBus(events)
+	delays.Start()
+	defer delays.Stop()
+
+	// Fail executions left running by a crashed or killed worker
+	reaper := engine.NewExecutionReaper(store, registry)
+	reaper.Timeout = envDuration("CONV3N_REAPER_TIMEOUT", reaper.Timeout)
+	reaper.SetEventBus(events)
+	reaper.Start()
+	defer reaper.Stop()
+
+	// Keep the daily workflow stats served by /api/stats up to date
+	statsRollup := engine.NewStatsRollup(store)
+	statsRollup.PollInterval = envDuration("CONV3N_STATS_INTERVAL", statsRollup.PollInterval)
+	statsRollup.Start()
+	defer statsRollup.Stop()
+
+	// Sync the workflows defined as files, before the triggers running them are loaded
+	if workflowsDir != "" {
+		workflowSync := engine.NewWorkflowSync(workflowsDir, store)
+		workflowSync.PollInterval = envDuration("CONV3N_WORKFLOWS_SYNC_INTERVAL", workflowSync.PollInterval)
+		workflowSync.ConfigSchema = engine.NewBunRunner(blocksDir).ConfigSchema
+		workflowSync.Start()
+		defer workflowSync.Stop()
+		fmt.Printf("Syncing workflows from %s\n", workflowsDir)
+	}
+
+	// Limit the executions each workflow starts and the history it stores, so
+	// one runaway automation can't take over the server; admins set per-workflow quotas
+	quotas := engine.NewQuotas(store, engine.Quota{
+		ExecutionsPerDay: envInt("CONV3N_QUOTA_EXECUTIONS_PER_DAY", 0),
+		StorageBytes:     int64(envInt("CONV3N_QUOTA_STORAGE_BYTES", 0)),
+	})
+
 	// Initialize trigger manager
 	triggerManager := engine.NewTriggerManager(store, blocksDir, registry, workerPool)
+	triggerManager.SetEventBus(events)
+	triggerManager.SetDelayScheduler(delays)
+	triggerManager.SetQuotas(quotas)
 
 	// Load existing triggers from storage
 	if err := triggerManager.LoadTriggers(context.Background()); err != nil {
 		log.Printf("Warning: failed to load triggers: %v", err)
 	}
 	defer triggerManager.StopAll()
+	// Run the fires queued while a previous server was in maintenance
+	go func() {
+		if _, err := triggerManager.RunQueuedFires(context.Background()); err != nil {
+			log.Printf("Warning: failed to run queued trigger fires: %v", err)
+		}
+	}()
 
 	server := &Server{
 		BlocksDir: blocksDir,
 		Store:     store,
 		Registry:  registry,
+		Events:    events,
+		Quotas:    quotas,
 	}
 
 	mux := http.NewServeMux()
+	// Compress the responses of the routes returning large payloads (lists,
+	// execution state and logs) for clients that accept it
+	compress := func(h http.HandlerFunc) http.Handler { return api.Compress(h) }
 
 	// Execution API
 	mux.HandleFunc("POST /api/run", server.handleRun)
 
 	// Workflow CRUD API
 	wfHandler := api.NewWorkflowHandler(store)
+	// Node configs are checked against the schemas of the installed blocks too
+	wfHandler.ConfigSchema = engine.NewBunRunner(blocksDir).ConfigSchema
 	mux.HandleFunc("POST /api/workflows", wfHandler.Create)
 	mux.HandleFunc("GET /api/workflows/{id}", wfHandler.Get)
 	mux.HandleFunc("PUT /api/workflows/{id}", wfHandler.Update)
 	mux.HandleFunc("DELETE /api/workflows/{id}", wfHandler.Delete)
-	mux.HandleFunc("GET /api/workflows", wfHandler.List)
+	mux.Handle("GET /api/workflows", compress(wfHandler.List))
+	mux.HandleFunc("GET /api/workflows/{id}/static", wfHandler.GetStaticData)
+	mux.HandleFunc("PUT /api/workflows/{id}/static", wfHandler.UpdateStaticData)
+	// Canary deployments: a new version runs for a share of trigger fires until promoted
+	mux.HandleFunc("GET /api/workflows/{id}/canary", wfHandler.GetCanary)
+	mux.HandleFunc("PUT /api/workflows/{id}/canary", wfHandler.SetCanary)
+	mux.HandleFunc("DELETE /api/workflows/{id}/canary", wfHandler.DeleteCanary)
+	mux.HandleFunc("POST /api/workflows/{id}/canary/promote", wfHandler.PromoteCanary)
+	// Snapshot tests: re-run a workflow against a blessed execution's node outputs;
+	// coverage reports the branches no test run went down
+	snapshotHandler := api.NewSnapshotHandler(store, blocksDir)
+	mux.HandleFunc("GET /api/workflows/{id}/snapshot", snapshotHandler.Get)
+	mux.HandleFunc("PUT /api/workflows/{id}/snapshot", snapshotHandler.Set)
+	mux.HandleFunc("DELETE /api/workflows/{id}/snapshot", snapshotHandler.Delete)
+	mux.HandleFunc("POST /api/workflows/{id}/snapshot/test", snapshotHandler.Test)
+	mux.HandleFunc("GET /api/workflows/{id}/coverage", snapshotHandler.Coverage)
+
+	// Quota API; setting quotas takes the admin key (X-Admin-Key) when CONV3N_ADMIN_KEY is set
+	quotaHandler := api.NewQuotaHandler(store, quotas)
+	adminKey := os.Getenv("CONV3N_ADMIN_KEY")
+	mux.HandleFunc("GET /api/workflows/{id}/quota", quotaHandler.Get)
+	mux.Handle("PUT /api/workflows/{id}/quota", api.RequireAdminKey(adminKey, http.HandlerFunc(quotaHandler.Set)))
+	mux.Handle("DELETE /api/workflows/{id}/quota", api.RequireAdminKey(adminKey, http.HandlerFunc(quotaHandler.Delete)))
+
+	// Maintenance mode (stops triggers and drains executions for deploys; admin key required)
+	maintenanceHandler := api.NewMaintenanceHandler(triggerManager)
+	mux.Handle("GET /api/admin/maintenance", api.RequireAdminKey(adminKey, http.HandlerFunc(maintenanceHandler.Get)))
+	mux.Handle("PUT /api/admin/maintenance", api.RequireAdminKey(adminKey, http.HandlerFunc(maintenanceHandler.Set)))
+
+	// Data purges (erasure of a data subject's stored data, with an audit trail
+	// keeping an HMAC of the subject); only served when an admin key and the
+	// hash key are set, as they delete data of every workflow
+	if hashKey := os.Getenv("CONV3N_HASH_KEY"); adminKey != "" && hashKey != "" {
+		purgeHandler := api.NewPurgeHandler(store, []byte(hashKey))
+		mux.Handle("POST /api/admin/purge", api.RequireAdminKey(adminKey, http.HandlerFunc(purgeHandler.Purge)))
+		mux.Handle("GET /api/admin/purges", api.RequireAdminKey(adminKey, http.HandlerFunc(purgeHandler.List)))
+	} else {
+		fmt.Println("Data purge API disabled: it requires CONV3N_ADMIN_KEY and CONV3N_HASH_KEY")
+	}
+
+	// Global variables and environments API
+	globalsHandler := api.NewGlobalsHandler(store)
+	mux.HandleFunc("GET /api/environments", globalsHandler.ListEnvironments)
+	mux.HandleFunc("POST /api/environments", globalsHandler.CreateEnvironment)
+	mux.HandleFunc("DELETE /api/environments/{name}", globalsHandler.DeleteEnvironment)
+	mux.HandleFunc("GET /api/globals", globalsHandler.ListGlobals)
+	mux.HandleFunc("PUT /api/globals/{name}", globalsHandler.SetGlobal)
+	mux.HandleFunc("DELETE /api/globals/{name}", globalsHandler.DeleteGlobal)
 
 	// Trigger API
 	triggerHandler := api.NewTriggerHandler(store, triggerManager)
+	triggerHandler.WebhookSecret = []byte(os.Getenv("CONV3N_WEBHOOK_SECRET"))
 	mux.HandleFunc("POST /api/triggers", triggerHandler.Create)
 	mux.HandleFunc("GET /api/triggers/{id}", triggerHandler.Get)
 	mux.HandleFunc("PUT /api/triggers/{id}", triggerHandler.Update)
 	mux.HandleFunc("DELETE /api/triggers/{id}", triggerHandler.Delete)
-	mux.HandleFunc("GET /api/triggers", triggerHandler.List)
-	mux.HandleFunc("GET /api/triggers", triggerHandler.List)
-	mux.HandleFunc("GET /api/triggers/{id}/executions", triggerHandler.ListExecutions)
-	mux.HandleFunc("POST /api/webhooks/{id}", triggerHandler.HandleWebhook)
+	mux.Handle("GET /api/triggers", compress(triggerHandler.List))
+	mux.Handle("GET /api/triggers/{id}/executions", compress(triggerHandler.ListExecutions))
+	mux.HandleFunc("GET /api/triggers/{id}/next-runs", triggerHandler.NextRuns)
+	mux.HandleFunc("GET /api/triggers/{id}/stats", triggerHandler.Stats)
+	mux.HandleFunc("POST /api/triggers/{id}/fire", triggerHandler.Fire)
+	mux.HandleFunc("POST /api/triggers/{id}/webhook-urls", triggerHandler.CreateWebhookURL)
+	mux.HandleFunc("POST /api/triggers/{id}/webhook-urls/rotate", triggerHandler.RotateWebhookURLs)
+	// Webhook triggers are routed by the trigger manager, at their ID and custom path
+	mux.HandleFunc("GET /api/webhooks", triggerHandler.ListWebhookRoutes)
+	mux.HandleFunc("POST /api/webhooks/{path...}", triggerHandler.HandleWebhook)
+	mux.HandleFunc("POST /api/webhooks-test/{id}", triggerHandler.HandleTestWebhook)
+	mux.HandleFunc("GET /api/ws/{id}", triggerHandler.HandleWebSocket)
+
+	// Template gallery API (starter workflows created with their triggers)
+	templateHandler := api.NewTemplateHandler(store, triggerHandler)
+	templateHandler.ConfigSchema = wfHandler.ConfigSchema
+	mux.HandleFunc("GET /api/templates", templateHandler.List)
+	mux.HandleFunc("GET /api/templates/{id}", templateHandler.Get)
+	mux.HandleFunc("POST /api/templates/{id}/instantiate", templateHandler.Instantiate)
+
+	// Block registry and package API
+	blocksHandler := api.NewBlocksHandler(blocksDir)
+	mux.HandleFunc("GET /api/blocks", blocksHandler.List)
+	mux.HandleFunc("GET /api/blocks/packages", blocksHandler.ListPackages)
+	mux.HandleFunc("POST /api/blocks/install", blocksHandler.Install)
+
+	// Validation API
+	validateHandler := api.NewValidateHandler()
+	mux.HandleFunc("POST /api/validate/cron", validateHandler.Cron)
 
 	// Execution history API
 	execHandler := api.NewExecutionHandler(store)
-	mux.HandleFunc("GET /api/workflows/{id}/executions", execHandler.ListByWorkflow)
-	mux.HandleFunc("GET /api/executions/{id}", execHandler.Get)
-	mux.HandleFunc("GET /api/executions/{id}/nodes/{nodeId}", execHandler.GetNodeResult)
-
-	// Lifecycle API (stop, restart)
+	mux.Handle("GET /api/workflows/{id}/executions", compress(execHandler.ListByWorkflow))
+	mux.Handle("GET /api/workflows/{id}/executions/diff", compress(execHandler.Diff))
+	mux.Handle("GET /api/executions", compress(execHandler.Search))
+	mux.Handle("GET /api/executions/search", compress(execHandler.SearchContent))
+	mux.Handle("GET /api/executions/{id}", compress(execHandler.Get))
+	mux.Handle("GET /api/executions/{id}/nodes/{nodeId}", compress(execHandler.GetNodeResult))
+	mux.Handle("GET /api/executions/{id}/logs", compress(execHandler.Logs))
+
+	// Stats API (daily runs, failures, run time and resource usage per workflow)
+	statsHandler := api.NewStatsHandler(store)
+	mux.HandleFunc("GET /api/stats", statsHandler.Get)
+
+	// Lifecycle API (stop, restart, running executions)
 	lifecycleHandler := api.NewLifecycleHandler(store, registry, blocksDir)
+	lifecycleHandler.Events = events
+	lifecycleHandler.Quotas = quotas
 	mux.HandleFunc("POST /api/executions/{id}/stop", lifecycleHandler.StopExecution)
 	mux.HandleFunc("POST /api/executions/{id}/restart", lifecycleHandler.RestartExecution)
 	mux.HandleFunc("POST /api/executions/batch/stop", lifecycleHandler.BatchStopExecutions)
+	mux.Handle("GET /api/executions/active", compress(lifecycleHandler.ListActive))
+
+	// Signal API (resumes executions waiting in std/enqueue nodes)
+	signalHandler := api.NewSignalHandler(delays)
+	mux.HandleFunc("POST /api/signals/{name}", signalHandler.Send)
+
+	// Liveness and readiness probes
+	healthHandler := api.NewHealthHandler(store, blocksDir, triggerManager)
+	mux.HandleFunc("GET /livez", healthHandler.Livez)
+	mux.HandleFunc("GET /readyz", healthHandler.Readyz)
 
 	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
-		enableCors(w)
 		// Return worker pool stats
 		stats := workerPool.Stats()
+		limits, limiter := engine.CurrentResourceLimits()
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"status":  "OK",
 			"workers": stats,
+			"bun_processes": map[string]int{
+				"active": limiter.InUse(),
+				"max":    limits.MaxProcesses,
+			},
 		})
 	})
 
-	fmt.Printf("Listening on http://localhost:8080\n")
+	// Host the editor UI at /, from --ui-dir or else the embedded build
+	ui, err := uiFS(uiDir)
+	if err != nil {
+		return err
+	}
+	if ui != nil {
+		mux.Handle("GET /", api.UIHandler(ui))
+		fmt.Println("Serving the editor UI at /")
+	}
+
+	fmt.Printf("Listening on %s\n", addr)
 	fmt.Printf("Blocks loaded from: %s\n", blocksDir)
 
-	if err := http.ListenAndServe(":8080", mux); err != nil {
-		log.Fatal(err)
+	cors, err := corsConfig()
+	if err != nil {
+		return err
+	}
+
+	// Require an API key on /api/ routes when one is configured
+	apiKey := os.Getenv("CONV3N_API_KEY")
+	if apiKey != "" {
+		fmt.Println("API key authentication enabled")
+	}
+
+	// Serve the gRPC API on its own port when configured
+	if grpcAddr != "" {
+		lis, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", grpcAddr, err)
+		}
+		grpcService := grpcapi.NewServer(store, blocksDir, registry, events, delays)
+		grpcService.Quotas = quotas
+		grpcServer := grpcapi.NewGRPCServer(grpcService, apiKey)
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Printf("gRPC server stopped: %v", err)
+			}
+		}()
+		defer grpcServer.Stop()
+		fmt.Printf("gRPC API listening on %s\n", grpcAddr)
+	}
+
+	handler := api.LimitBodies(bodyLimits(), mux)
+	handler = api.AccessLog(slog.Default(), api.CORS(cors, api.RequireAPIKey(apiKey, handler)))
+	handler = api.RequestID(telemetry.Middleware(handler))
+	return newHTTPServer(addr, handler).ListenAndServe()
+}
+
+// uiFS returns the editor UI build to host: the directory dir when set, else
+// the build embedded with the embedui tag, nil if neither.
+func uiFS(dir string) (fs.FS, error) {
+	if dir == "" {
+		return web.FS(), nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, "index.html")); err != nil {
+		return nil, fmt.Errorf("invalid --ui-dir: %w", err)
+	}
+	return os.DirFS(dir), nil
+}
+
+// corsConfig reads the CORS settings of the API. CONV3N_CORS_ORIGINS,
+// CONV3N_CORS_METHODS and CONV3N_CORS_HEADERS are comma-separated lists,
+// CONV3N_CORS_CREDENTIALS=true allows credentials and CONV3N_CORS_MAX_AGE sets
+// how long preflights are cached. Unset, any origin may call the API, but
+// credentials require CONV3N_CORS_ORIGINS to list the origins allowed.
+func corsConfig() (api.CORSConfig, error) {
+	cfg := api.DefaultCORSConfig()
+	if origins := envList("CONV3N_CORS_ORIGINS"); origins != nil {
+		cfg.AllowedOrigins = origins
+	}
+	if methods := envList("CONV3N_CORS_METHODS"); methods != nil {
+		cfg.AllowedMethods = methods
+	}
+	if headers := envList("CONV3N_CORS_HEADERS"); headers != nil {
+		cfg.AllowedHeaders = headers
+	}
+	cfg.AllowCredentials = os.Getenv("CONV3N_CORS_CREDENTIALS") == "true"
+	cfg.MaxAge = envDuration("CONV3N_CORS_MAX_AGE", cfg.MaxAge)
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid CORS settings: %w", err)
+	}
+	return cfg, nil
+}
+
+// newHTTPServer creates the API server. Its timeouts keep slow or idle clients
+// from holding connections; the streaming routes (test webhooks, websockets)
+// and synchronous runs lift the write deadline for their own requests.
+func newHTTPServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: envDuration("CONV3N_HTTP_READ_HEADER_TIMEOUT", 10*time.Second),
+		ReadTimeout:       envDuration("CONV3N_HTTP_READ_TIMEOUT", time.Minute),
+		WriteTimeout:      envDuration("CONV3N_HTTP_WRITE_TIMEOUT", 2*time.Minute),
+		IdleTimeout:       envDuration("CONV3N_HTTP_IDLE_TIMEOUT", 2*time.Minute),
+		MaxHeaderBytes:    envInt("CONV3N_HTTP_MAX_HEADER_BYTES", 64<<10),
+	}
+}
+
+// bodyLimits caps request bodies at CONV3N_MAX_BODY_BYTES (1 MiB by default).
+// Workflow definitions and runs may carry more; webhooks enforce the
+// max_body_size of their trigger instead.
+func bodyLimits() api.BodyLimits {
+	def := int64(envInt("CONV3N_MAX_BODY_BYTES", 1<<20))
+	return api.BodyLimits{
+		Default: def,
+		Prefixes: map[string]int64{
+			"/api/workflows":      max(def, 10<<20),
+			"/api/run":            max(def, 10<<20),
+			"/api/webhooks/":      0,
+			"/api/webhooks-test/": 0,
+		},
 	}
 }
 
 type RunRequest struct {
 	Workflow engine.Workflow `json:"workflow"`
+	// Environment selects the $globals overrides to run with (e.g. "staging")
+	Environment string `json:"environment,omitempty"`
+	// CorrelationID and Labels tag the execution, on top of the
+	// X-Correlation-ID and X-Execution-Labels headers
+	CorrelationID string            `json:"correlation_id,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	// Mocks run the workflow in mock mode, as a test run (see engine.Mocks)
+	Mocks *engine.Mocks `json:"mocks,omitempty"`
 }
 
 func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
-	enableCors(w)
-	if r.Method == "OPTIONS" {
-		return
-	}
-
 	var req RunRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Bad JSON: "+err.Error(), 400)
+		api.WriteError(w, http.StatusBadRequest, "Bad JSON: "+err.Error())
+		return
+	}
+	// Labels of the body add to those of the headers
+	headerLabels, err := api.LabelsFromHeaders(r.Header)
+	if err != nil {
+		api.WriteError(w, http.StatusUnprocessableEntity, "Invalid labels: "+err.Error())
+		return
+	}
+	runCtx := engine.WithLabels(engine.WithLabels(r.Context(), headerLabels), engine.ExecutionLabels{CorrelationID: req.CorrelationID, Labels: req.Labels})
+	if err := engine.LabelsFromContext(runCtx).Validate(); err != nil {
+		api.WriteError(w, http.StatusUnprocessableEntity, "Invalid labels: "+err.Error())
 		return
 	}
 
+	if req.Mocks != nil {
+		if err := req.Mocks.Validate(); err != nil {
+			api.WriteError(w, http.StatusUnprocessableEntity, "Invalid mocks: "+err.Error())
+			return
+		}
+	}
+
 	ctx := engine.NewExecutionContext(req.Workflow.ID)
+	ctx.Environment = req.Environment
+	ctx.Mocks = req.Mocks
+	ctx.Test = req.Mocks != nil
 	runner := engine.NewWorkflowRunner(ctx, s.BlocksDir, s.Store, s.Registry)
+	runner.SetEventBus(s.Events)
+	runner.SetQuotas(s.Quotas)
 
 	fmt.Printf("New Job: %s\n", req.Workflow.Name)
 
+	// The response waits for the whole run, which may outlast the write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
 	// Create cancellable context for execution
-	execCtx, cancel := context.WithCancel(r.Context())
+	execCtx, cancel := context.WithCancel(runCtx)
 	defer cancel()
 
-	// Note: We would register execution ID here if we had it before running
-	// For now, the runner creates the execution ID internally
-
 	if err := runner.Run(execCtx, req.Workflow); err != nil {
-		http.Error(w, "Execution Failed: "+err.Error(), 500)
+		if api.WriteQuotaError(w, err) {
+			return
+		}
+		api.WriteError(w, http.StatusInternalServerError, "Execution Failed: "+err.Error())
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status":  "success",
-		"results": ctx.Results,
+		"results": ctx.Results(),
 	})
 }
 
-func enableCors(w http.ResponseWriter) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-}
-
 // --- CLI Mode ---
 
-func runCLI(filePath string, blocksDir string, store storage.Storage) {
-	fmt.Printf("Reading workflow from: %s\n", filePath)
+// runResult is the outcome of a CLI run; it is what `run --output json` prints.
+type runResult struct {
+	WorkflowID  string                 `json:"workflow_id"`
+	ExecutionID string                 `json:"execution_id,omitempty"`
+	Status      string                 `json:"status"`
+	Error       string                 `json:"error,omitempty"`
+	DurationMS  int64                  `json:"duration_ms"`
+	Results     map[string]interface{} `json:"results"`
+	Variables   map[string]interface{} `json:"variables"`
+}
 
-	// Read file
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		log.Fatalf("Failed to read file: %v", err)
+func runCLI(filePath string, blocksDir string, store storage.Storage, input runInput, out runOutput) error {
+	// Text progress goes to stdout only in the default mode; JSON and quiet
+	// output must stay machine-readable, so engine logs are silenced for --quiet.
+	text := out.Format == "text" && !out.Quiet
+	say := func(format string, args ...interface{}) {
+		if text {
+			fmt.Printf(format, args...)
+		}
 	}
+	if out.Quiet {
+		log.SetOutput(io.Discard)
+	}
+
+	say("Reading workflow from: %s\n", filePath)
 
-	var workflow engine.Workflow
-	if err := json.Unmarshal(data, &workflow); err != nil {
-		log.Fatalf("Failed to parse workflow JSON: %v", err)
+	workflow, err := loadWorkflowFile(filePath)
+	if err != nil {
+		return err
 	}
 
-	fmt.Println("Starting conv3n (Bunock) Engine...")
-	fmt.Printf("Using Blocks Directory: %s\n", blocksDir)
+	say("Starting conv3n (Bunock) Engine...\n")
+	say("Using Blocks Directory: %s\n", blocksDir)
 
 	ctx := engine.NewExecutionContext(workflow.ID)
+	ctx.TriggerData = input.TriggerData
+	ctx.Environment = input.Environment
+	ctx.Mocks = input.Mocks
+	ctx.Test = input.Mocks != nil
+	for name, value := range input.Variables {
+		ctx.SetVar(name, value)
+	}
 	// CLI mode doesn't need lifecycle management, pass nil registry
 	runner := engine.NewWorkflowRunner(ctx, blocksDir, store, nil)
 
-	fmt.Printf("Running Workflow: %s\n", workflow.Name)
-
-	execCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// Capture the execution ID and final status from the runner's events
+	result := runResult{WorkflowID: workflow.ID}
+	bus := engine.NewEventBus()
+	unsubscribe := bus.Subscribe(func(ev engine.Event) {
+		finished := ev.(engine.ExecutionFinished)
+		result.ExecutionID = finished.ExecutionID
+		result.Status = finished.Status
+	}, engine.EventExecutionFinished)
+	runner.SetEventBus(bus)
+
+	// The runner applies settings.timeout; --timeout replaces it
+	if _, err := workflow.Timeout(); err != nil {
+		return err
+	}
+	execCtx, cancel := context.WithCancel(context.Background())
+	if timeout := out.Timeout; timeout > 0 {
+		if workflow.Settings != nil {
+			workflow.Settings.Timeout = ""
+		}
+		execCtx, cancel = context.WithTimeoutCause(context.Background(), timeout,
+			fmt.Errorf("run exceeded its %s timeout (--timeout)", timeout))
+	}
 	defer cancel()
 
-	if err := runner.Run(execCtx, workflow); err != nil {
-		log.Fatalf("Workflow execution failed: %v", err)
+	say("Running Workflow: %s\n", workflow.Name)
+
+	started := time.Now()
+	runErr := runner.Run(execCtx, *workflow)
+	unsubscribe()
+
+	result.DurationMS = time.Since(started).Milliseconds()
+	result.Results = ctx.Results()
+	result.Variables = ctx.Variables()
+	if runErr != nil {
+		result.Error = runErr.Error()
+		if result.Status == "" || result.Status == string(storage.ExecutionStatusCompleted) {
+			result.Status = string(storage.ExecutionStatusFailed)
+		}
+	} else if result.Status == "" {
+		result.Status = string(storage.ExecutionStatusCompleted)
+	}
+
+	if out.Format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+	} else if text && runErr == nil {
+		printResults(result.Results)
+	}
+
+	if errors.Is(runErr, context.DeadlineExceeded) {
+		// execCtx has a cause only when --timeout set the deadline; runErr
+		// carries that of the runner's settings.timeout
+		cause := runErr
+		if execCtx.Err() != nil {
+			cause = context.Cause(execCtx)
+		}
+		return &exitCodeError{code: exitTimeout, err: fmt.Errorf("workflow timed out: %w", cause)}
+	}
+	if runErr != nil {
+		return &exitCodeError{code: exitExecutionFailed, err: fmt.Errorf("workflow execution failed: %w", runErr)}
 	}
+	return nil
+}
 
+// printResults pretty-prints node results for the default text output.
+func printResults(results map[string]interface{}) {
 	fmt.Println("\n--- Execution Results ---")
-	for blockID, result := range ctx.Results {
+	for blockID, result := range results {
 		resMap, ok := result.(map[string]interface{})
 		if !ok {
 			fmt.Printf("Block [%s]: %+v\n\n", blockID, result)
@@ -247,7 +761,6 @@ func runCLI(filePath string, blocksDir string, store storage.Storage) {
 			fmt.Printf("Block [%s]: %+v\n\n", blockID, resMap)
 		}
 	}
-
 }
 
 // btw i want t suicide
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// --- new ---
+
+// scaffoldNamePattern restricts each segment of a scaffolded block/trigger name
+// to what is safe as both a file name and part of a node type.
+var scaffoldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// scaffoldManifest describes a generated block or trigger. It is written next
+// to the script as <name>.manifest.json.
+type scaffoldManifest struct {
+	ID          string                 `json:"id"`
+	Kind        string                 `json:"kind"`
+	Version     string                 `json:"version"`
+	Description string                 `json:"description"`
+	Entry       string                 `json:"entry"`
+	Config      map[string]interface{} `json:"config"`
+	Ports       []string               `json:"ports,omitempty"`
+}
+
+// scaffoldData is passed to the file templates.
+type scaffoldData struct {
+	ID        string // e.g. "acme/greet_user"
+	File      string // e.g. "pkg/blocks/acme/greet_user.ts"
+	Name      string // e.g. "greet_user"
+	Title     string // e.g. "Greet User"
+	TypeName  string // e.g. "GreetUser"
+	SDKImport string // relative import of the SDK trigger module (triggers only)
+}
+
+func cmdNew(args []string) error {
+	fs, opts := newFlagSet("new")
+	cwd, _ := os.Getwd()
+	triggersDir := fs.String("triggers-dir", filepath.Join(cwd, "pkg", "triggers"), "triggers directory (new trigger)")
+	sdkDir := fs.String("sdk-dir", filepath.Join(cwd, "sdk"), "SDK directory imported by generated triggers")
+	positional, err := parseFlags(fs, opts, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return fmt.Errorf("usage: conv3n new block|trigger <namespace/name>")
+	}
+
+	ns, name, err := parseScaffoldName(positional[1])
+	if err != nil {
+		return err
+	}
+	data := scaffoldData{
+		ID:       ns + "/" + name,
+		Name:     name,
+		Title:    scaffoldTitle(name),
+		TypeName: strings.ReplaceAll(scaffoldTitle(name), " ", ""),
+	}
+
+	switch positional[0] {
+	case "block":
+		dir := filepath.Join(opts.BlocksDir, ns)
+		manifest := scaffoldManifest{
+			ID:          data.ID,
+			Kind:        "block",
+			Version:     "0.1.0",
+			Description: data.Title + " block",
+			Entry:       name + ".ts",
+			Config:      map[string]interface{}{},
+			Ports:       []string{"default"},
+		}
+		return writeScaffold(dir, data, manifest, blockTemplate, blockTestTemplate)
+
+	case "trigger":
+		dir := filepath.Join(*triggersDir, ns)
+		rel, err := filepath.Rel(dir, filepath.Join(*sdkDir, "src", "trigger"))
+		if err != nil {
+			return fmt.Errorf("failed to locate SDK relative to %s: %w", dir, err)
+		}
+		data.SDKImport = filepath.ToSlash(rel)
+		if !strings.HasPrefix(data.SDKImport, ".") {
+			data.SDKImport = "./" + data.SDKImport
+		}
+		manifest := scaffoldManifest{
+			ID:          data.ID,
+			Kind:        "trigger",
+			Version:     "0.1.0",
+			Description: data.Title + " trigger",
+			Entry:       name + ".ts",
+			Config:      map[string]interface{}{},
+		}
+		return writeScaffold(dir, data, manifest, triggerTemplate, triggerTestTemplate)
+
+	default:
+		return fmt.Errorf("unknown new subcommand %q (want block or trigger)", positional[0])
+	}
+}
+
+// parseScaffoldName splits "namespace/name" (namespace defaults to "custom").
+func parseScaffoldName(s string) (string, string, error) {
+	ns, name, ok := strings.Cut(s, "/")
+	if !ok {
+		ns, name = "custom", s
+	}
+	if !scaffoldNamePattern.MatchString(ns) || !scaffoldNamePattern.MatchString(name) {
+		return "", "", fmt.Errorf("invalid name %q: use namespace/name with lowercase letters, digits and underscores", s)
+	}
+	return ns, name, nil
+}
+
+// scaffoldTitle turns "greet_user" into "Greet User".
+func scaffoldTitle(name string) string {
+	words := strings.Split(name, "_")
+	for i, w := range words {
+		if w != "" {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return strings.Join(strings.Fields(strings.Join(words, " ")), " ")
+}
+
+// writeScaffold renders the script, its test and the manifest into dir.
+// Nothing is written if any of the files already exists.
+func writeScaffold(dir string, data scaffoldData, manifest scaffoldManifest, script, test *template.Template) error {
+	scriptPath := filepath.Join(dir, data.Name+".ts")
+	testPath := filepath.Join(dir, data.Name+".test.ts")
+	manifestPath := filepath.Join(dir, data.Name+".manifest.json")
+
+	for _, p := range []string{scriptPath, testPath, manifestPath} {
+		if _, err := os.Stat(p); err == nil {
+			return fmt.Errorf("%s already exists", displayPath(p))
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	files := []struct {
+		path string
+		tmpl *template.Template
+	}{
+		{scriptPath, script},
+		{testPath, test},
+	}
+	for _, f := range files {
+		data.File = displayPath(f.path)
+		var sb strings.Builder
+		if err := f.tmpl.Execute(&sb, data); err != nil {
+			return fmt.Errorf("failed to render %s: %w", f.path, err)
+		}
+		if err := os.WriteFile(f.path, []byte(sb.String()), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.path, err)
+		}
+		fmt.Printf("created %s\n", displayPath(f.path))
+	}
+	if err := os.WriteFile(manifestPath, append(manifestJSON, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", manifestPath, err)
+	}
+	fmt.Printf("created %s\n", displayPath(manifestPath))
+	return nil
+}
+
+// displayPath shows p relative to the working directory when it is inside it.
+func displayPath(p string) string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return p
+	}
+	if rel, err := filepath.Rel(cwd, p); err == nil && filepath.IsLocal(rel) {
+		return filepath.ToSlash(rel)
+	}
+	return p
+}
+
+var blockTemplate = template.Must(template.New("block").Parse(`// {{.File}}
+// Block: {{.Title}}
+// TODO: describe what this block does.
+
+// Type definitions for input/output
+export interface {{.TypeName}}Config {
+    // TODO: add configuration fields
+}
+
+export interface {{.TypeName}}Input {
+    config: {{.TypeName}}Config;
+    input?: unknown;         // Data from previous blocks
+}
+
+export interface BlockResult {
+    data: unknown;
+    port: string;
+}
+
+// Validate configuration
+export function validateConfig(config: unknown): asserts config is {{.TypeName}}Config {
+    if (!config || typeof config !== "object") {
+        throw new Error("Missing required config");
+    }
+}
+
+// Block logic: returns the data to pass on and the output port to route to
+export async function execute(config: {{.TypeName}}Config, input: unknown): Promise<BlockResult> {
+    return {
+        data: input ?? null,
+        port: "default",
+    };
+}
+
+// Main execution function
+export async function main(): Promise<void> {
+    try {
+        // 1. Read input
+        const { config, input }: {{.TypeName}}Input = await Bun.stdin.json();
+
+        // 2. Validate config
+        validateConfig(config);
+
+        // 3. Execute and write output
+        const output = await execute(config, input);
+        await Bun.write(Bun.stdout, JSON.stringify(output));
+
+    } catch (error) {
+        const message = error instanceof Error ? error.message : String(error);
+        console.error(` + "`{{.Title}} Block Failed: ${message}`" + `);
+        process.exit(1);
+    }
+}
+
+// Only run main if this is the entry point
+if (import.meta.main) {
+    main();
+}
+`))
+
+var blockTestTemplate = template.Must(template.New("block_test").Parse(`// {{.File}}
+// Unit tests for {{.Title}} block
+
+import { describe, test, expect } from "bun:test";
+import { validateConfig, execute } from "./{{.Name}}";
+
+describe("{{.Title}} Block", () => {
+    describe("validateConfig", () => {
+        test("should pass for an empty config object", () => {
+            expect(() => validateConfig({})).not.toThrow();
+        });
+
+        test("should throw error when config is missing", () => {
+            expect(() => validateConfig(null)).toThrow("Missing required config");
+        });
+    });
+
+    describe("execute", () => {
+        test("should pass input through on the default port", async () => {
+            const result = await execute({}, { value: 42 });
+            expect(result).toEqual({ data: { value: 42 }, port: "default" });
+        });
+    });
+});
+`))
+
+var triggerTemplate = template.Must(template.New("trigger").Parse(`/**
+ * {{.Title}} trigger for Conv3n.
+ * TODO: describe what fires this trigger.
+ */
+
+import { createTrigger, runTrigger } from "{{.SDKImport}}";
+
+export interface {{.TypeName}}TriggerConfig {
+  // TODO: add configuration fields
+}
+
+const trigger = createTrigger<{{.TypeName}}TriggerConfig>({
+  id: "{{.ID}}",
+
+  // onStart is called once with the trigger's configuration.
+  // Set up listeners/timers here and call ctx.fire(payload) to start the workflow.
+  async onStart(ctx) {
+    console.error(` + "`{{.ID}} started with config ${JSON.stringify(ctx.config)}`" + `);
+  },
+
+  // onStop is called before the process exits; release resources here.
+  async onStop(ctx) {
+    console.error("{{.ID}} stopped");
+  },
+});
+
+export default trigger;
+
+// Only connect to the orchestrator if this is the entry point
+if (import.meta.main) {
+  runTrigger(trigger);
+}
+`))
+
+var triggerTestTemplate = template.Must(template.New("trigger_test").Parse(`// {{.File}}
+// Unit tests for {{.Title}} trigger
+
+import { describe, test, expect } from "bun:test";
+import trigger from "./{{.Name}}";
+
+describe("{{.Title}} Trigger", () => {
+  test("should have the expected id", () => {
+    expect(trigger.id).toBe("{{.ID}}");
+  });
+
+  test("should start and stop without firing", async () => {
+    const fired: unknown[] = [];
+    const ctx = {
+      config: {},
+      fire: async (payload: unknown) => {
+        fired.push(payload);
+        return undefined as any;
+      },
+    };
+
+    await trigger.onStart(ctx);
+    await trigger.onStop(ctx);
+    expect(fired).toEqual([]);
+  });
+});
+`))
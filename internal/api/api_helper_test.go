@@ -2,6 +2,7 @@ package api_test
 
 import (
 	"context"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -11,10 +12,17 @@ import (
 var testCtx = context.Background()
 
 func newTestStorage(t *testing.T) storage.Storage {
-	tmpDir := t.TempDir()
-	dbPath := filepath.Join(tmpDir, "test.db")
-
-	store, err := storage.NewSQLite(dbPath)
+	// Tests use the in-memory storage; the storage package holds it to the same
+	// behavior as SQLite. CONV3N_TEST_DB runs them against another driver, e.g.
+	// "sqlite://" for a fresh database file per test.
+	dsn := os.Getenv("CONV3N_TEST_DB")
+	switch dsn {
+	case "":
+		dsn = "memory://"
+	case "sqlite://":
+		dsn += filepath.Join(t.TempDir(), "test.db")
+	}
+	store, err := storage.Open(dsn)
 	if err != nil {
 		t.Fatalf("failed to create storage: %v", err)
 	}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// RequireAPIKey protects the /api/ routes with a static API key.
+// Clients send it as "Authorization: Bearer <key>" or "X-API-Key: <key>".
+// Webhooks stay public (external services can't be expected to send the key),
+// as do health probes and CORS preflight requests. WebSocket connections may
+// carry a signed trigger URL token instead, since browsers can't set headers on
+// them; the trigger handler checks it. An empty key disables the check.
+func RequireAPIKey(key string, next http.Handler) http.Handler {
+	if key == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") ||
+			strings.HasPrefix(r.URL.Path, "/api/webhooks/") ||
+			strings.HasPrefix(r.URL.Path, "/api/ws/") && r.URL.Query().Has("token") ||
+			r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		provided := r.Header.Get("X-API-Key")
+		if auth := r.Header.Get("Authorization"); provided == "" && strings.HasPrefix(auth, "Bearer ") {
+			provided = strings.TrimPrefix(auth, "Bearer ")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(key)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAdminKey protects admin routes, such as setting quotas, with a second
+// key sent as "X-Admin-Key: <key>" on top of the API key. An empty key leaves
+// them to every API client.
+func RequireAdminKey(key string, next http.Handler) http.Handler {
+	if key == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Admin-Key")), []byte(key)) != 1 {
+			WriteError(w, http.StatusForbidden, "Admin key required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
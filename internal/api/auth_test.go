@@ -0,0 +1,57 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/api"
+)
+
+func TestRequireAPIKey(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := api.RequireAPIKey("secret", ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header map[string]string
+		want   int
+	}{
+		{"MissingKey", http.MethodGet, "/api/workflows", nil, http.StatusUnauthorized},
+		{"WrongKey", http.MethodGet, "/api/workflows", map[string]string{"X-API-Key": "nope"}, http.StatusUnauthorized},
+		{"HeaderKey", http.MethodGet, "/api/workflows", map[string]string{"X-API-Key": "secret"}, http.StatusOK},
+		{"BearerKey", http.MethodGet, "/api/workflows", map[string]string{"Authorization": "Bearer secret"}, http.StatusOK},
+		{"WebhookIsPublic", http.MethodPost, "/api/webhooks/tr-1", nil, http.StatusOK},
+		{"WebSocketWithToken", http.MethodGet, "/api/ws/tr-1?token=abc", nil, http.StatusOK},
+		{"WebSocketWithoutToken", http.MethodGet, "/api/ws/tr-1", nil, http.StatusUnauthorized},
+		{"TokenOnlyForWebSockets", http.MethodGet, "/api/workflows?token=abc", nil, http.StatusUnauthorized},
+		{"HealthIsPublic", http.MethodGet, "/readyz", nil, http.StatusOK},
+		{"PreflightIsPublic", http.MethodOptions, "/api/run", nil, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+
+	t.Run("EmptyKeyDisablesAuth", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		api.RequireAPIKey("", ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/workflows", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// BlocksHandler lists the node types provided by block scripts and installs
+// block packages into the blocks directory.
+type BlocksHandler struct {
+	Registry *engine.BlockRegistry
+}
+
+// NewBlocksHandler creates a new blocks handler
+func NewBlocksHandler(blocksDir string) *BlocksHandler {
+	return &BlocksHandler{Registry: engine.BlockRegistryFor(blocksDir)}
+}
+
+// InstallBlockPackageRequest represents the request body for installing a block package
+type InstallBlockPackageRequest struct {
+	Source   string `json:"source"`             // Git URL or npm package
+	Checksum string `json:"checksum,omitempty"` // Expected "sha256:<hex>" of the package contents
+	Force    bool   `json:"force,omitempty"`    // Replace an installed package with the same namespace
+}
+
+// List handles GET /api/blocks
+func (h *BlocksHandler) List(w http.ResponseWriter, r *http.Request) {
+	blocks, err := h.Registry.Types()
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to list blocks: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(blocks)
+}
+
+// ListPackages handles GET /api/blocks/packages
+func (h *BlocksHandler) ListPackages(w http.ResponseWriter, r *http.Request) {
+	packages, err := h.Registry.Packages()
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to list block packages: "+err.Error())
+		return
+	}
+	if packages == nil {
+		packages = []engine.BlockPackage{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(packages)
+}
+
+// Install handles POST /api/blocks/install
+func (h *BlocksHandler) Install(w http.ResponseWriter, r *http.Request) {
+	var req InstallBlockPackageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+	if req.Source == "" {
+		WriteError(w, http.StatusUnprocessableEntity, "source is required")
+		return
+	}
+
+	pkg, err := h.Registry.Install(r.Context(), req.Source, engine.BlockInstallOptions{Checksum: req.Checksum, Force: req.Force})
+	if errors.Is(err, engine.ErrBlockPackageExists) {
+		WriteError(w, http.StatusConflict, err.Error()+" (set force to replace it)")
+		return
+	}
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "Failed to install block package: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(pkg)
+}
@@ -0,0 +1,85 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/api"
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+func TestBlocksAPI(t *testing.T) {
+	// A git repository holding one block package
+	repo := t.TempDir()
+	for name, content := range map[string]string{
+		"greet.ts":            "",
+		"greet.manifest.json": `{"id":"acme/greet","kind":"block","version":"1.0.0"}`,
+	} {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{{"init", "-q"}, {"add", "-A"}, {"commit", "-q", "-m", "package"}} {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s failed: %v: %s", args[0], err, out)
+		}
+	}
+
+	h := api.NewBlocksHandler(t.TempDir())
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/blocks", h.List)
+	mux.HandleFunc("GET /api/blocks/packages", h.ListPackages)
+	mux.HandleFunc("POST /api/blocks/install", h.Install)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	if w := do("POST", "/api/blocks/install", `{}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 without a source, got %d", w.Code)
+	}
+
+	install := `{"source":"file://` + repo + `"}`
+	w := do("POST", "/api/blocks/install", install)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var pkg engine.BlockPackage
+	json.NewDecoder(w.Body).Decode(&pkg)
+	if pkg.Namespace != "acme" || !strings.HasPrefix(pkg.Checksum, "sha256:") {
+		t.Errorf("unexpected package %+v", pkg)
+	}
+
+	if w := do("POST", "/api/blocks/install", install); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a second install, got %d", w.Code)
+	}
+	if w := do("POST", "/api/blocks/install", `{"source":"file://`+repo+`","force":true,"checksum":"sha256:00"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a checksum mismatch, got %d", w.Code)
+	}
+
+	var blocks []engine.RegisteredBlock
+	json.NewDecoder(do("GET", "/api/blocks", "").Body).Decode(&blocks)
+	found := false
+	for _, b := range blocks {
+		found = found || (b.Type == "acme/greet" && b.Manifest != nil && b.Manifest.Version == "1.0.0")
+	}
+	if !found {
+		t.Errorf("expected acme/greet in the block list, got %+v", blocks)
+	}
+
+	var packages []engine.BlockPackage
+	json.NewDecoder(do("GET", "/api/blocks/packages", "").Body).Decode(&packages)
+	if len(packages) != 1 || packages[0].Namespace != "acme" {
+		t.Errorf("expected the acme package, got %+v", packages)
+	}
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// CanaryRequest is the body of PUT /api/workflows/{id}/canary: a new version
+// of the workflow, run by Percent of its trigger fires.
+type CanaryRequest struct {
+	Workflow engine.Workflow `json:"workflow"`
+	Percent  int             `json:"percent"`
+}
+
+// CanaryResponse is the canary version of a workflow and how both versions
+// fared since it was deployed.
+type CanaryResponse struct {
+	WorkflowID string          `json:"workflow_id"`
+	Percent    int             `json:"percent"`
+	Workflow   engine.Workflow `json:"workflow"`
+	CreatedAt  time.Time       `json:"created_at"`
+	Stable     VersionMetrics  `json:"stable"`
+	Canary     VersionMetrics  `json:"canary"`
+}
+
+// VersionMetrics are the executions of one version of a workflow. Test-mode
+// runs are left out.
+type VersionMetrics struct {
+	Runs      int `json:"runs"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	// SuccessRate is the share of the finished runs that completed, 0 to 1
+	SuccessRate   float64 `json:"success_rate"`
+	AvgDurationMS int64   `json:"avg_duration_ms"`
+}
+
+func newVersionMetrics(v storage.VersionStats) VersionMetrics {
+	m := VersionMetrics{Runs: v.Runs, Completed: v.Completed, Failed: v.Failed}
+	if finished := v.Completed + v.Failed; finished > 0 {
+		m.SuccessRate = float64(v.Completed) / float64(finished)
+		m.AvgDurationMS = v.DurationMS / int64(finished)
+	}
+	return m
+}
+
+// GetCanary handles GET /api/workflows/{id}/canary
+func (h *WorkflowHandler) GetCanary(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	canary, err := h.Store.GetWorkflowCanary(r.Context(), id)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get canary: "+err.Error())
+		return
+	}
+	if canary == nil {
+		WriteError(w, http.StatusNotFound, "Workflow has no canary")
+		return
+	}
+
+	resp := CanaryResponse{WorkflowID: id, Percent: canary.Percent, CreatedAt: canary.CreatedAt}
+	if err := json.Unmarshal(canary.Definition, &resp.Workflow); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Invalid canary definition: "+err.Error())
+		return
+	}
+	stable, canaryStats, err := h.Store.WorkflowVersionStats(r.Context(), id, canary.CreatedAt)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get version stats: "+err.Error())
+		return
+	}
+	resp.Stable = newVersionMetrics(stable)
+	resp.Canary = newVersionMetrics(canaryStats)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// SetCanary handles PUT /api/workflows/{id}/canary, deploying a new version of
+// the workflow for a share of its trigger fires. Replacing the version resets
+// its metrics; changing only the percent keeps them.
+func (h *WorkflowHandler) SetCanary(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := h.Store.GetWorkflow(r.Context(), id); err != nil {
+		WriteError(w, http.StatusNotFound, "Workflow not found: "+err.Error())
+		return
+	}
+	if h.refuseSynced(w, r, id) {
+		return
+	}
+
+	var req CanaryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+	if req.Percent < 1 || req.Percent > 100 {
+		writeValidationError(w, "Invalid canary", &engine.FieldError{Field: "percent", Message: "must be between 1 and 100"})
+		return
+	}
+	req.Workflow.ID = id
+	if err := req.Workflow.ValidateDefinition(h.ConfigSchema); err != nil {
+		writeValidationError(w, "Invalid workflow", err)
+		return
+	}
+
+	defBytes, err := json.Marshal(req.Workflow)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to marshal definition: "+err.Error())
+		return
+	}
+	canary := &storage.WorkflowCanary{WorkflowID: id, Definition: defBytes, Percent: req.Percent}
+	if err := h.Store.SetWorkflowCanary(r.Context(), canary); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to set canary: "+err.Error())
+		return
+	}
+	h.GetCanary(w, r)
+}
+
+// DeleteCanary handles DELETE /api/workflows/{id}/canary: every fire runs the
+// stable version again.
+func (h *WorkflowHandler) DeleteCanary(w http.ResponseWriter, r *http.Request) {
+	if err := h.Store.DeleteWorkflowCanary(r.Context(), r.PathValue("id")); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to delete canary: "+err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PromoteCanary handles POST /api/workflows/{id}/canary/promote: the canary
+// version replaces the workflow's definition and runs for every fire.
+func (h *WorkflowHandler) PromoteCanary(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if h.refuseSynced(w, r, id) {
+		return
+	}
+	canary, err := h.Store.GetWorkflowCanary(r.Context(), id)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get canary: "+err.Error())
+		return
+	}
+	if canary == nil {
+		WriteError(w, http.StatusNotFound, "Workflow has no canary")
+		return
+	}
+	var wf engine.Workflow
+	if err := json.Unmarshal(canary.Definition, &wf); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Invalid canary definition: "+err.Error())
+		return
+	}
+
+	err = storage.WithTx(r.Context(), h.Store, func(tx storage.Tx) error {
+		if err := tx.UpdateWorkflow(r.Context(), &storage.Workflow{ID: id, Name: wf.Name, Definition: canary.Definition}); err != nil {
+			return err
+		}
+		return tx.DeleteWorkflowCanary(r.Context(), id)
+	})
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to promote canary: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wf)
+}
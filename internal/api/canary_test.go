@@ -0,0 +1,82 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/api"
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func TestWorkflowAPI_Canary(t *testing.T) {
+	mux, store := newWorkflowMux(t)
+	handler := api.NewWorkflowHandler(store)
+	mux.HandleFunc("GET /api/workflows/{id}/canary", handler.GetCanary)
+	mux.HandleFunc("PUT /api/workflows/{id}/canary", handler.SetCanary)
+	mux.HandleFunc("DELETE /api/workflows/{id}/canary", handler.DeleteCanary)
+	mux.HandleFunc("POST /api/workflows/{id}/canary/promote", handler.PromoteCanary)
+
+	store.CreateWorkflow(testCtx, &storage.Workflow{ID: "wf-1", Name: "Stable", Definition: []byte(`{"id":"wf-1","name":"Stable"}`)})
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := do(http.MethodGet, "/api/workflows/wf-1/canary", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 without a canary, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/api/workflows/wf-1/canary", `{"workflow":{"name":"Next"},"percent":0}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422 for a percent of 0, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/api/workflows/missing/canary", `{"workflow":{"name":"Next"},"percent":10}`); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown workflow, got %d", rec.Code)
+	}
+	rec := do(http.MethodPut, "/api/workflows/wf-1/canary", `{"workflow":{"name":"Next"},"percent":10}`)
+	var resp api.CanaryResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusOK || resp.Percent != 10 || resp.Workflow.ID != "wf-1" || resp.Workflow.Name != "Next" {
+		t.Fatalf("unexpected canary %d %+v", rec.Code, resp)
+	}
+
+	// Each version's executions count towards its own metrics
+	for i, status := range []storage.ExecutionStatus{storage.ExecutionStatusCompleted, storage.ExecutionStatusCompleted, storage.ExecutionStatusFailed} {
+		execID, _ := store.CreateExecution(testCtx, "wf-1")
+		if i > 0 {
+			store.MarkCanaryExecution(testCtx, execID)
+		}
+		store.UpdateExecutionStatus(testCtx, execID, status, []byte(`{}`), nil)
+	}
+	rec = do(http.MethodGet, "/api/workflows/wf-1/canary", "")
+	resp = api.CanaryResponse{}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Stable.Runs != 1 || resp.Stable.SuccessRate != 1 || resp.Canary.Runs != 2 || resp.Canary.Failed != 1 || resp.Canary.SuccessRate != 0.5 {
+		t.Errorf("unexpected version metrics %+v and %+v", resp.Stable, resp.Canary)
+	}
+
+	rec = do(http.MethodPost, "/api/workflows/wf-1/canary/promote", "")
+	var promoted engine.Workflow
+	json.NewDecoder(rec.Body).Decode(&promoted)
+	if rec.Code != http.StatusOK || promoted.Name != "Next" {
+		t.Fatalf("unexpected promotion %d %+v", rec.Code, promoted)
+	}
+	if stored, _ := store.GetWorkflow(testCtx, "wf-1"); stored.Name != "Next" || !strings.Contains(string(stored.Definition), `"Next"`) {
+		t.Errorf("expected the canary to replace the workflow, got %+v", stored)
+	}
+	if rec := do(http.MethodGet, "/api/workflows/wf-1/canary", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected the canary to be gone after promotion, got %d", rec.Code)
+	}
+
+	// Rolling back drops the canary
+	do(http.MethodPut, "/api/workflows/wf-1/canary", `{"workflow":{"name":"Broken"},"percent":50}`)
+	if rec := do(http.MethodDelete, "/api/workflows/wf-1/canary", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", rec.Code)
+	}
+	if canary, _ := store.GetWorkflowCanary(testCtx, "wf-1"); canary != nil {
+		t.Errorf("expected no canary, got %+v", canary)
+	}
+}
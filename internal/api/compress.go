@@ -0,0 +1,177 @@
+package api
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// compressMinSize is the response size from which compressing pays off;
+// smaller responses are sent as they are.
+const compressMinSize = 1024
+
+var gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(io.Discard) }}
+
+// Compress compresses the responses of next with gzip or deflate, whichever the
+// client accepts (gzip first), for the routes whose payloads run large:
+// execution state, node results and logs, and lists. Responses under
+// compressMinSize, without a body or already encoded are left alone.
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// acceptedEncoding picks the encoding to use from an Accept-Encoding header,
+// empty when the client accepts neither gzip nor deflate.
+func acceptedEncoding(header string) string {
+	q := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		weight := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				weight = f
+			}
+		}
+		q[strings.ToLower(strings.TrimSpace(name))] = weight
+	}
+	best, bestQ := "", 0.0
+	for _, encoding := range []string{"gzip", "deflate"} {
+		weight, ok := q[encoding]
+		if !ok {
+			weight, ok = q["*"]
+		}
+		if ok && weight > bestQ {
+			best, bestQ = encoding, weight
+		}
+	}
+	return best
+}
+
+// compressWriter holds a response back until it is known to reach
+// compressMinSize, then compresses it on the fly.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+
+	status  int    // pending status, 0 until WriteHeader
+	buf     []byte // body written before deciding
+	decided bool
+	zw      io.WriteCloser // nil when sent uncompressed
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.decided {
+		cw.ResponseWriter.WriteHeader(code) // superfluous; let net/http report it
+		return
+	}
+	if cw.status != 0 {
+		return
+	}
+	cw.status = code
+	if code == http.StatusNoContent || code == http.StatusNotModified || code < 200 {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.decided {
+		cw.buf = append(cw.buf, b...)
+		if len(cw.buf) < compressMinSize {
+			return len(b), nil
+		}
+		cw.decide(true)
+		if _, err := cw.flushBuf(); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if cw.zw != nil {
+		return cw.zw.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// decide sends the header, compressed if compress is set and nothing else
+// encoded the response already.
+func (cw *compressWriter) decide(compress bool) {
+	cw.decided = true
+	h := cw.Header()
+	if compress && h.Get("Content-Encoding") == "" {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		if cw.encoding == "gzip" {
+			gz := gzipWriters.Get().(*gzip.Writer)
+			gz.Reset(cw.ResponseWriter)
+			cw.zw = gz
+		} else {
+			cw.zw, _ = flate.NewWriter(cw.ResponseWriter, flate.DefaultCompression)
+		}
+	}
+	if cw.status != 0 {
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+}
+
+// flushBuf writes the body held back while deciding.
+func (cw *compressWriter) flushBuf() (int, error) {
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return 0, nil
+	}
+	if cw.zw != nil {
+		return cw.zw.Write(buf)
+	}
+	return cw.ResponseWriter.Write(buf)
+}
+
+// Flush sends what was written so far; a response flushed before reaching
+// compressMinSize is sent uncompressed.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide(false)
+		cw.flushBuf()
+	}
+	if f, ok := cw.zw.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// close ends the response: a small one is sent uncompressed, a compressed one
+// is terminated.
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		cw.decide(false)
+		cw.flushBuf()
+	}
+	if cw.zw == nil {
+		return
+	}
+	cw.zw.Close()
+	if gz, ok := cw.zw.(*gzip.Writer); ok {
+		gz.Reset(io.Discard)
+		gzipWriters.Put(gz)
+	}
+}
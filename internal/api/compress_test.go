@@ -0,0 +1,92 @@
+package api_test
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/api"
+)
+
+func TestCompress(t *testing.T) {
+	large := `{"state":"` + strings.Repeat("x", 4096) + `"}`
+	small := `{"ok":true}`
+	serve := func(body, acceptEncoding string) *httptest.ResponseRecorder {
+		handler := api.Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			// Written in pieces, as encoders do
+			for len(body) > 0 {
+				n := min(len(body), 512)
+				io.WriteString(w, body[:n])
+				body = body[n:]
+			}
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/api/executions/exec-1", nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		acceptEncoding string
+		wantEncoding   string
+	}{
+		{"Gzip", large, "gzip, deflate, br", "gzip"},
+		{"Deflate", large, "deflate", "deflate"},
+		{"PreferredByWeight", large, "gzip;q=0.5, deflate", "deflate"},
+		{"Refused", large, "gzip;q=0, identity", ""},
+		{"NotAccepted", large, "", ""},
+		{"Small", small, "gzip", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(tt.body, tt.acceptEncoding)
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("expected encoding %q, got %q", tt.wantEncoding, got)
+			}
+			if !strings.Contains(rec.Header().Get("Vary"), "Accept-Encoding") {
+				t.Error("expected Vary: Accept-Encoding")
+			}
+			var r io.Reader = rec.Body
+			switch tt.wantEncoding {
+			case "gzip":
+				zr, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatalf("invalid gzip body: %v", err)
+				}
+				r = zr
+			case "deflate":
+				r = flate.NewReader(rec.Body)
+			}
+			body, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("failed to read body: %v", err)
+			}
+			if string(body) != tt.body {
+				t.Errorf("expected the body to round-trip, got %d bytes", len(body))
+			}
+		})
+	}
+
+	t.Run("StatusKept", func(t *testing.T) {
+		handler := api.Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Execution not found", http.StatusNotFound)
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/api/executions/missing", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Encoding") != "" {
+			t.Errorf("expected an uncompressed 404, got %d %v", rec.Code, rec.Header())
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures the cross-origin access to the API, e.g. by the editor
+// UI served from another origin.
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to call the API, like
+	// "https://editor.example.com"; "*" allows any, but only without
+	// credentials.
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response; 0 leaves it to them.
+	MaxAge time.Duration
+}
+
+// DefaultCORSConfig allows any origin to call every route, without credentials.
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+		AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Key", RequestIDHeader, idempotencyKeyHeader, CorrelationIDHeader, LabelsHeader},
+		MaxAge:         10 * time.Minute,
+	}
+}
+
+// Validate checks the config: credentials may only be allowed for origins
+// listed explicitly, as any site could otherwise make credentialed calls.
+func (c CORSConfig) Validate() error {
+	if c.AllowCredentials && slices.Contains(c.AllowedOrigins, "*") {
+		return errors.New(`CORS credentials can't be allowed for any origin ("*"); list the allowed origins`)
+	}
+	return nil
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin of a request from origin,
+// empty when origin isn't allowed.
+func (c CORSConfig) allowOrigin(origin string) string {
+	if slices.Contains(c.AllowedOrigins, origin) {
+		return origin
+	}
+	if slices.Contains(c.AllowedOrigins, "*") {
+		return "*"
+	}
+	return ""
+}
+
+// CORS answers the preflight requests of allowed origins for every route and
+// adds the CORS headers to their actual requests. Requests without an Origin
+// and from other origins go through untouched; browsers keep them from reading
+// the responses of the latter.
+func CORS(config CORSConfig, next http.Handler) http.Handler {
+	methods := strings.Join(config.AllowedMethods, ", ")
+	headers := strings.Join(config.AllowedHeaders, ", ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		allowed := config.allowOrigin(origin)
+		if allowed != "" {
+			h.Set("Access-Control-Allow-Origin", allowed)
+			if config.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+		if !preflight {
+			if allowed != "" {
+				h.Set("Access-Control-Expose-Headers", RequestIDHeader)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if allowed != "" {
+			h.Set("Access-Control-Allow-Methods", methods)
+			h.Set("Access-Control-Allow-Headers", headers)
+			if config.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(config.MaxAge.Seconds())))
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,95 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/api"
+)
+
+func TestCORS(t *testing.T) {
+	reached := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusOK)
+	})
+	serve := func(config api.CORSConfig, method, origin string, preflight bool) *httptest.ResponseRecorder {
+		reached = false
+		req := httptest.NewRequest(method, "/api/workflows/wf-1", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if preflight {
+			req.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+		}
+		rec := httptest.NewRecorder()
+		api.CORS(config, next).ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("PreflightAnsweredForAnyRoute", func(t *testing.T) {
+		rec := serve(api.DefaultCORSConfig(), http.MethodOptions, "https://ui.example.com", true)
+		if reached || rec.Code != http.StatusNoContent {
+			t.Fatalf("expected the preflight to be answered with 204, got %d (reached=%v)", rec.Code, reached)
+		}
+		h := rec.Header()
+		if h.Get("Access-Control-Allow-Origin") != "*" || h.Get("Access-Control-Allow-Methods") == "" || h.Get("Access-Control-Max-Age") != "600" {
+			t.Errorf("unexpected preflight headers %v", h)
+		}
+	})
+
+	t.Run("ActualRequest", func(t *testing.T) {
+		rec := serve(api.DefaultCORSConfig(), http.MethodPut, "https://ui.example.com", false)
+		if !reached || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+			t.Errorf("expected the request to be served with CORS headers, got %v (reached=%v)", rec.Header(), reached)
+		}
+		if rec.Header().Get("Access-Control-Expose-Headers") != api.RequestIDHeader {
+			t.Errorf("expected the request ID header to be exposed, got %v", rec.Header())
+		}
+	})
+
+	t.Run("NoOrigin", func(t *testing.T) {
+		rec := serve(api.DefaultCORSConfig(), http.MethodOptions, "", false)
+		if !reached || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("expected a request without Origin to go through untouched, got %v", rec.Header())
+		}
+	})
+
+	config := api.CORSConfig{
+		AllowedOrigins:   []string{"https://ui.example.com"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodDelete},
+		AllowedHeaders:   []string{"Content-Type"},
+		AllowCredentials: true,
+		MaxAge:           time.Minute,
+	}
+
+	t.Run("ConfiguredOrigin", func(t *testing.T) {
+		rec := serve(config, http.MethodOptions, "https://ui.example.com", true)
+		h := rec.Header()
+		if h.Get("Access-Control-Allow-Origin") != "https://ui.example.com" || h.Get("Access-Control-Allow-Credentials") != "true" ||
+			h.Get("Access-Control-Allow-Methods") != "GET, DELETE" || h.Get("Access-Control-Allow-Headers") != "Content-Type" {
+			t.Errorf("unexpected preflight headers %v", h)
+		}
+	})
+
+	t.Run("OtherOrigin", func(t *testing.T) {
+		rec := serve(config, http.MethodOptions, "https://evil.example.com", true)
+		if reached || rec.Header().Get("Access-Control-Allow-Origin") != "" || rec.Header().Get("Access-Control-Allow-Methods") != "" {
+			t.Errorf("expected the preflight of another origin to get no CORS headers, got %v", rec.Header())
+		}
+	})
+
+	t.Run("WildcardWithCredentials", func(t *testing.T) {
+		config := config
+		config.AllowedOrigins = []string{"*"}
+		if err := config.Validate(); err == nil {
+			t.Error("expected credentials for any origin to be rejected")
+		}
+		rec := serve(config, http.MethodGet, "https://any.example.com", false)
+		if rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+			t.Errorf("expected the origin not to be echoed, got %v", rec.Header())
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Error codes of ErrorResponse, one per kind of failure a client may handle.
+const (
+	ErrCodeBadRequest      = "bad_request"       // 400: malformed request, e.g. invalid JSON
+	ErrCodeUnauthorized    = "unauthorized"      // 401
+	ErrCodeForbidden       = "forbidden"         // 403
+	ErrCodeNotFound        = "not_found"         // 404
+	ErrCodeConflict        = "conflict"          // 409: clashes with the resource's state, e.g. it already exists
+	ErrCodePayloadTooLarge = "payload_too_large" // 413
+	ErrCodeValidation      = "validation_failed" // 422: well-formed request with invalid values
+	ErrCodeQuotaExceeded   = "quota_exceeded"    // 429: the workflow used up its quota
+	ErrCodeUnavailable     = "unavailable"       // 503: a feature that isn't configured, or the server is in maintenance
+	ErrCodeInternal        = "internal"          // 500 and other server failures
+)
+
+// ErrorResponse is the body of every API error:
+// {"error": {"code": "not_found", "message": "Trigger not found", "details": ...}}.
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody describes an API error. Code is stable for clients to switch on;
+// Message is for humans and may change. Details, when set, holds structured
+// information about the failure, such as the invalid fields of a request.
+type ErrorBody struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// WriteError answers with status and an ErrorResponse of message, coded after status.
+func WriteError(w http.ResponseWriter, status int, message string) {
+	WriteErrorDetails(w, status, message, nil)
+}
+
+// WriteErrorDetails is WriteError with details for the ErrorBody.
+func WriteErrorDetails(w http.ResponseWriter, status int, message string, details interface{}) {
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: ErrorBody{
+		Code:    ErrorCode(status),
+		Message: message,
+		Details: details,
+	}})
+}
+
+// ErrorCode returns the error code of a status.
+func ErrorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrCodeBadRequest
+	case http.StatusUnauthorized:
+		return ErrCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrCodeForbidden
+	case http.StatusNotFound:
+		return ErrCodeNotFound
+	case http.StatusConflict:
+		return ErrCodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return ErrCodePayloadTooLarge
+	case http.StatusUnprocessableEntity:
+		return ErrCodeValidation
+	case http.StatusTooManyRequests:
+		return ErrCodeQuotaExceeded
+	case http.StatusServiceUnavailable:
+		return ErrCodeUnavailable
+	}
+	if status < http.StatusInternalServerError {
+		return ErrCodeBadRequest
+	}
+	return ErrCodeInternal
+}
@@ -0,0 +1,70 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/api"
+)
+
+func TestWriteError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	api.WriteErrorDetails(rec, http.StatusUnprocessableEntity, "Invalid trigger", map[string]string{"type": "unknown"})
+
+	if rec.Code != http.StatusUnprocessableEntity || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	var resp api.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode error: %v", err)
+	}
+	if resp.Error.Code != api.ErrCodeValidation || resp.Error.Message != "Invalid trigger" || resp.Error.Details == nil {
+		t.Errorf("unexpected error %+v", resp.Error)
+	}
+
+	for status, code := range map[int]string{
+		http.StatusBadRequest:          api.ErrCodeBadRequest,
+		http.StatusNotFound:            api.ErrCodeNotFound,
+		http.StatusConflict:            api.ErrCodeConflict,
+		http.StatusTooManyRequests:     api.ErrCodeQuotaExceeded,
+		http.StatusInternalServerError: api.ErrCodeInternal,
+		http.StatusBadGateway:          api.ErrCodeInternal,
+	} {
+		if got := api.ErrorCode(status); got != code {
+			t.Errorf("expected code %s for %d, got %s", code, status, got)
+		}
+	}
+}
+
+// TestErrorEnvelope checks that handlers answer errors with the shared envelope.
+func TestErrorEnvelope(t *testing.T) {
+	mux, _, _ := newTriggerMux(t)
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"InvalidJSON", http.MethodPost, "/api/triggers", `{`, http.StatusBadRequest, api.ErrCodeBadRequest},
+		{"Validation", http.MethodPost, "/api/triggers", `{"type":"cron"}`, http.StatusUnprocessableEntity, api.ErrCodeValidation},
+		{"NotFound", http.MethodGet, "/api/triggers/missing", ``, http.StatusNotFound, api.ErrCodeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			var resp api.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("expected a JSON error, got %v", err)
+			}
+			if rec.Code != tt.status || resp.Error.Code != tt.code || resp.Error.Message == "" {
+				t.Errorf("expected %d %s, got %d %+v", tt.status, tt.code, rec.Code, resp.Error)
+			}
+		})
+	}
+}
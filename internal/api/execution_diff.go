@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// ExecutionDiffResponse compares the node outputs of two executions of a workflow.
+type ExecutionDiffResponse struct {
+	WorkflowID string            `json:"workflow_id"`
+	A          ExecutionResponse `json:"a"`
+	B          ExecutionResponse `json:"b"`
+	// Nodes lists the nodes of a in the order their results were saved,
+	// followed by the nodes only b ran.
+	Nodes []NodeDiff `json:"nodes"`
+}
+
+// NodeDiff is how the output of one node differs between the two executions:
+// engine.DiffAdded for a node only b ran, engine.DiffRemoved for one only a
+// ran, engine.DiffChanged or engine.DiffUnchanged for one both ran.
+type NodeDiff struct {
+	NodeID string `json:"node_id"`
+	Status string `json:"status"`
+	// Changes are the fields of a changed output that differ, by path.
+	Changes []engine.FieldChange `json:"changes,omitempty"`
+}
+
+// Diff handles GET /api/workflows/{id}/executions/diff?a=...&b=..., comparing
+// the node outputs of two executions of the workflow field by field.
+func (h *ExecutionHandler) Diff(w http.ResponseWriter, r *http.Request) {
+	workflowID := r.PathValue("id")
+	idA, idB := r.URL.Query().Get("a"), r.URL.Query().Get("b")
+	if workflowID == "" || idA == "" || idB == "" {
+		WriteError(w, http.StatusBadRequest, "Missing workflow ID or executions a and b")
+		return
+	}
+
+	resp := ExecutionDiffResponse{WorkflowID: workflowID, Nodes: []NodeDiff{}}
+	var results [2][]*storage.NodeResult
+	for i, id := range []string{idA, idB} {
+		exec, err := h.Store.GetExecution(r.Context(), id)
+		if err != nil || exec.WorkflowID != workflowID {
+			WriteError(w, http.StatusNotFound, fmt.Sprintf("Execution %s not found in workflow %s", id, workflowID))
+			return
+		}
+		summary := NewExecutionResponse(exec)
+		if i == 0 {
+			resp.A = summary
+		} else {
+			resp.B = summary
+		}
+		results[i], err = h.Store.ListNodeResults(r.Context(), id)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, "Failed to list node results: "+err.Error())
+			return
+		}
+	}
+
+	outputsB := make(map[string][]byte, len(results[1]))
+	for _, nr := range results[1] {
+		outputsB[nr.NodeID] = nr.Result
+	}
+	inA := make(map[string]bool, len(results[0]))
+	for _, nr := range results[0] {
+		inA[nr.NodeID] = true
+		outputB, ok := outputsB[nr.NodeID]
+		if !ok {
+			resp.Nodes = append(resp.Nodes, NodeDiff{NodeID: nr.NodeID, Status: engine.DiffRemoved})
+			continue
+		}
+		changes := engine.DiffJSON(nr.Result, outputB)
+		status := engine.DiffUnchanged
+		if len(changes) > 0 {
+			status = engine.DiffChanged
+		}
+		resp.Nodes = append(resp.Nodes, NodeDiff{NodeID: nr.NodeID, Status: status, Changes: changes})
+	}
+	for _, nr := range results[1] {
+		if !inA[nr.NodeID] {
+			resp.Nodes = append(resp.Nodes, NodeDiff{NodeID: nr.NodeID, Status: engine.DiffAdded})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/conv3n/conv3n/internal/storage"
@@ -24,43 +25,211 @@ type ExecutionResponse struct {
 	StartedAt   time.Time               `json:"started_at"`
 	CompletedAt *time.Time              `json:"completed_at,omitempty"`
 	Error       *string                 `json:"error,omitempty"`
+	// Test marks runs of the test webhook endpoint.
+	Test bool `json:"test,omitempty"`
+	// HeartbeatAt is the last sign of life recorded while the execution ran.
+	HeartbeatAt *time.Time `json:"heartbeat_at,omitempty"`
+	// RequestID is the X-Request-ID of the API call that started the run.
+	RequestID string `json:"request_id,omitempty"`
+	// Revision is the git revision of the workflows directory the workflow was
+	// synced at when the run started.
+	Revision string `json:"revision,omitempty"`
+	// ElapsedMS is how long the execution has been running, or ran once finished.
+	ElapsedMS int64 `json:"elapsed_ms"`
+	// Usage is the resources the execution used, recorded when it finished or
+	// started waiting.
+	Usage ExecutionUsage `json:"usage"`
+	// Canary marks runs of the workflow's canary version.
+	Canary bool `json:"canary,omitempty"`
+	// CorrelationID and Labels are those the run was started with, from the
+	// X-Correlation-ID and X-Execution-Labels headers or the trigger.
+	CorrelationID string            `json:"correlation_id,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+}
+
+// ExecutionUsage is the resources used by executions, for attributing load to
+// the workflows and teams causing it.
+type ExecutionUsage struct {
+	Nodes       int   `json:"nodes"`        // Nodes run
+	CPUTimeMS   int64 `json:"cpu_time_ms"`  // CPU time of the Bun processes running them
+	BytesStored int64 `json:"bytes_stored"` // Size of the node inputs and results saved
+	HTTPCalls   int   `json:"http_calls"`   // Requests to external services, retries and pages included
+}
+
+func newExecutionUsage(u storage.ExecutionUsage) ExecutionUsage {
+	return ExecutionUsage{Nodes: u.Nodes, CPUTimeMS: u.CPUTimeMS, BytesStored: u.BytesStored, HTTPCalls: u.HTTPCalls}
+}
+
+// NewExecutionResponse summarizes exec as of now.
+func NewExecutionResponse(exec *storage.Execution) ExecutionResponse {
+	end := time.Now()
+	if exec.CompletedAt != nil {
+		end = *exec.CompletedAt
+	}
+	return ExecutionResponse{
+		ID:            exec.ID,
+		WorkflowID:    exec.WorkflowID,
+		Status:        exec.Status,
+		StartedAt:     exec.StartedAt,
+		CompletedAt:   exec.CompletedAt,
+		Error:         exec.Error,
+		Test:          exec.Test,
+		HeartbeatAt:   exec.HeartbeatAt,
+		RequestID:     exec.RequestID,
+		Revision:      exec.Revision,
+		ElapsedMS:     end.Sub(exec.StartedAt).Milliseconds(),
+		Usage:         newExecutionUsage(exec.Usage),
+		Canary:        exec.Canary,
+		CorrelationID: exec.CorrelationID,
+		Labels:        exec.Labels,
+	}
 }
 
 type ExecutionDetailResponse struct {
 	ExecutionResponse
 	State json.RawMessage `json:"state"`
+	// TriggerData is the payload of the event that started the run
+	TriggerData json.RawMessage `json:"trigger_data,omitempty"`
+	// Nodes is the execution's node timeline in the order the nodes started,
+	// followed by the nodes it skipped.
+	Nodes []NodeTimelineEntry `json:"nodes"`
+}
+
+// NodeTimelineEntry is the status and timing of one node in an execution.
+type NodeTimelineEntry struct {
+	NodeID     string             `json:"node_id"`
+	Status     storage.NodeStatus `json:"status"`
+	Port       string             `json:"port,omitempty"`
+	Attempts   int                `json:"attempts"`
+	StartedAt  *time.Time         `json:"started_at,omitempty"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+	DurationMS *int64             `json:"duration_ms,omitempty"`
+	Error      *string            `json:"error,omitempty"`
 }
 
+// ExecutionLogEntry is one node's output within an execution log.
+type ExecutionLogEntry struct {
+	NodeID string          `json:"node_id"`
+	Time   time.Time       `json:"time"`
+	Result json.RawMessage `json:"result"`
+}
+
+// ExecutionLogsResponse is an execution's summary followed by its node outputs in order.
+type ExecutionLogsResponse struct {
+	ExecutionResponse
+	Entries []ExecutionLogEntry `json:"entries"`
+}
+
+// ListByWorkflow handles GET /api/workflows/{id}/executions. The
+// correlation_id and repeatable label=key=value parameters narrow the list to
+// the executions tagged with them.
 func (h *ExecutionHandler) ListByWorkflow(w http.ResponseWriter, r *http.Request) {
 	workflowID := r.PathValue("id")
 	if workflowID == "" {
-		http.Error(w, "Missing workflow ID", http.StatusBadRequest)
+		WriteError(w, http.StatusBadRequest, "Missing workflow ID")
+		return
+	}
+	filter, err := executionFilter(r.URL.Query())
+	if err != nil {
+		writeValidationError(w, "Invalid filter", err)
+		return
+	}
+	filter.WorkflowID = workflowID
+	h.writeExecutions(w, r, filter)
+}
+
+// Search handles GET /api/executions: the executions of any workflow with the
+// correlation_id and every label=key=value given, to follow a business
+// transaction across workflows. At least one of them is required.
+func (h *ExecutionHandler) Search(w http.ResponseWriter, r *http.Request) {
+	filter, err := executionFilter(r.URL.Query())
+	if err != nil {
+		writeValidationError(w, "Invalid filter", err)
+		return
+	}
+	if filter.CorrelationID == "" && len(filter.Labels) == 0 {
+		WriteError(w, http.StatusBadRequest, "correlation_id or label is required")
 		return
 	}
+	h.writeExecutions(w, r, filter)
+}
+
+// ExecutionSearchHit is a node result or error matching a content search.
+type ExecutionSearchHit struct {
+	ExecutionID string    `json:"execution_id"`
+	WorkflowID  string    `json:"workflow_id"`
+	StartedAt   time.Time `json:"started_at"`
+	// NodeID is the node whose result or error matched, empty for the
+	// execution's own error.
+	NodeID string `json:"node_id,omitempty"`
+	// Field is "result" or "error".
+	Field string `json:"field"`
+	// Snippet is the matching text, the words searched for wrapped in <mark>.
+	Snippet string `json:"snippet"`
+}
+
+// SearchContent handles GET /api/executions/search?q=: the node results and
+// errors containing every word of q, most recent executions first, to find
+// e.g. the run that processed an order from its number.
+func (h *ExecutionHandler) SearchContent(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		WriteError(w, http.StatusBadRequest, "Missing search query q")
+		return
+	}
+
+	hits, err := h.Store.SearchExecutions(r.Context(), q, queryLimit(r))
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to search executions: "+err.Error())
+		return
+	}
+
+	resp := make([]ExecutionSearchHit, len(hits))
+	for i, hit := range hits {
+		resp[i] = ExecutionSearchHit{
+			ExecutionID: hit.ExecutionID,
+			WorkflowID:  hit.WorkflowID,
+			StartedAt:   hit.StartedAt,
+			NodeID:      hit.NodeID,
+			Field:       hit.Field,
+			Snippet:     hit.Snippet,
+		}
+	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// queryLimit reads the limit parameter of a listing: 20 by default, at most 100.
+func queryLimit(r *http.Request) int {
 	limit := 20
 	if raw := r.URL.Query().Get("limit"); raw != "" {
 		if v, err := strconv.Atoi(raw); err == nil && v > 0 && v <= 100 {
 			limit = v
 		}
 	}
+	return limit
+}
 
-	execs, err := h.Store.ListExecutions(r.Context(), workflowID, limit)
+// writeExecutions answers with the most recent executions matching filter, up
+// to the limit parameter.
+func (h *ExecutionHandler) writeExecutions(w http.ResponseWriter, r *http.Request, filter storage.ExecutionFilter) {
+	limit := queryLimit(r)
+	var execs []*storage.Execution
+	var err error
+	if filter.CorrelationID == "" && len(filter.Labels) == 0 {
+		execs, err = h.Store.ListExecutions(r.Context(), filter.WorkflowID, limit)
+	} else {
+		execs, err = h.Store.FindExecutions(r.Context(), filter, limit)
+	}
 	if err != nil {
-		http.Error(w, "Failed to list executions: "+err.Error(), http.StatusInternalServerError)
+		WriteError(w, http.StatusInternalServerError, "Failed to list executions: "+err.Error())
 		return
 	}
 
 	resp := make([]ExecutionResponse, len(execs))
 	for i, e := range execs {
-		resp[i] = ExecutionResponse{
-			ID:          e.ID,
-			WorkflowID:  e.WorkflowID,
-			Status:      e.Status,
-			StartedAt:   e.StartedAt,
-			CompletedAt: e.CompletedAt,
-			Error:       e.Error,
-		}
+		resp[i] = NewExecutionResponse(e)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -70,46 +239,109 @@ func (h *ExecutionHandler) ListByWorkflow(w http.ResponseWriter, r *http.Request
 func (h *ExecutionHandler) Get(w http.ResponseWriter, r *http.Request) {
 	execID := r.PathValue("id")
 	if execID == "" {
-		http.Error(w, "Missing execution ID", http.StatusBadRequest)
+		WriteError(w, http.StatusBadRequest, "Missing execution ID")
 		return
 	}
 
 	exec, err := h.Store.GetExecution(r.Context(), execID)
 	if err != nil {
-		http.Error(w, "Execution not found: "+err.Error(), http.StatusNotFound)
+		WriteError(w, http.StatusNotFound, "Execution not found: "+err.Error())
 		return
 	}
 
 	resp := ExecutionDetailResponse{
-		ExecutionResponse: ExecutionResponse{
-			ID:          exec.ID,
-			WorkflowID:  exec.WorkflowID,
-			Status:      exec.Status,
-			StartedAt:   exec.StartedAt,
-			CompletedAt: exec.CompletedAt,
-			Error:       exec.Error,
-		},
-		State: exec.State,
+		ExecutionResponse: NewExecutionResponse(exec),
+		State:             exec.State,
+		TriggerData:       exec.TriggerData,
+		Nodes:             []NodeTimelineEntry{},
+	}
+
+	nodes, err := h.Store.ListNodeExecutions(r.Context(), execID)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to list node executions: "+err.Error())
+		return
+	}
+	for _, ne := range nodes {
+		entry := NodeTimelineEntry{
+			NodeID:     ne.NodeID,
+			Status:     ne.Status,
+			Port:       ne.Port,
+			Attempts:   ne.Attempts,
+			StartedAt:  ne.StartedAt,
+			FinishedAt: ne.FinishedAt,
+			Error:      ne.Error,
+		}
+		if ne.StartedAt != nil && ne.FinishedAt != nil {
+			ms := ne.FinishedAt.Sub(*ne.StartedAt).Milliseconds()
+			entry.DurationMS = &ms
+		}
+		resp.Nodes = append(resp.Nodes, entry)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
+// NodeExecutionResponse is what a node of an execution was called with and
+// what it returned. A node that failed or is still running has no output.
+type NodeExecutionResponse struct {
+	ExecutionID string `json:"execution_id"`
+	NodeID      string `json:"node_id"`
+	// Input is the node's resolved input, with secrets redacted.
+	Input  json.RawMessage `json:"input,omitempty"`
+	Output json.RawMessage `json:"output,omitempty"`
+}
+
+// GetNodeResult handles GET /api/executions/{id}/nodes/{nodeId}.
 func (h *ExecutionHandler) GetNodeResult(w http.ResponseWriter, r *http.Request) {
 	execID := r.PathValue("id")
 	nodeID := r.PathValue("nodeId")
 	if execID == "" || nodeID == "" {
-		http.Error(w, "Missing execution or node ID", http.StatusBadRequest)
+		WriteError(w, http.StatusBadRequest, "Missing execution or node ID")
 		return
 	}
 
+	resp := NodeExecutionResponse{ExecutionID: execID, NodeID: nodeID}
+	resp.Input, _ = h.Store.GetNodeInput(r.Context(), execID, nodeID)
 	result, err := h.Store.GetNodeResult(r.Context(), execID, nodeID)
+	if err != nil && resp.Input == nil {
+		WriteError(w, http.StatusNotFound, "Node result not found: "+err.Error())
+		return
+	}
+	resp.Output = result
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Logs returns the node results of an execution in the order they completed.
+func (h *ExecutionHandler) Logs(w http.ResponseWriter, r *http.Request) {
+	execID := r.PathValue("id")
+	if execID == "" {
+		WriteError(w, http.StatusBadRequest, "Missing execution ID")
+		return
+	}
+
+	exec, err := h.Store.GetExecution(r.Context(), execID)
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "Execution not found: "+err.Error())
+		return
+	}
+
+	results, err := h.Store.ListNodeResults(r.Context(), execID)
 	if err != nil {
-		http.Error(w, "Node result not found: "+err.Error(), http.StatusNotFound)
+		WriteError(w, http.StatusInternalServerError, "Failed to list node results: "+err.Error())
 		return
 	}
 
+	resp := ExecutionLogsResponse{
+		ExecutionResponse: NewExecutionResponse(exec),
+		Entries:           make([]ExecutionLogEntry, len(results)),
+	}
+	for i, nr := range results {
+		resp.Entries[i] = ExecutionLogEntry{NodeID: nr.NodeID, Time: nr.CreatedAt, Result: nr.Result}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	w.Write(result)
+	json.NewEncoder(w).Encode(resp)
 }
@@ -2,8 +2,10 @@ package api_test
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/conv3n/conv3n/internal/api"
@@ -16,8 +18,11 @@ func newExecutionMux(t *testing.T) (*http.ServeMux, storage.Storage) {
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /api/workflows/{id}/executions", handler.ListByWorkflow)
+	mux.HandleFunc("GET /api/workflows/{id}/executions/diff", handler.Diff)
+	mux.HandleFunc("GET /api/executions/search", handler.SearchContent)
 	mux.HandleFunc("GET /api/executions/{id}", handler.Get)
 	mux.HandleFunc("GET /api/executions/{id}/nodes/{nodeId}", handler.GetNodeResult)
+	mux.HandleFunc("GET /api/executions/{id}/logs", handler.Logs)
 
 	return mux, store
 }
@@ -67,6 +72,9 @@ func TestExecutionAPI_Get(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to create execution: %v", err)
 	}
+	store.SaveExecutionTriggerData(ctx, execID, []byte(`{"user":"ada"}`))
+	store.HeartbeatExecution(ctx, execID)
+	store.AddExecutionUsage(ctx, execID, storage.ExecutionUsage{Nodes: 2, CPUTimeMS: 15, BytesStored: 64, HTTPCalls: 1})
 
 	// Get
 	req := httptest.NewRequest(http.MethodGet, "/api/executions/"+execID, nil)
@@ -85,6 +93,54 @@ func TestExecutionAPI_Get(t *testing.T) {
 	if resp.ID != execID {
 		t.Errorf("expected ID %s, got %s", execID, resp.ID)
 	}
+	if resp.Nodes == nil || len(resp.Nodes) != 0 {
+		t.Errorf("expected an empty node timeline, got %v", resp.Nodes)
+	}
+	if string(resp.TriggerData) != `{"user":"ada"}` {
+		t.Errorf("expected the trigger payload, got %s", resp.TriggerData)
+	}
+	if resp.HeartbeatAt == nil || resp.ElapsedMS < 0 {
+		t.Errorf("expected the heartbeat and elapsed time of the running execution, got %v and %dms", resp.HeartbeatAt, resp.ElapsedMS)
+	}
+	if want := (api.ExecutionUsage{Nodes: 2, CPUTimeMS: 15, BytesStored: 64, HTTPCalls: 1}); resp.Usage != want {
+		t.Errorf("expected usage %+v, got %+v", want, resp.Usage)
+	}
+}
+
+func TestExecutionAPI_GetNodeTimeline(t *testing.T) {
+	mux, store := newExecutionMux(t)
+	ctx := testCtx
+
+	execID, _ := store.CreateExecution(ctx, "wf-1")
+	store.StartNodeExecution(ctx, execID, "fetch")
+	store.FinishNodeExecution(ctx, execID, "fetch", storage.NodeStatusSuccess, "default", nil)
+	store.FinishNodeExecution(ctx, execID, "fallback", storage.NodeStatusSkipped, "", nil)
+	msg := "boom"
+	store.StartNodeExecution(ctx, execID, "save")
+	store.FinishNodeExecution(ctx, execID, "save", storage.NodeStatusFailed, "", &msg)
+	store.StartNodeExecution(ctx, execID, "save")
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/executions/"+execID, nil))
+	var resp api.ExecutionDetailResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(resp.Nodes) != 3 {
+		t.Fatalf("expected 3 timeline entries, got %+v", resp.Nodes)
+	}
+	fetch, save, fallback := resp.Nodes[0], resp.Nodes[1], resp.Nodes[2]
+	if fetch.NodeID != "fetch" || fetch.Status != storage.NodeStatusSuccess || fetch.Port != "default" || fetch.DurationMS == nil {
+		t.Errorf("unexpected entry %+v", fetch)
+	}
+	// A second attempt resets the outcome of the first
+	if save.NodeID != "save" || save.Status != storage.NodeStatusRunning || save.Attempts != 2 || save.Error != nil || save.FinishedAt != nil {
+		t.Errorf("unexpected entry %+v", save)
+	}
+	if fallback.NodeID != "fallback" || fallback.Status != storage.NodeStatusSkipped || fallback.StartedAt != nil {
+		t.Errorf("unexpected entry %+v", fallback)
+	}
 }
 
 func TestExecutionAPI_GetNodeResult(t *testing.T) {
@@ -97,9 +153,11 @@ func TestExecutionAPI_GetNodeResult(t *testing.T) {
 		t.Fatalf("failed to create execution: %v", err)
 	}
 
-	// Save node result
+	// Save node input and result
 	nodeID := "node-1"
+	input := []byte(`{"config":{"password":"[REDACTED]"}}`)
 	result := []byte(`{"foo":"bar"}`)
+	store.SaveNodeInput(ctx, execID, nodeID, input)
 	store.SaveNodeResult(ctx, execID, nodeID, result)
 
 	// Get node result
@@ -111,8 +169,158 @@ func TestExecutionAPI_GetNodeResult(t *testing.T) {
 		t.Errorf("expected status 200, got %d", rec.Code)
 	}
 
-	if rec.Body.String() != string(result) {
-		t.Errorf("expected body %s, got %s", result, rec.Body.String())
+	var resp api.NodeExecutionResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if string(resp.Input) != string(input) || string(resp.Output) != string(result) {
+		t.Errorf("expected input %s and output %s, got %+v", input, result, resp)
+	}
+
+	// A failed node has an input but no output
+	store.SaveNodeInput(ctx, execID, "failed", input)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/executions/"+execID+"/nodes/failed", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200 for a failed node, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/executions/"+execID+"/nodes/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown node, got %d", rec.Code)
+	}
+}
+
+func TestExecutionAPI_Logs(t *testing.T) {
+	mux, store := newExecutionMux(t)
+	ctx := testCtx
+
+	execID, err := store.CreateExecution(ctx, "wf-1")
+	if err != nil {
+		t.Fatalf("failed to create execution: %v", err)
+	}
+	store.SaveNodeResult(ctx, execID, "start", []byte(`{"data":1}`))
+	store.SaveNodeResult(ctx, execID, "end", []byte(`{"data":2}`))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/executions/"+execID+"/logs", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var logs api.ExecutionLogsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&logs); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if logs.ID != execID || len(logs.Entries) != 2 {
+		t.Fatalf("unexpected logs: %+v", logs)
+	}
+	if logs.Entries[0].NodeID != "start" || string(logs.Entries[1].Result) != `{"data":2}` {
+		t.Errorf("unexpected entries: %+v", logs.Entries)
+	}
+
+	// Unknown execution
+	req = httptest.NewRequest(http.MethodGet, "/api/executions/missing/logs", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestExecutionAPI_Diff(t *testing.T) {
+	mux, store := newExecutionMux(t)
+	ctx := testCtx
+
+	execA, _ := store.CreateExecution(ctx, "wf-1")
+	execB, _ := store.CreateExecution(ctx, "wf-1")
+	other, _ := store.CreateExecution(ctx, "wf-2")
+	store.SaveNodeResult(ctx, execA, "start", []byte(`{"data":{"ok":true},"port":"default"}`))
+	store.SaveNodeResult(ctx, execA, "fetch", []byte(`{"data":{"body":{"price":10,"items":[1,2],"etag":"x"}},"port":"success"}`))
+	store.SaveNodeResult(ctx, execA, "notify", []byte(`{"data":null,"port":"default"}`))
+	store.SaveNodeResult(ctx, execB, "start", []byte(`{"data":{"ok":true},"port":"default"}`))
+	store.SaveNodeResult(ctx, execB, "fetch", []byte(`{"data":{"body":{"price":12,"items":[1],"retry":1}},"port":"server_error"}`))
+	store.SaveNodeResult(ctx, execB, "alert", []byte(`{"data":null,"port":"default"}`))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/workflows/wf-1/executions/diff?a="+execA+"&b="+execB, nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var diff api.ExecutionDiffResponse
+	if err := json.NewDecoder(rec.Body).Decode(&diff); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if diff.A.ID != execA || diff.B.ID != execB {
+		t.Errorf("unexpected executions: %s, %s", diff.A.ID, diff.B.ID)
+	}
+	var statuses []string
+	for _, n := range diff.Nodes {
+		statuses = append(statuses, n.NodeID+":"+n.Status)
+	}
+	if got := strings.Join(statuses, " "); got != "start:unchanged fetch:changed notify:removed alert:added" {
+		t.Fatalf("unexpected node statuses: %s", got)
+	}
+
+	var changes []string
+	for _, c := range diff.Nodes[1].Changes {
+		changes = append(changes, fmt.Sprintf("%s %s %s>%s", c.Change, c.Path, string(c.A), string(c.B)))
+	}
+	want := []string{
+		"removed data.body.etag \"x\">",
+		"removed data.body.items[1] 2>",
+		"changed data.body.price 10>12",
+		"added data.body.retry >1",
+		"changed port \"success\">\"server_error\"",
+	}
+	if strings.Join(changes, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected changes:\n%s", strings.Join(changes, "\n"))
+	}
+
+	// Executions of another workflow, and missing parameters
+	for url, code := range map[string]int{
+		"/api/workflows/wf-1/executions/diff?a=" + execA + "&b=" + other: http.StatusNotFound,
+		"/api/workflows/wf-1/executions/diff?a=" + execA:                 http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != code {
+			t.Errorf("%s: expected status %d, got %d", url, code, rec.Code)
+		}
+	}
+}
+
+func TestExecutionAPI_SearchContent(t *testing.T) {
+	mux, store := newExecutionMux(t)
+	store.CreateWorkflow(testCtx, &storage.Workflow{ID: "wf-1", Name: "Orders", Definition: []byte(`{}`)})
+	execID, _ := store.CreateExecution(testCtx, "wf-1")
+	store.SaveNodeResult(testCtx, execID, "fetch", []byte(`{"data":{"order_id":12345}}`))
+	other, _ := store.CreateExecution(testCtx, "wf-1")
+	store.SaveNodeResult(testCtx, other, "fetch", []byte(`{"data":{"order_id":999}}`))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/executions/search?q=order+12345", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var hits []api.ExecutionSearchHit
+	if err := json.NewDecoder(rec.Body).Decode(&hits); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(hits) != 1 || hits[0].ExecutionID != execID || hits[0].WorkflowID != "wf-1" || hits[0].NodeID != "fetch" || hits[0].Field != "result" {
+		t.Fatalf("expected the execution that processed the order, got %+v", hits)
+	}
+	if !strings.Contains(hits[0].Snippet, "<mark>12345</mark>") {
+		t.Errorf("expected the snippet to mark the match, got %q", hits[0].Snippet)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/executions/search?q=+", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without a query, got %d", rec.Code)
 	}
 }
 
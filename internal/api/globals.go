@@ -0,0 +1,203 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+var (
+	// environmentNamePattern keeps environment names usable in URLs and query strings
+	environmentNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	// globalNamePattern keeps variable names addressable as $globals.<name>
+	globalNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// GlobalsHandler manages server-wide global variables ($globals) and the named
+// environments (dev, staging, prod, ...) that override them per execution.
+type GlobalsHandler struct {
+	Store storage.Storage
+}
+
+// NewGlobalsHandler creates a new globals handler
+func NewGlobalsHandler(store storage.Storage) *GlobalsHandler {
+	return &GlobalsHandler{Store: store}
+}
+
+// CreateEnvironmentRequest represents the request body for creating an environment
+type CreateEnvironmentRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// GlobalVariableResponse is a global variable with its value decoded as JSON
+type GlobalVariableResponse struct {
+	Environment string          `json:"environment,omitempty"`
+	Name        string          `json:"name"`
+	Value       json.RawMessage `json:"value"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// SetGlobalVariableRequest represents the request body for setting a global variable
+type SetGlobalVariableRequest struct {
+	Value json.RawMessage `json:"value"`
+}
+
+// ListEnvironments handles GET /api/environments
+func (h *GlobalsHandler) ListEnvironments(w http.ResponseWriter, r *http.Request) {
+	envs, err := h.Store.ListEnvironments(r.Context())
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to list environments: "+err.Error())
+		return
+	}
+	if envs == nil {
+		envs = []*storage.Environment{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(envs)
+}
+
+// CreateEnvironment handles POST /api/environments
+func (h *GlobalsHandler) CreateEnvironment(w http.ResponseWriter, r *http.Request) {
+	var req CreateEnvironmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+	if !environmentNamePattern.MatchString(req.Name) {
+		WriteError(w, http.StatusUnprocessableEntity, "name is required and may only contain letters, digits, '-' and '_'")
+		return
+	}
+	if _, err := h.Store.GetEnvironment(r.Context(), req.Name); err == nil {
+		WriteError(w, http.StatusConflict, "Environment already exists: "+req.Name)
+		return
+	}
+
+	env := &storage.Environment{Name: req.Name, Description: req.Description}
+	if err := h.Store.CreateEnvironment(r.Context(), env); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to create environment: "+err.Error())
+		return
+	}
+	created, err := h.Store.GetEnvironment(r.Context(), req.Name)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get environment: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// DeleteEnvironment handles DELETE /api/environments/{name}
+// The environment's variable overrides are deleted with it.
+func (h *GlobalsHandler) DeleteEnvironment(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, err := h.Store.GetEnvironment(r.Context(), name); err != nil {
+		WriteError(w, http.StatusNotFound, "Environment not found: "+name)
+		return
+	}
+	if err := h.Store.DeleteEnvironment(r.Context(), name); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to delete environment: "+err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListGlobals handles GET /api/globals?environment={name}
+// Without environment it lists the shared defaults; with one, only that environment's overrides.
+func (h *GlobalsHandler) ListGlobals(w http.ResponseWriter, r *http.Request) {
+	env, ok := h.environmentParam(w, r)
+	if !ok {
+		return
+	}
+
+	vars, err := h.Store.ListGlobalVariables(r.Context(), env)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to list global variables: "+err.Error())
+		return
+	}
+
+	resp := make([]GlobalVariableResponse, len(vars))
+	for i, v := range vars {
+		resp[i] = toGlobalVariableResponse(v)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// SetGlobal handles PUT /api/globals/{name}?environment={name}
+// Body: {"value": <any JSON>}
+func (h *GlobalsHandler) SetGlobal(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !globalNamePattern.MatchString(name) {
+		WriteError(w, http.StatusUnprocessableEntity, "Invalid variable name: use letters, digits and '_', not starting with a digit")
+		return
+	}
+	env, ok := h.environmentParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req SetGlobalVariableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+	if len(req.Value) == 0 {
+		WriteError(w, http.StatusUnprocessableEntity, "value is required")
+		return
+	}
+
+	v := &storage.GlobalVariable{Environment: env, Name: name, Value: req.Value}
+	if err := h.Store.SetGlobalVariable(r.Context(), v); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to set global variable: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GlobalVariableResponse{Environment: env, Name: name, Value: req.Value, UpdatedAt: time.Now().UTC()})
+}
+
+// DeleteGlobal handles DELETE /api/globals/{name}?environment={name}
+func (h *GlobalsHandler) DeleteGlobal(w http.ResponseWriter, r *http.Request) {
+	env, ok := h.environmentParam(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Store.DeleteGlobalVariable(r.Context(), env, r.PathValue("name")); err != nil {
+		WriteError(w, http.StatusNotFound, "Global variable not found: "+err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// environmentParam reads the optional ?environment= query parameter and checks
+// that the environment exists. It writes the error response when it returns false.
+func (h *GlobalsHandler) environmentParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	env := r.URL.Query().Get("environment")
+	if env == "" {
+		return "", true
+	}
+	if _, err := h.Store.GetEnvironment(r.Context(), env); err != nil {
+		WriteError(w, http.StatusNotFound, "Environment not found: "+env)
+		return "", false
+	}
+	return env, true
+}
+
+func toGlobalVariableResponse(v *storage.GlobalVariable) GlobalVariableResponse {
+	return GlobalVariableResponse{
+		Environment: v.Environment,
+		Name:        v.Name,
+		Value:       json.RawMessage(v.Value),
+		UpdatedAt:   v.UpdatedAt,
+	}
+}
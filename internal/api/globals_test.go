@@ -0,0 +1,111 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/api"
+)
+
+func newGlobalsMux(t *testing.T) *http.ServeMux {
+	handler := api.NewGlobalsHandler(newTestStorage(t))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/environments", handler.ListEnvironments)
+	mux.HandleFunc("POST /api/environments", handler.CreateEnvironment)
+	mux.HandleFunc("DELETE /api/environments/{name}", handler.DeleteEnvironment)
+	mux.HandleFunc("GET /api/globals", handler.ListGlobals)
+	mux.HandleFunc("PUT /api/globals/{name}", handler.SetGlobal)
+	mux.HandleFunc("DELETE /api/globals/{name}", handler.DeleteGlobal)
+	return mux
+}
+
+func TestGlobalsAPI_Environments(t *testing.T) {
+	mux := newGlobalsMux(t)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/api/environments", `{"name":"staging","description":"Pre-release"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost, "/api/environments", `{"name":"staging"}`); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for duplicate environment, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/environments", `{"name":"bad name"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for invalid name, got %d", rec.Code)
+	}
+
+	rec := do(http.MethodGet, "/api/environments", "")
+	var envs []map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&envs); err != nil || len(envs) != 1 || envs[0]["name"] != "staging" {
+		t.Fatalf("expected staging environment, got %v (%v)", envs, err)
+	}
+
+	if rec := do(http.MethodDelete, "/api/environments/staging", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/api/environments/staging", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for deleted environment, got %d", rec.Code)
+	}
+}
+
+func TestGlobalsAPI_Variables(t *testing.T) {
+	mux := newGlobalsMux(t)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+		return rec
+	}
+	list := func(path string) []api.GlobalVariableResponse {
+		rec := do(http.MethodGet, path, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200 for %s, got %d", path, rec.Code)
+		}
+		var vars []api.GlobalVariableResponse
+		if err := json.NewDecoder(rec.Body).Decode(&vars); err != nil {
+			t.Fatalf("failed to decode %s: %v", path, err)
+		}
+		return vars
+	}
+
+	do(http.MethodPost, "/api/environments", `{"name":"prod"}`)
+
+	if rec := do(http.MethodPut, "/api/globals/apiBaseUrl", `{"value":"https://api.example.com"}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPut, "/api/globals/limits?environment=prod", `{"value":{"pageSize":100}}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	defaults := list("/api/globals")
+	if len(defaults) != 1 || defaults[0].Name != "apiBaseUrl" || string(defaults[0].Value) != `"https://api.example.com"` {
+		t.Errorf("unexpected defaults %+v", defaults)
+	}
+	prod := list("/api/globals?environment=prod")
+	if len(prod) != 1 || prod[0].Environment != "prod" || string(prod[0].Value) != `{"pageSize":100}` {
+		t.Errorf("unexpected prod variables %+v", prod)
+	}
+
+	if rec := do(http.MethodPut, "/api/globals/x?environment=missing", `{"value":1}`); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown environment, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/api/globals/a.b", `{"value":1}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for invalid name, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/api/globals/empty", `{}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for missing value, got %d", rec.Code)
+	}
+
+	if rec := do(http.MethodDelete, "/api/globals/apiBaseUrl", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/api/globals/apiBaseUrl", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for deleted variable, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// checkTimeout bounds each individual readiness check so a hung dependency
+// cannot stall the probe.
+const checkTimeout = 3 * time.Second
+
+// runtimeCheckTTL is how long the outcome of the runtime check is reused, so
+// that frequent probes don't start a runtime process each.
+const runtimeCheckTTL = 30 * time.Second
+
+// HealthHandler serves liveness and readiness probes.
+type HealthHandler struct {
+	Store     storage.Storage
+	BlocksDir string
+	// Runtime is the script runtime to check (defaults to the configured one,
+	// resolved when the handler is created)
+	Runtime engine.Runtime
+	// Triggers is optional; when set, per-trigger runner status is reported
+	Triggers *engine.TriggerManager
+
+	runtimeMu      sync.Mutex
+	runtimeResult  CheckResult
+	runtimeChecked time.Time
+}
+
+func NewHealthHandler(store storage.Storage, blocksDir string, triggers *engine.TriggerManager) *HealthHandler {
+	return &HealthHandler{
+		Store:     store,
+		BlocksDir: blocksDir,
+		Runtime:   engine.CurrentRuntime(),
+		Triggers:  triggers,
+	}
+}
+
+// CheckResult is the outcome of a single readiness check.
+type CheckResult struct {
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type ReadinessResponse struct {
+	Status   string                 `json:"status"`
+	Checks   map[string]CheckResult `json:"checks"`
+	Triggers []engine.TriggerStatus `json:"triggers,omitempty"`
+	// Maintenance is set while the server is in maintenance, with how far it drained
+	Maintenance *engine.MaintenanceState `json:"maintenance,omitempty"`
+}
+
+// Livez reports that the process is up and serving HTTP. It performs no
+// dependency checks so orchestrators don't restart the server over a DB blip.
+func (h *HealthHandler) Livez(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "OK"})
+}
+
+// Readyz verifies the database, the script runtime and the blocks directory.
+// Responds 503 when any of them fails. Trigger runner status is reported
+// but does not affect readiness: one crashed trigger shouldn't take the API out of rotation.
+// A server in maintenance answers 503 with status MAINTENANCE, so that it is
+// taken out of rotation while its executions drain.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	resp := ReadinessResponse{
+		Status: "OK",
+		Checks: map[string]CheckResult{
+			"database":   h.checkDatabase(r.Context()),
+			"runtime":    h.checkRuntime(r.Context()),
+			"blocks_dir": h.checkBlocksDir(),
+		},
+	}
+	if h.Triggers != nil {
+		resp.Triggers = h.Triggers.Statuses()
+	}
+
+	code := http.StatusOK
+	for _, c := range resp.Checks {
+		if !c.OK {
+			resp.Status = "FAIL"
+			code = http.StatusServiceUnavailable
+			break
+		}
+	}
+	if h.Triggers != nil {
+		if state := h.Triggers.Maintenance(); state.Enabled {
+			resp.Maintenance = &state
+			if code == http.StatusOK {
+				resp.Status = "MAINTENANCE"
+				code = http.StatusServiceUnavailable
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *HealthHandler) checkDatabase(ctx context.Context) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	if err := h.Store.Ping(ctx); err != nil {
+		return CheckResult{Error: err.Error()}
+	}
+	return CheckResult{OK: true}
+}
+
+// checkRuntime reports whether the runtime can run scripts, reusing the last
+// outcome for runtimeCheckTTL. Concurrent probes wait for a single check.
+func (h *HealthHandler) checkRuntime(ctx context.Context) CheckResult {
+	h.runtimeMu.Lock()
+	defer h.runtimeMu.Unlock()
+	if !h.runtimeChecked.IsZero() && time.Since(h.runtimeChecked) < runtimeCheckTTL {
+		return h.runtimeResult
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	rt := h.Runtime
+	if rt == nil {
+		rt = engine.BunRuntime{}
+	}
+	var result CheckResult
+	if version, err := rt.Check(ctx); err != nil {
+		result = CheckResult{Error: err.Error()}
+	} else {
+		result = CheckResult{OK: true, Detail: fmt.Sprintf("%s %s", rt.Name(), version)}
+	}
+	// A probe that went away says nothing about the runtime
+	if ctx.Err() == nil || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		h.runtimeResult, h.runtimeChecked = result, time.Now()
+	}
+	return result
+}
+
+func (h *HealthHandler) checkBlocksDir() CheckResult {
+	info, err := os.Stat(h.BlocksDir)
+	if err != nil {
+		return CheckResult{Error: err.Error()}
+	}
+	if !info.IsDir() {
+		return CheckResult{Error: h.BlocksDir + " is not a directory"}
+	}
+	if _, err := os.ReadDir(h.BlocksDir); err != nil {
+		return CheckResult{Error: err.Error()}
+	}
+	return CheckResult{OK: true, Detail: h.BlocksDir}
+}
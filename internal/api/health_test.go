@@ -0,0 +1,106 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/api"
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// writeFakeBunVersion creates an executable that answers `--version` like bun
+// does, and records each call in the file calls next to it.
+func writeFakeBunVersion(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bun")
+	script := "#!/bin/sh\necho call >> " + filepath.Join(dir, "calls") + "\necho 1.1.0\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake bun: %v", err)
+	}
+	return path
+}
+
+func newHealthMux(t *testing.T, bunPath string) *http.ServeMux {
+	store := newTestStorage(t)
+	tm := engine.NewTriggerManager(store, t.TempDir(), engine.NewExecutionRegistry(), engine.NewWorkerPool(1))
+	tm.Register(engine.NewWebhookTrigger("tr-1", "wf-1", tm))
+
+	handler := api.NewHealthHandler(store, t.TempDir(), tm)
+	handler.Runtime = engine.BunRuntime{Path: bunPath}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /livez", handler.Livez)
+	mux.HandleFunc("GET /readyz", handler.Readyz)
+	return mux
+}
+
+func TestHealthAPI_Livez(t *testing.T) {
+	mux := newHealthMux(t, "/nonexistent/bun")
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+}
+
+func TestHealthAPI_Readyz(t *testing.T) {
+	t.Run("Ready", func(t *testing.T) {
+		bunPath := writeFakeBunVersion(t)
+		mux := newHealthMux(t, bunPath)
+
+		for i := 0; i < 2; i++ {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			var resp api.ReadinessResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Checks["runtime"].Detail != "bun 1.1.0" {
+				t.Errorf("expected runtime bun 1.1.0, got %q", resp.Checks["runtime"].Detail)
+			}
+			if len(resp.Triggers) != 1 || resp.Triggers[0].ID != "tr-1" || !resp.Triggers[0].Healthy {
+				t.Errorf("expected healthy trigger tr-1, got %+v", resp.Triggers)
+			}
+		}
+		// The second probe reuses the first one's runtime check
+		calls, _ := os.ReadFile(filepath.Join(filepath.Dir(bunPath), "calls"))
+		if n := strings.Count(string(calls), "call"); n != 1 {
+			t.Errorf("expected the runtime to be run once, got %d", n)
+		}
+	})
+
+	t.Run("RuntimeMissing", func(t *testing.T) {
+		mux := newHealthMux(t, "/nonexistent/bun")
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected 503, got %d", rec.Code)
+		}
+
+		var resp api.ReadinessResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Checks["runtime"].OK {
+			t.Error("expected runtime check to fail")
+		}
+		if !resp.Checks["database"].OK || !resp.Checks["blocks_dir"].OK {
+			t.Errorf("expected database and blocks_dir checks to pass, got %+v", resp.Checks)
+		}
+	})
+}
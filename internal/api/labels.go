@@ -0,0 +1,68 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// CorrelationIDHeader carries the correlation ID of the executions a run or
+// fire request starts (see engine.ExecutionLabels).
+const CorrelationIDHeader = "X-Correlation-ID"
+
+// LabelsHeader carries labels for the executions a run or fire request starts,
+// as comma-separated key=value pairs: "order_id=A-17, team=billing".
+const LabelsHeader = "X-Execution-Labels"
+
+// LabelsFromHeaders reads the correlation ID and labels of a request from its
+// headers, returning *engine.FieldErrors for invalid ones.
+func LabelsFromHeaders(h http.Header) (engine.ExecutionLabels, error) {
+	l := engine.ExecutionLabels{CorrelationID: strings.TrimSpace(h.Get(CorrelationIDHeader))}
+	for _, value := range h.Values(LabelsHeader) {
+		for _, pair := range strings.Split(value, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			key, val, err := splitLabel(pair)
+			if err != nil {
+				return l, &engine.FieldError{Field: LabelsHeader, Message: err.Error()}
+			}
+			if l.Labels == nil {
+				l.Labels = make(map[string]string)
+			}
+			l.Labels[key] = val
+		}
+	}
+	return l, l.Validate()
+}
+
+// splitLabel splits a "key=value" label.
+func splitLabel(pair string) (key, value string, err error) {
+	key, value, ok := strings.Cut(pair, "=")
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if !ok || key == "" {
+		return "", "", fmt.Errorf("label %q is not key=value", strings.TrimSpace(pair))
+	}
+	return key, value, nil
+}
+
+// executionFilter reads the correlation_id and label=key=value parameters of
+// an executions listing.
+func executionFilter(query url.Values) (storage.ExecutionFilter, error) {
+	filter := storage.ExecutionFilter{CorrelationID: query.Get("correlation_id")}
+	for _, pair := range query["label"] {
+		key, value, err := splitLabel(pair)
+		if err != nil {
+			return filter, &engine.FieldError{Field: "label", Message: err.Error()}
+		}
+		if filter.Labels == nil {
+			filter.Labels = make(map[string]string)
+		}
+		filter.Labels[key] = value
+	}
+	return filter, nil
+}
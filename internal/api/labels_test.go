@@ -0,0 +1,84 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/api"
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func TestExecutionLabels_Webhook(t *testing.T) {
+	mux, store, _ := newTriggerMux(t)
+	execHandler := api.NewExecutionHandler(store)
+	mux.HandleFunc("GET /api/workflows/{id}/executions", execHandler.ListByWorkflow)
+	mux.HandleFunc("GET /api/executions", execHandler.Search)
+
+	def, _ := json.Marshal(engine.Workflow{ID: "wf-1", Nodes: map[string]engine.Node{
+		"mark": {ID: "mark", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "seen", "value": true}},
+	}})
+	store.CreateWorkflow(testCtx, &storage.Workflow{ID: "wf-1", Name: "Test Workflow", Definition: def})
+	store.CreateExecution(testCtx, "wf-1") // An untagged run
+	do := func(method, path, body string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for k, values := range header {
+			for _, v := range values {
+				req.Header.Add(k, v)
+			}
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := do(http.MethodPost, "/api/triggers", `{"id":"hook","workflow_id":"wf-1","type":"webhook","enabled":true}`, nil); rec.Code != http.StatusCreated {
+		t.Fatalf("failed to create trigger: %d %s", rec.Code, rec.Body.String())
+	}
+
+	bad := http.Header{api.LabelsHeader: {"order id=17"}}
+	if rec := do(http.MethodPost, "/api/webhooks/hook", `{}`, bad); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422 for an invalid label, got %d", rec.Code)
+	}
+	tags := http.Header{api.CorrelationIDHeader: {"order-17"}, api.LabelsHeader: {"team=billing, region=eu"}}
+	if rec := do(http.MethodPost, "/api/webhooks/hook", `{}`, tags); rec.Code != http.StatusOK {
+		t.Fatalf("webhook failed: %d %s", rec.Code, rec.Body.String())
+	}
+
+	list := func(path string) []api.ExecutionResponse {
+		t.Helper()
+		rec := do(http.MethodGet, path, "", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s failed: %d %s", path, rec.Code, rec.Body.String())
+		}
+		var execs []api.ExecutionResponse
+		json.NewDecoder(rec.Body).Decode(&execs)
+		return execs
+	}
+	var execs []api.ExecutionResponse
+	for deadline := time.Now().Add(5 * time.Second); len(execs) == 0 && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		execs = list("/api/executions?correlation_id=order-17")
+	}
+	if len(execs) != 1 || execs[0].CorrelationID != "order-17" || execs[0].Labels["team"] != "billing" || execs[0].Labels["region"] != "eu" {
+		t.Fatalf("expected the webhook's execution to be tagged, got %+v", execs)
+	}
+
+	if got := list("/api/workflows/wf-1/executions?label=team=billing&label=region=eu"); len(got) != 1 || got[0].ID != execs[0].ID {
+		t.Errorf("expected the tagged execution of the workflow, got %+v", got)
+	}
+	if got := list("/api/workflows/wf-1/executions"); len(got) != 2 {
+		t.Errorf("expected every execution without a filter, got %d", len(got))
+	}
+	if got := list("/api/executions?label=team=ops"); len(got) != 0 {
+		t.Errorf("expected no executions for another label value, got %+v", got)
+	}
+	if rec := do(http.MethodGet, "/api/executions", "", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without a filter, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/executions?label=team", "", nil); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422 for a label without a value, got %d", rec.Code)
+	}
+}
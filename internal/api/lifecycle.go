@@ -16,6 +16,8 @@ type LifecycleHandler struct {
 	Store     storage.Storage
 	Registry  *engine.ExecutionRegistry
 	BlocksDir string
+	Events    *engine.EventBus // Optional; restarted executions publish here
+	Quotas    *engine.Quotas   // Optional; restarts count against the workflow's quota
 }
 
 // NewLifecycleHandler creates a new lifecycle handler
@@ -27,85 +29,169 @@ func NewLifecycleHandler(store storage.Storage, registry *engine.ExecutionRegist
 	}
 }
 
+// ActiveExecutionResponse is an execution running right now.
+type ActiveExecutionResponse struct {
+	ID          string    `json:"id"`
+	WorkflowID  string    `json:"workflow_id"`
+	TriggerID   string    `json:"trigger_id,omitempty"`
+	CurrentNode string    `json:"current_node,omitempty"`
+	StartedAt   time.Time `json:"started_at"`
+	ElapsedMS   int64     `json:"elapsed_ms"`
+	// HeartbeatAt is the execution's last recorded sign of life, if any yet
+	HeartbeatAt *time.Time `json:"heartbeat_at,omitempty"`
+}
+
+// ListActive handles GET /api/executions/active. It lists the executions
+// running in this server, oldest first, with the node each is running.
+// Executions waiting in a delay node hold no worker and aren't listed.
+func (h *LifecycleHandler) ListActive(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	resp := []ActiveExecutionResponse{}
+	for _, active := range h.Registry.Active() {
+		entry := ActiveExecutionResponse{
+			ID:         active.ExecutionID,
+			WorkflowID: active.WorkflowID,
+			TriggerID:  active.TriggerID,
+			StartedAt:  active.StartedAt,
+		}
+		// Storage has the start of runs resumed after a delay, and the node timeline
+		if exec, err := h.Store.GetExecution(r.Context(), active.ExecutionID); err == nil {
+			entry.WorkflowID = exec.WorkflowID
+			entry.StartedAt = exec.StartedAt
+			entry.HeartbeatAt = exec.HeartbeatAt
+		}
+		if nodes, err := h.Store.ListNodeExecutions(r.Context(), active.ExecutionID); err == nil {
+			for _, ne := range nodes {
+				if ne.Status == storage.NodeStatusRunning {
+					entry.CurrentNode = ne.NodeID
+				}
+			}
+		}
+		entry.ElapsedMS = now.Sub(entry.StartedAt).Milliseconds()
+		resp = append(resp, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
 // StopExecution handles POST /api/executions/{id}/stop
 // Cancels a running execution gracefully
 func (h *LifecycleHandler) StopExecution(w http.ResponseWriter, r *http.Request) {
 	execID := r.PathValue("id")
 	if execID == "" {
-		http.Error(w, "Missing execution ID", http.StatusBadRequest)
+		WriteError(w, http.StatusBadRequest, "Missing execution ID")
 		return
 	}
 
 	// Check if execution exists
 	exec, err := h.Store.GetExecution(r.Context(), execID)
 	if err != nil {
-		http.Error(w, "Execution not found: "+err.Error(), http.StatusNotFound)
+		WriteError(w, http.StatusNotFound, "Execution not found: "+err.Error())
 		return
 	}
 
-	// Check if execution is still running
-	if exec.Status != storage.ExecutionStatusRunning {
-		http.Error(w, fmt.Sprintf("Execution is not running (status: %s)", exec.Status), http.StatusBadRequest)
-		return
+	// The stop is carried out even if the client disconnects meanwhile
+	ctx, cancel := storage.Detach(r.Context())
+	defer cancel()
+	stopped, err := h.stop(ctx, exec, "Execution stopped by user")
+	if err == nil && !stopped {
+		// The status changed under us (e.g. a waiting execution was resumed); retry once
+		if exec, err = h.Store.GetExecution(ctx, execID); err == nil {
+			stopped, err = h.stop(ctx, exec, "Execution stopped by user")
+		}
 	}
-
-	// Cancel the execution via registry
-	if err := h.Registry.Cancel(execID); err != nil {
-		// Execution might have already completed between the check and cancel
-		http.Error(w, "Failed to stop execution: "+err.Error(), http.StatusInternalServerError)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to stop execution: "+err.Error())
 		return
 	}
-
-	// Update status in database
-	// Note: The workflow runner should also update this when context is cancelled
-	msg := "Execution stopped by user"
-	if err := h.Store.UpdateExecutionStatus(r.Context(), execID, storage.ExecutionStatusCancelled, exec.State, &msg); err != nil {
-		// Log error but don't fail the request - execution was already cancelled
-		fmt.Printf("Warning: failed to update execution status: %v\n", err)
+	if !stopped {
+		WriteError(w, http.StatusConflict, fmt.Sprintf("Execution is not running (status: %s)", exec.Status))
+		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// stop stops exec, given its status as last read. A running execution is
+// cancelled through the registry and its runner records the cancellation, so
+// the status may still read running for a moment. Waiting executions, and
+// running ones left without a runner (e.g. by a crash), are cancelled in
+// storage with a conditional update. Returns false when exec no longer has
+// the status it was read with, or isn't running or waiting.
+func (h *LifecycleHandler) stop(ctx context.Context, exec *storage.Execution, msg string) (bool, error) {
+	switch exec.Status {
+	case storage.ExecutionStatusRunning:
+		if err := h.Registry.Cancel(exec.ID); err == nil {
+			return true, nil
+		}
+		// Not running in this process; it may also have just finished
+		return h.Store.TransitionExecutionStatus(ctx, exec.ID, storage.ExecutionStatusRunning, storage.ExecutionStatusCancelled, nil, &msg)
+	case storage.ExecutionStatusWaiting:
+		// A waiting execution holds no worker; cancelling it in storage keeps it from being resumed
+		return h.Store.TransitionExecutionStatus(ctx, exec.ID, storage.ExecutionStatusWaiting, storage.ExecutionStatusCancelled, nil, &msg)
+	}
+	return false, nil
+}
+
 // RestartExecution handles POST /api/executions/{id}/restart
 // Restarts a completed or failed execution with a new execution ID
 func (h *LifecycleHandler) RestartExecution(w http.ResponseWriter, r *http.Request) {
 	execID := r.PathValue("id")
 	if execID == "" {
-		http.Error(w, "Missing execution ID", http.StatusBadRequest)
+		WriteError(w, http.StatusBadRequest, "Missing execution ID")
 		return
 	}
 
 	// Get the original execution
 	exec, err := h.Store.GetExecution(r.Context(), execID)
 	if err != nil {
-		http.Error(w, "Execution not found: "+err.Error(), http.StatusNotFound)
+		WriteError(w, http.StatusNotFound, "Execution not found: "+err.Error())
 		return
 	}
 
 	// Check if execution can be restarted (must not be running)
 	if exec.Status == storage.ExecutionStatusRunning {
-		http.Error(w, "Cannot restart a running execution. Stop it first.", http.StatusBadRequest)
+		WriteError(w, http.StatusConflict, "Cannot restart a running execution. Stop it first.")
 		return
 	}
 
 	// Get the workflow definition
 	workflow, err := h.Store.GetWorkflow(r.Context(), exec.WorkflowID)
 	if err != nil {
-		http.Error(w, "Workflow not found: "+err.Error(), http.StatusNotFound)
+		WriteError(w, http.StatusNotFound, "Workflow not found: "+err.Error())
 		return
 	}
 
 	// Parse workflow definition
 	var wf engine.Workflow
 	if err := json.Unmarshal(workflow.Definition, &wf); err != nil {
-		http.Error(w, "Failed to parse workflow: "+err.Error(), http.StatusInternalServerError)
+		WriteError(w, http.StatusInternalServerError, "Failed to parse workflow: "+err.Error())
 		return
 	}
 
-	// Create new execution context
+	// Create new execution context; $trigger resolves to the original payload
 	ctx := engine.NewExecutionContext(wf.ID)
+	if len(exec.TriggerData) > 0 {
+		if err := json.Unmarshal(exec.TriggerData, &ctx.TriggerData); err != nil {
+			WriteError(w, http.StatusInternalServerError, "Failed to parse trigger data: "+err.Error())
+			return
+		}
+	}
 	runner := engine.NewWorkflowRunner(ctx, h.BlocksDir, h.Store, h.Registry)
+	runner.SetEventBus(h.Events)
+	runner.SetQuotas(h.Quotas)
+
+	// Create the new execution up front so its ID can be returned; it can be
+	// stopped from now on
+	newExecID, err := runner.CreateExecution(r.Context(), wf.ID)
+	if WriteQuotaError(w, err) {
+		return
+	}
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to restart workflow: "+err.Error())
+		return
+	}
 
 	// Run workflow asynchronously
 	go func() {
@@ -117,12 +203,11 @@ func (h *LifecycleHandler) RestartExecution(w http.ResponseWriter, r *http.Reque
 		}
 	}()
 
-	// Return the new execution ID (created by runner)
-	// Note: We need to modify the runner to return execution ID
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"message":               "Workflow restarted successfully",
+		"execution_id":          newExecID,
 		"original_execution_id": execID,
 		"status":                "running",
 	})
@@ -136,28 +221,33 @@ func (h *LifecycleHandler) BatchStopExecutions(w http.ResponseWriter, r *http.Re
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		WriteError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
 		return
 	}
 
 	if len(req.ExecutionIDs) == 0 {
-		http.Error(w, "No execution IDs provided", http.StatusBadRequest)
+		WriteError(w, http.StatusUnprocessableEntity, "No execution IDs provided")
 		return
 	}
 
+	// Like StopExecution, every stop is carried out even if the client disconnects
+	ctx, cancel := storage.Detach(r.Context())
+	defer cancel()
 	results := make(map[string]string)
 	for _, execID := range req.ExecutionIDs {
-		if err := h.Registry.Cancel(execID); err != nil {
+		exec, err := h.Store.GetExecution(ctx, execID)
+		if err != nil {
 			results[execID] = "failed: " + err.Error()
-		} else {
+			continue
+		}
+		stopped, err := h.stop(ctx, exec, "Execution stopped by batch operation")
+		switch {
+		case err != nil:
+			results[execID] = "failed: " + err.Error()
+		case !stopped:
+			results[execID] = fmt.Sprintf("failed: execution is not running (status: %s)", exec.Status)
+		default:
 			results[execID] = "stopped"
-
-			// Update status in database
-			msg := "Execution stopped by batch operation"
-			exec, _ := h.Store.GetExecution(r.Context(), execID)
-			if exec != nil {
-				h.Store.UpdateExecutionStatus(r.Context(), execID, storage.ExecutionStatusCancelled, exec.State, &msg)
-			}
 		}
 	}
 
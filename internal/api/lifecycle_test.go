@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/conv3n/conv3n/internal/api"
 	"github.com/conv3n/conv3n/internal/engine"
@@ -23,6 +25,7 @@ func newLifecycleMux(t *testing.T) (*http.ServeMux, storage.Storage, *engine.Exe
 	mux.HandleFunc("POST /api/executions/{id}/stop", handler.StopExecution)
 	mux.HandleFunc("POST /api/executions/{id}/restart", handler.RestartExecution)
 	mux.HandleFunc("POST /api/executions/batch/stop", handler.BatchStopExecutions)
+	mux.HandleFunc("GET /api/executions/active", handler.ListActive)
 
 	return mux, store, registry
 }
@@ -31,13 +34,29 @@ func TestLifecycleAPI_StopExecution(t *testing.T) {
 	mux, store, registry := newLifecycleMux(t)
 	ctx := testCtx
 
-	// Create running execution
-	execID, _ := store.CreateExecution(ctx, "wf-1")
+	// A run sleeping in a delay node until stopped
+	wf := engine.Workflow{
+		ID: "wf-stop",
+		Nodes: map[string]engine.Node{
+			"wait": {ID: "wait", Type: engine.NodeTypeDelay, Config: map[string]interface{}{"duration": 30.0}},
+		},
+	}
+	def, _ := json.Marshal(wf)
+	store.CreateWorkflow(ctx, &storage.Workflow{ID: wf.ID, Name: "Stop", Definition: def})
+	runner := engine.NewWorkflowRunner(engine.NewExecutionContext(wf.ID), t.TempDir(), store, registry)
+	done := make(chan error, 1)
+	go func() { done <- runner.Run(context.Background(), wf) }()
 
-	// Register it as active
-	_, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	registry.Register(execID, cancel)
+	var execID string
+	for deadline := time.Now().Add(5 * time.Second); execID == "" && time.Now().Before(deadline); {
+		if execs, _ := store.ListExecutions(ctx, wf.ID, 1); len(execs) == 1 && registry.IsActive(execs[0].ID) {
+			execID = execs[0].ID
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if execID == "" {
+		t.Fatal("execution never became active")
+	}
 
 	// Stop it
 	req := httptest.NewRequest(http.MethodPost, "/api/executions/"+execID+"/stop", nil)
@@ -48,16 +67,81 @@ func TestLifecycleAPI_StopExecution(t *testing.T) {
 		t.Errorf("expected status 204, got %d: %s", rec.Code, rec.Body.String())
 	}
 
-	// Verify it's cancelled in registry
+	// The runner records the cancellation
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("runner did not stop")
+	}
 	if registry.IsActive(execID) {
 		t.Error("execution should be removed from registry")
 	}
+	exec, _ := store.GetExecution(ctx, execID)
+	if exec.Status != storage.ExecutionStatusCancelled {
+		t.Errorf("expected status cancelled, got %s", exec.Status)
+	}
+
+	// Stopping it again finds it no longer running
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/executions/"+execID+"/stop", nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", rec.Code)
+	}
+}
+
+// TestLifecycleAPI_StopFinishedExecution verifies that a stop never overwrites
+// a terminal status, while a running execution without a runner is cancelled.
+func TestLifecycleAPI_StopFinishedExecution(t *testing.T) {
+	mux, store, _ := newLifecycleMux(t)
+	ctx := testCtx
+
+	completed, _ := store.CreateExecution(ctx, "wf-1")
+	store.UpdateExecutionStatus(ctx, completed, storage.ExecutionStatusCompleted, []byte(`{}`), nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/executions/"+completed+"/stop", nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", rec.Code)
+	}
+	if exec, _ := store.GetExecution(ctx, completed); exec.Status != storage.ExecutionStatusCompleted {
+		t.Errorf("expected status completed, got %s", exec.Status)
+	}
+
+	// Left running by a server that went away
+	orphan, _ := store.CreateExecution(ctx, "wf-1")
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/executions/"+orphan+"/stop", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if exec, _ := store.GetExecution(ctx, orphan); exec.Status != storage.ExecutionStatusCancelled {
+		t.Errorf("expected status cancelled, got %s", exec.Status)
+	}
+}
+
+func TestLifecycleAPI_StopWaitingExecution(t *testing.T) {
+	mux, store, _ := newLifecycleMux(t)
+	ctx := testCtx
+
+	// A waiting execution is parked in storage, not in the registry
+	execID, _ := store.CreateExecution(ctx, "wf-1")
+	if err := store.SuspendExecution(ctx, execID, []byte(`{}`), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("failed to suspend execution: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/executions/"+execID+"/stop", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d: %s", rec.Code, rec.Body.String())
+	}
 
-	// Verify status in DB
 	exec, _ := store.GetExecution(ctx, execID)
 	if exec.Status != storage.ExecutionStatusCancelled {
 		t.Errorf("expected status cancelled, got %s", exec.Status)
 	}
+	// A cancelled execution is never resumed
+	if claimed, _ := store.ClaimDueExecutions(ctx, time.Now().Add(2*time.Hour), 10); len(claimed) != 0 {
+		t.Errorf("expected no executions to resume, got %d", len(claimed))
+	}
 }
 
 func TestLifecycleAPI_RestartExecution(t *testing.T) {
@@ -66,15 +150,18 @@ func TestLifecycleAPI_RestartExecution(t *testing.T) {
 
 	// Create workflow
 	wfDef := map[string]interface{}{
-		"id":    "wf-1",
-		"nodes": map[string]interface{}{},
+		"id": "wf-1",
+		"nodes": map[string]interface{}{
+			"set": map[string]interface{}{"id": "set", "type": engine.NodeTypeSetVar, "config": map[string]interface{}{"name": "x", "value": "{{ $trigger.user }}"}},
+		},
 		"edges": []interface{}{},
 	}
 	wfBytes, _ := json.Marshal(wfDef)
 	store.CreateWorkflow(ctx, &storage.Workflow{ID: "wf-1", Name: "Test Workflow", Definition: wfBytes})
 
-	// Create completed execution
+	// Create completed execution started by a trigger
 	execID, _ := store.CreateExecution(ctx, "wf-1")
+	store.SaveExecutionTriggerData(ctx, execID, []byte(`{"user":"ada"}`))
 	err := store.UpdateExecutionStatus(ctx, execID, storage.ExecutionStatusCompleted, []byte("{}"), nil)
 	if err != nil {
 		t.Fatalf("failed to update execution status: %v", err)
@@ -86,7 +173,33 @@ func TestLifecycleAPI_RestartExecution(t *testing.T) {
 	mux.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusAccepted {
-		t.Errorf("expected status 202, got %d: %s", rec.Code, rec.Body.String())
+		t.Fatalf("expected status 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The response names the new execution, which can be followed
+	var resp map[string]interface{}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	newID, _ := resp["execution_id"].(string)
+	if newID == "" || newID == execID {
+		t.Fatalf("expected a new execution ID, got %v", resp)
+	}
+	var exec *storage.Execution
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if exec, err = store.GetExecution(ctx, newID); err == nil && exec.Status != storage.ExecutionStatusRunning {
+			break
+		}
+	}
+	if exec == nil || exec.Status != storage.ExecutionStatusCompleted {
+		t.Fatalf("expected the new execution to complete, got %+v (%v)", exec, err)
+	}
+
+	// The restart runs with the original trigger payload, and keeps it
+	if string(exec.TriggerData) != `{"user":"ada"}` {
+		t.Errorf("expected the trigger payload to be carried over, got %s", exec.TriggerData)
+	}
+	raw, _ := store.GetNodeResult(ctx, newID, "set")
+	if !strings.Contains(string(raw), `"value":"ada"`) {
+		t.Errorf("expected $trigger to resolve to the original payload, got %s", raw)
 	}
 }
 
@@ -120,3 +233,59 @@ func TestLifecycleAPI_BatchStop(t *testing.T) {
 		t.Error("executions should be stopped")
 	}
 }
+
+func TestLifecycleAPI_ListActive(t *testing.T) {
+	mux, store, registry := newLifecycleMux(t)
+	ctx := testCtx
+
+	list := func() []api.ActiveExecutionResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/executions/active", nil))
+		var active []api.ActiveExecutionResponse
+		if err := json.NewDecoder(rec.Body).Decode(&active); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return active
+	}
+	if active := list(); active == nil || len(active) != 0 {
+		t.Fatalf("expected an empty list, got %v", active)
+	}
+
+	wf := engine.Workflow{
+		ID: "wf-active",
+		Nodes: map[string]engine.Node{
+			"wait": {ID: "wait", Type: engine.NodeTypeDelay, Config: map[string]interface{}{"duration": 30.0}},
+		},
+	}
+	ectx := engine.NewExecutionContext(wf.ID)
+	ectx.TriggerID = "tr-active"
+	runner := engine.NewWorkflowRunner(ectx, t.TempDir(), store, registry)
+	execID, err := runner.CreateExecution(ctx, wf.ID)
+	if err != nil {
+		t.Fatalf("CreateExecution failed: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		runner.Run(context.Background(), wf)
+		close(done)
+	}()
+	defer func() {
+		registry.Cancel(execID)
+		<-done
+	}()
+
+	var active []api.ActiveExecutionResponse
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if active = list(); len(active) == 1 && active[0].CurrentNode == "wait" {
+			break
+		}
+	}
+	if len(active) != 1 {
+		t.Fatalf("expected one active execution, got %+v", active)
+	}
+	got := active[0]
+	if got.ID != execID || got.WorkflowID != wf.ID || got.TriggerID != "tr-active" || got.CurrentNode != "wait" || got.ElapsedMS < 0 {
+		t.Errorf("unexpected active execution %+v", got)
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// BodyLimits caps the size of request bodies by route. The limit of the
+// longest prefix in Prefixes matching the request path applies, and Default
+// to the paths none matches. A limit of 0 leaves bodies unlimited, for the
+// handlers that enforce their own (webhooks are limited per trigger).
+type BodyLimits struct {
+	Default  int64
+	Prefixes map[string]int64
+}
+
+// limit returns the body size limit of path.
+func (l BodyLimits) limit(path string) int64 {
+	limit, matched := l.Default, ""
+	for prefix, n := range l.Prefixes {
+		if strings.HasPrefix(path, prefix) && len(prefix) > len(matched) {
+			limit, matched = n, prefix
+		}
+	}
+	return limit
+}
+
+// LimitBodies enforces limits on the requests to next. Bodies declared larger
+// than their limit are refused with 413 up front; reads past the limit of the
+// others fail, and the connection is closed once the response is written.
+func LimitBodies(limits BodyLimits, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := limits.limit(r.URL.Path)
+		if limit > 0 && r.Body != nil && r.Body != http.NoBody {
+			if r.ContentLength > limit {
+				WriteError(w, http.StatusRequestEntityTooLarge, "Request body too large")
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clearDeadlines lifts the server's write deadline, and its read deadline when
+// read is set, off a request that legitimately outlives them: a streamed
+// response or a websocket. Writers that can't set deadlines are left as they are.
+func clearDeadlines(w http.ResponseWriter, read bool) {
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+	if read {
+		rc.SetReadDeadline(time.Time{})
+	}
+}
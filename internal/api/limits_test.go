@@ -0,0 +1,59 @@
+package api_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/api"
+)
+
+func TestLimitBodies(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write(body)
+	})
+	handler := api.LimitBodies(api.BodyLimits{
+		Default: 8,
+		Prefixes: map[string]int64{
+			"/api/workflows":      16,
+			"/api/workflows/big/": 0,
+			"/api/webhooks/":      0,
+		},
+	}, echo)
+
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		chunked bool
+		want    int
+	}{
+		{"WithinDefault", "/api/triggers", "12345678", false, http.StatusOK},
+		{"OverDefault", "/api/triggers", "123456789", false, http.StatusRequestEntityTooLarge},
+		{"OverDefaultChunked", "/api/triggers", "123456789", true, http.StatusBadRequest},
+		{"RouteLimit", "/api/workflows/wf-1", "1234567890123456", false, http.StatusOK},
+		{"OverRouteLimit", "/api/workflows/wf-1", "12345678901234567", false, http.StatusRequestEntityTooLarge},
+		{"LongestPrefixWins", "/api/workflows/big/x", strings.Repeat("x", 100), false, http.StatusOK},
+		{"Unlimited", "/api/webhooks/tr-1", strings.Repeat("x", 100), false, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// MaintenanceHandler switches the server in and out of maintenance mode, for
+// clean deploys: triggers stop starting executions, the running ones drain and
+// /readyz takes the server out of rotation until it is drained and replaced.
+type MaintenanceHandler struct {
+	Triggers *engine.TriggerManager
+}
+
+// NewMaintenanceHandler creates a new maintenance handler
+func NewMaintenanceHandler(triggers *engine.TriggerManager) *MaintenanceHandler {
+	return &MaintenanceHandler{Triggers: triggers}
+}
+
+// SetMaintenanceRequest is the body of PUT /api/admin/maintenance.
+type SetMaintenanceRequest struct {
+	Enabled bool `json:"enabled"`
+	// Policy for trigger fires while in maintenance: "queue" (the default) runs
+	// them once maintenance ends, "skip" drops them
+	Policy engine.MaintenancePolicy `json:"policy,omitempty"`
+	Reason string                   `json:"reason,omitempty"`
+}
+
+// Get handles GET /api/admin/maintenance
+func (h *MaintenanceHandler) Get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Triggers.Maintenance())
+}
+
+// Set handles PUT /api/admin/maintenance. Leaving maintenance runs the fires
+// queued meanwhile in the background.
+func (h *MaintenanceHandler) Set(w http.ResponseWriter, r *http.Request) {
+	var req SetMaintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+
+	if req.Enabled {
+		if req.Policy == "" {
+			req.Policy = engine.MaintenanceQueue
+		}
+		if err := h.Triggers.EnterMaintenance(req.Policy, req.Reason); err != nil {
+			writeValidationError(w, "Invalid maintenance mode", &engine.FieldError{Field: "policy", Message: err.Error()})
+			return
+		}
+	} else {
+		h.Triggers.ExitMaintenance()
+		go func() {
+			if _, err := h.Triggers.RunQueuedFires(context.WithoutCancel(r.Context())); err != nil {
+				log.Printf("Warning: failed to run queued trigger fires: %v", err)
+			}
+		}()
+	}
+	h.Get(w, r)
+}
+
+// writeMaintenanceError answers with a 503 if err is engine.ErrMaintenance,
+// and reports whether it did.
+func writeMaintenanceError(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, engine.ErrMaintenance) {
+		return false
+	}
+	w.Header().Set("Retry-After", "60")
+	WriteError(w, http.StatusServiceUnavailable, "Server is in maintenance mode, fire skipped")
+	return true
+}
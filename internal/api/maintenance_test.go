@@ -0,0 +1,92 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/api"
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func TestMaintenanceAPI(t *testing.T) {
+	mux, store, tm := newTriggerMux(t)
+	handler := api.NewMaintenanceHandler(tm)
+	mux.HandleFunc("GET /api/admin/maintenance", handler.Get)
+	mux.HandleFunc("PUT /api/admin/maintenance", handler.Set)
+	health := api.NewHealthHandler(store, t.TempDir(), tm)
+	health.Runtime = engine.BunRuntime{Path: writeFakeBunVersion(t)}
+	mux.HandleFunc("GET /readyz", health.Readyz)
+
+	def, _ := json.Marshal(engine.Workflow{ID: "wf-1", Nodes: map[string]engine.Node{
+		"mark": {ID: "mark", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "seen", "value": true}},
+	}})
+	store.CreateWorkflow(testCtx, &storage.Workflow{ID: "wf-1", Name: "Test Workflow", Definition: def})
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+	if rec := do(http.MethodPost, "/api/triggers", `{"id":"hook","workflow_id":"wf-1","type":"webhook","enabled":true}`); rec.Code != http.StatusCreated {
+		t.Fatalf("failed to create trigger: %d %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := do(http.MethodPut, "/api/admin/maintenance", `{"enabled":true,"policy":"pause"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422 for an unknown policy, got %d", rec.Code)
+	}
+	rec := do(http.MethodPut, "/api/admin/maintenance", `{"enabled":true,"policy":"skip","reason":"deploy"}`)
+	var state engine.MaintenanceState
+	json.NewDecoder(rec.Body).Decode(&state)
+	if rec.Code != http.StatusOK || !state.Enabled || state.Policy != engine.MaintenanceSkip || state.Since == nil {
+		t.Fatalf("unexpected maintenance state %d %+v", rec.Code, state)
+	}
+
+	// The server is taken out of rotation once drained
+	rec = do(http.MethodGet, "/readyz", "")
+	var ready api.ReadinessResponse
+	json.NewDecoder(rec.Body).Decode(&ready)
+	if rec.Code != http.StatusServiceUnavailable || ready.Status != "MAINTENANCE" || ready.Maintenance == nil || !ready.Maintenance.Drained {
+		t.Errorf("expected a drained server in maintenance, got %d %+v", rec.Code, ready)
+	}
+
+	rec = do(http.MethodPost, "/api/webhooks/hook", `{}`)
+	var errResp api.ErrorResponse
+	json.NewDecoder(rec.Body).Decode(&errResp)
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" || errResp.Error.Code != api.ErrCodeUnavailable {
+		t.Errorf("expected the webhook to be refused with 503, got %d %+v", rec.Code, errResp)
+	}
+
+	// Queued fires run once maintenance ends
+	do(http.MethodPut, "/api/admin/maintenance", `{"enabled":true}`)
+	if rec := do(http.MethodGet, "/api/admin/maintenance", ""); !strings.Contains(rec.Body.String(), `"policy":"queue"`) {
+		t.Errorf("expected fires to be queued by default, got %s", rec.Body.String())
+	}
+	if rec := do(http.MethodPost, "/api/webhooks/hook", `{}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected the webhook to be queued, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if execs, _ := store.ListExecutions(testCtx, "wf-1", 10); len(execs) != 0 {
+		t.Fatalf("expected no execution during maintenance, got %d", len(execs))
+	}
+	rec = do(http.MethodPut, "/api/admin/maintenance", `{"enabled":false}`)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), `"enabled":true`) {
+		t.Fatalf("expected maintenance to end, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodGet, "/readyz", ""); rec.Code != http.StatusOK {
+		t.Errorf("expected the server to be ready again, got %d", rec.Code)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		execs, _ := store.ListExecutions(testCtx, "wf-1", 10)
+		if len(execs) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the queued webhook to run, got %d executions", len(execs))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
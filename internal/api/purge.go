@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// PurgeHandler deletes the data stored about a data subject, for erasure
+// requests, and serves the audit trail of the purges (see engine.PurgeSubject).
+type PurgeHandler struct {
+	Store storage.Storage
+	// HashKey keys the hashes of the subjects in the audit trail
+	HashKey []byte
+}
+
+// NewPurgeHandler creates a new purge handler
+func NewPurgeHandler(store storage.Storage, hashKey []byte) *PurgeHandler {
+	return &PurgeHandler{Store: store, HashKey: hashKey}
+}
+
+// PurgeRequest is the body of POST /api/admin/purge.
+type PurgeRequest struct {
+	// Subject identifies the data subject, e.g. an email address or customer ID
+	Subject string `json:"subject"`
+	// Label matches the executions labelled label=subject; without it, data
+	// containing the subject is matched
+	Label  string `json:"label,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	// DryRun counts what would be purged, deleting nothing
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// PurgeResponse is the audit record of a purge. It holds a hash of the
+// subject, never the subject.
+type PurgeResponse struct {
+	ID                string    `json:"id,omitempty"` // Empty for a dry run
+	SubjectHash       string    `json:"subject_hash"`
+	MatchedBy         string    `json:"matched_by"`
+	Reason            string    `json:"reason,omitempty"`
+	Executions        int       `json:"executions"`
+	TriggerExecutions int       `json:"trigger_executions"`
+	QueuedFires       int       `json:"queued_fires"`
+	Snapshots         int       `json:"snapshots"`
+	SkippedRunning    int       `json:"skipped_running"`
+	PurgedAt          time.Time `json:"purged_at"`
+	DryRun            bool      `json:"dry_run,omitempty"`
+}
+
+func toPurgeResponse(p *storage.PurgeRecord) PurgeResponse {
+	return PurgeResponse{
+		ID:                p.ID,
+		SubjectHash:       p.SubjectHash,
+		MatchedBy:         p.MatchedBy,
+		Reason:            p.Reason,
+		Executions:        p.Executions,
+		TriggerExecutions: p.TriggerExecutions,
+		QueuedFires:       p.QueuedFires,
+		Snapshots:         p.Snapshots,
+		SkippedRunning:    p.SkippedRunning,
+		PurgedAt:          p.PurgedAt,
+	}
+}
+
+// Purge handles POST /api/admin/purge
+func (h *PurgeHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	var req PurgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+
+	record, err := engine.PurgeSubject(r.Context(), h.Store, h.HashKey, engine.PurgeRequest{
+		Subject: req.Subject,
+		Label:   req.Label,
+		Reason:  req.Reason,
+		DryRun:  req.DryRun,
+	})
+	var fieldErr *engine.FieldError
+	if errors.As(err, &fieldErr) {
+		writeValidationError(w, "Invalid purge request", err)
+		return
+	}
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to purge: "+err.Error())
+		return
+	}
+
+	resp := toPurgeResponse(record)
+	if req.DryRun {
+		resp.ID, resp.DryRun = "", true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// List handles GET /api/admin/purges
+func (h *PurgeHandler) List(w http.ResponseWriter, r *http.Request) {
+	purges, err := h.Store.ListPurges(r.Context(), queryLimit(r))
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to list purges: "+err.Error())
+		return
+	}
+	resp := make([]PurgeResponse, 0, len(purges))
+	for _, p := range purges {
+		resp = append(resp, toPurgeResponse(p))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
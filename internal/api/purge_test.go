@@ -0,0 +1,67 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/api"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func TestPurgeAPI(t *testing.T) {
+	mux, store := newWorkflowMux(t)
+	handler := api.NewPurgeHandler(store, []byte("hash-key"))
+	mux.Handle("POST /api/admin/purge", api.RequireAdminKey("s3cret", http.HandlerFunc(handler.Purge)))
+	mux.Handle("GET /api/admin/purges", api.RequireAdminKey("s3cret", http.HandlerFunc(handler.List)))
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Admin-Key", "s3cret")
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	store.CreateWorkflow(testCtx, &storage.Workflow{ID: "wf-1", Name: "Signup", Definition: []byte(`{}`)})
+	id, _ := store.CreateExecution(testCtx, "wf-1")
+	store.SaveNodeResult(testCtx, id, "fetch", []byte(`{"email":"ada@example.com"}`))
+	store.UpdateExecutionStatus(testCtx, id, storage.ExecutionStatusCompleted, []byte(`{}`), nil)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/admin/purge", strings.NewReader(`{"subject":"ada@example.com"}`)))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 without the admin key, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/admin/purge", `{"subject":"ad"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422 for a short subject, got %d", rec.Code)
+	}
+
+	rec = do(http.MethodPost, "/api/admin/purge", `{"subject":"ada@example.com","reason":"erasure request 17","dry_run":true}`)
+	var dry api.PurgeResponse
+	json.NewDecoder(rec.Body).Decode(&dry)
+	if rec.Code != http.StatusOK || !dry.DryRun || dry.ID != "" || dry.Executions != 1 {
+		t.Errorf("unexpected dry run %d %+v", rec.Code, dry)
+	}
+
+	rec = do(http.MethodPost, "/api/admin/purge", `{"subject":"ada@example.com","reason":"erasure request 17"}`)
+	var purge api.PurgeResponse
+	json.NewDecoder(rec.Body).Decode(&purge)
+	if rec.Code != http.StatusOK || purge.ID == "" || purge.Executions != 1 || purge.MatchedBy != "content" {
+		t.Fatalf("unexpected purge %d %+v", rec.Code, purge)
+	}
+	if _, err := store.GetExecution(testCtx, id); err == nil {
+		t.Error("expected the execution to be purged")
+	}
+
+	rec = do(http.MethodGet, "/api/admin/purges", "")
+	var purges []api.PurgeResponse
+	json.NewDecoder(rec.Body).Decode(&purges)
+	if len(purges) != 1 || purges[0].ID != purge.ID || purges[0].Reason != "erasure request 17" {
+		t.Errorf("expected the purge in the audit trail, got %+v", purges)
+	}
+	if strings.Contains(rec.Body.String(), "ada@example.com") {
+		t.Errorf("expected the audit trail not to hold the subject, got %s", rec.Body.String())
+	}
+}
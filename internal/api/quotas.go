@@ -0,0 +1,151 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// QuotaHandler serves the quotas of workflows (see engine.Quotas). Reading them
+// is open to every client; setting them is for admins (see RequireAdminKey).
+type QuotaHandler struct {
+	Store  storage.Storage
+	Quotas *engine.Quotas
+}
+
+// NewQuotaHandler creates a new quota handler
+func NewQuotaHandler(store storage.Storage, quotas *engine.Quotas) *QuotaHandler {
+	return &QuotaHandler{Store: store, Quotas: quotas}
+}
+
+// QuotaResponse is the quota of a workflow and how much of it is used.
+type QuotaResponse struct {
+	WorkflowID string       `json:"workflow_id"`
+	Quota      engine.Quota `json:"quota"`   // Limits in force
+	Default    engine.Quota `json:"default"` // Server's defaults
+	// Custom is the quota set by an admin, whose non-zero limits replace the defaults
+	Custom *engine.Quota `json:"custom,omitempty"`
+	// OverrideUntil is when the limits lifted by an admin apply again
+	OverrideUntil *time.Time `json:"override_until,omitempty"`
+	Usage         QuotaUsage `json:"usage"`
+}
+
+// QuotaUsage is the use of a workflow counted against its quota.
+type QuotaUsage struct {
+	ExecutionsToday int   `json:"executions_today"` // Since midnight UTC
+	StorageBytes    int64 `json:"storage_bytes"`
+}
+
+// SetQuotaRequest is the body of PUT /api/workflows/{id}/quota.
+type SetQuotaRequest struct {
+	ExecutionsPerDay int   `json:"executions_per_day"`
+	StorageBytes     int64 `json:"storage_bytes"`
+	// OverrideUntil lifts the workflow's limits until then, e.g. to let a
+	// backfill through; omit it to enforce them
+	OverrideUntil *time.Time `json:"override_until,omitempty"`
+}
+
+func (req *SetQuotaRequest) validate() error {
+	var errs []error
+	if req.ExecutionsPerDay < 0 {
+		errs = append(errs, &engine.FieldError{Field: "executions_per_day", Message: "must not be negative"})
+	}
+	if req.StorageBytes < 0 {
+		errs = append(errs, &engine.FieldError{Field: "storage_bytes", Message: "must not be negative"})
+	}
+	return errors.Join(errs...)
+}
+
+// Get handles GET /api/workflows/{id}/quota
+func (h *QuotaHandler) Get(w http.ResponseWriter, r *http.Request) {
+	workflowID := r.PathValue("id")
+	if _, err := h.Store.GetWorkflow(r.Context(), workflowID); err != nil {
+		WriteError(w, http.StatusNotFound, "Workflow not found")
+		return
+	}
+	quota, set, err := h.Quotas.For(r.Context(), workflowID)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get quota: "+err.Error())
+		return
+	}
+	resp := QuotaResponse{WorkflowID: workflowID, Quota: quota, Default: h.Quotas.Default}
+	if set != nil {
+		resp.Custom = &engine.Quota{ExecutionsPerDay: set.ExecutionsPerDay, StorageBytes: set.StorageBytes}
+		if set.OverrideUntil != nil && set.OverrideUntil.After(time.Now()) {
+			resp.OverrideUntil = set.OverrideUntil
+		}
+	}
+
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	if resp.Usage.ExecutionsToday, _, err = h.Store.WorkflowUsageSince(r.Context(), workflowID, day); err == nil {
+		var usage storage.ExecutionUsage
+		_, usage, err = h.Store.WorkflowUsageSince(r.Context(), workflowID, time.Time{})
+		resp.Usage.StorageBytes = usage.BytesStored
+	}
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get usage: "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Set handles PUT /api/workflows/{id}/quota, replacing the quota set for the workflow.
+func (h *QuotaHandler) Set(w http.ResponseWriter, r *http.Request) {
+	workflowID := r.PathValue("id")
+	if _, err := h.Store.GetWorkflow(r.Context(), workflowID); err != nil {
+		WriteError(w, http.StatusNotFound, "Workflow not found")
+		return
+	}
+	var req SetQuotaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+	if err := req.validate(); err != nil {
+		writeValidationError(w, "Invalid quota", err)
+		return
+	}
+	quota := &storage.WorkflowQuota{
+		WorkflowID:       workflowID,
+		ExecutionsPerDay: req.ExecutionsPerDay,
+		StorageBytes:     req.StorageBytes,
+		OverrideUntil:    req.OverrideUntil,
+	}
+	if err := h.Store.SetWorkflowQuota(r.Context(), quota); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to set quota: "+err.Error())
+		return
+	}
+	h.Get(w, r)
+}
+
+// Delete handles DELETE /api/workflows/{id}/quota: the workflow gets the
+// default quota again.
+func (h *QuotaHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.Store.DeleteWorkflowQuota(r.Context(), r.PathValue("id")); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to delete quota: "+err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// WriteQuotaError answers with a 429 if err is an *engine.QuotaExceededError,
+// with a Retry-After header when the limit resets, and reports whether it did.
+func WriteQuotaError(w http.ResponseWriter, err error) bool {
+	var quotaErr *engine.QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		return false
+	}
+	if !quotaErr.RetryAt.IsZero() {
+		seconds := int(time.Until(quotaErr.RetryAt).Seconds()) + 1
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	WriteError(w, http.StatusTooManyRequests, fmt.Sprintf("Quota exceeded: %v", quotaErr))
+	return true
+}
@@ -0,0 +1,98 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/api"
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func TestQuotaAPI(t *testing.T) {
+	mux, store, tm := newTriggerMux(t)
+	quotas := engine.NewQuotas(store, engine.Quota{ExecutionsPerDay: 1})
+	tm.SetQuotas(quotas)
+	handler := api.NewQuotaHandler(store, quotas)
+	mux.HandleFunc("GET /api/workflows/{id}/quota", handler.Get)
+	mux.Handle("PUT /api/workflows/{id}/quota", api.RequireAdminKey("admin-secret", http.HandlerFunc(handler.Set)))
+	mux.Handle("DELETE /api/workflows/{id}/quota", api.RequireAdminKey("admin-secret", http.HandlerFunc(handler.Delete)))
+
+	store.CreateWorkflow(testCtx, &storage.Workflow{ID: "wf-1", Name: "Test Workflow", Definition: []byte("{}")})
+	do := func(method, path, body string, header http.Header) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := do(http.MethodPost, "/api/triggers", `{"id":"hook","workflow_id":"wf-1","type":"webhook","enabled":true}`, nil); rec.Code != http.StatusCreated {
+		t.Fatalf("failed to create trigger: %d %s", rec.Code, rec.Body.String())
+	}
+	store.CreateExecution(testCtx, "wf-1")
+
+	// The day's execution used up the default quota
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/webhooks/hook", bytes.NewBufferString(`{}`)))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected status 429 with Retry-After, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var errResp api.ErrorResponse
+	json.NewDecoder(rec.Body).Decode(&errResp)
+	if errResp.Error.Code != api.ErrCodeQuotaExceeded {
+		t.Errorf("expected error code %s, got %s", api.ErrCodeQuotaExceeded, errResp.Error.Code)
+	}
+	if rec := do(http.MethodPost, "/api/triggers/hook/fire", "", nil); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected firing the trigger to be refused, got %d", rec.Code)
+	}
+
+	var resp api.QuotaResponse
+	rec = do(http.MethodGet, "/api/workflows/wf-1/quota", "", nil)
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusOK || resp.Quota.ExecutionsPerDay != 1 || resp.Custom != nil || resp.Usage.ExecutionsToday != 1 {
+		t.Fatalf("unexpected quota %d %+v", rec.Code, resp)
+	}
+
+	// Only admins change quotas
+	admin := http.Header{"X-Admin-Key": {"admin-secret"}}
+	if rec := do(http.MethodPut, "/api/workflows/wf-1/quota", `{"executions_per_day":5}`, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 without the admin key, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/api/workflows/wf-1/quota", `{"executions_per_day":-1}`, admin); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422 for a negative limit, got %d", rec.Code)
+	}
+	rec = do(http.MethodPut, "/api/workflows/wf-1/quota", `{"executions_per_day":5}`, admin)
+	resp = api.QuotaResponse{}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusOK || resp.Quota.ExecutionsPerDay != 5 || resp.Custom == nil || resp.Default.ExecutionsPerDay != 1 {
+		t.Fatalf("unexpected quota %d %+v", rec.Code, resp)
+	}
+	if rec := do(http.MethodPost, "/api/webhooks/hook", `{}`, nil); rec.Code != http.StatusOK {
+		t.Errorf("expected the raised quota to let the webhook through, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Back to the default, then lifted for a while
+	if rec := do(http.MethodDelete, "/api/workflows/wf-1/quota", "", admin); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/webhooks/hook", `{}`, nil); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected the default quota again, got %d", rec.Code)
+	}
+	until := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	rec = do(http.MethodPut, "/api/workflows/wf-1/quota", `{"override_until":"`+until+`"}`, admin)
+	resp = api.QuotaResponse{}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusOK || resp.OverrideUntil == nil {
+		t.Fatalf("expected the override, got %d %+v", rec.Code, resp)
+	}
+	if rec := do(http.MethodPost, "/api/webhooks/hook", `{}`, nil); rec.Code != http.StatusOK {
+		t.Errorf("expected the override to let the webhook through, got %d", rec.Code)
+	}
+}
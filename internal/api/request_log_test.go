@@ -0,0 +1,94 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/api"
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	handler := api.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = engine.RequestIDFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"Propagated", "client-req-1", true},
+		{"Assigned", "", false},
+		{"InvalidReplaced", "has space", false},
+		{"TooLongReplaced", strings.Repeat("x", 200), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/workflows", nil)
+			if tt.header != "" {
+				req.Header.Set(api.RequestIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			got := rec.Header().Get(api.RequestIDHeader)
+			if got == "" || got != seen {
+				t.Fatalf("expected the response and context to carry the same ID, got %q and %q", got, seen)
+			}
+			if (got == tt.header) != tt.keep {
+				t.Errorf("expected the client ID kept=%v, got %q", tt.keep, got)
+			}
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/workflows/{id}", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+	})
+	mux.HandleFunc("GET /livez", func(w http.ResponseWriter, r *http.Request) {})
+	handler := api.RequestID(api.AccessLog(logger, mux))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/workflows/wf-1?token=secret", nil)
+	req.Header.Set(api.RequestIDHeader, "req-7")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/livez", nil))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 access log line without the probe, got %d: %s", len(lines), buf.String())
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("failed to parse log line: %v", err)
+	}
+	want := map[string]interface{}{
+		"request_id": "req-7",
+		"method":     "GET",
+		"path":       "/api/workflows/wf-1",
+		"route":      "GET /api/workflows/{id}",
+		"status":     float64(http.StatusNotFound),
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("expected %s=%v, got %v", key, value, entry[key])
+		}
+	}
+	if _, ok := entry["duration_ms"]; !ok {
+		t.Error("expected the duration to be logged")
+	}
+	if strings.Contains(lines[0], "secret") {
+		t.Error("expected the query to stay out of the log")
+	}
+}
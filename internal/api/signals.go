@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// SignalHandler resumes executions waiting in std/enqueue nodes for a signal
+type SignalHandler struct {
+	Delays *engine.DelayScheduler
+}
+
+// NewSignalHandler creates a new signal handler
+func NewSignalHandler(delays *engine.DelayScheduler) *SignalHandler {
+	return &SignalHandler{Delays: delays}
+}
+
+// SignalResponse reports how many executions a signal resumed
+type SignalResponse struct {
+	Signal  string `json:"signal"`
+	Resumed int    `json:"resumed"`
+}
+
+// Send handles POST /api/signals/{name}
+// The optional JSON body becomes the "payload" of the resumed enqueue nodes.
+func (h *SignalHandler) Send(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if name == "" {
+		WriteError(w, http.StatusBadRequest, "Missing signal name")
+		return
+	}
+
+	var payload interface{}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && err != io.EOF {
+		WriteError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+
+	resumed, err := h.Delays.Signal(r.Context(), name, payload)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to send signal: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SignalResponse{Signal: name, Resumed: resumed})
+}
@@ -0,0 +1,60 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/api"
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func TestSignalAPI_Send(t *testing.T) {
+	store := newTestStorage(t)
+	delays := engine.NewDelayScheduler(store, t.TempDir(), nil, engine.NewWorkerPool(1))
+	handler := api.NewSignalHandler(delays)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/signals/{name}", handler.Send)
+
+	// A waiting execution whose saved state cannot be resumed is claimed and failed
+	execID, _ := store.CreateExecution(testCtx, "wf-1")
+	wakeAt := time.Now().Add(time.Hour)
+	if err := store.SuspendExecutionForSignal(testCtx, execID, []byte(`not json`), "order-42-paid", &wakeAt); err != nil {
+		t.Fatalf("failed to suspend execution: %v", err)
+	}
+
+	send := func(name, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/signals/"+name, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("order-42-paid", `{"amount": 10}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp api.SignalResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.Signal != "order-42-paid" || resp.Resumed != 1 {
+		t.Errorf("expected one resumed execution, got %+v", resp)
+	}
+	if exec, _ := store.GetExecution(testCtx, execID); exec.Status != storage.ExecutionStatusFailed {
+		t.Errorf("expected the execution with invalid state to fail, got %s", exec.Status)
+	}
+
+	// Signals nobody waits for, or without a body, resume nothing
+	rec = send("order-42-paid", "")
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if rec.Code != http.StatusOK || resp.Resumed != 0 {
+		t.Errorf("expected no resumed executions, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := send("order-42-paid", `{bad`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid JSON, got %d", rec.Code)
+	}
+}
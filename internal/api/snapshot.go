@@ -0,0 +1,170 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// SnapshotHandler records blessed runs of workflows and tests the workflows
+// against them (see engine.Snapshot), and reports what their test runs cover.
+type SnapshotHandler struct {
+	Store     storage.Storage
+	BlocksDir string
+}
+
+// NewSnapshotHandler creates a new snapshot handler
+func NewSnapshotHandler(store storage.Storage, blocksDir string) *SnapshotHandler {
+	return &SnapshotHandler{Store: store, BlocksDir: blocksDir}
+}
+
+// SetSnapshotRequest is the body of PUT /api/workflows/{id}/snapshot: the
+// execution to bless and how test runs are compared to it.
+type SetSnapshotRequest struct {
+	ExecutionID string `json:"execution_id"`
+	engine.SnapshotOptions
+}
+
+// Get handles GET /api/workflows/{id}/snapshot
+func (h *SnapshotHandler) Get(w http.ResponseWriter, r *http.Request) {
+	snap, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snap)
+}
+
+// Set handles PUT /api/workflows/{id}/snapshot, recording a finished execution
+// of the workflow as the snapshot its test runs must match. It replaces any
+// earlier snapshot.
+func (h *SnapshotHandler) Set(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var req SetSnapshotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+	if req.ExecutionID == "" {
+		writeValidationError(w, "Invalid snapshot", &engine.FieldError{Field: "execution_id", Message: "is required"})
+		return
+	}
+	if err := req.SnapshotOptions.Validate(); err != nil {
+		writeValidationError(w, "Invalid snapshot", err)
+		return
+	}
+	exec, err := h.Store.GetExecution(r.Context(), req.ExecutionID)
+	if err != nil || exec.WorkflowID != id {
+		WriteError(w, http.StatusNotFound, "Execution "+req.ExecutionID+" not found in workflow "+id)
+		return
+	}
+
+	snap, err := engine.NewSnapshot(r.Context(), h.Store, req.ExecutionID, req.SnapshotOptions)
+	var fieldErr *engine.FieldError
+	if errors.As(err, &fieldErr) {
+		writeValidationError(w, "Invalid snapshot", err)
+		return
+	}
+	if err != nil {
+		WriteError(w, http.StatusConflict, "Cannot snapshot execution: "+err.Error())
+		return
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to marshal snapshot: "+err.Error())
+		return
+	}
+	if err := h.Store.SetWorkflowSnapshot(r.Context(), &storage.WorkflowSnapshot{WorkflowID: id, ExecutionID: req.ExecutionID, Data: data}); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to set snapshot: "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snap)
+}
+
+// Delete handles DELETE /api/workflows/{id}/snapshot
+func (h *SnapshotHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.Store.DeleteWorkflowSnapshot(r.Context(), r.PathValue("id")); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to delete snapshot: "+err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Test handles POST /api/workflows/{id}/snapshot/test, running the workflow's
+// current definition against its snapshot. The response waits for the run and
+// reports whether it matched; a mismatch is not an error.
+func (h *SnapshotHandler) Test(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	workflow, err := h.Store.GetWorkflow(r.Context(), id)
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "Workflow not found: "+err.Error())
+		return
+	}
+	var wf engine.Workflow
+	if err := json.Unmarshal(workflow.Definition, &wf); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to parse workflow: "+err.Error())
+		return
+	}
+	snap, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	// The response waits for the whole run, which may outlast the write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	report, err := engine.RunSnapshotTest(r.Context(), h.Store, h.BlocksDir, wf, snap)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to run snapshot test: "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// Coverage handles GET /api/workflows/{id}/coverage, reporting which nodes,
+// ports and edges of the workflow its latest test runs exercised, as many as
+// the limit parameter (see engine.Coverage).
+func (h *SnapshotHandler) Coverage(w http.ResponseWriter, r *http.Request) {
+	workflow, err := h.Store.GetWorkflow(r.Context(), r.PathValue("id"))
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "Workflow not found: "+err.Error())
+		return
+	}
+	var wf engine.Workflow
+	if err := json.Unmarshal(workflow.Definition, &wf); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to parse workflow: "+err.Error())
+		return
+	}
+	cov, err := engine.WorkflowCoverage(r.Context(), h.Store, h.BlocksDir, wf, queryLimit(r))
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to compute coverage: "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cov)
+}
+
+// load reads the snapshot of the workflow in the request path, writing the
+// error response if there is none.
+func (h *SnapshotHandler) load(w http.ResponseWriter, r *http.Request) (*engine.Snapshot, bool) {
+	stored, err := h.Store.GetWorkflowSnapshot(r.Context(), r.PathValue("id"))
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get snapshot: "+err.Error())
+		return nil, false
+	}
+	if stored == nil {
+		WriteError(w, http.StatusNotFound, "Workflow has no snapshot")
+		return nil, false
+	}
+	var snap engine.Snapshot
+	if err := json.Unmarshal(stored.Data, &snap); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Invalid snapshot: "+err.Error())
+		return nil, false
+	}
+	return &snap, true
+}
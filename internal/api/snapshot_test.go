@@ -0,0 +1,102 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/api"
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func TestWorkflowAPI_Snapshot(t *testing.T) {
+	mux, store := newWorkflowMux(t)
+	handler := api.NewSnapshotHandler(store, t.TempDir())
+	mux.HandleFunc("GET /api/workflows/{id}/snapshot", handler.Get)
+	mux.HandleFunc("PUT /api/workflows/{id}/snapshot", handler.Set)
+	mux.HandleFunc("DELETE /api/workflows/{id}/snapshot", handler.Delete)
+	mux.HandleFunc("POST /api/workflows/{id}/snapshot/test", handler.Test)
+	mux.HandleFunc("GET /api/workflows/{id}/coverage", handler.Coverage)
+
+	workflow := func(value interface{}) engine.Workflow {
+		return engine.Workflow{ID: "wf-1", Name: "Totals", Nodes: map[string]engine.Node{
+			"total": {ID: "total", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "total", "value": value}},
+		}}
+	}
+	def, _ := json.Marshal(workflow("{{ $trigger.amount }}"))
+	store.CreateWorkflow(testCtx, &storage.Workflow{ID: "wf-1", Name: "Totals", Definition: def})
+	ectx := engine.NewExecutionContext("wf-1")
+	ectx.TriggerData = map[string]interface{}{"amount": 40}
+	runner := engine.NewWorkflowRunner(ectx, t.TempDir(), store, nil)
+	execID, err := runner.CreateExecution(testCtx, "wf-1")
+	if err != nil {
+		t.Fatalf("failed to create execution: %v", err)
+	}
+	if err := runner.Run(testCtx, workflow("{{ $trigger.amount }}")); err != nil {
+		t.Fatalf("failed to run workflow: %v", err)
+	}
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+	test := func() engine.SnapshotReport {
+		t.Helper()
+		rec := do(http.MethodPost, "/api/workflows/wf-1/snapshot/test", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("snapshot test failed: %d %s", rec.Code, rec.Body.String())
+		}
+		var report engine.SnapshotReport
+		json.NewDecoder(rec.Body).Decode(&report)
+		return report
+	}
+
+	if rec := do(http.MethodGet, "/api/workflows/wf-1/snapshot", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 without a snapshot, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/api/workflows/wf-1/snapshot", `{"execution_id":"missing"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown execution, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/api/workflows/wf-1/snapshot", `{"execution_id":"`+execID+`","mock":["other"]}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422 for mocking a node without output, got %d", rec.Code)
+	}
+	rec := do(http.MethodPut, "/api/workflows/wf-1/snapshot", `{"execution_id":"`+execID+`","tolerance":0.5}`)
+	var snap engine.Snapshot
+	json.NewDecoder(rec.Body).Decode(&snap)
+	if rec.Code != http.StatusOK || snap.ExecutionID != execID || snap.Tolerance != 0.5 || len(snap.Nodes) != 1 {
+		t.Fatalf("unexpected snapshot %d %+v", rec.Code, snap)
+	}
+
+	if report := test(); !report.Passed || report.ExecutionID == execID {
+		t.Errorf("expected a new run matching the snapshot, got %+v", report)
+	}
+
+	// The snapshot test is a test run; the blessed run isn't
+	rec = do(http.MethodGet, "/api/workflows/wf-1/coverage", "")
+	var cov engine.Coverage
+	json.NewDecoder(rec.Body).Decode(&cov)
+	if rec.Code != http.StatusOK || cov.Runs != 1 || cov.NodesReached != 1 || cov.Nodes[0].Ports[engine.DefaultPort] != 1 {
+		t.Errorf("expected the test run's coverage, got %d %+v", rec.Code, cov)
+	}
+	if rec := do(http.MethodGet, "/api/workflows/missing/coverage", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown workflow, got %d", rec.Code)
+	}
+
+	def, _ = json.Marshal(workflow(45))
+	store.UpdateWorkflow(testCtx, &storage.Workflow{ID: "wf-1", Name: "Totals", Definition: def})
+	report := test()
+	if report.Passed || len(report.Nodes) != 1 || report.Nodes[0].Status != engine.DiffChanged {
+		t.Errorf("expected the changed workflow to fail its snapshot test, got %+v", report)
+	}
+
+	if rec := do(http.MethodDelete, "/api/workflows/wf-1/snapshot", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/workflows/wf-1/snapshot/test", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 once deleted, got %d", rec.Code)
+	}
+}
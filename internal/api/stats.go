@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// StatsHandler serves the execution stats of workflows, read from the daily
+// aggregates kept by engine.StatsRollup rather than the execution history
+type StatsHandler struct {
+	Store storage.Storage
+}
+
+// NewStatsHandler creates a new stats handler
+func NewStatsHandler(store storage.Storage) *StatsHandler {
+	return &StatsHandler{Store: store}
+}
+
+// WorkflowStatsResponse sums the daily stats of the listed days.
+type WorkflowStatsResponse struct {
+	WorkflowID string `json:"workflow_id,omitempty"` // Empty for every workflow
+	Runs       int    `json:"runs"`
+	Failures   int    `json:"failures"`
+	DurationMS int64  `json:"duration_ms"`
+	SampledOut int    `json:"sampled_out"`
+	// Usage is the resources the runs used, by which load is attributed
+	Usage ExecutionUsage `json:"usage"`
+	Days  []WorkflowDay  `json:"days"`
+}
+
+// WorkflowDay is the stats of one workflow on one day.
+type WorkflowDay struct {
+	WorkflowID string         `json:"workflow_id"`
+	Day        string         `json:"day"` // YYYY-MM-DD
+	Runs       int            `json:"runs"`
+	Failures   int            `json:"failures"`
+	DurationMS int64          `json:"duration_ms"` // Total run time of the day's runs
+	SampledOut int            `json:"sampled_out"`
+	Usage      ExecutionUsage `json:"usage"`
+}
+
+// Get handles GET /api/stats?workflow_id=&days=30, returning the daily stats of
+// a workflow, or of every workflow, over the last days (today included). They
+// are as of the last rollup, so the latest runs may not be counted yet.
+func (h *StatsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	workflowID := r.URL.Query().Get("workflow_id")
+	days := 30
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		if v, err := strconv.Atoi(raw); err == nil && v > 0 && v <= 366 {
+			days = v
+		}
+	}
+
+	if workflowID != "" {
+		if _, err := h.Store.GetWorkflow(r.Context(), workflowID); err != nil {
+			WriteError(w, http.StatusNotFound, "Workflow not found")
+			return
+		}
+	}
+	since := time.Now().UTC().AddDate(0, 0, 1-days)
+	stats, err := h.Store.ListWorkflowStats(r.Context(), workflowID, since)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to list stats: "+err.Error())
+		return
+	}
+
+	resp := WorkflowStatsResponse{WorkflowID: workflowID, Days: []WorkflowDay{}}
+	var usage storage.ExecutionUsage
+	for _, st := range stats {
+		resp.Runs += st.Runs
+		resp.Failures += st.Failures
+		resp.DurationMS += st.DurationMS
+		resp.SampledOut += st.SampledOut
+		usage.Add(st.Usage)
+		resp.Days = append(resp.Days, WorkflowDay{
+			WorkflowID: st.WorkflowID,
+			Day:        st.Day,
+			Runs:       st.Runs,
+			Failures:   st.Failures,
+			DurationMS: st.DurationMS,
+			SampledOut: st.SampledOut,
+			Usage:      newExecutionUsage(st.Usage),
+		})
+	}
+	resp.Usage = newExecutionUsage(usage)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
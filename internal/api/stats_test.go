@@ -0,0 +1,61 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/api"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func TestStatsAPI_Get(t *testing.T) {
+	store := newTestStorage(t)
+	ctx := testCtx
+	store.CreateWorkflow(ctx, &storage.Workflow{ID: "wf-stats", Name: "Stats", Definition: []byte("{}")})
+	for _, status := range []storage.ExecutionStatus{storage.ExecutionStatusCompleted, storage.ExecutionStatusFailed} {
+		execID, _ := store.CreateExecution(ctx, "wf-stats")
+		store.AddExecutionUsage(ctx, execID, storage.ExecutionUsage{Nodes: 3, CPUTimeMS: 40, BytesStored: 512, HTTPCalls: 2})
+		store.UpdateExecutionStatus(ctx, execID, status, []byte(`{}`), nil)
+	}
+	execID, _ := store.CreateExecution(ctx, "wf-other")
+	store.UpdateExecutionStatus(ctx, execID, storage.ExecutionStatusCompleted, []byte(`{}`), nil)
+	if err := store.RollupWorkflowStats(ctx, time.Now()); err != nil {
+		t.Fatalf("failed to roll up stats: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/stats", api.NewStatsHandler(store).Get)
+
+	get := func(url string) (*httptest.ResponseRecorder, api.WorkflowStatsResponse) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		var resp api.WorkflowStatsResponse
+		if rec.Code == http.StatusOK {
+			json.NewDecoder(rec.Body).Decode(&resp)
+		}
+		return rec, resp
+	}
+
+	rec, resp := get("/api/stats?workflow_id=wf-stats&days=7")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	today := time.Now().UTC().Format(time.DateOnly)
+	if resp.Runs != 2 || resp.Failures != 1 || len(resp.Days) != 1 || resp.Days[0].Day != today {
+		t.Errorf("unexpected stats %+v", resp)
+	}
+	wantUsage := api.ExecutionUsage{Nodes: 6, CPUTimeMS: 80, BytesStored: 1024, HTTPCalls: 4}
+	if resp.Usage != wantUsage || len(resp.Days) != 1 || resp.Days[0].Usage != wantUsage {
+		t.Errorf("expected usage %+v, got %+v", wantUsage, resp)
+	}
+
+	if _, resp := get("/api/stats"); resp.Runs != 3 || len(resp.Days) != 2 {
+		t.Errorf("expected the stats of both workflows, got %+v", resp)
+	}
+	if rec, _ := get("/api/stats?workflow_id=missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a missing workflow, got %d", rec.Code)
+	}
+}
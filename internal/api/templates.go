@@ -0,0 +1,190 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// TemplateHandler serves the workflow templates shipped with the server (see
+// engine.WorkflowTemplate) and creates workflows from them.
+type TemplateHandler struct {
+	Store storage.Storage
+	// Triggers starts the enabled triggers of instantiated templates; without
+	// a trigger manager they are saved and start with the server.
+	Triggers *TriggerHandler
+	// ConfigSchema returns the config schema of a node type, which the nodes of
+	// instantiated workflows are checked against; the built-in schemas by default.
+	ConfigSchema func(engine.NodeType) (engine.ConfigSchema, error)
+}
+
+// NewTemplateHandler creates a new template handler
+func NewTemplateHandler(store storage.Storage, triggers *TriggerHandler) *TemplateHandler {
+	return &TemplateHandler{Store: store, Triggers: triggers, ConfigSchema: engine.BuiltinConfigSchema}
+}
+
+// TemplateListItem is the summary returned by GET /api/templates
+type TemplateListItem struct {
+	ID          string                     `json:"id"`
+	Name        string                     `json:"name"`
+	Description string                     `json:"description"`
+	Category    string                     `json:"category,omitempty"`
+	Parameters  []engine.TemplateParameter `json:"parameters,omitempty"`
+}
+
+// InstantiateTemplateRequest is the body of POST /api/templates/{id}/instantiate.
+type InstantiateTemplateRequest struct {
+	// WorkflowID and Name of the created workflow; by default a new ID and the
+	// template's workflow name.
+	WorkflowID string                 `json:"workflow_id,omitempty"`
+	Name       string                 `json:"name,omitempty"`
+	Parameters map[string]interface{} `json:"parameters"`
+}
+
+// InstantiateTemplateResponse is the workflow and triggers created from a template.
+type InstantiateTemplateResponse struct {
+	Workflow *engine.Workflow   `json:"workflow"`
+	Triggers []*storage.Trigger `json:"triggers"`
+}
+
+// List handles GET /api/templates
+func (h *TemplateHandler) List(w http.ResponseWriter, r *http.Request) {
+	templates, err := engine.Templates()
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to load templates: "+err.Error())
+		return
+	}
+	list := make([]TemplateListItem, len(templates))
+	for i, t := range templates {
+		list[i] = TemplateListItem{ID: t.ID, Name: t.Name, Description: t.Description, Category: t.Category, Parameters: t.Parameters}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// Get handles GET /api/templates/{id}
+func (h *TemplateHandler) Get(w http.ResponseWriter, r *http.Request) {
+	t, ok := engine.TemplateByID(r.PathValue("id"))
+	if !ok {
+		WriteError(w, http.StatusNotFound, "Template not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t)
+}
+
+// Instantiate handles POST /api/templates/{id}/instantiate: it creates the
+// template's workflow and triggers with the parameters given. Nothing is kept
+// when a trigger fails to start.
+func (h *TemplateHandler) Instantiate(w http.ResponseWriter, r *http.Request) {
+	t, ok := engine.TemplateByID(r.PathValue("id"))
+	if !ok {
+		WriteError(w, http.StatusNotFound, "Template not found")
+		return
+	}
+	var req InstantiateTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+
+	wf, templateTriggers, err := t.Instantiate(req.Parameters)
+	if err != nil {
+		writeValidationError(w, "Invalid parameters", err)
+		return
+	}
+	wf.ID = req.WorkflowID
+	if wf.ID == "" {
+		wf.ID = storage.NewID()
+	}
+	if req.Name != "" {
+		wf.Name = req.Name
+	}
+
+	// Parameters end up in node and trigger configs, so check them as saved
+	errs := []error{wf.ValidateDefinition(h.ConfigSchema)}
+	triggerReqs := make([]CreateTriggerRequest, len(templateTriggers))
+	for i, tr := range templateTriggers {
+		triggerReqs[i] = CreateTriggerRequest{WorkflowID: wf.ID, Type: tr.Type, Config: tr.Config, Enabled: tr.Enabled}
+		if err := triggerReqs[i].validate(); err != nil {
+			for _, fe := range fieldErrors(err) {
+				fe.Field = fmt.Sprintf("triggers[%d].%s", i, fe.Field)
+				errs = append(errs, &fe)
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		writeValidationError(w, "Invalid workflow", err)
+		return
+	}
+
+	if _, err := h.Store.GetWorkflow(r.Context(), wf.ID); err == nil {
+		WriteError(w, http.StatusConflict, "Workflow "+wf.ID+" already exists")
+		return
+	}
+	definition, err := json.Marshal(wf)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to marshal definition: "+err.Error())
+		return
+	}
+	if err := h.Store.CreateWorkflow(r.Context(), &storage.Workflow{ID: wf.ID, Name: wf.Name, Definition: definition}); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to create workflow: "+err.Error())
+		return
+	}
+
+	resp := InstantiateTemplateResponse{Workflow: wf, Triggers: []*storage.Trigger{}}
+	for _, req := range triggerReqs {
+		trigger, status, err := h.createTrigger(r, req)
+		if err != nil {
+			// Undone even if the client disconnected meanwhile
+			ctx, cancel := storage.Detach(r.Context())
+			for _, created := range resp.Triggers {
+				if h.Triggers != nil && h.Triggers.TriggerManager != nil {
+					h.Triggers.TriggerManager.Unregister(created.ID)
+				}
+				h.Store.DeleteTrigger(ctx, created.ID)
+			}
+			h.Store.DeleteWorkflow(ctx, wf.ID)
+			cancel()
+			WriteError(w, status, "Failed to instantiate template: "+err.Error())
+			return
+		}
+		resp.Triggers = append(resp.Triggers, trigger)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// createTrigger saves the trigger of req and starts it if enabled.
+func (h *TemplateHandler) createTrigger(r *http.Request, req CreateTriggerRequest) (*storage.Trigger, int, error) {
+	config, err := json.Marshal(req.Config)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to encode trigger config: %w", err)
+	}
+	trigger := &storage.Trigger{
+		ID:         storage.NewID(),
+		WorkflowID: req.WorkflowID,
+		Type:       req.Type,
+		Config:     config,
+		Enabled:    req.Enabled,
+	}
+	if err := h.Store.CreateTrigger(r.Context(), trigger); err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to create trigger: %w", err)
+	}
+	if trigger.Enabled && h.Triggers != nil && h.Triggers.TriggerManager != nil {
+		if err := h.Triggers.registerTrigger(trigger); err != nil {
+			ctx, cancel := storage.Detach(r.Context())
+			h.Store.DeleteTrigger(ctx, trigger.ID)
+			cancel()
+			return nil, http.StatusUnprocessableEntity, fmt.Errorf("failed to start trigger: %w", err)
+		}
+	}
+	trigger.RuntimeStatus = runtimeStatus(trigger)
+	return trigger, 0, nil
+}
@@ -0,0 +1,91 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/api"
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func newTemplateMux(t *testing.T) (*http.ServeMux, storage.Storage, *engine.TriggerManager) {
+	store := newTestStorage(t)
+	tm := engine.NewTriggerManager(store, t.TempDir(), engine.NewExecutionRegistry(), engine.NewWorkerPool(10))
+	t.Cleanup(tm.StopAll)
+	handler := api.NewTemplateHandler(store, api.NewTriggerHandler(store, tm))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/templates", handler.List)
+	mux.HandleFunc("GET /api/templates/{id}", handler.Get)
+	mux.HandleFunc("POST /api/templates/{id}/instantiate", handler.Instantiate)
+	return mux, store, tm
+}
+
+func TestTemplateAPI_List(t *testing.T) {
+	mux, _, _ := newTemplateMux(t)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/templates", nil))
+	var list []api.TemplateListItem
+	json.NewDecoder(rec.Body).Decode(&list)
+	ids := make([]string, len(list))
+	for i, item := range list {
+		ids[i] = item.ID
+	}
+	if strings.Join(ids, ",") != "cron-http-sync,webhook-to-slack" {
+		t.Errorf("expected the shipped templates, got %v", ids)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/templates/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestTemplateAPI_Instantiate(t *testing.T) {
+	mux, store, tm := newTemplateMux(t)
+
+	body := `{"workflow_id":"alerts","parameters":{"slack_webhook_url":"https://hooks.slack.com/services/T/B/X","path":"alerts/new"}}`
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/templates/webhook-to-slack/instantiate", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp api.InstantiateTemplateResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Workflow.ID != "alerts" || resp.Workflow.Nodes["notify"].Config["url"] != "https://hooks.slack.com/services/T/B/X" {
+		t.Errorf("unexpected workflow %+v", resp.Workflow)
+	}
+	if _, err := store.GetWorkflow(testCtx, "alerts"); err != nil {
+		t.Errorf("expected the workflow to be saved: %v", err)
+	}
+	if len(resp.Triggers) != 1 {
+		t.Fatalf("expected a webhook trigger, got %+v", resp.Triggers)
+	}
+	if target, ok := tm.WebhookTarget("alerts/new"); !ok || target.ID() != resp.Triggers[0].ID {
+		t.Errorf("expected the webhook trigger to be served at its path")
+	}
+
+	// Missing parameters are reported and nothing is created
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/templates/webhook-to-slack/instantiate", strings.NewReader(`{"workflow_id":"other"}`)))
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"field":"parameters.slack_webhook_url"`) {
+		t.Errorf("expected status 422 for the missing URL, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Parameters are checked as saved, e.g. a cron schedule
+	body = `{"workflow_id":"sync","parameters":{"source_url":"https://a.example.com","target_url":"https://b.example.com","schedule":"often"}}`
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/templates/cron-http-sync/instantiate", strings.NewReader(body)))
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"field":"triggers[0].config.schedule"`) {
+		t.Errorf("expected status 422 for the schedule, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if workflows, _ := store.ListWorkflows(testCtx); len(workflows) != 1 {
+		t.Errorf("expected only the first workflow to be created, got %d", len(workflows))
+	}
+}
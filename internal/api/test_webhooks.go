@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// TestWebhookEvent is one line of the NDJSON stream returned by the test
+// webhook endpoint: a "node.finished" line per node as it completes, then one
+// "execution.finished" line.
+type TestWebhookEvent struct {
+	Type        engine.EventType `json:"type"`
+	ExecutionID string           `json:"execution_id,omitempty"`
+	NodeID      string           `json:"node_id,omitempty"`
+	Port        string           `json:"port,omitempty"`
+	// Output is the node's result on node lines.
+	Output interface{} `json:"output,omitempty"`
+	// Status, and Results holding every node's result, are set on the final line.
+	Status  storage.ExecutionStatus `json:"status,omitempty"`
+	Results map[string]interface{}  `json:"results,omitempty"`
+	Error   string                  `json:"error,omitempty"`
+}
+
+// HandleTestWebhook handles POST /api/webhooks-test/{id}. It runs the
+// workflow of a webhook trigger once in test mode, for building a workflow
+// against real calls: the execution is marked as a test in history, left out
+// of the trigger's execution history and of the server's event stream, and
+// the trigger may be disabled. Node results are streamed back to the caller
+// as they complete. Unlike /api/webhooks/, the endpoint requires the API key.
+func (h *TriggerHandler) HandleTestWebhook(w http.ResponseWriter, r *http.Request) {
+	triggerID := r.PathValue("id")
+	trigger, err := h.Store.GetTrigger(r.Context(), triggerID)
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "Trigger not found: "+err.Error())
+		return
+	}
+	// TS webhook triggers run their own handler, which can't run in test mode
+	if trigger.Type != string(engine.TriggerTypeWebhook) {
+		WriteError(w, http.StatusBadRequest, "Trigger is not a webhook trigger")
+		return
+	}
+
+	var config map[string]interface{}
+	json.Unmarshal(trigger.Config, &config)
+	scope, err := h.checkWebhookToken(r, triggerID, config)
+	if err != nil {
+		WriteError(w, http.StatusUnauthorized, "Unauthorized: "+err.Error())
+		return
+	}
+	if scope == "" {
+		scope = WebhookScopeTest
+	}
+	payload, err := webhookPayload(r, config, scope)
+	if err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	// The lines are streamed for as long as the run takes
+	clearDeadlines(w, false)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	var mu sync.Mutex
+	write := func(ev TestWebhookEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		json.NewEncoder(w).Encode(ev)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	// The run publishes to a bus of its own, so only this caller sees it
+	bus := engine.NewEventBus()
+	var executionID string
+	unsubscribe := bus.Subscribe(func(ev engine.Event) {
+		switch ev := ev.(type) {
+		case engine.ExecutionStarted:
+			mu.Lock()
+			executionID = ev.ExecutionID
+			mu.Unlock()
+		case engine.NodeFinished:
+			write(TestWebhookEvent{
+				Type:        ev.EventType(),
+				ExecutionID: ev.ExecutionID,
+				NodeID:      ev.NodeID,
+				Port:        ev.Port,
+				Output:      ev.Output,
+				Error:       ev.Error,
+			})
+		}
+	}, engine.EventExecutionStarted, engine.EventNodeFinished)
+
+	results, err := h.TriggerManager.FireTest(r.Context(), triggerID, payload, bus)
+	// Unsubscribing waits for the node lines to be written
+	unsubscribe()
+
+	final := TestWebhookEvent{
+		Type:        engine.EventExecutionFinished,
+		ExecutionID: executionID,
+		Status:      storage.ExecutionStatusCompleted,
+		Results:     results,
+	}
+	if err != nil {
+		final.Status = storage.ExecutionStatusFailed
+		final.Error = err.Error()
+	}
+	// A run can also have been cancelled or suspended in a delay node
+	if exec, getErr := h.Store.GetExecution(r.Context(), executionID); getErr == nil {
+		final.Status = exec.Status
+	}
+	write(final)
+}
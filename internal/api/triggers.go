@@ -1,11 +1,18 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
 	"github.com/conv3n/conv3n/internal/engine"
 	"github.com/conv3n/conv3n/internal/storage"
 )
@@ -14,6 +21,8 @@ import (
 type TriggerHandler struct {
 	Store          storage.Storage
 	TriggerManager *engine.TriggerManager
+	// WebhookSecret signs webhook URL tokens; without it no signed URLs are issued
+	WebhookSecret []byte
 }
 
 // NewTriggerHandler creates a new trigger handler
@@ -26,58 +35,83 @@ func NewTriggerHandler(store storage.Storage, manager *engine.TriggerManager) *T
 
 // CreateTriggerRequest represents the request body for creating a trigger
 type CreateTriggerRequest struct {
+	// ID is chosen by the server unless set on creation, e.g. by bundles that
+	// keep their triggers' IDs stable across deployments. Updates ignore it.
+	ID         string                 `json:"id,omitempty"`
 	WorkflowID string                 `json:"workflow_id"`
-	Type       string                 `json:"type"` // cron, interval, webhook, typescript
+	Type       string                 `json:"type"` // cron, interval, webhook, websocket, typescript
 	Config     map[string]interface{} `json:"config"`
 	Enabled    bool                   `json:"enabled"`
 	FilePath   string                 `json:"file_path"` // Path to the TypeScript trigger file
 }
 
+// validate checks the request before anything is saved: its required fields,
+// its type and the config keys the type takes (see engine.ValidateTriggerConfig).
+// Every problem is reported, as an *engine.FieldError.
+func (req *CreateTriggerRequest) validate() error {
+	var errs []error
+	if req.ID != "" {
+		errs = append(errs, engine.ValidateTriggerID(req.ID))
+	}
+	if req.WorkflowID == "" {
+		errs = append(errs, &engine.FieldError{Field: "workflow_id", Message: "is required"})
+	}
+	switch engine.TriggerType(req.Type) {
+	case "":
+		errs = append(errs, &engine.FieldError{Field: "type", Message: "is required"})
+	case engine.TriggerTypeCron, engine.TriggerTypeInterval, engine.TriggerTypeWebhook, engine.TriggerTypeWebSocket, engine.TriggerTypeTS:
+		errs = append(errs, engine.ValidateTriggerConfig(engine.TriggerType(req.Type), req.Config))
+	default:
+		errs = append(errs, &engine.FieldError{Field: "type", Message: "must be cron, interval, webhook, websocket, or typescript"})
+	}
+	if req.Type == string(engine.TriggerTypeTS) && req.FilePath == "" {
+		errs = append(errs, &engine.FieldError{Field: "file_path", Message: "is required for typescript triggers"})
+	}
+	return errors.Join(errs...)
+}
+
 // Create handles POST /api/triggers
 func (h *TriggerHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req CreateTriggerRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		WriteError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
 		return
 	}
 
-	// Validate required fields
-	if req.WorkflowID == "" {
-		http.Error(w, "workflow_id is required", http.StatusBadRequest)
-		return
-	}
-	if req.Type == "" {
-		http.Error(w, "type is required", http.StatusBadRequest)
+	if err := req.validate(); err != nil {
+		writeValidationError(w, "Invalid trigger", err)
 		return
 	}
-	// Updated type validation to include 'typescript'
-	if req.Type != string(engine.TriggerTypeCron) && req.Type != string(engine.TriggerTypeInterval) &&
-		req.Type != string(engine.TriggerTypeWebhook) && req.Type != string(engine.TriggerTypeTS) {
-		http.Error(w, "type must be cron, interval, webhook, or typescript", http.StatusBadRequest)
-		return
-	}
-	if req.Type == string(engine.TriggerTypeTS) && req.FilePath == "" {
-		http.Error(w, "file_path is required for typescript triggers", http.StatusBadRequest)
+	if status, err := h.checkBindings(r.Context(), req.Config); err != nil {
+		WriteError(w, status, err.Error())
 		return
 	}
 
 	// Verify workflow exists
 	_, err := h.Store.GetWorkflow(r.Context(), req.WorkflowID)
 	if err != nil {
-		http.Error(w, "Workflow not found: "+err.Error(), http.StatusNotFound)
+		WriteError(w, http.StatusNotFound, "Workflow not found: "+err.Error())
+		return
+	}
+
+	id := req.ID
+	if id == "" {
+		id = storage.NewID()
+	} else if _, err := h.Store.GetTrigger(r.Context(), id); err == nil {
+		WriteError(w, http.StatusConflict, "Trigger "+id+" already exists")
 		return
 	}
 
 	// Encode config as JSON
 	configBytes, err := json.Marshal(req.Config)
 	if err != nil {
-		http.Error(w, "Failed to encode config: "+err.Error(), http.StatusInternalServerError)
+		WriteError(w, http.StatusInternalServerError, "Failed to encode config: "+err.Error())
 		return
 	}
 
 	// Create trigger
 	trigger := &storage.Trigger{
-		ID:         fmt.Sprintf("trigger_%d", time.Now().UnixNano()),
+		ID:         id,
 		WorkflowID: req.WorkflowID,
 		Type:       req.Type,
 		Config:     configBytes,
@@ -86,17 +120,23 @@ func (h *TriggerHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.Store.CreateTrigger(r.Context(), trigger); err != nil {
-		http.Error(w, "Failed to create trigger: "+err.Error(), http.StatusInternalServerError)
+		WriteError(w, http.StatusInternalServerError, "Failed to create trigger: "+err.Error())
 		return
 	}
 
-	// If enabled, register with TriggerManager
+	// If enabled, register with TriggerManager; a trigger that can't start is
+	// not kept, so that it doesn't sit there silently never firing
 	if trigger.Enabled {
 		if err := h.registerTrigger(trigger); err != nil {
-			// Log error but don't fail the request
-			fmt.Printf("Warning: failed to register trigger: %v\n", err)
+			// Undone even if the client disconnected meanwhile
+			ctx, cancel := storage.Detach(r.Context())
+			h.Store.DeleteTrigger(ctx, trigger.ID)
+			cancel()
+			WriteError(w, http.StatusUnprocessableEntity, "Failed to start trigger: "+err.Error())
+			return
 		}
 	}
+	trigger.RuntimeStatus = runtimeStatus(trigger)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -107,13 +147,13 @@ func (h *TriggerHandler) Create(w http.ResponseWriter, r *http.Request) {
 func (h *TriggerHandler) Get(w http.ResponseWriter, r *http.Request) {
 	triggerID := r.PathValue("id")
 	if triggerID == "" {
-		http.Error(w, "Missing trigger ID", http.StatusBadRequest)
+		WriteError(w, http.StatusBadRequest, "Missing trigger ID")
 		return
 	}
 
 	trigger, err := h.Store.GetTrigger(r.Context(), triggerID)
 	if err != nil {
-		http.Error(w, "Trigger not found: "+err.Error(), http.StatusNotFound)
+		WriteError(w, http.StatusNotFound, "Trigger not found: "+err.Error())
 		return
 	}
 
@@ -135,59 +175,120 @@ func (h *TriggerHandler) List(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err != nil {
-		http.Error(w, "Failed to list triggers: "+err.Error(), http.StatusInternalServerError)
+		WriteError(w, http.StatusInternalServerError, "Failed to list triggers: "+err.Error())
 		return
 	}
 
+	now := time.Now()
+	resp := make([]TriggerListItem, len(triggers))
+	for i, t := range triggers {
+		resp[i], err = h.triggerHealth(r.Context(), t, now)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, "Failed to list trigger executions: "+err.Error())
+			return
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(triggers)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// triggerHealthHistory is how many of its latest executions a trigger's health
+// is computed from.
+const triggerHealthHistory = 100
+
+// idempotencyKeyHeader carries the delivery key of a fire or webhook request;
+// a retry with the same key maps to the first delivery's execution instead of
+// running the workflow again (see engine.TriggerManager.FireOnce).
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// TriggerListItem is a trigger with its health, as listed by GET /api/triggers.
+type TriggerListItem struct {
+	storage.Trigger
+	LastFiredAt *time.Time `json:"last_fired_at,omitempty"`
+	LastStatus  string     `json:"last_status,omitempty"`
+	// ConsecutiveFailures counts the failed fires since the last successful one,
+	// up to the last 100 fires; skipped fires are not counted.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+	// NextFireAt is when a cron or interval trigger fires next.
+	NextFireAt *time.Time `json:"next_fire_at,omitempty"`
+}
+
+// triggerHealth computes the health of a trigger from its execution history and schedule.
+func (h *TriggerHandler) triggerHealth(ctx context.Context, t *storage.Trigger, now time.Time) (TriggerListItem, error) {
+	item := TriggerListItem{Trigger: *t}
+	history, err := h.Store.ListTriggerExecutions(ctx, t.ID, triggerHealthHistory)
+	if err != nil {
+		return item, err
+	}
+	if len(history) > 0 {
+		item.LastFiredAt = &history[0].FiredAt
+		item.LastStatus = history[0].Status
+	}
+	for _, te := range history {
+		if te.Status == "success" {
+			break
+		}
+		if te.Status == "failed" {
+			item.ConsecutiveFailures++
+		}
+	}
+	// A schedule that doesn't parse only leaves the next fire time out
+	item.NextFireAt, _ = engine.NextTriggerFire(t, item.LastFiredAt, now)
+	return item, nil
 }
 
 // Update handles PUT /api/triggers/{id}
 func (h *TriggerHandler) Update(w http.ResponseWriter, r *http.Request) {
 	triggerID := r.PathValue("id")
 	if triggerID == "" {
-		http.Error(w, "Missing trigger ID", http.StatusBadRequest)
+		WriteError(w, http.StatusBadRequest, "Missing trigger ID")
 		return
 	}
 
 	var req CreateTriggerRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		WriteError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
 		return
 	}
 
-	// Validate type
-	if req.Type == "" {
-		http.Error(w, "type is required", http.StatusBadRequest)
+	if err := req.validate(); err != nil {
+		writeValidationError(w, "Invalid trigger", err)
 		return
 	}
-	// Updated type validation to include 'typescript'
-	if req.Type != string(engine.TriggerTypeCron) && req.Type != string(engine.TriggerTypeInterval) &&
-		req.Type != string(engine.TriggerTypeWebhook) && req.Type != string(engine.TriggerTypeTS) {
-		http.Error(w, "type must be cron, interval, webhook, or typescript", http.StatusBadRequest)
-		return
-	}
-	if req.Type == string(engine.TriggerTypeTS) && req.FilePath == "" {
-		http.Error(w, "file_path is required for typescript triggers", http.StatusBadRequest)
+	if status, err := h.checkBindings(r.Context(), req.Config); err != nil {
+		WriteError(w, status, err.Error())
 		return
 	}
 
 	// Get existing trigger
 	existing, err := h.Store.GetTrigger(r.Context(), triggerID)
 	if err != nil {
-		http.Error(w, "Trigger not found: "+err.Error(), http.StatusNotFound)
+		WriteError(w, http.StatusNotFound, "Trigger not found: "+err.Error())
 		return
 	}
 
+	// Only RotateWebhookURLs changes the generation: an update can't bring
+	// rotated webhook URLs back by omitting or lowering it
+	var existingConfig map[string]interface{}
+	json.Unmarshal(existing.Config, &existingConfig)
+	delete(req.Config, "token_generation")
+	if gen, ok := existingConfig["token_generation"]; ok {
+		if req.Config == nil {
+			req.Config = map[string]interface{}{}
+		}
+		req.Config["token_generation"] = gen
+	}
+
 	// Encode config
 	configBytes, err := json.Marshal(req.Config)
 	if err != nil {
-		http.Error(w, "Failed to encode config: "+err.Error(), http.StatusInternalServerError)
+		WriteError(w, http.StatusInternalServerError, "Failed to encode config: "+err.Error())
 		return
 	}
 
 	// Update trigger
+	previous := *existing
 	existing.WorkflowID = req.WorkflowID
 	existing.Type = req.Type
 	existing.Config = configBytes
@@ -196,26 +297,35 @@ func (h *TriggerHandler) Update(w http.ResponseWriter, r *http.Request) {
 	existing.Enabled = req.Enabled
 
 	if err := h.Store.UpdateTrigger(r.Context(), existing); err != nil {
-		http.Error(w, "Failed to update trigger: "+err.Error(), http.StatusInternalServerError)
+		WriteError(w, http.StatusInternalServerError, "Failed to update trigger: "+err.Error())
 		return
 	}
 
 	// Handle trigger manager updates
-	if wasEnabled && !existing.Enabled {
-		// Trigger was disabled
+	var startErr error
+	switch {
+	case wasEnabled && existing.Enabled:
+		startErr = h.restartTrigger(existing)
+	case wasEnabled:
 		h.TriggerManager.Unregister(triggerID)
-	} else if !wasEnabled && existing.Enabled {
-		// Trigger was enabled
-		if err := h.registerTrigger(existing); err != nil {
-			fmt.Printf("Warning: failed to register trigger: %v\n", err)
-		}
-	} else if existing.Enabled {
-		// Trigger config changed while enabled - re-register
-		h.TriggerManager.Unregister(triggerID)
-		if err := h.registerTrigger(existing); err != nil {
-			fmt.Printf("Warning: failed to register trigger: %v\n", err)
+	case existing.Enabled:
+		startErr = h.registerTrigger(existing)
+	}
+	if startErr != nil {
+		// Put the previous trigger back rather than keep one that can't start
+		ctx, cancel := storage.Detach(r.Context())
+		h.Store.UpdateTrigger(ctx, &previous)
+		cancel()
+		if wasEnabled {
+			if err := h.restartTrigger(&previous); err != nil {
+				log.Printf("Warning: failed to restart trigger %s: %v", triggerID, err)
+			}
 		}
+		WriteError(w, http.StatusUnprocessableEntity, "Failed to start trigger: "+startErr.Error())
+		return
 	}
+	existing.RuntimeStatus = runtimeStatus(existing)
+	existing.RuntimeError = nil
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(existing)
@@ -225,7 +335,7 @@ func (h *TriggerHandler) Update(w http.ResponseWriter, r *http.Request) {
 func (h *TriggerHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	triggerID := r.PathValue("id")
 	if triggerID == "" {
-		http.Error(w, "Missing trigger ID", http.StatusBadRequest)
+		WriteError(w, http.StatusBadRequest, "Missing trigger ID")
 		return
 	}
 
@@ -234,24 +344,91 @@ func (h *TriggerHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	// Delete from database
 	if err := h.Store.DeleteTrigger(r.Context(), triggerID); err != nil {
-		http.Error(w, "Failed to delete trigger: "+err.Error(), http.StatusInternalServerError)
+		WriteError(w, http.StatusInternalServerError, "Failed to delete trigger: "+err.Error())
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// Fire handles POST /api/triggers/{id}/fire
+// Queues one run of the trigger's workflow, regardless of trigger type, with an optional JSON body as payload.
+// A request repeating the Idempotency-Key of an earlier one answers with that one's execution instead.
+// The X-Correlation-ID and X-Execution-Labels headers tag the executions it starts.
+func (h *TriggerHandler) Fire(w http.ResponseWriter, r *http.Request) {
+	triggerID := r.PathValue("id")
+	if triggerID == "" {
+		WriteError(w, http.StatusBadRequest, "Missing trigger ID")
+		return
+	}
+
+	if _, exists := h.TriggerManager.GetTrigger(triggerID); !exists {
+		WriteError(w, http.StatusNotFound, "Trigger not found or not running")
+		return
+	}
+	trigger, err := h.Store.GetTrigger(r.Context(), triggerID)
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "Trigger not found: "+err.Error())
+		return
+	}
+	if err := h.TriggerManager.Quotas().Check(r.Context(), trigger.WorkflowID, time.Now()); err != nil {
+		if !WriteQuotaError(w, err) {
+			WriteError(w, http.StatusInternalServerError, "Failed to check quota: "+err.Error())
+		}
+		return
+	}
+
+	labels, err := LabelsFromHeaders(r.Header)
+	if err != nil {
+		writeValidationError(w, "Invalid labels", err)
+		return
+	}
+
+	var payload map[string]interface{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && err != io.EOF {
+			WriteError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+			return
+		}
+	}
+
+	// The run outlives this request, so detach it from the request's cancellation
+	ctx := engine.WithLabels(context.WithoutCancel(r.Context()), labels)
+	executionID, duplicate, err := h.TriggerManager.FireOnce(ctx, triggerID, r.Header.Get(idempotencyKeyHeader), payload)
+	if err != nil {
+		if !writeMaintenanceError(w, err) {
+			WriteError(w, http.StatusInternalServerError, "Failed to fire trigger: "+err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if duplicate {
+		writeDuplicateFire(w, executionID)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "queued"})
+}
+
+// writeDuplicateFire answers a fire request whose idempotency key was seen
+// before with the execution of the first delivery, empty while it is starting.
+func writeDuplicateFire(w http.ResponseWriter, executionID string) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "duplicate", "execution_id": executionID})
+}
+
 // ListExecutions handles GET /api/triggers/{id}/executions
 func (h *TriggerHandler) ListExecutions(w http.ResponseWriter, r *http.Request) {
 	triggerID := r.PathValue("id")
 	if triggerID == "" {
-		http.Error(w, "Missing trigger ID", http.StatusBadRequest)
+		WriteError(w, http.StatusBadRequest, "Missing trigger ID")
 		return
 	}
 
 	executions, err := h.Store.ListTriggerExecutions(r.Context(), triggerID, 100)
 	if err != nil {
-		http.Error(w, "Failed to list executions: "+err.Error(), http.StatusInternalServerError)
+		WriteError(w, http.StatusInternalServerError, "Failed to list executions: "+err.Error())
 		return
 	}
 
@@ -259,107 +436,291 @@ func (h *TriggerHandler) ListExecutions(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(executions)
 }
 
+// NextRunsResponse lists the upcoming fire times of a scheduled trigger.
+type NextRunsResponse struct {
+	TriggerID string `json:"trigger_id"`
+	// Timezone is the zone the schedule runs in and the runs are expressed in.
+	Timezone string      `json:"timezone"`
+	Runs     []time.Time `json:"runs"`
+}
+
+// NextRuns handles GET /api/triggers/{id}/next-runs?count=5, returning when a
+// cron or interval trigger fires next, to verify its schedule expression.
+func (h *TriggerHandler) NextRuns(w http.ResponseWriter, r *http.Request) {
+	triggerID := r.PathValue("id")
+	if triggerID == "" {
+		WriteError(w, http.StatusBadRequest, "Missing trigger ID")
+		return
+	}
+
+	count := 5
+	if raw := r.URL.Query().Get("count"); raw != "" {
+		if v, err := strconv.Atoi(raw); err == nil && v > 0 && v <= 100 {
+			count = v
+		}
+	}
+
+	trigger, err := h.Store.GetTrigger(r.Context(), triggerID)
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "Trigger not found")
+		return
+	}
+	history, err := h.Store.ListTriggerExecutions(r.Context(), triggerID, 1)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to list executions: "+err.Error())
+		return
+	}
+	var lastFired *time.Time
+	if len(history) > 0 {
+		lastFired = &history[0].FiredAt
+	}
+
+	runs, err := engine.NextTriggerRuns(trigger, lastFired, time.Now(), count)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "Cannot compute next runs: "+err.Error())
+		return
+	}
+
+	resp := NextRunsResponse{TriggerID: triggerID, Timezone: time.Local.String(), Runs: runs}
+	if len(runs) > 0 {
+		resp.Timezone = runs[0].Location().String()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// TriggerStatsResponse counts the runs of a trigger left out of execution
+// history by its sampling ({"sample_every": N}), per day in UTC.
+type TriggerStatsResponse struct {
+	TriggerID  string          `json:"trigger_id"`
+	SampledOut int             `json:"sampled_out"` // Over all listed days
+	Days       []TriggerDayRun `json:"days"`
+}
+
+// TriggerDayRun is the run count of a trigger on one day.
+type TriggerDayRun struct {
+	Day        string `json:"day"` // YYYY-MM-DD
+	SampledOut int    `json:"sampled_out"`
+}
+
+// Stats handles GET /api/triggers/{id}/stats?days=30, returning the daily
+// counts of the trigger's sampled out runs over the last days (today included).
+// Days without such runs are left out.
+func (h *TriggerHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	triggerID := r.PathValue("id")
+	if triggerID == "" {
+		WriteError(w, http.StatusBadRequest, "Missing trigger ID")
+		return
+	}
+
+	days := 30
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		if v, err := strconv.Atoi(raw); err == nil && v > 0 && v <= 366 {
+			days = v
+		}
+	}
+
+	if _, err := h.Store.GetTrigger(r.Context(), triggerID); err != nil {
+		WriteError(w, http.StatusNotFound, "Trigger not found")
+		return
+	}
+	since := time.Now().UTC().AddDate(0, 0, 1-days)
+	counts, err := h.Store.ListTriggerRunCounts(r.Context(), triggerID, since)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to list run counts: "+err.Error())
+		return
+	}
+
+	resp := TriggerStatsResponse{TriggerID: triggerID, Days: []TriggerDayRun{}}
+	for _, c := range counts {
+		resp.SampledOut += c.SampledOut
+		resp.Days = append(resp.Days, TriggerDayRun{Day: c.Day, SampledOut: c.SampledOut})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// checkBindings checks that the workflows bound by a trigger config exist,
+// returning the HTTP status to fail with. The bindings themselves are
+// validated with the rest of the request.
+func (h *TriggerHandler) checkBindings(ctx context.Context, config map[string]interface{}) (int, error) {
+	bindings, err := engine.TriggerBindings(config)
+	if err != nil {
+		return http.StatusUnprocessableEntity, err
+	}
+	for _, binding := range bindings {
+		if _, err := h.Store.GetWorkflow(ctx, binding.WorkflowID); err != nil {
+			return http.StatusNotFound, fmt.Errorf("bound workflow %s not found: %w", binding.WorkflowID, err)
+		}
+	}
+	return 0, nil
+}
+
+// runtimeStatus is the runtime status of a trigger that was just saved and,
+// if enabled, started.
+func runtimeStatus(trigger *storage.Trigger) storage.TriggerRuntimeStatus {
+	if trigger.Enabled {
+		return storage.TriggerRuntimeRunning
+	}
+	return storage.TriggerRuntimeStopped
+}
+
 // registerTrigger creates and registers a trigger runner with the TriggerManager
 func (h *TriggerHandler) registerTrigger(trigger *storage.Trigger) error {
+	runner, err := h.newTriggerRunner(trigger)
+	if err != nil {
+		return err
+	}
+	return h.TriggerManager.Register(runner)
+}
+
+// restartTrigger replaces the running runner of a trigger with a new one
+func (h *TriggerHandler) restartTrigger(trigger *storage.Trigger) error {
+	runner, err := h.newTriggerRunner(trigger)
+	if err != nil {
+		return err
+	}
+	return h.TriggerManager.Restart(runner)
+}
+
+// newTriggerRunner creates the runner of a stored trigger
+func (h *TriggerHandler) newTriggerRunner(trigger *storage.Trigger) (engine.TriggerRunner, error) {
 	// Parse config
 	var config map[string]interface{}
 	if err := json.Unmarshal(trigger.Config, &config); err != nil {
-		return fmt.Errorf("failed to parse trigger config: %w", err)
+		return nil, fmt.Errorf("failed to parse trigger config: %w", err)
 	}
 
 	var runner engine.TriggerRunner
 
 	switch engine.TriggerType(trigger.Type) {
 	case engine.TriggerTypeCron:
-		schedule, ok := config["schedule"].(string)
-		if !ok {
-			return fmt.Errorf("cron trigger requires 'schedule' field")
+		schedule, err := engine.CronSpec(config)
+		if err != nil {
+			return nil, err
 		}
 		runner = engine.NewCronTrigger(trigger.ID, trigger.WorkflowID, schedule, h.TriggerManager)
 
 	case engine.TriggerTypeInterval:
 		intervalSec, ok := config["interval"].(float64)
 		if !ok {
-			return fmt.Errorf("interval trigger requires 'interval' field (seconds)")
+			return nil, fmt.Errorf("interval trigger requires 'interval' field (seconds)")
 		}
 		interval := time.Duration(intervalSec) * time.Second
 		runner = engine.NewIntervalTrigger(trigger.ID, trigger.WorkflowID, interval, h.TriggerManager)
 
 	case engine.TriggerTypeWebhook:
-		runner = engine.NewWebhookTrigger(trigger.ID, trigger.WorkflowID, h.TriggerManager)
+		path, err := engine.WebhookPath(config)
+		if err != nil {
+			return nil, err
+		}
+		webhook := engine.NewWebhookTrigger(trigger.ID, trigger.WorkflowID, h.TriggerManager)
+		webhook.SetPath(path)
+		runner = webhook
+
+	case engine.TriggerTypeWebSocket:
+		runner = engine.NewWebSocketTrigger(trigger.ID, trigger.WorkflowID, h.TriggerManager)
 
 	case engine.TriggerTypeTS: // Handle TypeScript triggers
 		if trigger.FilePath == "" {
-			return fmt.Errorf("typescript trigger requires 'file_path'")
+			return nil, fmt.Errorf("typescript trigger requires 'file_path'")
 		}
 		runner = engine.NewTSTriggerRunner(trigger.ID, trigger.WorkflowID, trigger.FilePath, config, h.TriggerManager)
 
 	default:
-		return fmt.Errorf("unsupported trigger type: %s", trigger.Type)
+		return nil, fmt.Errorf("unsupported trigger type: %s", trigger.Type)
 	}
 
-	return h.TriggerManager.Register(runner)
+	return runner, nil
 }
 
-// HandleWebhook handles POST /api/webhooks/{id}
-func (h *TriggerHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
-	triggerID := r.PathValue("id")
-	if triggerID == "" {
-		http.Error(w, "Missing trigger ID", http.StatusBadRequest)
-		return
-	}
+// ListWebhookRoutes handles GET /api/webhooks: the paths the registered
+// webhook triggers are served at, under /api/webhooks/.
+func (h *TriggerHandler) ListWebhookRoutes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.TriggerManager.WebhookRoutes())
+}
 
-	// Get trigger from manager to access its runner instance
-	triggerRunner, exists := h.TriggerManager.GetTrigger(triggerID)
+// HandleWebhook handles POST /api/webhooks/{path...}: the trigger's ID or the
+// custom "path" of its config, routed by the TriggerManager while the trigger
+// is registered.
+// For Go-native webhook triggers, a call repeating the Idempotency-Key of an
+// earlier one answers with that one's execution instead of firing again.
+// The X-Correlation-ID and X-Execution-Labels headers tag the executions it
+// starts, also for TS webhook triggers, which are passed them to fire with.
+func (h *TriggerHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
+	// Only running webhook triggers are routed
+	triggerRunner, exists := h.TriggerManager.WebhookTarget(r.PathValue("path"))
 	if !exists {
-		http.Error(w, "Trigger not found", http.StatusNotFound)
+		WriteError(w, http.StatusNotFound, "Trigger not found")
 		return
 	}
+	triggerID := triggerRunner.ID()
 
 	// Get trigger details from storage (for enabled status and type validation)
 	triggerFromStore, err := h.Store.GetTrigger(r.Context(), triggerID)
 	if err != nil {
-		http.Error(w, "Trigger not found: "+err.Error(), http.StatusNotFound)
+		WriteError(w, http.StatusNotFound, "Trigger not found: "+err.Error())
 		return
 	}
 
 	if !triggerFromStore.Enabled {
-		http.Error(w, "Trigger is disabled", http.StatusForbidden)
+		WriteError(w, http.StatusForbidden, "Trigger is disabled")
 		return
 	}
 
 	// Ensure it's a webhook trigger (either Go-native or TS-based webhook)
-	if triggerFromStore.Type != string(engine.TriggerTypeWebhook) && triggerFromStore.Type != string(engine.TriggerTypeTS) {
-		http.Error(w, "Trigger is not a webhook type", http.StatusBadRequest)
+	if !isWebhookType(triggerFromStore.Type) {
+		WriteError(w, http.StatusBadRequest, "Trigger is not a webhook type")
 		return
 	}
 
-	// Read body
-	var body interface{}
-	if r.Body != nil {
-		defer r.Body.Close()
-		// Try to parse as JSON. If not JSON, it will be nil, which is acceptable.
-		json.NewDecoder(r.Body).Decode(&body)
+	// Check the signed URL token, if any
+	var config map[string]interface{}
+	json.Unmarshal(triggerFromStore.Config, &config)
+	scope, err := h.checkWebhookToken(r, triggerID, config)
+	if err != nil {
+		WriteError(w, http.StatusUnauthorized, "Unauthorized: "+err.Error())
+		return
 	}
 
-	// Construct payload
-	payload := map[string]interface{}{
-		"headers": r.Header,
-		"method":  r.Method,
-		"query":   r.URL.Query(),
-		"body":    body,
+	payload, err := webhookPayload(r, config, scope)
+	if err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	labels, err := LabelsFromHeaders(r.Header)
+	if err != nil {
+		writeValidationError(w, "Invalid labels", err)
+		return
+	}
+	ctx := engine.WithLabels(r.Context(), labels)
+
+	// A workflow over its quota is refused up front, so senders back off and retry
+	if err := h.TriggerManager.Quotas().Check(r.Context(), triggerFromStore.WorkflowID, time.Now()); err != nil {
+		if !WriteQuotaError(w, err) {
+			WriteError(w, http.StatusInternalServerError, "Failed to check quota: "+err.Error())
+		}
+		return
 	}
 
 	// Check if it's a TypeScript trigger runner and invoke it directly
 	if tsRunner, ok := triggerRunner.(*engine.TSTriggerRunner); ok {
-		if err := tsRunner.Invoke(r.Context(), payload); err != nil {
-			http.Error(w, "Failed to invoke TS webhook trigger: "+err.Error(), http.StatusInternalServerError)
+		if err := tsRunner.InvokeWithKey(ctx, r.Header.Get(idempotencyKeyHeader), payload); err != nil {
+			WriteError(w, http.StatusInternalServerError, "Failed to invoke TS webhook trigger: "+err.Error())
 			return
 		}
 	} else {
 		// Fallback for old Go-native webhook triggers
-		if err := h.TriggerManager.Fire(r.Context(), triggerID, payload); err != nil {
-			http.Error(w, "Failed to fire Go-native webhook trigger: "+err.Error(), http.StatusInternalServerError)
+		executionID, duplicate, err := h.TriggerManager.FireOnce(ctx, triggerID, r.Header.Get(idempotencyKeyHeader), payload)
+		if err != nil {
+			if !writeMaintenanceError(w, err) {
+				WriteError(w, http.StatusInternalServerError, "Failed to fire Go-native webhook trigger: "+err.Error())
+			}
+			return
+		}
+		if duplicate {
+			writeDuplicateFire(w, executionID)
 			return
 		}
 	}
@@ -367,3 +728,149 @@ func (h *TriggerHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
+
+// webhookPayload builds the trigger payload of a webhook call to a trigger with
+// config. scope is the scope of its signed URL, if any.
+func webhookPayload(r *http.Request, config map[string]interface{}, scope string) (map[string]interface{}, error) {
+	body, err := readWebhookBody(r, webhookMaxBody(config))
+	if err != nil {
+		return nil, err
+	}
+
+	// Construct payload; the token stays out of it, and so out of execution history
+	query := r.URL.Query()
+	query.Del("token")
+	payload := map[string]interface{}{
+		"headers":      r.Header,
+		"method":       r.Method,
+		"query":        query,
+		"body":         body.Value,
+		"content_type": r.Header.Get("Content-Type"),
+	}
+	if body.Encoding != "" {
+		payload["body_encoding"] = body.Encoding
+	}
+	if scope != "" {
+		payload["scope"] = scope
+	}
+	return payload, nil
+}
+
+// websocketTriggerConfig is the part of a websocket trigger's config read when
+// a client connects.
+type websocketTriggerConfig struct {
+	// ReplyNode makes each frame wait for its run and sends the output of this
+	// node back on the socket.
+	ReplyNode string `json:"reply_node"`
+	// Origins lists the host patterns of other origins allowed to connect.
+	Origins []string `json:"origins"`
+}
+
+// HandleWebSocket handles GET /api/ws/{id}. Every frame a client sends fires the
+// trigger's workflow with {"body", "headers", "query", "connection"}, plus the
+// "scope" of a signed URL; the body is the frame's JSON value, or its text when
+// it isn't JSON. Connections are authorized like webhook calls (see
+// checkWebhookToken), when they aren't by the API key.
+func (h *TriggerHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	triggerID := r.PathValue("id")
+	if triggerID == "" {
+		WriteError(w, http.StatusBadRequest, "Missing trigger ID")
+		return
+	}
+
+	if _, exists := h.TriggerManager.GetTrigger(triggerID); !exists {
+		WriteError(w, http.StatusNotFound, "Trigger not found")
+		return
+	}
+	trigger, err := h.Store.GetTrigger(r.Context(), triggerID)
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "Trigger not found: "+err.Error())
+		return
+	}
+	if !trigger.Enabled {
+		WriteError(w, http.StatusForbidden, "Trigger is disabled")
+		return
+	}
+	if trigger.Type != string(engine.TriggerTypeWebSocket) {
+		WriteError(w, http.StatusBadRequest, "Trigger is not a websocket type")
+		return
+	}
+	var config websocketTriggerConfig
+	if err := json.Unmarshal(trigger.Config, &config); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Invalid trigger config: "+err.Error())
+		return
+	}
+	var rawConfig map[string]interface{}
+	json.Unmarshal(trigger.Config, &rawConfig)
+	scope, err := h.checkWebhookToken(r, triggerID, rawConfig)
+	if err != nil {
+		WriteError(w, http.StatusUnauthorized, "Unauthorized: "+err.Error())
+		return
+	}
+
+	// The connection stays open for as long as the client keeps it
+	clearDeadlines(w, true)
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: config.Origins})
+	if err != nil {
+		return // Accept has written the error response
+	}
+	defer conn.CloseNow()
+
+	ctx := r.Context()
+	connection := storage.NewID()
+	for {
+		_, frame, err := conn.Read(ctx)
+		if err != nil {
+			return // the client closed the connection or went away
+		}
+		var body interface{}
+		if json.Unmarshal(frame, &body) != nil {
+			body = string(frame)
+		}
+		payload := map[string]interface{}{
+			"headers":    r.Header,
+			"query":      r.URL.Query(),
+			"connection": connection,
+			"body":       body,
+		}
+		if scope != "" {
+			payload["scope"] = scope
+		}
+
+		if config.ReplyNode == "" {
+			// The run must outlive the connection
+			if err := h.TriggerManager.Fire(context.WithoutCancel(ctx), triggerID, payload); err != nil {
+				if errors.Is(err, engine.ErrMaintenance) {
+					conn.Close(websocket.StatusTryAgainLater, "server is in maintenance mode")
+					return
+				}
+				conn.Close(websocket.StatusInternalError, "failed to fire trigger")
+				return
+			}
+			continue
+		}
+
+		results, err := h.TriggerManager.FireSync(ctx, triggerID, payload)
+		if err := wsjson.Write(ctx, conn, websocketReply(results, config.ReplyNode, err)); err != nil {
+			return
+		}
+	}
+}
+
+// websocketReply builds the message sent back for a frame: the reply node's
+// output data, or {"error"} when the run failed before producing it.
+func websocketReply(results map[string]interface{}, replyNode string, err error) interface{} {
+	if err != nil {
+		return map[string]string{"error": err.Error()}
+	}
+	result, ok := results[replyNode]
+	if !ok {
+		return map[string]string{"error": fmt.Sprintf("node %q did not run", replyNode)}
+	}
+	if m, ok := result.(map[string]interface{}); ok {
+		if data, ok := m["data"]; ok {
+			return data
+		}
+	}
+	return result
+}
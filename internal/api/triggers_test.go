@@ -3,10 +3,17 @@ package api_test
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
 	"github.com/conv3n/conv3n/internal/api"
 	"github.com/conv3n/conv3n/internal/engine"
 	"github.com/conv3n/conv3n/internal/storage"
@@ -19,6 +26,7 @@ func newTriggerMux(t *testing.T) (*http.ServeMux, storage.Storage, *engine.Trigg
 	tm := engine.NewTriggerManager(store, t.TempDir(), registry, workerPool)
 
 	handler := api.NewTriggerHandler(store, tm)
+	handler.WebhookSecret = []byte("test-secret")
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("POST /api/triggers", handler.Create)
@@ -27,7 +35,15 @@ func newTriggerMux(t *testing.T) (*http.ServeMux, storage.Storage, *engine.Trigg
 	mux.HandleFunc("PUT /api/triggers/{id}", handler.Update)
 	mux.HandleFunc("DELETE /api/triggers/{id}", handler.Delete)
 	mux.HandleFunc("GET /api/triggers/{id}/executions", handler.ListExecutions)
-	mux.HandleFunc("POST /api/webhooks/{id}", handler.HandleWebhook)
+	mux.HandleFunc("GET /api/triggers/{id}/next-runs", handler.NextRuns)
+	mux.HandleFunc("GET /api/triggers/{id}/stats", handler.Stats)
+	mux.HandleFunc("POST /api/triggers/{id}/fire", handler.Fire)
+	mux.HandleFunc("POST /api/triggers/{id}/webhook-urls", handler.CreateWebhookURL)
+	mux.HandleFunc("POST /api/triggers/{id}/webhook-urls/rotate", handler.RotateWebhookURLs)
+	mux.HandleFunc("GET /api/webhooks", handler.ListWebhookRoutes)
+	mux.HandleFunc("POST /api/webhooks/{path...}", handler.HandleWebhook)
+	mux.HandleFunc("POST /api/webhooks-test/{id}", handler.HandleTestWebhook)
+	mux.HandleFunc("GET /api/ws/{id}", handler.HandleWebSocket)
 
 	return mux, store, tm
 }
@@ -67,6 +83,30 @@ func TestTriggerAPI_Create(t *testing.T) {
 	}
 }
 
+func TestTriggerAPI_CreateWithID(t *testing.T) {
+	mux, store, _ := newTriggerMux(t)
+	store.CreateWorkflow(testCtx, &storage.Workflow{ID: "wf-1", Name: "Test Workflow", Definition: []byte("{}")})
+
+	create := func(id string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(api.CreateTriggerRequest{ID: id, WorkflowID: "wf-1", Type: "webhook"})
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/triggers", bytes.NewReader(body)))
+		return rec
+	}
+	if rec := create("orders-hook"); rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := store.GetTrigger(testCtx, "orders-hook"); err != nil {
+		t.Errorf("expected the trigger to keep its ID: %v", err)
+	}
+	if rec := create("orders-hook"); rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409 for a taken ID, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := create("orders/hook"); rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"field":"id"`) {
+		t.Errorf("expected status 422 for an invalid ID, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
 func TestTriggerAPI_Webhook(t *testing.T) {
 	mux, store, _ := newTriggerMux(t)
 	ctx := testCtx
@@ -108,6 +148,149 @@ func TestTriggerAPI_Webhook(t *testing.T) {
 	}
 }
 
+// TestTriggerAPI_WebhookCustomPath verifies that a webhook trigger is served at
+// its custom path while it exists and enabled, and only then.
+func TestTriggerAPI_WebhookCustomPath(t *testing.T) {
+	mux, store, _ := newTriggerMux(t)
+	store.CreateWorkflow(testCtx, &storage.Workflow{ID: "wf-1", Name: "Test Workflow", Definition: []byte("{}")})
+
+	post := func(path string) int {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(`{}`)))
+		return rec.Code
+	}
+	save := func(method, path, body string) storage.Trigger {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+			t.Fatalf("%s %s: unexpected status %d: %s", method, path, rec.Code, rec.Body.String())
+		}
+		var trigger storage.Trigger
+		json.NewDecoder(rec.Body).Decode(&trigger)
+		return trigger
+	}
+
+	created := save(http.MethodPost, "/api/triggers", `{"workflow_id":"wf-1","type":"webhook","config":{"path":"/orders/created"},"enabled":true}`)
+	if code := post("/api/webhooks/orders/created"); code != http.StatusOK {
+		t.Errorf("expected the custom path to fire the trigger, got %d", code)
+	}
+	if code := post("/api/webhooks/" + created.ID); code != http.StatusOK {
+		t.Errorf("expected the trigger ID to keep working, got %d", code)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/webhooks", nil))
+	var routes []engine.WebhookRoute
+	json.NewDecoder(rec.Body).Decode(&routes)
+	if len(routes) != 2 || !slices.Contains(routes, engine.WebhookRoute{Path: "orders/created", TriggerID: created.ID}) {
+		t.Errorf("unexpected routes %+v", routes)
+	}
+
+	// A second trigger can't take the path
+	clash := `{"workflow_id":"wf-1","type":"webhook","config":{"path":"orders/created"},"enabled":true}`
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/triggers", strings.NewReader(clash)))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for a taken path, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Moving, disabling and deleting the trigger update its routes
+	save(http.MethodPut, "/api/triggers/"+created.ID, `{"workflow_id":"wf-1","type":"webhook","config":{"path":"orders/new"},"enabled":true}`)
+	if post("/api/webhooks/orders/created") != http.StatusNotFound || post("/api/webhooks/orders/new") != http.StatusOK {
+		t.Error("expected the route to move with the path")
+	}
+	save(http.MethodPut, "/api/triggers/"+created.ID, `{"workflow_id":"wf-1","type":"webhook","config":{"path":"orders/new"},"enabled":false}`)
+	if code := post("/api/webhooks/orders/new"); code != http.StatusNotFound {
+		t.Errorf("expected a disabled trigger not to be routed, got %d", code)
+	}
+	save(http.MethodPut, "/api/triggers/"+created.ID, `{"workflow_id":"wf-1","type":"webhook","config":{"path":"orders/new"},"enabled":true}`)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/triggers/"+created.ID, nil))
+	if code := post("/api/webhooks/orders/new"); code != http.StatusNotFound {
+		t.Errorf("expected a deleted trigger not to be routed, got %d", code)
+	}
+}
+
+func TestTriggerAPI_Fire(t *testing.T) {
+	mux, store, tm := newTriggerMux(t)
+	ctx := testCtx
+
+	t.Run("UnknownTrigger", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/triggers/missing/fire", nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", rec.Code)
+		}
+	})
+
+	t.Run("NonWebhookTrigger", func(t *testing.T) {
+		store.CreateWorkflow(ctx, &storage.Workflow{ID: "wf-fire", Name: "Fire WF", Definition: []byte(`{"id":"wf-fire","nodes":{},"edges":[]}`)})
+		store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-cron", WorkflowID: "wf-fire", Type: "cron", Config: []byte(`{}`), Enabled: true})
+		tm.Register(engine.NewCronTrigger("tr-cron", "wf-fire", "@yearly", tm))
+		t.Cleanup(func() { tm.Unregister("tr-cron") })
+
+		req := httptest.NewRequest(http.MethodPost, "/api/triggers/tr-cron/fire", bytes.NewBufferString(`{"manual":true}`))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		// The empty workflow fails to run, but the fire itself must be recorded with its payload
+		var execs []*storage.TriggerExecution
+		for deadline := time.Now().Add(2 * time.Second); len(execs) == 0 && time.Now().Before(deadline); {
+			time.Sleep(10 * time.Millisecond)
+			execs, _ = store.ListTriggerExecutions(ctx, "tr-cron", 10)
+		}
+		if len(execs) != 1 {
+			t.Fatalf("expected 1 trigger execution, got %d", len(execs))
+		}
+		if string(execs[0].Payload) != `{"manual":true}` {
+			t.Errorf("expected payload to be recorded, got %s", execs[0].Payload)
+		}
+	})
+
+	t.Run("IdempotencyKey", func(t *testing.T) {
+		store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-idem", WorkflowID: "wf-fire", Type: "cron", Config: []byte(`{}`), Enabled: true})
+		tm.Register(engine.NewCronTrigger("tr-idem", "wf-fire", "@yearly", tm))
+		t.Cleanup(func() { tm.Unregister("tr-idem") })
+
+		fire := func() *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/api/triggers/tr-idem/fire", nil)
+			req.Header.Set("Idempotency-Key", "delivery-1")
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			return rec
+		}
+		if rec := fire(); rec.Code != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var execs []*storage.TriggerExecution
+		for deadline := time.Now().Add(2 * time.Second); len(execs) == 0 && time.Now().Before(deadline); {
+			time.Sleep(10 * time.Millisecond)
+			execs, _ = store.ListTriggerExecutions(ctx, "tr-idem", 10)
+		}
+		if len(execs) != 1 || execs[0].ExecutionID == nil {
+			t.Fatalf("expected 1 trigger execution with an execution, got %v", execs)
+		}
+
+		rec := fire()
+		var resp map[string]string
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if rec.Code != http.StatusOK || resp["status"] != "duplicate" || resp["execution_id"] != *execs[0].ExecutionID {
+			t.Errorf("expected a duplicate of %s, got %d: %v", *execs[0].ExecutionID, rec.Code, resp)
+		}
+		time.Sleep(50 * time.Millisecond)
+		if execs, _ := store.ListTriggerExecutions(ctx, "tr-idem", 10); len(execs) != 1 {
+			t.Errorf("expected the retry not to fire again, got %d trigger executions", len(execs))
+		}
+	})
+}
+
 func TestTriggerAPI_ListExecutions(t *testing.T) {
 	mux, store, _ := newTriggerMux(t)
 	ctx := testCtx
@@ -135,6 +318,256 @@ func TestTriggerAPI_ListExecutions(t *testing.T) {
 	}
 }
 
+func TestTriggerAPI_ListHealth(t *testing.T) {
+	mux, store, _ := newTriggerMux(t)
+	ctx := testCtx
+
+	store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-cron", WorkflowID: "wf-health", Type: "cron", Config: []byte(`{"schedule":"0 * * * *"}`), Enabled: true})
+	store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-hook", WorkflowID: "wf-health", Type: "webhook", Config: []byte(`{}`), Enabled: true})
+	fired := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	for i, status := range []string{"failed", "success", "failed", "skipped", "failed"} {
+		store.CreateTriggerExecution(ctx, &storage.TriggerExecution{
+			ID:        fmt.Sprintf("te-%d", i),
+			TriggerID: "tr-cron",
+			FiredAt:   fired.Add(time.Duration(i) * time.Minute),
+			Status:    status,
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/triggers?workflow_id=wf-health", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var list []api.TriggerListItem
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	items := make(map[string]api.TriggerListItem)
+	for _, item := range list {
+		items[item.ID] = item
+	}
+
+	cron := items["tr-cron"]
+	if cron.LastFiredAt == nil || !cron.LastFiredAt.Equal(fired.Add(4*time.Minute)) || cron.LastStatus != "failed" {
+		t.Errorf("unexpected last fire: %v %q", cron.LastFiredAt, cron.LastStatus)
+	}
+	if cron.ConsecutiveFailures != 2 {
+		t.Errorf("expected 2 consecutive failures, got %d", cron.ConsecutiveFailures)
+	}
+	if cron.NextFireAt == nil || !cron.NextFireAt.After(time.Now()) || cron.NextFireAt.Minute() != 0 {
+		t.Errorf("expected the next full hour, got %v", cron.NextFireAt)
+	}
+
+	hook := items["tr-hook"]
+	if hook.LastFiredAt != nil || hook.ConsecutiveFailures != 0 || hook.NextFireAt != nil {
+		t.Errorf("expected a webhook that never fired, got %+v", hook)
+	}
+}
+
+func TestTriggerAPI_NextRuns(t *testing.T) {
+	mux, store, _ := newTriggerMux(t)
+	ctx := testCtx
+
+	store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-cron", WorkflowID: "wf-1", Type: "cron", Config: []byte(`{"schedule":"0 9 * * *","timezone":"America/New_York"}`)})
+	store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-hook", WorkflowID: "wf-1", Type: "webhook", Config: []byte(`{}`), Enabled: true})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/triggers/tr-cron/next-runs?count=3", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp api.NextRunsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Timezone != "America/New_York" || len(resp.Runs) != 3 {
+		t.Fatalf("expected 3 runs in America/New_York, got %+v", resp)
+	}
+	ny, _ := time.LoadLocation("America/New_York")
+	for i, run := range resp.Runs {
+		if local := run.In(ny); local.Hour() != 9 || local.Minute() != 0 {
+			t.Errorf("run %d: expected 09:00 New York time, got %s", i, local)
+		}
+		if i > 0 && !run.After(resp.Runs[i-1]) {
+			t.Errorf("expected runs in order, got %v", resp.Runs)
+		}
+	}
+
+	for url, code := range map[string]int{
+		"/api/triggers/tr-hook/next-runs": http.StatusBadRequest,
+		"/api/triggers/missing/next-runs": http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != code {
+			t.Errorf("%s: expected status %d, got %d", url, code, rec.Code)
+		}
+	}
+}
+
+func TestTriggerAPI_Stats(t *testing.T) {
+	mux, store, _ := newTriggerMux(t)
+	ctx := testCtx
+
+	store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-sampled", WorkflowID: "wf-1", Type: "interval", Config: []byte(`{"interval":"5s","sample_every":100}`)})
+	now := time.Now()
+	for _, at := range []time.Time{now.AddDate(0, 0, -40), now.AddDate(0, 0, -1), now, now} {
+		store.CountSampledOutRun(ctx, "tr-sampled", at)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/triggers/tr-sampled/stats", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp api.TriggerStatsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.SampledOut != 3 || len(resp.Days) != 2 || resp.Days[1].Day != now.UTC().Format(time.DateOnly) || resp.Days[1].SampledOut != 2 {
+		t.Errorf("expected the last 30 days of counts, got %+v", resp)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/triggers/missing/stats", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a missing trigger, got %d", rec.Code)
+	}
+}
+
+func TestTriggerAPI_RejectsInvalidSchedule(t *testing.T) {
+	mux, store, _ := newTriggerMux(t)
+	ctx := testCtx
+	store.CreateWorkflow(ctx, &storage.Workflow{ID: "wf-1", Name: "Test Workflow", Definition: []byte("{}")})
+	store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-cron", WorkflowID: "wf-1", Type: "cron", Config: []byte(`{"schedule":"@daily"}`)})
+
+	body := `{"workflow_id":"wf-1","type":"cron","config":{"schedule":"every morning"},"enabled":false}`
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/api/triggers", strings.NewReader(body)),
+		httptest.NewRequest(http.MethodPut, "/api/triggers/tr-cron", strings.NewReader(body)),
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"field":"config.schedule"`) {
+			t.Errorf("%s: expected 422 for config.schedule, got %d: %s", req.Method, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestTriggerAPI_ReportsInvalidFields(t *testing.T) {
+	mux, _, _ := newTriggerMux(t)
+
+	body := `{"type":"webhook","config":{"require_token":"yes","bindings":[{"filter":"true"}]}}`
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/triggers", strings.NewReader(body)))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Error struct {
+			Details api.ValidationDetails `json:"details"`
+		} `json:"error"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	var fields []string
+	for _, f := range resp.Error.Details.Fields {
+		fields = append(fields, f.Field)
+	}
+	// The bindings are checked once the config's types are right
+	if strings.Join(fields, ",") != "workflow_id,config.require_token" {
+		t.Errorf("expected workflow_id and config.require_token to be reported, got %v", fields)
+	}
+}
+
+func TestTriggerAPI_RegistrationFailure(t *testing.T) {
+	mux, store, tm := newTriggerMux(t)
+	ctx := testCtx
+	store.CreateWorkflow(ctx, &storage.Workflow{ID: "wf-1", Name: "Test Workflow", Definition: []byte("{}")})
+
+	// An interval trigger without an interval can't start, and is refused before being saved
+	broken := `{"workflow_id":"wf-1","type":"interval","config":{},"enabled":true}`
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/triggers", strings.NewReader(broken)))
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"field":"config.interval"`) {
+		t.Fatalf("expected 422 on create, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if triggers, _ := store.ListTriggers(ctx, "wf-1"); len(triggers) != 0 {
+		t.Errorf("expected the trigger to be rolled back, got %d triggers", len(triggers))
+	}
+
+	// Updating a running trigger into one that can't start keeps the old one
+	valid := `{"workflow_id":"wf-1","type":"interval","config":{"interval":3600},"enabled":true}`
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/triggers", strings.NewReader(valid)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created storage.Trigger
+	json.NewDecoder(rec.Body).Decode(&created)
+	defer tm.Unregister(created.ID)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/triggers/"+created.ID, strings.NewReader(broken)))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 on update, got %d: %s", rec.Code, rec.Body.String())
+	}
+	stored, err := store.GetTrigger(ctx, created.ID)
+	if err != nil || string(stored.Config) != `{"interval":3600}` || !stored.Enabled || stored.RuntimeStatus != storage.TriggerRuntimeRunning {
+		t.Errorf("expected the previous trigger to be kept, got %+v (%v)", stored, err)
+	}
+	if err := tm.Register(engine.NewWebhookTrigger(created.ID, "wf-1", tm)); err == nil {
+		t.Error("expected the previous trigger to be running again")
+	}
+}
+
+// TestTriggerAPI_RuntimeStatus verifies that triggers expose whether they are
+// actually running, which the enabled flag alone doesn't tell.
+func TestTriggerAPI_RuntimeStatus(t *testing.T) {
+	mux, store, tm := newTriggerMux(t)
+	store.CreateWorkflow(testCtx, &storage.Workflow{ID: "wf-1", Name: "Test Workflow", Definition: []byte("{}")})
+
+	save := func(method, path, body string) storage.Trigger {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		if rec.Code != http.StatusCreated && rec.Code != http.StatusOK {
+			t.Fatalf("%s %s: got %d: %s", method, path, rec.Code, rec.Body.String())
+		}
+		var tr storage.Trigger
+		json.NewDecoder(rec.Body).Decode(&tr)
+		return tr
+	}
+	created := save(http.MethodPost, "/api/triggers", `{"workflow_id":"wf-1","type":"webhook","config":{},"enabled":true}`)
+	defer tm.StopAll()
+	if created.RuntimeStatus != storage.TriggerRuntimeRunning {
+		t.Errorf("expected a created trigger to be running, got %q", created.RuntimeStatus)
+	}
+
+	// A crash leaves the trigger enabled but errored
+	tm.ReportCrash(created.ID, "bun process exited unexpectedly")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/triggers/"+created.ID, nil))
+	var got storage.Trigger
+	json.NewDecoder(rec.Body).Decode(&got)
+	if !got.Enabled || got.RuntimeStatus != storage.TriggerRuntimeErrored || got.RuntimeError == nil || *got.RuntimeError != "bun process exited unexpectedly" {
+		t.Errorf("expected an enabled, errored trigger, got %+v", got)
+	}
+
+	// Saving it restarts it
+	updated := save(http.MethodPut, "/api/triggers/"+created.ID, `{"workflow_id":"wf-1","type":"webhook","config":{},"enabled":true}`)
+	stored, _ := store.GetTrigger(testCtx, created.ID)
+	if updated.RuntimeStatus != storage.TriggerRuntimeRunning || stored.RuntimeStatus != storage.TriggerRuntimeRunning || stored.RuntimeError != nil {
+		t.Errorf("expected the restarted trigger to be running, got %q, stored %q %v", updated.RuntimeStatus, stored.RuntimeStatus, stored.RuntimeError)
+	}
+
+	disabled := save(http.MethodPut, "/api/triggers/"+created.ID, `{"workflow_id":"wf-1","type":"webhook","config":{},"enabled":false}`)
+	stored, _ = store.GetTrigger(testCtx, created.ID)
+	if disabled.RuntimeStatus != storage.TriggerRuntimeStopped || stored.RuntimeStatus != storage.TriggerRuntimeStopped {
+		t.Errorf("expected a disabled trigger to be stopped, got %q, stored %q", disabled.RuntimeStatus, stored.RuntimeStatus)
+	}
+}
+
 func TestTriggerAPI_CRUD(t *testing.T) {
 	mux, store, _ := newTriggerMux(t)
 	ctx := testCtx
@@ -218,3 +651,352 @@ func TestTriggerAPI_CRUD(t *testing.T) {
 		t.Errorf("Get after Delete: expected 404, got %d", getRec3.Code)
 	}
 }
+
+// TestTriggerAPI_WebSocket verifies that each frame sent to a websocket trigger
+// fires its workflow and that the reply node's output is sent back.
+func TestTriggerAPI_WebSocket(t *testing.T) {
+	mux, store, tm := newTriggerMux(t)
+	ctx := testCtx
+
+	wf := engine.Workflow{
+		ID: "wf-ws",
+		Nodes: map[string]engine.Node{
+			"echo": {ID: "echo", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{
+				"name": "said", "value": "{{ $trigger.body.text }}",
+			}},
+		},
+	}
+	wfBytes, _ := json.Marshal(wf)
+	store.CreateWorkflow(ctx, &storage.Workflow{ID: "wf-ws", Name: "WebSocket WF", Definition: wfBytes})
+	store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-ws", WorkflowID: "wf-ws", Type: "websocket", Config: []byte(`{"reply_node":"echo"}`), Enabled: true})
+	tm.Register(engine.NewWebSocketTrigger("tr-ws", "wf-ws", tm))
+	store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-hook", WorkflowID: "wf-ws", Type: "webhook", Config: []byte(`{}`), Enabled: true})
+	tm.Register(engine.NewWebhookTrigger("tr-hook", "wf-ws", tm))
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/ws/"
+
+	conn, _, err := websocket.Dial(ctx, wsURL+"tr-ws", nil)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.CloseNow()
+
+	for _, text := range []string{"hello", "again"} {
+		if err := wsjson.Write(ctx, conn, map[string]string{"text": text}); err != nil {
+			t.Fatalf("failed to send frame: %v", err)
+		}
+		var reply map[string]interface{}
+		if err := wsjson.Read(ctx, conn, &reply); err != nil {
+			t.Fatalf("failed to read reply: %v", err)
+		}
+		if reply["value"] != text {
+			t.Errorf("expected reply value %q, got %v", text, reply)
+		}
+	}
+	conn.Close(websocket.StatusNormalClosure, "")
+
+	execs, _ := store.ListTriggerExecutions(ctx, "tr-ws", 10)
+	if len(execs) != 2 {
+		t.Errorf("expected 2 trigger executions, got %d", len(execs))
+	}
+
+	if _, resp, err := websocket.Dial(ctx, wsURL+"tr-hook", nil); err == nil || resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected connecting to a webhook trigger to fail with 400, got %v", err)
+	}
+}
+
+// TestTriggerAPI_SignedWebSocketURLs verifies that a websocket trigger behind
+// the API key accepts connections through its signed URLs, which browsers can
+// open without setting headers, until they are rotated.
+func TestTriggerAPI_SignedWebSocketURLs(t *testing.T) {
+	mux, store, tm := newTriggerMux(t)
+	ctx := testCtx
+
+	wfBytes, _ := json.Marshal(engine.Workflow{ID: "wf-ws", Nodes: map[string]engine.Node{
+		"echo": {ID: "echo", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "scope", "value": "{{ $trigger.scope }}"}},
+	}})
+	store.CreateWorkflow(ctx, &storage.Workflow{ID: "wf-ws", Name: "WebSocket WF", Definition: wfBytes})
+	store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-ws", WorkflowID: "wf-ws", Type: "websocket", Config: []byte(`{"reply_node":"echo"}`), Enabled: true})
+	tm.Register(engine.NewWebSocketTrigger("tr-ws", "wf-ws", tm))
+
+	srv := httptest.NewServer(api.RequireAPIKey("api-key", mux))
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+	keyHeader := http.Header{"X-API-Key": {"api-key"}}
+
+	if _, resp, err := websocket.Dial(ctx, wsURL+"/api/ws/tr-ws", nil); err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected a connection without key or token to fail with 401, got %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/triggers/tr-ws/webhook-urls", nil)
+	req.Header = keyHeader
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to create URL: %v", err)
+	}
+	var signed api.WebhookURLResponse
+	json.NewDecoder(resp.Body).Decode(&signed)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || !strings.HasPrefix(signed.URL, "/api/ws/tr-ws?token=") {
+		t.Fatalf("expected a signed websocket URL, got %d %q", resp.StatusCode, signed.URL)
+	}
+
+	conn, _, err := websocket.Dial(ctx, wsURL+signed.URL, nil)
+	if err != nil {
+		t.Fatalf("failed to connect with the signed URL: %v", err)
+	}
+	if err := wsjson.Write(ctx, conn, map[string]string{"text": "hello"}); err != nil {
+		t.Fatalf("failed to send frame: %v", err)
+	}
+	var reply map[string]interface{}
+	if err := wsjson.Read(ctx, conn, &reply); err != nil {
+		t.Fatalf("failed to read reply: %v", err)
+	}
+	if reply["value"] != api.WebhookScopeProduction {
+		t.Errorf("expected the URL's scope in the payload, got %v", reply)
+	}
+	conn.Close(websocket.StatusNormalClosure, "")
+
+	if _, resp, err := websocket.Dial(ctx, wsURL+"/api/ws/tr-ws?token=forged", nil); err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected a forged token to fail with 401, got %v", err)
+	}
+	req, _ = http.NewRequest(http.MethodPost, srv.URL+"/api/triggers/tr-ws/webhook-urls/rotate", nil)
+	req.Header = keyHeader
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to rotate URLs: %v", err)
+	}
+	if _, resp, err := websocket.Dial(ctx, wsURL+signed.URL, nil); err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected a rotated URL to fail with 401, got %v", err)
+	}
+	conn, _, err = websocket.Dial(ctx, wsURL+"/api/ws/tr-ws", &websocket.DialOptions{HTTPHeader: keyHeader})
+	if err != nil {
+		t.Fatalf("expected the API key to still connect, got %v", err)
+	}
+	conn.Close(websocket.StatusNormalClosure, "")
+}
+
+// TestTriggerAPI_SignedWebhookURLs verifies that signed webhook URLs are
+// accepted until the trigger's URLs are rotated, and that a trigger requiring
+// them refuses unsigned and tampered calls.
+func TestTriggerAPI_SignedWebhookURLs(t *testing.T) {
+	mux, store, tm := newTriggerMux(t)
+	ctx := testCtx
+
+	store.CreateWorkflow(ctx, &storage.Workflow{ID: "wf-signed", Name: "Signed", Definition: []byte(`{"id":"wf-signed"}`)})
+	for id, config := range map[string]string{"tr-signed": `{"require_token": true}`, "tr-other": `{}`} {
+		store.CreateTrigger(ctx, &storage.Trigger{ID: id, WorkflowID: "wf-signed", Type: "webhook", Config: []byte(config), Enabled: true})
+		tm.Register(engine.NewWebhookTrigger(id, "wf-signed", tm))
+	}
+
+	post := func(url, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, url, bytes.NewBufferString(body)))
+		return rec
+	}
+	issue := func() api.WebhookURLResponse {
+		t.Helper()
+		rec := post("/api/triggers/tr-signed/webhook-urls", `{"scope": "test", "expires_in": "1h"}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp api.WebhookURLResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return resp
+	}
+
+	if rec := post("/api/webhooks/tr-signed", `{}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected an unsigned call to be refused, got %d", rec.Code)
+	}
+	if rec := post("/api/webhooks/tr-other", `{}`); rec.Code != http.StatusOK {
+		t.Errorf("expected an unsigned call to a trigger not requiring tokens to pass, got %d", rec.Code)
+	}
+
+	signed := issue()
+	if signed.Scope != "test" || signed.ExpiresAt == nil || time.Until(*signed.ExpiresAt) > time.Hour {
+		t.Errorf("unexpected signed URL %+v", signed)
+	}
+	if rec := post(signed.URL, `{}`); rec.Code != http.StatusOK {
+		t.Errorf("expected the signed URL to be accepted, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := post(signed.URL+"x", `{}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected a tampered token to be refused, got %d", rec.Code)
+	}
+	if rec := post("/api/webhooks/tr-other?token="+signed.Token, `{}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected a token for another trigger to be refused, got %d", rec.Code)
+	}
+	if rec := post("/api/triggers/tr-signed/webhook-urls", `{"scope": "staging"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected an unknown scope to be refused, got %d", rec.Code)
+	}
+
+	if rec := post("/api/triggers/tr-signed/webhook-urls/rotate", ``); rec.Code != http.StatusOK {
+		t.Fatalf("expected rotation to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := post(signed.URL, `{}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected a rotated URL to be refused, got %d", rec.Code)
+	}
+	if rec := post(issue().URL, `{}`); rec.Code != http.StatusOK {
+		t.Errorf("expected a URL issued after rotation to be accepted, got %d", rec.Code)
+	}
+
+	// An update can't lower the generation to bring rotated URLs back
+	update := httptest.NewRequest(http.MethodPut, "/api/triggers/tr-signed", bytes.NewBufferString(
+		`{"workflow_id": "wf-signed", "type": "webhook", "config": {"require_token": true, "token_generation": 0}, "enabled": true}`))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, update)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the update to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := post(signed.URL, `{}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected a rotated URL to stay refused after an update, got %d", rec.Code)
+	}
+}
+
+// TestTriggerAPI_TestWebhook verifies that a test webhook call streams the node
+// results back and leaves a test execution, but no trigger history, behind.
+func TestTriggerAPI_TestWebhook(t *testing.T) {
+	mux, store, _ := newTriggerMux(t)
+	ctx := testCtx
+
+	wf := engine.Workflow{
+		ID: "wf-test-hook",
+		Nodes: map[string]engine.Node{
+			"greet": {ID: "greet", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{
+				"name": "greeting", "value": "hello {{ $trigger.body.name }}",
+			}},
+		},
+	}
+	def, _ := json.Marshal(wf)
+	store.CreateWorkflow(ctx, &storage.Workflow{ID: wf.ID, Name: "Test hook", Definition: def})
+	// Test calls work before the trigger is enabled
+	store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-test-hook", WorkflowID: wf.ID, Type: "webhook", Config: []byte(`{}`)})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/webhooks-test/tr-test-hook", bytes.NewBufferString(`{"name": "ada"}`)))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("unexpected response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var lines []api.TestWebhookEvent
+	dec := json.NewDecoder(rec.Body)
+	for dec.More() {
+		var line api.TestWebhookEvent
+		if err := dec.Decode(&line); err != nil {
+			t.Fatalf("invalid line: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 2 || lines[0].Type != engine.EventNodeFinished || lines[1].Type != engine.EventExecutionFinished {
+		t.Fatalf("unexpected lines %+v", lines)
+	}
+	output, _ := lines[0].Output.(map[string]interface{})
+	if data, _ := output["data"].(map[string]interface{}); lines[0].NodeID != "greet" || data["value"] != "hello ada" {
+		t.Errorf("unexpected node line %+v", lines[0])
+	}
+	final := lines[1]
+	if final.Status != storage.ExecutionStatusCompleted || final.Results["greet"] == nil {
+		t.Errorf("unexpected final line %+v", final)
+	}
+
+	exec, err := store.GetExecution(ctx, final.ExecutionID)
+	if err != nil || !exec.Test {
+		t.Errorf("expected a test execution, got %+v (%v)", exec, err)
+	}
+	if history, _ := store.ListTriggerExecutions(ctx, "tr-test-hook", 10); len(history) != 0 {
+		t.Errorf("expected no trigger history, got %d entries", len(history))
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/webhooks-test/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown trigger, got %d", rec.Code)
+	}
+}
+
+// TestTriggerAPI_WebhookBodies verifies that webhook bodies are decoded by
+// their Content-Type and that oversized bodies are refused.
+func TestTriggerAPI_WebhookBodies(t *testing.T) {
+	mux, store, _ := newTriggerMux(t)
+	ctx := testCtx
+
+	wf := engine.Workflow{
+		ID: "wf-bodies",
+		Nodes: map[string]engine.Node{
+			"echo": {ID: "echo", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{
+				"name": "payload", "value": "{{ $trigger }}",
+			}},
+		},
+	}
+	def, _ := json.Marshal(wf)
+	store.CreateWorkflow(ctx, &storage.Workflow{ID: wf.ID, Name: "Bodies", Definition: def})
+	store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-bodies", WorkflowID: wf.ID, Type: "webhook", Config: []byte(`{"max_body_size": 512}`)})
+
+	// send posts body to the test endpoint and returns the payload the workflow saw
+	send := func(contentType string, body []byte) (int, map[string]interface{}) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/webhooks-test/tr-bodies", bytes.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			return rec.Code, nil
+		}
+		var final api.TestWebhookEvent
+		for dec := json.NewDecoder(rec.Body); dec.More(); {
+			dec.Decode(&final)
+		}
+		echo, _ := final.Results["echo"].(map[string]interface{})
+		data, _ := echo["data"].(map[string]interface{})
+		payload, _ := data["value"].(map[string]interface{})
+		return rec.Code, payload
+	}
+
+	_, payload := send("application/json", []byte(`{"name": "ada"}`))
+	if body, _ := payload["body"].(map[string]interface{}); body["name"] != "ada" {
+		t.Errorf("unexpected JSON payload %v", payload)
+	}
+	if code, _ := send("application/json", []byte(`{"name": `)); code != http.StatusBadRequest {
+		t.Errorf("expected invalid JSON to be refused, got %d", code)
+	}
+
+	_, payload = send("application/x-www-form-urlencoded", []byte("name=ada&tag=a&tag=b"))
+	if body, _ := payload["body"].(map[string]interface{}); body["name"] != "ada" || len(body["tag"].([]interface{})) != 2 {
+		t.Errorf("unexpected form payload %v", payload)
+	}
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	mw.WriteField("name", "ada")
+	fw, _ := mw.CreateFormFile("file", "hello.txt")
+	fw.Write([]byte("hello"))
+	mw.Close()
+	_, payload = send(mw.FormDataContentType(), form.Bytes())
+	body, _ := payload["body"].(map[string]interface{})
+	if file, _ := body["file"].(map[string]interface{}); body["name"] != "ada" || file["filename"] != "hello.txt" || file["data"] != "aGVsbG8=" {
+		t.Errorf("unexpected multipart payload %v", payload)
+	}
+
+	_, payload = send("text/plain", []byte("hello"))
+	if payload["body"] != "hello" || payload["content_type"] != "text/plain" {
+		t.Errorf("unexpected text payload %v", payload)
+	}
+	_, payload = send("application/octet-stream", []byte{0xff, 0x00, 0xfe})
+	if payload["body"] != "/wD+" || payload["body_encoding"] != "base64" {
+		t.Errorf("unexpected binary payload %v", payload)
+	}
+
+	if code, _ := send("text/plain", bytes.Repeat([]byte("x"), 513)); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status 413 for an oversized body, got %d", code)
+	}
+	mw = multipart.NewWriter(&form)
+	form.Reset()
+	fw, _ = mw.CreateFormFile("file", "big.bin")
+	fw.Write(bytes.Repeat([]byte("x"), 1024))
+	mw.Close()
+	if code, _ := send(mw.FormDataContentType(), form.Bytes()); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status 413 for an oversized upload, got %d", code)
+	}
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// UIHandler serves the editor UI build in fsys. Paths that aren't files of the
+// build get its index.html, so the UI's client-side routes load on a refresh
+// or from a link; a missing file with an extension is a 404, not a route.
+// Vite fingerprints the files under assets/, which are cached for good; the
+// rest are revalidated on every load so a new build is picked up.
+func UIHandler(fsys fs.FS) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Unknown API routes fall through to / but are no UI route
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			WriteError(w, http.StatusNotFound, "Not found")
+			return
+		}
+
+		name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+		if info, err := fs.Stat(fsys, name); name == "" || err != nil || info.IsDir() {
+			if path.Ext(name) != "" {
+				http.NotFound(w, r)
+				return
+			}
+			name = "index.html"
+		}
+
+		if strings.HasPrefix(name, "assets/") {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+		http.ServeFileFS(w, r, fsys, name)
+	})
+}
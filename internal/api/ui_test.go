@@ -0,0 +1,55 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/conv3n/conv3n/internal/api"
+)
+
+func TestUIHandler(t *testing.T) {
+	ui := fstest.MapFS{
+		"index.html":         {Data: []byte("<html>editor</html>")},
+		"assets/app-1a2b.js": {Data: []byte("console.log(1)")},
+		"favicon.svg":        {Data: []byte("<svg/>")},
+	}
+	handler := api.UIHandler(ui)
+
+	tests := []struct {
+		name     string
+		path     string
+		status   int
+		body     string
+		cacheTTL bool
+	}{
+		{"Index", "/", http.StatusOK, "editor", false},
+		{"Asset", "/assets/app-1a2b.js", http.StatusOK, "console.log", true},
+		{"File", "/favicon.svg", http.StatusOK, "<svg/>", false},
+		{"ClientRoute", "/workflows/wf-1/edit", http.StatusOK, "editor", false},
+		{"Directory", "/assets/", http.StatusOK, "editor", false},
+		{"MissingAsset", "/assets/gone.js", http.StatusNotFound, "", false},
+		{"UnknownAPIRoute", "/api/nope", http.StatusNotFound, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rec.Code)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			if !strings.Contains(rec.Body.String(), tt.body) {
+				t.Errorf("expected body to contain %q, got %q", tt.body, rec.Body.String())
+			}
+			cache := rec.Header().Get("Cache-Control")
+			if tt.cacheTTL != strings.Contains(cache, "immutable") || (!tt.cacheTTL && cache != "no-cache") {
+				t.Errorf("unexpected Cache-Control %q", cache)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// ValidateHandler checks user input, such as schedule expressions, before it is saved.
+type ValidateHandler struct{}
+
+// NewValidateHandler creates a new validate handler
+func NewValidateHandler() *ValidateHandler {
+	return &ValidateHandler{}
+}
+
+// ValidateCronRequest is the body of POST /api/validate/cron.
+type ValidateCronRequest struct {
+	Schedule string `json:"schedule"`
+	// Timezone is the IANA zone the schedule runs in; the server's local time by default.
+	Timezone string `json:"timezone,omitempty"`
+	// Count is how many next occurrences to return, 5 by default and at most 100.
+	Count int `json:"count,omitempty"`
+}
+
+// ValidateCronResponse tells whether a schedule is valid and, if so, how it reads
+// and when it fires next.
+type ValidateCronResponse struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+	// Schedule is the normalized expression, with @daily and the like expanded.
+	Schedule    string      `json:"schedule,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Timezone    string      `json:"timezone,omitempty"`
+	NextRuns    []time.Time `json:"next_runs,omitempty"`
+}
+
+// Cron handles POST /api/validate/cron. An invalid schedule is reported with
+// valid=false and status 200; only a malformed request is a 400.
+func (h *ValidateHandler) Cron(w http.ResponseWriter, r *http.Request) {
+	var req ValidateCronRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+	count := 5
+	if req.Count > 0 && req.Count <= 100 {
+		count = req.Count
+	}
+
+	var resp ValidateCronResponse
+	config := map[string]interface{}{"schedule": req.Schedule, "timezone": req.Timezone}
+	spec, err := engine.CronSpec(config)
+	if err == nil {
+		loc := time.Local
+		if req.Timezone != "" {
+			loc, _ = time.LoadLocation(req.Timezone) // checked by CronSpec
+		}
+		resp.NextRuns, err = engine.CronOccurrences(spec, time.Now().In(loc), count)
+		resp.Timezone = loc.String()
+	}
+	if err != nil {
+		resp = ValidateCronResponse{Error: err.Error()}
+	} else {
+		resp.Valid = true
+		resp.Schedule, resp.Description = engine.DescribeCron(req.Schedule)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,69 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/api"
+)
+
+func TestValidateAPI_Cron(t *testing.T) {
+	handler := api.NewValidateHandler()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/validate/cron", handler.Cron)
+
+	validate := func(body string) (int, api.ValidateCronResponse) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/validate/cron", bytes.NewReader([]byte(body))))
+		var resp api.ValidateCronResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return rec.Code, resp
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		code, resp := validate(`{"schedule":"0  9 * * 1-5","timezone":"Europe/Berlin","count":3}`)
+		if code != http.StatusOK || !resp.Valid {
+			t.Fatalf("expected a valid schedule, got %d %+v", code, resp)
+		}
+		if resp.Schedule != "0 9 * * 1-5" || resp.Description != "At 09:00 on Monday through Friday" {
+			t.Errorf("unexpected normalization: %q, %q", resp.Schedule, resp.Description)
+		}
+		berlin, _ := time.LoadLocation("Europe/Berlin")
+		if resp.Timezone != "Europe/Berlin" || len(resp.NextRuns) != 3 {
+			t.Fatalf("expected 3 runs in Europe/Berlin, got %+v", resp)
+		}
+		for _, run := range resp.NextRuns {
+			if local := run.In(berlin); local.Hour() != 9 || local.Weekday() == time.Saturday || local.Weekday() == time.Sunday {
+				t.Errorf("expected weekdays at 09:00 Berlin time, got %s", local)
+			}
+		}
+	})
+
+	t.Run("Descriptor", func(t *testing.T) {
+		_, resp := validate(`{"schedule":"@daily"}`)
+		if !resp.Valid || resp.Schedule != "0 0 * * *" || len(resp.NextRuns) != 5 {
+			t.Errorf("expected @daily expanded with 5 runs, got %+v", resp)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, body := range []string{
+			`{"schedule":"61 * * * *"}`,
+			`{"schedule":"* * * *"}`,
+			`{"schedule":""}`,
+			`{"schedule":"* * * * *","timezone":"Mars/Olympus"}`,
+		} {
+			code, resp := validate(body)
+			if code != http.StatusOK || resp.Valid || resp.Error == "" || resp.NextRuns != nil {
+				t.Errorf("%s: expected valid=false with an error, got %d %+v", body, code, resp)
+			}
+		}
+		if code, _ := validate(`not json`); code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for malformed JSON, got %d", code)
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// ValidationDetails are the details of a 422 refusing a request for invalid
+// fields, one entry per problem:
+//
+//	{"fields": [{"field": "config.schedule", "message": "is required"}]}
+type ValidationDetails struct {
+	Fields []engine.FieldError `json:"fields"`
+}
+
+// fieldErrors flattens err, as joined by errors.Join, into the field errors it
+// holds. Problems that aren't about a field are reported with an empty field.
+func fieldErrors(err error) []engine.FieldError {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var fields []engine.FieldError
+		for _, e := range joined.Unwrap() {
+			fields = append(fields, fieldErrors(e)...)
+		}
+		return fields
+	}
+	var fe *engine.FieldError
+	if errors.As(err, &fe) {
+		return []engine.FieldError{*fe}
+	}
+	return []engine.FieldError{{Message: err.Error()}}
+}
+
+// writeValidationError answers with a 422 listing the problems of err in its
+// details. The message sums them up after prefix, so that clients showing only
+// the message still tell what is wrong.
+func writeValidationError(w http.ResponseWriter, prefix string, err error) {
+	fields := fieldErrors(err)
+	problems := make([]string, len(fields))
+	for i, f := range fields {
+		problems[i] = (&f).Error()
+		if f.Field == "" {
+			problems[i] = f.Message
+		}
+	}
+	WriteErrorDetails(w, http.StatusUnprocessableEntity, prefix+": "+strings.Join(problems, "; "), ValidationDetails{Fields: fields})
+}
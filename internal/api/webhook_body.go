@@ -0,0 +1,161 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// defaultWebhookMaxBody is the body size limit of webhook calls whose trigger
+// doesn't set "max_body_size" (in bytes) in its config.
+const defaultWebhookMaxBody = 10 << 20
+
+// webhookMaxBody returns the body size limit of a webhook trigger.
+func webhookMaxBody(config map[string]interface{}) int64 {
+	if size, ok := config["max_body_size"].(float64); ok && size > 0 {
+		return int64(size)
+	}
+	return defaultWebhookMaxBody
+}
+
+// webhookBody is a decoded webhook request body.
+type webhookBody struct {
+	Value interface{}
+	// Encoding is "base64" when Value is a binary body encoded as base64.
+	Encoding string
+}
+
+// readWebhookBody reads and decodes the body of r by its Content-Type:
+//   - JSON (application/json, */*+json) is decoded, and must be valid
+//   - forms (urlencoded or multipart) become an object of their fields; a field
+//     sent more than once becomes a list, and uploaded files become
+//     {"filename", "content_type", "size", "data"} with base64 data
+//   - text (text/*, XML) is kept as a string
+//   - anything else is kept as a string when it is valid UTF-8 and as base64
+//     otherwise; a body without a Content-Type is decoded as JSON when it parses
+//
+// Bodies over maxBytes return an *http.MaxBytesError.
+func readWebhookBody(r *http.Request, maxBytes int64) (webhookBody, error) {
+	if r.Body == nil {
+		return webhookBody{}, nil
+	}
+	defer r.Body.Close()
+
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	body := http.MaxBytesReader(nil, r.Body, maxBytes)
+	if mediaType == "multipart/form-data" {
+		return readMultipartBody(body, params["boundary"], maxBytes)
+	}
+
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return webhookBody{}, fmt.Errorf("failed to read body: %w", err)
+	}
+	if len(raw) == 0 {
+		return webhookBody{}, nil
+	}
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return webhookBody{}, fmt.Errorf("invalid JSON body: %w", err)
+		}
+		return webhookBody{Value: value}, nil
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(raw))
+		if err != nil {
+			return webhookBody{}, fmt.Errorf("invalid form body: %w", err)
+		}
+		return webhookBody{Value: formFields(values)}, nil
+	case strings.HasPrefix(mediaType, "text/") || mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return webhookBody{Value: string(raw)}, nil
+	case mediaType == "":
+		var value interface{}
+		if json.Unmarshal(raw, &value) == nil {
+			return webhookBody{Value: value}, nil
+		}
+	}
+	if utf8.Valid(raw) {
+		return webhookBody{Value: string(raw)}, nil
+	}
+	return webhookBody{Value: base64.StdEncoding.EncodeToString(raw), Encoding: "base64"}, nil
+}
+
+// readMultipartBody decodes a multipart/form-data body of at most maxBytes.
+func readMultipartBody(body io.Reader, boundary string, maxBytes int64) (webhookBody, error) {
+	if boundary == "" {
+		return webhookBody{}, errors.New("invalid multipart body: missing boundary")
+	}
+	form, err := multipart.NewReader(body, boundary).ReadForm(maxBytes)
+	if err != nil {
+		return webhookBody{}, fmt.Errorf("invalid multipart body: %w", err)
+	}
+	defer form.RemoveAll()
+
+	fields := formFields(form.Value)
+	for name, headers := range form.File {
+		files := make([]interface{}, 0, len(headers))
+		for _, header := range headers {
+			f, err := header.Open()
+			if err != nil {
+				return webhookBody{}, fmt.Errorf("failed to read file %s: %w", header.Filename, err)
+			}
+			var buf bytes.Buffer
+			_, err = io.Copy(&buf, f)
+			f.Close()
+			if err != nil {
+				return webhookBody{}, fmt.Errorf("failed to read file %s: %w", header.Filename, err)
+			}
+			files = append(files, map[string]interface{}{
+				"filename":     header.Filename,
+				"content_type": header.Header.Get("Content-Type"),
+				"size":         header.Size,
+				"data":         base64.StdEncoding.EncodeToString(buf.Bytes()),
+			})
+		}
+		if len(files) == 1 {
+			fields[name] = files[0]
+		} else {
+			fields[name] = files
+		}
+	}
+	return webhookBody{Value: fields}, nil
+}
+
+// formFields turns form values into an object: single values as strings and
+// repeated fields as lists.
+func formFields(values map[string][]string) map[string]interface{} {
+	fields := make(map[string]interface{}, len(values))
+	for name, vals := range values {
+		if len(vals) == 1 {
+			fields[name] = vals[0]
+			continue
+		}
+		list := make([]interface{}, len(vals))
+		for i, v := range vals {
+			list[i] = v
+		}
+		fields[name] = list
+	}
+	return fields
+}
+
+// writeBodyError answers a webhook call whose body couldn't be read.
+func writeBodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		WriteError(w, http.StatusRequestEntityTooLarge, "Request body too large")
+		return
+	}
+	WriteError(w, http.StatusBadRequest, err.Error())
+}
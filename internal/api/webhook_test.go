@@ -105,7 +105,7 @@ func TestWebhookTrigger(t *testing.T) {
 	// httptest.NewRequest doesn't set PathValue.
 	// We can use a mux to route it.
 	mux := http.NewServeMux()
-	mux.HandleFunc("POST /api/webhooks/{id}", handler.HandleWebhook)
+	mux.HandleFunc("POST /api/webhooks/{path...}", handler.HandleWebhook)
 	mux.ServeHTTP(w, req)
 
 	// 6. Verify Response
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// Webhook URL scopes. Test URLs run the same workflow; the scope is passed in
+// the payload so a workflow can tell test calls apart.
+const (
+	WebhookScopeProduction = "production"
+	WebhookScopeTest       = "test"
+)
+
+// webhookClaims is the signed content of a webhook token.
+type webhookClaims struct {
+	TriggerID string `json:"tid"`
+	Scope     string `json:"scope"`
+	// Expires is a Unix time; 0 never expires.
+	Expires int64 `json:"exp,omitempty"`
+	// Generation must match the trigger's "token_generation" config, which
+	// rotating the trigger's URLs increments.
+	Generation int `json:"gen"`
+}
+
+// signWebhookToken returns the token for claims: base64url JSON claims, a dot,
+// and their base64url HMAC-SHA256 with secret.
+func signWebhookToken(secret []byte, claims webhookClaims) string {
+	raw, _ := json.Marshal(claims)
+	payload := base64.RawURLEncoding.EncodeToString(raw)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyWebhookToken checks the signature and expiry of token, without any
+// storage lookup, and returns its claims.
+func verifyWebhookToken(secret []byte, token string, now time.Time) (*webhookClaims, error) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errors.New("malformed token")
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return nil, errors.New("malformed token")
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return nil, errors.New("invalid token signature")
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, errors.New("malformed token")
+	}
+	var claims webhookClaims
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return nil, errors.New("malformed token")
+	}
+	if claims.Expires != 0 && now.Unix() >= claims.Expires {
+		return nil, errors.New("token expired")
+	}
+	return &claims, nil
+}
+
+// tokenGeneration reads the token generation of a trigger config.
+func tokenGeneration(config map[string]interface{}) int {
+	gen, _ := config["token_generation"].(float64)
+	return int(gen)
+}
+
+// CreateWebhookURLRequest is the body of POST /api/triggers/{id}/webhook-urls.
+type CreateWebhookURLRequest struct {
+	// Scope is "production" (default) or "test".
+	Scope string `json:"scope"`
+	// ExpiresIn is a duration such as "24h"; URLs without it don't expire.
+	ExpiresIn string `json:"expires_in"`
+}
+
+// WebhookURLResponse is a signed webhook URL.
+type WebhookURLResponse struct {
+	URL       string     `json:"url"`
+	Token     string     `json:"token"`
+	Scope     string     `json:"scope"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreateWebhookURL handles POST /api/triggers/{id}/webhook-urls. It returns a
+// webhook or websocket URL carrying a token signed with the server's webhook
+// secret, which HandleWebhook or HandleWebSocket accepts until it expires or
+// the trigger's URLs are rotated.
+func (h *TriggerHandler) CreateWebhookURL(w http.ResponseWriter, r *http.Request) {
+	if len(h.WebhookSecret) == 0 {
+		WriteError(w, http.StatusServiceUnavailable, "Webhook URL signing is not configured (set CONV3N_WEBHOOK_SECRET)")
+		return
+	}
+	triggerID := r.PathValue("id")
+
+	var req CreateWebhookURLRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+			return
+		}
+	}
+	if req.Scope == "" {
+		req.Scope = WebhookScopeProduction
+	}
+	if req.Scope != WebhookScopeProduction && req.Scope != WebhookScopeTest {
+		WriteError(w, http.StatusUnprocessableEntity, "scope must be production or test")
+		return
+	}
+
+	trigger, err := h.Store.GetTrigger(r.Context(), triggerID)
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "Trigger not found: "+err.Error())
+		return
+	}
+	basePath, ok := signedURLBase(trigger.Type)
+	if !ok {
+		WriteError(w, http.StatusBadRequest, "Trigger is not a webhook or websocket type")
+		return
+	}
+	var config map[string]interface{}
+	json.Unmarshal(trigger.Config, &config)
+
+	claims := webhookClaims{TriggerID: triggerID, Scope: req.Scope, Generation: tokenGeneration(config)}
+	resp := WebhookURLResponse{Scope: req.Scope}
+	if req.ExpiresIn != "" {
+		ttl, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || ttl <= 0 {
+			WriteError(w, http.StatusUnprocessableEntity, "expires_in must be a positive duration such as 24h")
+			return
+		}
+		expiresAt := time.Now().Add(ttl).Truncate(time.Second).UTC()
+		claims.Expires = expiresAt.Unix()
+		resp.ExpiresAt = &expiresAt
+	}
+	resp.Token = signWebhookToken(h.WebhookSecret, claims)
+	resp.URL = fmt.Sprintf("%s%s?token=%s", basePath, triggerID, resp.Token)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// RotateWebhookURLs handles POST /api/triggers/{id}/webhook-urls/rotate. It
+// invalidates every signed URL issued for the trigger so far.
+func (h *TriggerHandler) RotateWebhookURLs(w http.ResponseWriter, r *http.Request) {
+	triggerID := r.PathValue("id")
+	trigger, err := h.Store.GetTrigger(r.Context(), triggerID)
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "Trigger not found: "+err.Error())
+		return
+	}
+	if _, ok := signedURLBase(trigger.Type); !ok {
+		WriteError(w, http.StatusBadRequest, "Trigger is not a webhook or websocket type")
+		return
+	}
+
+	var config map[string]interface{}
+	if err := json.Unmarshal(trigger.Config, &config); err != nil || config == nil {
+		config = map[string]interface{}{}
+	}
+	generation := tokenGeneration(config) + 1
+	config["token_generation"] = generation
+	trigger.Config, _ = json.Marshal(config)
+	if err := h.Store.UpdateTrigger(r.Context(), trigger); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to update trigger: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"token_generation": generation})
+}
+
+// checkWebhookToken authorizes a webhook call for a trigger with config. A
+// call with a token must carry a valid one for the trigger; calls without are
+// only refused when the config sets "require_token". Returns the token's
+// scope, or "" for calls without one.
+func (h *TriggerHandler) checkWebhookToken(r *http.Request, triggerID string, config map[string]interface{}) (string, error) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		if required, _ := config["require_token"].(bool); required {
+			return "", errors.New("a signed webhook URL is required")
+		}
+		return "", nil
+	}
+	if len(h.WebhookSecret) == 0 {
+		return "", errors.New("webhook URL signing is not configured")
+	}
+	claims, err := verifyWebhookToken(h.WebhookSecret, token, time.Now())
+	if err != nil {
+		return "", err
+	}
+	if claims.TriggerID != triggerID {
+		return "", errors.New("token was issued for another trigger")
+	}
+	if claims.Generation != tokenGeneration(config) {
+		return "", errors.New("token was revoked")
+	}
+	return claims.Scope, nil
+}
+
+// isWebhookType reports whether a trigger of type is called through /api/webhooks/.
+func isWebhookType(triggerType string) bool {
+	return triggerType == string(engine.TriggerTypeWebhook) || triggerType == string(engine.TriggerTypeTS)
+}
+
+// signedURLBase returns the path, up to the trigger ID, of the URLs a trigger of
+// type is called through, and whether it accepts signed URLs at all.
+func signedURLBase(triggerType string) (string, bool) {
+	switch {
+	case isWebhookType(triggerType):
+		return "/api/webhooks/", true
+	case triggerType == string(engine.TriggerTypeWebSocket):
+		return "/api/ws/", true
+	}
+	return "", false
+}
@@ -2,7 +2,6 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"time"
 
@@ -13,30 +12,36 @@ import (
 // WorkflowHandler handles HTTP requests for workflow management
 type WorkflowHandler struct {
 	Store storage.Storage
+	// ConfigSchema returns the config schema of a node type, which the nodes of
+	// saved workflows are checked against; the built-in schemas by default.
+	ConfigSchema func(engine.NodeType) (engine.ConfigSchema, error)
 }
 
 // NewWorkflowHandler creates a new WorkflowHandler
 func NewWorkflowHandler(store storage.Storage) *WorkflowHandler {
-	return &WorkflowHandler{Store: store}
+	return &WorkflowHandler{Store: store, ConfigSchema: engine.BuiltinConfigSchema}
 }
 
 // Create handles POST /api/workflows
 func (h *WorkflowHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var wf engine.Workflow
 	if err := json.NewDecoder(r.Body).Decode(&wf); err != nil {
-		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		WriteError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
 		return
 	}
 
+	if err := wf.ValidateDefinition(h.ConfigSchema); err != nil {
+		writeValidationError(w, "Invalid workflow", err)
+		return
+	}
 	if wf.ID == "" {
-		// Generate simple ID if missing
-		wf.ID = fmt.Sprintf("wf_%d", time.Now().UnixNano())
+		wf.ID = storage.NewID()
 	}
 
 	// Marshal definition back to bytes to store
 	defBytes, err := json.Marshal(wf)
 	if err != nil {
-		http.Error(w, "Failed to marshal definition: "+err.Error(), http.StatusInternalServerError)
+		WriteError(w, http.StatusInternalServerError, "Failed to marshal definition: "+err.Error())
 		return
 	}
 
@@ -47,7 +52,7 @@ func (h *WorkflowHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.Store.CreateWorkflow(r.Context(), storedWf); err != nil {
-		http.Error(w, "Failed to create workflow: "+err.Error(), http.StatusInternalServerError)
+		WriteError(w, http.StatusInternalServerError, "Failed to create workflow: "+err.Error())
 		return
 	}
 
@@ -61,13 +66,13 @@ func (h *WorkflowHandler) Create(w http.ResponseWriter, r *http.Request) {
 func (h *WorkflowHandler) Get(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	if id == "" {
-		http.Error(w, "Missing ID", http.StatusBadRequest)
+		WriteError(w, http.StatusBadRequest, "Missing ID")
 		return
 	}
 
 	storedWf, err := h.Store.GetWorkflow(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Workflow not found: "+err.Error(), http.StatusNotFound)
+		WriteError(w, http.StatusNotFound, "Workflow not found: "+err.Error())
 		return
 	}
 
@@ -80,22 +85,30 @@ func (h *WorkflowHandler) Get(w http.ResponseWriter, r *http.Request) {
 func (h *WorkflowHandler) Update(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	if id == "" {
-		http.Error(w, "Missing ID", http.StatusBadRequest)
+		WriteError(w, http.StatusBadRequest, "Missing ID")
+		return
+	}
+
+	if h.refuseSynced(w, r, id) {
 		return
 	}
 
 	var wf engine.Workflow
 	if err := json.NewDecoder(r.Body).Decode(&wf); err != nil {
-		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		WriteError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
 		return
 	}
 
 	// Ensure ID in body matches ID in path
 	wf.ID = id
+	if err := wf.ValidateDefinition(h.ConfigSchema); err != nil {
+		writeValidationError(w, "Invalid workflow", err)
+		return
+	}
 
 	defBytes, err := json.Marshal(wf)
 	if err != nil {
-		http.Error(w, "Failed to marshal definition: "+err.Error(), http.StatusInternalServerError)
+		WriteError(w, http.StatusInternalServerError, "Failed to marshal definition: "+err.Error())
 		return
 	}
 
@@ -106,7 +119,7 @@ func (h *WorkflowHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.Store.UpdateWorkflow(r.Context(), storedWf); err != nil {
-		http.Error(w, "Failed to update workflow: "+err.Error(), http.StatusInternalServerError)
+		WriteError(w, http.StatusInternalServerError, "Failed to update workflow: "+err.Error())
 		return
 	}
 
@@ -118,33 +131,52 @@ func (h *WorkflowHandler) Update(w http.ResponseWriter, r *http.Request) {
 func (h *WorkflowHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	if id == "" {
-		http.Error(w, "Missing ID", http.StatusBadRequest)
+		WriteError(w, http.StatusBadRequest, "Missing ID")
+		return
+	}
+	if h.refuseSynced(w, r, id) {
 		return
 	}
 
 	if err := h.Store.DeleteWorkflow(r.Context(), id); err != nil {
-		http.Error(w, "Failed to delete workflow: "+err.Error(), http.StatusInternalServerError)
+		WriteError(w, http.StatusInternalServerError, "Failed to delete workflow: "+err.Error())
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// refuseSynced answers with a 409 when the workflow id is synced from the
+// workflows directory, whose files are the only way to change it.
+func (h *WorkflowHandler) refuseSynced(w http.ResponseWriter, r *http.Request, id string) bool {
+	stored, err := h.Store.GetWorkflow(r.Context(), id)
+	if err != nil || stored.Source == "" {
+		return false
+	}
+	WriteError(w, http.StatusConflict, "Workflow is managed by the workflows directory: edit "+stored.Source+" instead")
+	return true
+}
+
+// WorkflowListItem is the summary returned by GET /api/workflows
+type WorkflowListItem struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Source and Revision are set for workflows synced from the workflows
+	// directory: the file and git revision they were read from.
+	Source   string `json:"source,omitempty"`
+	Revision string `json:"revision,omitempty"`
+}
+
 // List handles GET /api/workflows
 func (h *WorkflowHandler) List(w http.ResponseWriter, r *http.Request) {
 	storedWfs, err := h.Store.ListWorkflows(r.Context())
 	if err != nil {
-		http.Error(w, "Failed to list workflows: "+err.Error(), http.StatusInternalServerError)
+		WriteError(w, http.StatusInternalServerError, "Failed to list workflows: "+err.Error())
 		return
 	}
 
-	type WorkflowListItem struct {
-		ID        string    `json:"id"`
-		Name      string    `json:"name"`
-		CreatedAt time.Time `json:"created_at"`
-		UpdatedAt time.Time `json:"updated_at"`
-	}
-
 	list := make([]WorkflowListItem, len(storedWfs))
 	for i, sw := range storedWfs {
 		list[i] = WorkflowListItem{
@@ -152,9 +184,62 @@ func (h *WorkflowHandler) List(w http.ResponseWriter, r *http.Request) {
 			Name:      sw.Name,
 			CreatedAt: sw.CreatedAt,
 			UpdatedAt: sw.UpdatedAt,
+			Source:    sw.Source,
+			Revision:  sw.Revision,
 		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(list)
 }
+
+// GetStaticData handles GET /api/workflows/{id}/static
+// Returns the key/value data the workflow persists across executions ($workflowStatic).
+func (h *WorkflowHandler) GetStaticData(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := h.Store.GetWorkflow(r.Context(), id); err != nil {
+		WriteError(w, http.StatusNotFound, "Workflow not found: "+err.Error())
+		return
+	}
+
+	data, err := h.Store.GetWorkflowStaticData(r.Context(), id)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to get static data: "+err.Error())
+		return
+	}
+	if len(data) == 0 {
+		data = []byte("{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// UpdateStaticData handles PUT /api/workflows/{id}/static
+// Replaces the workflow's static data, e.g. to reset a sync cursor.
+func (h *WorkflowHandler) UpdateStaticData(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := h.Store.GetWorkflow(r.Context(), id); err != nil {
+		WriteError(w, http.StatusNotFound, "Workflow not found: "+err.Error())
+		return
+	}
+
+	var data map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil || data == nil {
+		WriteError(w, http.StatusUnprocessableEntity, "Static data must be a JSON object")
+		return
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to encode static data: "+err.Error())
+		return
+	}
+	if err := h.Store.SaveWorkflowStaticData(r.Context(), id, raw); err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to save static data: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(raw)
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/conv3n/conv3n/internal/api"
@@ -22,6 +23,8 @@ func newWorkflowMux(t *testing.T) (*http.ServeMux, storage.Storage) {
 	mux.HandleFunc("GET /api/workflows/{id}", handler.Get)
 	mux.HandleFunc("PUT /api/workflows/{id}", handler.Update)
 	mux.HandleFunc("DELETE /api/workflows/{id}", handler.Delete)
+	mux.HandleFunc("GET /api/workflows/{id}/static", handler.GetStaticData)
+	mux.HandleFunc("PUT /api/workflows/{id}/static", handler.UpdateStaticData)
 
 	return mux, store
 }
@@ -211,3 +214,109 @@ func TestWorkflowAPI_Update(t *testing.T) {
 		t.Errorf("expected name 'Updated Name', got %q", updated.Name)
 	}
 }
+
+func TestWorkflowAPI_StaticData(t *testing.T) {
+	mux, store := newWorkflowMux(t)
+
+	if err := store.CreateWorkflow(testCtx, &storage.Workflow{ID: "wf-sync", Name: "Sync", Definition: []byte("{}")}); err != nil {
+		t.Fatalf("failed to create workflow: %v", err)
+	}
+
+	// Empty until something is stored
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/workflows/wf-sync/static", nil))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "{}" {
+		t.Fatalf("expected empty object, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Replace and read back
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/workflows/wf-sync/static", bytes.NewBufferString(`{"cursor":"abc"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/workflows/wf-sync/static", nil))
+	var data map[string]interface{}
+	json.NewDecoder(rec.Body).Decode(&data)
+	if data["cursor"] != "abc" {
+		t.Errorf("expected cursor abc, got %v", data)
+	}
+
+	// Non-object bodies and unknown workflows are rejected
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/workflows/wf-sync/static", bytes.NewBufferString(`[1]`)))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/workflows/missing/static", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestWorkflowAPI_RejectsInvalidDefinition(t *testing.T) {
+	mux, store := newWorkflowMux(t)
+	store.CreateWorkflow(testCtx, &storage.Workflow{ID: "wf-1", Name: "Existing", Definition: []byte("{}")})
+
+	body := `{"name":"Broken","nodes":{` +
+		`"fetch":{"type":"std/http_request","config":{"method":"GET"}},` +
+		`"parse":{"type":"std/csv","config":{"operation":"pivot"}}},` +
+		`"edges":[{"source":"fetch","target":"parse"}]}`
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/api/workflows", strings.NewReader(body)),
+		httptest.NewRequest(http.MethodPut, "/api/workflows/wf-1", strings.NewReader(body)),
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("%s: expected 422, got %d: %s", req.Method, rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Error struct {
+				Details api.ValidationDetails `json:"details"`
+			} `json:"error"`
+		}
+		json.NewDecoder(rec.Body).Decode(&resp)
+		fields := resp.Error.Details.Fields
+		if len(fields) != 2 || fields[0].Field != "nodes.fetch.config.url" || fields[1].Field != "nodes.parse.config.operation" {
+			t.Errorf("%s: expected url and operation to be reported, got %+v", req.Method, fields)
+		}
+	}
+
+	if stored, _ := store.GetWorkflow(testCtx, "wf-1"); string(stored.Definition) != "{}" {
+		t.Errorf("expected the workflow to be left alone, got %s", stored.Definition)
+	}
+	if workflows, _ := store.ListWorkflows(testCtx); len(workflows) != 1 {
+		t.Errorf("expected no workflow to be created, got %d", len(workflows))
+	}
+}
+
+func TestWorkflowAPI_SyncedWorkflowsAreReadOnly(t *testing.T) {
+	mux, store := newWorkflowMux(t)
+	store.CreateWorkflow(testCtx, &storage.Workflow{ID: "orders", Name: "Orders", Definition: []byte("{}"), Source: "orders.yaml", Revision: "abc123"})
+
+	body := `{"name":"Edited","nodes":{}}`
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPut, "/api/workflows/orders", strings.NewReader(body)),
+		httptest.NewRequest(http.MethodDelete, "/api/workflows/orders", nil),
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "orders.yaml") {
+			t.Errorf("%s: expected 409 naming the file, got %d: %s", req.Method, rec.Code, rec.Body.String())
+		}
+	}
+	if stored, err := store.GetWorkflow(testCtx, "orders"); err != nil || stored.Name != "Orders" {
+		t.Errorf("expected the workflow to be left alone, got %+v (%v)", stored, err)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/workflows", nil))
+	var list []api.WorkflowListItem
+	json.NewDecoder(rec.Body).Decode(&list)
+	if len(list) != 1 || list[0].Source != "orders.yaml" || list[0].Revision != "abc123" {
+		t.Errorf("expected the list to show the source and revision, got %+v", list)
+	}
+}
@@ -0,0 +1,54 @@
+package awsauth_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/awsauth"
+)
+
+// TestSign checks the signature of the get-vanilla case of the AWS Signature
+// Version 4 test suite.
+func TestSign(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	creds := awsauth.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	awsauth.Sign(req, nil, "service", "us-east-1", creds, time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("unexpected signature:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestClient_Call(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in struct{ KeyId string }
+		json.NewDecoder(r.Body).Decode(&in)
+		if r.Header.Get("X-Amz-Target") != "TrentService.Encrypt" || !strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/kms/aws4_request") {
+			http.Error(w, "bad request", http.StatusForbidden)
+			return
+		}
+		if in.KeyId != "alias/app" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"NotFoundException","message":"Alias alias/other is not found."}`))
+			return
+		}
+		w.Write([]byte(`{"CiphertextBlob":"AQID"}`))
+	}))
+	defer srv.Close()
+	c := &awsauth.Client{Service: "kms", Region: "eu-west-1", Credentials: awsauth.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, Endpoint: srv.URL}
+
+	var out struct{ CiphertextBlob []byte }
+	if err := c.Call(context.Background(), "TrentService.Encrypt", map[string]string{"KeyId": "alias/app"}, &out); err != nil || string(out.CiphertextBlob) != "\x01\x02\x03" {
+		t.Errorf("unexpected response %v (%v)", out, err)
+	}
+	err := c.Call(context.Background(), "TrentService.Encrypt", map[string]string{"KeyId": "alias/other"}, &out)
+	if err == nil || !strings.Contains(err.Error(), "NotFoundException") || strings.Contains(err.Error(), "alias/other") {
+		t.Errorf("expected the error type only, got %v", err)
+	}
+}
@@ -0,0 +1,357 @@
+package engine
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// archiveConfig is the config of a std/archive node. Archives and file contents
+// travel as base64 in node data, as with std/sftp, or as local files:
+//
+//	{"operation": "create", "format": "zip", "baseDir": "/data/reports", "include": ["*.pdf"]}
+//	{"operation": "extract", "format": "tar.gz", "content": "{{ $node.download.data.content }}",
+//	 "include": ["invoices/*.xml"], "encoding": "utf8"}
+type archiveConfig struct {
+	Operation string `json:"operation"`
+	// Format is "zip" (default), "tar" or "tar.gz".
+	Format string `json:"format"`
+	// Files to archive: {"name", "content", "encoding"} with base64 or utf8 (default) content.
+	Files []archiveFile `json:"files"`
+	// BaseDir adds the local files under it to the archive, named relative to it.
+	BaseDir string `json:"baseDir"`
+	// Include selects files by glob ("*.pdf", "reports/*.csv"); patterns without a
+	// "/" match the base name at any depth. Empty selects everything.
+	Include []string `json:"include"`
+	// Content is the archive to extract (base64); Path reads it from a local file.
+	Content string `json:"content"`
+	Path    string `json:"path"`
+	// OutputPath writes the created archive to a local file instead of the output.
+	OutputPath string `json:"outputPath"`
+	// OutputDir extracts to a local directory instead of the output.
+	OutputDir string `json:"outputDir"`
+	// Encoding of extracted file contents in the output: "base64" (default) or "utf8".
+	Encoding string `json:"encoding"`
+}
+
+type archiveFile struct {
+	Name     string `json:"name"`
+	Content  string `json:"content"`
+	Encoding string `json:"encoding"`
+}
+
+// archiveEntry is a file going into or coming out of an archive.
+type archiveEntry struct {
+	name    string
+	data    []byte
+	modTime time.Time
+}
+
+// executeArchive runs a std/archive node. Creating outputs {"content": base64,
+// "size", "files": [names]} (or {"path", "size", "files"} with outputPath);
+// extracting outputs {"files": [{"name", "size", "content", "encoding"}]}
+// (without content when extracting to outputDir).
+func executeArchive(input any) (any, error) {
+	payload, _ := input.(map[string]interface{})
+	var cfg archiveConfig
+	raw, _ := json.Marshal(payload["config"])
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("archive: invalid config: %w", err)
+	}
+	if cfg.Format == "" {
+		cfg.Format = "zip"
+	}
+	if cfg.Format != "zip" && cfg.Format != "tar" && cfg.Format != "tar.gz" {
+		return nil, fmt.Errorf("archive: config 'format' must be 'zip', 'tar' or 'tar.gz'")
+	}
+	for _, pattern := range cfg.Include {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("archive: invalid include pattern %q", pattern)
+		}
+	}
+
+	var data map[string]interface{}
+	var err error
+	switch cfg.Operation {
+	case "create":
+		data, err = createArchive(cfg)
+	case "extract":
+		data, err = extractArchive(cfg)
+	default:
+		return nil, fmt.Errorf("archive: config 'operation' must be 'create' or 'extract'")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("archive: %w", err)
+	}
+	data["format"] = cfg.Format
+	return map[string]interface{}{"data": data, "port": "default"}, nil
+}
+
+func createArchive(cfg archiveConfig) (map[string]interface{}, error) {
+	var entries []archiveEntry
+	for _, f := range cfg.Files {
+		if f.Name == "" {
+			return nil, fmt.Errorf("every file needs a 'name'")
+		}
+		if !archiveIncluded(cfg.Include, f.Name) {
+			continue
+		}
+		content := []byte(f.Content)
+		if f.Encoding == "base64" {
+			b, err := base64.StdEncoding.DecodeString(f.Content)
+			if err != nil {
+				return nil, fmt.Errorf("file %s: content is not valid base64: %w", f.Name, err)
+			}
+			content = b
+		}
+		entries = append(entries, archiveEntry{name: f.Name, data: content, modTime: time.Now()})
+	}
+	if cfg.BaseDir != "" {
+		err := filepath.WalkDir(cfg.BaseDir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, _ := filepath.Rel(cfg.BaseDir, p)
+			rel = filepath.ToSlash(rel)
+			if !archiveIncluded(cfg.Include, rel) {
+				return nil
+			}
+			content, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			info, _ := d.Info()
+			entries = append(entries, archiveEntry{name: rel, data: content, modTime: info.ModTime()})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no files to archive")
+	}
+
+	var buf bytes.Buffer
+	var err error
+	if cfg.Format == "zip" {
+		err = writeZip(&buf, entries)
+	} else {
+		err = writeTar(&buf, entries, cfg.Format == "tar.gz")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.name
+	}
+	data := map[string]interface{}{"size": buf.Len(), "files": names}
+	if cfg.OutputPath != "" {
+		if err := os.WriteFile(cfg.OutputPath, buf.Bytes(), 0644); err != nil {
+			return nil, err
+		}
+		data["path"] = cfg.OutputPath
+	} else {
+		data["content"] = base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+	return data, nil
+}
+
+func writeZip(w io.Writer, entries []archiveEntry) error {
+	zw := zip.NewWriter(w)
+	for _, e := range entries {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: e.name, Method: zip.Deflate, Modified: e.modTime})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(e.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeTar(w io.Writer, entries []archiveEntry, gzipped bool) error {
+	var gz *gzip.Writer
+	if gzipped {
+		gz = gzip.NewWriter(w)
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.data)), ModTime: e.modTime}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(e.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if gz != nil {
+		return gz.Close()
+	}
+	return nil
+}
+
+func extractArchive(cfg archiveConfig) (map[string]interface{}, error) {
+	var archive []byte
+	switch {
+	case cfg.Path != "":
+		b, err := os.ReadFile(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		archive = b
+	case cfg.Content != "":
+		b, err := base64.StdEncoding.DecodeString(cfg.Content)
+		if err != nil {
+			return nil, fmt.Errorf("config 'content' is not valid base64: %w", err)
+		}
+		archive = b
+	default:
+		return nil, fmt.Errorf("config 'content' or 'path' is required")
+	}
+	if cfg.Encoding != "" && cfg.Encoding != "base64" && cfg.Encoding != "utf8" {
+		return nil, fmt.Errorf("config 'encoding' must be 'base64' or 'utf8'")
+	}
+
+	var entries []archiveEntry
+	var err error
+	if cfg.Format == "zip" {
+		entries, err = readZip(archive)
+	} else {
+		entries, err = readTar(archive, cfg.Format == "tar.gz")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	files := []interface{}{}
+	for _, e := range entries {
+		if !archiveIncluded(cfg.Include, e.name) {
+			continue
+		}
+		file := map[string]interface{}{"name": e.name, "size": len(e.data)}
+		if cfg.OutputDir != "" {
+			dest, err := archiveDest(cfg.OutputDir, e.name)
+			if err != nil {
+				return nil, err
+			}
+			if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+				return nil, err
+			}
+			if err := os.WriteFile(dest, e.data, 0644); err != nil {
+				return nil, err
+			}
+			file["path"] = dest
+		} else if cfg.Encoding == "utf8" {
+			file["content"] = string(e.data)
+			file["encoding"] = "utf8"
+		} else {
+			file["content"] = base64.StdEncoding.EncodeToString(e.data)
+			file["encoding"] = "base64"
+		}
+		files = append(files, file)
+	}
+	return map[string]interface{}{"files": files, "count": len(files)}, nil
+}
+
+func readZip(archive []byte) ([]archiveEntry, error) {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive: %w", err)
+	}
+	var entries []archiveEntry
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		entries = append(entries, archiveEntry{name: f.Name, data: data, modTime: f.Modified})
+	}
+	return entries, nil
+}
+
+func readTar(archive []byte, gzipped bool) ([]archiveEntry, error) {
+	var r io.Reader = bytes.NewReader(archive)
+	if gzipped {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip stream: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	var entries []archiveEntry
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid tar archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", hdr.Name, err)
+		}
+		entries = append(entries, archiveEntry{name: hdr.Name, data: data, modTime: hdr.ModTime})
+	}
+	return entries, nil
+}
+
+// archiveIncluded reports whether name matches one of the include patterns.
+func archiveIncluded(include []string, name string) bool {
+	if len(include) == 0 {
+		return true
+	}
+	for _, pattern := range include {
+		target := name
+		if !strings.Contains(pattern, "/") {
+			target = path.Base(name)
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveDest resolves an entry name inside dir, refusing names that would
+// escape it ("../etc/passwd", absolute paths).
+func archiveDest(dir, name string) (string, error) {
+	dest := filepath.Join(dir, filepath.FromSlash(name))
+	rel, err := filepath.Rel(dir, dest)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(name) {
+		return "", fmt.Errorf("archive entry %q escapes the output directory", name)
+	}
+	return dest, nil
+}
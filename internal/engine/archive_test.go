@@ -0,0 +1,126 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+func runArchive(t *testing.T, config map[string]interface{}) (map[string]interface{}, error) {
+	t.Helper()
+	runner := engine.NewBunRunner(t.TempDir())
+	runner.RuntimePath = "/nonexistent/bun" // any process start would fail
+	node := &engine.Node{ID: "archive", Type: engine.NodeTypeArchive}
+	raw, err := runner.ExecuteNode(context.Background(), node, map[string]interface{}{"config": config})
+	if err != nil {
+		return nil, err
+	}
+	return raw.(map[string]interface{})["data"].(map[string]interface{}), nil
+}
+
+// TestBunRunner_ExecuteNode_Archive verifies that std/archive round-trips files
+// through zip, tar and tar.gz archives, selecting files by glob.
+func TestBunRunner_ExecuteNode_Archive(t *testing.T) {
+	baseDir := t.TempDir()
+	os.MkdirAll(filepath.Join(baseDir, "2024"), 0755)
+	os.WriteFile(filepath.Join(baseDir, "summary.pdf"), []byte("%PDF summary"), 0644)
+	os.WriteFile(filepath.Join(baseDir, "2024", "q1.pdf"), []byte("%PDF q1"), 0644)
+	os.WriteFile(filepath.Join(baseDir, "notes.txt"), []byte("skip me"), 0644)
+	binary := []byte{0x00, 0x01, 0xfe, 0xff}
+
+	for _, format := range []string{"zip", "tar", "tar.gz"} {
+		t.Run(format, func(t *testing.T) {
+			created, err := runArchive(t, map[string]interface{}{
+				"operation": "create",
+				"format":    format,
+				"baseDir":   baseDir,
+				"include":   []interface{}{"*.pdf", "*.bin"},
+				"files": []interface{}{
+					map[string]interface{}{"name": "raw.bin", "content": base64.StdEncoding.EncodeToString(binary), "encoding": "base64"},
+					map[string]interface{}{"name": "readme.md", "content": "excluded by include"},
+				},
+			})
+			if err != nil {
+				t.Fatalf("create failed: %v", err)
+			}
+			names := created["files"].([]string)
+			sort.Strings(names)
+			if len(names) != 3 || names[0] != "2024/q1.pdf" || names[1] != "raw.bin" || names[2] != "summary.pdf" {
+				t.Fatalf("unexpected archived files %v", names)
+			}
+
+			extracted, err := runArchive(t, map[string]interface{}{
+				"operation": "extract",
+				"format":    format,
+				"content":   created["content"],
+				"include":   []interface{}{"2024/*", "*.bin"},
+			})
+			if err != nil {
+				t.Fatalf("extract failed: %v", err)
+			}
+			got := map[string]string{}
+			for _, f := range extracted["files"].([]interface{}) {
+				file := f.(map[string]interface{})
+				got[file["name"].(string)] = file["content"].(string)
+			}
+			want := map[string]string{
+				"2024/q1.pdf": base64.StdEncoding.EncodeToString([]byte("%PDF q1")),
+				"raw.bin":     base64.StdEncoding.EncodeToString(binary),
+			}
+			if len(got) != len(want) || got["2024/q1.pdf"] != want["2024/q1.pdf"] || got["raw.bin"] != want["raw.bin"] {
+				t.Errorf("expected %v, got %v", want, got)
+			}
+		})
+	}
+
+	// Archives can also be written to and extracted from local files
+	archivePath := filepath.Join(t.TempDir(), "reports.zip")
+	if _, err := runArchive(t, map[string]interface{}{
+		"operation":  "create",
+		"baseDir":    baseDir,
+		"outputPath": archivePath,
+	}); err != nil {
+		t.Fatalf("create to file failed: %v", err)
+	}
+	outDir := t.TempDir()
+	if _, err := runArchive(t, map[string]interface{}{
+		"operation": "extract",
+		"path":      archivePath,
+		"outputDir": outDir,
+	}); err != nil {
+		t.Fatalf("extract to directory failed: %v", err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(outDir, "notes.txt")); string(b) != "skip me" {
+		t.Errorf("expected notes.txt to be extracted, got %q", b)
+	}
+}
+
+// TestBunRunner_ExecuteNode_ArchiveZipSlip verifies that entries escaping the
+// output directory are refused.
+func TestBunRunner_ExecuteNode_ArchiveZipSlip(t *testing.T) {
+	created, err := runArchive(t, map[string]interface{}{
+		"operation": "create",
+		"format":    "tar",
+		"files":     []interface{}{map[string]interface{}{"name": "../evil.sh", "content": "rm -rf /"}},
+	})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	outDir := filepath.Join(t.TempDir(), "out")
+	if _, err := runArchive(t, map[string]interface{}{
+		"operation": "extract",
+		"format":    "tar",
+		"content":   created["content"],
+		"outputDir": outDir,
+	}); err == nil {
+		t.Fatal("expected an entry escaping the output directory to be refused")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(outDir), "evil.sh")); !os.IsNotExist(err) {
+		t.Error("expected nothing to be written outside the output directory")
+	}
+}
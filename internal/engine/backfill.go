@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/storage"
+	"github.com/robfig/cron/v3"
+)
+
+// defaultMaxCatchUp is how many missed runs a cron trigger with "catch_up": true
+// fires when "max_catch_up" is not set.
+const defaultMaxCatchUp = 1
+
+// cronCatchUpLimit reads the catch-up settings of a cron trigger config:
+// {"catch_up": true, "max_catch_up": 5}. Returns 0 when catch-up is disabled.
+func cronCatchUpLimit(config map[string]interface{}) int {
+	if enabled, _ := config["catch_up"].(bool); !enabled {
+		return 0
+	}
+	if max, ok := config["max_catch_up"].(float64); ok && max >= 1 {
+		return int(max)
+	}
+	return defaultMaxCatchUp
+}
+
+// missedRuns returns the schedule windows strictly after since and not after now.
+// Only the most recent max windows are kept; skipped counts the older ones dropped.
+func missedRuns(schedule cron.Schedule, since, now time.Time, max int) (runs []time.Time, skipped int) {
+	for t := schedule.Next(since); !t.IsZero() && !t.After(now); t = schedule.Next(t) {
+		runs = append(runs, t)
+		if len(runs) > max {
+			runs = runs[1:]
+			skipped++
+		}
+	}
+	return runs, skipped
+}
+
+// NextTriggerFire returns when an enabled cron or interval trigger fires next
+// after now, or nil for other and disabled triggers.
+func NextTriggerFire(t *storage.Trigger, lastFired *time.Time, now time.Time) (*time.Time, error) {
+	if !t.Enabled {
+		return nil, nil
+	}
+	runs, err := NextTriggerRuns(t, lastFired, now, 1)
+	if errors.Is(err, ErrNoSchedule) {
+		return nil, nil
+	}
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+	return &runs[0], nil
+}
+
+// NextTriggerRuns returns the next count fire times after now of a cron or
+// interval trigger, in the trigger's timezone (local time by default), or
+// ErrNoSchedule for other types. Interval triggers tick from when they were
+// started, so their runs are estimated from lastFired (or the trigger's last
+// update when it never fired) in steps of the interval.
+func NextTriggerRuns(t *storage.Trigger, lastFired *time.Time, now time.Time, count int) ([]time.Time, error) {
+	var config map[string]interface{}
+	if err := json.Unmarshal(t.Config, &config); err != nil {
+		return nil, fmt.Errorf("invalid trigger config: %w", err)
+	}
+	loc := time.Local
+	if tz, _ := config["timezone"].(string); tz != "" {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+
+	var runs []time.Time
+	switch TriggerType(t.Type) {
+	case TriggerTypeCron:
+		spec, err := CronSpec(config)
+		if err != nil {
+			return nil, err
+		}
+		if runs, err = CronOccurrences(spec, now.In(loc), count); err != nil {
+			return nil, err
+		}
+	case TriggerTypeInterval:
+		seconds, _ := config["interval"].(float64)
+		interval := time.Duration(seconds) * time.Second
+		if interval <= 0 {
+			return nil, fmt.Errorf("invalid interval %v", config["interval"])
+		}
+		next := t.UpdatedAt
+		if lastFired != nil && lastFired.After(next) {
+			next = *lastFired
+		}
+		if !next.After(now) {
+			next = next.Add((now.Sub(next)/interval + 1) * interval)
+		}
+		for ; len(runs) < count; next = next.Add(interval) {
+			runs = append(runs, next.In(loc))
+		}
+	default:
+		return nil, ErrNoSchedule
+	}
+	return runs, nil
+}
+
+// ErrNoSchedule is returned for the next runs of a trigger that doesn't fire on a schedule.
+var ErrNoSchedule = errors.New("trigger has no schedule")
+
+// backfillCron fires the runs of a cron trigger that were missed while the server
+// was down: every window between the trigger's last recorded firing (or its creation
+// when it never fired) and now, bounded by maxCatchUp. Each run gets the window in
+// its payload ($trigger.scheduled_time) and is tagged as a backfill in the history.
+func (tm *TriggerManager) backfillCron(ctx context.Context, t *storage.Trigger, spec string, maxCatchUp int) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		log.Printf("Cron trigger %s: cannot catch up, invalid schedule: %v", t.ID, err)
+		return
+	}
+
+	since := t.CreatedAt
+	history, err := tm.Store.ListTriggerExecutions(ctx, t.ID, 1)
+	if err != nil {
+		log.Printf("Cron trigger %s: cannot catch up, failed to read history: %v", t.ID, err)
+		return
+	}
+	if len(history) > 0 {
+		since = history[0].FiredAt
+	}
+
+	// The live scheduler runs in local time unless the spec names a timezone,
+	// so compute windows the same way
+	runs, skipped := missedRuns(schedule, since.In(time.Local), time.Now(), maxCatchUp)
+	if len(runs) == 0 {
+		return
+	}
+	if skipped > 0 {
+		log.Printf("Cron trigger %s: %d missed runs older than the last %d are not caught up", t.ID, skipped, maxCatchUp)
+	}
+
+	for _, scheduled := range runs {
+		log.Printf("Cron trigger %s: catching up missed run scheduled for %s", t.ID, scheduled.Format(time.RFC3339))
+		payload := map[string]interface{}{
+			"backfill":       true,
+			"scheduled_time": scheduled.Format(time.RFC3339),
+		}
+		if err := tm.fire(context.WithoutCancel(ctx), t.ID, payload, triggerRun{backfill: true}); err != nil {
+			log.Printf("Cron trigger %s: catch-up run failed: %v", t.ID, err)
+		}
+	}
+}
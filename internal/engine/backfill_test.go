@@ -0,0 +1,131 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// TestLoadTriggers_CronCatchUp verifies that on load, a cron trigger with catch_up
+// fires the most recent missed windows (bounded by max_catch_up) tagged as backfill,
+// while triggers without catch_up fire nothing.
+func TestLoadTriggers_CronCatchUp(t *testing.T) {
+	ctx := context.Background()
+	store := createTestStorage(t)
+
+	if err := store.CreateWorkflow(ctx, &storage.Workflow{ID: "wf-1", Name: "Sync", Definition: []byte("{}")}); err != nil {
+		t.Fatalf("failed to create workflow: %v", err)
+	}
+	for id, config := range map[string]string{
+		"tr-catch-up": `{"schedule": "* * * * *", "catch_up": true, "max_catch_up": 3}`,
+		"tr-plain":    `{"schedule": "* * * * *"}`,
+	} {
+		if err := store.CreateTrigger(ctx, &storage.Trigger{ID: id, WorkflowID: "wf-1", Type: "cron", Config: []byte(config), Enabled: true}); err != nil {
+			t.Fatalf("failed to create trigger %s: %v", id, err)
+		}
+		// Both last fired ten minutes ago, so about ten windows were missed
+		if err := store.CreateTriggerExecution(ctx, &storage.TriggerExecution{
+			ID: "texec-" + id, TriggerID: id, FiredAt: time.Now().Add(-10 * time.Minute), Status: "success",
+		}); err != nil {
+			t.Fatalf("failed to create trigger execution: %v", err)
+		}
+	}
+
+	tm := engine.NewTriggerManager(store, t.TempDir(), nil, engine.NewWorkerPool(2))
+	defer tm.StopAll()
+	if err := tm.LoadTriggers(ctx); err != nil {
+		t.Fatalf("LoadTriggers failed: %v", err)
+	}
+
+	// Fire is asynchronous; wait for the catch-up runs to be recorded
+	var backfills []*storage.TriggerExecution
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		execs, err := store.ListTriggerExecutions(ctx, "tr-catch-up", 20)
+		if err != nil {
+			t.Fatalf("failed to list trigger executions: %v", err)
+		}
+		backfills = backfills[:0]
+		for _, e := range execs {
+			if e.Backfill {
+				backfills = append(backfills, e)
+			}
+		}
+		if len(backfills) >= 3 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if len(backfills) != 3 {
+		t.Fatalf("expected 3 backfilled runs, got %d", len(backfills))
+	}
+	for _, e := range backfills {
+		var payload map[string]interface{}
+		if err := json.Unmarshal(e.Payload, &payload); err != nil {
+			t.Fatalf("invalid payload %s: %v", e.Payload, err)
+		}
+		scheduled, err := time.Parse(time.RFC3339, payload["scheduled_time"].(string))
+		if err != nil {
+			t.Fatalf("invalid scheduled_time: %v", err)
+		}
+		// Only the most recent windows are caught up
+		if time.Since(scheduled) > 4*time.Minute {
+			t.Errorf("expected one of the last 3 windows, got %s", scheduled)
+		}
+	}
+
+	execs, err := store.ListTriggerExecutions(ctx, "tr-plain", 20)
+	if err != nil {
+		t.Fatalf("failed to list trigger executions: %v", err)
+	}
+	for _, e := range execs {
+		if e.Backfill {
+			t.Errorf("trigger without catch_up should not backfill, got %s", e.ID)
+		}
+	}
+}
+
+func TestNextTriggerFire(t *testing.T) {
+	now := time.Date(2026, 3, 4, 10, 20, 0, 0, time.UTC)
+	updated := now.Add(-time.Hour)
+	trigger := func(typ, config string, enabled bool) *storage.Trigger {
+		return &storage.Trigger{ID: "tr", Type: typ, Config: []byte(config), Enabled: enabled, UpdatedAt: updated}
+	}
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name      string
+		trigger   *storage.Trigger
+		lastFired *time.Time
+		want      *time.Time
+		wantErr   bool
+	}{
+		{"Cron", trigger("cron", `{"schedule":"*/15 * * * *"}`, true), nil, at(10 * time.Minute), false},
+		{"IntervalFromLastFire", trigger("interval", `{"interval":600}`, true), at(-4 * time.Minute), at(6 * time.Minute), false},
+		{"IntervalNeverFired", trigger("interval", `{"interval":1800}`, true), nil, at(30 * time.Minute), false},
+		{"Disabled", trigger("cron", `{"schedule":"* * * * *"}`, false), nil, nil, false},
+		{"Webhook", trigger("webhook", `{}`, true), nil, nil, false},
+		{"InvalidSchedule", trigger("cron", `{"schedule":"soon"}`, true), nil, nil, true},
+		{"CronTimezone", trigger("cron", `{"schedule":"30 12 * * *","timezone":"Asia/Tokyo"}`, true), nil, at(17*time.Hour + 10*time.Minute), false},
+		{"UnknownTimezone", trigger("cron", `{"schedule":"* * * * *","timezone":"Mars/Olympus"}`, true), nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := engine.NextTriggerFire(tt.trigger, tt.lastFired, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,414 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// packageRecordFile is written into an installed package's directory.
+const packageRecordFile = ".package.json"
+
+// maxBlockPackageSize caps the size of a downloaded npm tarball.
+const maxBlockPackageSize = 50 << 20
+
+var (
+	// blockNamePattern matches each half of a packaged block's "namespace/name"
+	// id, as `conv3n new block` generates them.
+	blockNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	// unsafeDirChars are replaced in the version part of a package directory.
+	unsafeDirChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+	// npmPackagePattern matches npm package names, scoped or not.
+	npmPackagePattern = regexp.MustCompile(`^(@[a-z0-9][a-z0-9._~-]*/)?[a-z0-9][a-z0-9._~-]*$`)
+
+	// reservedBlockNamespaces hold the standard blocks and can't be installed over.
+	reservedBlockNamespaces = map[string]bool{"std": true, "custom": true}
+
+	packageClient = &http.Client{Timeout: 2 * time.Minute}
+)
+
+// ErrBlockPackageExists is returned by Install when the same version of the
+// package is already installed and BlockInstallOptions.Force is not set.
+var ErrBlockPackageExists = errors.New("block package already installed")
+
+// BlockPackage describes an installed block package. It is recorded as
+// .package.json in the package's directory.
+type BlockPackage struct {
+	Namespace   string     `json:"namespace"`
+	Dir         string     `json:"dir"` // Relative to the blocks directory
+	Source      string     `json:"source"`
+	Version     string     `json:"version,omitempty"`
+	Checksum    string     `json:"checksum"`
+	Blocks      []NodeType `json:"blocks"`
+	InstalledAt time.Time  `json:"installed_at"`
+}
+
+// BlockInstallOptions tunes BlockRegistry.Install.
+type BlockInstallOptions struct {
+	// Checksum pins the package contents ("sha256:<hex>", see PackageChecksum).
+	// npm tarballs are always checked against the registry's integrity hash.
+	Checksum string
+	// Force replaces an installed copy of the same package version.
+	Force bool
+	// NPMRegistry defaults to $NPM_CONFIG_REGISTRY, then https://registry.npmjs.org.
+	NPMRegistry string
+}
+
+func (o BlockInstallOptions) npmRegistry() string {
+	if o.NPMRegistry != "" {
+		return o.NPMRegistry
+	}
+	if env := os.Getenv("NPM_CONFIG_REGISTRY"); env != "" {
+		return env
+	}
+	return "https://registry.npmjs.org"
+}
+
+// Install downloads a block package and installs it under
+// <blocks dir>/<namespace>@<version>, next to other versions of the package.
+// source is a git URL (optionally with #branch or #tag; the version is then
+// the commit) or an npm package (name or name@version). A package is a
+// directory of blocks with manifests, as `conv3n new block` lays them out, all
+// in one namespace. Its blocks are registered as soon as Install returns;
+// nodes run the newest block version unless pinned to another.
+func (r *BlockRegistry) Install(ctx context.Context, source string, opts BlockInstallOptions) (*BlockPackage, error) {
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create blocks directory: %w", err)
+	}
+	// Download next to the final location so the install is a rename
+	work, err := os.MkdirTemp(r.dir, ".download-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create download directory: %w", err)
+	}
+	defer os.RemoveAll(work)
+
+	var version string
+	if isGitSource(source) {
+		version, err = fetchGitPackage(ctx, source, work)
+	} else {
+		version, err = fetchNPMPackage(ctx, source, work, opts.npmRegistry())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.installDir(work, source, version, opts)
+}
+
+// installDir verifies the package in dir and moves it into place.
+func (r *BlockRegistry) installDir(dir, source, version string, opts BlockInstallOptions) (*BlockPackage, error) {
+	pkg := &BlockPackage{Source: source, Version: version, InstalledAt: time.Now().UTC()}
+
+	// Check every manifest the registry will find once the package is in place
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), manifestSuffix) {
+			return nil
+		}
+		block, err := readBlockManifest(path)
+		if err != nil || block == nil {
+			return err
+		}
+		ns, name, _ := strings.Cut(string(block.Type), "/")
+		if !blockNamePattern.MatchString(ns) || !blockNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid block id %q: use namespace/name with lowercase letters, digits and underscores", block.Type)
+		}
+		if pkg.Namespace != "" && ns != pkg.Namespace {
+			return fmt.Errorf("package mixes namespaces %s and %s", pkg.Namespace, ns)
+		}
+		pkg.Namespace = ns
+		pkg.Blocks = append(pkg.Blocks, block.Type)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(pkg.Blocks) == 0 {
+		return nil, fmt.Errorf("%s contains no block manifests", source)
+	}
+	if reservedBlockNamespaces[pkg.Namespace] {
+		return nil, fmt.Errorf("namespace %s is reserved for the standard blocks", pkg.Namespace)
+	}
+	sort.Slice(pkg.Blocks, func(i, j int) bool { return pkg.Blocks[i] < pkg.Blocks[j] })
+
+	pkg.Checksum, err = PackageChecksum(dir)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Checksum != "" && !strings.EqualFold(opts.Checksum, pkg.Checksum) {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", source, opts.Checksum, pkg.Checksum)
+	}
+
+	pkg.Dir = pkg.Namespace
+	if version != "" {
+		pkg.Dir += "@" + unsafeDirChars.ReplaceAllString(version, "_")
+	}
+	record, err := json.MarshalIndent(pkg, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, packageRecordFile), append(record, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write package record: %w", err)
+	}
+
+	target := filepath.Join(r.dir, pkg.Dir)
+	var previous string
+	if _, err := os.Stat(target); err == nil {
+		if !opts.Force {
+			return nil, fmt.Errorf("%w: %s", ErrBlockPackageExists, pkg.Dir)
+		}
+		previous = filepath.Join(r.dir, fmt.Sprintf(".%s.old-%d", pkg.Dir, time.Now().UnixNano()))
+		if err := os.Rename(target, previous); err != nil {
+			return nil, fmt.Errorf("failed to replace %s: %w", pkg.Dir, err)
+		}
+	}
+	if err := os.Rename(dir, target); err != nil {
+		if previous != "" {
+			os.Rename(previous, target)
+		}
+		return nil, fmt.Errorf("failed to install %s: %w", pkg.Dir, err)
+	}
+	if previous != "" {
+		os.RemoveAll(previous)
+	}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// Packages returns the installed packages, sorted by directory.
+func (r *BlockRegistry) Packages() ([]BlockPackage, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	var packages []BlockPackage
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(r.dir, entry.Name(), packageRecordFile))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // Blocks scaffolded in place
+		}
+		if err != nil {
+			return nil, err
+		}
+		var pkg BlockPackage
+		if err := json.Unmarshal(data, &pkg); err != nil {
+			return nil, fmt.Errorf("invalid package record in %s: %w", entry.Name(), err)
+		}
+		packages = append(packages, pkg)
+	}
+	return packages, nil
+}
+
+// PackageChecksum hashes the files of a package directory, excluding .git and
+// the install record: the SHA-256 of the `sha256sum` listing of its files,
+// sorted by slash-separated path, formatted as "sha256:<hex>". The same
+// listing can be produced with
+//
+//	find . -type f ! -path './.git/*' ! -name .package.json | sed 's|^\./||' | LC_ALL=C sort | xargs sha256sum | sha256sum
+func PackageChecksum(dir string) (string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		switch {
+		case d.IsDir() && d.Name() == ".git":
+			return fs.SkipDir
+		case d.IsDir() || rel == packageRecordFile:
+			return nil
+		case !d.Type().IsRegular():
+			return fmt.Errorf("package contains %s, which is not a regular file", filepath.ToSlash(rel))
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+
+	listing := sha256.New()
+	for _, name := range files {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(listing, "%x  %s\n", sha256.Sum256(data), name)
+	}
+	return "sha256:" + hex.EncodeToString(listing.Sum(nil)), nil
+}
+
+// isGitSource tells git URLs from npm package specs.
+func isGitSource(source string) bool {
+	for _, prefix := range []string{"git+", "git@", "git://", "ssh://", "file://", "http://", "https://"} {
+		if strings.HasPrefix(source, prefix) {
+			return true
+		}
+	}
+	repo, _, _ := strings.Cut(source, "#")
+	return strings.HasSuffix(repo, ".git")
+}
+
+// fetchGitPackage shallow-clones source into dir and returns the commit.
+func fetchGitPackage(ctx context.Context, source, dir string) (string, error) {
+	repo, ref, _ := strings.Cut(strings.TrimPrefix(source, "git+"), "#")
+	args := []string{"clone", "--quiet", "--depth", "1"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	args = append(args, "--", repo, dir)
+	if out, err := exec.CommandContext(ctx, "git", args...).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("git clone %s failed: %w: %s", repo, err, strings.TrimSpace(string(out)))
+	}
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the cloned commit: %w", err)
+	}
+	if err := os.RemoveAll(filepath.Join(dir, ".git")); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// npmVersion is the part of the registry's version document Install reads.
+type npmVersion struct {
+	Version string `json:"version"`
+	Dist    struct {
+		Tarball   string `json:"tarball"`
+		Integrity string `json:"integrity"`
+		Shasum    string `json:"shasum"`
+	} `json:"dist"`
+}
+
+// fetchNPMPackage downloads spec ("name" or "name@version") from an npm
+// registry, verifies it and extracts it into dir. It returns the version.
+func fetchNPMPackage(ctx context.Context, spec, dir, registry string) (string, error) {
+	name, version := spec, "latest"
+	if at := strings.LastIndex(spec, "@"); at > 0 {
+		name, version = spec[:at], spec[at+1:]
+	}
+	if !npmPackagePattern.MatchString(name) || version == "" {
+		return "", fmt.Errorf("invalid source %q: expected a git URL or an npm package name", spec)
+	}
+
+	metaURL := strings.TrimSuffix(registry, "/") + "/" + url.PathEscape(name) + "/" + url.PathEscape(version)
+	meta, err := httpGet(ctx, metaURL)
+	if err != nil {
+		return "", err
+	}
+	var v npmVersion
+	if err := json.Unmarshal(meta, &v); err != nil {
+		return "", fmt.Errorf("invalid registry response for %s: %w", spec, err)
+	}
+	if v.Dist.Tarball == "" {
+		return "", fmt.Errorf("registry has no tarball for %s", spec)
+	}
+
+	tarball, err := httpGet(ctx, v.Dist.Tarball)
+	if err != nil {
+		return "", err
+	}
+	if err := verifyNPMIntegrity(tarball, v.Dist.Integrity, v.Dist.Shasum); err != nil {
+		return "", fmt.Errorf("%s@%s: %w", name, v.Version, err)
+	}
+
+	entries, err := readTar(tarball, true)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		// npm puts the package under a single top-level directory, usually "package/"
+		_, rel, ok := strings.Cut(entry.name, "/")
+		if !ok || rel == "" {
+			continue
+		}
+		dest, err := archiveDest(dir, rel)
+		if err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(dest, entry.data, 0644); err != nil {
+			return "", err
+		}
+	}
+	return v.Version, nil
+}
+
+// verifyNPMIntegrity checks a tarball against the registry's SRI integrity
+// string (sha512), or its legacy sha1 shasum when that is all there is.
+func verifyNPMIntegrity(data []byte, integrity, shasum string) error {
+	for _, sri := range strings.Fields(integrity) {
+		if digest, ok := strings.CutPrefix(sri, "sha512-"); ok {
+			sum := sha512.Sum512(data)
+			if base64.StdEncoding.EncodeToString(sum[:]) != digest {
+				return errors.New("tarball does not match the registry's integrity hash")
+			}
+			return nil
+		}
+	}
+	if shasum != "" {
+		sum := sha1.Sum(data)
+		if hex.EncodeToString(sum[:]) != strings.ToLower(shasum) {
+			return errors.New("tarball does not match the registry's shasum")
+		}
+		return nil
+	}
+	return errors.New("registry published no checksum to verify the tarball against")
+}
+
+// httpGet fetches url, failing on non-2xx responses and bodies over maxBlockPackageSize.
+func httpGet(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := packageClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("failed to download %s: %s", rawURL, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBlockPackageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	if len(data) > maxBlockPackageSize {
+		return nil, fmt.Errorf("%s is larger than %d MB", rawURL, maxBlockPackageSize>>20)
+	}
+	return data, nil
+}
@@ -0,0 +1,293 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// builtinBlockScripts maps the standard node types run in Bun to their scripts,
+// relative to the blocks directory.
+var builtinBlockScripts = map[NodeType]string{
+	NodeTypeHTTPRequest: "std/http_request.ts",
+	NodeTypeCustomCode:  "custom/code.ts",
+	NodeTypeCondition:   "std/condition.ts",
+	NodeTypeLoop:        "std/loop.ts",
+	NodeTypeTransform:   "std/transform.ts",
+	NodeTypeDelay:       "std/delay.ts",
+	NodeTypeFile:        "std/file.ts",
+	NodeTypeDatabase:    "std/database.ts",
+	NodeTypeWebhook:     "std/webhook.ts",
+}
+
+// manifestSuffix ends the name of every block manifest.
+const manifestSuffix = ".manifest.json"
+
+// RegisteredBlock is one version of a node type run by a block script.
+type RegisteredBlock struct {
+	Type    NodeType `json:"type"`
+	Version string   `json:"version,omitempty"`
+	Script  string   `json:"script"`
+	// Yanked is set when a manifest of the type lists this version as withdrawn.
+	Yanked bool `json:"yanked,omitempty"`
+	// Manifest is nil for the standard blocks.
+	Manifest *BlockManifest `json:"manifest,omitempty"`
+}
+
+// BlockRegistry maps node types to the scripts that run them: the standard
+// blocks plus every block with a manifest under the blocks directory, whether
+// scaffolded by `conv3n new block` or installed from a package. A type may be
+// installed in several versions side by side.
+type BlockRegistry struct {
+	dir string
+
+	mu     sync.RWMutex
+	types  map[NodeType][]RegisteredBlock // Newest version first
+	loaded bool
+}
+
+var (
+	blockRegistriesMu sync.Mutex
+	blockRegistries   = map[string]*BlockRegistry{}
+)
+
+// BlockRegistryFor returns the registry of a blocks directory, shared by every
+// runner using that directory so installed blocks are visible to all of them.
+func BlockRegistryFor(blocksDir string) *BlockRegistry {
+	if abs, err := filepath.Abs(blocksDir); err == nil {
+		blocksDir = abs
+	}
+	blockRegistriesMu.Lock()
+	defer blockRegistriesMu.Unlock()
+	r, ok := blockRegistries[blocksDir]
+	if !ok {
+		r = &BlockRegistry{dir: blocksDir}
+		blockRegistries[blocksDir] = r
+	}
+	return r
+}
+
+// Dir returns the blocks directory.
+func (r *BlockRegistry) Dir() string {
+	return r.dir
+}
+
+// Reload rescans the blocks directory for manifests.
+func (r *BlockRegistry) Reload() error {
+	types := make(map[NodeType][]RegisteredBlock, len(builtinBlockScripts))
+	for nodeType, script := range builtinBlockScripts {
+		types[nodeType] = []RegisteredBlock{{Type: nodeType, Script: filepath.Join(r.dir, filepath.FromSlash(script))}}
+	}
+	yanked := map[NodeType]map[string]bool{}
+
+	err := filepath.WalkDir(r.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == r.dir && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() {
+			// Skip install staging directories and package dependencies
+			if path != r.dir && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), manifestSuffix) {
+			return nil
+		}
+		block, err := readBlockManifest(path)
+		if err != nil {
+			log.Printf("Warning: skipping block manifest: %v", err)
+			return nil
+		}
+		if block == nil {
+			return nil
+		}
+		if _, ok := builtinBlockScripts[block.Type]; ok {
+			log.Printf("Warning: %s declares %s, a standard block", path, block.Type)
+			return nil
+		}
+		for _, existing := range types[block.Type] {
+			if existing.Version == block.Version {
+				log.Printf("Warning: %s declares %s %s, already provided by %s", path, block.Type, block.Version, existing.Script)
+				return nil
+			}
+		}
+		types[block.Type] = append(types[block.Type], *block)
+		for _, v := range block.Manifest.Yanked {
+			if yanked[block.Type] == nil {
+				yanked[block.Type] = map[string]bool{}
+			}
+			yanked[block.Type][strings.TrimPrefix(v, "v")] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan blocks directory %s: %w", r.dir, err)
+	}
+
+	for nodeType, versions := range types {
+		for i := range versions {
+			versions[i].Yanked = yanked[nodeType][versions[i].Version]
+		}
+		sort.SliceStable(versions, func(i, j int) bool { return newerBlock(versions[i], versions[j]) })
+	}
+
+	r.mu.Lock()
+	r.types = types
+	r.loaded = true
+	r.mu.Unlock()
+	return nil
+}
+
+// newerBlock orders versions newest first, unversioned blocks last.
+func newerBlock(a, b RegisteredBlock) bool {
+	va, okA := parseSemver(a.Version)
+	vb, okB := parseSemver(b.Version)
+	if okA != okB {
+		return okA
+	}
+	return okA && va.compare(vb) > 0
+}
+
+// readBlockManifest reads a manifest and resolves its script. It returns nil
+// for manifests of other kinds, e.g. triggers.
+func readBlockManifest(path string) (*RegisteredBlock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest BlockManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid block manifest %s: %w", path, err)
+	}
+	if manifest.Kind != "" && manifest.Kind != "block" {
+		return nil, nil
+	}
+	if manifest.ID == "" {
+		return nil, fmt.Errorf("block manifest %s has no id", path)
+	}
+	if manifest.Version != "" {
+		v, ok := parseSemver(manifest.Version)
+		if !ok {
+			return nil, fmt.Errorf("block manifest %s: version %q is not a semantic version", path, manifest.Version)
+		}
+		manifest.Version = v.String()
+	}
+	entry := manifest.Entry
+	if entry == "" {
+		entry = strings.TrimSuffix(filepath.Base(path), manifestSuffix) + ".ts"
+	}
+	if !filepath.IsLocal(entry) {
+		return nil, fmt.Errorf("block manifest %s: entry %q is outside its directory", path, entry)
+	}
+	script := filepath.Join(filepath.Dir(path), filepath.FromSlash(entry))
+	if info, err := os.Stat(script); err != nil || info.IsDir() {
+		return nil, fmt.Errorf("block manifest %s: entry %s not found", path, entry)
+	}
+	return &RegisteredBlock{Type: NodeType(manifest.ID), Version: manifest.Version, Script: script, Manifest: &manifest}, nil
+}
+
+// Lookup returns the newest version of the block that runs nodeType, passing
+// over yanked versions unless nothing else is installed.
+func (r *BlockRegistry) Lookup(nodeType NodeType) (RegisteredBlock, bool) {
+	block, err := r.Resolve(nodeType, VersionConstraint{})
+	return block, err == nil
+}
+
+// Resolve returns the newest version of nodeType that satisfies c, preferring
+// versions that aren't yanked or pre-releases. The directory is rescanned when nothing matches,
+// so blocks installed by another process are found.
+func (r *BlockRegistry) Resolve(nodeType NodeType, c VersionConstraint) (RegisteredBlock, error) {
+	r.mu.RLock()
+	block, err := r.resolveLoaded(nodeType, c)
+	loaded := r.loaded
+	r.mu.RUnlock()
+	if err == nil || (loaded && isBuiltinType(nodeType)) {
+		return block, err
+	}
+	if err := r.Reload(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.resolveLoaded(nodeType, c)
+}
+
+// resolveLoaded is Resolve without the rescan; r.mu must be held.
+func (r *BlockRegistry) resolveLoaded(nodeType NodeType, c VersionConstraint) (RegisteredBlock, error) {
+	versions := r.types[nodeType]
+	if len(versions) == 0 {
+		return RegisteredBlock{}, fmt.Errorf("unknown node type: %s", nodeType)
+	}
+	var yanked, pre *RegisteredBlock
+	for i, block := range versions {
+		v, ok := parseSemver(block.Version)
+		if !c.Any() && (!ok || !c.matches(v)) {
+			continue
+		}
+		// As with npm, pre-releases are only picked when asked for by name
+		if ok && v.pre != "" && !c.allowsPre() {
+			if c.Any() && pre == nil {
+				pre = &versions[i]
+			}
+			continue
+		}
+		if !block.Yanked {
+			return block, nil
+		}
+		if yanked == nil {
+			yanked = &versions[i]
+		}
+	}
+	if yanked != nil {
+		return *yanked, nil
+	}
+	if pre != nil {
+		return *pre, nil
+	}
+	installed := make([]string, 0, len(versions))
+	for _, block := range versions {
+		if block.Version != "" {
+			installed = append(installed, block.Version)
+		}
+	}
+	return RegisteredBlock{}, fmt.Errorf("no installed version of %s matches %q (installed: %s)", nodeType, c, strings.Join(installed, ", "))
+}
+
+// isBuiltinType reports whether the engine handles nodeType without a script
+// of its own, so a missing registry entry doesn't warrant a rescan.
+func isBuiltinType(nodeType NodeType) bool {
+	_, ok := builtinPorts[nodeType]
+	return ok || nodeType.IsTrigger()
+}
+
+// Types returns every registered block version, sorted by type and then
+// newest version first.
+func (r *BlockRegistry) Types() ([]RegisteredBlock, error) {
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var types []RegisteredBlock
+	for _, versions := range r.types {
+		types = append(types, versions...)
+	}
+	sort.SliceStable(types, func(i, j int) bool {
+		if types[i].Type != types[j].Type {
+			return types[i].Type < types[j].Type
+		}
+		return newerBlock(types[i], types[j])
+	})
+	return types, nil
+}
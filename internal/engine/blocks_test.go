@@ -0,0 +1,333 @@
+package engine_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// writeGitPackage commits files into a new git repository and returns its URL.
+func writeGitPackage(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		writeBlock(t, dir, name, content)
+	}
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s failed: %v: %s", args[0], err, out)
+		}
+	}
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "package")
+	return "file://" + dir
+}
+
+func TestBlockRegistry_Lookup(t *testing.T) {
+	blocksDir := t.TempDir()
+	writeBlock(t, blocksDir, "acme/greet.ts", "")
+	writeBlock(t, blocksDir, "acme/greet.manifest.json", `{"id":"acme/greet","kind":"block","version":"1.2.0"}`)
+	writeBlock(t, blocksDir, "acme/lib/shout.js", "")
+	writeBlock(t, blocksDir, "acme/shout.manifest.json", `{"id":"acme/loud","entry":"lib/shout.js"}`)
+	writeBlock(t, blocksDir, "acme/hook.manifest.json", `{"id":"acme/hook","kind":"trigger"}`)
+	writeBlock(t, blocksDir, "acme/missing.manifest.json", `{"id":"acme/missing"}`)
+	writeBlock(t, blocksDir, "acme/clash.ts", "")
+	writeBlock(t, blocksDir, "acme/clash.manifest.json", `{"id":"std/condition"}`)
+	registry := engine.BlockRegistryFor(blocksDir)
+
+	for nodeType, want := range map[engine.NodeType]string{
+		engine.NodeTypeCondition: "std/condition.ts",
+		"acme/greet":             "acme/greet.ts",
+		"acme/loud":              "acme/lib/shout.js",
+	} {
+		block, ok := registry.Lookup(nodeType)
+		if !ok || block.Script != filepath.Join(blocksDir, want) {
+			t.Errorf("%s: expected %s, got %+v", nodeType, want, block)
+		}
+	}
+	for _, nodeType := range []engine.NodeType{"acme/hook", "acme/missing", engine.NodeTypeSSH} {
+		if block, ok := registry.Lookup(nodeType); ok {
+			t.Errorf("%s: expected no block, got %+v", nodeType, block)
+		}
+	}
+
+	// A block added after the first scan is found without a restart
+	writeBlock(t, blocksDir, "acme/late.ts", "")
+	writeBlock(t, blocksDir, "acme/late.manifest.json", `{"id":"acme/late"}`)
+	if _, ok := registry.Lookup("acme/late"); !ok {
+		t.Error("expected acme/late to be found after a rescan")
+	}
+}
+
+func TestBlockRegistry_InstallGit(t *testing.T) {
+	installFakeBun(t)
+	blocksDir := t.TempDir()
+	registry := engine.BlockRegistryFor(blocksDir)
+	source := writeGitPackage(t, map[string]string{
+		"greet.ts":            `cat > /dev/null; echo '{"greeting":"hello"}'`,
+		"greet.manifest.json": `{"id":"acme/greet","kind":"block","version":"1.0.0","entry":"greet.ts","ports":["default"]}`,
+		"README.md":           "# acme blocks",
+	})
+
+	pkg, err := registry.Install(context.Background(), source, engine.BlockInstallOptions{})
+	if err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	if pkg.Namespace != "acme" || len(pkg.Blocks) != 1 || pkg.Blocks[0] != "acme/greet" || pkg.Version == "" {
+		t.Errorf("unexpected package %+v", pkg)
+	}
+	if pkg.Dir != "acme@"+pkg.Version {
+		t.Errorf("expected the package in acme@<commit>, got %s", pkg.Dir)
+	}
+	if _, err := os.Stat(filepath.Join(blocksDir, pkg.Dir, ".git")); !os.IsNotExist(err) {
+		t.Errorf("expected the git metadata to be dropped, got %v", err)
+	}
+	if sum, _ := engine.PackageChecksum(filepath.Join(blocksDir, pkg.Dir)); sum != pkg.Checksum {
+		t.Errorf("expected the recorded checksum %s to match the installed files, got %s", pkg.Checksum, sum)
+	}
+
+	// The installed type runs without further registration
+	runner := engine.NewBunRunner(blocksDir)
+	result, err := runner.ExecuteNode(context.Background(), &engine.Node{ID: "n", Type: "acme/greet"}, map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("ExecuteNode failed: %v", err)
+	}
+	if result.(map[string]interface{})["greeting"] != "hello" {
+		t.Errorf("unexpected result %v", result)
+	}
+
+	packages, err := registry.Packages()
+	if err != nil || len(packages) != 1 || packages[0].Source != source {
+		t.Errorf("expected the package to be listed, got %+v, %v", packages, err)
+	}
+
+	// Reinstalling needs Force, and a pinned checksum must match
+	if _, err := registry.Install(context.Background(), source, engine.BlockInstallOptions{}); !errors.Is(err, engine.ErrBlockPackageExists) {
+		t.Errorf("expected ErrBlockPackageExists, got %v", err)
+	}
+	_, err = registry.Install(context.Background(), source, engine.BlockInstallOptions{Force: true, Checksum: "sha256:00"})
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected a checksum mismatch, got %v", err)
+	}
+	if _, err := registry.Install(context.Background(), source, engine.BlockInstallOptions{Force: true, Checksum: pkg.Checksum}); err != nil {
+		t.Errorf("expected a forced reinstall with the right checksum to succeed, got %v", err)
+	}
+}
+
+func TestBlockRegistry_InstallRejects(t *testing.T) {
+	registry := engine.BlockRegistryFor(t.TempDir())
+	for name, files := range map[string]map[string]string{
+		"no manifests": {"index.ts": ""},
+		"reserved":     {"x.ts": "", "x.manifest.json": `{"id":"std/x"}`},
+		"mixed":        {"a.ts": "", "a.manifest.json": `{"id":"one/a"}`, "b.ts": "", "b.manifest.json": `{"id":"two/b"}`},
+		"bad id":       {"a.ts": "", "a.manifest.json": `{"id":"Acme/A"}`},
+		"escape":       {"a.manifest.json": `{"id":"acme/a","entry":"../../etc/passwd"}`},
+	} {
+		if _, err := registry.Install(context.Background(), writeGitPackage(t, files), engine.BlockInstallOptions{}); err == nil {
+			t.Errorf("%s: expected the install to fail", name)
+		}
+	}
+	if _, err := registry.Install(context.Background(), "Not A Package!", engine.BlockInstallOptions{}); err == nil {
+		t.Error("expected an invalid source to fail")
+	}
+	entries, _ := os.ReadDir(registry.Dir())
+	if len(entries) != 0 {
+		t.Errorf("expected failed installs to leave nothing behind, got %v", entries)
+	}
+}
+
+func TestBlockRegistry_InstallNPM(t *testing.T) {
+	var tarball bytes.Buffer
+	gz := gzip.NewWriter(&tarball)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{
+		"package/package.json":        `{"name":"@acme/blocks","version":"2.0.0"}`,
+		"package/greet.ts":            "",
+		"package/greet.manifest.json": `{"id":"acme/greet"}`,
+	} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	sum := sha512.Sum512(tarball.Bytes())
+	integrity := "sha512-" + base64.StdEncoding.EncodeToString(sum[:])
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/@acme%2Fblocks/latest", "/@acme%2Fblocks/2.0.0":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"version": "2.0.0",
+				"dist":    map[string]string{"tarball": server.URL + "/blocks.tgz", "integrity": integrity},
+			})
+		case "/@acme%2Fblocks/6.6.6":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"version": "6.6.6",
+				"dist":    map[string]string{"tarball": server.URL + "/blocks.tgz", "integrity": "sha512-AAAA"},
+			})
+		case "/blocks.tgz":
+			w.Write(tarball.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	registry := engine.BlockRegistryFor(t.TempDir())
+	opts := engine.BlockInstallOptions{NPMRegistry: server.URL}
+	pkg, err := registry.Install(context.Background(), "@acme/blocks", opts)
+	if err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	if pkg.Version != "2.0.0" || pkg.Namespace != "acme" {
+		t.Errorf("unexpected package %+v", pkg)
+	}
+	if _, ok := registry.Lookup("acme/greet"); !ok {
+		t.Error("expected acme/greet to be registered")
+	}
+
+	_, err = registry.Install(context.Background(), "@acme/blocks@6.6.6", engine.BlockInstallOptions{NPMRegistry: server.URL, Force: true})
+	if err == nil || !strings.Contains(err.Error(), "integrity") {
+		t.Errorf("expected an integrity error, got %v", err)
+	}
+	if _, err := registry.Install(context.Background(), "@acme/missing", opts); err == nil {
+		t.Error("expected an unknown package to fail")
+	}
+}
+
+func TestBlockRegistry_Versions(t *testing.T) {
+	blocksDir := t.TempDir()
+	for dir, manifest := range map[string]string{
+		"acme@1.0.0": `{"id":"acme/greet","version":"1.0.0"}`,
+		"acme@1.1.0": `{"id":"acme/greet","version":"1.1.0","yanked":["1.0.0"]}`,
+		"acme@1.2.0": `{"id":"acme/greet","version":"1.2.0"}`,
+		"acme@2.0.0": `{"id":"acme/greet","version":"2.0.0-beta.1"}`,
+	} {
+		writeBlock(t, blocksDir, dir+"/greet.ts", "")
+		writeBlock(t, blocksDir, dir+"/greet.manifest.json", manifest)
+	}
+	writeBlock(t, blocksDir, "acme@bad/greet.ts", "")
+	writeBlock(t, blocksDir, "acme@bad/greet.manifest.json", `{"id":"acme/greet","version":"one"}`)
+	registry := engine.BlockRegistryFor(blocksDir)
+
+	for _, tc := range []struct {
+		constraint string
+		want       string
+		yanked     bool
+	}{
+		{"", "1.2.0", false},
+		{"1.0.0", "1.0.0", true},
+		{"^1.0.0", "1.2.0", false},
+		{"~1.1.0", "1.1.0", false},
+		{"1.0", "1.0.0", true},
+		{"1.x", "1.2.0", false},
+		{">=1.0.0 <1.2.0", "1.1.0", false},
+		{"2.0.0-beta.1", "2.0.0-beta.1", false},
+	} {
+		c, err := engine.ParseVersionConstraint(tc.constraint)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.constraint, err)
+		}
+		block, err := registry.Resolve("acme/greet", c)
+		if err != nil || block.Version != tc.want || block.Yanked != tc.yanked {
+			t.Errorf("%q: expected %s (yanked %v), got %+v, %v", tc.constraint, tc.want, tc.yanked, block, err)
+		}
+	}
+
+	c, _ := engine.ParseVersionConstraint("^3")
+	if _, err := registry.Resolve("acme/greet", c); err == nil || !strings.Contains(err.Error(), "no installed version of acme/greet matches") {
+		t.Errorf("expected no match for ^3, got %v", err)
+	}
+	for _, bad := range []string{"one", ">=1", "1.2.3.4", "^x"} {
+		if _, err := engine.ParseVersionConstraint(bad); err == nil {
+			t.Errorf("%q: expected an invalid constraint", bad)
+		}
+	}
+}
+
+// TestWorkflowRunner_Run_PinnedBlock verifies that a node pinned to a block
+// version runs that version, with a warning when it is yanked or outdated.
+func TestWorkflowRunner_Run_PinnedBlock(t *testing.T) {
+	installFakeBun(t)
+	blocksDir := t.TempDir()
+	for _, v := range []string{"1.0.0", "1.1.0", "2.0.0"} {
+		yanked := ""
+		if v == "2.0.0" {
+			yanked = `,"yanked":["1.0.0"]`
+		}
+		writeBlock(t, blocksDir, "acme@"+v+"/greet.ts", `cat > /dev/null; echo '{"version":"`+v+`"}'`)
+		writeBlock(t, blocksDir, "acme@"+v+"/greet.manifest.json", `{"id":"acme/greet","version":"`+v+`"`+yanked+`}`)
+	}
+	store := createTestStorage(t)
+
+	run := func(pin string) (interface{}, []string, error) {
+		workflow := engine.Workflow{
+			ID:    "pinned-wf",
+			Nodes: map[string]engine.Node{"greet": {ID: "greet", Type: "acme/greet", Version: pin}},
+		}
+		bus := engine.NewEventBus()
+		var warnings []string
+		var mu sync.Mutex
+		unsubscribe := bus.Subscribe(func(ev engine.Event) {
+			if out := ev.(engine.NodeOutput); out.Stream == "warning" {
+				mu.Lock()
+				warnings = append(warnings, out.Line)
+				mu.Unlock()
+			}
+		}, engine.EventNodeOutput)
+		ctx := engine.NewExecutionContext(workflow.ID)
+		runner := engine.NewWorkflowRunner(ctx, blocksDir, store, nil)
+		runner.SetEventBus(bus)
+		execCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := runner.Run(execCtx, workflow)
+		unsubscribe()
+		mu.Lock()
+		defer mu.Unlock()
+		return ctx.GetResult("greet"), warnings, err
+	}
+
+	for _, tc := range []struct {
+		pin, want, warning string
+	}{
+		{"", "2.0.0", ""},
+		{"^1.0", "1.1.0", "acme/greet 1.1.0 is outdated; 2.0.0 is installed"},
+		{"1.0.0", "1.0.0", "acme/greet 1.0.0 is yanked"},
+	} {
+		result, warnings, err := run(tc.pin)
+		if err != nil {
+			t.Fatalf("pin %q: run failed: %v", tc.pin, err)
+		}
+		if got := fmt.Sprint(result); !strings.Contains(got, tc.want) {
+			t.Errorf("pin %q: expected version %s to run, got %s", tc.pin, tc.want, got)
+		}
+		if (tc.warning == "") != (len(warnings) == 0) || (tc.warning != "" && !strings.Contains(warnings[0], tc.warning)) {
+			t.Errorf("pin %q: expected warning %q, got %v", tc.pin, tc.warning, warnings)
+		}
+	}
+
+	if _, _, err := run("^3"); err == nil || !strings.Contains(err.Error(), "no installed version") {
+		t.Errorf("expected a pin nothing satisfies to fail the node, got %v", err)
+	}
+}
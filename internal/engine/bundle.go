@@ -0,0 +1,289 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Bundle is a set of workflow and trigger definitions deployed together, e.g.
+// by a CI pipeline running `conv3n apply`. A bundle directory holds them as
+// JSON or YAML files under workflows/ and triggers/; as in a workflows
+// directory (see WorkflowSync), IDs default to the file's path.
+type Bundle struct {
+	Workflows []*Workflow
+	Triggers  []*BundleTrigger
+}
+
+// BundleTrigger is a trigger of a bundle, with the fields of the trigger API.
+// Unlike triggers created through the API it has a stable ID, by which it is
+// matched against the deployed triggers.
+type BundleTrigger struct {
+	ID         string                 `json:"id"`
+	WorkflowID string                 `json:"workflow_id"`
+	Type       string                 `json:"type"`
+	Config     map[string]interface{} `json:"config,omitempty"`
+	Enabled    bool                   `json:"enabled"`
+	FilePath   string                 `json:"file_path,omitempty"`
+}
+
+// Change actions and kinds of a bundle plan.
+const (
+	ChangeCreate = "create"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+
+	ChangeKindWorkflow = "workflow"
+	ChangeKindTrigger  = "trigger"
+)
+
+// BundleChange is a change applying a bundle makes to a deployed workflow or trigger.
+type BundleChange struct {
+	Action string `json:"action"`
+	Kind   string `json:"kind"`
+	ID     string `json:"id"`
+	// Fields are what an update changes: "name", "nodes.<id>", "edges" and
+	// "settings" of workflows, the API fields of triggers.
+	Fields []string `json:"fields,omitempty"`
+}
+
+// LoadBundle reads and validates the bundle in dir. Every invalid file is
+// reported, joined with errors.Join.
+func LoadBundle(dir string, schema func(NodeType) (ConfigSchema, error)) (*Bundle, error) {
+	if info, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	bundle := &Bundle{}
+	var errs []error
+	workflowIDs := make(map[string]string)
+	workflowsDir := filepath.Join(dir, "workflows")
+	files, err := bundleFiles(workflowsDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		wf, err := loadWorkflowFile(workflowsDir, file, schema)
+		if err == nil {
+			err = claimID(workflowIDs, wf.ID, file)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("workflows/%s: %w", file, err))
+			continue
+		}
+		bundle.Workflows = append(bundle.Workflows, wf)
+	}
+
+	triggerIDs := make(map[string]string)
+	triggersDir := filepath.Join(dir, "triggers")
+	if files, err = bundleFiles(triggersDir); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		tr, err := loadBundleTrigger(triggersDir, file)
+		if err == nil {
+			err = claimID(triggerIDs, tr.ID, file)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("triggers/%s: %w", file, err))
+			continue
+		}
+		bundle.Triggers = append(bundle.Triggers, tr)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// bundleFiles returns the definition files under dir, none if it doesn't exist.
+func bundleFiles(dir string) ([]string, error) {
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return definitionFiles(dir)
+}
+
+// claimID records that file defines id, failing if another file did.
+func claimID(ids map[string]string, id, file string) error {
+	if other, taken := ids[id]; taken {
+		return fmt.Errorf("%s is already defined by %s", id, other)
+	}
+	ids[id] = file
+	return nil
+}
+
+func loadBundleTrigger(dir, file string) (*BundleTrigger, error) {
+	var tr BundleTrigger
+	if err := readDefinitionFile(filepath.Join(dir, filepath.FromSlash(file)), &tr); err != nil {
+		return nil, fmt.Errorf("invalid trigger: %w", err)
+	}
+	if tr.ID == "" {
+		tr.ID = fileID(file)
+	}
+	var errs []error
+	if err := ValidateTriggerID(tr.ID); err != nil {
+		errs = append(errs, err)
+	}
+	if tr.WorkflowID == "" {
+		errs = append(errs, fieldErrorf("workflow_id", "is required"))
+	}
+	if tr.Type == "" {
+		errs = append(errs, fieldErrorf("type", "is required"))
+	} else {
+		errs = append(errs, ValidateTriggerConfig(TriggerType(tr.Type), tr.Config))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return &tr, nil
+}
+
+// ValidateTriggerID checks a trigger ID chosen by a client rather than the
+// server: it is part of webhook URLs, so it is one segment of the characters
+// allowed in webhook paths.
+func ValidateTriggerID(id string) error {
+	if id == "" || id == "." || id == ".." || len(id) > maxWebhookPathLength {
+		return fieldErrorf("id", "must be 1 to %d characters", maxWebhookPathLength)
+	}
+	for _, c := range id {
+		if !isWebhookPathChar(c) {
+			return fieldErrorf("id", "%q is not allowed", c)
+		}
+	}
+	return nil
+}
+
+// Plan returns the changes that make the deployed workflows and triggers match
+// the bundle: creates and updates of workflows, then of triggers, so triggers
+// find their workflow, followed, when prune is set, by deletes of the triggers
+// and workflows missing from the bundle. Without prune what isn't in the
+// bundle is left alone.
+func (b *Bundle) Plan(workflows []*Workflow, triggers []*BundleTrigger, prune bool) []BundleChange {
+	var changes []BundleChange
+
+	deployedWorkflows := make(map[string]*Workflow, len(workflows))
+	for _, wf := range workflows {
+		deployedWorkflows[wf.ID] = wf
+	}
+	bundledWorkflows := make(map[string]bool)
+	for _, wf := range b.Workflows {
+		bundledWorkflows[wf.ID] = true
+		deployed, ok := deployedWorkflows[wf.ID]
+		if !ok {
+			changes = append(changes, BundleChange{Action: ChangeCreate, Kind: ChangeKindWorkflow, ID: wf.ID})
+		} else if fields := workflowChanges(deployed, wf); len(fields) > 0 {
+			changes = append(changes, BundleChange{Action: ChangeUpdate, Kind: ChangeKindWorkflow, ID: wf.ID, Fields: fields})
+		}
+	}
+
+	deployedTriggers := make(map[string]*BundleTrigger, len(triggers))
+	for _, tr := range triggers {
+		deployedTriggers[tr.ID] = tr
+	}
+	bundledTriggers := make(map[string]bool)
+	for _, tr := range b.Triggers {
+		bundledTriggers[tr.ID] = true
+		deployed, ok := deployedTriggers[tr.ID]
+		if !ok {
+			changes = append(changes, BundleChange{Action: ChangeCreate, Kind: ChangeKindTrigger, ID: tr.ID})
+		} else if fields := triggerChanges(deployed, tr); len(fields) > 0 {
+			changes = append(changes, BundleChange{Action: ChangeUpdate, Kind: ChangeKindTrigger, ID: tr.ID, Fields: fields})
+		}
+	}
+
+	if !prune {
+		return changes
+	}
+	var deletes []BundleChange
+	for _, tr := range triggers {
+		if !bundledTriggers[tr.ID] {
+			deletes = append(deletes, BundleChange{Action: ChangeDelete, Kind: ChangeKindTrigger, ID: tr.ID})
+		}
+	}
+	sort.Slice(deletes, func(i, j int) bool { return deletes[i].ID < deletes[j].ID })
+	changes = append(changes, deletes...)
+	deletes = nil
+	for _, wf := range workflows {
+		if !bundledWorkflows[wf.ID] {
+			deletes = append(deletes, BundleChange{Action: ChangeDelete, Kind: ChangeKindWorkflow, ID: wf.ID})
+		}
+	}
+	sort.Slice(deletes, func(i, j int) bool { return deletes[i].ID < deletes[j].ID })
+	return append(changes, deletes...)
+}
+
+// workflowChanges returns the fields that differ between two definitions.
+func workflowChanges(deployed, wf *Workflow) []string {
+	var fields []string
+	if deployed.Name != wf.Name {
+		fields = append(fields, "name")
+	}
+	ids := make(map[string]bool)
+	for id := range deployed.Nodes {
+		ids[id] = true
+	}
+	for id := range wf.Nodes {
+		ids[id] = true
+	}
+	var nodes []string
+	for id := range ids {
+		if !sameJSON(deployed.Nodes[id], wf.Nodes[id]) {
+			nodes = append(nodes, "nodes."+id)
+		}
+	}
+	sort.Strings(nodes)
+	fields = append(fields, nodes...)
+	if len(deployed.Edges)+len(wf.Edges) > 0 && !sameJSON(deployed.Edges, wf.Edges) {
+		fields = append(fields, "edges")
+	}
+	if !sameJSON(deployed.Settings, wf.Settings) {
+		fields = append(fields, "settings")
+	}
+	return fields
+}
+
+// triggerChanges returns the fields that differ between two triggers.
+func triggerChanges(deployed, tr *BundleTrigger) []string {
+	var fields []string
+	if deployed.WorkflowID != tr.WorkflowID {
+		fields = append(fields, "workflow_id")
+	}
+	if deployed.Type != tr.Type {
+		fields = append(fields, "type")
+	}
+	// The API keeps the token generation of rotated webhook URLs when a
+	// config omits it, so it isn't a change
+	config := deployed.Config
+	if _, set := tr.Config["token_generation"]; !set && config != nil {
+		config = make(map[string]interface{}, len(deployed.Config))
+		for k, v := range deployed.Config {
+			if k != "token_generation" {
+				config[k] = v
+			}
+		}
+	}
+	if len(config)+len(tr.Config) > 0 && !sameJSON(config, tr.Config) {
+		fields = append(fields, "config")
+	}
+	if deployed.Enabled != tr.Enabled {
+		fields = append(fields, "enabled")
+	}
+	if deployed.FilePath != tr.FilePath {
+		fields = append(fields, "file_path")
+	}
+	return fields
+}
+
+// sameJSON reports whether a and b encode to the same JSON; maps encode with
+// sorted keys, so key order doesn't matter.
+func sameJSON(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
@@ -0,0 +1,98 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+func TestLoadBundle(t *testing.T) {
+	dir := t.TempDir()
+	writeWorkflowFile(t, dir, "workflows/orders.yaml", "nodes:\n  wait:\n    type: std/delay\n    config:\n      duration: 1\n")
+	writeWorkflowFile(t, dir, "triggers/orders-hook.json", `{"workflow_id":"orders","type":"webhook","enabled":true}`)
+
+	bundle, err := engine.LoadBundle(dir, engine.BuiltinConfigSchema)
+	if err != nil {
+		t.Fatalf("failed to load bundle: %v", err)
+	}
+	if len(bundle.Workflows) != 1 || bundle.Workflows[0].ID != "orders" || bundle.Workflows[0].Name != "orders" {
+		t.Errorf("expected the orders workflow, got %+v", bundle.Workflows)
+	}
+	if len(bundle.Triggers) != 1 || bundle.Triggers[0].ID != "orders-hook" || !bundle.Triggers[0].Enabled {
+		t.Errorf("expected the orders-hook trigger, got %+v", bundle.Triggers)
+	}
+
+	// Every invalid file is reported
+	writeWorkflowFile(t, dir, "workflows/broken.json", `{"nodes":{}}`)
+	writeWorkflowFile(t, dir, "triggers/nightly.json", `{"workflow_id":"orders","type":"cron","config":{"schedule":"nightly"}}`)
+	writeWorkflowFile(t, dir, "triggers/copy.json", `{"id":"orders-hook","workflow_id":"orders","type":"webhook"}`)
+	_, err = engine.LoadBundle(dir, engine.BuiltinConfigSchema)
+	if err == nil {
+		t.Fatal("expected the invalid files to be reported")
+	}
+	if got := len(err.(interface{ Unwrap() []error }).Unwrap()); got != 3 {
+		t.Errorf("expected 3 problems, got %d: %v", got, err)
+	}
+}
+
+func TestBundlePlan(t *testing.T) {
+	delay := func(duration float64) engine.Node {
+		return engine.Node{Type: engine.NodeTypeDelay, Config: map[string]interface{}{"duration": duration}}
+	}
+	bundle := &engine.Bundle{
+		Workflows: []*engine.Workflow{
+			{ID: "orders", Name: "Orders", Nodes: map[string]engine.Node{"wait": delay(2)}},
+			{ID: "invoices", Name: "Invoices", Nodes: map[string]engine.Node{"wait": delay(1)}},
+			{ID: "same", Name: "Same", Nodes: map[string]engine.Node{"wait": delay(1)}},
+		},
+		Triggers: []*engine.BundleTrigger{
+			{ID: "orders-hook", WorkflowID: "orders", Type: "webhook", Enabled: true},
+			{ID: "nightly", WorkflowID: "invoices", Type: "cron", Config: map[string]interface{}{"schedule": "@daily"}},
+		},
+	}
+	workflows := []*engine.Workflow{
+		{ID: "orders", Name: "Old orders", Nodes: map[string]engine.Node{"wait": delay(1)}},
+		{ID: "same", Name: "Same", Nodes: map[string]engine.Node{"wait": delay(1)}},
+		{ID: "legacy", Name: "Legacy", Nodes: map[string]engine.Node{"wait": delay(1)}},
+	}
+	triggers := []*engine.BundleTrigger{
+		// Rotated webhook URLs aren't a change
+		{ID: "orders-hook", WorkflowID: "orders", Type: "webhook", Enabled: true, Config: map[string]interface{}{"token_generation": float64(2)}},
+		{ID: "legacy-hook", WorkflowID: "legacy", Type: "webhook"},
+	}
+
+	plan := bundle.Plan(workflows, triggers, false)
+	want := []engine.BundleChange{
+		{Action: engine.ChangeUpdate, Kind: engine.ChangeKindWorkflow, ID: "orders", Fields: []string{"name", "nodes.wait"}},
+		{Action: engine.ChangeCreate, Kind: engine.ChangeKindWorkflow, ID: "invoices"},
+		{Action: engine.ChangeCreate, Kind: engine.ChangeKindTrigger, ID: "nightly"},
+	}
+	checkPlan(t, plan, want)
+
+	// Pruning deletes what the bundle doesn't define, triggers first
+	plan = bundle.Plan(workflows, triggers, true)
+	want = append(want,
+		engine.BundleChange{Action: engine.ChangeDelete, Kind: engine.ChangeKindTrigger, ID: "legacy-hook"},
+		engine.BundleChange{Action: engine.ChangeDelete, Kind: engine.ChangeKindWorkflow, ID: "legacy"},
+	)
+	checkPlan(t, plan, want)
+}
+
+func checkPlan(t *testing.T, got, want []engine.BundleChange) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected plan %+v, got %+v", want, got)
+	}
+	for i := range got {
+		if got[i].Action != want[i].Action || got[i].Kind != want[i].Kind || got[i].ID != want[i].ID ||
+			len(got[i].Fields) != len(want[i].Fields) {
+			t.Errorf("change %d: expected %+v, got %+v", i, want[i], got[i])
+			continue
+		}
+		for j := range got[i].Fields {
+			if got[i].Fields[j] != want[i].Fields[j] {
+				t.Errorf("change %d: expected %+v, got %+v", i, want[i], got[i])
+			}
+		}
+	}
+}
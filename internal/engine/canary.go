@@ -0,0 +1,29 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// pickCanary returns the canary version of a workflow for the share of runs
+// set by its storage.WorkflowCanary, and nil for the other runs or if the
+// workflow has no canary. The other runs use the stable version, the
+// workflow's definition, until the canary is promoted to replace it.
+func pickCanary(ctx context.Context, store storage.Storage, workflowID string) (*Workflow, error) {
+	canary, err := store.GetWorkflowCanary(ctx, workflowID)
+	if err != nil || canary == nil {
+		return nil, err
+	}
+	if rand.IntN(100) >= canary.Percent {
+		return nil, nil
+	}
+	var wf Workflow
+	if err := json.Unmarshal(canary.Definition, &wf); err != nil {
+		return nil, fmt.Errorf("invalid canary definition: %w", err)
+	}
+	return &wf, nil
+}
@@ -0,0 +1,74 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// TestTriggerManager_Canary verifies that trigger fires run the canary version
+// of a workflow while it has one, and flag their executions.
+func TestTriggerManager_Canary(t *testing.T) {
+	ctx := context.Background()
+	store := createTestStorage(t)
+
+	version := func(name string) []byte {
+		def, _ := json.Marshal(engine.Workflow{ID: "wf-canary", Nodes: map[string]engine.Node{
+			"mark": {ID: "mark", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "version", "value": name}},
+		}})
+		return def
+	}
+	if err := store.CreateWorkflow(ctx, &storage.Workflow{ID: "wf-canary", Name: "wf-canary", Definition: version("stable")}); err != nil {
+		t.Fatalf("failed to create workflow: %v", err)
+	}
+	if err := store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-canary", WorkflowID: "wf-canary", Type: "webhook", Config: []byte(`{}`), Enabled: true}); err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+	tm := engine.NewTriggerManager(store, t.TempDir(), nil, engine.NewWorkerPool(1))
+	defer tm.StopAll()
+	tm.Register(engine.NewWebhookTrigger("tr-canary", "wf-canary", tm))
+
+	fire := func() string {
+		t.Helper()
+		results, err := tm.FireSync(ctx, "tr-canary", nil)
+		if err != nil {
+			t.Fatalf("failed to fire: %v", err)
+		}
+		var mark struct {
+			Data struct{ Value string } `json:"data"`
+		}
+		raw, _ := json.Marshal(results["mark"])
+		json.Unmarshal(raw, &mark)
+		return mark.Data.Value
+	}
+
+	if got := fire(); got != "stable" {
+		t.Errorf("expected the stable version without a canary, got %q", got)
+	}
+	store.SetWorkflowCanary(ctx, &storage.WorkflowCanary{WorkflowID: "wf-canary", Definition: version("next"), Percent: 100})
+	if got := fire(); got != "next" {
+		t.Errorf("expected the canary version, got %q", got)
+	}
+
+	stable, canary, err := store.WorkflowVersionStats(ctx, "wf-canary", time.Time{})
+	if err != nil {
+		t.Fatalf("failed to get version stats: %v", err)
+	}
+	if stable.Runs != 1 || stable.Completed != 1 || canary.Runs != 1 || canary.Completed != 1 {
+		t.Errorf("expected one completed run of each version, got %+v and %+v", stable, canary)
+	}
+	execs, _ := store.ListExecutions(ctx, "wf-canary", 10)
+	flagged := 0
+	for _, exec := range execs {
+		if exec.Canary {
+			flagged++
+		}
+	}
+	if flagged != 1 {
+		t.Errorf("expected the canary run to be flagged, got %d flagged", flagged)
+	}
+}
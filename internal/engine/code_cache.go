@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// codeCacheVersion is part of every code hash. Bump it when custom/code.ts
+// changes how it compiles code, so modules compiled the old way aren't reused.
+const codeCacheVersion = "1"
+
+// maxCodeCacheEntries bounds how many compiled modules are kept; the least
+// recently used go first.
+const maxCodeCacheEntries = 1000
+
+// DefaultCodeCacheDir is where compiled custom/code modules are kept:
+// $CONV3N_CODE_CACHE_DIR, or conv3n/code in the user's cache directory.
+func DefaultCodeCacheDir() string {
+	if dir := os.Getenv("CONV3N_CODE_CACHE_DIR"); dir != "" {
+		return dir
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "conv3n", "code")
+}
+
+// codeModulePath returns where the module compiled from code is cached.
+func codeModulePath(dir, code string) string {
+	sum := sha256.Sum256([]byte("conv3n-code-v" + codeCacheVersion + "\x00" + code))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".js")
+}
+
+// executeCustomCode runs the custom/code block, telling it where the compiled
+// module for its code is cached. The block compiles the code and writes the
+// module there on a miss, and imports it without compiling on a hit.
+func (r *BunRunner) executeCustomCode(ctx context.Context, scriptPath string, input any) (any, error) {
+	payload, _ := input.(map[string]interface{})
+	config, _ := payload["config"].(map[string]interface{})
+	code, _ := config["code"].(string)
+	if code == "" || r.CodeCacheDir == "" {
+		return r.Execute(ctx, scriptPath, input)
+	}
+	if err := os.MkdirAll(r.CodeCacheDir, 0700); err != nil {
+		log.Printf("Warning: custom code cache disabled: %v", err)
+		return r.Execute(ctx, scriptPath, input)
+	}
+
+	module := codeModulePath(r.CodeCacheDir, code)
+	_, err := os.Stat(module)
+	cached := err == nil
+	if cached {
+		// Keep recently used modules when pruning
+		now := time.Now()
+		os.Chtimes(module, now, now)
+	}
+
+	withModule := make(map[string]interface{}, len(payload)+1)
+	maps.Copy(withModule, payload)
+	withModule["codeModule"] = module
+	result, err := r.Execute(ctx, scriptPath, withModule)
+
+	if !cached {
+		if _, statErr := os.Stat(module); statErr == nil {
+			pruneCodeCache(r.CodeCacheDir, maxCodeCacheEntries)
+		}
+	}
+	return result, err
+}
+
+// pruneCodeCache removes the least recently used modules beyond max.
+func pruneCodeCache(dir string, max int) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	type module struct {
+		path    string
+		modTime time.Time
+	}
+	var modules []module
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".js") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		modules = append(modules, module{filepath.Join(dir, entry.Name()), info.ModTime()})
+	}
+	if len(modules) <= max {
+		return
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].modTime.Before(modules[j].modTime) })
+	for _, m := range modules[:len(modules)-max] {
+		os.Remove(m.path)
+	}
+}
@@ -0,0 +1,55 @@
+package engine_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+func TestBunRunner_CustomCodeCache(t *testing.T) {
+	installFakeBun(t)
+	blocksDir := t.TempDir()
+	// Echo the payload and create the module it names, as the real block does on a miss
+	writeBlock(t, blocksDir, "custom/code.ts", `input=$(cat)
+module=$(printf '%s' "$input" | sed 's/.*"codeModule":"\([^"]*\)".*/\1/')
+: > "$module"
+printf '%s' "$input"
+`)
+
+	runner := engine.NewBunRunner(blocksDir)
+	runner.CodeCacheDir = filepath.Join(t.TempDir(), "code")
+
+	run := func(code string) string {
+		t.Helper()
+		input := map[string]interface{}{"config": map[string]interface{}{"code": code}}
+		result, err := runner.ExecuteBlock(context.Background(), engine.Block{Type: engine.NodeTypeCustomCode}, input)
+		if err != nil {
+			t.Fatalf("ExecuteBlock failed: %v", err)
+		}
+		if _, ok := input["codeModule"]; ok {
+			t.Error("expected the caller's input to be left unchanged")
+		}
+		module, _ := result.(map[string]interface{})["codeModule"].(string)
+		if filepath.Dir(module) != runner.CodeCacheDir {
+			t.Fatalf("expected a module in %s, got %q", runner.CodeCacheDir, module)
+		}
+		return module
+	}
+
+	first := run("return { a: 1 }")
+	if run("return { a: 1 }") != first {
+		t.Error("expected the same module for the same code")
+	}
+	second := run("return { a: 2 }")
+	if second == first {
+		t.Error("expected a different module for different code")
+	}
+
+	entries, err := os.ReadDir(runner.CodeCacheDir)
+	if err != nil || len(entries) != 2 {
+		t.Errorf("expected 2 cached modules, got %d (%v)", len(entries), err)
+	}
+}
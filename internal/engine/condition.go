@@ -0,0 +1,225 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+)
+
+// executeCondition evaluates a std/condition node in-process instead of spawning Bun.
+// Input and output match the Bun block: {"config": {"expression": ...}, "input": ...}
+// in, {"data": {"result": bool, "expression": ...}, "port": "true"|"false"} out.
+func executeCondition(input any) (any, error) {
+	payload, _ := input.(map[string]interface{})
+	config, _ := payload["config"].(map[string]interface{})
+	expression, _ := config["expression"].(string)
+	if strings.TrimSpace(expression) == "" {
+		return nil, fmt.Errorf("condition: field 'expression' must not be empty")
+	}
+
+	data := payload["input"]
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	env := map[string]interface{}{"input": data}
+
+	translated, strict, loose := jsToExpr(expression)
+	if strict && loose {
+		// expr has a single equality operator: it can't hold both semantics
+		return nil, fmt.Errorf("%w: expression mixes == and ===", errNeedsBun)
+	}
+	options := []expr.Option{expr.Env(env)}
+	if loose {
+		options = append(options,
+			expr.Function("looseEqual", func(params ...any) (any, error) { return looseEqual(params[0], params[1]) }, new(func(any, any) bool)),
+			expr.Patch(looseEqualityPatcher{}))
+	}
+	program, err := expr.Compile(translated, options...)
+	if err != nil {
+		// JavaScript-only syntax (typeof, .includes(), ...) still works through the Bun block
+		return nil, fmt.Errorf("%w: %v", errNeedsBun, err)
+	}
+	value, err := expr.Run(program, env)
+	if err != nil {
+		// expr refuses what JavaScript coerces: comparing a missing field
+		// (undefined > 5), && on non-booleans... The Bun block has JS semantics.
+		return nil, fmt.Errorf("%w: %v", errNeedsBun, err)
+	}
+
+	result := truthy(value)
+	port := "false"
+	if result {
+		port = "true"
+	}
+	return map[string]interface{}{
+		"data": map[string]interface{}{
+			"result":     result,
+			"expression": expression,
+		},
+		"port": port,
+	}, nil
+}
+
+// jsToExpr rewrites the JavaScript operators and literals common in condition
+// expressions (===, !==, null, undefined) to their expr equivalents, leaving
+// string literals untouched. It reports whether s compares with strict (===,
+// !==) and loose (==, !=) equality, which expr's == has neither of.
+func jsToExpr(s string) (out string, strict, loose bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			// Copy the string literal verbatim, honouring escapes
+			j := i + 1
+			for j < len(s) && s[j] != c {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j < len(s) {
+				j++
+			}
+			b.WriteString(s[i:min(j, len(s))])
+			i = j
+		case strings.HasPrefix(s[i:], "==="), strings.HasPrefix(s[i:], "!=="):
+			b.WriteString(s[i : i+2])
+			i += 3
+			strict = true
+		case strings.HasPrefix(s[i:], "=="), strings.HasPrefix(s[i:], "!="):
+			b.WriteString(s[i : i+2])
+			i += 2
+			loose = true
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(s) && (isIdentStart(s[j]) || (s[j] >= '0' && s[j] <= '9')) {
+				j++
+			}
+			word := s[i:j]
+			// Property names (input.null) are not literals
+			if (word == "null" || word == "undefined") && (i == 0 || s[i-1] != '.') {
+				word = "nil"
+			}
+			b.WriteString(word)
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String(), strict, loose
+}
+
+// looseEqualityPatcher replaces the == and != of an expression with calls to
+// looseEqual, for JavaScript's loose equality.
+type looseEqualityPatcher struct{}
+
+func (looseEqualityPatcher) Visit(node *ast.Node) {
+	bin, ok := (*node).(*ast.BinaryNode)
+	if !ok || (bin.Operator != "==" && bin.Operator != "!=") {
+		return
+	}
+	var call ast.Node = &ast.CallNode{
+		Callee:    &ast.IdentifierNode{Value: "looseEqual"},
+		Arguments: []ast.Node{bin.Left, bin.Right},
+	}
+	if bin.Operator == "!=" {
+		call = &ast.UnaryNode{Operator: "!", Node: call}
+	}
+	ast.Patch(node, call)
+}
+
+// looseEqual applies JavaScript's == to values decoded from JSON (and expr's
+// int literals): null equals only null, and otherwise booleans and strings are
+// compared to numbers as numbers. Objects and arrays compare by reference in
+// JavaScript, which a copy can't, so they make it fail.
+func looseEqual(a, b any) (bool, error) {
+	if a == nil || b == nil {
+		return a == nil && b == nil, nil
+	}
+	switch a.(type) {
+	case map[string]interface{}, []interface{}:
+		return false, fmt.Errorf("loose equality of %T", a)
+	}
+	switch b.(type) {
+	case map[string]interface{}, []interface{}:
+		return false, fmt.Errorf("loose equality of %T", b)
+	}
+	as, aString := a.(string)
+	bs, bString := b.(string)
+	if aString && bString {
+		return as == bs, nil
+	}
+	x, y := jsNumber(a), jsNumber(b)
+	return x == y, nil // NaN equals nothing
+}
+
+// jsNumber applies JavaScript's Number() coercion to a primitive.
+func jsNumber(v any) float64 {
+	switch val := v.(type) {
+	case bool:
+		if val {
+			return 1
+		}
+		return 0
+	case int:
+		return float64(val)
+	case int64:
+		return float64(val)
+	case float64:
+		return val
+	case string:
+		s := strings.TrimSpace(val)
+		switch s {
+		case "":
+			return 0
+		case "Infinity", "+Infinity":
+			return math.Inf(1)
+		case "-Infinity":
+			return math.Inf(-1)
+		}
+		if len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+			if n, err := strconv.ParseUint(s[2:], 16, 64); err == nil {
+				return float64(n)
+			}
+			return math.NaN()
+		}
+		// ParseFloat also reads "inf", "nan" and hex floats, which Number() doesn't
+		if strings.ContainsAny(s, "iInNxXpP_") {
+			return math.NaN()
+		}
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n
+		}
+	}
+	return math.NaN()
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// truthy applies JavaScript's Boolean() coercion, as the Bun block did.
+func truthy(v any) bool {
+	switch val := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return val
+	case string:
+		return val != ""
+	case int:
+		return val != 0
+	case int64:
+		return val != 0
+	case float64:
+		return val != 0 && !math.IsNaN(val)
+	default:
+		return true
+	}
+}
@@ -0,0 +1,114 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// TestBunRunner_ExecuteNode_NativeCondition verifies that std/condition is evaluated
+// in-process with the Bun block's semantics, without a bun executable.
+func TestBunRunner_ExecuteNode_NativeCondition(t *testing.T) {
+	runner := engine.NewBunRunner(t.TempDir())
+	runner.RuntimePath = "/nonexistent/bun" // any process start would fail
+
+	tests := []struct {
+		name       string
+		expression string
+		input      interface{}
+		want       bool
+	}{
+		{"literal true", "true", nil, true},
+		{"resolved comparison", "150 > 100", nil, true},
+		{"strict equality", "input.status === 'completed'", map[string]interface{}{"status": "completed"}, true},
+		{"strict inequality", "input.status !== 'completed'", map[string]interface{}{"status": "completed"}, false},
+		{"logical operators", "input.a > 10 && !(input.b > 10)", map[string]interface{}{"a": 15.0, "b": 5.0}, true},
+		{"nested access", "input.user.age >= 18", map[string]interface{}{"user": map[string]interface{}{"age": 20.0}}, true},
+		{"null check", "input.value === null", map[string]interface{}{"value": nil}, true},
+		{"truthy string", "input.value", map[string]interface{}{"value": "x"}, true},
+		{"falsy zero", "input.value", map[string]interface{}{"value": 0.0}, false},
+		{"falsy empty string", "input.value", map[string]interface{}{"value": ""}, false},
+		{"operator inside string literal", "'a === b' == 'a === b'", nil, true},
+		{"loose equality coerces strings", "input.n == '5'", map[string]interface{}{"n": 5.0}, true},
+		{"loose inequality coerces strings", "input.n != '5'", map[string]interface{}{"n": 5.0}, false},
+		{"loose equality coerces booleans", "input.flag == 1", map[string]interface{}{"flag": true}, true},
+		{"loose equality of a missing field and null", "input.missing == null", map[string]interface{}{}, true},
+		{"loose equality of null and zero", "input.value == 0", map[string]interface{}{"value": nil}, false},
+		{"strict equality doesn't coerce", "input.n === '5'", map[string]interface{}{"n": 5.0}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &engine.Node{ID: "cond", Type: engine.NodeTypeCondition}
+			input := map[string]interface{}{
+				"config": map[string]interface{}{"expression": tt.expression},
+				"input":  tt.input,
+			}
+			raw, err := runner.ExecuteNode(context.Background(), node, input)
+			if err != nil {
+				t.Fatalf("ExecuteNode failed: %v", err)
+			}
+			out := raw.(map[string]interface{})
+			data := out["data"].(map[string]interface{})
+			if data["result"] != tt.want {
+				t.Errorf("expected result %v, got %v", tt.want, data["result"])
+			}
+			wantPort := "false"
+			if tt.want {
+				wantPort = "true"
+			}
+			if out["port"] != wantPort {
+				t.Errorf("expected port %s, got %v", wantPort, out["port"])
+			}
+		})
+	}
+
+	t.Run("empty expression", func(t *testing.T) {
+		node := &engine.Node{ID: "cond", Type: engine.NodeTypeCondition}
+		input := map[string]interface{}{"config": map[string]interface{}{"expression": " "}}
+		if _, err := runner.ExecuteNode(context.Background(), node, input); err == nil {
+			t.Error("expected error for empty expression")
+		}
+	})
+}
+
+// TestBunRunner_ExecuteNode_ConditionFallback verifies that expressions the
+// native evaluator cannot compile, or evaluates otherwise than JavaScript would,
+// run through the Bun block.
+func TestBunRunner_ExecuteNode_ConditionFallback(t *testing.T) {
+	installFakeBun(t)
+	blocksDir := t.TempDir()
+	writeBlock(t, blocksDir, "std/condition.ts", `echo '{"data":{"result":true,"expression":"js"},"port":"true"}'`+"\n")
+	runner := engine.NewBunRunner(blocksDir)
+
+	tests := []struct {
+		name       string
+		expression string
+		input      interface{}
+	}{
+		{"JavaScript-only syntax", "typeof input.value === 'string'", nil},
+		{"missing field compared", "input.count > 5", map[string]interface{}{}},
+		{"non-boolean operands", "input.a && input.b", map[string]interface{}{"a": "x", "b": 1.0}},
+		{"negated non-boolean", "!input.name", map[string]interface{}{"name": ""}},
+		{"strict and loose equality", "input.a === 1 && input.b == '2'", map[string]interface{}{"a": 1.0, "b": 2.0}},
+		{"loose equality of objects", "input.a == input.b", map[string]interface{}{"a": map[string]interface{}{}, "b": map[string]interface{}{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &engine.Node{ID: "cond", Type: engine.NodeTypeCondition}
+			input := map[string]interface{}{
+				"config": map[string]interface{}{"expression": tt.expression},
+				"input":  tt.input,
+			}
+			raw, err := runner.ExecuteNode(context.Background(), node, input)
+			if err != nil {
+				t.Fatalf("ExecuteNode failed: %v", err)
+			}
+			data := raw.(map[string]interface{})["data"].(map[string]interface{})
+			if data["expression"] != "js" {
+				t.Errorf("expected the Bun block's result, got %v", data)
+			}
+		})
+	}
+}
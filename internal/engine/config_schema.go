@@ -0,0 +1,416 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// FieldError is a problem with one field of a definition, named by its path
+// in the JSON, like "nodes.fetch.config.url" or "config.schedule".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// fieldErrorf returns a *FieldError of field.
+func fieldErrorf(field, format string, args ...interface{}) error {
+	return &FieldError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// ConfigField describes one key of a node or trigger config.
+type ConfigField struct {
+	// Type is string, number, integer, boolean, object or array; empty allows any.
+	Type     string `json:"type,omitempty"`
+	Required bool   `json:"required,omitempty"`
+	// Enum lists the values a string field may take.
+	Enum        []string `json:"enum,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// ConfigSchema maps the keys of a config to their description. Keys it doesn't
+// list are not checked, so a schema may describe only the keys that matter.
+type ConfigSchema map[string]ConfigField
+
+// Check validates config against the schema, reporting every problem as a
+// *FieldError under prefix ("nodes.fetch.config"), joined with errors.Join.
+// A string holding a {{ }} template stands for any type, as its value is only
+// known at run time; empty strings count as missing.
+func (s ConfigSchema) Check(prefix string, config map[string]interface{}) error {
+	keys := make([]string, 0, len(s))
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		field := s[key]
+		path := prefix + "." + key
+		value, ok := config[key]
+		if !ok || value == nil || value == "" {
+			if field.Required {
+				errs = append(errs, fieldErrorf(path, "is required"))
+			}
+			continue
+		}
+		if str, isString := value.(string); isString && isTemplate(str) {
+			continue
+		}
+		if field.Type != "" && !hasConfigType(value, field.Type) {
+			errs = append(errs, fieldErrorf(path, "must be %s %s, got %s", article(field.Type), field.Type, jsonType(value)))
+			continue
+		}
+		if str, isString := value.(string); isString && len(field.Enum) > 0 && !slices.Contains(field.Enum, str) {
+			errs = append(errs, fieldErrorf(path, "must be one of %s, got %q", strings.Join(field.Enum, ", "), str))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isTemplate reports whether s holds a {{ }} expression resolved at run time.
+func isTemplate(s string) bool {
+	return strings.Contains(s, "{{")
+}
+
+// hasConfigType reports whether value, decoded from JSON or set from Go, is of
+// the schema type typ.
+func hasConfigType(value interface{}, typ string) bool {
+	switch typ {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number", "integer":
+		var f float64
+		switch v := value.(type) {
+		case float64:
+			f = v
+		case float32:
+			f = float64(v)
+		case int, int32, int64:
+			return true
+		case json.Number:
+			var err error
+			if f, err = v.Float64(); err != nil {
+				return false
+			}
+		default:
+			return false
+		}
+		return typ == "number" || f == math.Trunc(f)
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		if !ok {
+			_, ok = value.([]string)
+		}
+		return ok
+	}
+	return true
+}
+
+// jsonType names the JSON type of a config value for error messages.
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, float32, int, int32, int64, json.Number:
+		return "number"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}, []string:
+		return "array"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func article(typ string) string {
+	if strings.ContainsRune("aeiou", rune(typ[0])) {
+		return "an"
+	}
+	return "a"
+}
+
+// Schemas shared by several node types.
+var (
+	durationUnits = []string{"ms", "s", "m", "h"}
+
+	sshConnectionSchema = ConfigSchema{
+		"host":                  {Type: "string", Required: true},
+		"port":                  {Type: "integer"},
+		"user":                  {Type: "string", Required: true},
+		"password":              {Type: "string"},
+		"privateKey":            {Type: "string"},
+		"passphrase":            {Type: "string"},
+		"hostKey":               {Type: "string"},
+		"insecureIgnoreHostKey": {Type: "boolean"},
+		"connectTimeout":        {Type: "integer"},
+	}
+)
+
+// withFields returns a copy of schema with fields added.
+func withFields(schema, fields ConfigSchema) ConfigSchema {
+	merged := make(ConfigSchema, len(schema)+len(fields))
+	for key, field := range schema {
+		merged[key] = field
+	}
+	for key, field := range fields {
+		merged[key] = field
+	}
+	return merged
+}
+
+// builtinConfigSchemas describe the configs of the standard nodes.
+var builtinConfigSchemas = map[NodeType]ConfigSchema{
+	NodeTypeHTTPRequest: {
+		"url":     {Type: "string", Required: true},
+		"method":  {Type: "string"},
+		"headers": {Type: "object"},
+	},
+	NodeTypeCustomCode: {"code": {Type: "string", Required: true}},
+	NodeTypeCondition:  {"expression": {Type: "string", Required: true}},
+	NodeTypeLoop: {
+		"items":            {Type: "array", Required: true},
+		"filterExpression": {Type: "string"},
+		"mapExpression":    {Type: "string"},
+	},
+	NodeTypeTransform: {"operations": {Type: "array", Required: true}},
+	NodeTypeDelay: {
+		"duration": {Type: "number", Required: true},
+		"unit":     {Type: "string", Enum: []string{"ms", "s"}},
+	},
+	NodeTypeSetVar: {"name": {Type: "string", Required: true}},
+	NodeTypeGetVar: {"name": {Type: "string", Required: true}},
+	NodeTypeSSH: withFields(sshConnectionSchema, ConfigSchema{
+		"command": {Type: "string"},
+		"script":  {Type: "string"},
+	}),
+	NodeTypeSFTP: withFields(sshConnectionSchema, ConfigSchema{
+		"path":      {Type: "string", Required: true},
+		"operation": {Type: "object", Required: true},
+	}),
+	NodeTypeCSV: {
+		"operation": {Type: "string", Required: true, Enum: []string{"parse", "serialize"}},
+		"content":   {Type: "string"},
+		"items":     {Type: "array"},
+		"header":    {Type: "boolean"},
+		"columns":   {Type: "array"},
+		"delimiter": {Type: "string"},
+	},
+	NodeTypeSpreadsheet: {
+		"operation":     {Type: "string", Required: true, Enum: []string{"read", "append"}},
+		"spreadsheetId": {Type: "string", Required: true},
+		"range":         {Type: "string", Required: true},
+		"header":        {Type: "boolean"},
+		"rows":          {Type: "array"},
+		"columns":       {Type: "array"},
+	},
+	NodeTypeArchive: {
+		"operation": {Type: "string", Required: true, Enum: []string{"create", "extract"}},
+		"format":    {Type: "string", Enum: []string{"zip", "tar", "tar.gz"}},
+		"files":     {Type: "array"},
+		"include":   {Type: "array"},
+		"encoding":  {Type: "string", Enum: []string{"base64", "utf8"}},
+	},
+	NodeTypeDatetime: {
+		"operation": {Type: "string", Required: true, Enum: []string{"parse", "format", "convert", "add", "subtract", "diff", "compare"}},
+		"timezone":  {Type: "string"},
+		"unit":      {Type: "string", Enum: []string{"ms", "s", "m", "h", "d"}},
+	},
+	NodeTypeXML: {
+		"operation":   {Type: "string", Required: true, Enum: []string{"parse", "serialize", "soap"}},
+		"arrays":      {Type: "array"},
+		"data":        {Type: "object"},
+		"declaration": {Type: "boolean"},
+		"version":     {Type: "string", Enum: []string{"1.1", "1.2"}},
+	},
+	NodeTypeEnqueue: {
+		"duration": {Type: "number"},
+		"unit":     {Type: "string", Enum: durationUnits},
+		"at":       {Type: "string"},
+		"signal":   {Type: "string"},
+	},
+	NodeTypeRateLimit: {
+		"key":    {Type: "string", Required: true},
+		"limit":  {Type: "integer", Required: true},
+		"window": {Type: "number"},
+		"unit":   {Type: "string", Enum: durationUnits},
+	},
+	NodeTypeDedupe: {
+		"key":   {Required: true},
+		"scope": {Type: "string"},
+		"ttl":   {Type: "number"},
+		"unit":  {Type: "string", Enum: durationUnits},
+	},
+}
+
+// triggerConfigSchemas describe the configs of the trigger types. Every type
+// also takes the keys of commonTriggerSchema.
+var triggerConfigSchemas = map[TriggerType]ConfigSchema{
+	TriggerTypeCron: {
+		"schedule":     {Type: "string", Required: true},
+		"timezone":     {Type: "string"},
+		"catch_up":     {Type: "boolean"},
+		"max_catch_up": {Type: "integer"},
+	},
+	TriggerTypeInterval: {"interval": {Type: "number", Required: true}},
+	TriggerTypeWebhook: {
+		"max_body_size":    {Type: "integer"},
+		"require_token":    {Type: "boolean"},
+		"token_generation": {Type: "integer"},
+		"path":             {Type: "string"},
+	},
+	TriggerTypeWebSocket: {},
+	TriggerTypeTS: {
+		"working_dir":     {Type: "string"},
+		"env":             {Type: "object"},
+		"env_passthrough": {Type: "array"},
+		"bun_args":        {Type: "array"},
+		"path":            {Type: "string"},
+	},
+}
+
+var commonTriggerSchema = ConfigSchema{
+	"bindings":     {Type: "array"},
+	"sample_every": {Type: "integer"},
+}
+
+// ValidateTriggerConfig checks the config of a trigger of a type against the
+// type's schema and, beyond it, that a cron schedule parses, an interval is at
+// least a second, a webhook path is well-formed (see WebhookPath) and the
+// bindings are well-formed (see TriggerBindings). Every
+// problem is a *FieldError under "config", joined with errors.Join.
+func ValidateTriggerConfig(triggerType TriggerType, config map[string]interface{}) error {
+	schema, ok := triggerConfigSchemas[triggerType]
+	if !ok {
+		return fieldErrorf("type", "unknown trigger type %q", triggerType)
+	}
+	errs := []error{withFields(commonTriggerSchema, schema).Check("config", config)}
+	if errs[0] != nil {
+		// The checks below assume well-typed values
+		return errs[0]
+	}
+
+	switch triggerType {
+	case TriggerTypeCron:
+		if err := ValidateCronConfig(config); err != nil {
+			field := "config.schedule"
+			if strings.HasPrefix(err.Error(), "unknown timezone") {
+				field = "config.timezone"
+			}
+			errs = append(errs, &FieldError{Field: field, Message: err.Error()})
+		}
+	case TriggerTypeInterval:
+		if seconds, _ := config["interval"].(float64); seconds < 1 {
+			errs = append(errs, fieldErrorf("config.interval", "must be at least 1 (seconds)"))
+		}
+	case TriggerTypeWebhook, TriggerTypeTS:
+		if _, err := WebhookPath(config); err != nil {
+			errs = append(errs, &FieldError{Field: "config.path", Message: err.Error()})
+		}
+	}
+	if _, err := TriggerBindings(config); err != nil {
+		errs = append(errs, &FieldError{Field: "config.bindings", Message: err.Error()})
+	}
+	return errors.Join(errs...)
+}
+
+// BuiltinConfigSchema returns the config schema of a standard node type, nil
+// for the other types.
+func BuiltinConfigSchema(nodeType NodeType) (ConfigSchema, error) {
+	return builtinConfigSchemas[nodeType], nil
+}
+
+// ConfigSchema returns the config schema of a node type: the built-in one of a
+// standard node, or the "config" of the block's manifest. A nil schema means
+// the type's config isn't described and is not checked.
+func (r *BunRunner) ConfigSchema(nodeType NodeType) (ConfigSchema, error) {
+	if schema, ok := builtinConfigSchemas[nodeType]; ok {
+		return schema, nil
+	}
+	if block, ok := BlockRegistryFor(r.BlocksDir).Lookup(nodeType); ok && block.Manifest != nil {
+		return block.Manifest.Config, nil
+	}
+	return nil, nil
+}
+
+// ValidateDefinition checks a workflow being saved for what is known to fail
+// at run time: nodes without a type or with an invalid version, configs that
+// don't match the schema of their node type (as returned by schema), edges
+// between unknown nodes and malformed settings. Unlike Validate it accepts
+// graphs still being built, without nodes or a start node. Every problem is
+// a *FieldError, joined with errors.Join.
+func (w *Workflow) ValidateDefinition(schema func(NodeType) (ConfigSchema, error)) error {
+	var errs []error
+
+	ids := make([]string, 0, len(w.Nodes))
+	for id := range w.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		node := w.Nodes[id]
+		path := "nodes." + id
+		if node.ID != "" && node.ID != id {
+			errs = append(errs, fieldErrorf(path+".id", "%q does not match the node's key", node.ID))
+		}
+		if node.Type == "" {
+			errs = append(errs, fieldErrorf(path+".type", "is required"))
+			continue
+		}
+		if _, err := ParseVersionConstraint(node.Version); err != nil {
+			errs = append(errs, fieldErrorf(path+".version", "%v", err))
+		}
+		nodeSchema, err := schema(node.Type)
+		if err != nil {
+			errs = append(errs, fieldErrorf(path+".type", "%v", err))
+			continue
+		}
+		errs = append(errs, nodeSchema.Check(path+".config", node.Config))
+	}
+
+	for i, edge := range w.Edges {
+		path := fmt.Sprintf("edges[%d]", i)
+		if _, ok := w.Nodes[edge.Source]; !ok {
+			errs = append(errs, fieldErrorf(path+".source", "unknown node %q", edge.Source))
+		}
+		if _, ok := w.Nodes[edge.Target]; !ok {
+			errs = append(errs, fieldErrorf(path+".target", "unknown node %q", edge.Target))
+		}
+	}
+
+	settings := []struct {
+		field string
+		check func() error
+	}{
+		{"timeout", func() error { _, err := w.Timeout(); return err }},
+		{"edge_matching", func() error { _, err := w.EdgeMatching(); return err }},
+		{"timezone", func() error { _, err := w.Timezone(); return err }},
+		{"node_timeout", func() error { _, err := w.NodeTimeout(); return err }},
+		{"save_success_data", func() error { _, err := w.SaveSuccessData(); return err }},
+	}
+	for _, setting := range settings {
+		if err := setting.check(); err != nil {
+			// The setting's errors name it already
+			message := strings.TrimPrefix(err.Error(), "invalid settings."+setting.field+" ")
+			errs = append(errs, fieldErrorf("settings."+setting.field, "invalid %s", message))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,152 @@
+package engine_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// fields returns the sorted fields of the *engine.FieldError problems in err.
+func fields(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var fields []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var fe *engine.FieldError
+		if !errors.As(e, &fe) {
+			t.Fatalf("expected a field error, got %v", e)
+		}
+		fields = append(fields, fe.Field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func TestConfigSchemaCheck(t *testing.T) {
+	schema := engine.ConfigSchema{
+		"operation": {Type: "string", Required: true, Enum: []string{"parse", "serialize"}},
+		"count":     {Type: "integer"},
+		"header":    {Type: "boolean"},
+		"items":     {Type: "array"},
+	}
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		want   []string
+	}{
+		{"Valid", map[string]interface{}{"operation": "parse", "count": float64(3), "header": true, "items": []interface{}{}}, nil},
+		{"Missing", map[string]interface{}{"operation": ""}, []string{"config.operation"}},
+		{"WrongTypes", map[string]interface{}{"operation": "parse", "count": 1.5, "header": "yes", "items": "a,b"}, []string{"config.count", "config.header", "config.items"}},
+		{"NotInEnum", map[string]interface{}{"operation": "zip"}, []string{"config.operation"}},
+		{"Templates", map[string]interface{}{"operation": "{{ $node.a.data.op }}", "items": "{{ $node.a.data.rows }}"}, nil},
+		{"UnknownKeys", map[string]interface{}{"operation": "parse", "continue_on_fail": true}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fields(t, schema.Check("config", tt.config))
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected problems with %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected problems with %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateDefinition(t *testing.T) {
+	t.Run("Draft", func(t *testing.T) {
+		// Workflows being built may be saved without nodes or a start node
+		wf := engine.Workflow{Nodes: map[string]engine.Node{}}
+		if err := wf.ValidateDefinition(engine.BuiltinConfigSchema); err != nil {
+			t.Errorf("expected an empty workflow to be valid, got %v", err)
+		}
+	})
+
+	t.Run("ReportsFields", func(t *testing.T) {
+		wf := engine.Workflow{
+			Nodes: map[string]engine.Node{
+				"fetch": {Type: engine.NodeTypeHTTPRequest, Config: map[string]interface{}{"url": "https://example.com"}},
+				"csv":   {Type: engine.NodeTypeCSV, Config: map[string]interface{}{"operation": "pivot"}},
+				"wait":  {Type: engine.NodeTypeDelay, Version: "soonish", Config: map[string]interface{}{"duration": float64(5)}},
+				"blank": {},
+			},
+			Edges:    []engine.Edge{{Source: "fetch", Target: "missing"}},
+			Settings: &engine.WorkflowSettings{EdgeMatching: "fuzzy"},
+		}
+		want := []string{"edges[0].target", "nodes.blank.type", "nodes.csv.config.operation", "nodes.wait.version", "settings.edge_matching"}
+		got := fields(t, wf.ValidateDefinition(engine.BuiltinConfigSchema))
+		if len(got) != len(want) {
+			t.Fatalf("expected problems with %v, got %v", want, got)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("expected problems with %v, got %v", want, got)
+			}
+		}
+	})
+
+	t.Run("BlockManifest", func(t *testing.T) {
+		dir := t.TempDir()
+		os.MkdirAll(filepath.Join(dir, "acme"), 0o755)
+		os.WriteFile(filepath.Join(dir, "acme", "notify.ts"), []byte("export {}"), 0o644)
+		os.WriteFile(filepath.Join(dir, "acme", "notify.manifest.json"),
+			[]byte(`{"id":"acme/notify","version":"1.0.0","config":{"channel":{"type":"string","required":true}}}`), 0o644)
+		runner := engine.NewBunRunner(dir)
+		if err := engine.BlockRegistryFor(dir).Reload(); err != nil {
+			t.Fatalf("failed to load blocks: %v", err)
+		}
+
+		wf := engine.Workflow{Nodes: map[string]engine.Node{"n": {Type: "acme/notify", Config: map[string]interface{}{}}}}
+		if got := fields(t, wf.ValidateDefinition(runner.ConfigSchema)); len(got) != 1 || got[0] != "nodes.n.config.channel" {
+			t.Errorf("expected the manifest's required channel to be reported, got %v", got)
+		}
+	})
+}
+
+func TestValidateTriggerConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		triggerType engine.TriggerType
+		config      map[string]interface{}
+		want        []string
+	}{
+		{"Cron", engine.TriggerTypeCron, map[string]interface{}{"schedule": "@daily", "timezone": "Europe/Berlin"}, nil},
+		{"CronSchedule", engine.TriggerTypeCron, map[string]interface{}{"schedule": "every morning"}, []string{"config.schedule"}},
+		{"CronTimezone", engine.TriggerTypeCron, map[string]interface{}{"schedule": "@daily", "timezone": "Mars/Olympus"}, []string{"config.timezone"}},
+		{"IntervalMissing", engine.TriggerTypeInterval, map[string]interface{}{}, []string{"config.interval"}},
+		{"IntervalTooShort", engine.TriggerTypeInterval, map[string]interface{}{"interval": 0.5}, []string{"config.interval"}},
+		{"Webhook", engine.TriggerTypeWebhook, nil, nil},
+		{"WebhookTypes", engine.TriggerTypeWebhook, map[string]interface{}{"require_token": "yes", "sample_every": 2.5}, []string{"config.require_token", "config.sample_every"}},
+		{"Bindings", engine.TriggerTypeWebhook, map[string]interface{}{"bindings": []interface{}{map[string]interface{}{"filter": "true"}}}, []string{"config.bindings"}},
+		{"UnknownType", "email", nil, []string{"type"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := engine.ValidateTriggerConfig(tt.triggerType, tt.config)
+			var got []string
+			var fe *engine.FieldError
+			if _, joined := err.(interface{ Unwrap() []error }); joined {
+				got = fields(t, err)
+			} else if errors.As(err, &fe) {
+				got = []string{fe.Field}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected problems with %v, got %v (%v)", tt.want, got, err)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected problems with %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// Coverage reports which parts of a workflow its test runs exercised: the nodes
+// they reached, the output ports those nodes took and the edges they followed,
+// so that authors see the branches no test goes down. Test runs are the
+// executions marked as tests: test webhook fires, snapshot tests and runs in
+// mock mode. It is computed from their node timelines, which are kept even when
+// the workflow's settings drop the data of successful runs.
+type Coverage struct {
+	WorkflowID     string         `json:"workflow_id"`
+	Runs           int            `json:"runs"` // Test runs counted
+	NodesReached   int            `json:"nodes_reached"`
+	EdgesTraversed int            `json:"edges_traversed"`
+	Nodes          []NodeCoverage `json:"nodes"` // Sorted by node ID
+	Edges          []EdgeCoverage `json:"edges"` // In workflow order
+}
+
+// NodeCoverage counts the test runs that reached a node and the ports it took.
+type NodeCoverage struct {
+	NodeID string   `json:"node_id"`
+	Type   NodeType `json:"type"`
+	Runs   int      `json:"runs"`   // Runs that reached the node
+	Failed int      `json:"failed"` // Runs in which it failed
+	// Ports counts the runs that took each output port of the node: those it
+	// declares or has edges leaving from, 0 when no run took them, and any
+	// other it took.
+	Ports map[string]int `json:"ports,omitempty"`
+}
+
+// EdgeCoverage counts the test runs that followed an edge.
+type EdgeCoverage struct {
+	ID           string `json:"id"`
+	Source       string `json:"source"`
+	Target       string `json:"target"`
+	SourceHandle string `json:"source_handle,omitempty"`
+	Runs         int    `json:"runs"`
+}
+
+// Unreached returns the IDs of the nodes no test run reached.
+func (c *Coverage) Unreached() []string {
+	var ids []string
+	for _, n := range c.Nodes {
+		if n.Runs == 0 {
+			ids = append(ids, n.NodeID)
+		}
+	}
+	return ids
+}
+
+// WorkflowCoverage reports the coverage of workflow by its latest test runs, at
+// most limit of them. The runs are read against the current definition: the
+// edges they followed are those its edges match, and nodes removed since are
+// left out.
+func WorkflowCoverage(ctx context.Context, store storage.Storage, blocksDir string, workflow Workflow, limit int) (*Coverage, error) {
+	execs, err := store.FindExecutions(ctx, storage.ExecutionFilter{WorkflowID: workflow.ID, Test: true}, limit)
+	if err != nil {
+		return nil, err
+	}
+	runner := NewBunRunner(blocksDir)
+	mode, _ := workflow.EdgeMatching()
+
+	cov := &Coverage{WorkflowID: workflow.ID, Runs: len(execs), Nodes: make([]NodeCoverage, 0, len(workflow.Nodes))}
+	index := make(map[string]int, len(workflow.Nodes))
+	strict := make(map[string]bool, len(workflow.Nodes))
+	ids := make([]string, 0, len(workflow.Nodes))
+	for id := range workflow.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		node := workflow.Nodes[id]
+		ports, _ := runner.nodePorts(&node)
+		strict[id] = mode == EdgeMatchingStrict && ports != nil
+		counts := make(map[string]int, len(ports))
+		for _, port := range ports {
+			counts[port] = 0
+		}
+		index[id] = len(cov.Nodes)
+		cov.Nodes = append(cov.Nodes, NodeCoverage{NodeID: id, Type: node.Type, Ports: counts})
+	}
+	cov.Edges = make([]EdgeCoverage, len(workflow.Edges))
+	for i, edge := range workflow.Edges {
+		cov.Edges[i] = EdgeCoverage{ID: edge.ID, Source: edge.Source, Target: edge.Target, SourceHandle: edge.SourceHandle}
+		n, ok := index[edge.Source]
+		if !ok || edge.SourceHandle == "" {
+			continue
+		}
+		if _, ok := cov.Nodes[n].Ports[edge.SourceHandle]; !ok {
+			cov.Nodes[n].Ports[edge.SourceHandle] = 0
+		}
+	}
+
+	for _, exec := range execs {
+		nodeExecs, err := store.ListNodeExecutions(ctx, exec.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list node executions of %s: %w", exec.ID, err)
+		}
+		for _, ne := range nodeExecs {
+			i, ok := index[ne.NodeID]
+			if !ok || ne.Status == storage.NodeStatusSkipped {
+				continue
+			}
+			n := &cov.Nodes[i]
+			n.Runs++
+			if ne.Status == storage.NodeStatusFailed {
+				n.Failed++
+			}
+			// A failed node has a port only if it continued on ErrorPort
+			if ne.Port == "" {
+				continue
+			}
+			n.Ports[ne.Port]++
+			for j, edge := range workflow.Edges {
+				if edge.Source == ne.NodeID && edge.follows(ne.Port, strict[ne.NodeID]) {
+					cov.Edges[j].Runs++
+				}
+			}
+		}
+	}
+
+	for _, n := range cov.Nodes {
+		if n.Runs > 0 {
+			cov.NodesReached++
+		}
+	}
+	for _, e := range cov.Edges {
+		if e.Runs > 0 {
+			cov.EdgesTraversed++
+		}
+	}
+	return cov, nil
+}
@@ -0,0 +1,55 @@
+package engine_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+func TestWorkflowCoverage(t *testing.T) {
+	ctx := context.Background()
+	store := createTestStorage(t)
+	w := dedupeWorkflow("wf-coverage", map[string]interface{}{"key": "{{ $trigger.id }}"})
+	run := func(test bool, id string) {
+		t.Helper()
+		ectx := engine.NewExecutionContext(w.ID)
+		ectx.Test = test
+		ectx.TriggerData = map[string]interface{}{"id": id}
+		if err := engine.NewWorkflowRunner(ectx, t.TempDir(), store, nil).Run(ctx, w); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+	coverage := func() *engine.Coverage {
+		t.Helper()
+		cov, err := engine.WorkflowCoverage(ctx, store, t.TempDir(), w, 20)
+		if err != nil {
+			t.Fatalf("failed to compute coverage: %v", err)
+		}
+		return cov
+	}
+
+	// Only test runs count: the production run sending "a" down duplicate doesn't
+	run(true, "a")
+	run(false, "a")
+	cov := coverage()
+	if cov.Runs != 1 || cov.NodesReached != 2 || cov.EdgesTraversed != 1 {
+		t.Fatalf("expected one test run down the new branch, got %+v", cov)
+	}
+	if got := cov.Unreached(); !slices.Equal(got, []string{"duplicate"}) {
+		t.Errorf("expected the duplicate branch to be unreached, got %v", got)
+	}
+	dedupe := cov.Nodes[0]
+	if dedupe.NodeID != "dedupe" || dedupe.Ports["new"] != 1 || dedupe.Ports["duplicate"] != 0 || len(dedupe.Ports) != 2 {
+		t.Errorf("expected the untaken duplicate port to be listed, got %+v", dedupe)
+	}
+	if cov.Edges[0].Runs != 1 || cov.Edges[1].Runs != 0 {
+		t.Errorf("unexpected edge coverage: %+v", cov.Edges)
+	}
+
+	run(true, "a")
+	if cov := coverage(); cov.Runs != 2 || cov.NodesReached != 3 || cov.EdgesTraversed != 2 || len(cov.Unreached()) != 0 {
+		t.Errorf("expected both branches to be covered, got %+v", cov)
+	}
+}
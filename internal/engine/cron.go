@@ -0,0 +1,187 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// CronSpec returns the schedule of a cron trigger config, {"schedule": "0 9 * * 1-5",
+// "timezone": "Europe/Berlin"}, as a spec for the cron parser. Without a
+// timezone the schedule runs in the server's local time.
+func CronSpec(config map[string]interface{}) (string, error) {
+	schedule, ok := config["schedule"].(string)
+	if !ok || schedule == "" {
+		return "", fmt.Errorf("cron trigger requires 'schedule' field")
+	}
+	tz, _ := config["timezone"].(string)
+	if tz == "" {
+		return schedule, nil
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return "", fmt.Errorf("unknown timezone %q", tz)
+	}
+	return "CRON_TZ=" + tz + " " + schedule, nil
+}
+
+// ValidateCronConfig checks that a cron trigger config has a schedule the
+// scheduler accepts, so that mistakes surface when the trigger is saved rather
+// than in the server log when it is registered.
+func ValidateCronConfig(config map[string]interface{}) error {
+	spec, err := CronSpec(config)
+	if err != nil {
+		return err
+	}
+	_, err = CronOccurrences(spec, time.Now(), 0)
+	return err
+}
+
+// CronOccurrences returns the next count times after from that a cron spec fires.
+func CronOccurrences(spec string, from time.Time, count int) ([]time.Time, error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron schedule %q: %w", spec, err)
+	}
+	var runs []time.Time
+	for next := schedule.Next(from); !next.IsZero() && len(runs) < count; next = schedule.Next(next) {
+		runs = append(runs, next)
+	}
+	return runs, nil
+}
+
+// cronDescriptors are the predefined schedules and their five-field form.
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames   = []string{"", "January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+	weekdayNames = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+)
+
+// DescribeCron returns a valid schedule in its normalized form, with the
+// predefined schedules (@daily, ...) expanded to five fields and whitespace
+// collapsed, and an English description of when it fires, e.g.
+// "At 09:00 on Monday through Friday".
+func DescribeCron(schedule string) (normalized, description string) {
+	normalized = strings.Join(strings.Fields(schedule), " ")
+	if expanded, ok := cronDescriptors[strings.ToLower(normalized)]; ok {
+		normalized = expanded
+	}
+	if every, ok := strings.CutPrefix(normalized, "@every "); ok {
+		return normalized, "Every " + every
+	}
+	fields := strings.Fields(normalized)
+	if len(fields) != 5 {
+		return normalized, ""
+	}
+	minute, hour, dom, month, dow := fields[0], fields[1], fields[2], fields[3], fields[4]
+
+	parts := []string{describeCronTime(minute, hour)}
+	if dom != "*" && dom != "?" {
+		parts = append(parts, "on day "+describeCronField(dom, nil)+" of the month")
+	}
+	if dow != "*" && dow != "?" {
+		if dom != "*" && dom != "?" {
+			parts = append(parts, "and")
+		}
+		parts = append(parts, "on "+describeCronField(dow, weekdayNames))
+	}
+	if month != "*" && month != "?" {
+		parts = append(parts, "in "+describeCronField(month, monthNames))
+	}
+	description = strings.Join(parts, " ")
+	return normalized, strings.ToUpper(description[:1]) + description[1:]
+}
+
+// describeCronTime describes the minute and hour fields.
+func describeCronTime(minute, hour string) string {
+	_, minuteErr := strconv.Atoi(minute)
+	switch {
+	case minute == "*" && hour == "*":
+		return "every minute"
+	case strings.HasPrefix(minute, "*/") && hour == "*":
+		return "every " + strings.TrimPrefix(minute, "*/") + " minutes"
+	case minuteErr == nil && hour == "*":
+		return "at minute " + minute + " of every hour"
+	case minuteErr == nil && strings.HasPrefix(hour, "*/"):
+		return "at minute " + minute + " of every " + strings.TrimPrefix(hour, "*/") + " hours"
+	case minuteErr == nil:
+		// 0 9,17 → at 09:00 and 17:00, when every hour is a plain number
+		var times []string
+		for _, h := range strings.Split(hour, ",") {
+			n, err := strconv.Atoi(h)
+			if err != nil {
+				times = nil
+				break
+			}
+			m, _ := strconv.Atoi(minute)
+			times = append(times, fmt.Sprintf("%02d:%02d", n, m))
+		}
+		if times != nil {
+			return "at " + joinEnglish(times)
+		}
+	}
+	return "at minute " + describeCronField(minute, nil) + " past hour " + describeCronField(hour, nil)
+}
+
+// describeCronField describes one field: lists, ranges and steps, with values
+// spelled out by names when given.
+func describeCronField(field string, names []string) string {
+	name := func(v string) string {
+		if n, err := strconv.Atoi(v); err == nil && names != nil && n >= 0 && n < len(names) && names[n] != "" {
+			return names[n]
+		}
+		if names != nil {
+			// Names as written in the spec: mon, JAN, ...
+			for _, full := range names {
+				if len(v) == 3 && strings.EqualFold(full[:min(3, len(full))], v) {
+					return full
+				}
+			}
+		}
+		return v
+	}
+
+	var parts []string
+	for _, part := range strings.Split(field, ",") {
+		rng, step, hasStep := strings.Cut(part, "/")
+		var desc string
+		if lo, hi, ok := strings.Cut(rng, "-"); ok {
+			desc = name(lo) + " through " + name(hi)
+		} else if rng == "*" {
+			desc = ""
+		} else {
+			desc = name(rng)
+		}
+		if hasStep {
+			if desc == "" {
+				desc = "every " + step
+			} else {
+				desc = "every " + step + " from " + desc
+			}
+		}
+		if desc == "" {
+			desc = "every"
+		}
+		parts = append(parts, desc)
+	}
+	return joinEnglish(parts)
+}
+
+// joinEnglish joins items as "a", "a and b" or "a, b and c".
+func joinEnglish(items []string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
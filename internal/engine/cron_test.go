@@ -0,0 +1,32 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+func TestDescribeCron(t *testing.T) {
+	tests := []struct {
+		schedule    string
+		normalized  string
+		description string
+	}{
+		{"* * * * *", "* * * * *", "Every minute"},
+		{"*/15 * * * *", "*/15 * * * *", "Every 15 minutes"},
+		{"5 * * * *", "5 * * * *", "At minute 5 of every hour"},
+		{"0 9,17 * * *", "0 9,17 * * *", "At 09:00 and 17:00"},
+		{"30 8 * * MON-FRI", "30 8 * * MON-FRI", "At 08:30 on Monday through Friday"},
+		{"0 0 1,15 * *", "0 0 1,15 * *", "At 00:00 on day 1 and 15 of the month"},
+		{"0 12 * 1,7 0", "0 12 * 1,7 0", "At 12:00 on Sunday in January and July"},
+		{"0 */2 * * *", "0 */2 * * *", "At minute 0 of every 2 hours"},
+		{" @Weekly ", "0 0 * * 0", "At 00:00 on Sunday"},
+		{"@every 1h30m", "@every 1h30m", "Every 1h30m"},
+	}
+	for _, tt := range tests {
+		normalized, description := engine.DescribeCron(tt.schedule)
+		if normalized != tt.normalized || description != tt.description {
+			t.Errorf("DescribeCron(%q) = %q, %q; want %q, %q", tt.schedule, normalized, description, tt.normalized, tt.description)
+		}
+	}
+}
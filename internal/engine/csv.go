@@ -0,0 +1,181 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// csvConfig is the config of a std/csv node:
+//
+//	{"operation": "parse", "content": "{{ $node.download.data.content }}"}
+//	{"operation": "serialize", "items": "{{ $node.query.data.rows }}", "columns": ["id", "total"]}
+type csvConfig struct {
+	Operation string `json:"operation"`
+	// Content is the CSV text to parse.
+	Content string `json:"content"`
+	// Items are the rows to serialize: objects, or arrays of values.
+	Items []interface{} `json:"items"`
+	// Header, true by default, means the first row holds column names: parsing
+	// yields objects keyed by them and serializing writes them first.
+	Header *bool `json:"header"`
+	// Columns sets the column order when serializing objects (default: sorted keys).
+	Columns []string `json:"columns"`
+	// Delimiter is a single character, "," by default.
+	Delimiter string `json:"delimiter"`
+}
+
+// executeCSV runs a std/csv node. Parsing outputs {"items": [...], "count": n},
+// one item per row; serializing outputs {"content": "...", "count": n}.
+func executeCSV(input any) (any, error) {
+	payload, _ := input.(map[string]interface{})
+	var cfg csvConfig
+	raw, _ := json.Marshal(payload["config"])
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("csv: invalid config: %w", err)
+	}
+
+	comma := ','
+	if cfg.Delimiter != "" {
+		r, size := utf8.DecodeRuneInString(cfg.Delimiter)
+		if size != len(cfg.Delimiter) {
+			return nil, fmt.Errorf("csv: config 'delimiter' must be a single character")
+		}
+		comma = r
+	}
+	header := cfg.Header == nil || *cfg.Header
+
+	var data map[string]interface{}
+	var err error
+	switch cfg.Operation {
+	case "parse":
+		data, err = parseCSV(cfg.Content, comma, header)
+	case "serialize":
+		data, err = serializeCSV(cfg.Items, cfg.Columns, comma, header)
+	default:
+		return nil, fmt.Errorf("csv: config 'operation' must be 'parse' or 'serialize'")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("csv: %w", err)
+	}
+	return map[string]interface{}{"data": data, "port": "default"}, nil
+}
+
+func parseCSV(content string, comma rune, header bool) (map[string]interface{}, error) {
+	r := csv.NewReader(strings.NewReader(content))
+	r.Comma = comma
+	r.FieldsPerRecord = -1 // tolerate ragged rows; missing cells become empty
+	r.TrimLeadingSpace = true
+
+	var columns []string
+	items := []interface{}{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !header {
+			row := make([]interface{}, len(record))
+			for i, v := range record {
+				row[i] = v
+			}
+			items = append(items, row)
+			continue
+		}
+		if columns == nil {
+			columns = record
+			continue
+		}
+		item := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			value := ""
+			if i < len(record) {
+				value = record[i]
+			}
+			item[col] = value
+		}
+		items = append(items, item)
+	}
+	return map[string]interface{}{"items": items, "count": len(items)}, nil
+}
+
+func serializeCSV(items []interface{}, columns []string, comma rune, header bool) (map[string]interface{}, error) {
+	if len(columns) == 0 {
+		columns = objectColumns(items)
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = comma
+	if header && len(columns) > 0 {
+		w.Write(columns)
+	}
+	for i, item := range items {
+		var record []string
+		switch row := item.(type) {
+		case map[string]interface{}:
+			record = make([]string, len(columns))
+			for j, col := range columns {
+				record[j] = csvCell(row[col])
+			}
+		case []interface{}:
+			record = make([]string, len(row))
+			for j, v := range row {
+				record[j] = csvCell(v)
+			}
+		default:
+			return nil, fmt.Errorf("item %d must be an object or an array, got %T", i, item)
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"content": buf.String(), "count": len(items)}, nil
+}
+
+// objectColumns returns the sorted union of the keys of all object items.
+func objectColumns(items []interface{}) []string {
+	seen := map[string]bool{}
+	var columns []string
+	for _, item := range items {
+		if row, ok := item.(map[string]interface{}); ok {
+			for k := range row {
+				if !seen[k] {
+					seen[k] = true
+					columns = append(columns, k)
+				}
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// csvCell formats a value for a CSV cell; nested values are written as JSON.
+func csvCell(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		// Avoid exponent notation for large numbers (1e+06)
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(val)
+		return string(b)
+	default:
+		return fmt.Sprint(val)
+	}
+}
@@ -0,0 +1,74 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+func runCSV(t *testing.T, config map[string]interface{}) (map[string]interface{}, error) {
+	t.Helper()
+	runner := engine.NewBunRunner(t.TempDir())
+	runner.RuntimePath = "/nonexistent/bun" // any process start would fail
+	node := &engine.Node{ID: "csv", Type: engine.NodeTypeCSV}
+	raw, err := runner.ExecuteNode(context.Background(), node, map[string]interface{}{"config": config})
+	if err != nil {
+		return nil, err
+	}
+	return raw.(map[string]interface{})["data"].(map[string]interface{}), nil
+}
+
+// TestBunRunner_ExecuteNode_CSV verifies that std/csv parses CSV into items and
+// serializes items back, with and without a header row.
+func TestBunRunner_ExecuteNode_CSV(t *testing.T) {
+	data, err := runCSV(t, map[string]interface{}{
+		"operation": "parse",
+		"content":   "id,name,total\n1,\"Smith, Ada\",9.99\n2,Bob\n",
+	})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	items, _ := json.Marshal(data["items"])
+	want := `[{"id":"1","name":"Smith, Ada","total":"9.99"},{"id":"2","name":"Bob","total":""}]`
+	if string(items) != want {
+		t.Errorf("expected items %s, got %s", want, items)
+	}
+
+	data, err = runCSV(t, map[string]interface{}{
+		"operation": "parse",
+		"content":   "a;b\nc;d\n",
+		"delimiter": ";",
+		"header":    false,
+	})
+	if err != nil {
+		t.Fatalf("parse without header failed: %v", err)
+	}
+	if items, _ := json.Marshal(data["items"]); string(items) != `[["a","b"],["c","d"]]` {
+		t.Errorf("expected rows as arrays, got %s", items)
+	}
+
+	data, err = runCSV(t, map[string]interface{}{
+		"operation": "serialize",
+		"items": []interface{}{
+			map[string]interface{}{"id": 1.0, "name": "Smith, Ada", "tags": []interface{}{"vip"}},
+			map[string]interface{}{"id": 1000000.0, "name": "Bob"},
+		},
+		"columns": []interface{}{"id", "name", "tags"},
+	})
+	if err != nil {
+		t.Fatalf("serialize failed: %v", err)
+	}
+	wantCSV := "id,name,tags\n1,\"Smith, Ada\",\"[\"\"vip\"\"]\"\n1000000,Bob,\n"
+	if data["content"] != wantCSV || data["count"] != 2 {
+		t.Errorf("expected %q, got %q (count %v)", wantCSV, data["content"], data["count"])
+	}
+
+	if _, err := runCSV(t, map[string]interface{}{"operation": "serialize", "items": []interface{}{"scalar"}}); err == nil {
+		t.Error("expected a scalar item to fail")
+	}
+	if _, err := runCSV(t, map[string]interface{}{"operation": "parse", "content": "a,b", "delimiter": "::"}); err == nil {
+		t.Error("expected a multi-character delimiter to fail")
+	}
+}
@@ -0,0 +1,224 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// datetimeLayouts are the named formats std/datetime accepts besides Go layouts
+// ("2006-01-02 15:04").
+var datetimeLayouts = map[string]string{
+	"rfc3339":  time.RFC3339,
+	"rfc1123":  time.RFC1123,
+	"date":     time.DateOnly,
+	"datetime": time.DateTime,
+	"time":     time.TimeOnly,
+}
+
+// datetimeConfig is the config of a std/datetime node:
+//
+//	{"operation": "add", "value": "{{ $trigger.createdAt }}", "duration": {"days": 3}}
+//	{"operation": "compare", "value": "now", "other": "{{ $node.order.data.deadline }}"}
+type datetimeConfig struct {
+	Operation string `json:"operation"`
+	// Value is the date to work on: a string in InputFormat (RFC 3339 by default),
+	// a Unix timestamp in seconds, or "now" (also the default).
+	Value interface{} `json:"value"`
+	// Other is the second date for diff and compare, read like Value.
+	Other       interface{} `json:"other"`
+	InputFormat string      `json:"inputFormat"`
+	// Format of the "formatted" output: a named format, "unix", "unixms" or a Go layout.
+	Format string `json:"format"`
+	// Timezone (IANA name) to parse zone-less inputs in and to express results in;
+	// the workflow's settings.timezone or else UTC by default.
+	Timezone string `json:"timezone"`
+	// Duration for add and subtract: a Go duration ("90m", "1h30m", with "d" for
+	// days) or calendar units {"years", "months", "days", "hours", "minutes", "seconds"}.
+	Duration interface{} `json:"duration"`
+	// Unit of diff: ms, s (default), m, h or d.
+	Unit string `json:"unit"`
+}
+
+// executeDatetime runs a std/datetime node. Every operation outputs the resulting
+// date as {"iso", "formatted", "unix", "unixMs", "timezone", "weekday"}; diff adds
+// {"diff", "unit"} and compare routes to the "before", "after" or "equal" port
+// depending on how value compares to other.
+func executeDatetime(input any) (any, error) {
+	payload, _ := input.(map[string]interface{})
+	var cfg datetimeConfig
+	raw, _ := json.Marshal(payload["config"])
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("datetime: invalid config: %w", err)
+	}
+
+	// A node without a timezone works in its workflow's settings.timezone
+	if cfg.Timezone == "" {
+		cfg.Timezone, _ = payload["timezone"].(string)
+	}
+	loc := time.UTC
+	if cfg.Timezone != "" {
+		l, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("datetime: unknown timezone %q", cfg.Timezone)
+		}
+		loc = l
+	}
+	now := time.Now()
+	t, err := parseDatetime(cfg.Value, cfg.InputFormat, loc, now)
+	if err != nil {
+		return nil, fmt.Errorf("datetime: config 'value': %w", err)
+	}
+
+	port := "default"
+	extra := map[string]interface{}{}
+	switch cfg.Operation {
+	case "parse", "format", "convert":
+		// Parsing and expressing the date in the timezone and format is all there is to do
+	case "add", "subtract":
+		sign := 1
+		if cfg.Operation == "subtract" {
+			sign = -1
+		}
+		if t, err = addDuration(t, cfg.Duration, sign); err != nil {
+			return nil, fmt.Errorf("datetime: config 'duration': %w", err)
+		}
+	case "diff", "compare":
+		other, err := parseDatetime(cfg.Other, cfg.InputFormat, loc, now)
+		if err != nil {
+			return nil, fmt.Errorf("datetime: config 'other': %w", err)
+		}
+		if cfg.Operation == "compare" {
+			switch {
+			case t.Before(other):
+				port = "before"
+			case t.After(other):
+				port = "after"
+			default:
+				port = "equal"
+			}
+			extra["result"] = port
+			break
+		}
+		unit := cfg.Unit
+		if unit == "" {
+			unit = "s"
+		}
+		scale, ok := map[string]time.Duration{"ms": time.Millisecond, "s": time.Second, "m": time.Minute, "h": time.Hour, "d": 24 * time.Hour}[unit]
+		if !ok {
+			return nil, fmt.Errorf("datetime: config 'unit' must be 'ms', 's', 'm', 'h' or 'd'")
+		}
+		// value - other, so a later value gives a positive diff
+		extra["diff"] = float64(t.Sub(other)) / float64(scale)
+		extra["unit"] = unit
+	default:
+		return nil, fmt.Errorf("datetime: config 'operation' must be one of parse, format, convert, add, subtract, diff or compare")
+	}
+
+	t = t.In(loc)
+	data := map[string]interface{}{
+		"iso":       t.Format(time.RFC3339Nano),
+		"formatted": formatDatetime(t, cfg.Format),
+		"unix":      t.Unix(),
+		"unixMs":    t.UnixMilli(),
+		"timezone":  loc.String(),
+		"weekday":   t.Weekday().String(),
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	return map[string]interface{}{"data": data, "port": port}, nil
+}
+
+// parseDatetime reads a date given as a string, a Unix timestamp or "now".
+func parseDatetime(v interface{}, format string, loc *time.Location, now time.Time) (time.Time, error) {
+	switch val := v.(type) {
+	case nil:
+		return now, nil
+	case float64:
+		if format == "unixms" {
+			return time.UnixMilli(int64(val)), nil
+		}
+		sec, frac := math.Modf(val)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	case string:
+		val = strings.TrimSpace(val)
+		if val == "" || val == "now" {
+			return now, nil
+		}
+		switch format {
+		case "unix", "unixms":
+			n, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("%q is not a Unix timestamp", val)
+			}
+			return parseDatetime(n, format, loc, now)
+		case "":
+			format = "rfc3339"
+		}
+		layout := format
+		if named, ok := datetimeLayouts[format]; ok {
+			layout = named
+		}
+		t, err := time.ParseInLocation(layout, val, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%q does not match format %q", val, format)
+		}
+		return t, nil
+	default:
+		return time.Time{}, fmt.Errorf("must be a string or a Unix timestamp, got %T", v)
+	}
+}
+
+func formatDatetime(t time.Time, format string) interface{} {
+	switch format {
+	case "":
+		return t.Format(time.RFC3339)
+	case "unix":
+		return t.Unix()
+	case "unixms":
+		return t.UnixMilli()
+	}
+	if named, ok := datetimeLayouts[format]; ok {
+		return t.Format(named)
+	}
+	return t.Format(format)
+}
+
+// addDuration adds sign times the duration to t. Calendar units are applied with
+// AddDate, so adding a month to Jan 31 normalizes like Go does (to Mar 2 or 3).
+func addDuration(t time.Time, duration interface{}, sign int) (time.Time, error) {
+	switch d := duration.(type) {
+	case string:
+		days := 0
+		if i := strings.Index(d, "d"); i > 0 {
+			n, err := strconv.Atoi(d[:i])
+			if err != nil {
+				return t, fmt.Errorf("invalid duration %q", d)
+			}
+			days, d = n, d[i+1:]
+		}
+		var parsed time.Duration
+		if d != "" {
+			var err error
+			if parsed, err = time.ParseDuration(d); err != nil {
+				return t, fmt.Errorf("invalid duration %q", duration)
+			}
+		}
+		return t.AddDate(0, 0, sign*days).Add(time.Duration(sign) * parsed), nil
+	case map[string]interface{}:
+		unit := func(name string) int {
+			n, _ := d[name].(float64)
+			return sign * int(n)
+		}
+		t = t.AddDate(unit("years"), unit("months"), unit("days"))
+		return t.Add(time.Duration(unit("hours"))*time.Hour +
+			time.Duration(unit("minutes"))*time.Minute +
+			time.Duration(unit("seconds"))*time.Second), nil
+	default:
+		return t, fmt.Errorf("must be a duration string or an object of units")
+	}
+}
@@ -0,0 +1,114 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+func runDatetime(t *testing.T, config map[string]interface{}) (map[string]interface{}, string, error) {
+	t.Helper()
+	runner := engine.NewBunRunner(t.TempDir())
+	runner.RuntimePath = "/nonexistent/bun" // any process start would fail
+	node := &engine.Node{ID: "dt", Type: engine.NodeTypeDatetime}
+	raw, err := runner.ExecuteNode(context.Background(), node, map[string]interface{}{"config": config})
+	if err != nil {
+		return nil, "", err
+	}
+	result := raw.(map[string]interface{})
+	return result["data"].(map[string]interface{}), result["port"].(string), nil
+}
+
+// TestBunRunner_ExecuteNode_Datetime verifies that std/datetime parses, formats,
+// converts and shifts dates, and routes comparisons to before/after/equal ports.
+func TestBunRunner_ExecuteNode_Datetime(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		key    string
+		want   interface{}
+		port   string
+	}{
+		{
+			name:   "parse custom layout in timezone",
+			config: map[string]interface{}{"operation": "parse", "value": "2024-03-10 09:30", "inputFormat": "2006-01-02 15:04", "timezone": "Europe/Berlin"},
+			key:    "iso",
+			want:   "2024-03-10T09:30:00+01:00",
+		},
+		{
+			name:   "convert timezone",
+			config: map[string]interface{}{"operation": "convert", "value": "2024-07-01T12:00:00Z", "timezone": "America/New_York", "format": "datetime"},
+			key:    "formatted",
+			want:   "2024-07-01 08:00:00",
+		},
+		{
+			name:   "format unix timestamp",
+			config: map[string]interface{}{"operation": "format", "value": 1704067200.0, "format": "Mon, 02 Jan 2006"},
+			key:    "formatted",
+			want:   "Mon, 01 Jan 2024",
+		},
+		{
+			name:   "add duration string with days",
+			config: map[string]interface{}{"operation": "add", "value": "2024-01-31T00:00:00Z", "duration": "1d12h"},
+			key:    "iso",
+			want:   "2024-02-01T12:00:00Z",
+		},
+		{
+			name:   "subtract calendar units",
+			config: map[string]interface{}{"operation": "subtract", "value": "2024-03-15T10:00:00Z", "duration": map[string]interface{}{"months": 1.0, "hours": 2.0}},
+			key:    "iso",
+			want:   "2024-02-15T08:00:00Z",
+		},
+		{
+			name:   "diff in hours",
+			config: map[string]interface{}{"operation": "diff", "value": "2024-01-02T06:00:00Z", "other": "2024-01-01T00:00:00Z", "unit": "h"},
+			key:    "diff",
+			want:   30.0,
+		},
+		{
+			name:   "compare before",
+			config: map[string]interface{}{"operation": "compare", "value": "2024-01-01T00:00:00Z", "other": "now"},
+			key:    "result",
+			want:   "before",
+			port:   "before",
+		},
+		{
+			name:   "compare equal across zones",
+			config: map[string]interface{}{"operation": "compare", "value": "2024-01-01T01:00:00+01:00", "other": "2024-01-01T00:00:00Z"},
+			key:    "result",
+			want:   "equal",
+			port:   "equal",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, port, err := runDatetime(t, tt.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if data[tt.key] != tt.want {
+				t.Errorf("expected %s %v, got %v", tt.key, tt.want, data[tt.key])
+			}
+			wantPort := tt.port
+			if wantPort == "" {
+				wantPort = "default"
+			}
+			if port != wantPort {
+				t.Errorf("expected port %q, got %q", wantPort, port)
+			}
+		})
+	}
+
+	for name, config := range map[string]map[string]interface{}{
+		"unknown operation": {"operation": "shift"},
+		"unknown timezone":  {"operation": "parse", "timezone": "Mars/Olympus"},
+		"layout mismatch":   {"operation": "parse", "value": "01/02/2024"},
+		"invalid duration":  {"operation": "add", "duration": "soon"},
+		"invalid unit":      {"operation": "diff", "other": "now", "unit": "weeks"},
+	} {
+		if _, _, err := runDatetime(t, config); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// dedupeConfig is the config of a std/dedupe node:
+//
+//	{"key": "{{ $trigger.body.eventId }}", "ttl": 24, "unit": "h"}
+//	{"key": {"customer": "{{ $trigger.customer }}", "day": "{{ $node.today.data.formatted }}"}}
+type dedupeConfig struct {
+	// Key identifies the input: a string, or any value, which is keyed by the
+	// SHA-256 of its JSON (object keys sorted).
+	Key interface{} `json:"key"`
+	// Scope names the seen-set; the workflow's own by default, so workflows only
+	// share one when they name it.
+	Scope string `json:"scope"`
+	// TTL and Unit (ms, s, m, h; s by default) set how long a key is remembered;
+	// without a TTL it is remembered forever.
+	TTL  float64 `json:"ttl"`
+	Unit string  `json:"unit"`
+}
+
+// executeDedupe runs a std/dedupe node: it adds the key to its seen-set and
+// routes to "new" if it was not there (or had expired) and to "duplicate"
+// otherwise, outputting {"key", "scope", "duplicate", "expiresAt"}.
+func executeDedupe(ctx context.Context, store storage.Storage, ectx *ExecutionContext, input map[string]interface{}) (any, error) {
+	var cfg dedupeConfig
+	raw, _ := json.Marshal(input["config"])
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("dedupe: invalid config: %w", err)
+	}
+
+	var key string
+	switch k := cfg.Key.(type) {
+	case nil:
+		return nil, fmt.Errorf("dedupe: config 'key' is required")
+	case string:
+		if k == "" {
+			return nil, fmt.Errorf("dedupe: config 'key' must not be empty")
+		}
+		key = k
+	default:
+		b, _ := json.Marshal(k)
+		sum := sha256.Sum256(b)
+		key = hex.EncodeToString(sum[:])
+	}
+	scope := cfg.Scope
+	if scope == "" {
+		scope = "workflow:" + ectx.WorkflowID
+	}
+
+	now := time.Now()
+	var expiresAt *time.Time
+	if cfg.TTL < 0 {
+		return nil, fmt.Errorf("dedupe: config 'ttl' must be positive")
+	}
+	if cfg.TTL > 0 {
+		if cfg.Unit == "" {
+			cfg.Unit = "s"
+		}
+		scale, ok := delayUnits[cfg.Unit]
+		if !ok {
+			return nil, fmt.Errorf("dedupe: config 'unit' must be 'ms', 's', 'm' or 'h'")
+		}
+		t := now.Add(time.Duration(cfg.TTL * float64(scale)))
+		expiresAt = &t
+	}
+	if store == nil {
+		return nil, fmt.Errorf("dedupe: needs a runner with storage")
+	}
+
+	isNew, err := store.MarkSeen(ctx, scope, key, now, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("dedupe: %w", err)
+	}
+
+	data := map[string]interface{}{"key": key, "scope": scope, "duplicate": !isNew, "expiresAt": nil}
+	if expiresAt != nil {
+		data["expiresAt"] = expiresAt.UTC().Format(time.RFC3339Nano)
+	}
+	port := "new"
+	if !isNew {
+		port = "duplicate"
+	}
+	return map[string]interface{}{"data": data, "port": port}, nil
+}
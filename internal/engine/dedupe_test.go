@@ -0,0 +1,83 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// dedupeWorkflow records in $vars.port which port the dedupe node routed to.
+func dedupeWorkflow(id string, config map[string]interface{}) engine.Workflow {
+	return engine.Workflow{
+		ID:   id,
+		Name: "Dedupe",
+		Nodes: map[string]engine.Node{
+			"dedupe":    {ID: "dedupe", Type: engine.NodeTypeDedupe, Config: config},
+			"new":       {ID: "new", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "port", "value": "new"}},
+			"duplicate": {ID: "duplicate", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "port", "value": "duplicate"}},
+		},
+		Edges: []engine.Edge{
+			{ID: "e1", Source: "dedupe", Target: "new", SourceHandle: "new"},
+			{ID: "e2", Source: "dedupe", Target: "duplicate", SourceHandle: "duplicate"},
+		},
+	}
+}
+
+// TestWorkflowRunner_Dedupe verifies that std/dedupe routes a key it has seen
+// to "duplicate", per workflow unless a scope is shared, and forgets keys after
+// their TTL.
+func TestWorkflowRunner_Dedupe(t *testing.T) {
+	store := createTestStorage(t)
+	run := func(workflowID string, trigger, config map[string]interface{}) interface{} {
+		t.Helper()
+		ectx := engine.NewExecutionContext(workflowID)
+		ectx.TriggerData = trigger
+		runner := engine.NewWorkflowRunner(ectx, t.TempDir(), store, nil)
+		if err := runner.Run(context.Background(), dedupeWorkflow(workflowID, config)); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		return ectx.GetVar("port")
+	}
+	byEvent := map[string]interface{}{"key": "{{ $trigger.eventId }}"}
+
+	for i, tt := range []struct {
+		workflowID, eventID, want string
+	}{
+		{"orders-wf", "evt-1", "new"},
+		{"orders-wf", "evt-1", "duplicate"},
+		{"orders-wf", "evt-2", "new"},
+		{"billing-wf", "evt-1", "new"},
+	} {
+		if got := run(tt.workflowID, map[string]interface{}{"eventId": tt.eventID}, byEvent); got != tt.want {
+			t.Errorf("execution %d (%s %s): expected %s, got %v", i+1, tt.workflowID, tt.eventID, tt.want, got)
+		}
+	}
+
+	// Object keys are hashed, and a shared scope spans workflows
+	composite := func(a, b string) map[string]interface{} {
+		return map[string]interface{}{"scope": "customers", "key": map[string]interface{}{"customer": a, "day": b}}
+	}
+	if got := run("orders-wf", nil, composite("ada", "mon")); got != "new" {
+		t.Errorf("expected composite key to be new, got %v", got)
+	}
+	if got := run("billing-wf", nil, composite("ada", "mon")); got != "duplicate" {
+		t.Errorf("expected composite key to be a duplicate in the shared scope, got %v", got)
+	}
+
+	// Expired keys are new again
+	ttl := map[string]interface{}{"key": "short", "ttl": 1.0, "unit": "ms"}
+	if got := run("ttl-wf", nil, ttl); got != "new" {
+		t.Fatalf("expected key to be new, got %v", got)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if got := run("ttl-wf", nil, ttl); got != "new" {
+		t.Errorf("expected expired key to be new again, got %v", got)
+	}
+
+	runner := engine.NewWorkflowRunner(engine.NewExecutionContext("orders-wf"), t.TempDir(), store, nil)
+	if err := runner.Run(context.Background(), dedupeWorkflow("orders-wf", map[string]interface{}{"ttl": 1.0})); err == nil {
+		t.Error("expected a missing key to fail")
+	}
+}
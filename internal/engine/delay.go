@@ -0,0 +1,267 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+const (
+	// defaultMinDurableDelay is the shortest delay that suspends an execution;
+	// shorter ones are not worth a round-trip through storage and sleep in-process.
+	defaultMinDurableDelay = 5 * time.Second
+	// defaultDelayPollInterval is how often the scheduler looks for due executions.
+	defaultDelayPollInterval = time.Second
+	// delayClaimBatch bounds how many due executions are claimed per poll.
+	delayClaimBatch = 20
+)
+
+// delayDuration reads a std/delay config: {"duration": 90, "unit": "s"}.
+// Units are ms (default), s, m and h.
+func delayDuration(config interface{}) (time.Duration, string, error) {
+	cfg, _ := config.(map[string]interface{})
+	if cfg == nil {
+		return 0, "", fmt.Errorf("delay: missing required config")
+	}
+	duration, ok := cfg["duration"].(float64)
+	if !ok {
+		return 0, "", fmt.Errorf("delay: config 'duration' must be a number")
+	}
+	if duration < 0 {
+		return 0, "", fmt.Errorf("delay: config 'duration' must be non-negative")
+	}
+
+	unit := "ms"
+	if u, ok := cfg["unit"].(string); ok && u != "" {
+		unit = u
+	}
+	scale, ok := delayUnits[unit]
+	if !ok {
+		return 0, "", fmt.Errorf("delay: config 'unit' must be 'ms', 's', 'm' or 'h'")
+	}
+	return time.Duration(duration * float64(scale)), unit, nil
+}
+
+var delayUnits = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+}
+
+// delayResult builds the output of a delay node, matching the Bun block.
+func delayResult(delayed time.Duration, unit string) map[string]interface{} {
+	return map[string]interface{}{
+		"data": map[string]interface{}{
+			"delayed":   delayed.Milliseconds(),
+			"timestamp": time.Now().UTC().Format(time.RFC3339Nano),
+			"unit":      unit,
+		},
+		"port": "default",
+	}
+}
+
+// executeDelay runs a std/delay node in-process: it sleeps without spawning Bun,
+// returning early if ctx is cancelled. Runners with a DelayScheduler suspend long
+// delays instead of reaching this.
+func executeDelay(ctx context.Context, input any) (any, error) {
+	payload, _ := input.(map[string]interface{})
+	d, unit, err := delayDuration(payload["config"])
+	if err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return delayResult(time.Since(started), unit), nil
+}
+
+// suspendedState is what a waiting execution stores to be resumed after its delay.
+// It keeps a snapshot of the definition so edits made while it waits don't change
+// the run, and so runs of unsaved workflows (POST /api/run) can resume too.
+type suspendedState struct {
+	Version     int                    `json:"version"` // executionStateVersion
+	Workflow    Workflow               `json:"workflow"`
+	NodeID      string                 `json:"node_id"` // the delay or enqueue node being waited in
+	NodeType    NodeType               `json:"node_type,omitempty"`
+	Unit        string                 `json:"unit"`
+	StartedAt   time.Time              `json:"started_at"`   // when the execution started
+	SuspendedAt time.Time              `json:"suspended_at"` // when the delay started
+	WakeAt      time.Time              `json:"wake_at"`      // zero when only waiting for Signal
+	Signal      string                 `json:"signal,omitempty"`
+	TriggerData map[string]interface{} `json:"trigger_data"`
+	Results     map[string]interface{} `json:"results"`
+	Variables   map[string]interface{} `json:"variables"`
+	// Static holds $workflowStatic changes not yet saved; they are saved when the run completes
+	Static      map[string]interface{} `json:"static,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	// Pending holds the nodes of other fan-out branches still to run after this one
+	Pending []string `json:"pending,omitempty"`
+
+	// Set on resume when Signal woke the execution rather than WakeAt
+	signaled      bool
+	signalPayload interface{}
+}
+
+// DelayScheduler resumes executions suspended in long delay nodes and enqueue
+// nodes. Runners given a scheduler park such executions in storage with their
+// wake-up time or signal and release their worker; the scheduler polls for due
+// executions, is told about signals through Signal, and continues them on the
+// worker pool. Because the wait is persisted, it survives restarts.
+type DelayScheduler struct {
+	// MinDuration is the shortest delay that suspends the execution.
+	MinDuration time.Duration
+	// PollInterval is how often due executions are looked up.
+	PollInterval time.Duration
+
+	store      storage.Storage
+	blocksDir  string
+	registry   *ExecutionRegistry
+	workerPool *WorkerPool
+	events     *EventBus
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewDelayScheduler creates a scheduler that resumes executions on workerPool.
+func NewDelayScheduler(store storage.Storage, blocksDir string, registry *ExecutionRegistry, workerPool *WorkerPool) *DelayScheduler {
+	return &DelayScheduler{
+		MinDuration:  defaultMinDurableDelay,
+		PollInterval: defaultDelayPollInterval,
+		store:        store,
+		blocksDir:    blocksDir,
+		registry:     registry,
+		workerPool:   workerPool,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// SetEventBus makes resumed executions publish events to bus.
+func (s *DelayScheduler) SetEventBus(bus *EventBus) {
+	s.events = bus
+}
+
+// Start begins polling for due executions in the background.
+func (s *DelayScheduler) Start() {
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.PollInterval)
+		defer ticker.Stop()
+		for {
+			s.resumeDue()
+			select {
+			case <-ticker.C:
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops polling. Executions already resumed keep running on the worker pool;
+// waiting ones stay in storage and are picked up on the next Start.
+func (s *DelayScheduler) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+}
+
+// parkNode returns the wait to suspend the execution in for a delay node of at
+// least MinDuration or an enqueue node, and nil for nodes that run in-process.
+func (s *DelayScheduler) parkNode(node *Node, config interface{}) (*suspendedState, error) {
+	now := time.Now()
+	wait := &suspendedState{Version: executionStateVersion, NodeID: node.ID, NodeType: node.Type, SuspendedAt: now}
+	switch node.Type {
+	case NodeTypeDelay:
+		d, unit, err := delayDuration(config)
+		if err != nil || d < s.MinDuration {
+			return nil, err
+		}
+		wait.Unit, wait.WakeAt = unit, now.Add(d)
+	case NodeTypeEnqueue:
+		wakeAt, unit, signal, err := enqueueWait(config, now)
+		if err != nil {
+			return nil, err
+		}
+		wait.Unit, wait.Signal = unit, signal
+		if wakeAt != nil {
+			wait.WakeAt = *wakeAt
+		}
+	default:
+		return nil, nil
+	}
+	return wait, nil
+}
+
+// resumeDue claims the executions whose delay is over and resumes each one.
+func (s *DelayScheduler) resumeDue() {
+	ctx := context.Background()
+	execs, err := s.store.ClaimDueExecutions(ctx, time.Now(), delayClaimBatch)
+	if err != nil {
+		log.Printf("Delay scheduler: failed to claim due executions: %v", err)
+		return
+	}
+	for _, exec := range execs {
+		s.resume(ctx, exec, false, nil)
+	}
+}
+
+// Signal resumes the executions waiting in enqueue nodes for signal, passing
+// payload to them as the node's output, and returns how many were resumed.
+// Resumed executions keep running after ctx is done.
+func (s *DelayScheduler) Signal(ctx context.Context, signal string, payload interface{}) (int, error) {
+	execs, err := s.store.ClaimSignaledExecutions(ctx, signal)
+	if err != nil {
+		return 0, err
+	}
+	for _, exec := range execs {
+		s.resume(context.WithoutCancel(ctx), exec, true, payload)
+	}
+	return len(execs), nil
+}
+
+func (s *DelayScheduler) resume(ctx context.Context, exec *storage.Execution, signaled bool, payload interface{}) {
+	state, err := decodeSuspendedState(exec.State)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to resume after delay: invalid saved state: %v", err)
+		s.store.UpdateExecutionStatus(ctx, exec.ID, storage.ExecutionStatusFailed, exec.State, &msg)
+		return
+	}
+	state.signaled, state.signalPayload = signaled, payload
+
+	if signaled {
+		log.Printf("Resuming execution %s on signal %s in node %s", exec.ID, state.Signal, state.NodeID)
+	} else {
+		log.Printf("Resuming execution %s after delay in node %s", exec.ID, state.NodeID)
+	}
+	err = s.workerPool.Execute(ctx, func() error {
+		ectx := NewExecutionContext(state.Workflow.ID)
+		ectx.ExecutionID = exec.ID
+		ectx.Environment = state.Environment
+		if state.TriggerData != nil {
+			ectx.TriggerData = state.TriggerData
+		}
+		ectx.Restore(state.Results, state.Variables)
+
+		runner := NewWorkflowRunner(ectx, s.blocksDir, s.store, s.registry)
+		runner.SetEventBus(s.events)
+		runner.SetDelayScheduler(s)
+		return runner.runGraph(ctx, state.Workflow, exec.ID, &resumePoint{suspended: &state, startedAt: state.StartedAt})
+	})
+	if err != nil {
+		msg := fmt.Sprintf("Failed to resume after delay: %v", err)
+		s.store.UpdateExecutionStatus(ctx, exec.ID, storage.ExecutionStatusFailed, exec.State, &msg)
+	}
+}
@@ -0,0 +1,150 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// TestBunRunner_ExecuteNode_NativeDelay verifies that std/delay sleeps in-process
+// and stops early when its context is cancelled.
+func TestBunRunner_ExecuteNode_NativeDelay(t *testing.T) {
+	runner := engine.NewBunRunner(t.TempDir())
+	runner.RuntimePath = "/nonexistent/bun" // any process start would fail
+	node := &engine.Node{ID: "wait", Type: engine.NodeTypeDelay}
+
+	input := map[string]interface{}{"config": map[string]interface{}{"duration": 20.0}}
+	started := time.Now()
+	raw, err := runner.ExecuteNode(context.Background(), node, input)
+	if err != nil {
+		t.Fatalf("ExecuteNode failed: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed < 20*time.Millisecond {
+		t.Errorf("expected to wait at least 20ms, waited %s", elapsed)
+	}
+	data := raw.(map[string]interface{})["data"].(map[string]interface{})
+	if data["unit"] != "ms" {
+		t.Errorf("expected unit ms, got %v", data["unit"])
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	input = map[string]interface{}{"config": map[string]interface{}{"duration": 1.0, "unit": "h"}}
+	if _, err := runner.ExecuteNode(ctx, node, input); err == nil {
+		t.Error("expected cancelled delay to fail")
+	}
+
+	input = map[string]interface{}{"config": map[string]interface{}{"duration": 1.0, "unit": "weeks"}}
+	if _, err := runner.ExecuteNode(context.Background(), node, input); err == nil {
+		t.Error("expected invalid unit to fail")
+	}
+}
+
+// TestWorkflowRunner_DurableDelay verifies that with a DelayScheduler a long delay
+// parks the execution as waiting and releases the run, and that the scheduler
+// resumes it after the delay with its trigger data and results intact and runs
+// the fan-out branch that was still pending.
+func TestWorkflowRunner_DurableDelay(t *testing.T) {
+	installFakeBun(t)
+	blocksDir := t.TempDir()
+	writeBlock(t, blocksDir, "test/echo.ts", "cat\n")
+
+	store := createTestStorage(t)
+	pool := engine.NewWorkerPool(1)
+	delays := engine.NewDelayScheduler(store, blocksDir, nil, pool)
+	delays.MinDuration = 200 * time.Millisecond
+	delays.PollInterval = 20 * time.Millisecond
+
+	workflow := engine.Workflow{
+		ID:   "delay-wf",
+		Name: "Delay",
+		Nodes: map[string]engine.Node{
+			"before": {ID: "before", Type: "test/echo", Config: map[string]interface{}{"step": "before"}},
+			"wait":   {ID: "wait", Type: engine.NodeTypeDelay, Config: map[string]interface{}{"duration": 300.0}},
+			"after": {ID: "after", Type: "test/echo", Config: map[string]interface{}{
+				"user": "{{ $trigger.user }}",
+				"prev": "{{ $node.before.config.step }}",
+			}},
+			"side": {ID: "side", Type: "test/echo", Config: map[string]interface{}{"step": "side"}},
+		},
+		Edges: []engine.Edge{
+			{ID: "e1", Source: "before", Target: "wait"},
+			{ID: "e2", Source: "wait", Target: "after"},
+			{ID: "e3", Source: "before", Target: "side"},
+		},
+	}
+
+	ectx := engine.NewExecutionContext(workflow.ID)
+	ectx.TriggerData = map[string]interface{}{"user": "ada"}
+	runner := engine.NewWorkflowRunner(ectx, blocksDir, store, nil)
+	runner.SetDelayScheduler(delays)
+
+	started := time.Now()
+	if err := runner.Run(context.Background(), workflow); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed >= 300*time.Millisecond {
+		t.Errorf("expected Run to return before the delay elapsed, took %s", elapsed)
+	}
+
+	execs, err := store.ListExecutions(context.Background(), workflow.ID, 1)
+	if err != nil || len(execs) != 1 {
+		t.Fatalf("expected one execution, got %v (%v)", execs, err)
+	}
+	execID := execs[0].ID
+	if execs[0].Status != storage.ExecutionStatusWaiting {
+		t.Fatalf("expected execution to be waiting, got %s", execs[0].Status)
+	}
+	if _, err := store.GetNodeResult(context.Background(), execID, "side"); err == nil {
+		t.Error("expected the second branch to wait for the first")
+	}
+
+	delays.Start()
+	defer delays.Stop()
+
+	var exec *storage.Execution
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		exec, err = store.GetExecution(context.Background(), execID)
+		if err != nil {
+			t.Fatalf("failed to get execution: %v", err)
+		}
+		if exec.Status == storage.ExecutionStatusCompleted || exec.Status == storage.ExecutionStatusFailed {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if exec.Status != storage.ExecutionStatusCompleted {
+		t.Fatalf("expected resumed execution to complete, got %s (error %v)", exec.Status, exec.Error)
+	}
+
+	raw, err := store.GetNodeResult(context.Background(), execID, "after")
+	if err != nil {
+		t.Fatalf("expected a result for the node after the delay: %v", err)
+	}
+	for _, want := range []string{`"user":"ada"`, `"prev":"before"`} {
+		if !strings.Contains(string(raw), want) {
+			t.Errorf("expected %s in result %s", want, raw)
+		}
+	}
+	if _, err := store.GetNodeResult(context.Background(), execID, "side"); err != nil {
+		t.Errorf("expected the pending branch to run after resuming: %v", err)
+	}
+	raw, err = store.GetNodeResult(context.Background(), execID, "wait")
+	if err != nil {
+		t.Fatalf("expected a result for the delay node: %v", err)
+	}
+	var waited struct {
+		Data struct {
+			Delayed int64 `json:"delayed"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &waited); err != nil || waited.Data.Delayed < 300 {
+		t.Errorf("expected the execution to resume after 300ms, delay result %s (%v)", raw, err)
+	}
+}
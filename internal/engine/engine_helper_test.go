@@ -0,0 +1,50 @@
+package engine_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFakeBun creates a stand-in for the bun binary that runs the given
+// "script" with /bin/sh, so runner behavior can be tested without Bun installed.
+func writeFakeBun(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bun")
+	script := "#!/bin/sh\n# drop the 'run' subcommand\nshift\nexec /bin/sh \"$@\"\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake bun: %v", err)
+	}
+	return path
+}
+
+// writeScript writes a shell "block" script into a temp dir.
+func writeScript(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "block.sh")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	return path
+}
+
+// installFakeBun puts a fake bun binary first in PATH for the duration of the test.
+func installFakeBun(t *testing.T) {
+	t.Helper()
+	fakeBun := writeFakeBun(t)
+	t.Setenv("PATH", filepath.Dir(fakeBun)+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// writeBlock writes a shell "block" into blocksDir at the given relative path
+// (e.g. "std/condition.ts"), to be executed by the fake bun.
+func writeBlock(t *testing.T, blocksDir, relPath, content string) {
+	t.Helper()
+	path := filepath.Join(blocksDir, relPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create block dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write block: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// enqueueConfig is the config of a std/enqueue node, which defers the rest of
+// its branch until a time, a named signal, or whichever comes first:
+//
+//	{"duration": 72, "unit": "h", "signal": "reply-{{ $trigger.ticketId }}"}
+//	{"at": "2025-01-06T09:00:00Z"}
+//
+// The "default" port continues when the time comes, the "signal" port when the
+// signal is sent (POST /api/signals/{name}), so "remind in 3 days unless replied"
+// connects the reminder to "default" and nothing, or a thank-you, to "signal".
+type enqueueConfig struct {
+	// Duration and Unit (ms, s, m, h; ms by default) wait relative to now, like std/delay.
+	Duration *float64 `json:"duration"`
+	Unit     string   `json:"unit"`
+	// At waits until an RFC 3339 time.
+	At string `json:"at"`
+	// Signal waits for the named signal.
+	Signal string `json:"signal"`
+}
+
+// enqueueWait reads a std/enqueue config: when to wake up (nil for only a
+// signal), the unit to report the wait in, and the signal to wait for.
+func enqueueWait(config interface{}, now time.Time) (*time.Time, string, string, error) {
+	var cfg enqueueConfig
+	raw, _ := json.Marshal(config)
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, "", "", fmt.Errorf("enqueue: invalid config: %w", err)
+	}
+	if cfg.Duration != nil && cfg.At != "" {
+		return nil, "", "", fmt.Errorf("enqueue: config 'duration' and 'at' are mutually exclusive")
+	}
+
+	unit := "ms"
+	var wakeAt *time.Time
+	switch {
+	case cfg.Duration != nil:
+		if *cfg.Duration < 0 {
+			return nil, "", "", fmt.Errorf("enqueue: config 'duration' must be non-negative")
+		}
+		if cfg.Unit != "" {
+			unit = cfg.Unit
+		}
+		scale, ok := delayUnits[unit]
+		if !ok {
+			return nil, "", "", fmt.Errorf("enqueue: config 'unit' must be 'ms', 's', 'm' or 'h'")
+		}
+		t := now.Add(time.Duration(*cfg.Duration * float64(scale)))
+		wakeAt = &t
+	case cfg.At != "":
+		t, err := time.Parse(time.RFC3339, cfg.At)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("enqueue: config 'at' must be an RFC 3339 time: %w", err)
+		}
+		wakeAt = &t
+	case cfg.Signal == "":
+		return nil, "", "", fmt.Errorf("enqueue: config needs 'duration', 'at' or 'signal'")
+	}
+	return wakeAt, unit, cfg.Signal, nil
+}
+
+// enqueueResult builds the output of an enqueue node when its execution continues.
+func enqueueResult(waited time.Duration, unit, signal string, signaled bool, payload interface{}) map[string]interface{} {
+	data := map[string]interface{}{
+		"waited":    waited.Milliseconds(),
+		"unit":      unit,
+		"timestamp": time.Now().UTC().Format(time.RFC3339Nano),
+		"resumedBy": "timer",
+	}
+	if signal != "" {
+		data["signal"] = signal
+	}
+	port := "default"
+	if signaled {
+		data["resumedBy"] = "signal"
+		data["payload"] = payload
+		port = "signal"
+	}
+	return map[string]interface{}{"data": data, "port": port}
+}
+
+// executeEnqueue runs a std/enqueue node without a DelayScheduler: it sleeps
+// until the wake-up time in-process. Waiting for a signal needs the scheduler,
+// so runs without one fail on such nodes.
+func executeEnqueue(ctx context.Context, input any) (any, error) {
+	payload, _ := input.(map[string]interface{})
+	started := time.Now()
+	wakeAt, unit, signal, err := enqueueWait(payload["config"], started)
+	if err != nil {
+		return nil, err
+	}
+	if signal != "" {
+		return nil, fmt.Errorf("enqueue: waiting for signal %q needs the server's delay scheduler", signal)
+	}
+
+	timer := time.NewTimer(time.Until(*wakeAt))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return enqueueResult(time.Since(started), unit, "", false, nil), nil
+}
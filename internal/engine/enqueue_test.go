@@ -0,0 +1,144 @@
+package engine_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// reminderWorkflow defers a reminder until wait's config elapses, unless the
+// reply signal arrives first.
+func reminderWorkflow(id string, wait map[string]interface{}) engine.Workflow {
+	return engine.Workflow{
+		ID:   id,
+		Name: "Reminder",
+		Nodes: map[string]engine.Node{
+			"remind":   {ID: "remind", Type: engine.NodeTypeEnqueue, Config: wait},
+			"reminder": {ID: "reminder", Type: "test/echo", Config: map[string]interface{}{"to": "{{ $trigger.user }}"}},
+			"thanks":   {ID: "thanks", Type: "test/echo", Config: map[string]interface{}{"reply": "{{ $node.remind.data.payload.text }}"}},
+		},
+		Edges: []engine.Edge{
+			{ID: "e1", Source: "remind", Target: "reminder", SourceHandle: "default"},
+			{ID: "e2", Source: "remind", Target: "thanks", SourceHandle: "signal"},
+		},
+	}
+}
+
+// waitForExecution polls until the execution has finished.
+func waitForExecution(t *testing.T, store storage.Storage, execID string) *storage.Execution {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		exec, err := store.GetExecution(context.Background(), execID)
+		if err != nil {
+			t.Fatalf("failed to get execution: %v", err)
+		}
+		if exec.Status == storage.ExecutionStatusCompleted || exec.Status == storage.ExecutionStatusFailed {
+			return exec
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("execution %s did not finish", execID)
+	return nil
+}
+
+// TestWorkflowRunner_Enqueue verifies that std/enqueue parks the execution and
+// continues on the "signal" port when its signal is sent, or on the "default"
+// port when its time comes first.
+func TestWorkflowRunner_Enqueue(t *testing.T) {
+	installFakeBun(t)
+	blocksDir := t.TempDir()
+	writeBlock(t, blocksDir, "test/echo.ts", "cat\n")
+
+	store := createTestStorage(t)
+	delays := engine.NewDelayScheduler(store, blocksDir, nil, engine.NewWorkerPool(2))
+	delays.PollInterval = 20 * time.Millisecond
+
+	start := func(t *testing.T, workflowID string, wait map[string]interface{}) string {
+		t.Helper()
+		ectx := engine.NewExecutionContext(workflowID)
+		ectx.TriggerData = map[string]interface{}{"user": "ada"}
+		runner := engine.NewWorkflowRunner(ectx, blocksDir, store, nil)
+		runner.SetDelayScheduler(delays)
+		if err := runner.Run(context.Background(), reminderWorkflow(workflowID, wait)); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		execs, _ := store.ListExecutions(context.Background(), workflowID, 1)
+		if len(execs) != 1 || execs[0].Status != storage.ExecutionStatusWaiting {
+			t.Fatalf("expected a waiting execution, got %v", execs)
+		}
+		return execs[0].ID
+	}
+
+	t.Run("signal", func(t *testing.T) {
+		execID := start(t, "reply-wf", map[string]interface{}{"duration": 1.0, "unit": "h", "signal": "reply-1"})
+
+		if n, err := delays.Signal(context.Background(), "other", nil); err != nil || n != 0 {
+			t.Fatalf("expected an unrelated signal to resume nothing, got %d (%v)", n, err)
+		}
+		n, err := delays.Signal(context.Background(), "reply-1", map[string]interface{}{"text": "done, thanks"})
+		if err != nil || n != 1 {
+			t.Fatalf("expected the signal to resume one execution, got %d (%v)", n, err)
+		}
+
+		if exec := waitForExecution(t, store, execID); exec.Status != storage.ExecutionStatusCompleted {
+			t.Fatalf("expected resumed execution to complete, got %s (error %v)", exec.Status, exec.Error)
+		}
+		raw, err := store.GetNodeResult(context.Background(), execID, "thanks")
+		if err != nil || !strings.Contains(string(raw), `"reply":"done, thanks"`) {
+			t.Errorf("expected the signal payload to reach the signal branch, got %s (%v)", raw, err)
+		}
+		if _, err := store.GetNodeResult(context.Background(), execID, "reminder"); err == nil {
+			t.Error("expected the reminder branch not to run")
+		}
+	})
+
+	t.Run("timer", func(t *testing.T) {
+		execID := start(t, "no-reply-wf", map[string]interface{}{"duration": 50.0, "signal": "reply-2"})
+		delays.Start()
+		defer delays.Stop()
+
+		if exec := waitForExecution(t, store, execID); exec.Status != storage.ExecutionStatusCompleted {
+			t.Fatalf("expected resumed execution to complete, got %s (error %v)", exec.Status, exec.Error)
+		}
+		raw, err := store.GetNodeResult(context.Background(), execID, "reminder")
+		if err != nil || !strings.Contains(string(raw), `"to":"ada"`) {
+			t.Errorf("expected the reminder branch to run, got %s (%v)", raw, err)
+		}
+		if n, _ := delays.Signal(context.Background(), "reply-2", nil); n != 0 {
+			t.Errorf("expected a late signal to resume nothing, got %d", n)
+		}
+	})
+}
+
+// TestBunRunner_ExecuteNode_NativeEnqueue verifies that without a scheduler
+// std/enqueue waits in-process and refuses to wait for a signal.
+func TestBunRunner_ExecuteNode_NativeEnqueue(t *testing.T) {
+	runner := engine.NewBunRunner(t.TempDir())
+	runner.RuntimePath = "/nonexistent/bun" // any process start would fail
+	node := &engine.Node{ID: "later", Type: engine.NodeTypeEnqueue}
+
+	raw, err := runner.ExecuteNode(context.Background(), node, map[string]interface{}{"config": map[string]interface{}{"duration": 20.0}})
+	if err != nil {
+		t.Fatalf("ExecuteNode failed: %v", err)
+	}
+	result := raw.(map[string]interface{})
+	if result["port"] != "default" || result["data"].(map[string]interface{})["resumedBy"] != "timer" {
+		t.Errorf("unexpected result %v", result)
+	}
+
+	for name, config := range map[string]map[string]interface{}{
+		"signal":          {"signal": "reply-1"},
+		"no wait":         {},
+		"invalid at":      {"at": "tomorrow"},
+		"duration and at": {"duration": 1.0, "at": "2025-01-01T00:00:00Z"},
+	} {
+		if _, err := runner.ExecuteNode(context.Background(), node, map[string]interface{}{"config": config}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// errorWorkflowPayload is the trigger data ($trigger) of an error workflow run
+// started for the failed execution executionID of failed.
+func errorWorkflowPayload(failed *Workflow, executionID, errMsg string) map[string]interface{} {
+	return map[string]interface{}{
+		"workflow": map[string]interface{}{
+			"id":   failed.ID,
+			"name": failed.Name,
+		},
+		"execution": map[string]interface{}{
+			"id":    executionID,
+			"error": errMsg,
+		},
+	}
+}
+
+// startErrorWorkflow starts the settings.error_workflow of failed, if it names
+// one, for its failed execution executionID. The error workflow runs in the
+// background; its own failure starts no error workflow in turn.
+func startErrorWorkflow(store storage.Storage, blocksDir string, events *EventBus, failed *Workflow, executionID, errMsg string) {
+	id := failed.ErrorWorkflow()
+	if id == "" || id == failed.ID {
+		return
+	}
+	go func() {
+		ctx := context.Background()
+		stored, err := store.GetWorkflow(ctx, id)
+		if err != nil {
+			log.Printf("Warning: failed to load error workflow %s of workflow %s: %v", id, failed.ID, err)
+			return
+		}
+		var workflow Workflow
+		if err := json.Unmarshal(stored.Definition, &workflow); err != nil {
+			log.Printf("Warning: failed to parse error workflow %s: %v", id, err)
+			return
+		}
+
+		ectx := NewExecutionContext(workflow.ID)
+		ectx.TriggerData = errorWorkflowPayload(failed, executionID, errMsg)
+		runner := NewWorkflowRunner(ectx, blocksDir, store, nil)
+		runner.SetEventBus(events)
+		runner.errorRun = true
+
+		log.Printf("Execution %s of workflow %s failed; starting error workflow %s", executionID, failed.ID, id)
+		if err := runner.Run(ctx, workflow); err != nil {
+			log.Printf("Warning: error workflow %s failed: %v", id, err)
+		}
+	}()
+}
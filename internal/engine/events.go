@@ -0,0 +1,231 @@
+package engine
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// EventType identifies the kind of engine event.
+type EventType string
+
+const (
+	EventExecutionStarted  EventType = "execution.started"
+	EventExecutionFinished EventType = "execution.finished"
+	EventNodeFinished      EventType = "node.finished"
+	EventNodeOutput        EventType = "node.output"
+	EventTriggerFired      EventType = "trigger.fired"
+	EventTriggerCrashed    EventType = "trigger.crashed"
+)
+
+// Event is implemented by every typed engine event.
+// Subscribers type-switch on the concrete type to access its fields.
+type Event interface {
+	EventType() EventType
+	EventTime() time.Time
+}
+
+// ExecutionStarted is published when a workflow execution record is created.
+type ExecutionStarted struct {
+	WorkflowID  string `json:"workflow_id"`
+	ExecutionID string `json:"execution_id"`
+	// RequestID is the X-Request-ID of the API call that started the run, if any
+	RequestID string    `json:"request_id,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// ExecutionFinished is published when an execution reaches a terminal status.
+type ExecutionFinished struct {
+	WorkflowID  string        `json:"workflow_id"`
+	ExecutionID string        `json:"execution_id"`
+	Status      string        `json:"status"`
+	Error       string        `json:"error,omitempty"`
+	Duration    time.Duration `json:"duration"`
+	Time        time.Time     `json:"time"`
+}
+
+// NodeFinished is published after each node execution, successful or not.
+type NodeFinished struct {
+	WorkflowID  string        `json:"workflow_id"`
+	ExecutionID string        `json:"execution_id"`
+	NodeID      string        `json:"node_id"`
+	NodeType    NodeType      `json:"node_type"`
+	Port        string        `json:"port,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	Duration    time.Duration `json:"duration"`
+	Time        time.Time     `json:"time"`
+	// Output is the node's result for in-process subscribers; it is left out
+	// of the JSON form, which is published outside the process.
+	Output interface{} `json:"-"`
+}
+
+// NodeOutput is published for each line of output a node streams while it runs
+// (e.g. the remote command of a std/ssh node). Stream is "stdout" or "stderr",
+// or "warning" for the engine's warnings about the node, e.g. a yanked block.
+type NodeOutput struct {
+	WorkflowID  string    `json:"workflow_id"`
+	ExecutionID string    `json:"execution_id"`
+	NodeID      string    `json:"node_id"`
+	Stream      string    `json:"stream"`
+	Line        string    `json:"line"`
+	Time        time.Time `json:"time"`
+}
+
+// TriggerFired is published when a trigger fires, before the workflow runs.
+type TriggerFired struct {
+	TriggerID  string    `json:"trigger_id"`
+	WorkflowID string    `json:"workflow_id"`
+	Time       time.Time `json:"time"`
+}
+
+// TriggerCrashed is published when a long-running trigger process fails on its own:
+// it exits with a non-zero status or is killed by a signal.
+type TriggerCrashed struct {
+	TriggerID string    `json:"trigger_id"`
+	Error     string    `json:"error"`
+	Time      time.Time `json:"time"`
+}
+
+func (e ExecutionStarted) EventType() EventType  { return EventExecutionStarted }
+func (e ExecutionFinished) EventType() EventType { return EventExecutionFinished }
+func (e NodeFinished) EventType() EventType      { return EventNodeFinished }
+func (e NodeOutput) EventType() EventType        { return EventNodeOutput }
+func (e TriggerFired) EventType() EventType      { return EventTriggerFired }
+func (e TriggerCrashed) EventType() EventType    { return EventTriggerCrashed }
+
+func (e ExecutionStarted) EventTime() time.Time  { return e.Time }
+func (e ExecutionFinished) EventTime() time.Time { return e.Time }
+func (e NodeFinished) EventTime() time.Time      { return e.Time }
+func (e NodeOutput) EventTime() time.Time        { return e.Time }
+func (e TriggerFired) EventTime() time.Time      { return e.Time }
+func (e TriggerCrashed) EventTime() time.Time    { return e.Time }
+
+// subscriberBuffer is how many undelivered events a slow subscriber may lag behind
+// before new events are dropped for it.
+const subscriberBuffer = 256
+
+// EventBus fans out engine events to in-process subscribers.
+// Each subscriber gets its own goroutine and buffer, so a slow subscriber never
+// blocks workflow execution; when its buffer is full, events are dropped for it.
+// A nil *EventBus is valid and discards everything.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers map[int]*subscriber
+	nextID      int
+}
+
+type subscriber struct {
+	handler func(Event)
+	filter  map[EventType]bool
+	events  chan Event
+	done    chan struct{}
+}
+
+// NewEventBus creates an empty event bus.
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[int]*subscriber)}
+}
+
+// Subscribe registers handler for the given event types (all types when none given).
+// Returns a function that removes the subscription and waits for its goroutine to exit.
+func (b *EventBus) Subscribe(handler func(Event), types ...EventType) (unsubscribe func()) {
+	if b == nil {
+		return func() {}
+	}
+
+	sub := &subscriber{
+		handler: handler,
+		events:  make(chan Event, subscriberBuffer),
+		done:    make(chan struct{}),
+	}
+	if len(types) > 0 {
+		sub.filter = make(map[EventType]bool, len(types))
+		for _, t := range types {
+			sub.filter[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = sub
+	b.mu.Unlock()
+
+	go func() {
+		defer close(sub.done)
+		for ev := range sub.events {
+			sub.handler(ev)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, id)
+			close(sub.events)
+			b.mu.Unlock()
+			<-sub.done
+		})
+	}
+}
+
+// Publish delivers ev to every matching subscriber without blocking.
+func (b *EventBus) Publish(ev Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, sub := range b.subscribers {
+		if sub.filter != nil && !sub.filter[ev.EventType()] {
+			continue
+		}
+		select {
+		case sub.events <- ev:
+		default:
+			log.Printf("Event bus: subscriber lagging, dropped %s event", ev.EventType())
+		}
+	}
+}
+
+// SubscriberCount returns the number of active subscriptions.
+func (b *EventBus) SubscriberCount() int {
+	if b == nil {
+		return 0
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subscribers)
+}
+
+type nodeOutputKey struct{}
+
+// withNodeOutput returns a context under which streamed node output is published
+// to b as NodeOutput events for the given node.
+func (b *EventBus) withNodeOutput(ctx context.Context, workflowID, executionID, nodeID string) context.Context {
+	if b == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, nodeOutputKey{}, func(stream, line string) {
+		b.Publish(NodeOutput{
+			WorkflowID:  workflowID,
+			ExecutionID: executionID,
+			NodeID:      nodeID,
+			Stream:      stream,
+			Line:        line,
+			Time:        time.Now(),
+		})
+	})
+}
+
+// publishNodeOutput streams one line of a running node's output, if the runner
+// set up a destination with withNodeOutput.
+func publishNodeOutput(ctx context.Context, stream, line string) {
+	if publish, ok := ctx.Value(nodeOutputKey{}).(func(string, string)); ok {
+		publish(stream, line)
+	}
+}
@@ -0,0 +1,196 @@
+package engine_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// eventRecorder collects events delivered to a subscriber.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []engine.Event
+}
+
+func (r *eventRecorder) handle(ev engine.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+}
+
+func (r *eventRecorder) types() []engine.EventType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	types := make([]engine.EventType, len(r.events))
+	for i, ev := range r.events {
+		types[i] = ev.EventType()
+	}
+	return types
+}
+
+func TestEventBus(t *testing.T) {
+	t.Run("DeliversToAllSubscribers", func(t *testing.T) {
+		bus := engine.NewEventBus()
+		var a, b eventRecorder
+		unsubA := bus.Subscribe(a.handle)
+		unsubB := bus.Subscribe(b.handle)
+
+		bus.Publish(engine.TriggerFired{TriggerID: "tr-1", Time: time.Now()})
+
+		// Unsubscribing drains the subscriber's queue
+		unsubA()
+		unsubB()
+
+		if len(a.types()) != 1 || len(b.types()) != 1 {
+			t.Fatalf("expected 1 event each, got %d and %d", len(a.types()), len(b.types()))
+		}
+		if bus.SubscriberCount() != 0 {
+			t.Errorf("expected 0 subscribers, got %d", bus.SubscriberCount())
+		}
+	})
+
+	t.Run("FiltersByType", func(t *testing.T) {
+		bus := engine.NewEventBus()
+		var rec eventRecorder
+		unsub := bus.Subscribe(rec.handle, engine.EventTriggerCrashed)
+
+		bus.Publish(engine.TriggerFired{TriggerID: "tr-1"})
+		bus.Publish(engine.TriggerCrashed{TriggerID: "tr-1", Error: "boom"})
+		unsub()
+
+		types := rec.types()
+		if len(types) != 1 || types[0] != engine.EventTriggerCrashed {
+			t.Errorf("expected only trigger.crashed, got %v", types)
+		}
+	})
+
+	t.Run("SlowSubscriberDoesNotBlockPublish", func(t *testing.T) {
+		bus := engine.NewEventBus()
+		release := make(chan struct{})
+		unsub := bus.Subscribe(func(engine.Event) { <-release })
+
+		done := make(chan struct{})
+		go func() {
+			for i := 0; i < 1000; i++ {
+				bus.Publish(engine.NodeFinished{NodeID: "n"})
+			}
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("publish blocked on a slow subscriber")
+		}
+		close(release)
+		unsub()
+	})
+
+	t.Run("NilBusIsNoop", func(t *testing.T) {
+		var bus *engine.EventBus
+		bus.Publish(engine.TriggerFired{})
+		bus.Subscribe(func(engine.Event) {})()
+	})
+}
+
+func TestWorkflowRunner_PublishesEvents(t *testing.T) {
+	installFakeBun(t)
+	blocksDir := t.TempDir()
+	writeBlock(t, blocksDir, "std/http_request.ts", `cat > /dev/null
+echo '{"data": {"ok": true}, "port": "default"}'
+`)
+
+	workflow := engine.Workflow{
+		ID:   "wf-events",
+		Name: "Events",
+		Nodes: map[string]engine.Node{
+			"a": {ID: "a", Type: engine.NodeTypeHTTPRequest},
+			"b": {ID: "b", Type: engine.NodeTypeHTTPRequest},
+		},
+		Edges: []engine.Edge{{ID: "e1", Source: "a", Target: "b"}},
+	}
+
+	bus := engine.NewEventBus()
+	var rec eventRecorder
+	unsub := bus.Subscribe(rec.handle)
+
+	runner := engine.NewWorkflowRunner(engine.NewExecutionContext(workflow.ID), blocksDir, createTestStorage(t), nil)
+	runner.SetEventBus(bus)
+
+	if err := runner.Run(context.Background(), workflow); err != nil {
+		t.Fatalf("workflow failed: %v", err)
+	}
+	unsub()
+
+	expected := []engine.EventType{
+		engine.EventExecutionStarted,
+		engine.EventNodeFinished,
+		engine.EventNodeFinished,
+		engine.EventExecutionFinished,
+	}
+	types := rec.types()
+	if len(types) != len(expected) {
+		t.Fatalf("expected events %v, got %v", expected, types)
+	}
+	for i := range expected {
+		if types[i] != expected[i] {
+			t.Errorf("event %d: expected %s, got %s", i, expected[i], types[i])
+		}
+	}
+
+	finished := rec.events[len(rec.events)-1].(engine.ExecutionFinished)
+	if finished.Status != "completed" {
+		t.Errorf("expected completed status, got %s", finished.Status)
+	}
+}
+
+// TestWorkflowRunner_FinishedEvent verifies that ExecutionFinished is published
+// once the outcome is saved, and not for a run whose execution was finished
+// elsewhere meanwhile.
+func TestWorkflowRunner_FinishedEvent(t *testing.T) {
+	store := createTestStorage(t)
+	run := func(duration float64, finishElsewhere bool) (saved storage.ExecutionStatus, published bool) {
+		workflow := engine.Workflow{
+			ID:    "wf-finished",
+			Nodes: map[string]engine.Node{"wait": {ID: "wait", Type: engine.NodeTypeDelay, Config: map[string]interface{}{"duration": duration}}},
+		}
+		bus := engine.NewEventBus()
+		statuses := make(chan storage.ExecutionStatus, 1)
+		defer bus.Subscribe(func(ev engine.Event) {
+			switch ev := ev.(type) {
+			case engine.ExecutionStarted:
+				if finishElsewhere {
+					store.TransitionExecutionStatus(context.Background(), ev.ExecutionID, storage.ExecutionStatusRunning, storage.ExecutionStatusCancelled, nil, nil)
+				}
+			case engine.ExecutionFinished:
+				exec, err := store.GetExecution(context.Background(), ev.ExecutionID)
+				if err != nil {
+					t.Errorf("failed to get execution: %v", err)
+					return
+				}
+				statuses <- exec.Status
+			}
+		})()
+
+		runner := engine.NewWorkflowRunner(engine.NewExecutionContext(workflow.ID), t.TempDir(), store, nil)
+		runner.SetEventBus(bus)
+		runner.Run(context.Background(), workflow)
+		select {
+		case status := <-statuses:
+			return status, true
+		case <-time.After(200 * time.Millisecond):
+			return "", false
+		}
+	}
+
+	if status, published := run(10, false); !published || status != storage.ExecutionStatusCompleted {
+		t.Errorf("expected ExecutionFinished for a saved completed execution, got %v %q", published, status)
+	}
+	if _, published := run(300, true); published {
+		t.Error("expected no ExecutionFinished for an execution finished elsewhere")
+	}
+}
@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// loadGlobals seeds ectx with the server-managed global variables ($globals):
+// the shared defaults, overridden by the variables of ectx.Environment when set.
+// Selecting an environment that does not exist is an error rather than a silent
+// fallback, so a workflow never runs against the wrong endpoints by accident.
+func loadGlobals(ctx context.Context, store storage.Storage, ectx *ExecutionContext) error {
+	globals := make(map[string]interface{})
+
+	scopes := []string{""}
+	if ectx.Environment != "" {
+		if _, err := store.GetEnvironment(ctx, ectx.Environment); err != nil {
+			return fmt.Errorf("unknown environment %q: %w", ectx.Environment, err)
+		}
+		scopes = append(scopes, ectx.Environment)
+	}
+
+	for _, env := range scopes {
+		vars, err := store.ListGlobalVariables(ctx, env)
+		if err != nil {
+			return err
+		}
+		for _, v := range vars {
+			var value interface{}
+			if err := json.Unmarshal(v.Value, &value); err != nil {
+				return fmt.Errorf("failed to parse global variable %s: %w", v.Name, err)
+			}
+			globals[v.Name] = value
+		}
+	}
+
+	ectx.SetGlobals(globals)
+	return nil
+}
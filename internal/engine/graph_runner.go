@@ -2,316 +2,83 @@ package engine
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
-	"fmt"
-	"log"
 	"time"
 
 	"github.com/conv3n/conv3n/internal/storage"
 )
 
-// GraphRunner executes workflows using pointer-based graph traversal.
-// Unlike the linear WorkflowRunner, this supports branching, loops, and multiple output ports.
+// GraphRunner runs one workflow given up front, for callers written before
+// WorkflowRunner, which it wraps, ran graph workflows. Results are stored as
+// blocks return them, {data, port}, but as before GetResults returns the data
+// of each node alone, {{ $node.X.field }} reads its data's field, and nodes
+// that set no timeout are stopped after defaultNodeTimeout.
+//
+// Deprecated: Use WorkflowRunner.
 type GraphRunner struct {
-	workflow    *Workflow
-	bunRunner   *BunRunner
-	ctx         *ExecutionContext
-	storage     storage.Storage
-	executionID string
-	lastNodeID  string
+	workflow *Workflow
+	ctx      *ExecutionContext
+	runner   *WorkflowRunner
 }
 
+// defaultNodeTimeout bounds the nodes of GraphRunner runs that set no timeout.
 const defaultNodeTimeout = 30 * time.Second
 
-type resumeState struct {
-	Results       map[string]interface{} `json:"results"`
-	Variables     map[string]interface{} `json:"variables"`
-	CurrentNodeID string                 `json:"current_node_id"`
-}
-
-// NewGraphRunner creates a new graph-based workflow runner.
+// NewGraphRunner creates a runner for workflow.
 func NewGraphRunner(workflow *Workflow, blocksDir string, store storage.Storage) *GraphRunner {
+	ectx := NewExecutionContext(workflow.ID)
+	runner := NewWorkflowRunner(ectx, blocksDir, store, nil)
+	runner.SetDefaultNodeTimeout(defaultNodeTimeout)
 	return &GraphRunner{
-		workflow:  workflow,
-		bunRunner: NewBunRunner(blocksDir),
-		ctx:       NewExecutionContext(workflow.ID),
-		storage:   store,
-	}
-}
-
-func getNodeTimeout(node *Node) time.Duration {
-	if node == nil {
-		return defaultNodeTimeout
-	}
-
-	if node.Config == nil {
-		return defaultNodeTimeout
-	}
-
-	if raw, ok := node.Config["timeout_ms"]; ok {
-		switch v := raw.(type) {
-		case float64:
-			if v > 0 {
-				return time.Duration(v) * time.Millisecond
-			}
-		}
+		workflow: workflow,
+		ctx:      ectx,
+		runner:   runner,
 	}
-
-	return defaultNodeTimeout
 }
 
-// Run executes the workflow starting from the first node without incoming edges.
-// Uses pointer-based execution: follows edges based on output ports.
-func (gr *GraphRunner) Run(ctx context.Context) error {
-	log.Printf("Starting graph workflow: %s (%s)", gr.workflow.Name, gr.workflow.ID)
-
-	// Create execution record in storage
-	execID, err := gr.storage.CreateExecution(ctx, gr.workflow.ID)
-	if err != nil {
-		return fmt.Errorf("failed to create execution record: %w", err)
-	}
-	gr.executionID = execID
-	gr.ctx.ExecutionID = execID
-
-	var finalStatus = storage.ExecutionStatusCompleted
-	var finalError *string
-
-	defer func() {
-		state := resumeState{
-			Results:       gr.ctx.Results,
-			Variables:     gr.ctx.Variables,
-			CurrentNodeID: gr.lastNodeID,
-		}
-		stateBytes, _ := json.Marshal(state)
-		if err := gr.storage.UpdateExecutionStatus(ctx, execID, finalStatus, stateBytes, finalError); err != nil {
-			log.Printf("Failed to update execution status: %v", err)
-		}
-	}()
-
-	// Find start nodes (nodes with no incoming edges)
-	startNodes := gr.workflow.FindStartNodes()
-	if len(startNodes) == 0 {
-		return fmt.Errorf("no start nodes found in workflow")
-	}
-
-	// For now, execute from the first start node
-	// TODO: Support parallel execution of multiple start nodes
-	startNodeID := startNodes[0]
-
-	// Execute using pointer-based traversal
-	if err := gr.executeFromNode(ctx, startNodeID); err != nil {
-		finalStatus = storage.ExecutionStatusFailed
-		msg := err.Error()
-		finalError = &msg
-		return err
-	}
-
-	log.Printf("Graph workflow %s completed", gr.workflow.ID)
-	return nil
+// SetCheckpointInterval sets how often the state of the running execution is
+// saved at most (see WorkflowRunner.SetCheckpointInterval).
+func (gr *GraphRunner) SetCheckpointInterval(d time.Duration) {
+	gr.runner.SetCheckpointInterval(d)
 }
 
-// executeFromNode executes the workflow starting from the given node.
-// This is the core pointer-based execution loop.
-func (gr *GraphRunner) executeFromNode(ctx context.Context, startNodeID string) error {
-	currentNodeID := startNodeID
-
-	for currentNodeID != "" {
-		// Check for context cancellation (kill switch)
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-
-		// Get the current node
-		node := gr.workflow.GetNode(currentNodeID)
-		if node == nil {
-			return fmt.Errorf("node not found: %s", currentNodeID)
-		}
-
-		log.Printf("Executing node: %s (%s)", node.ID, node.Type)
-
-		var (
-			result *BlockResult
-			port   string
-		)
-
-		cachedBytes, err := gr.storage.GetNodeResult(ctx, gr.executionID, node.ID)
-		if err == nil {
-			var cachedData interface{}
-			if err := json.Unmarshal(cachedBytes, &cachedData); err != nil {
-				log.Printf("Warning: failed to unmarshal cached node result for %s: %v", node.ID, err)
-			} else {
-				gr.ctx.SetResult(node.ID, cachedData)
-				gr.lastNodeID = node.ID
-				log.Printf("Node %s skipped execution, using cached result", node.ID)
-				port = ""
-			}
-		}
-
-		if port == "" && gr.ctx.GetResult(node.ID) == nil {
-			res, execErr := gr.executeNode(ctx, node)
-			if execErr != nil {
-				return fmt.Errorf("failed to execute node %s: %w", node.ID, execErr)
-			}
-			result = res
-			gr.ctx.SetResult(node.ID, result.Data)
-			gr.lastNodeID = node.ID
-
-			resBytes, _ := json.Marshal(result.Data)
-			if err := gr.storage.SaveNodeResult(ctx, gr.executionID, node.ID, resBytes); err != nil {
-				log.Printf("Warning: failed to save node result: %v", err)
-			}
-
-			port = result.Port
-			log.Printf("Node %s completed, output port: %s", node.ID, port)
-		}
-
-		// Find the next node based on the output port
-		currentNodeID = gr.workflow.FindNextNode(node.ID, port)
-	}
-
-	return nil
+// SetEventBus makes the runner publish execution and node events to bus.
+func (gr *GraphRunner) SetEventBus(bus *EventBus) {
+	gr.runner.SetEventBus(bus)
 }
 
-// executeNode executes a single node and returns the result with output port.
-func (gr *GraphRunner) executeNode(ctx context.Context, node *Node) (*BlockResult, error) {
-	nodeTimeout := getNodeTimeout(node)
-	nodeCtx, cancel := context.WithTimeout(ctx, nodeTimeout)
-	defer cancel()
-
-	resolvedConfig, err := ResolveVariables(node.Config, gr.ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to resolve variables: %w", err)
-	}
-
-	input := map[string]interface{}{
-		"config": resolvedConfig,
-	}
-
-	rawResult, err := gr.bunRunner.ExecuteNode(nodeCtx, node, input)
-	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) || errors.Is(nodeCtx.Err(), context.DeadlineExceeded) {
-			return nil, fmt.Errorf("node %s execution timed out after %s: %w", node.ID, nodeTimeout, err)
-		}
-		if errors.Is(err, context.Canceled) || errors.Is(nodeCtx.Err(), context.Canceled) {
-			return nil, fmt.Errorf("node %s execution canceled: %w", node.ID, err)
-		}
-		return nil, err
-	}
-
-	return gr.parseBlockResult(rawResult)
+// Run executes the workflow (see WorkflowRunner.Run).
+func (gr *GraphRunner) Run(ctx context.Context) error {
+	return gr.runner.Run(ctx, *gr.workflow)
 }
 
-// parseBlockResult converts raw Bun output to BlockResult with port routing.
-func (gr *GraphRunner) parseBlockResult(raw interface{}) (*BlockResult, error) {
-	result := &BlockResult{
-		Data: raw,
-		Port: "default", // Default output port
-	}
-
-	// Try to extract port from the result if it's a map
-	if resMap, ok := raw.(map[string]interface{}); ok {
-		// Check if result has explicit port field
-		if port, hasPort := resMap["port"]; hasPort {
-			if portStr, ok := port.(string); ok {
-				result.Port = portStr
-			}
-		}
-
-		// For condition blocks, use the boolean result to determine port
-		if data, hasData := resMap["data"]; hasData {
-			result.Data = data
-
-			// Check for condition result (true/false routing)
-			if dataMap, ok := data.(map[string]interface{}); ok {
-				if condResult, hasResult := dataMap["result"]; hasResult {
-					if boolResult, ok := condResult.(bool); ok {
-						if boolResult {
-							result.Port = "true"
-						} else {
-							result.Port = "false"
-						}
-					}
-				}
-			}
+// GetResults returns a snapshot of the data of every node result from the
+// execution, e.g. GetResults()["check"]["result"] for a condition node.
+func (gr *GraphRunner) GetResults() map[string]interface{} {
+	results := gr.ctx.Results()
+	for nodeID, result := range results {
+		if data, ok := blockData(result); ok {
+			results[nodeID] = data
 		}
 	}
-
-	return result, nil
+	return results
 }
 
-// GetResults returns all node results from the execution.
-func (gr *GraphRunner) GetResults() map[string]interface{} {
-	return gr.ctx.Results
+// GetVariables returns a snapshot of all user-defined variables.
+func (gr *GraphRunner) GetVariables() map[string]interface{} {
+	return gr.ctx.Variables()
 }
 
-// GetVariables returns all user-defined variables.
-func (gr *GraphRunner) GetVariables() map[string]interface{} {
-	return gr.ctx.Variables
+// SetVariable sets a user-defined variable before or during execution.
+func (gr *GraphRunner) SetVariable(name string, value interface{}) {
+	gr.ctx.SetVar(name, value)
 }
 
+// ResumeGraphExecution continues the running execution executionID of workflow
+// from its last checkpoint (see WorkflowRunner.ResumeExecution).
+//
+// Deprecated: Use WorkflowRunner.ResumeExecution.
 func ResumeGraphExecution(ctx context.Context, store storage.Storage, executionID string, workflow *Workflow, blocksDir string) error {
-	exec, err := store.GetExecution(ctx, executionID)
-	if err != nil {
-		return fmt.Errorf("failed to get execution %s: %w", executionID, err)
-	}
-
-	var state resumeState
-	if len(exec.State) == 0 {
-		return fmt.Errorf("execution %s has no saved state", executionID)
-	}
-	if err := json.Unmarshal(exec.State, &state); err != nil {
-		return fmt.Errorf("failed to parse execution state for %s: %w", executionID, err)
-	}
-
-	if state.CurrentNodeID == "" {
-		return fmt.Errorf("execution %s has empty current_node_id", executionID)
-	}
-
-	if workflow.GetNode(state.CurrentNodeID) == nil {
-		return fmt.Errorf("node %s not found in workflow %s", state.CurrentNodeID, workflow.ID)
-	}
-
-	runner := &GraphRunner{
-		workflow:    workflow,
-		bunRunner:   NewBunRunner(blocksDir),
-		ctx:         NewExecutionContext(workflow.ID),
-		storage:     store,
-		executionID: executionID,
-		lastNodeID:  state.CurrentNodeID,
-	}
-
-	runner.ctx.ExecutionID = executionID
-	if state.Results != nil {
-		runner.ctx.Results = state.Results
-	}
-	if state.Variables != nil {
-		runner.ctx.Variables = state.Variables
-	}
-
-	var finalStatus = storage.ExecutionStatusCompleted
-	var finalError *string
-
-	defer func() {
-		resume := resumeState{
-			Results:       runner.ctx.Results,
-			Variables:     runner.ctx.Variables,
-			CurrentNodeID: runner.lastNodeID,
-		}
-		stateBytes, _ := json.Marshal(resume)
-		if err := store.UpdateExecutionStatus(ctx, executionID, finalStatus, stateBytes, finalError); err != nil {
-			log.Printf("Failed to update execution status during resume: %v", err)
-		}
-	}()
-
-	if err := runner.executeFromNode(ctx, state.CurrentNodeID); err != nil {
-		finalStatus = storage.ExecutionStatusFailed
-		msg := err.Error()
-		finalError = &msg
-		return err
-	}
-
-	return nil
+	runner := NewWorkflowRunner(NewExecutionContext(workflow.ID), blocksDir, store, nil)
+	runner.SetDefaultNodeTimeout(defaultNodeTimeout)
+	return runner.ResumeExecution(ctx, *workflow, executionID)
 }
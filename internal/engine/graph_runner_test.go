@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -127,7 +128,7 @@ func TestGraphRunner(t *testing.T) {
 		runner := engine.NewGraphRunner(wf, blocksDir, store)
 
 		// Set variable
-		runner.GetVariables()["foo"] = "bar"
+		runner.SetVariable("foo", "bar")
 
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -143,4 +144,164 @@ func TestGraphRunner(t *testing.T) {
 			t.Errorf("expected condition to be true with variable, got %v", startRes["result"])
 		}
 	})
+
+	t.Run("NodeResultTemplate", func(t *testing.T) {
+		// Templates read fields of a node's data directly, without .data
+		wf := &engine.Workflow{
+			ID:   "wf-node-template",
+			Name: "Node Template",
+			Nodes: map[string]engine.Node{
+				"start": {
+					ID:     "start",
+					Type:   "std/condition",
+					Config: map[string]interface{}{"expression": "1 == 1"},
+				},
+				"check": {
+					ID:     "check",
+					Type:   "std/condition",
+					Config: map[string]interface{}{"expression": "{{ $node.start.result }} == true"},
+				},
+			},
+			Edges: []engine.Edge{{ID: "edge-1", Source: "start", SourceHandle: "true", Target: "check"}},
+		}
+
+		runner := engine.NewGraphRunner(wf, blocksDir, store)
+		if err := runner.Run(context.Background()); err != nil {
+			t.Fatalf("execution failed: %v", err)
+		}
+		checkRes, _ := runner.GetResults()["check"].(map[string]interface{})
+		if checkRes["result"] != true {
+			t.Errorf("expected {{ $node.start.result }} to resolve to true, got %v", checkRes)
+		}
+	})
+}
+
+// checkpointRecorder records the checkpoints a runner saves.
+type checkpointRecorder struct {
+	storage.Storage
+	nodes []string
+}
+
+func (s *checkpointRecorder) SaveExecutionState(ctx context.Context, executionID string, state []byte) (bool, error) {
+	var checkpoint struct {
+		CurrentNodeID string `json:"current_node_id"`
+	}
+	json.Unmarshal(state, &checkpoint)
+	s.nodes = append(s.nodes, checkpoint.CurrentNodeID)
+	return s.Storage.SaveExecutionState(ctx, executionID, state)
+}
+
+// conditionChain returns a workflow of condition nodes run one after the other.
+func conditionChain(id string, nodeIDs ...string) *engine.Workflow {
+	wf := &engine.Workflow{ID: id, Nodes: map[string]engine.Node{}}
+	for i, nodeID := range nodeIDs {
+		wf.Nodes[nodeID] = engine.Node{ID: nodeID, Type: engine.NodeTypeCondition, Config: map[string]interface{}{"expression": "1 == 1"}}
+		if i > 0 {
+			wf.Edges = append(wf.Edges, engine.Edge{ID: "e" + nodeID, Source: nodeIDs[i-1], SourceHandle: "true", Target: nodeID})
+		}
+	}
+	return wf
+}
+
+func TestGraphRunner_Checkpoints(t *testing.T) {
+	ctx := context.Background()
+	wf := conditionChain("checkpoint-wf", "a", "b", "c")
+
+	store := &checkpointRecorder{Storage: createTestStorage(t)}
+	runner := engine.NewGraphRunner(wf, t.TempDir(), store)
+	runner.SetCheckpointInterval(0)
+	if err := runner.Run(ctx); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if strings.Join(store.nodes, ",") != "a,b,c" {
+		t.Errorf("expected a checkpoint after every node, got %v", store.nodes)
+	}
+
+	// Nodes finishing within the interval share a checkpoint
+	store.nodes = nil
+	if err := engine.NewGraphRunner(wf, t.TempDir(), store).Run(ctx); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if strings.Join(store.nodes, ",") != "a" {
+		t.Errorf("expected only the first node to be checkpointed, got %v", store.nodes)
+	}
+}
+
+func TestResumeGraphExecution_FromCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	wf := conditionChain("resume-wf", "a", "b", "c")
+	store := createTestStorage(t)
+
+	// A run that crashed after b: checkpointed at a, with b's result saved since
+	execID, _ := store.CreateExecution(ctx, wf.ID)
+	store.SaveExecutionState(ctx, execID, []byte(`{"version":1,"variables":{"x":1},"current_node_id":"a"}`))
+	store.SaveNodeResult(ctx, execID, "a", []byte(`{"data":{"result":true},"port":"true"}`))
+	store.SaveNodeResult(ctx, execID, "b", []byte(`{"data":{"result":true,"saved":"before the crash"},"port":"true"}`))
+
+	if err := engine.ResumeGraphExecution(ctx, store, execID, wf, t.TempDir()); err != nil {
+		t.Fatalf("ResumeGraphExecution failed: %v", err)
+	}
+
+	exec, _ := store.GetExecution(ctx, execID)
+	var state struct {
+		Results map[string]struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"results"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	json.Unmarshal(exec.State, &state)
+	if exec.Status != storage.ExecutionStatusCompleted || state.Variables["x"] != 1.0 {
+		t.Fatalf("expected a completed run keeping its variables, got %s %s", exec.Status, exec.State)
+	}
+	if state.Results["b"].Data["saved"] != "before the crash" || state.Results["c"].Data["result"] != true {
+		t.Errorf("expected b's saved result and c to run, got %v", state.Results)
+	}
+	nodes, _ := store.ListNodeExecutions(ctx, execID)
+	for _, ne := range nodes {
+		if ne.NodeID != "c" && ne.Attempts > 0 {
+			t.Errorf("expected %s not to run again, got %+v", ne.NodeID, ne)
+		}
+	}
+}
+
+func TestResumeGraphExecution_PortlessResults(t *testing.T) {
+	ctx := context.Background()
+	wf := conditionChain("resume-legacy-wf", "a", "b", "c")
+	store := createTestStorage(t)
+
+	// Results saved without their port route as their node's record says:
+	// b went out its false port, which leads nowhere
+	execID, _ := store.CreateExecution(ctx, wf.ID)
+	store.SaveExecutionState(ctx, execID, []byte(`{"version":1,"current_node_id":"a"}`))
+	store.SaveNodeResult(ctx, execID, "a", []byte(`{"result":true}`))
+	store.SaveNodeResult(ctx, execID, "b", []byte(`{"result":false}`))
+	store.FinishNodeExecution(ctx, execID, "a", storage.NodeStatusSuccess, "true", nil)
+	store.FinishNodeExecution(ctx, execID, "b", storage.NodeStatusSuccess, "false", nil)
+
+	if err := engine.ResumeGraphExecution(ctx, store, execID, wf, t.TempDir()); err != nil {
+		t.Fatalf("ResumeGraphExecution failed: %v", err)
+	}
+	nodes, _ := store.ListNodeExecutions(ctx, execID)
+	for _, ne := range nodes {
+		if ne.NodeID == "c" && ne.Status != storage.NodeStatusSkipped {
+			t.Errorf("expected c not to run after b's false port, got %+v", ne)
+		}
+	}
+}
+
+func TestGraphRunner_MissingNode(t *testing.T) {
+	ctx := context.Background()
+	wf := conditionChain("missing-node-wf", "a")
+	wf.Edges = append(wf.Edges, engine.Edge{ID: "e-gone", Source: "a", SourceHandle: "true", Target: "gone"})
+	store := createTestStorage(t)
+
+	runner := engine.NewGraphRunner(wf, t.TempDir(), store)
+	if err := runner.Run(ctx); err == nil || !strings.Contains(err.Error(), "node not found: gone") {
+		t.Fatalf("expected the missing node to fail the run, got %v", err)
+	}
+	execs, _ := store.ListExecutions(ctx, wf.ID, 10)
+	if len(execs) != 1 || execs[0].Status != storage.ExecutionStatusFailed ||
+		execs[0].Error == nil || *execs[0].Error != "node not found: gone" {
+		t.Errorf("expected a failed execution recording the missing node, got %+v", execs)
+	}
 }
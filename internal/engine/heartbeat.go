@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// defaultHeartbeatInterval is how often a running execution records a
+// heartbeat, well within the ExecutionReaper's timeout.
+const defaultHeartbeatInterval = 30 * time.Second
+
+// startHeartbeat records a heartbeat for execID every interval until stop is
+// called or ctx is done, so the execution isn't taken for lost while it runs.
+// It stops early once the execution is no longer running in storage.
+func startHeartbeat(ctx context.Context, store storage.Storage, execID string, interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			ok, err := store.HeartbeatExecution(ctx, execID)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Warning: failed to record heartbeat of execution %s: %v", execID, err)
+				}
+				continue
+			}
+			if !ok {
+				return
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// NodeHooks are functions run around every node a WorkflowRunner executes, for
+// integrators embedding the engine to add logging, metrics or policies in Go
+// (see RegisterNodeHooks). Any of them may be nil. Hooks run on the goroutine
+// running the node, so they should be quick; nodes reusing the result saved
+// before a run was resumed don't run them.
+type NodeHooks struct {
+	// BeforeNode runs before the node, once its config is resolved. It may
+	// change call.Input, which the node then runs with. An error fails the
+	// node without running it, like any node failure: the execution fails
+	// unless the node continues on fail.
+	BeforeNode func(ctx context.Context, call *NodeCall) error
+	// AfterNode runs after the node succeeded, with its result.
+	AfterNode func(ctx context.Context, call *NodeCall, result *BlockResult)
+	// OnError runs after the node failed, also when the execution continues past
+	// it, and when a BeforeNode hook failed it.
+	OnError func(ctx context.Context, call *NodeCall, err error)
+}
+
+// NodeCall is the node a hook runs for.
+type NodeCall struct {
+	WorkflowID  string
+	ExecutionID string
+	Node        *Node
+	// Input is what the node is called with: its resolved config and the
+	// workflow's timezone. Nil when the config failed to resolve.
+	Input map[string]interface{}
+	// Test is set for test-mode runs
+	Test bool
+}
+
+var (
+	nodeHooksMu sync.RWMutex
+	nodeHooks   []*NodeHooks
+)
+
+// RegisterNodeHooks makes every runner of the process run h around its nodes,
+// after the hooks registered before. Integrators usually register their hooks
+// before starting the server; the returned function unregisters them.
+func RegisterNodeHooks(h NodeHooks) (unregister func()) {
+	hooks := &h
+	nodeHooksMu.Lock()
+	nodeHooks = append(nodeHooks, hooks)
+	nodeHooksMu.Unlock()
+	return func() {
+		nodeHooksMu.Lock()
+		defer nodeHooksMu.Unlock()
+		nodeHooks = slices.DeleteFunc(nodeHooks, func(registered *NodeHooks) bool { return registered == hooks })
+	}
+}
+
+// registeredNodeHooks returns the hooks registered, in order.
+func registeredNodeHooks() []*NodeHooks {
+	nodeHooksMu.RLock()
+	defer nodeHooksMu.RUnlock()
+	return slices.Clone(nodeHooks)
+}
+
+// beforeNode runs the BeforeNode hooks for call, stopping at the first error.
+func beforeNode(ctx context.Context, call *NodeCall) error {
+	for _, h := range registeredNodeHooks() {
+		if h.BeforeNode == nil {
+			continue
+		}
+		if err := h.BeforeNode(ctx, call); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// afterNode runs the AfterNode hooks for call, which succeeded with result.
+func afterNode(ctx context.Context, call *NodeCall, result *BlockResult) {
+	for _, h := range registeredNodeHooks() {
+		if h.AfterNode != nil {
+			h.AfterNode(ctx, call, result)
+		}
+	}
+}
+
+// onNodeError runs the OnError hooks for call, which failed with err.
+func onNodeError(ctx context.Context, call *NodeCall, err error) {
+	for _, h := range registeredNodeHooks() {
+		if h.OnError != nil {
+			h.OnError(ctx, call, err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package engine_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+func TestNodeHooks(t *testing.T) {
+	w := engine.Workflow{
+		ID: "wf-hooks",
+		Nodes: map[string]engine.Node{
+			"first":  {ID: "first", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "a", "value": "one"}},
+			"second": {ID: "second", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "b", "value": "two"}},
+		},
+		Edges: []engine.Edge{{ID: "e1", Source: "first", Target: "second"}},
+	}
+	run := func() (*engine.ExecutionContext, error) {
+		ectx := engine.NewExecutionContext(w.ID)
+		return ectx, engine.NewWorkflowRunner(ectx, t.TempDir(), createTestStorage(t), nil).Run(context.Background(), w)
+	}
+
+	var calls []string
+	unregister := engine.RegisterNodeHooks(engine.NodeHooks{
+		BeforeNode: func(ctx context.Context, call *engine.NodeCall) error {
+			calls = append(calls, "before "+call.Node.ID)
+			// Hooks may rewrite the input the node runs with
+			if call.Node.ID == "second" {
+				call.Input["config"].(map[string]interface{})["value"] = "rewritten"
+			}
+			return nil
+		},
+		AfterNode: func(ctx context.Context, call *engine.NodeCall, result *engine.BlockResult) {
+			calls = append(calls, "after "+call.Node.ID)
+		},
+	})
+	defer unregister()
+
+	ectx, err := run()
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if got := strings.Join(calls, ", "); got != "before first, after first, before second, after second" {
+		t.Errorf("unexpected hook calls: %s", got)
+	}
+	if got := ectx.GetVar("b"); got != "rewritten" {
+		t.Errorf("expected the node to run with the hook's input, got %v", got)
+	}
+
+	// A BeforeNode error fails the node without running it and reaches OnError
+	var failed []string
+	unregisterPolicy := engine.RegisterNodeHooks(engine.NodeHooks{
+		BeforeNode: func(ctx context.Context, call *engine.NodeCall) error {
+			if call.Node.ID == "second" {
+				return errors.New("policy: second is not allowed")
+			}
+			return nil
+		},
+		OnError: func(ctx context.Context, call *engine.NodeCall, err error) {
+			failed = append(failed, call.Node.ID+": "+err.Error())
+		},
+	})
+	defer unregisterPolicy()
+
+	ectx, err = run()
+	if err == nil || !strings.Contains(err.Error(), "policy: second is not allowed") {
+		t.Fatalf("expected the policy to fail the run, got %v", err)
+	}
+	if ectx.GetVar("b") != nil {
+		t.Error("expected the refused node not to run")
+	}
+	if len(failed) != 1 || failed[0] != "second: policy: second is not allowed" {
+		t.Errorf("unexpected OnError calls: %v", failed)
+	}
+
+	// Unregistered hooks no longer run
+	unregister()
+	unregisterPolicy()
+	calls = nil
+	if _, err := run(); err != nil || len(calls) != 0 {
+		t.Errorf("expected unregistered hooks not to run, got %v and %v", err, calls)
+	}
+}
@@ -0,0 +1,310 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxPages bounds a paginated request when the config sets no maxPages.
+const defaultMaxPages = 100
+
+// Pagination strategies for std/http_request.
+const (
+	paginationPage   = "page"   // ?page=1, 2, 3...
+	paginationOffset = "offset" // ?offset=0, n, 2n... advancing by the items received
+	paginationCursor = "cursor" // ?cursor=<value read from the previous response>
+	paginationLink   = "link"   // follows the rel="next" URL of the Link header
+)
+
+// paginationConfig is the "pagination" key of a std/http_request config:
+//
+//	{"type": "cursor", "items": "data.results", "cursorPath": "meta.next", "param": "after"}
+type paginationConfig struct {
+	Type string `json:"type"`
+	// Items is the dotted path to the array of items in each response body;
+	// empty means the body itself is the array.
+	Items string `json:"items"`
+	// Param is the query parameter carrying the page number, offset or cursor.
+	// Defaults to the strategy name.
+	Param string `json:"param"`
+	// Start is the first page number (default 1) or offset (default 0).
+	Start *int `json:"start"`
+	// Limit, if set, is sent as LimitParam (default "limit") on every request, and a
+	// page with fewer items ends page and offset pagination.
+	Limit      int    `json:"limit"`
+	LimitParam string `json:"limitParam"`
+	// CursorPath is the dotted path to the next cursor in each response body.
+	CursorPath string `json:"cursorPath"`
+	MaxPages   int    `json:"maxPages"`
+}
+
+// httpResponse is the output of one request, matching the Bun block.
+type httpResponse struct {
+	Status     int               `json:"status"`
+	StatusText string            `json:"statusText"`
+	Headers    map[string]string `json:"headers"`
+	Data       interface{}       `json:"data"`
+}
+
+// executeHTTPPaginated runs a std/http_request node whose config has a "pagination"
+// key: it requests page after page in the engine and outputs the items of all pages
+// concatenated as data, with the status and headers of the last response. A non-2xx
+// page stops the loop and is output as is. Requests without pagination run in the
+// Bun block.
+func executeHTTPPaginated(ctx context.Context, input any) (any, error) {
+	payload, _ := input.(map[string]interface{})
+	config, _ := payload["config"].(map[string]interface{})
+	if config["pagination"] == nil {
+		return nil, errNotNative
+	}
+
+	rawURL, _ := config["url"].(string)
+	if rawURL == "" {
+		return nil, fmt.Errorf("http_request: missing required config: url")
+	}
+	var p paginationConfig
+	raw, _ := json.Marshal(config["pagination"])
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, fmt.Errorf("http_request: invalid pagination config: %w", err)
+	}
+	if err := p.normalize(); err != nil {
+		return nil, err
+	}
+	policy, err := parseRetryPolicy(config)
+	if err != nil {
+		return nil, err
+	}
+	// Each page is retried on its own, so a transient error doesn't restart the listing
+	fetch := func(u string) (*httpResponse, error) {
+		if policy == nil {
+			return doHTTPRequest(ctx, config, u)
+		}
+		resp, _, err := withRetry(ctx, policy, func() (*httpResponse, int, map[string]string, error) {
+			resp, err := doHTTPRequest(ctx, config, u)
+			if err != nil {
+				return nil, 0, nil, err
+			}
+			return resp, resp.Status, resp.Headers, nil
+		})
+		return resp, err
+	}
+
+	next, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("http_request: invalid url: %w", err)
+	}
+	position := *p.Start
+	if p.Type == paginationPage || p.Type == paginationOffset {
+		next = withQuery(next, p.Param, strconv.Itoa(position))
+	}
+	if p.Limit > 0 {
+		next = withQuery(next, p.LimitParam, strconv.Itoa(p.Limit))
+	}
+
+	items := []interface{}{}
+	var resp *httpResponse
+	pages := 0
+	for next != nil && pages < p.MaxPages {
+		resp, err = fetch(next.String())
+		if err != nil {
+			return nil, fmt.Errorf("http_request: %w", err)
+		}
+		pages++
+		if resp.Status < 200 || resp.Status >= 300 {
+			return paginatedResult(resp, resp.Data, pages), nil
+		}
+
+		page, err := lookupPath(resp.Data, p.Items)
+		if err != nil {
+			return nil, fmt.Errorf("http_request: pagination items: %w", err)
+		}
+		pageItems, ok := page.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("http_request: pagination items %q is not an array", p.Items)
+		}
+		items = append(items, pageItems...)
+
+		current := next
+		next = nil
+		switch p.Type {
+		case paginationPage, paginationOffset:
+			// An empty or short page is the last one
+			if len(pageItems) == 0 || (p.Limit > 0 && len(pageItems) < p.Limit) {
+				continue
+			}
+			if p.Type == paginationPage {
+				position++
+			} else {
+				position += len(pageItems)
+			}
+			next = withQuery(current, p.Param, strconv.Itoa(position))
+		case paginationCursor:
+			if cursor, _ := lookupPath(resp.Data, p.CursorPath); cursor != nil && cursor != "" {
+				next = withQuery(current, p.Param, fmt.Sprint(cursor))
+			}
+		case paginationLink:
+			if link := nextLink(resp.Headers["link"]); link != "" {
+				if next, err = current.Parse(link); err != nil {
+					return nil, fmt.Errorf("http_request: invalid Link header: %w", err)
+				}
+			}
+		}
+	}
+	return paginatedResult(resp, items, pages), nil
+}
+
+// normalize checks the strategy and fills in defaults.
+func (p *paginationConfig) normalize() error {
+	switch p.Type {
+	case paginationPage, paginationOffset, paginationCursor, paginationLink:
+	default:
+		return fmt.Errorf("http_request: pagination 'type' must be 'page', 'offset', 'cursor' or 'link'")
+	}
+	if p.Type == paginationCursor && p.CursorPath == "" {
+		return fmt.Errorf("http_request: cursor pagination requires 'cursorPath'")
+	}
+	if p.Param == "" {
+		p.Param = p.Type
+	}
+	if p.Start == nil {
+		start := 0
+		if p.Type == paginationPage {
+			start = 1
+		}
+		p.Start = &start
+	}
+	if p.LimitParam == "" {
+		p.LimitParam = "limit"
+	}
+	if p.MaxPages <= 0 {
+		p.MaxPages = defaultMaxPages
+	}
+	return nil
+}
+
+// doHTTPRequest sends one request built from a std/http_request config to rawURL.
+func doHTTPRequest(ctx context.Context, config map[string]interface{}, rawURL string) (*httpResponse, error) {
+	method, _ := config["method"].(string)
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if b, ok := config["body"]; ok && b != nil {
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode body: %w", err)
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return nil, err
+	}
+	headers, _ := config["headers"].(map[string]interface{})
+	for k, v := range headers {
+		req.Header.Set(k, fmt.Sprint(v))
+	}
+
+	usageFrom(ctx).addHTTPCall()
+	res, err := httpClient(ctx).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	text, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	resp := &httpResponse{
+		Status:     res.StatusCode,
+		StatusText: strings.TrimPrefix(res.Status, strconv.Itoa(res.StatusCode)+" "),
+		Headers:    make(map[string]string, len(res.Header)),
+	}
+	for k, v := range res.Header {
+		resp.Headers[strings.ToLower(k)] = strings.Join(v, ", ")
+	}
+	// Fall back to raw text if not valid JSON, as the Bun block does
+	if err := json.Unmarshal(text, &resp.Data); err != nil {
+		resp.Data = string(text)
+	}
+	return resp, nil
+}
+
+// paginatedResult builds the node output, routed by status like the Bun block.
+func paginatedResult(resp *httpResponse, data interface{}, pages int) map[string]interface{} {
+	return map[string]interface{}{
+		"data": map[string]interface{}{
+			"status":     resp.Status,
+			"statusText": resp.StatusText,
+			"headers":    resp.Headers,
+			"data":       data,
+			"pages":      pages,
+		},
+		"port": httpPort(resp.Status),
+	}
+}
+
+// httpPort returns the port a std/http_request node routes a response with
+// status to, like the Bun block.
+func httpPort(status int) string {
+	switch {
+	case status >= 200 && status < 300:
+		return "success"
+	case status >= 400 && status < 500:
+		return "client_error"
+	case status >= 500:
+		return "server_error"
+	}
+	return "default"
+}
+
+// withQuery returns a copy of u with the query parameter key set to value.
+func withQuery(u *url.URL, key, value string) *url.URL {
+	next := *u
+	q := next.Query()
+	q.Set(key, value)
+	next.RawQuery = q.Encode()
+	return &next
+}
+
+// lookupPath follows a dotted path ("meta.next", "items.0.id") into decoded JSON.
+func lookupPath(v interface{}, path string) (interface{}, error) {
+	if path == "" {
+		return v, nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			v = node[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("invalid index %q in path %q", key, path)
+			}
+			v = node[i]
+		default:
+			return nil, nil
+		}
+	}
+	return v, nil
+}
+
+var linkNextPattern = regexp.MustCompile(`<([^>]*)>\s*;[^,]*\brel="?next"?`)
+
+// nextLink returns the rel="next" URL of an RFC 8288 Link header, or "".
+func nextLink(header string) string {
+	if m := linkNextPattern.FindStringSubmatch(header); m != nil {
+		return m[1]
+	}
+	return ""
+}
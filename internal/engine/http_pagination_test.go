@@ -0,0 +1,175 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// paginatedAPI serves items 1..total in pages, via page/offset/limit query
+// parameters, a "next" cursor in the body, or a Link header.
+func paginatedAPI(t *testing.T, total int) *httptest.Server {
+	t.Helper()
+	page := func(from, size int) []int {
+		items := []int{}
+		for i := from; i < from+size && i <= total; i++ {
+			items = append(items, i)
+		}
+		return items
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pages", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": page((n-1)*limit+1, limit)})
+	})
+	mux.HandleFunc("/offset", func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		json.NewEncoder(w).Encode(page(offset+1, 2))
+	})
+	mux.HandleFunc("/cursor", func(w http.ResponseWriter, r *http.Request) {
+		from := 1
+		if c := r.URL.Query().Get("after"); c != "" {
+			from, _ = strconv.Atoi(c)
+		}
+		next := interface{}(nil)
+		if from+2 <= total {
+			next = strconv.Itoa(from + 2)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": page(from, 2),
+			"meta": map[string]interface{}{"next": next},
+		})
+	})
+	mux.HandleFunc("/link", func(w http.ResponseWriter, r *http.Request) {
+		from := 1
+		if p := r.URL.Query().Get("from"); p != "" {
+			from, _ = strconv.Atoi(p)
+		}
+		if from+2 <= total {
+			w.Header().Set("Link", fmt.Sprintf(`</link?from=%d>; rel="next", </link?from=%d>; rel="last"`, from+2, total))
+		}
+		json.NewEncoder(w).Encode(page(from, 2))
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			http.Error(w, `{"error":"rate limited"}`, http.StatusTooManyRequests)
+			return
+		}
+		json.NewEncoder(w).Encode([]int{1, 2})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestBunRunner_ExecuteNode_HTTPPagination verifies that http_request nodes with
+// a pagination config loop over pages in the engine and output all items.
+func TestBunRunner_ExecuteNode_HTTPPagination(t *testing.T) {
+	srv := paginatedAPI(t, 5)
+	runner := engine.NewBunRunner(t.TempDir())
+	runner.RuntimePath = "/nonexistent/bun" // any process start would fail
+	node := &engine.Node{ID: "list", Type: engine.NodeTypeHTTPRequest}
+
+	tests := []struct {
+		name       string
+		path       string
+		pagination map[string]interface{}
+		wantItems  string
+		wantPages  int
+	}{
+		{
+			name:       "page",
+			path:       "/pages",
+			pagination: map[string]interface{}{"type": "page", "items": "items", "limit": 2, "limitParam": "per_page"},
+			wantItems:  "[1,2,3,4,5]",
+			wantPages:  3,
+		},
+		{
+			name:       "offset",
+			path:       "/offset",
+			pagination: map[string]interface{}{"type": "offset"},
+			wantItems:  "[1,2,3,4,5]",
+			wantPages:  4, // the last page is empty
+		},
+		{
+			name:       "cursor",
+			path:       "/cursor",
+			pagination: map[string]interface{}{"type": "cursor", "items": "data", "cursorPath": "meta.next", "param": "after"},
+			wantItems:  "[1,2,3,4,5]",
+			wantPages:  3,
+		},
+		{
+			name:       "link",
+			path:       "/link",
+			pagination: map[string]interface{}{"type": "link"},
+			wantItems:  "[1,2,3,4,5]",
+			wantPages:  3,
+		},
+		{
+			name:       "max pages",
+			path:       "/link",
+			pagination: map[string]interface{}{"type": "link", "maxPages": 2},
+			wantItems:  "[1,2,3,4]",
+			wantPages:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := map[string]interface{}{"config": map[string]interface{}{
+				"url":        srv.URL + tt.path,
+				"pagination": tt.pagination,
+			}}
+			raw, err := runner.ExecuteNode(context.Background(), node, input)
+			if err != nil {
+				t.Fatalf("ExecuteNode failed: %v", err)
+			}
+			result := raw.(map[string]interface{})
+			if result["port"] != "success" {
+				t.Errorf("expected port success, got %v", result["port"])
+			}
+			data := result["data"].(map[string]interface{})
+			items, _ := json.Marshal(data["data"])
+			if string(items) != tt.wantItems {
+				t.Errorf("expected items %s, got %s", tt.wantItems, items)
+			}
+			if data["pages"] != tt.wantPages {
+				t.Errorf("expected %v pages, got %v", tt.wantPages, data["pages"])
+			}
+		})
+	}
+
+	t.Run("error page", func(t *testing.T) {
+		input := map[string]interface{}{"config": map[string]interface{}{
+			"url":        srv.URL + "/broken",
+			"pagination": map[string]interface{}{"type": "page"},
+		}}
+		raw, err := runner.ExecuteNode(context.Background(), node, input)
+		if err != nil {
+			t.Fatalf("ExecuteNode failed: %v", err)
+		}
+		result := raw.(map[string]interface{})
+		if result["port"] != "client_error" {
+			t.Errorf("expected port client_error, got %v", result["port"])
+		}
+		if status := result["data"].(map[string]interface{})["status"]; status != http.StatusTooManyRequests {
+			t.Errorf("expected status 429, got %v", status)
+		}
+	})
+
+	t.Run("invalid strategy", func(t *testing.T) {
+		input := map[string]interface{}{"config": map[string]interface{}{
+			"url":        srv.URL + "/pages",
+			"pagination": map[string]interface{}{"type": "scroll"},
+		}}
+		if _, err := runner.ExecuteNode(context.Background(), node, input); err == nil {
+			t.Error("expected an unknown pagination type to fail")
+		}
+	})
+}
@@ -0,0 +1,191 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Retry defaults for std/http_request nodes with a "retry" config.
+const (
+	defaultRetryAttempts     = 3
+	defaultRetryInitialDelay = time.Second
+	defaultRetryMaxDelay     = 30 * time.Second
+)
+
+// retryConfig is the "retry" key of a std/http_request config:
+//
+//	{"maxAttempts": 5, "initialDelay": 500, "maxDelay": 10000, "statuses": [429, 503]}
+//
+// Delays are in milliseconds.
+type retryConfig struct {
+	MaxAttempts  int   `json:"maxAttempts"`
+	InitialDelay int   `json:"initialDelay"`
+	MaxDelay     int   `json:"maxDelay"`
+	Statuses     []int `json:"statuses"` // defaults to 429 and 5xx
+}
+
+// retryPolicy decides whether and when a failed HTTP response is retried.
+type retryPolicy struct {
+	maxAttempts  int
+	initialDelay time.Duration
+	maxDelay     time.Duration
+	statuses     map[int]bool
+}
+
+// parseRetryPolicy reads the retry config of a std/http_request node; it returns
+// nil when the node has none.
+func parseRetryPolicy(config map[string]interface{}) (*retryPolicy, error) {
+	if config["retry"] == nil {
+		return nil, nil
+	}
+	var c retryConfig
+	raw, _ := json.Marshal(config["retry"])
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, fmt.Errorf("http_request: invalid retry config: %w", err)
+	}
+	if c.MaxAttempts < 0 || c.InitialDelay < 0 || c.MaxDelay < 0 {
+		return nil, fmt.Errorf("http_request: retry config values must be non-negative")
+	}
+
+	p := &retryPolicy{
+		maxAttempts:  c.MaxAttempts,
+		initialDelay: time.Duration(c.InitialDelay) * time.Millisecond,
+		maxDelay:     time.Duration(c.MaxDelay) * time.Millisecond,
+	}
+	if p.maxAttempts == 0 {
+		p.maxAttempts = defaultRetryAttempts
+	}
+	if c.InitialDelay == 0 {
+		p.initialDelay = defaultRetryInitialDelay
+	}
+	if c.MaxDelay == 0 {
+		p.maxDelay = defaultRetryMaxDelay
+	}
+	if len(c.Statuses) > 0 {
+		p.statuses = make(map[int]bool, len(c.Statuses))
+		for _, s := range c.Statuses {
+			p.statuses[s] = true
+		}
+	}
+	return p, nil
+}
+
+func (p *retryPolicy) retryable(status int) bool {
+	if p.statuses != nil {
+		return p.statuses[status]
+	}
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// backoff returns how long to wait before attempt+1 after a response with status
+// and headers, and false when it should not be retried. A Retry-After header takes
+// precedence over exponential backoff; one asking to wait longer than maxDelay
+// gives up instead of retrying early.
+func (p *retryPolicy) backoff(attempt, status int, headers map[string]string) (time.Duration, bool) {
+	if attempt >= p.maxAttempts || !p.retryable(status) {
+		return 0, false
+	}
+	if wait, ok := retryAfter(headers["retry-after"]); ok {
+		return wait, wait <= p.maxDelay
+	}
+	wait := p.initialDelay << (attempt - 1)
+	if wait > p.maxDelay || wait <= 0 {
+		wait = p.maxDelay
+	}
+	return wait, true
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(header string) (time.Duration, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(header); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// withRetry calls do until it returns a response the policy does not retry,
+// waiting between attempts. It reports the number of attempts made.
+func withRetry[T any](ctx context.Context, p *retryPolicy, do func() (T, int, map[string]string, error)) (T, int, error) {
+	for attempt := 1; ; attempt++ {
+		resp, status, headers, err := do()
+		if err != nil {
+			return resp, attempt, err
+		}
+		wait, retry := p.backoff(attempt, status, headers)
+		if !retry {
+			return resp, attempt, nil
+		}
+
+		log.Printf("http_request: got status %d, retrying in %s (attempt %d of %d)", status, wait, attempt+1, p.maxAttempts)
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, attempt, ctx.Err()
+		}
+	}
+}
+
+// executeHTTPRequest runs the std/http_request Bun block, retrying responses
+// the node's retry config allows.
+func (r *BunRunner) executeHTTPRequest(ctx context.Context, scriptPath string, input any) (any, error) {
+	payload, _ := input.(map[string]interface{})
+	config, _ := payload["config"].(map[string]interface{})
+	policy, err := parseRetryPolicy(config)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		usageFrom(ctx).addHTTPCall()
+		return r.Execute(ctx, scriptPath, input)
+	}
+
+	result, attempts, err := withRetry(ctx, policy, func() (any, int, map[string]string, error) {
+		usageFrom(ctx).addHTTPCall()
+		raw, err := r.Execute(ctx, scriptPath, input)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		status, headers := blockHTTPStatus(raw)
+		return raw, status, headers, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resMap, ok := result.(map[string]interface{}); ok {
+		if data, ok := resMap["data"].(map[string]interface{}); ok {
+			data["attempts"] = attempts
+		}
+	}
+	return result, nil
+}
+
+// blockHTTPStatus reads the status and headers from the output of the Bun block.
+func blockHTTPStatus(raw any) (int, map[string]string) {
+	resMap, _ := raw.(map[string]interface{})
+	data, _ := resMap["data"].(map[string]interface{})
+	status, _ := data["status"].(float64)
+	headers := map[string]string{}
+	if h, ok := data["headers"].(map[string]interface{}); ok {
+		for k, v := range h {
+			if s, ok := v.(string); ok {
+				headers[strings.ToLower(k)] = s
+			}
+		}
+	}
+	return int(status), headers
+}
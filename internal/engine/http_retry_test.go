@@ -0,0 +1,118 @@
+package engine_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// TestBunRunner_ExecuteNode_HTTPRetry verifies that http_request nodes with a
+// retry config are re-run by the engine on retryable statuses.
+func TestBunRunner_ExecuteNode_HTTPRetry(t *testing.T) {
+	installFakeBun(t)
+	blocksDir := t.TempDir()
+	counter := filepath.Join(t.TempDir(), "calls")
+	// Answers 503 (with Retry-After: 0) twice, then 200
+	writeBlock(t, blocksDir, "std/http_request.ts", fmt.Sprintf(`cat >/dev/null
+echo x >> %[1]s
+if [ $(wc -l < %[1]s) -le 2 ]; then
+  echo '{"data":{"status":503,"headers":{"retry-after":"0"},"data":"busy"},"port":"server_error"}'
+else
+  echo '{"data":{"status":200,"headers":{},"data":"ok"},"port":"success"}'
+fi
+`, counter))
+	runner := engine.NewBunRunner(blocksDir)
+	node := &engine.Node{ID: "call", Type: engine.NodeTypeHTTPRequest}
+
+	input := map[string]interface{}{"config": map[string]interface{}{
+		"url":   "http://example.invalid",
+		"retry": map[string]interface{}{"maxAttempts": 3.0},
+	}}
+	raw, err := runner.ExecuteNode(context.Background(), node, input)
+	if err != nil {
+		t.Fatalf("ExecuteNode failed: %v", err)
+	}
+	result := raw.(map[string]interface{})
+	if result["port"] != "success" {
+		t.Errorf("expected port success after retries, got %v", result["port"])
+	}
+	if attempts := result["data"].(map[string]interface{})["attempts"]; attempts != 3 {
+		t.Errorf("expected 3 attempts, got %v", attempts)
+	}
+
+	// Without a retry config the first response is final
+	input = map[string]interface{}{"config": map[string]interface{}{"url": "http://example.invalid"}}
+	raw, err = runner.ExecuteNode(context.Background(), node, input)
+	if err != nil {
+		t.Fatalf("ExecuteNode failed: %v", err)
+	}
+	if port := raw.(map[string]interface{})["port"]; port != "success" {
+		t.Errorf("expected the fourth call to succeed, got %v", port)
+	}
+}
+
+// TestBunRunner_ExecuteNode_HTTPRetryPaginated verifies per-page retries of
+// paginated requests, and that non-retryable statuses and Retry-After values
+// beyond maxDelay are returned as is.
+func TestBunRunner_ExecuteNode_HTTPRetryPaginated(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/flaky":
+			// The second page fails once
+			if r.URL.Query().Get("page") == "2" && calls.Add(1) == 1 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			if r.URL.Query().Get("page") == "3" {
+				fmt.Fprint(w, "[]")
+				return
+			}
+			fmt.Fprint(w, "[1]")
+		case "/slow":
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	runner := engine.NewBunRunner(t.TempDir())
+	runner.RuntimePath = "/nonexistent/bun" // any process start would fail
+	node := &engine.Node{ID: "list", Type: engine.NodeTypeHTTPRequest}
+	run := func(path string) map[string]interface{} {
+		t.Helper()
+		input := map[string]interface{}{"config": map[string]interface{}{
+			"url":        srv.URL + path,
+			"pagination": map[string]interface{}{"type": "page"},
+			"retry":      map[string]interface{}{"initialDelay": 1.0, "maxDelay": 1000.0},
+		}}
+		raw, err := runner.ExecuteNode(context.Background(), node, input)
+		if err != nil {
+			t.Fatalf("ExecuteNode failed: %v", err)
+		}
+		return raw.(map[string]interface{})
+	}
+
+	result := run("/flaky")
+	if result["port"] != "success" {
+		t.Fatalf("expected port success, got %v", result["port"])
+	}
+	if pages := result["data"].(map[string]interface{})["pages"]; pages != 3 {
+		t.Errorf("expected 3 pages, got %v", pages)
+	}
+
+	if port := run("/slow")["port"]; port != "server_error" {
+		t.Errorf("expected a Retry-After beyond maxDelay to give up, got %v", port)
+	}
+	if port := run("/missing")["port"]; port != "client_error" {
+		t.Errorf("expected 404 not to be retried, got %v", port)
+	}
+}
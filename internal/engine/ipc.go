@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// The engine talks to script runtimes (Bun today) over newline-delimited
+// JSON-RPC 2.0 on the process's stdin and stdout; sdk/PROTOCOL.md documents the
+// contract for runtimes written outside this repo.
+//
+// A trigger process is long-running: the host opens it with an initialize
+// request, which negotiates the protocol version, then sends invoke and
+// shutdown notifications, while the runtime sends fire requests and log
+// notifications. A block process is one-shot: it gets its input as plain JSON
+// and may answer with a JSON-RPC response instead of a bare result.
+const jsonRPCVersion = "2.0"
+
+// IPCProtocolVersions are the versions of the conv3n method set the engine
+// speaks, newest first.
+var IPCProtocolVersions = []int{1}
+
+// IPC methods.
+const (
+	RPCMethodInitialize = "initialize" // host → trigger request: {protocolVersions, config} → {protocolVersion}
+	RPCMethodInvoke     = "invoke"     // host → trigger notification: {payload, idempotencyKey}
+	RPCMethodShutdown   = "shutdown"   // host → trigger notification
+	RPCMethodFire       = "fire"       // trigger → host request: {payload} → {}
+	RPCMethodLog        = "log"        // trigger → host notification: {level, message, stack}
+)
+
+// JSON-RPC error codes; -32000 to -32099 are left to the application.
+const (
+	RPCParseError     = -32700
+	RPCInvalidRequest = -32600
+	RPCMethodNotFound = -32601
+	RPCInvalidParams  = -32602
+	RPCInternalError  = -32603
+	RPCFireFailed     = -32000 // The triggered workflow could not be started
+)
+
+// RPCMessage is a JSON-RPC 2.0 request, notification or response. Requests
+// have a Method and an ID, notifications a Method only, responses no Method.
+type RPCMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError is the error object of a JSON-RPC response.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// IsNotification reports whether the message is a request that takes no response.
+func (m *RPCMessage) IsNotification() bool { return m.Method != "" && m.ID == nil }
+
+// IsResponse reports whether the message answers a request.
+func (m *RPCMessage) IsResponse() bool { return m.Method == "" }
+
+// ParseRPCMessage decodes one line of IPC output.
+func ParseRPCMessage(line []byte) (*RPCMessage, error) {
+	var msg RPCMessage
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return nil, err
+	}
+	if msg.JSONRPC != jsonRPCVersion {
+		return nil, fmt.Errorf("not a JSON-RPC %s message", jsonRPCVersion)
+	}
+	if msg.IsResponse() && (msg.Result == nil) == (msg.Error == nil) {
+		return nil, fmt.Errorf("response must have either a result or an error")
+	}
+	return &msg, nil
+}
+
+// newRPCRequest builds a request, or a notification when id is nil.
+func newRPCRequest(id *int64, method string, params interface{}) (*RPCMessage, error) {
+	msg := &RPCMessage{JSONRPC: jsonRPCVersion, Method: method}
+	if id != nil {
+		msg.ID = json.RawMessage(fmt.Sprint(*id))
+	}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s params: %w", method, err)
+		}
+		msg.Params = data
+	}
+	return msg, nil
+}
+
+// newRPCResponse answers the request with id with result, or with rpcErr if set.
+func newRPCResponse(id json.RawMessage, result interface{}, rpcErr *RPCError) *RPCMessage {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	msg := &RPCMessage{JSONRPC: jsonRPCVersion, ID: id, Error: rpcErr}
+	if rpcErr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			msg.Error = &RPCError{Code: RPCInternalError, Message: err.Error()}
+		} else {
+			msg.Result = data
+		}
+	}
+	return msg
+}
+
+// negotiateProtocol checks the initialize result of a runtime against the
+// versions the engine speaks.
+func negotiateProtocol(result json.RawMessage) (int, error) {
+	var init struct {
+		ProtocolVersion int `json:"protocolVersion"`
+	}
+	if err := json.Unmarshal(result, &init); err != nil {
+		return 0, fmt.Errorf("invalid initialize result: %w", err)
+	}
+	if !slices.Contains(IPCProtocolVersions, init.ProtocolVersion) {
+		return 0, fmt.Errorf("unsupported protocol version %d, the engine speaks %v", init.ProtocolVersion, IPCProtocolVersions)
+	}
+	return init.ProtocolVersion, nil
+}
+
+// unwrapRPCResult returns the result of a block whose output is a JSON-RPC
+// response, or output itself for blocks that print a bare result.
+func unwrapRPCResult(output any) (any, error) {
+	obj, ok := output.(map[string]interface{})
+	if !ok || obj["jsonrpc"] != jsonRPCVersion {
+		return output, nil
+	}
+	if rawErr, ok := obj["error"]; ok && rawErr != nil {
+		data, _ := json.Marshal(rawErr)
+		var rpcErr RPCError
+		if err := json.Unmarshal(data, &rpcErr); err != nil {
+			return nil, fmt.Errorf("invalid error in block response: %w", err)
+		}
+		return nil, &rpcErr
+	}
+	return obj["result"], nil
+}
@@ -0,0 +1,166 @@
+package engine_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// fireRecorder is a ManagerForRunner that records the payloads triggers fire.
+type fireRecorder struct {
+	mu       sync.Mutex
+	payloads []map[string]interface{}
+	keys     map[string]bool
+}
+
+func (f *fireRecorder) FireOnce(ctx context.Context, triggerID, key string, payload map[string]interface{}) (string, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if key != "" {
+		if f.keys[key] {
+			return "exec-" + key, true, nil
+		}
+		if f.keys == nil {
+			f.keys = make(map[string]bool)
+		}
+		f.keys[key] = true
+	}
+	f.payloads = append(f.payloads, payload)
+	return "", false, nil
+}
+func (f *fireRecorder) GetTrigger(triggerID string) (engine.TriggerRunner, bool) { return nil, false }
+func (f *fireRecorder) Register(trigger engine.TriggerRunner) error              { return nil }
+func (f *fireRecorder) Unregister(triggerID string) error                        { return nil }
+func (f *fireRecorder) Events() *engine.EventBus                                 { return nil }
+func (f *fireRecorder) ReportCrash(triggerID string, reason string)              {}
+func (f *fireRecorder) ReportStopped(triggerID string)                           {}
+
+// writeTrigger writes a shell "trigger" run by the fake bun; what the host
+// sends it after initialize is appended to the returned file.
+func writeTrigger(t *testing.T, initResult, body string) (script, received string) {
+	t.Helper()
+	dir := t.TempDir()
+	received = filepath.Join(dir, "received")
+	content := "read request\n" +
+		"echo '{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":" + initResult + "}'\n" +
+		body +
+		"while read line; do echo \"$line\" >> " + received + "; done\n"
+	script = filepath.Join(dir, "trigger.sh")
+	if err := os.WriteFile(script, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write trigger: %v", err)
+	}
+	return script, received
+}
+
+func TestTSTriggerRunner_JSONRPC(t *testing.T) {
+	installFakeBun(t)
+
+	t.Run("FireAndUnknownMethod", func(t *testing.T) {
+		script, received := writeTrigger(t, `{"protocolVersion":1}`,
+			"echo '{\"jsonrpc\":\"2.0\",\"id\":\"f1\",\"method\":\"fire\",\"params\":{\"payload\":{\"n\":1}}}'\n"+
+				"echo 'plain console output'\n"+
+				"echo '{\"jsonrpc\":\"2.0\",\"id\":\"f2\",\"method\":\"fire\",\"params\":{\"payload\":{\"n\":2},\"idempotencyKey\":\"m1\"}}'\n"+
+				"sleep 0.2\n"+
+				"echo '{\"jsonrpc\":\"2.0\",\"id\":\"f3\",\"method\":\"fire\",\"params\":{\"payload\":{\"n\":2},\"idempotencyKey\":\"m1\"}}'\n"+
+				"echo '{\"jsonrpc\":\"2.0\",\"id\":\"u1\",\"method\":\"bogus\"}'\n")
+		manager := &fireRecorder{}
+		runner := engine.NewTSTriggerRunner("tr-rpc", "wf-1", script, nil, manager)
+		if err := runner.Start(context.Background()); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		defer runner.Stop()
+		if err := runner.Invoke(context.Background(), map[string]interface{}{"hello": "world"}); err != nil {
+			t.Fatalf("Invoke failed: %v", err)
+		}
+
+		want := []string{
+			`{"jsonrpc":"2.0","id":"f1","result":{}}`,
+			`{"jsonrpc":"2.0","id":"f2","result":{}}`,
+			`{"jsonrpc":"2.0","id":"f3","result":{"duplicate":true,"executionId":"exec-m1"}}`,
+			`{"jsonrpc":"2.0","id":"u1","error":{"code":-32601,"message":"method not found: bogus"}}`,
+			`{"jsonrpc":"2.0","method":"invoke","params":{"payload":{"hello":"world"}}}`,
+		}
+		var got string
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			data, _ := os.ReadFile(received)
+			got = string(data)
+			if strings.Count(got, "\n") >= len(want) {
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		for _, line := range want {
+			if !strings.Contains(got, line) {
+				t.Errorf("expected the trigger to receive %s, got:\n%s", line, got)
+			}
+		}
+		manager.mu.Lock()
+		defer manager.mu.Unlock()
+		// The duplicate delivery of m1 isn't fired again
+		if len(manager.payloads) != 2 || manager.payloads[0]["n"] == manager.payloads[1]["n"] {
+			t.Errorf("expected fires with n=1 and n=2, got %v", manager.payloads)
+		}
+	})
+
+	t.Run("UnsupportedProtocolVersion", func(t *testing.T) {
+		script, _ := writeTrigger(t, `{"protocolVersion":99}`, "")
+		runner := engine.NewTSTriggerRunner("tr-old", "wf-1", script, nil, &fireRecorder{})
+		err := runner.Start(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "unsupported protocol version 99") {
+			t.Errorf("expected a protocol version error, got %v", err)
+		}
+	})
+}
+
+func TestBunRunner_JSONRPCResponse(t *testing.T) {
+	installFakeBun(t)
+	runner := engine.NewBunRunner(t.TempDir())
+
+	ok := writeScript(t, `cat > /dev/null
+echo '{"jsonrpc":"2.0","id":null,"result":{"data":{"sum":3},"port":"default"}}'`)
+	result, err := runner.Execute(context.Background(), ok, map[string]interface{}{"a": 1})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	data, _ := result.(map[string]interface{})["data"].(map[string]interface{})
+	if data["sum"] != float64(3) {
+		t.Errorf("expected the unwrapped result, got %v", result)
+	}
+
+	failing := writeScript(t, `cat > /dev/null
+echo '{"jsonrpc":"2.0","id":null,"error":{"code":-32000,"message":"quota exceeded"}}'
+exit 1`)
+	_, err = runner.Execute(context.Background(), failing, map[string]interface{}{})
+	var rpcErr *engine.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Message != "quota exceeded" || rpcErr.Code != -32000 {
+		t.Errorf("expected the block's error object, got %v", err)
+	}
+}
+
+func TestParseRPCMessage(t *testing.T) {
+	for _, tc := range []struct {
+		line    string
+		wantErr bool
+	}{
+		{`{"jsonrpc":"2.0","id":1,"method":"fire","params":{}}`, false},
+		{`{"jsonrpc":"2.0","method":"log","params":{"message":"hi"}}`, false},
+		{`{"jsonrpc":"2.0","id":1,"result":null}`, false},
+		{`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"nope"}}`, false},
+		{`{"jsonrpc":"2.0","id":1}`, true},           // neither result nor error
+		{`{"type":"status","status":"ready"}`, true}, // the old protocol
+		{`not json`, true},
+	} {
+		_, err := engine.ParseRPCMessage([]byte(tc.line))
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tc.line, tc.wantErr, err)
+		}
+	}
+}
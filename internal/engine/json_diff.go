@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Change kinds of a node or field in a diff of node outputs.
+const (
+	DiffAdded     = "added"     // only in the second output
+	DiffRemoved   = "removed"   // only in the first output
+	DiffChanged   = "changed"   // in both, with different values
+	DiffUnchanged = "unchanged" // in both, equal
+)
+
+// FieldChange is one differing field of a node output. Path addresses it in the
+// stored result, e.g. "data.body.items[2].price" or "port".
+type FieldChange struct {
+	Path   string          `json:"path"`
+	Change string          `json:"change"`
+	A      json.RawMessage `json:"a,omitempty"`
+	B      json.RawMessage `json:"b,omitempty"`
+}
+
+// DiffJSON returns the fields that differ between two JSON documents. Output
+// that isn't valid JSON is compared as a whole.
+func DiffJSON(a, b []byte) []FieldChange {
+	va, errA := decodeJSON(a)
+	vb, errB := decodeJSON(b)
+	if errA != nil || errB != nil {
+		if bytes.Equal(a, b) {
+			return nil
+		}
+		return []FieldChange{{Change: DiffChanged, A: rawJSON(string(a)), B: rawJSON(string(b))}}
+	}
+	var changes []FieldChange
+	diffValues("", va, vb, &changes)
+	return changes
+}
+
+// decodeJSON decodes data keeping numbers exact, so 0.1 and 0.10000000000000001 differ.
+func decodeJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	err := dec.Decode(&v)
+	return v, err
+}
+
+func diffValues(path string, a, b interface{}, changes *[]FieldChange) {
+	switch va := a.(type) {
+	case map[string]interface{}:
+		if vb, ok := b.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(va)+len(vb))
+			for k := range va {
+				keys = append(keys, k)
+			}
+			for k := range vb {
+				if _, ok := va[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				diffMember(joinPath(path, k), va, vb, k, changes)
+			}
+			return
+		}
+	case []interface{}:
+		if vb, ok := b.([]interface{}); ok {
+			for i := 0; i < max(len(va), len(vb)); i++ {
+				elemPath := fmt.Sprintf("%s[%d]", path, i)
+				switch {
+				case i >= len(vb):
+					*changes = append(*changes, FieldChange{Path: elemPath, Change: DiffRemoved, A: rawJSON(va[i])})
+				case i >= len(va):
+					*changes = append(*changes, FieldChange{Path: elemPath, Change: DiffAdded, B: rawJSON(vb[i])})
+				default:
+					diffValues(elemPath, va[i], vb[i], changes)
+				}
+			}
+			return
+		}
+	default:
+		if a == b {
+			return
+		}
+	}
+	*changes = append(*changes, FieldChange{Path: path, Change: DiffChanged, A: rawJSON(a), B: rawJSON(b)})
+}
+
+// diffMember compares the field k of two objects.
+func diffMember(path string, a, b map[string]interface{}, k string, changes *[]FieldChange) {
+	va, inA := a[k]
+	vb, inB := b[k]
+	switch {
+	case !inB:
+		*changes = append(*changes, FieldChange{Path: path, Change: DiffRemoved, A: rawJSON(va)})
+	case !inA:
+		*changes = append(*changes, FieldChange{Path: path, Change: DiffAdded, B: rawJSON(vb)})
+	default:
+		diffValues(path, va, vb, changes)
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func rawJSON(v interface{}) json.RawMessage {
+	data, _ := json.Marshal(v)
+	return data
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"regexp"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// Limits on the labels of an execution.
+const (
+	maxLabels           = 20
+	maxLabelValueLen    = 256
+	maxCorrelationIDLen = 128
+)
+
+// labelKeyPattern is what a label key may look like, e.g. "order_id" or "team/billing".
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.\-/]{0,62}$`)
+
+// ExecutionLabels tag executions so that a business transaction can be traced
+// across systems: the correlation ID the caller's systems share, and free-form
+// key/value labels. Both are stored on the execution and can be searched for
+// (see storage.FindExecutions).
+type ExecutionLabels struct {
+	CorrelationID string
+	Labels        map[string]string
+}
+
+// Validate checks the correlation ID and labels, returning a *FieldError for
+// each problem found.
+func (l ExecutionLabels) Validate() error {
+	var errs []error
+	if len(l.CorrelationID) > maxCorrelationIDLen {
+		errs = append(errs, &FieldError{Field: "correlation_id", Message: fmt.Sprintf("must be at most %d characters", maxCorrelationIDLen)})
+	}
+	if len(l.Labels) > maxLabels {
+		errs = append(errs, &FieldError{Field: "labels", Message: fmt.Sprintf("at most %d labels are allowed", maxLabels)})
+	}
+	for key, value := range l.Labels {
+		switch {
+		case !labelKeyPattern.MatchString(key):
+			errs = append(errs, &FieldError{Field: "labels." + key, Message: "key must be 1-63 letters, digits or _.-/ starting with a letter or digit"})
+		case len(value) > maxLabelValueLen:
+			errs = append(errs, &FieldError{Field: "labels." + key, Message: fmt.Sprintf("value must be at most %d characters", maxLabelValueLen)})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type labelsKey struct{}
+
+// WithLabels returns a copy of ctx whose executions are tagged with l. Labels
+// add to those ctx carries already; a correlation ID replaces its own.
+func WithLabels(ctx context.Context, l ExecutionLabels) context.Context {
+	merged := LabelsFromContext(ctx)
+	if l.CorrelationID != "" {
+		merged.CorrelationID = l.CorrelationID
+	}
+	if len(l.Labels) > 0 {
+		if merged.Labels == nil {
+			merged.Labels = make(map[string]string, len(l.Labels))
+		}
+		maps.Copy(merged.Labels, l.Labels)
+	}
+	return context.WithValue(ctx, labelsKey{}, merged)
+}
+
+// LabelsFromContext returns the labels carried by ctx, a copy safe to change.
+func LabelsFromContext(ctx context.Context) ExecutionLabels {
+	l, _ := ctx.Value(labelsKey{}).(ExecutionLabels)
+	l.Labels = maps.Clone(l.Labels)
+	return l
+}
+
+// saveLabels records the labels carried by ctx on a new execution.
+func saveLabels(ctx context.Context, store storage.Storage, execID string) {
+	l := LabelsFromContext(ctx)
+	if l.CorrelationID == "" && len(l.Labels) == 0 {
+		return
+	}
+	if err := store.SetExecutionLabels(ctx, execID, l.CorrelationID, l.Labels); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func TestExecutionLabels_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels engine.ExecutionLabels
+		valid  bool
+	}{
+		{"empty", engine.ExecutionLabels{}, true},
+		{"valid", engine.ExecutionLabels{CorrelationID: "order-17", Labels: map[string]string{"team/billing": "yes", "order_id": "A-17"}}, true},
+		{"invalid key", engine.ExecutionLabels{Labels: map[string]string{"-team": "billing"}}, false},
+		{"key with space", engine.ExecutionLabels{Labels: map[string]string{"order id": "A-17"}}, false},
+		{"long value", engine.ExecutionLabels{Labels: map[string]string{"note": strings.Repeat("x", 257)}}, false},
+		{"long correlation ID", engine.ExecutionLabels{CorrelationID: strings.Repeat("x", 129)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.labels.Validate(); (err == nil) != tt.valid {
+				t.Errorf("expected valid=%v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestWithLabels_Merges(t *testing.T) {
+	ctx := engine.WithLabels(context.Background(), engine.ExecutionLabels{CorrelationID: "first", Labels: map[string]string{"team": "billing"}})
+	ctx = engine.WithLabels(ctx, engine.ExecutionLabels{Labels: map[string]string{"region": "eu"}})
+
+	got := engine.LabelsFromContext(ctx)
+	if got.CorrelationID != "first" || got.Labels["team"] != "billing" || got.Labels["region"] != "eu" {
+		t.Errorf("expected the labels to add up, got %+v", got)
+	}
+	got.Labels["team"] = "changed"
+	if engine.LabelsFromContext(ctx).Labels["team"] != "billing" {
+		t.Error("expected LabelsFromContext to return a copy")
+	}
+}
+
+// TestTriggerManager_FireLabels verifies that a fire tagged with labels records
+// them on its execution.
+func TestTriggerManager_FireLabels(t *testing.T) {
+	ctx := context.Background()
+	store := createTestStorage(t)
+	def, _ := json.Marshal(engine.Workflow{ID: "wf-labels", Nodes: map[string]engine.Node{
+		"mark": {ID: "mark", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "seen", "value": true}},
+	}})
+	if err := store.CreateWorkflow(ctx, &storage.Workflow{ID: "wf-labels", Name: "wf-labels", Definition: def}); err != nil {
+		t.Fatalf("failed to create workflow: %v", err)
+	}
+	if err := store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-labels", WorkflowID: "wf-labels", Type: "webhook", Config: []byte(`{}`), Enabled: true}); err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+	tm := engine.NewTriggerManager(store, t.TempDir(), nil, engine.NewWorkerPool(1))
+	defer tm.StopAll()
+	tm.Register(engine.NewWebhookTrigger("tr-labels", "wf-labels", tm))
+
+	fireCtx := engine.WithLabels(ctx, engine.ExecutionLabels{CorrelationID: "order-17", Labels: map[string]string{"team": "billing"}})
+	if _, err := tm.FireSync(fireCtx, "tr-labels", nil); err != nil {
+		t.Fatalf("failed to fire: %v", err)
+	}
+
+	execs, err := store.FindExecutions(ctx, storage.ExecutionFilter{CorrelationID: "order-17", Labels: map[string]string{"team": "billing"}}, 10)
+	if err != nil {
+		t.Fatalf("failed to find executions: %v", err)
+	}
+	if len(execs) != 1 || execs[0].WorkflowID != "wf-labels" {
+		t.Errorf("expected the fire's execution to be tagged, got %+v", execs)
+	}
+}
@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ResourceLimits bounds the resources a single Bun block process may consume.
+// Zero values mean "unlimited" for every field.
+type ResourceLimits struct {
+	// MaxMemoryMB is the maximum resident memory of one Bun process in megabytes.
+	// Enforced by a watchdog where the OS exposes process memory (Linux /proc),
+	// and passed to JavaScriptCore as a heap size hint everywhere else.
+	MaxMemoryMB int
+	// MaxWallTime is the hard upper bound on how long one Bun process may run,
+	// regardless of the node-level timeout_ms.
+	MaxWallTime time.Duration
+	// MaxProcesses is the maximum number of concurrent Bun block processes across
+	// the whole engine, independent of the workflow WorkerPool.
+	MaxProcesses int
+}
+
+// ProcessLimiter caps the number of concurrently running Bun processes.
+// A single limiter is shared by every BunRunner so that parallel workflows
+// cannot fork an unbounded number of processes.
+type ProcessLimiter struct {
+	slots chan struct{}
+}
+
+// NewProcessLimiter creates a limiter allowing max concurrent processes (0 = unlimited).
+func NewProcessLimiter(max int) *ProcessLimiter {
+	if max <= 0 {
+		return &ProcessLimiter{}
+	}
+	return &ProcessLimiter{slots: make(chan struct{}, max)}
+}
+
+// Acquire blocks until a process slot is available or ctx is done.
+// The returned function releases the slot and must always be called.
+func (pl *ProcessLimiter) Acquire(ctx context.Context) (func(), error) {
+	if pl == nil || pl.slots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case pl.slots <- struct{}{}:
+		return func() { <-pl.slots }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for a free bun process slot: %w", ctx.Err())
+	}
+}
+
+// InUse returns the number of slots currently held.
+func (pl *ProcessLimiter) InUse() int {
+	if pl == nil || pl.slots == nil {
+		return 0
+	}
+	return len(pl.slots)
+}
+
+var (
+	limitsMu       sync.RWMutex
+	defaultLimits  ResourceLimits
+	defaultLimiter = NewProcessLimiter(0)
+)
+
+// SetResourceLimits configures the limits applied to every BunRunner created afterwards.
+// Typically called once at startup before any workflow runs.
+func SetResourceLimits(limits ResourceLimits) {
+	limitsMu.Lock()
+	defer limitsMu.Unlock()
+	defaultLimits = limits
+	defaultLimiter = NewProcessLimiter(limits.MaxProcesses)
+}
+
+// CurrentResourceLimits returns the engine-wide limits and the shared process limiter.
+func CurrentResourceLimits() (ResourceLimits, *ProcessLimiter) {
+	limitsMu.RLock()
+	defer limitsMu.RUnlock()
+	return defaultLimits, defaultLimiter
+}
@@ -0,0 +1,92 @@
+package engine_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+func TestProcessLimiter(t *testing.T) {
+	t.Run("Unlimited", func(t *testing.T) {
+		limiter := engine.NewProcessLimiter(0)
+		for i := 0; i < 10; i++ {
+			if _, err := limiter.Acquire(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if limiter.InUse() != 0 {
+			t.Errorf("unlimited limiter should not track usage, got %d", limiter.InUse())
+		}
+	})
+
+	t.Run("BlocksWhenFull", func(t *testing.T) {
+		limiter := engine.NewProcessLimiter(1)
+		release, err := limiter.Acquire(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := limiter.Acquire(ctx); err == nil {
+			t.Fatal("expected acquire to fail while limiter is full")
+		}
+
+		release()
+		if limiter.InUse() != 0 {
+			t.Errorf("expected 0 slots in use after release, got %d", limiter.InUse())
+		}
+	})
+}
+
+func TestBunRunner_MaxWallTime(t *testing.T) {
+	runner := engine.NewBunRunner(t.TempDir())
+	runner.RuntimePath = writeFakeBun(t)
+	runner.Limits.MaxWallTime = 100 * time.Millisecond
+
+	script := writeScript(t, "sleep 5\necho '{}'\n")
+
+	start := time.Now()
+	_, err := runner.Execute(context.Background(), script, map[string]interface{}{})
+	if err == nil {
+		t.Fatal("expected wall time error, got nil")
+	}
+	if !strings.Contains(err.Error(), "exceeded max wall time") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Error("process was not killed at the wall time limit")
+	}
+}
+
+func TestBunRunner_MaxProcesses(t *testing.T) {
+	engine.SetResourceLimits(engine.ResourceLimits{MaxProcesses: 1})
+	t.Cleanup(func() { engine.SetResourceLimits(engine.ResourceLimits{}) })
+
+	fakeBun := writeFakeBun(t)
+	script := writeScript(t, "cat > /dev/null\nsleep 0.2\necho '{\"ok\": true}'\n")
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runner := engine.NewBunRunner(t.TempDir())
+			runner.RuntimePath = fakeBun
+			if _, err := runner.Execute(context.Background(), script, map[string]interface{}{}); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Two 200ms processes with a single slot must run back to back
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("expected processes to be serialized, finished in %s", elapsed)
+	}
+}
@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// MaintenancePolicy is what happens to the fires of triggers while the server
+// is in maintenance.
+type MaintenancePolicy string
+
+const (
+	// MaintenanceSkip drops fires, recording them as skipped in the trigger's history
+	MaintenanceSkip MaintenancePolicy = "skip"
+	// MaintenanceQueue holds fires in storage until maintenance ends, or until
+	// the next server to start runs them (see RunQueuedFires)
+	MaintenanceQueue MaintenancePolicy = "queue"
+)
+
+// ErrMaintenance is returned for the fires a server in maintenance refuses.
+var ErrMaintenance = errors.New("server is in maintenance mode")
+
+// MaintenanceState describes the maintenance mode of a server. While in
+// maintenance, triggers stop starting executions and the running ones drain.
+type MaintenanceState struct {
+	Enabled bool              `json:"enabled"`
+	Policy  MaintenancePolicy `json:"policy,omitempty"`
+	Reason  string            `json:"reason,omitempty"`
+	Since   *time.Time        `json:"since,omitempty"`
+	// ActiveExecutions still run in this server; it is drained once none do
+	ActiveExecutions int  `json:"active_executions"`
+	Drained          bool `json:"drained"`
+}
+
+// EnterMaintenance stops the triggers from starting executions: their fires
+// are skipped or queued after policy until ExitMaintenance. Executions already
+// running go on to finish. Calling it again changes the policy and reason.
+func (tm *TriggerManager) EnterMaintenance(policy MaintenancePolicy, reason string) error {
+	if policy != MaintenanceSkip && policy != MaintenanceQueue {
+		return fmt.Errorf("invalid maintenance policy %q: must be %s or %s", policy, MaintenanceSkip, MaintenanceQueue)
+	}
+	tm.maintMu.Lock()
+	defer tm.maintMu.Unlock()
+	since := time.Now()
+	if tm.maintenance != nil {
+		since = *tm.maintenance.Since
+	}
+	tm.maintenance = &MaintenanceState{Enabled: true, Policy: policy, Reason: reason, Since: &since}
+	log.Printf("Entered maintenance mode (%s fires): %s", policy, reason)
+	return nil
+}
+
+// ExitMaintenance lets the triggers start executions again. Fires queued
+// meanwhile are left for RunQueuedFires.
+func (tm *TriggerManager) ExitMaintenance() {
+	tm.maintMu.Lock()
+	defer tm.maintMu.Unlock()
+	if tm.maintenance != nil {
+		log.Println("Exited maintenance mode")
+	}
+	tm.maintenance = nil
+}
+
+// Maintenance returns the maintenance state of the server.
+func (tm *TriggerManager) Maintenance() MaintenanceState {
+	tm.maintMu.Lock()
+	state := MaintenanceState{}
+	if tm.maintenance != nil {
+		state = *tm.maintenance
+	}
+	tm.maintMu.Unlock()
+
+	if tm.registry != nil {
+		state.ActiveExecutions = tm.registry.ActiveCount()
+	}
+	state.Drained = state.Enabled && state.ActiveExecutions == 0
+	return state
+}
+
+// holdFire keeps a fire of triggerID from running while the server is in
+// maintenance, and reports whether it did. Held fires are queued if the
+// policy and canQueue allow it; otherwise they are recorded as skipped and
+// ErrMaintenance is returned. Test runs are never held.
+func (tm *TriggerManager) holdFire(ctx context.Context, triggerID string, payload map[string]interface{}, run triggerRun, canQueue bool) (bool, error) {
+	state := tm.Maintenance()
+	if !state.Enabled || run.test {
+		return false, nil
+	}
+	var payloadBytes []byte
+	if payload != nil {
+		payloadBytes, _ = json.Marshal(payload)
+	}
+	saveCtx, cancel := storage.Detach(ctx)
+	defer cancel()
+
+	if state.Policy == MaintenanceQueue && canQueue {
+		fire := &storage.QueuedTriggerFire{
+			ID:        storage.NewID(),
+			TriggerID: triggerID,
+			Payload:   payloadBytes,
+			Backfill:  run.backfill,
+			QueuedAt:  time.Now(),
+		}
+		if err := tm.Store.QueueTriggerFire(saveCtx, fire); err != nil {
+			return true, err
+		}
+		log.Printf("Trigger %s: queued fire during maintenance", triggerID)
+		return true, nil
+	}
+
+	msg := ErrMaintenance.Error()
+	triggerExec := &storage.TriggerExecution{
+		ID:        storage.NewID(),
+		TriggerID: triggerID,
+		FiredAt:   time.Now(),
+		Status:    "skipped",
+		Payload:   payloadBytes,
+		Error:     &msg,
+		Backfill:  run.backfill,
+	}
+	if trigger, err := tm.Store.GetTrigger(saveCtx, triggerID); err == nil {
+		triggerExec.WorkflowID = trigger.WorkflowID
+	}
+	if err := tm.Store.CreateTriggerExecution(saveCtx, triggerExec); err != nil {
+		log.Printf("Warning: failed to record trigger execution: %v", err)
+	}
+	return true, ErrMaintenance
+}
+
+// RunQueuedFires runs the fires queued during maintenance, by this server or
+// another one sharing its storage, oldest first, and returns how many it
+// started. It stops early if the server is in maintenance again. Each fire is
+// taken off the queue before it runs, so only one server runs it.
+func (tm *TriggerManager) RunQueuedFires(ctx context.Context) (int, error) {
+	fires, err := tm.Store.ListQueuedTriggerFires(ctx)
+	if err != nil {
+		return 0, err
+	}
+	started := 0
+	for _, f := range fires {
+		if tm.Maintenance().Enabled {
+			break
+		}
+		claimed, err := tm.Store.DeleteQueuedTriggerFire(ctx, f.ID)
+		if err != nil {
+			return started, err
+		}
+		if !claimed {
+			continue
+		}
+		var payload map[string]interface{}
+		if len(f.Payload) > 0 {
+			if err := json.Unmarshal(f.Payload, &payload); err != nil {
+				log.Printf("Warning: dropping queued fire of trigger %s: invalid payload: %v", f.TriggerID, err)
+				continue
+			}
+		}
+		if err := tm.fire(ctx, f.TriggerID, payload, triggerRun{backfill: f.Backfill}); err != nil {
+			log.Printf("Warning: failed to run queued fire of trigger %s: %v", f.TriggerID, err)
+			continue
+		}
+		started++
+	}
+	if started > 0 {
+		log.Printf("Ran %d trigger fires queued during maintenance", started)
+	}
+	return started, nil
+}
@@ -0,0 +1,82 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func TestTriggerManager_Maintenance(t *testing.T) {
+	ctx := context.Background()
+	store := createTestStorage(t)
+
+	def, _ := json.Marshal(engine.Workflow{ID: "wf-maint", Nodes: map[string]engine.Node{
+		"mark": {ID: "mark", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "seen", "value": true}},
+	}})
+	if err := store.CreateWorkflow(ctx, &storage.Workflow{ID: "wf-maint", Name: "wf-maint", Definition: def}); err != nil {
+		t.Fatalf("failed to create workflow: %v", err)
+	}
+	if err := store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-maint", WorkflowID: "wf-maint", Type: "webhook", Config: []byte(`{}`), Enabled: true}); err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+
+	tm := engine.NewTriggerManager(store, t.TempDir(), engine.NewExecutionRegistry(), engine.NewWorkerPool(1))
+	defer tm.StopAll()
+	tm.Register(engine.NewWebhookTrigger("tr-maint", "wf-maint", tm))
+
+	if err := tm.EnterMaintenance("pause", ""); err == nil {
+		t.Error("expected an unknown policy to be refused")
+	}
+
+	// Skipped fires are refused and recorded as skipped
+	if err := tm.EnterMaintenance(engine.MaintenanceSkip, "deploy"); err != nil {
+		t.Fatalf("failed to enter maintenance: %v", err)
+	}
+	state := tm.Maintenance()
+	if !state.Enabled || state.Policy != engine.MaintenanceSkip || state.Reason != "deploy" || !state.Drained {
+		t.Errorf("unexpected maintenance state %+v", state)
+	}
+	if err := tm.Fire(ctx, "tr-maint", map[string]interface{}{"n": 1}); !errors.Is(err, engine.ErrMaintenance) {
+		t.Fatalf("expected ErrMaintenance, got %v", err)
+	}
+	if _, err := tm.FireSync(ctx, "tr-maint", nil); !errors.Is(err, engine.ErrMaintenance) {
+		t.Fatalf("expected ErrMaintenance for a sync fire, got %v", err)
+	}
+	fires, _ := store.ListTriggerExecutions(ctx, "tr-maint", 10)
+	if len(fires) != 2 || fires[0].Status != "skipped" || fires[0].WorkflowID != "wf-maint" {
+		t.Fatalf("expected 2 skipped fires, got %+v", fires)
+	}
+
+	// Queued fires wait in storage until maintenance ends
+	tm.EnterMaintenance(engine.MaintenanceQueue, "deploy")
+	if err := tm.Fire(ctx, "tr-maint", map[string]interface{}{"n": 2}); err != nil {
+		t.Fatalf("expected the fire to be queued, got %v", err)
+	}
+	if n, err := tm.RunQueuedFires(ctx); err != nil || n != 0 {
+		t.Fatalf("expected no queued fire to run during maintenance, got %d (%v)", n, err)
+	}
+	queued, _ := store.ListQueuedTriggerFires(ctx)
+	if len(queued) != 1 || queued[0].TriggerID != "tr-maint" {
+		t.Fatalf("expected 1 queued fire, got %+v", queued)
+	}
+
+	tm.ExitMaintenance()
+	if tm.Maintenance().Enabled {
+		t.Fatal("expected maintenance to end")
+	}
+	if n, err := tm.RunQueuedFires(ctx); err != nil || n != 1 {
+		t.Fatalf("expected the queued fire to run, got %d (%v)", n, err)
+	}
+	waitTriggerExecutions(t, store, "tr-maint", 3)
+	fires, _ = store.ListTriggerExecutions(ctx, "tr-maint", 10)
+	if fires[0].Status != "success" || string(fires[0].Payload) != `{"n":2}` {
+		t.Errorf("expected the queued fire to run with its payload, got %+v", fires[0])
+	}
+	if queued, _ := store.ListQueuedTriggerFires(ctx); len(queued) != 0 {
+		t.Errorf("expected the queue to be empty, got %d", len(queued))
+	}
+}
@@ -0,0 +1,64 @@
+//go:build linux
+
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const memoryPollInterval = 50 * time.Millisecond
+
+// watchProcessMemory polls the resident set size of proc and kills it once it
+// exceeds limitMB. Returns when done is closed or the process has been killed.
+func watchProcessMemory(proc *os.Process, limitMB int, done <-chan struct{}, exceeded *atomic.Bool) {
+	limitKB := uint64(limitMB) * 1024
+	ticker := time.NewTicker(memoryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			rssKB, err := readRSSKB(proc.Pid)
+			if err != nil {
+				// Process already gone or /proc unavailable
+				return
+			}
+			if rssKB > limitKB {
+				exceeded.Store(true)
+				proc.Kill()
+				return
+			}
+		}
+	}
+}
+
+// readRSSKB returns VmRSS of the given process in kilobytes.
+func readRSSKB(pid int) (uint64, error) {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "VmRSS:") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "VmRSS:"))
+		if len(fields) == 0 {
+			break
+		}
+		return strconv.ParseUint(fields[0], 10, 64)
+	}
+	return 0, fmt.Errorf("VmRSS not found for pid %d", pid)
+}
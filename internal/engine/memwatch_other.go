@@ -0,0 +1,13 @@
+//go:build !linux
+
+package engine
+
+import (
+	"os"
+	"sync/atomic"
+)
+
+// watchProcessMemory is a no-op on platforms without /proc; the JSC heap size
+// hint passed through the environment is the only memory limit there.
+func watchProcessMemory(proc *os.Process, limitMB int, done <-chan struct{}, exceeded *atomic.Bool) {
+}
@@ -0,0 +1,206 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mocks put a run in mock mode, for deterministic runs of a workflow in CI:
+// the network calls of its nodes are answered by canned responses instead of
+// leaving the process. The requests of std/http_request and std/webhook nodes,
+// and those std/xml and std/spreadsheet send, are answered by the first of
+// HTTP matching them. Nodes listed in Nodes output their mock instead of
+// running; std/ssh and std/sftp nodes, and script blocks making calls of their
+// own (e.g. custom/code), can only be mocked this way. Requests no mock
+// answers and unmocked std/ssh and std/sftp nodes fail, unless Passthrough
+// lets them reach the network.
+type Mocks struct {
+	HTTP        []HTTPMock             `json:"http,omitempty"`
+	Nodes       map[string]interface{} `json:"nodes,omitempty"`
+	Passthrough bool                   `json:"passthrough,omitempty"`
+}
+
+// HTTPMock answers the requests matching Method and URL. URL must match the
+// whole request URL, query included; a * in it matches any run of characters,
+// e.g. "https://api.example.com/users/*".
+type HTTPMock struct {
+	Method  string            `json:"method,omitempty"` // Any method if empty
+	URL     string            `json:"url"`
+	Status  int               `json:"status,omitempty"` // 200 if unset
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is sent as is if it is a string and as JSON otherwise
+	Body interface{} `json:"body,omitempty"`
+}
+
+// Validate checks the mocks, returning a *FieldError for each problem found.
+func (m *Mocks) Validate() error {
+	return m.validate("")
+}
+
+// validate is Validate with field names starting with prefix.
+func (m *Mocks) validate(prefix string) error {
+	var errs []error
+	for i, h := range m.HTTP {
+		field := fmt.Sprintf("%shttp[%d]", prefix, i)
+		if h.URL == "" {
+			errs = append(errs, &FieldError{Field: field + ".url", Message: "is required"})
+		}
+		if h.Status != 0 && (h.Status < 100 || h.Status > 599) {
+			errs = append(errs, &FieldError{Field: field + ".status", Message: "must be an HTTP status code"})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// errNotMocked means a node runs as usual in mock mode.
+var errNotMocked = errors.New("node type is not mocked")
+
+type mocksKey struct{}
+
+// withMocks returns a context under which nodes run in mock mode with m.
+func withMocks(ctx context.Context, m *Mocks) context.Context {
+	t := &mockTransport{mocks: m, patterns: make([]*regexp.Regexp, len(m.HTTP))}
+	for i, h := range m.HTTP {
+		quoted := strings.ReplaceAll(regexp.QuoteMeta(h.URL), `\*`, `.*`)
+		t.patterns[i] = regexp.MustCompile(`^` + quoted + `$`)
+	}
+	return context.WithValue(ctx, mocksKey{}, t)
+}
+
+// mocksFrom returns the mocks of the run under ctx, nil if it isn't in mock mode.
+func mocksFrom(ctx context.Context) *mockTransport {
+	t, _ := ctx.Value(mocksKey{}).(*mockTransport)
+	return t
+}
+
+// httpClient returns the client the engine's blocks send requests with: the
+// default one, or in mock mode one answered by the run's mocks.
+func httpClient(ctx context.Context) *http.Client {
+	if t := mocksFrom(ctx); t != nil {
+		return &http.Client{Transport: t}
+	}
+	return http.DefaultClient
+}
+
+// mockTransport answers requests from Mocks.HTTP.
+type mockTransport struct {
+	mocks    *Mocks
+	patterns []*regexp.Regexp
+}
+
+func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	for i, m := range t.mocks.HTTP {
+		if (m.Method == "" || strings.EqualFold(m.Method, req.Method)) && t.patterns[i].MatchString(req.URL.String()) {
+			return m.response(req)
+		}
+	}
+	if t.mocks.Passthrough {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+	return nil, fmt.Errorf("mock mode: no mock for %s %s", req.Method, req.URL)
+}
+
+// response builds the response m answers req with.
+func (m HTTPMock) response(req *http.Request) (*http.Response, error) {
+	status := m.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	var body []byte
+	header := make(http.Header, len(m.Headers)+1)
+	switch b := m.Body.(type) {
+	case nil:
+	case string:
+		body = []byte(b)
+	default:
+		var err error
+		if body, err = json.Marshal(b); err != nil {
+			return nil, fmt.Errorf("mock mode: invalid body for %s: %w", m.URL, err)
+		}
+		header.Set("Content-Type", "application/json")
+	}
+	for k, v := range m.Headers {
+		header.Set(k, v)
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// executeMocked runs the nodes whose Bun blocks would reach the network in
+// mock mode: std/http_request and std/webhook send their request in-process,
+// answered by the mocks, and std/ssh and std/sftp fail unless mocks pass them
+// through. It returns errNotMocked for every other node.
+func executeMocked(ctx context.Context, node *Node, input any) (any, error) {
+	t := mocksFrom(ctx)
+	payload, _ := input.(map[string]interface{})
+	config, _ := payload["config"].(map[string]interface{})
+	switch node.Type {
+	case NodeTypeHTTPRequest:
+		if config["pagination"] != nil {
+			return nil, errNotMocked // Paginated requests are sent in-process anyway
+		}
+		rawURL, _ := config["url"].(string)
+		if rawURL == "" {
+			return nil, fmt.Errorf("http_request: missing required config: url")
+		}
+		resp, err := doHTTPRequest(ctx, config, rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("http_request: %w", err)
+		}
+		return map[string]interface{}{
+			"data": map[string]interface{}{
+				"status":     resp.Status,
+				"statusText": resp.StatusText,
+				"headers":    resp.Headers,
+				"data":       resp.Data,
+			},
+			"port": httpPort(resp.Status),
+		}, nil
+	case NodeTypeWebhook:
+		rawURL, _ := config["url"].(string)
+		if rawURL == "" {
+			return nil, fmt.Errorf("Webhook request failed: config 'url' must be a non-empty string")
+		}
+		started := time.Now()
+		resp, err := doHTTPRequest(ctx, config, rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("Webhook request failed: %w", err)
+		}
+		if resp.Status < 200 || resp.Status >= 300 {
+			return nil, fmt.Errorf("Webhook request failed: HTTP %d %s", resp.Status, resp.StatusText)
+		}
+		return map[string]interface{}{
+			"status":     resp.Status,
+			"statusText": resp.StatusText,
+			"headers":    resp.Headers,
+			"data":       resp.Data,
+			"duration":   time.Since(started).Milliseconds(),
+		}, nil
+	case NodeTypeSSH, NodeTypeSFTP:
+		if !t.mocks.Passthrough {
+			return nil, fmt.Errorf("mock mode: %s node %s connects to a remote host; mock its output in nodes", node.Type, node.ID)
+		}
+	}
+	return nil, errNotMocked
+}
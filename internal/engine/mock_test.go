@@ -0,0 +1,105 @@
+package engine_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// runMocked runs w in mock mode with mocks and returns its context and error.
+func runMocked(t *testing.T, w engine.Workflow, mocks *engine.Mocks) (*engine.ExecutionContext, error) {
+	t.Helper()
+	ectx := engine.NewExecutionContext(w.ID)
+	ectx.Test = true
+	ectx.Mocks = mocks
+	runner := engine.NewWorkflowRunner(ectx, t.TempDir(), createTestStorage(t), nil)
+	return ectx, runner.Run(context.Background(), w)
+}
+
+func TestMocks_HTTPRequest(t *testing.T) {
+	w := engine.Workflow{
+		ID: "wf-mock",
+		Nodes: map[string]engine.Node{
+			"fetch": {ID: "fetch", Type: engine.NodeTypeHTTPRequest, Config: map[string]interface{}{"url": "https://api.example.com/users/17?expand=true"}},
+			"name":  {ID: "name", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "user", "value": "{{ $node.fetch.data.data.name }}"}},
+		},
+		Edges: []engine.Edge{{ID: "e1", Source: "fetch", Target: "name", SourceHandle: "success"}},
+	}
+	mocks := &engine.Mocks{HTTP: []engine.HTTPMock{
+		{Method: "POST", URL: "https://api.example.com/users/*", Status: 500},
+		{URL: "https://api.example.com/users/*", Status: 201, Headers: map[string]string{"X-Request-Id": "r-1"}, Body: map[string]interface{}{"name": "Ada"}},
+	}}
+
+	ectx, err := runMocked(t, w, mocks)
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	fetch, _ := ectx.GetResult("fetch").(map[string]interface{})
+	data, _ := fetch["data"].(map[string]interface{})
+	if fetch["port"] != "success" || data["status"] != 201 || data["headers"].(map[string]string)["x-request-id"] != "r-1" {
+		t.Errorf("expected the mocked response, got %+v", fetch)
+	}
+	if got := ectx.GetVar("user"); got != "Ada" {
+		t.Errorf("expected the mocked body to reach the next node, got %v", got)
+	}
+
+	// A request no mock matches fails rather than reaching the network
+	mocks.HTTP = mocks.HTTP[:1]
+	if _, err := runMocked(t, w, mocks); err == nil || !strings.Contains(err.Error(), "no mock for GET") {
+		t.Errorf("expected an unmocked request to fail, got %v", err)
+	}
+}
+
+func TestMocks_Passthrough(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"live":true}`))
+	}))
+	defer srv.Close()
+	w := engine.Workflow{
+		ID: "wf-mock",
+		Nodes: map[string]engine.Node{
+			"fetch": {ID: "fetch", Type: engine.NodeTypeHTTPRequest, Config: map[string]interface{}{"url": srv.URL}},
+		},
+	}
+
+	ectx, err := runMocked(t, w, &engine.Mocks{Passthrough: true})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	fetch, _ := ectx.GetResult("fetch").(map[string]interface{})
+	if data, _ := fetch["data"].(map[string]interface{}); data["data"].(map[string]interface{})["live"] != true {
+		t.Errorf("expected the live response, got %+v", fetch)
+	}
+}
+
+func TestMocks_Nodes(t *testing.T) {
+	w := engine.Workflow{
+		ID: "wf-mock",
+		Nodes: map[string]engine.Node{
+			"deploy": {ID: "deploy", Type: engine.NodeTypeSSH, Config: map[string]interface{}{"host": "prod.example.com", "command": "deploy"}},
+		},
+	}
+
+	if _, err := runMocked(t, w, &engine.Mocks{}); err == nil || !strings.Contains(err.Error(), "remote host") {
+		t.Errorf("expected an unmocked ssh node to fail, got %v", err)
+	}
+	ectx, err := runMocked(t, w, &engine.Mocks{Nodes: map[string]interface{}{"deploy": map[string]interface{}{"data": map[string]interface{}{"exitCode": 0}}}})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if ectx.GetResult("deploy") == nil {
+		t.Error("expected the node's mocked output")
+	}
+}
+
+func TestMocks_Validate(t *testing.T) {
+	mocks := engine.Mocks{HTTP: []engine.HTTPMock{{URL: ""}, {URL: "https://x", Status: 42}}}
+	err := mocks.Validate()
+	if err == nil || !strings.Contains(err.Error(), "http[0].url") || !strings.Contains(err.Error(), "http[1].status") {
+		t.Errorf("expected both mocks to be invalid, got %v", err)
+	}
+}
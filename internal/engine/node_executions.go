@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// The helpers below keep the node timeline of an execution. They write with a
+// detached context (see storage.Detach), so that a node interrupted by the
+// run's ctx is still recorded, and only log failures: the timeline never fails a run.
+
+// recordNodeStart records that a node started running.
+func recordNodeStart(ctx context.Context, store storage.Storage, executionID, nodeID string) {
+	ctx, cancel := storage.Detach(ctx)
+	defer cancel()
+	if err := store.StartNodeExecution(ctx, executionID, nodeID); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// recordNodeFinish records the outcome of a node: failed when err is set.
+func recordNodeFinish(ctx context.Context, store storage.Storage, executionID, nodeID string, status storage.NodeStatus, port string, err error) {
+	var errorMsg *string
+	if err != nil {
+		status = storage.NodeStatusFailed
+		msg := err.Error()
+		errorMsg = &msg
+	}
+	ctx, cancel := storage.Detach(ctx)
+	defer cancel()
+	if err := store.FinishNodeExecution(ctx, executionID, nodeID, status, port, errorMsg); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// recordNodeSuccess records that a node succeeded together with its result, so
+// a crash can't leave a node marked successful without its output.
+func recordNodeSuccess(ctx context.Context, store storage.Storage, executionID, nodeID, port string, result interface{}) {
+	ctx, cancel := storage.Detach(ctx)
+	defer cancel()
+	resBytes, _ := json.Marshal(result)
+	usageFrom(ctx).addBytesStored(len(resBytes))
+	err := storage.WithTx(ctx, store, func(tx storage.Tx) error {
+		if err := tx.FinishNodeExecution(ctx, executionID, nodeID, storage.NodeStatusSuccess, port, nil); err != nil {
+			return err
+		}
+		return tx.SaveNodeResult(ctx, executionID, nodeID, resBytes)
+	})
+	if err != nil {
+		log.Printf("Warning: failed to save node result: %v", err)
+	}
+}
+
+// recordNodeContinued records that a continue_on_fail node failed with err,
+// together with the error output the execution carried on with.
+func recordNodeContinued(ctx context.Context, store storage.Storage, executionID, nodeID string, err error, result *BlockResult) {
+	ctx, cancel := storage.Detach(ctx)
+	defer cancel()
+	msg := err.Error()
+	resBytes, _ := json.Marshal(result.Data)
+	usageFrom(ctx).addBytesStored(len(resBytes))
+	err = storage.WithTx(ctx, store, func(tx storage.Tx) error {
+		if err := tx.FinishNodeExecution(ctx, executionID, nodeID, storage.NodeStatusFailed, result.Port, &msg); err != nil {
+			return err
+		}
+		return tx.SaveNodeResult(ctx, executionID, nodeID, resBytes)
+	})
+	if err != nil {
+		log.Printf("Warning: failed to save node result: %v", err)
+	}
+}
+
+// dropSuccessData drops the data of the completed execution executionID of w,
+// keeping its summary, when the workflow's settings.save_success_data is "none".
+func dropSuccessData(ctx context.Context, tx storage.Tx, w *Workflow, executionID string) error {
+	if mode, _ := w.SaveSuccessData(); mode != SaveDataNone {
+		return nil
+	}
+	return tx.DeleteExecutionData(ctx, executionID)
+}
+
+// failExecution records that an execution failed with err before running any node.
+func failExecution(ctx context.Context, store storage.Storage, executionID string, err error) {
+	ctx, cancel := storage.Detach(ctx)
+	defer cancel()
+	msg := err.Error()
+	if err := store.UpdateExecutionStatus(ctx, executionID, storage.ExecutionStatusFailed, []byte("{}"), &msg); err != nil {
+		log.Printf("Failed to update execution status: %v", err)
+	}
+}
+
+// recordSkippedNodes records the nodes of workflow the finished execution
+// never reached as skipped.
+func recordSkippedNodes(ctx context.Context, store storage.Storage, executionID string, workflow Workflow) {
+	ctx, cancel := storage.Detach(ctx)
+	defer cancel()
+	recorded, err := store.ListNodeExecutions(ctx, executionID)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+	seen := make(map[string]bool, len(recorded))
+	for _, ne := range recorded {
+		seen[ne.NodeID] = true
+	}
+	ids := make([]string, 0, len(workflow.Nodes))
+	for id := range workflow.Nodes {
+		if !seen[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		recordNodeFinish(ctx, store, executionID, id, storage.NodeStatusSkipped, "", nil)
+	}
+}
@@ -0,0 +1,152 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DefaultPort is the output port of a result that names none.
+const DefaultPort = "default"
+
+// ErrorPort is the output port of a node with continue_on_fail that failed.
+const ErrorPort = "error"
+
+// builtinPorts lists the output ports of the standard nodes. Types missing here
+// (custom/code, std/file, std/database, std/webhook) have undeclared ports.
+var builtinPorts = map[NodeType][]string{
+	NodeTypeCondition:   {"true", "false"},
+	NodeTypeLoop:        {DefaultPort, "empty"},
+	NodeTypeTransform:   {DefaultPort},
+	NodeTypeDelay:       {DefaultPort},
+	NodeTypeHTTPRequest: {DefaultPort, "success", "client_error", "server_error", "error"},
+	NodeTypeSetVar:      {DefaultPort},
+	NodeTypeGetVar:      {DefaultPort},
+	NodeTypeSSH:         {"success", "error"},
+	NodeTypeSFTP:        {DefaultPort},
+	NodeTypeCSV:         {DefaultPort},
+	NodeTypeSpreadsheet: {DefaultPort},
+	NodeTypeArchive:     {DefaultPort},
+	NodeTypeDatetime:    {DefaultPort, "before", "after", "equal"},
+	NodeTypeXML:         {DefaultPort, "fault"},
+	NodeTypeEnqueue:     {DefaultPort, "signal"},
+	NodeTypeRateLimit:   {"pass", "limited"},
+	NodeTypeDedupe:      {"new", "duplicate"},
+}
+
+// BlockManifest is the <name>.manifest.json written next to a block script by
+// `conv3n new block`. Only the fields the engine reads are decoded.
+type BlockManifest struct {
+	ID          string   `json:"id"`
+	Kind        string   `json:"kind,omitempty"`
+	Version     string   `json:"version"`
+	Description string   `json:"description,omitempty"`
+	Entry       string   `json:"entry,omitempty"` // Script relative to the manifest; defaults to <name>.ts
+	Ports       []string `json:"ports,omitempty"`
+	// Config describes the block's config, checked when workflows are saved.
+	Config ConfigSchema `json:"config,omitempty"`
+	// Yanked lists earlier versions of the block its author withdrew; nodes
+	// pinned to them still run, with a warning.
+	Yanked []string `json:"yanked,omitempty"`
+}
+
+// DeclaredPorts returns the output ports a node type declares: the built-in list
+// for standard nodes, or the "ports" of the block's manifest for scaffolded
+// blocks. A nil slice means the type declares nothing and may emit any port.
+func (r *BunRunner) DeclaredPorts(nodeType NodeType) ([]string, error) {
+	if ports, ok := builtinPorts[nodeType]; ok {
+		return ports, nil
+	}
+	if block, ok := BlockRegistryFor(r.BlocksDir).Lookup(nodeType); ok {
+		if block.Manifest == nil {
+			return nil, nil
+		}
+		return block.Manifest.Ports, nil
+	}
+	// The registry skips manifests it can't read; report why for the script they belong to
+	script := r.customScriptPath(nodeType)
+	if script == "" {
+		return nil, nil
+	}
+	path := strings.TrimSuffix(script, ".ts") + ".manifest.json"
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifest BlockManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid block manifest %s: %w", filepath.Base(path), err)
+	}
+	return manifest.Ports, nil
+}
+
+// nodePorts is DeclaredPorts for the block version node is pinned to.
+func (r *BunRunner) nodePorts(node *Node) ([]string, error) {
+	if node.Version == "" {
+		return r.DeclaredPorts(node.Type)
+	}
+	constraint, err := ParseVersionConstraint(node.Version)
+	if err != nil {
+		return nil, err
+	}
+	block, err := BlockRegistryFor(r.BlocksDir).Resolve(node.Type, constraint)
+	if err != nil || block.Manifest == nil {
+		return r.DeclaredPorts(node.Type)
+	}
+	return block.Manifest.Ports, nil
+}
+
+// checkPort fails a node whose result routes to a port its type doesn't declare.
+func (r *BunRunner) checkPort(node *Node, raw interface{}) error {
+	ports, err := r.nodePorts(node)
+	if err != nil || ports == nil {
+		return err
+	}
+	if port := resultPort(raw); !slices.Contains(ports, port) {
+		return fmt.Errorf("%s returned undeclared output port %q (declared: %s)", node.Type, port, strings.Join(ports, ", "))
+	}
+	return nil
+}
+
+// resultPort returns the "port" a block result routes to, or DefaultPort.
+func resultPort(raw interface{}) string {
+	if resMap, ok := raw.(map[string]interface{}); ok {
+		if port, ok := resMap["port"].(string); ok && port != "" {
+			return port
+		}
+	}
+	return DefaultPort
+}
+
+// continueOnFail reports whether node has "continue_on_fail": true in its
+// config: a failure then becomes its output on ErrorPort, {"error": message},
+// and the execution carries on instead of failing. A node interrupted because
+// the execution was stopped or timed out still ends it.
+func continueOnFail(ctx context.Context, node *Node) bool {
+	enabled, _ := node.Config["continue_on_fail"].(bool)
+	return enabled && ctx.Err() == nil
+}
+
+// failureResult is the output of a continue_on_fail node that failed with err.
+func failureResult(err error) *BlockResult {
+	return &BlockResult{Data: map[string]interface{}{"error": err.Error()}, Port: ErrorPort}
+}
+
+// nextNodes returns the nodes to run after node routed to port. Edges are matched
+// strictly when the workflow asks for it and the node declares its ports.
+func (r *BunRunner) nextNodes(w *Workflow, node *Node, port string) []string {
+	strict := false
+	if mode, _ := w.EdgeMatching(); mode == EdgeMatchingStrict {
+		ports, _ := r.nodePorts(node)
+		strict = ports != nil
+	}
+	return w.findNextNodes(node.ID, port, strict)
+}
@@ -0,0 +1,231 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// minPurgeSubject is the shortest identifier purged by content: shorter ones
+// would match the data of nearly every execution.
+const minPurgeSubject = 3
+
+// PurgeRequest selects the data stored about a data subject to delete, for
+// erasure requests.
+type PurgeRequest struct {
+	// Subject identifies the data subject, e.g. an email address or customer ID
+	Subject string
+	// Label purges the executions labelled Label=Subject and the
+	// trigger history of their fires. Empty purges by content instead: the
+	// executions, trigger history and queued fires whose stored data contains
+	// Subject.
+	Label  string
+	Reason string
+	// DryRun counts what would be purged without deleting it or recording it
+	DryRun bool
+}
+
+func (req *PurgeRequest) validate() error {
+	switch {
+	case req.Subject == "":
+		return fieldErrorf("subject", "is required")
+	case req.Label == "" && len(req.Subject) < minPurgeSubject:
+		return fieldErrorf("subject", "must be at least %d characters to purge by content", minPurgeSubject)
+	}
+	return nil
+}
+
+// PurgeSubject deletes the data store holds about the subject of req: the
+// matching executions with their node results, inputs and timeline (which
+// their logs are made of), their trigger history and its payloads, queued
+// fires and the workflow snapshots blessed from them. Executions still
+// running are left in place and counted. Unless req.DryRun, the purge is
+// recorded in the store's audit trail, which holds only an HMAC of the subject
+// under hashKey, a server secret, so that the trail can't be checked against
+// guessed identifiers without it.
+//
+// Matching by content reads every execution through store, so that payloads
+// are compared decrypted; it takes time on large histories.
+func PurgeSubject(ctx context.Context, store storage.Storage, hashKey []byte, req PurgeRequest) (*storage.PurgeRecord, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	if len(hashKey) == 0 {
+		return nil, errors.New("purges require a hash key for their audit records")
+	}
+	mac := hmac.New(sha256.New, hashKey)
+	mac.Write([]byte(req.Subject))
+	record := &storage.PurgeRecord{
+		ID:          storage.NewID(),
+		SubjectHash: hex.EncodeToString(mac.Sum(nil)),
+		MatchedBy:   "content",
+		Reason:      req.Reason,
+		PurgedAt:    time.Now().UTC(),
+	}
+	match := newSubjectMatcher(req.Subject)
+
+	filter := storage.ExecutionFilter{}
+	if req.Label != "" {
+		record.MatchedBy = "label:" + req.Label
+		filter.Labels = map[string]string{req.Label: req.Subject}
+	}
+	execs, err := store.FindExecutions(ctx, filter, -1)
+	if err != nil {
+		return nil, err
+	}
+	purged := make(map[string]bool)
+	for _, exec := range execs {
+		if req.Label == "" {
+			found, err := executionContains(ctx, store, exec.ID, match)
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				continue
+			}
+		}
+		if exec.Status == storage.ExecutionStatusRunning {
+			record.SkippedRunning++
+			continue
+		}
+		purged[exec.ID] = true
+	}
+
+	var triggerExecs []string
+	triggers, err := store.ListAllTriggers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range triggers {
+		history, err := store.ListTriggerExecutions(ctx, t.ID, -1)
+		if err != nil {
+			return nil, err
+		}
+		for _, te := range history {
+			if (te.ExecutionID != nil && purged[*te.ExecutionID]) || (req.Label == "" && match(te.Payload)) {
+				triggerExecs = append(triggerExecs, te.ID)
+			}
+		}
+	}
+
+	var fires []string
+	if req.Label == "" {
+		queued, err := store.ListQueuedTriggerFires(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range queued {
+			if match(f.Payload) {
+				fires = append(fires, f.ID)
+			}
+		}
+	}
+
+	var snapshots []string
+	workflows, err := store.ListWorkflows(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range workflows {
+		snap, err := store.GetWorkflowSnapshot(ctx, w.ID)
+		if err != nil {
+			return nil, err
+		}
+		if snap != nil && purged[snap.ExecutionID] {
+			snapshots = append(snapshots, w.ID)
+		}
+	}
+
+	record.Executions = len(purged)
+	record.TriggerExecutions = len(triggerExecs)
+	record.QueuedFires = len(fires)
+	record.Snapshots = len(snapshots)
+	if req.DryRun {
+		return record, nil
+	}
+
+	err = storage.WithTx(ctx, store, func(tx storage.Tx) error {
+		for _, id := range triggerExecs {
+			if err := tx.DeleteTriggerExecution(ctx, id); err != nil {
+				return err
+			}
+		}
+		for id := range purged {
+			if err := tx.DeleteExecution(ctx, id); err != nil {
+				return err
+			}
+		}
+		for _, id := range fires {
+			if _, err := tx.DeleteQueuedTriggerFire(ctx, id); err != nil {
+				return err
+			}
+		}
+		for _, workflowID := range snapshots {
+			if err := tx.DeleteWorkflowSnapshot(ctx, workflowID); err != nil {
+				return err
+			}
+		}
+		return tx.RecordPurge(ctx, record)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge subject: %w", err)
+	}
+	log.Printf("Purged subject %s (%s): %d executions, %d trigger executions, %d queued fires, %d snapshots",
+		record.SubjectHash[:16], record.MatchedBy, record.Executions, record.TriggerExecutions, record.QueuedFires, record.Snapshots)
+	return record, nil
+}
+
+// newSubjectMatcher returns a function reporting whether stored data contains
+// subject, as is or escaped as in JSON strings.
+func newSubjectMatcher(subject string) func([]byte) bool {
+	plain := []byte(subject)
+	escaped, _ := json.Marshal(subject)
+	escaped = escaped[1 : len(escaped)-1]
+	return func(data []byte) bool {
+		return bytes.Contains(data, plain) || bytes.Contains(data, escaped)
+	}
+}
+
+// executionContains reports whether the stored data of an execution matches:
+// its trigger data, state, error, node results, inputs or node errors.
+func executionContains(ctx context.Context, store storage.Storage, executionID string, match func([]byte) bool) (bool, error) {
+	exec, err := store.GetExecution(ctx, executionID)
+	if err != nil {
+		return false, err
+	}
+	if match(exec.TriggerData) || match(exec.State) || (exec.Error != nil && match([]byte(*exec.Error))) {
+		return true, nil
+	}
+	results, err := store.ListNodeResults(ctx, executionID)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range results {
+		if match(r.Result) {
+			return true, nil
+		}
+	}
+	nodes, err := store.ListNodeExecutions(ctx, executionID)
+	if err != nil {
+		return false, err
+	}
+	for _, n := range nodes {
+		if n.Error != nil && match([]byte(*n.Error)) {
+			return true, nil
+		}
+		// Nodes that didn't run have no input
+		if input, err := store.GetNodeInput(ctx, executionID, n.NodeID); err == nil && match(input) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
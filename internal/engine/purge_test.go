@@ -0,0 +1,126 @@
+package engine_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func TestPurgeSubject(t *testing.T) {
+	ctx := context.Background()
+	hashKey := []byte("hash-key")
+	store := createTestStorage(t)
+	store.CreateWorkflow(ctx, &storage.Workflow{ID: "wf-1", Name: "Signup", Definition: []byte(`{}`)})
+	if err := store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-1", WorkflowID: "wf-1", Type: "webhook", Config: []byte(`{}`), Enabled: true}); err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+	pii := []byte(`{"email":"ada@example.com"}`)
+	run := func(triggerData, result []byte, labels map[string]string, status storage.ExecutionStatus) string {
+		id, _ := store.CreateExecution(ctx, "wf-1")
+		store.SaveExecutionTriggerData(ctx, id, triggerData)
+		store.SetExecutionLabels(ctx, id, "", labels)
+		store.SaveNodeResult(ctx, id, "fetch", result)
+		if status != storage.ExecutionStatusRunning {
+			store.UpdateExecutionStatus(ctx, id, status, []byte(`{}`), nil)
+		}
+		return id
+	}
+	labelled := run(pii, []byte(`{}`), map[string]string{"customer": "42"}, storage.ExecutionStatusCompleted)
+	byContent := run([]byte(`{}`), pii, nil, storage.ExecutionStatusFailed)
+	other := run([]byte(`{"email":"bob@example.com"}`), []byte(`{}`), map[string]string{"customer": "7"}, storage.ExecutionStatusCompleted)
+	running := run(pii, []byte(`{}`), nil, storage.ExecutionStatusRunning)
+	store.CreateTriggerExecution(ctx, &storage.TriggerExecution{ID: "te-labelled", TriggerID: "tr-1", WorkflowID: "wf-1", ExecutionID: &labelled, FiredAt: time.Now(), Status: "success", Payload: []byte(`{}`)})
+	store.CreateTriggerExecution(ctx, &storage.TriggerExecution{ID: "te-failed", TriggerID: "tr-1", WorkflowID: "wf-1", FiredAt: time.Now(), Status: "failed", Payload: pii})
+	store.CreateTriggerExecution(ctx, &storage.TriggerExecution{ID: "te-other", TriggerID: "tr-1", WorkflowID: "wf-1", ExecutionID: &other, FiredAt: time.Now(), Status: "success", Payload: []byte(`{}`)})
+	store.QueueTriggerFire(ctx, &storage.QueuedTriggerFire{ID: "fire-pii", TriggerID: "tr-1", Payload: pii, QueuedAt: time.Now()})
+	store.QueueTriggerFire(ctx, &storage.QueuedTriggerFire{ID: "fire-other", TriggerID: "tr-1", Payload: []byte(`{}`), QueuedAt: time.Now()})
+	store.SetWorkflowSnapshot(ctx, &storage.WorkflowSnapshot{WorkflowID: "wf-1", ExecutionID: byContent, Data: []byte(`{}`)})
+
+	// By label: the labelled executions and the trigger history of their fires
+	record, err := engine.PurgeSubject(ctx, store, hashKey, engine.PurgeRequest{Subject: "42", Label: "customer", Reason: "ticket 1"})
+	if err != nil {
+		t.Fatalf("failed to purge by label: %v", err)
+	}
+	if record.MatchedBy != "label:customer" || record.Executions != 1 || record.TriggerExecutions != 1 || record.QueuedFires != 0 {
+		t.Errorf("unexpected label purge %+v", record)
+	}
+	if _, err := store.GetExecution(ctx, labelled); err == nil {
+		t.Error("expected the labelled execution to be deleted")
+	}
+
+	// A dry run counts without deleting or recording
+	dry, err := engine.PurgeSubject(ctx, store, hashKey, engine.PurgeRequest{Subject: "ada@example.com", DryRun: true})
+	if err != nil {
+		t.Fatalf("failed to dry run: %v", err)
+	}
+	want := storage.PurgeRecord{MatchedBy: "content", Executions: 1, TriggerExecutions: 1, QueuedFires: 1, Snapshots: 1, SkippedRunning: 1}
+	got := *dry
+	got.ID, got.SubjectHash, got.PurgedAt = "", "", time.Time{}
+	if got != want {
+		t.Errorf("expected dry run %+v, got %+v", want, got)
+	}
+	if _, err := store.GetExecution(ctx, byContent); err != nil {
+		t.Errorf("expected a dry run to delete nothing: %v", err)
+	}
+
+	// By content: whatever stored data holds the subject
+	record, err = engine.PurgeSubject(ctx, store, hashKey, engine.PurgeRequest{Subject: "ada@example.com", Reason: "ticket 2"})
+	if err != nil {
+		t.Fatalf("failed to purge by content: %v", err)
+	}
+	if record.Executions != 1 || record.SkippedRunning != 1 {
+		t.Errorf("unexpected content purge %+v", record)
+	}
+	if _, err := store.GetExecution(ctx, byContent); err == nil {
+		t.Error("expected the execution holding the subject to be deleted")
+	}
+	for _, id := range []string{other, running} {
+		if _, err := store.GetExecution(ctx, id); err != nil {
+			t.Errorf("expected execution %s to be kept: %v", id, err)
+		}
+	}
+	history, _ := store.ListTriggerExecutions(ctx, "tr-1", 10)
+	if len(history) != 1 || history[0].ID != "te-other" {
+		t.Errorf("expected only the other trigger execution to be kept, got %v", history)
+	}
+	if fires, _ := store.ListQueuedTriggerFires(ctx); len(fires) != 1 || fires[0].ID != "fire-other" {
+		t.Errorf("expected only the other queued fire to be kept, got %v", fires)
+	}
+	if snap, _ := store.GetWorkflowSnapshot(ctx, "wf-1"); snap != nil {
+		t.Error("expected the snapshot blessed from a purged run to be deleted")
+	}
+
+	// The audit trail records both purges, without the subject
+	purges, err := store.ListPurges(ctx, 10)
+	if err != nil || len(purges) != 2 {
+		t.Fatalf("expected two purges recorded, got %v (%v)", purges, err)
+	}
+	if purges[0].Reason != "ticket 2" || purges[0].ID != record.ID || purges[1].MatchedBy != "label:customer" {
+		t.Errorf("unexpected audit trail %+v %+v", purges[0], purges[1])
+	}
+	plain := sha256.Sum256([]byte("ada@example.com"))
+	if len(purges[0].SubjectHash) != 64 || strings.Contains(purges[0].SubjectHash, "ada") || purges[0].SubjectHash == hex.EncodeToString(plain[:]) {
+		t.Errorf("expected a keyed hash of the subject, got %q", purges[0].SubjectHash)
+	}
+}
+
+func TestPurgeSubject_Invalid(t *testing.T) {
+	store := createTestStorage(t)
+	for _, req := range []engine.PurgeRequest{{}, {Subject: "ad"}} {
+		_, err := engine.PurgeSubject(context.Background(), store, []byte("hash-key"), req)
+		var fieldErr *engine.FieldError
+		if !errors.As(err, &fieldErr) || fieldErr.Field != "subject" {
+			t.Errorf("expected a subject error for %+v, got %v", req, err)
+		}
+	}
+	if _, err := engine.PurgeSubject(context.Background(), store, nil, engine.PurgeRequest{Subject: "ada@example.com"}); err == nil {
+		t.Error("expected a purge without a hash key to be refused")
+	}
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// Quota limits what a workflow may use, so a runaway automation can't take over
+// a shared server. A limit of 0 means none.
+type Quota struct {
+	ExecutionsPerDay int   `json:"executions_per_day"` // Executions started per day (UTC)
+	StorageBytes     int64 `json:"storage_bytes"`      // Bytes stored by its executions in history
+}
+
+// Quota limits, as reported by QuotaExceededError.
+const (
+	QuotaExecutionsPerDay = "executions_per_day"
+	QuotaStorageBytes     = "storage_bytes"
+)
+
+// QuotaExceededError is returned when a workflow can't start an execution
+// because it used up one of its limits.
+type QuotaExceededError struct {
+	WorkflowID string
+	Limit      string // QuotaExecutionsPerDay or QuotaStorageBytes
+	Max        int64
+	// RetryAt is when the limit resets, zero if it only frees up as history is pruned
+	RetryAt time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	if e.Limit == QuotaStorageBytes {
+		return fmt.Sprintf("workflow %s exceeded its quota of %d bytes stored", e.WorkflowID, e.Max)
+	}
+	return fmt.Sprintf("workflow %s exceeded its quota of %d executions per day", e.WorkflowID, e.Max)
+}
+
+// Quotas enforces the quotas of workflows: Default, unless an admin set a
+// storage.WorkflowQuota for the workflow, or lifted its limits for a while.
+// Executions resumed after a wait and test runs aren't limited.
+type Quotas struct {
+	Store   storage.Storage
+	Default Quota
+}
+
+// NewQuotas creates an enforcer of quotas with the given default.
+func NewQuotas(store storage.Storage, def Quota) *Quotas {
+	return &Quotas{Store: store, Default: def}
+}
+
+// For returns the quota of a workflow, and the quota set for it by an admin,
+// nil if none was.
+func (q *Quotas) For(ctx context.Context, workflowID string) (Quota, *storage.WorkflowQuota, error) {
+	quota := q.Default
+	set, err := q.Store.GetWorkflowQuota(ctx, workflowID)
+	if err != nil || set == nil {
+		return quota, nil, err
+	}
+	if set.ExecutionsPerDay > 0 {
+		quota.ExecutionsPerDay = set.ExecutionsPerDay
+	}
+	if set.StorageBytes > 0 {
+		quota.StorageBytes = set.StorageBytes
+	}
+	return quota, set, nil
+}
+
+// Check returns a *QuotaExceededError if the workflow may not start another
+// execution at now. A nil Quotas allows everything.
+func (q *Quotas) Check(ctx context.Context, workflowID string, now time.Time) error {
+	if q == nil {
+		return nil
+	}
+	quota, set, err := q.For(ctx, workflowID)
+	if err != nil {
+		return err
+	}
+	if set != nil && set.OverrideUntil != nil && now.Before(*set.OverrideUntil) {
+		return nil
+	}
+
+	if quota.ExecutionsPerDay > 0 {
+		day := now.UTC().Truncate(24 * time.Hour)
+		executions, _, err := q.Store.WorkflowUsageSince(ctx, workflowID, day)
+		if err != nil {
+			return err
+		}
+		if executions >= quota.ExecutionsPerDay {
+			return &QuotaExceededError{WorkflowID: workflowID, Limit: QuotaExecutionsPerDay, Max: int64(quota.ExecutionsPerDay), RetryAt: day.AddDate(0, 0, 1)}
+		}
+	}
+	if quota.StorageBytes > 0 {
+		_, usage, err := q.Store.WorkflowUsageSince(ctx, workflowID, time.Time{})
+		if err != nil {
+			return err
+		}
+		if usage.BytesStored >= quota.StorageBytes {
+			return &QuotaExceededError{WorkflowID: workflowID, Limit: QuotaStorageBytes, Max: quota.StorageBytes}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package engine_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func TestQuotas_Check(t *testing.T) {
+	store := createTestStorage(t)
+	ctx := context.Background()
+	quotas := engine.NewQuotas(store, engine.Quota{ExecutionsPerDay: 2, StorageBytes: 1000})
+	now := time.Now()
+
+	check := func() *engine.QuotaExceededError {
+		t.Helper()
+		err := quotas.Check(ctx, "wf", now)
+		var quotaErr *engine.QuotaExceededError
+		if err != nil && !errors.As(err, &quotaErr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return quotaErr
+	}
+
+	first, _ := store.CreateExecution(ctx, "wf")
+	testRun, _ := store.CreateExecution(ctx, "wf")
+	store.MarkTestExecution(ctx, testRun)
+	if err := check(); err != nil {
+		t.Fatalf("expected test runs not to count, got %v", err)
+	}
+	store.CreateExecution(ctx, "wf")
+	err := check()
+	if err == nil || err.Limit != engine.QuotaExecutionsPerDay || !err.RetryAt.After(now) {
+		t.Fatalf("expected the executions per day to be exceeded until tomorrow, got %+v", err)
+	}
+
+	// A quota set by an admin replaces the default limits it sets
+	store.SetWorkflowQuota(ctx, &storage.WorkflowQuota{WorkflowID: "wf", ExecutionsPerDay: 10})
+	if err := check(); err != nil {
+		t.Fatalf("expected the raised quota to allow runs, got %v", err)
+	}
+	store.AddExecutionUsage(ctx, first, storage.ExecutionUsage{BytesStored: 1000})
+	if err := check(); err == nil || err.Limit != engine.QuotaStorageBytes || !err.RetryAt.IsZero() {
+		t.Fatalf("expected the default storage quota to be exceeded, got %+v", err)
+	}
+
+	// Lifting the limits lets runs through until the override ends
+	until := now.Add(time.Hour)
+	store.SetWorkflowQuota(ctx, &storage.WorkflowQuota{WorkflowID: "wf", OverrideUntil: &until})
+	if err := check(); err != nil {
+		t.Fatalf("expected the override to allow runs, got %v", err)
+	}
+	now = until.Add(time.Minute)
+	if err := check(); err == nil {
+		t.Error("expected the limits to apply again after the override")
+	}
+}
+
+// TestWorkflowRunner_Quota verifies that a runner with quotas refuses to start
+// executions of a workflow over its quota, except test runs.
+func TestWorkflowRunner_Quota(t *testing.T) {
+	workflow := engine.Workflow{
+		ID: "quota-wf",
+		Nodes: map[string]engine.Node{
+			"set": {ID: "set", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "x", "value": 1.0}},
+		},
+	}
+	store := createTestStorage(t)
+	ctx := context.Background()
+	quotas := engine.NewQuotas(store, engine.Quota{ExecutionsPerDay: 1})
+
+	run := func(test bool) error {
+		execCtx := engine.NewExecutionContext(workflow.ID)
+		execCtx.Test = test
+		runner := engine.NewWorkflowRunner(execCtx, t.TempDir(), store, nil)
+		runner.SetQuotas(quotas)
+		return runner.Run(ctx, workflow)
+	}
+	if err := run(false); err != nil {
+		t.Fatalf("expected the first run to complete, got %v", err)
+	}
+	var quotaErr *engine.QuotaExceededError
+	if err := run(false); !errors.As(err, &quotaErr) {
+		t.Fatalf("expected the second run to exceed the quota, got %v", err)
+	}
+	if err := run(true); err != nil {
+		t.Errorf("expected test runs not to be limited, got %v", err)
+	}
+	if executions, _ := store.ListExecutions(ctx, workflow.ID, 10); len(executions) != 2 {
+		t.Errorf("expected no execution for the refused run, got %d", len(executions))
+	}
+}
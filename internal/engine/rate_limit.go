@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// rateLimitConfig is the config of a std/rate_limit node. Executions sharing a
+// key share the budget, across workflows and restarts:
+//
+//	{"key": "crm-api", "limit": 100, "window": 1, "unit": "m"}
+type rateLimitConfig struct {
+	Key   string `json:"key"`
+	Limit int    `json:"limit"`
+	// Window and Unit (ms, s, m, h; s by default) set the length of the fixed
+	// windows the limit applies to, aligned to the Unix epoch.
+	Window float64 `json:"window"`
+	Unit   string  `json:"unit"`
+}
+
+// executeRateLimit runs a std/rate_limit node: it counts the execution against
+// the key's current window and routes to "pass" while the count is within the
+// limit and to "limited" after that, outputting {"key", "count", "limit",
+// "remaining", "resetAt"}. A "limited" branch can wait with std/delay until
+// resetAt and loop back to retry.
+func executeRateLimit(ctx context.Context, store storage.Storage, input map[string]interface{}) (any, error) {
+	var cfg rateLimitConfig
+	raw, _ := json.Marshal(input["config"])
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("rate_limit: invalid config: %w", err)
+	}
+	if cfg.Key == "" {
+		return nil, fmt.Errorf("rate_limit: config 'key' is required")
+	}
+	if cfg.Limit <= 0 {
+		return nil, fmt.Errorf("rate_limit: config 'limit' must be a positive number")
+	}
+	if cfg.Unit == "" {
+		cfg.Unit = "s"
+	}
+	scale, ok := delayUnits[cfg.Unit]
+	if !ok {
+		return nil, fmt.Errorf("rate_limit: config 'unit' must be 'ms', 's', 'm' or 'h'")
+	}
+	window := time.Duration(cfg.Window * float64(scale))
+	if window < time.Millisecond {
+		return nil, fmt.Errorf("rate_limit: config 'window' must be at least 1ms")
+	}
+	if store == nil {
+		return nil, fmt.Errorf("rate_limit: needs a runner with storage")
+	}
+
+	windowStart := time.Now().Truncate(window)
+	count, err := store.IncrementRateLimit(ctx, cfg.Key, windowStart)
+	if err != nil {
+		return nil, fmt.Errorf("rate_limit: %w", err)
+	}
+
+	port := "pass"
+	if count > cfg.Limit {
+		port = "limited"
+	}
+	return map[string]interface{}{
+		"data": map[string]interface{}{
+			"key":       cfg.Key,
+			"count":     count,
+			"limit":     cfg.Limit,
+			"remaining": max(cfg.Limit-count, 0),
+			"resetAt":   windowStart.Add(window).UTC().Format(time.RFC3339Nano),
+		},
+		"port": port,
+	}, nil
+}
@@ -0,0 +1,58 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// rateLimitedWorkflow records in $vars.port which port the rate limit routed to.
+func rateLimitedWorkflow(key string) engine.Workflow {
+	return engine.Workflow{
+		ID:   "rate-wf",
+		Name: "Rate limited",
+		Nodes: map[string]engine.Node{
+			"limit": {ID: "limit", Type: engine.NodeTypeRateLimit, Config: map[string]interface{}{
+				"key": key, "limit": 2.0, "window": 1.0, "unit": "h",
+			}},
+			"pass":    {ID: "pass", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "port", "value": "pass"}},
+			"limited": {ID: "limited", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "port", "value": "limited"}},
+		},
+		Edges: []engine.Edge{
+			{ID: "e1", Source: "limit", Target: "pass", SourceHandle: "pass"},
+			{ID: "e2", Source: "limit", Target: "limited", SourceHandle: "limited"},
+		},
+	}
+}
+
+// TestWorkflowRunner_RateLimit verifies that std/rate_limit shares its budget
+// across executions through storage and routes to "limited" once it is spent.
+func TestWorkflowRunner_RateLimit(t *testing.T) {
+	store := createTestStorage(t)
+	run := func(key string) interface{} {
+		t.Helper()
+		ectx := engine.NewExecutionContext("rate-wf")
+		runner := engine.NewWorkflowRunner(ectx, t.TempDir(), store, nil)
+		if err := runner.Run(context.Background(), rateLimitedWorkflow(key)); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		return ectx.GetVar("port")
+	}
+
+	for i, want := range []string{"pass", "pass", "limited"} {
+		if got := run("crm-api"); got != want {
+			t.Errorf("execution %d: expected %s, got %v", i+1, want, got)
+		}
+	}
+	if got := run("billing-api"); got != "pass" {
+		t.Errorf("expected another key to have its own budget, got %v", got)
+	}
+
+	runner := engine.NewWorkflowRunner(engine.NewExecutionContext("rate-wf"), t.TempDir(), store, nil)
+	invalid := rateLimitedWorkflow("crm-api")
+	invalid.Nodes["limit"].Config["limit"] = 0.0
+	if err := runner.Run(context.Background(), invalid); err == nil {
+		t.Error("expected a non-positive limit to fail")
+	}
+}
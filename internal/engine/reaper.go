@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+const (
+	// defaultReaperTimeout is how long a running execution may go without a
+	// sign of life before it is presumed lost.
+	defaultReaperTimeout = 10 * time.Minute
+	// defaultReaperPollInterval is how often the reaper looks for lost executions.
+	defaultReaperPollInterval = time.Minute
+	// reaperBatch bounds how many stale executions are looked at per poll.
+	reaperBatch = 100
+)
+
+// ExecutionReaper fails executions left running by a worker that went away: a
+// crashed or killed process never records their outcome, so they would stay
+// running forever. An execution is presumed lost once it has been running
+// without a heartbeat for Timeout and isn't active in this process's registry.
+type ExecutionReaper struct {
+	// Timeout is how long a running execution may go without a heartbeat.
+	Timeout time.Duration
+	// PollInterval is how often stale executions are looked up.
+	PollInterval time.Duration
+
+	store    storage.Storage
+	registry *ExecutionRegistry
+	events   *EventBus
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewExecutionReaper creates a reaper for the executions in store; those active
+// in registry are never reaped.
+func NewExecutionReaper(store storage.Storage, registry *ExecutionRegistry) *ExecutionReaper {
+	return &ExecutionReaper{
+		Timeout:      defaultReaperTimeout,
+		PollInterval: defaultReaperPollInterval,
+		store:        store,
+		registry:     registry,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// SetEventBus makes the reaper publish ExecutionFinished for the executions it fails.
+func (r *ExecutionReaper) SetEventBus(bus *EventBus) {
+	r.events = bus
+}
+
+// Start begins looking for lost executions in the background.
+func (r *ExecutionReaper) Start() {
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.PollInterval)
+		defer ticker.Stop()
+		for {
+			r.Reap(context.Background())
+			select {
+			case <-ticker.C:
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops looking for lost executions.
+func (r *ExecutionReaper) Stop() {
+	r.stopOnce.Do(func() { close(r.stop) })
+	<-r.done
+}
+
+// Reap fails the executions presumed lost now and returns how many it failed.
+func (r *ExecutionReaper) Reap(ctx context.Context) int {
+	now := time.Now()
+	execs, err := r.store.ListStaleExecutions(ctx, now.Add(-r.Timeout), reaperBatch)
+	if err != nil {
+		log.Printf("Execution reaper: failed to list stale executions: %v", err)
+		return 0
+	}
+	reaped := 0
+	for _, exec := range execs {
+		if r.registry != nil && r.registry.IsActive(exec.ID) {
+			continue
+		}
+		msg := fmt.Sprintf("worker lost: no heartbeat for %s", r.Timeout)
+		// Only a still-running execution is failed, in case it finished meanwhile
+		ok, err := r.store.TransitionExecutionStatus(ctx, exec.ID, storage.ExecutionStatusRunning, storage.ExecutionStatusFailed, nil, &msg)
+		if err != nil {
+			log.Printf("Execution reaper: failed to fail execution %s: %v", exec.ID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		reaped++
+		log.Printf("Execution reaper: execution %s of workflow %s failed: %s", exec.ID, exec.WorkflowID, msg)
+		r.events.Publish(ExecutionFinished{
+			WorkflowID:  exec.WorkflowID,
+			ExecutionID: exec.ID,
+			Status:      string(storage.ExecutionStatusFailed),
+			Error:       msg,
+			Duration:    now.Sub(exec.StartedAt),
+			Time:        now,
+		})
+	}
+	return reaped
+}
@@ -0,0 +1,63 @@
+package engine_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func TestExecutionReaper(t *testing.T) {
+	ctx := context.Background()
+	store := createTestStorage(t)
+	registry := engine.NewExecutionRegistry()
+
+	lost, _ := store.CreateExecution(ctx, "wf-reap")
+	active, _ := store.CreateExecution(ctx, "wf-reap")
+	registry.Register(active, func() {})
+	completed, _ := store.CreateExecution(ctx, "wf-reap")
+	store.UpdateExecutionStatus(ctx, completed, storage.ExecutionStatusCompleted, []byte(`{}`), nil)
+
+	bus := engine.NewEventBus()
+	finished := make(chan engine.ExecutionFinished, 10)
+	defer bus.Subscribe(func(ev engine.Event) { finished <- ev.(engine.ExecutionFinished) }, engine.EventExecutionFinished)()
+
+	reaper := engine.NewExecutionReaper(store, registry)
+	reaper.SetEventBus(bus)
+	reaper.Timeout = time.Hour
+	if n := reaper.Reap(ctx); n != 0 {
+		t.Fatalf("expected executions seen within the timeout to be kept, reaped %d", n)
+	}
+
+	reaper.Timeout = time.Millisecond
+	time.Sleep(20 * time.Millisecond)
+	if n := reaper.Reap(ctx); n != 1 {
+		t.Fatalf("expected 1 execution to be reaped, got %d", n)
+	}
+	exec, _ := store.GetExecution(ctx, lost)
+	if exec.Status != storage.ExecutionStatusFailed || exec.Error == nil || !strings.Contains(*exec.Error, "worker lost") {
+		t.Errorf("expected the lost execution to fail with worker lost, got %s (%v)", exec.Status, exec.Error)
+	}
+	if exec, _ := store.GetExecution(ctx, active); exec.Status != storage.ExecutionStatusRunning {
+		t.Errorf("expected the active execution to keep running, got %s", exec.Status)
+	}
+	if exec, _ := store.GetExecution(ctx, completed); exec.Status != storage.ExecutionStatusCompleted {
+		t.Errorf("expected the completed execution to be left alone, got %s", exec.Status)
+	}
+
+	select {
+	case ev := <-finished:
+		if ev.ExecutionID != lost || ev.Status != string(storage.ExecutionStatusFailed) {
+			t.Errorf("unexpected event %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected an ExecutionFinished event for the lost execution")
+	}
+
+	if n := reaper.Reap(ctx); n != 0 {
+		t.Errorf("expected nothing left to reap, got %d", n)
+	}
+}
@@ -0,0 +1,52 @@
+package engine
+
+import "strings"
+
+// redactedValue replaces the values of secret fields in stored node inputs.
+const redactedValue = "[REDACTED]"
+
+// secretKeys are the field names, lowercased without '_' and '-', whose values
+// are redacted: credentials in node configs and auth headers in HTTP requests.
+var secretKeys = []string{"password", "passphrase", "secret", "token", "apikey", "authorization", "privatekey", "credentials", "cookie"}
+
+// isSecretKey reports whether a config field holds a secret.
+func isSecretKey(key string) bool {
+	key = strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactSecrets returns a copy of v with the values of secret fields replaced,
+// at any depth, so that node inputs can be stored for debugging.
+func redactSecrets(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			switch value.(type) {
+			case nil, bool, float64, int:
+				// Settings such as max_tokens, not secrets
+				out[key] = value
+				continue
+			}
+			if isSecretKey(key) {
+				out[key] = redactedValue
+			} else {
+				out[key] = redactSecrets(value)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, value := range v {
+			out[i] = redactSecrets(value)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -3,29 +3,50 @@ package engine
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 )
 
+// ActiveExecution describes an execution running in this process.
+type ActiveExecution struct {
+	ExecutionID string
+	WorkflowID  string
+	TriggerID   string // Empty when no trigger started the run
+	StartedAt   time.Time
+}
+
+// activeEntry is a registered execution and the function that stops it.
+type activeEntry struct {
+	info   ActiveExecution
+	cancel context.CancelFunc
+}
+
 // ExecutionRegistry manages active workflow executions
 // Allows cancellation of running workflows via context
 type ExecutionRegistry struct {
 	mu       sync.RWMutex
-	contexts map[string]context.CancelFunc
+	contexts map[string]activeEntry
 }
 
 // NewExecutionRegistry creates a new registry for tracking active executions
 func NewExecutionRegistry() *ExecutionRegistry {
 	return &ExecutionRegistry{
-		contexts: make(map[string]context.CancelFunc),
+		contexts: make(map[string]activeEntry),
 	}
 }
 
 // Register adds an execution to the registry with its cancel function
 // This allows the execution to be stopped via Cancel()
 func (r *ExecutionRegistry) Register(execID string, cancel context.CancelFunc) {
+	r.RegisterActive(ActiveExecution{ExecutionID: execID, StartedAt: time.Now()}, cancel)
+}
+
+// RegisterActive is Register with a description of the execution, listed by Active.
+func (r *ExecutionRegistry) RegisterActive(info ActiveExecution, cancel context.CancelFunc) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.contexts[execID] = cancel
+	r.contexts[info.ExecutionID] = activeEntry{info: info, cancel: cancel}
 }
 
 // Unregister removes an execution from the registry
@@ -42,13 +63,13 @@ func (r *ExecutionRegistry) Cancel(execID string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	cancel, exists := r.contexts[execID]
+	entry, exists := r.contexts[execID]
 	if !exists {
 		return fmt.Errorf("execution not found or already completed: %s", execID)
 	}
 
 	// Call cancel function to stop the execution
-	cancel()
+	entry.cancel()
 
 	// Remove from registry
 	delete(r.contexts, execID)
@@ -71,14 +92,32 @@ func (r *ExecutionRegistry) ActiveCount() int {
 	return len(r.contexts)
 }
 
+// Active returns the active executions, oldest first.
+func (r *ExecutionRegistry) Active() []ActiveExecution {
+	r.mu.RLock()
+	active := make([]ActiveExecution, 0, len(r.contexts))
+	for _, entry := range r.contexts {
+		active = append(active, entry.info)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(active, func(i, j int) bool {
+		if !active[i].StartedAt.Equal(active[j].StartedAt) {
+			return active[i].StartedAt.Before(active[j].StartedAt)
+		}
+		return active[i].ExecutionID < active[j].ExecutionID
+	})
+	return active
+}
+
 // CancelAll stops all active executions
 // Useful for graceful shutdown
 func (r *ExecutionRegistry) CancelAll() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	for execID, cancel := range r.contexts {
-		cancel()
+	for execID, entry := range r.contexts {
+		entry.cancel()
 		delete(r.contexts, execID)
 	}
 }
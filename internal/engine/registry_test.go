@@ -3,6 +3,7 @@ package engine_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/conv3n/conv3n/internal/engine"
 )
@@ -100,4 +101,20 @@ func TestExecutionRegistry(t *testing.T) {
 			t.Error("expected ctx2 to be cancelled")
 		}
 	})
+
+	t.Run("Active", func(t *testing.T) {
+		registry := engine.NewExecutionRegistry()
+		now := time.Now()
+		registry.RegisterActive(engine.ActiveExecution{ExecutionID: "exec-new", WorkflowID: "wf-1", StartedAt: now}, func() {})
+		registry.RegisterActive(engine.ActiveExecution{ExecutionID: "exec-old", WorkflowID: "wf-2", TriggerID: "tr-1", StartedAt: now.Add(-time.Minute)}, func() {})
+
+		active := registry.Active()
+		if len(active) != 2 || active[0].ExecutionID != "exec-old" || active[0].TriggerID != "tr-1" || active[1].ExecutionID != "exec-new" {
+			t.Errorf("expected the oldest execution first, got %+v", active)
+		}
+		registry.Cancel("exec-old")
+		if active := registry.Active(); len(active) != 1 || active[0].ExecutionID != "exec-new" {
+			t.Errorf("expected the cancelled execution to be gone, got %+v", active)
+		}
+	})
 }
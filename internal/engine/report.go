@@ -0,0 +1,217 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// EventWorkflowReport is the type of the periodic health digest of a workflow.
+const EventWorkflowReport EventType = "workflow.report"
+
+// Report periods.
+const (
+	ReportDaily  = "daily"
+	ReportWeekly = "weekly"
+)
+
+// reportSlowestNodes is how many nodes a report lists as the slowest.
+const reportSlowestNodes = 5
+
+// WorkflowReport is the health digest of a workflow over a period: how often it
+// ran, how often it failed and which nodes took longest. Test-mode runs are left out.
+type WorkflowReport struct {
+	WorkflowID   string `json:"workflow_id"`
+	WorkflowName string `json:"workflow_name"`
+	Period       string `json:"period"`
+	// From and To bound the period; executions started at From are included,
+	// those started at To are not.
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Runs      int       `json:"runs"`
+	Succeeded int       `json:"succeeded"`
+	Failed    int       `json:"failed"`
+	// FailureRate is Failed over the finished runs, from 0 to 1.
+	FailureRate  float64      `json:"failure_rate"`
+	SlowestNodes []NodeTiming `json:"slowest_nodes"`
+	Time         time.Time    `json:"time"`
+}
+
+// NodeTiming is how long a node took across the runs of a report.
+type NodeTiming struct {
+	NodeID  string        `json:"node_id"`
+	Runs    int           `json:"runs"`
+	Average time.Duration `json:"average"`
+	Max     time.Duration `json:"max"`
+}
+
+func (e WorkflowReport) EventType() EventType { return EventWorkflowReport }
+func (e WorkflowReport) EventTime() time.Time { return e.Time }
+
+// BuildWorkflowReport computes the report of a workflow for the executions
+// started in [from, to).
+func BuildWorkflowReport(ctx context.Context, store storage.Storage, workflowID, period string, from, to time.Time) (*WorkflowReport, error) {
+	wf, err := store.GetWorkflow(ctx, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	execs, err := store.ListExecutions(ctx, workflowID, -1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list executions: %w", err)
+	}
+
+	report := &WorkflowReport{
+		WorkflowID:   workflowID,
+		WorkflowName: wf.Name,
+		Period:       period,
+		From:         from,
+		To:           to,
+		SlowestNodes: []NodeTiming{},
+		Time:         time.Now(),
+	}
+	timings := make(map[string]*NodeTiming)
+	total := make(map[string]time.Duration)
+	for _, exec := range execs {
+		if exec.Test || exec.StartedAt.Before(from) || !exec.StartedAt.Before(to) {
+			continue
+		}
+		report.Runs++
+		switch exec.Status {
+		case storage.ExecutionStatusCompleted:
+			report.Succeeded++
+		case storage.ExecutionStatusFailed:
+			report.Failed++
+		}
+
+		nodes, err := store.ListNodeExecutions(ctx, exec.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list node executions: %w", err)
+		}
+		for _, ne := range nodes {
+			if ne.StartedAt == nil || ne.FinishedAt == nil {
+				continue // skipped, cached or still running
+			}
+			d := ne.FinishedAt.Sub(*ne.StartedAt)
+			t, ok := timings[ne.NodeID]
+			if !ok {
+				t = &NodeTiming{NodeID: ne.NodeID}
+				timings[ne.NodeID] = t
+			}
+			t.Runs++
+			total[ne.NodeID] += d
+			t.Max = max(t.Max, d)
+		}
+	}
+	if finished := report.Succeeded + report.Failed; finished > 0 {
+		report.FailureRate = float64(report.Failed) / float64(finished)
+	}
+
+	for id, t := range timings {
+		t.Average = total[id] / time.Duration(t.Runs)
+		report.SlowestNodes = append(report.SlowestNodes, *t)
+	}
+	sort.Slice(report.SlowestNodes, func(i, j int) bool {
+		a, b := report.SlowestNodes[i], report.SlowestNodes[j]
+		if a.Average != b.Average {
+			return a.Average > b.Average
+		}
+		return a.NodeID < b.NodeID
+	})
+	if len(report.SlowestNodes) > reportSlowestNodes {
+		report.SlowestNodes = report.SlowestNodes[:reportSlowestNodes]
+	}
+	return report, nil
+}
+
+// ReportScheduler publishes a WorkflowReport for every workflow on the event
+// bus at the end of each day or week (UTC midnight, weeks starting on Monday).
+// Subscribers deliver them, e.g. the event sink posts them to Kafka or NATS.
+type ReportScheduler struct {
+	period string
+	store  storage.Storage
+	events *EventBus
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewReportScheduler creates a scheduler for the period, ReportDaily or ReportWeekly.
+func NewReportScheduler(period string, store storage.Storage, events *EventBus) (*ReportScheduler, error) {
+	if period != ReportDaily && period != ReportWeekly {
+		return nil, fmt.Errorf("report period must be %s or %s, got %q", ReportDaily, ReportWeekly, period)
+	}
+	return &ReportScheduler{
+		period: period,
+		store:  store,
+		events: events,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// Start publishes the reports at the end of every period until Stop is called.
+func (s *ReportScheduler) Start() {
+	go func() {
+		defer close(s.done)
+		for {
+			end := ReportPeriodEnd(s.period, time.Now())
+			timer := time.NewTimer(time.Until(end))
+			select {
+			case <-timer.C:
+				s.Publish(context.Background(), end)
+			case <-s.stop:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the scheduler and waits for it to exit.
+func (s *ReportScheduler) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+}
+
+// Publish publishes the report of every workflow for the period ending at end.
+func (s *ReportScheduler) Publish(ctx context.Context, end time.Time) {
+	workflows, err := s.store.ListWorkflows(ctx)
+	if err != nil {
+		log.Printf("Reports: failed to list workflows: %v", err)
+		return
+	}
+	from := ReportPeriodStart(s.period, end)
+	for _, wf := range workflows {
+		report, err := BuildWorkflowReport(ctx, s.store, wf.ID, s.period, from, end)
+		if err != nil {
+			log.Printf("Reports: failed to build report of workflow %s: %v", wf.ID, err)
+			continue
+		}
+		s.events.Publish(*report)
+	}
+}
+
+// ReportPeriodEnd returns the end of the period containing t, in UTC.
+func ReportPeriodEnd(period string, t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if period == ReportWeekly {
+		// Days until the next Monday; Sunday is weekday 0
+		return day.AddDate(0, 0, 7-(int(day.Weekday())+6)%7)
+	}
+	return day.AddDate(0, 0, 1)
+}
+
+// ReportPeriodStart returns the start of the period ending at end.
+func ReportPeriodStart(period string, end time.Time) time.Time {
+	if period == ReportWeekly {
+		return end.AddDate(0, 0, -7)
+	}
+	return end.AddDate(0, 0, -1)
+}
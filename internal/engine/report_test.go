@@ -0,0 +1,122 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func TestBuildWorkflowReport(t *testing.T) {
+	store := createTestStorage(t)
+	ctx := context.Background()
+	if err := store.CreateWorkflow(ctx, &storage.Workflow{ID: "wf-1", Name: "Nightly sync", Definition: []byte("{}")}); err != nil {
+		t.Fatalf("failed to create workflow: %v", err)
+	}
+
+	// run creates an execution with the given status whose "slow" node takes longer than "fast"
+	run := func(status storage.ExecutionStatus, test bool) {
+		id, err := store.CreateExecution(ctx, "wf-1")
+		if err != nil {
+			t.Fatalf("failed to create execution: %v", err)
+		}
+		if test {
+			store.MarkTestExecution(ctx, id)
+		}
+		for _, node := range []struct {
+			id    string
+			sleep time.Duration
+		}{{"fast", 0}, {"slow", 20 * time.Millisecond}} {
+			store.StartNodeExecution(ctx, id, node.id)
+			time.Sleep(node.sleep)
+			store.FinishNodeExecution(ctx, id, node.id, storage.NodeStatusSuccess, "default", nil)
+		}
+		store.FinishNodeExecution(ctx, id, "skipped", storage.NodeStatusSkipped, "", nil)
+		store.UpdateExecutionStatus(ctx, id, status, nil, nil)
+	}
+
+	from := time.Now().Add(-time.Minute)
+	run(storage.ExecutionStatusCompleted, false)
+	run(storage.ExecutionStatusCompleted, false)
+	run(storage.ExecutionStatusFailed, false)
+	run(storage.ExecutionStatusCancelled, false)
+	run(storage.ExecutionStatusFailed, true) // test-mode runs are left out
+	to := time.Now().Add(time.Minute)
+
+	report, err := engine.BuildWorkflowReport(ctx, store, "wf-1", engine.ReportDaily, from, to)
+	if err != nil {
+		t.Fatalf("BuildWorkflowReport failed: %v", err)
+	}
+	if report.WorkflowName != "Nightly sync" || report.Runs != 4 || report.Succeeded != 2 || report.Failed != 1 {
+		t.Errorf("unexpected counts: %+v", report)
+	}
+	if report.FailureRate < 0.33 || report.FailureRate > 0.34 {
+		t.Errorf("expected a failure rate of 1/3, got %v", report.FailureRate)
+	}
+	if len(report.SlowestNodes) != 2 || report.SlowestNodes[0].NodeID != "slow" || report.SlowestNodes[0].Runs != 4 {
+		t.Fatalf("expected slow then fast over 4 runs, got %+v", report.SlowestNodes)
+	}
+	if slow := report.SlowestNodes[0]; slow.Average < 20*time.Millisecond || slow.Max < slow.Average {
+		t.Errorf("unexpected timing of the slow node: %+v", slow)
+	}
+
+	// Executions outside the period are left out
+	report, err = engine.BuildWorkflowReport(ctx, store, "wf-1", engine.ReportDaily, to, to.Add(time.Hour))
+	if err != nil || report.Runs != 0 || report.FailureRate != 0 || len(report.SlowestNodes) != 0 {
+		t.Errorf("expected an empty report, got %+v (%v)", report, err)
+	}
+}
+
+func TestReportScheduler_Publish(t *testing.T) {
+	store := createTestStorage(t)
+	ctx := context.Background()
+	for _, id := range []string{"wf-1", "wf-2"} {
+		store.CreateWorkflow(ctx, &storage.Workflow{ID: id, Name: id, Definition: []byte("{}")})
+	}
+
+	if _, err := engine.NewReportScheduler("hourly", store, nil); err == nil {
+		t.Error("expected an invalid period to be rejected")
+	}
+
+	bus := engine.NewEventBus()
+	var rec eventRecorder
+	unsub := bus.Subscribe(rec.handle, engine.EventWorkflowReport)
+	reports, err := engine.NewReportScheduler(engine.ReportWeekly, store, bus)
+	if err != nil {
+		t.Fatalf("NewReportScheduler failed: %v", err)
+	}
+	end := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+	reports.Publish(ctx, end)
+	unsub()
+
+	if len(rec.events) != 2 {
+		t.Fatalf("expected a report per workflow, got %d", len(rec.events))
+	}
+	report := rec.events[0].(engine.WorkflowReport)
+	if report.Period != engine.ReportWeekly || !report.From.Equal(end.AddDate(0, 0, -7)) || !report.To.Equal(end) {
+		t.Errorf("unexpected period: %s from %s to %s", report.Period, report.From, report.To)
+	}
+}
+
+func TestReportPeriodEnd(t *testing.T) {
+	// Wednesday 2026-03-04 15:30 in UTC+2
+	at := time.Date(2026, 3, 4, 15, 30, 0, 0, time.FixedZone("EET", 2*3600))
+	tests := []struct {
+		period string
+		at     time.Time
+		want   time.Time
+	}{
+		{engine.ReportDaily, at, time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{engine.ReportWeekly, at, time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)},
+		// A week ending now ends next Monday
+		{engine.ReportWeekly, time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{engine.ReportWeekly, time.Date(2026, 3, 8, 23, 59, 0, 0, time.UTC), time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := engine.ReportPeriodEnd(tt.period, tt.at); !got.Equal(tt.want) {
+			t.Errorf("ReportPeriodEnd(%s, %s) = %s, want %s", tt.period, tt.at, got, tt.want)
+		}
+	}
+}
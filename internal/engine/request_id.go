@@ -0,0 +1,37 @@
+package engine
+
+import (
+	"context"
+	"log"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the ID of the API request it
+// serves. Executions created under it record the ID, so they can be told
+// apart in the access log and the engine's own logs.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, empty if none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// saveRequestID records the request ID carried by ctx on a new execution and
+// returns it.
+func saveRequestID(ctx context.Context, store storage.Storage, execID, workflowID string) string {
+	requestID := RequestIDFromContext(ctx)
+	if requestID == "" {
+		return ""
+	}
+	if err := store.SetExecutionRequestID(ctx, execID, requestID); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	log.Printf("Execution %s of workflow %s started by request %s", execID, workflowID, requestID)
+	return requestID
+}
@@ -0,0 +1,38 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+func TestWorkflowRunner_RequestID(t *testing.T) {
+	store := createTestStorage(t)
+	workflow := engine.Workflow{ID: "wf-request", Nodes: map[string]engine.Node{
+		"mark": {ID: "mark", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "seen", "value": true}},
+	}}
+
+	bus := engine.NewEventBus()
+	started := make(chan engine.ExecutionStarted, 1)
+	defer bus.Subscribe(func(ev engine.Event) { started <- ev.(engine.ExecutionStarted) }, engine.EventExecutionStarted)()
+
+	runner := engine.NewWorkflowRunner(engine.NewExecutionContext(workflow.ID), t.TempDir(), store, nil)
+	runner.SetEventBus(bus)
+	ctx := engine.WithRequestID(context.Background(), "req-42")
+	if err := runner.Run(ctx, workflow); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	ev := <-started
+	if ev.RequestID != "req-42" {
+		t.Errorf("expected the started event to carry request ID req-42, got %q", ev.RequestID)
+	}
+	exec, err := store.GetExecution(context.Background(), ev.ExecutionID)
+	if err != nil {
+		t.Fatalf("failed to get execution: %v", err)
+	}
+	if exec.RequestID != "req-42" {
+		t.Errorf("expected the execution to record request ID req-42, got %q", exec.RequestID)
+	}
+}
@@ -4,32 +4,87 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/telemetry"
 )
 
-// BunRunner manages the execution of Bun scripts via OS subprocesses.
+// processWaitDelay bounds how long Wait lingers on I/O after the process is killed.
+const processWaitDelay = time.Second
+
+// BunRunner manages the execution of block scripts via OS subprocesses, in
+// Bun or another Runtime.
 type BunRunner struct {
-	// RuntimePath is the path to the bun executable (usually "bun").
+	// Runtime runs the scripts; Bun unless configured otherwise with SetRuntime.
+	Runtime Runtime
+	// RuntimePath is the path to the runtime executable (usually "bun").
 	RuntimePath string
 	// BlocksDir is the base directory where block scripts are located.
 	BlocksDir string
+	// Limits bounds memory and wall time of each spawned process.
+	Limits ResourceLimits
+	// CodeCacheDir keeps custom/code modules compiled once per code hash;
+	// empty compiles the code on every run.
+	CodeCacheDir string
+	// limiter caps concurrent processes; shared across all runners.
+	limiter *ProcessLimiter
 }
 
-// NewBunRunner creates a new runner instance.
+// NewBunRunner creates a new runner instance using the engine-wide resource limits.
 func NewBunRunner(blocksDir string) *BunRunner {
+	limits, limiter := CurrentResourceLimits()
+	rt := CurrentRuntime()
 	return &BunRunner{
-		RuntimePath: "bun",
-		BlocksDir:   blocksDir,
+		Runtime:      rt,
+		RuntimePath:  rt.Executable(),
+		BlocksDir:    blocksDir,
+		Limits:       limits,
+		CodeCacheDir: DefaultCodeCacheDir(),
+		limiter:      limiter,
 	}
 }
 
 // Execute runs the configured Bun script with the provided input payload.
 // It writes the input to the subprocess's Stdin and reads the result from Stdout.
 func (r *BunRunner) Execute(ctx context.Context, scriptPath string, input any) (any, error) {
-	// Prepare the command: bun run <script>
-	cmd := exec.CommandContext(ctx, r.RuntimePath, "run", scriptPath)
+	// Wait for a free process slot before spawning anything
+	release, err := r.limiter.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Enforce the hard wall-time limit on top of the caller's deadline
+	parentCtx := ctx
+	if r.Limits.MaxWallTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Limits.MaxWallTime)
+		defer cancel()
+	}
+
+	// Prepare the command, e.g. bun run <script>
+	rt := r.Runtime
+	if rt == nil {
+		rt = BunRuntime{}
+	}
+	path := r.RuntimePath
+	if path == "" {
+		path = rt.Executable()
+	}
+	cmd := exec.CommandContext(ctx, path, rt.Args(scriptPath, nil, r.Limits)...)
+	// Don't let orphaned grandchildren holding stdout keep Wait blocked after a kill
+	cmd.WaitDelay = processWaitDelay
+	if env := rt.Env(r.Limits); len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 
 	// Setup pipes
 	stdin, err := cmd.StdinPipe()
@@ -55,14 +110,45 @@ func (r *BunRunner) Execute(ctx context.Context, scriptPath string, input any) (
 		}
 	}()
 
-	// Wait for the process to finish
-	if err := cmd.Wait(); err != nil {
+	// Watch resident memory while the process runs
+	var memoryExceeded atomic.Bool
+	if r.Limits.MaxMemoryMB > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		go watchProcessMemory(cmd.Process, r.Limits.MaxMemoryMB, done, &memoryExceeded)
+	}
+
+	// Wait for the process to finish; its CPU time counts also when it failed
+	err = cmd.Wait()
+	if cmd.ProcessState != nil {
+		usageFrom(ctx).addCPUTime(cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime())
+	}
+	if err != nil {
+		if memoryExceeded.Load() {
+			return nil, fmt.Errorf("bun process killed: exceeded memory limit of %d MB", r.Limits.MaxMemoryMB)
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && parentCtx.Err() == nil {
+			return nil, fmt.Errorf("bun process killed: exceeded max wall time of %s", r.Limits.MaxWallTime)
+		}
+		// A block that failed with a JSON-RPC error response says why itself
+		var output any
+		if json.Unmarshal(stdout.Bytes(), &output) == nil {
+			if _, rpcErr := unwrapRPCResult(output); rpcErr != nil {
+				return nil, rpcErr
+			}
+		}
 		return nil, fmt.Errorf("bun execution failed: %v, stderr: %s", err, stderr.String())
 	}
 
+	// Attribute Bun CPU time to the active node span
+	span := telemetry.SpanFromContext(ctx)
+	span.SetAttr("bun.process_time_ms", cmd.ProcessState.UserTime()+cmd.ProcessState.SystemTime())
+	span.SetAttr("bun.pid", cmd.ProcessState.Pid())
+
 	// Log stderr for debugging (even on success)
 	if stderr.Len() > 0 {
-		fmt.Printf("[BunRunner stderr]: %s\n", stderr.String())
+		// Goes through log (stderr) so stdout stays clean for `conv3n run --output json`
+		log.Printf("[BunRunner stderr]: %s", stderr.String())
 	}
 
 	// Parse the output JSON
@@ -71,54 +157,165 @@ func (r *BunRunner) Execute(ctx context.Context, scriptPath string, input any) (
 		return nil, fmt.Errorf("failed to parse bun output: %w, raw output: %s, stderr: %s", err, stdout.String(), stderr.String())
 	}
 
-	return result, nil
+	return unwrapRPCResult(result)
 }
 
 // ExecuteBlock executes a specific block using the appropriate template.
 // Deprecated: Use ExecuteNode for graph-based workflows.
 func (r *BunRunner) ExecuteBlock(ctx context.Context, block Block, input any) (any, error) {
+	if result, err := executeNative(ctx, NodeType(block.Type), input); !errors.Is(err, errNotNative) {
+		return result, err
+	}
 	scriptPath := r.getScriptPath(NodeType(block.Type))
 	if scriptPath == "" {
 		return nil, fmt.Errorf("unknown block type: %s", block.Type)
 	}
+	if err := checkBunOnly(r.Runtime, NodeType(block.Type)); err != nil {
+		return nil, err
+	}
+	switch NodeType(block.Type) {
+	case NodeTypeHTTPRequest:
+		return r.executeHTTPRequest(ctx, scriptPath, input)
+	case NodeTypeCustomCode:
+		return r.executeCustomCode(ctx, scriptPath, input)
+	}
 	return r.Execute(ctx, scriptPath, input)
 }
 
 // ExecuteNode executes a node from the graph-based workflow.
 // Returns raw result; caller is responsible for parsing port information.
 func (r *BunRunner) ExecuteNode(ctx context.Context, node *Node, input any) (any, error) {
-	scriptPath := r.getScriptPath(node.Type)
-	if scriptPath == "" {
-		return nil, fmt.Errorf("unknown node type: %s", node.Type)
+	if mocksFrom(ctx) != nil {
+		if result, err := executeMocked(ctx, node, input); !errors.Is(err, errNotMocked) {
+			return result, err
+		}
+	}
+	if result, err := executeNative(ctx, node.Type, input); !errors.Is(err, errNotNative) {
+		return result, err
+	}
+	scriptPath, err := r.nodeScript(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBunOnly(r.Runtime, node.Type); err != nil {
+		return nil, err
+	}
+	switch node.Type {
+	case NodeTypeHTTPRequest:
+		return r.executeHTTPRequest(ctx, scriptPath, input)
+	case NodeTypeCustomCode:
+		return r.executeCustomCode(ctx, scriptPath, input)
+	case NodeTypeWebhook:
+		usageFrom(ctx).addHTTPCall()
 	}
 	return r.Execute(ctx, scriptPath, input)
 }
 
-// getScriptPath returns the script path for a given node type.
-func (r *BunRunner) getScriptPath(nodeType NodeType) string {
+var (
+	// errNotNative means a node has no in-process implementation and must run in Bun.
+	errNotNative = errors.New("node type is not executed natively")
+	// errNeedsBun is returned by a native implementation for a config it cannot
+	// handle (e.g. a JavaScript expression); the node then runs in its Bun block.
+	errNeedsBun = errors.New("config requires the Bun block")
+)
+
+// executeNative runs node types the engine implements in Go, avoiding a Bun
+// process start. It returns errNotNative for everything else.
+func executeNative(ctx context.Context, nodeType NodeType, input any) (any, error) {
+	var result any
+	var err error
 	switch nodeType {
-	case NodeTypeHTTPRequest:
-		return filepath.Join(r.BlocksDir, "std", "http_request.ts")
-	case NodeTypeCustomCode:
-		return filepath.Join(r.BlocksDir, "custom", "code.ts")
 	case NodeTypeCondition:
-		return filepath.Join(r.BlocksDir, "std", "condition.ts")
-	case NodeTypeLoop:
-		return filepath.Join(r.BlocksDir, "std", "loop.ts")
+		result, err = executeCondition(input)
 	case NodeTypeTransform:
-		return filepath.Join(r.BlocksDir, "std", "transform.ts")
+		result, err = executeTransform(input)
 	case NodeTypeDelay:
-		return filepath.Join(r.BlocksDir, "std", "delay.ts")
-	case NodeTypeFile:
-		return filepath.Join(r.BlocksDir, "std", "file.ts")
-	case NodeTypeDatabase:
-		return filepath.Join(r.BlocksDir, "std", "database.ts")
-	case NodeTypeWebhook:
-		return filepath.Join(r.BlocksDir, "std", "webhook.ts")
-	case NodeTypeSetVar, NodeTypeGetVar:
-		// Variable blocks are handled natively in Go, no Bun script needed
-		return ""
+		result, err = executeDelay(ctx, input)
+	case NodeTypeEnqueue:
+		result, err = executeEnqueue(ctx, input)
+	case NodeTypeHTTPRequest:
+		result, err = executeHTTPPaginated(ctx, input)
+	case NodeTypeSSH:
+		result, err = executeSSH(ctx, input)
+	case NodeTypeSFTP:
+		result, err = executeSFTP(ctx, input)
+	case NodeTypeCSV:
+		result, err = executeCSV(input)
+	case NodeTypeSpreadsheet:
+		result, err = executeSpreadsheet(ctx, input)
+	case NodeTypeArchive:
+		result, err = executeArchive(input)
+	case NodeTypeDatetime:
+		result, err = executeDatetime(input)
+	case NodeTypeXML:
+		result, err = executeXML(ctx, input)
 	default:
+		return nil, errNotNative
+	}
+	if errors.Is(err, errNeedsBun) {
+		log.Printf("%s falls back to Bun: %v", nodeType, err)
+		return nil, errNotNative
+	}
+	return result, err
+}
+
+// getScriptPath returns the script path for a given node type: the block
+// registered for it, or a scaffolded script without a manifest.
+func (r *BunRunner) getScriptPath(nodeType NodeType) string {
+	if nodeType == NodeTypeSetVar || nodeType == NodeTypeGetVar {
+		// Variable nodes act on the execution context and are run by the workflow runners (see executeNode)
+		return ""
+	}
+	if block, ok := BlockRegistryFor(r.BlocksDir).Lookup(nodeType); ok {
+		return block.Script
+	}
+	return r.customScriptPath(nodeType)
+}
+
+// nodeScript returns the script that runs node, honoring its version pin. A
+// yanked or outdated version still runs, with a warning in the node's output.
+func (r *BunRunner) nodeScript(ctx context.Context, node *Node) (string, error) {
+	constraint, err := ParseVersionConstraint(node.Version)
+	if err != nil {
+		return "", fmt.Errorf("node %s: %w", node.ID, err)
+	}
+	registry := BlockRegistryFor(r.BlocksDir)
+	block, err := registry.Resolve(node.Type, constraint)
+	if err != nil {
+		if constraint.Any() {
+			if script := r.getScriptPath(node.Type); script != "" {
+				return script, nil
+			}
+		}
+		return "", err
+	}
+
+	if block.Yanked {
+		warnNode(ctx, node, "%s %s is yanked; pin the node to another version", block.Type, block.Version)
+	} else if latest, ok := registry.Lookup(node.Type); ok && newerBlock(latest, block) {
+		warnNode(ctx, node, "%s %s is outdated; %s is installed", block.Type, block.Version, latest.Version)
+	}
+	return block.Script, nil
+}
+
+// warnNode logs a warning about a node and streams it as the node's output.
+func warnNode(ctx context.Context, node *Node, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.Printf("Warning: node %s: %s", node.ID, msg)
+	publishNodeOutput(ctx, "warning", msg)
+}
+
+// customScriptPath resolves a "namespace/name" type to <BlocksDir>/namespace/name.ts,
+// which is where `conv3n new block` puts scaffolded blocks. Returns "" when the
+// type doesn't look like a block path or the script doesn't exist.
+func (r *BunRunner) customScriptPath(nodeType NodeType) string {
+	name := string(nodeType)
+	if !strings.Contains(name, "/") || !filepath.IsLocal(name) {
+		return ""
+	}
+	path := filepath.Join(r.BlocksDir, filepath.FromSlash(name)+".ts")
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
 		return ""
 	}
+	return path
 }
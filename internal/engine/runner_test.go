@@ -225,6 +225,33 @@ func TestBunRunner_ExecuteBlock_UnknownType(t *testing.T) {
 	}
 }
 
+// TestBunRunner_ExecuteBlock_CustomPath verifies that "namespace/name" types
+// resolve to scripts in the blocks directory and cannot escape it.
+func TestBunRunner_ExecuteBlock_CustomPath(t *testing.T) {
+	installFakeBun(t)
+	blocksDir := t.TempDir()
+	writeBlock(t, blocksDir, "acme/greet.ts", `echo '{"data":{"hello":"world"},"port":"default"}'`)
+	runner := engine.NewBunRunner(blocksDir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := runner.ExecuteBlock(ctx, engine.Block{ID: "b1", Type: "acme/greet"}, map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("expected scaffolded block to run, got %v", err)
+	}
+	data, _ := result.(map[string]interface{})["data"].(map[string]interface{})
+	if data["hello"] != "world" {
+		t.Errorf("unexpected result: %v", result)
+	}
+
+	for _, typ := range []string{"acme/missing", "../acme/greet", "greet"} {
+		if _, err := runner.ExecuteBlock(ctx, engine.Block{ID: "b2", Type: engine.BlockType(typ)}, nil); err == nil {
+			t.Errorf("expected error for type %q", typ)
+		}
+	}
+}
+
 // TestBunRunner_Execute_InvalidJSON verifies error handling for invalid JSON output
 func TestBunRunner_Execute_InvalidJSON(t *testing.T) {
 	// This test would require a script that outputs invalid JSON
@@ -0,0 +1,238 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Runtime is a JavaScript/TypeScript runtime that runs block and trigger
+// scripts. Bun is the default; Node and Deno serve where Bun can't be
+// installed, for scripts that stick to APIs the runtime has. The standard
+// blocks do, but for those in BunOnlyBlocks.
+type Runtime interface {
+	// Name identifies the runtime in configuration, e.g. "bun".
+	Name() string
+	// Executable is the program to start, looked up in PATH unless absolute.
+	Executable() string
+	// Args returns the arguments that run script, with flags (user-supplied
+	// runtime options) before it.
+	Args(script string, flags []string, limits ResourceLimits) []string
+	// Env returns variables to add to the environment of a script.
+	Env(limits ResourceLimits) []string
+	// Check verifies the runtime can run scripts and returns its version.
+	Check(ctx context.Context) (string, error)
+}
+
+// BunOnlyBlocks maps the standard node types whose blocks need Bun to the Bun
+// API they use; they fail to run under the other runtimes.
+var BunOnlyBlocks = map[NodeType]string{
+	NodeTypeDatabase:   "bun:sqlite",
+	NodeTypeCustomCode: "Bun.Transpiler",
+}
+
+// checkBunOnly returns an error for a node type in BunOnlyBlocks unless rt
+// is Bun.
+func checkBunOnly(rt Runtime, nodeType NodeType) error {
+	api, ok := BunOnlyBlocks[nodeType]
+	if !ok || rt == nil || rt.Name() == "bun" {
+		return nil
+	}
+	return fmt.Errorf("%s needs Bun (its block uses %s) but the runtime is %s", nodeType, api, rt.Name())
+}
+
+// RuntimeNames lists the runtimes RuntimeByName knows, in the order "auto"
+// tries them.
+var RuntimeNames = []string{"bun", "node", "tsx", "deno"}
+
+// RuntimeByName returns the runtime for a configured name; "auto" picks the
+// first of RuntimeNames that passes its Check.
+func RuntimeByName(ctx context.Context, name string) (Runtime, error) {
+	switch name {
+	case "", "bun":
+		return BunRuntime{}, nil
+	case "node":
+		return NodeRuntime{}, nil
+	case "tsx":
+		return NodeRuntime{TSX: true}, nil
+	case "deno":
+		return DenoRuntime{}, nil
+	case "auto":
+		for _, n := range RuntimeNames {
+			rt, _ := RuntimeByName(ctx, n)
+			if _, err := rt.Check(ctx); err == nil {
+				return rt, nil
+			}
+		}
+		return nil, fmt.Errorf("none of %s is installed", strings.Join(RuntimeNames, ", "))
+	default:
+		return nil, fmt.Errorf("unknown runtime %q (want %s or auto)", name, strings.Join(RuntimeNames, ", "))
+	}
+}
+
+var (
+	runtimeMu      sync.RWMutex
+	defaultRuntime Runtime = BunRuntime{}
+)
+
+// SetRuntime configures the runtime used by every BunRunner and TS trigger
+// created afterwards. Typically called once at startup.
+func SetRuntime(rt Runtime) {
+	runtimeMu.Lock()
+	defer runtimeMu.Unlock()
+	defaultRuntime = rt
+}
+
+// CurrentRuntime returns the engine-wide runtime.
+func CurrentRuntime() Runtime {
+	runtimeMu.RLock()
+	defer runtimeMu.RUnlock()
+	return defaultRuntime
+}
+
+// runtimeCheckTimeout bounds how long Check waits for `--version`.
+const runtimeCheckTimeout = 5 * time.Second
+
+// runtimeVersion runs `<executable> --version` and returns its first line.
+func runtimeVersion(ctx context.Context, executable string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, runtimeCheckTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, executable, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("%s --version failed: %w", executable, err)
+	}
+	version, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return version, nil
+}
+
+// BunRuntime runs scripts with `bun run`.
+type BunRuntime struct {
+	Path string // Defaults to "bun"
+}
+
+func (b BunRuntime) Name() string { return "bun" }
+
+func (b BunRuntime) Executable() string {
+	if b.Path != "" {
+		return b.Path
+	}
+	return "bun"
+}
+
+func (b BunRuntime) Args(script string, flags []string, limits ResourceLimits) []string {
+	args := append([]string{"run"}, flags...)
+	return append(args, script)
+}
+
+func (b BunRuntime) Env(limits ResourceLimits) []string {
+	if limits.MaxMemoryMB > 0 {
+		// JavaScriptCore sizes its heap from this hint
+		return []string{fmt.Sprintf("BUN_JSC_forceRAMSize=%d", limits.MaxMemoryMB*1024*1024)}
+	}
+	return nil
+}
+
+func (b BunRuntime) Check(ctx context.Context) (string, error) {
+	return runtimeVersion(ctx, b.Executable())
+}
+
+// NodeRuntime runs scripts with Node.js, which strips TypeScript types itself
+// from version 22.6, or through tsx with TSX set.
+type NodeRuntime struct {
+	Path string // Defaults to "node", or "tsx" with TSX set
+	TSX  bool
+}
+
+// nodeStripTypesVersion is the first Node release with --experimental-strip-types.
+var nodeStripTypesVersion = [2]int{22, 6}
+
+func (n NodeRuntime) Name() string {
+	if n.TSX {
+		return "tsx"
+	}
+	return "node"
+}
+
+func (n NodeRuntime) Executable() string {
+	if n.Path != "" {
+		return n.Path
+	}
+	return n.Name()
+}
+
+func (n NodeRuntime) Args(script string, flags []string, limits ResourceLimits) []string {
+	var args []string
+	if !n.TSX {
+		args = []string{"--experimental-strip-types", "--disable-warning=ExperimentalWarning"}
+	}
+	args = append(args, flags...)
+	return append(args, script)
+}
+
+func (n NodeRuntime) Env(limits ResourceLimits) []string {
+	if limits.MaxMemoryMB > 0 {
+		// Also reaches the node process tsx starts
+		return []string{fmt.Sprintf("NODE_OPTIONS=--max-old-space-size=%d", limits.MaxMemoryMB)}
+	}
+	return nil
+}
+
+func (n NodeRuntime) Check(ctx context.Context) (string, error) {
+	version, err := runtimeVersion(ctx, n.Executable())
+	if err != nil || n.TSX {
+		return version, err
+	}
+	major, minor, ok := parseNodeVersion(version)
+	if !ok {
+		return version, fmt.Errorf("unrecognized node version %q", version)
+	}
+	if major < nodeStripTypesVersion[0] || (major == nodeStripTypesVersion[0] && minor < nodeStripTypesVersion[1]) {
+		return version, fmt.Errorf("node %s can't run TypeScript: upgrade to v%d.%d or later, or use tsx", version, nodeStripTypesVersion[0], nodeStripTypesVersion[1])
+	}
+	return version, nil
+}
+
+// parseNodeVersion parses the major and minor version from e.g. "v22.6.0".
+func parseNodeVersion(version string) (major, minor int, ok bool) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, errMajor := strconv.Atoi(parts[0])
+	minor, errMinor := strconv.Atoi(parts[1])
+	return major, minor, errMajor == nil && errMinor == nil
+}
+
+// DenoRuntime runs scripts with `deno run`, granting every permission as Bun
+// and Node do.
+type DenoRuntime struct {
+	Path string // Defaults to "deno"
+}
+
+func (d DenoRuntime) Name() string { return "deno" }
+
+func (d DenoRuntime) Executable() string {
+	if d.Path != "" {
+		return d.Path
+	}
+	return "deno"
+}
+
+func (d DenoRuntime) Args(script string, flags []string, limits ResourceLimits) []string {
+	args := []string{"run", "--allow-all", "--quiet"}
+	if limits.MaxMemoryMB > 0 {
+		args = append(args, fmt.Sprintf("--v8-flags=--max-old-space-size=%d", limits.MaxMemoryMB))
+	}
+	args = append(args, flags...)
+	return append(args, script)
+}
+
+func (d DenoRuntime) Env(limits ResourceLimits) []string { return nil }
+
+func (d DenoRuntime) Check(ctx context.Context) (string, error) {
+	return runtimeVersion(ctx, d.Executable())
+}
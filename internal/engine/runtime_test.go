@@ -0,0 +1,178 @@
+package engine_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// writeFakeRuntime puts an executable named name on PATH that prints version
+// for --version and otherwise runs its last argument as a shell script.
+func writeFakeRuntime(t *testing.T, name, version string) string {
+	t.Helper()
+	dir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"if [ \"$1\" = \"--version\" ]; then echo '" + version + "'; exit 0; fi\n" +
+		"for last; do :; done\n" +
+		"exec /bin/sh \"$last\"\n"
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake %s: %v", name, err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return path
+}
+
+func TestRuntime_Args(t *testing.T) {
+	limits := engine.ResourceLimits{MaxMemoryMB: 128}
+	for _, tc := range []struct {
+		rt       engine.Runtime
+		wantArgs []string
+		wantEnv  []string
+	}{
+		{engine.BunRuntime{}, []string{"run", "--smol", "s.ts"}, []string{"BUN_JSC_forceRAMSize=134217728"}},
+		{engine.NodeRuntime{}, []string{"--experimental-strip-types", "--disable-warning=ExperimentalWarning", "--smol", "s.ts"}, []string{"NODE_OPTIONS=--max-old-space-size=128"}},
+		{engine.NodeRuntime{TSX: true}, []string{"--smol", "s.ts"}, []string{"NODE_OPTIONS=--max-old-space-size=128"}},
+		{engine.DenoRuntime{}, []string{"run", "--allow-all", "--quiet", "--v8-flags=--max-old-space-size=128", "--smol", "s.ts"}, nil},
+	} {
+		if got := tc.rt.Args("s.ts", []string{"--smol"}, limits); !reflect.DeepEqual(got, tc.wantArgs) {
+			t.Errorf("%s: expected args %v, got %v", tc.rt.Name(), tc.wantArgs, got)
+		}
+		if got := tc.rt.Env(limits); !reflect.DeepEqual(got, tc.wantEnv) {
+			t.Errorf("%s: expected env %v, got %v", tc.rt.Name(), tc.wantEnv, got)
+		}
+		if got := tc.rt.Env(engine.ResourceLimits{}); got != nil {
+			t.Errorf("%s: expected no env without limits, got %v", tc.rt.Name(), got)
+		}
+	}
+}
+
+func TestRuntimeByName(t *testing.T) {
+	for _, name := range engine.RuntimeNames {
+		rt, err := engine.RuntimeByName(context.Background(), name)
+		if err != nil || rt.Name() != name {
+			t.Errorf("%s: got %v, %v", name, rt, err)
+		}
+	}
+	if _, err := engine.RuntimeByName(context.Background(), "rhino"); err == nil {
+		t.Error("expected an error for an unknown runtime")
+	}
+
+	// With only deno installed, auto picks it
+	t.Setenv("PATH", "")
+	writeFakeRuntime(t, "deno", "deno 2.0.0")
+	rt, err := engine.RuntimeByName(context.Background(), "auto")
+	if err != nil || rt.Name() != "deno" {
+		t.Errorf("expected auto to pick deno, got %v, %v", rt, err)
+	}
+}
+
+func TestNodeRuntime_Check(t *testing.T) {
+	for _, tc := range []struct {
+		version string
+		wantErr string
+	}{
+		{"v22.6.0", ""},
+		{"v23.1.0", ""},
+		{"v20.19.5", "upgrade to v22.6"},
+		{"v22.5.1", "upgrade to v22.6"},
+		{"nightly", "unrecognized node version"},
+	} {
+		writeFakeRuntime(t, "node", tc.version)
+		version, err := engine.NodeRuntime{}.Check(context.Background())
+		switch {
+		case tc.wantErr == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tc.version, err)
+		case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+			t.Errorf("%s: expected error containing %q, got %v", tc.version, tc.wantErr, err)
+		case version != tc.version:
+			t.Errorf("expected version %s, got %s", tc.version, version)
+		}
+	}
+
+	// tsx brings its own loader, so any version will do
+	writeFakeRuntime(t, "tsx", "tsx v4.19.0")
+	if _, err := (engine.NodeRuntime{TSX: true}).Check(context.Background()); err != nil {
+		t.Errorf("unexpected tsx error: %v", err)
+	}
+}
+
+func TestBunRunner_Runtime(t *testing.T) {
+	writeFakeRuntime(t, "node", "v22.6.0")
+	engine.SetRuntime(engine.NodeRuntime{})
+	defer engine.SetRuntime(engine.BunRuntime{})
+
+	runner := engine.NewBunRunner(t.TempDir())
+	if runner.RuntimePath != "node" {
+		t.Errorf("expected the node executable, got %s", runner.RuntimePath)
+	}
+	script := writeScript(t, `cat > /dev/null
+echo '{"ran":"node"}'`)
+	result, err := runner.Execute(context.Background(), script, map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result.(map[string]interface{})["ran"] != "node" {
+		t.Errorf("unexpected result %v", result)
+	}
+}
+
+// TestBunRunner_StdBlocksUnderNode runs standard blocks through a real Node,
+// skipping where none that strips TypeScript types is installed.
+func TestBunRunner_StdBlocksUnderNode(t *testing.T) {
+	if _, err := (engine.NodeRuntime{}).Check(context.Background()); err != nil {
+		t.Skipf("node can't run the blocks: %v", err)
+	}
+	engine.SetRuntime(engine.NodeRuntime{})
+	defer engine.SetRuntime(engine.BunRuntime{})
+	runner := engine.NewBunRunner("../../pkg/blocks")
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "out", "note.txt")
+	for _, op := range []map[string]interface{}{
+		{"type": "write", "content": "hello"},
+		{"type": "read"},
+	} {
+		node := &engine.Node{ID: "file", Type: engine.NodeTypeFile}
+		result, err := runner.ExecuteNode(ctx, node, map[string]interface{}{
+			"config": map[string]interface{}{"path": path, "operation": op},
+		})
+		if err != nil {
+			t.Fatalf("std/file %s failed under node: %v", op["type"], err)
+		}
+		if op["type"] == "read" && result.(map[string]interface{})["data"] != "hello" {
+			t.Errorf("expected to read back what std/file wrote, got %v", result)
+		}
+	}
+
+	// JavaScript syntax makes std/condition fall back to its block, built on the SDK
+	node := &engine.Node{ID: "check", Type: engine.NodeTypeCondition}
+	result, err := runner.ExecuteNode(ctx, node, map[string]interface{}{
+		"config": map[string]interface{}{"expression": "input.tags.includes('vip')"},
+		"input":  map[string]interface{}{"tags": []string{"vip"}},
+	})
+	if err != nil {
+		t.Fatalf("std/condition failed under node: %v", err)
+	}
+	if result.(map[string]interface{})["port"] != "true" {
+		t.Errorf("expected the true port, got %v", result)
+	}
+}
+
+func TestBunRunner_BunOnlyBlocks(t *testing.T) {
+	writeFakeRuntime(t, "node", "v22.6.0")
+	engine.SetRuntime(engine.NodeRuntime{})
+	defer engine.SetRuntime(engine.BunRuntime{})
+
+	runner := engine.NewBunRunner("../../pkg/blocks")
+	node := &engine.Node{ID: "db", Type: engine.NodeTypeDatabase}
+	_, err := runner.ExecuteNode(context.Background(), node, map[string]interface{}{"config": map[string]interface{}{}})
+	if err == nil || !strings.Contains(err.Error(), "std/database needs Bun (its block uses bun:sqlite) but the runtime is node") {
+		t.Errorf("expected std/database to be refused under node, got %v", err)
+	}
+}
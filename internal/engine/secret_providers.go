@@ -0,0 +1,104 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/conv3n/conv3n/internal/awsauth"
+)
+
+// VaultSecrets reads secrets from HashiCorp Vault's HTTP API. Paths are API
+// paths below /v1, e.g. "secret/data/stripe" for the stripe secret of a KV
+// version 2 engine mounted at secret/; the fields of KV secrets are returned.
+type VaultSecrets struct {
+	Addr      string // e.g. "https://vault.example.com:8200"
+	Token     string
+	Namespace string       // Vault Enterprise namespace, if any
+	Client    *http.Client // http.DefaultClient if nil
+}
+
+// GetSecret implements SecretProvider.
+func (v *VaultSecrets) GetSecret(ctx context.Context, path string) (interface{}, error) {
+	u := strings.TrimRight(v.Addr, "/") + "/v1/" + strings.TrimLeft(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := doSecretRequest(v.Client, req, &body); err != nil {
+		return nil, err
+	}
+	// KV version 2 nests the secret's fields next to its metadata
+	if inner, ok := body.Data["data"].(map[string]interface{}); ok && body.Data["metadata"] != nil {
+		return inner, nil
+	}
+	return body.Data, nil
+}
+
+// AWSSecrets reads secrets from AWS Secrets Manager. Paths are secret names or
+// ARNs; secrets stored as JSON objects return their fields, others their string.
+type AWSSecrets struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string       // For temporary credentials
+	Endpoint        string       // https://secretsmanager.<region>.amazonaws.com if empty
+	Client          *http.Client // http.DefaultClient if nil
+}
+
+// GetSecret implements SecretProvider.
+func (a *AWSSecrets) GetSecret(ctx context.Context, path string) (interface{}, error) {
+	client := &awsauth.Client{
+		Service:     "secretsmanager",
+		Region:      a.Region,
+		Credentials: awsauth.Credentials{AccessKeyID: a.AccessKeyID, SecretAccessKey: a.SecretAccessKey, SessionToken: a.SessionToken},
+		Endpoint:    a.Endpoint,
+		HTTPClient:  a.Client,
+	}
+	var body struct {
+		SecretString *string `json:"SecretString"`
+	}
+	if err := client.Call(ctx, "secretsmanager.GetSecretValue", map[string]string{"SecretId": path}, &body); err != nil {
+		return nil, err
+	}
+	if body.SecretString == nil {
+		return nil, fmt.Errorf("secret %s has no string value", path)
+	}
+	var fields map[string]interface{}
+	if json.Unmarshal([]byte(*body.SecretString), &fields) == nil {
+		return fields, nil
+	}
+	return *body.SecretString, nil
+}
+
+// doSecretRequest sends req with client and decodes its JSON response into v.
+// Error responses are reported by status only: their bodies may echo secrets.
+func doSecretRequest(client *http.Client, req *http.Request, v interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", (&url.URL{Scheme: req.URL.Scheme, Host: req.URL.Host}).String(), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SecretProvider fetches secrets from an external store such as HashiCorp Vault
+// (VaultSecrets) or AWS Secrets Manager (AWSSecrets). Node configs reference
+// them as {{ $credentials.<provider>:<path>#<field> }}, e.g.
+// {{ $credentials.vault:secret/data/stripe#api_key }}; without #<field> the
+// whole secret resolves. Secrets are fetched when the nodes using them run and
+// kept in memory for secretCacheTTL only: conv3n never stores them, and their
+// values are redacted from the node inputs it saves.
+type SecretProvider interface {
+	// GetSecret returns the secret at path: a map of its fields, or a string
+	// for secrets that aren't JSON objects.
+	GetSecret(ctx context.Context, path string) (interface{}, error)
+}
+
+// secretCacheTTL is how long a fetched secret is reused, so that the nodes of
+// a run and runs close together don't query the provider each time, while
+// rotated secrets are picked up quickly.
+const secretCacheTTL = time.Minute
+
+// secretFetchTimeout bounds a provider call.
+const secretFetchTimeout = 10 * time.Second
+
+type cachedSecret struct {
+	value   interface{}
+	expires time.Time
+}
+
+var (
+	secretsMu       sync.RWMutex
+	secretProviders = make(map[string]SecretProvider)
+	secretCache     = make(map[string]cachedSecret)
+)
+
+// RegisterSecretProvider makes $credentials.<name>:... references resolve
+// through p. Like storage.Register it panics if name is registered twice or p
+// is nil.
+func RegisterSecretProvider(name string, p SecretProvider) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	if p == nil {
+		panic("engine: RegisterSecretProvider provider is nil")
+	}
+	if _, dup := secretProviders[name]; dup {
+		panic("engine: RegisterSecretProvider called twice for provider " + name)
+	}
+	secretProviders[name] = p
+}
+
+// SecretProviders returns the names of the registered providers, sorted.
+func SecretProviders() []string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	names := make([]string, 0, len(secretProviders))
+	for name := range secretProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fetchSecret returns the secret at path of provider name, from the cache
+// while it is fresh.
+func fetchSecret(name, path string) (interface{}, error) {
+	key := name + ":" + path
+	secretsMu.RLock()
+	p, ok := secretProviders[name]
+	cached, hit := secretCache[key]
+	secretsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown secret provider %q", name)
+	}
+	if hit && time.Now().Before(cached.expires) {
+		return cached.value, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretFetchTimeout)
+	defer cancel()
+	value, err := p.GetSecret(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("secret provider %s: %w", name, err)
+	}
+	secretsMu.Lock()
+	secretCache[key] = cachedSecret{value: value, expires: time.Now().Add(secretCacheTTL)}
+	secretsMu.Unlock()
+	return value, nil
+}
+
+// resolveCredential resolves a $credentials reference, ref being what follows
+// "$credentials.", and notes the values it returns as secrets of ctx.
+func resolveCredential(ref string, ctx *ExecutionContext) (interface{}, error) {
+	name, path, ok := strings.Cut(ref, ":")
+	if !ok || name == "" || path == "" {
+		return nil, fmt.Errorf("$credentials requires a provider and path: $credentials.provider:path#field")
+	}
+	path, field, hasField := strings.Cut(path, "#")
+	value, err := fetchSecret(name, path)
+	if err != nil {
+		return nil, err
+	}
+	if hasField {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("secret %s of %s has no fields", path, name)
+		}
+		if value, ok = fields[field]; !ok {
+			return nil, fmt.Errorf("secret %s of %s has no field %s", path, name, field)
+		}
+	}
+	ctx.noteSecrets(value)
+	return value, nil
+}
+
+// noteSecrets records the string values in v as secrets, so that they are
+// redacted from the node inputs saved for the run.
+func (ctx *ExecutionContext) noteSecrets(v interface{}) {
+	switch v := v.(type) {
+	case string:
+		if v == "" {
+			return
+		}
+		ctx.mu.Lock()
+		if ctx.secrets == nil {
+			ctx.secrets = make(map[string]bool)
+		}
+		ctx.secrets[v] = true
+		ctx.mu.Unlock()
+	case map[string]interface{}:
+		for _, value := range v {
+			ctx.noteSecrets(value)
+		}
+	case []interface{}:
+		for _, value := range v {
+			ctx.noteSecrets(value)
+		}
+	}
+}
+
+// redactResolvedSecrets returns a copy of v with the secrets resolved during
+// the run replaced wherever they appear in its strings.
+func (ctx *ExecutionContext) redactResolvedSecrets(v interface{}) interface{} {
+	ctx.mu.RLock()
+	secrets := make([]string, 0, len(ctx.secrets))
+	for s := range ctx.secrets {
+		secrets = append(secrets, s)
+	}
+	ctx.mu.RUnlock()
+	if len(secrets) == 0 {
+		return v
+	}
+	// Longest first, so that a secret containing another is redacted whole
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	pairs := make([]string, 0, 2*len(secrets))
+	for _, s := range secrets {
+		pairs = append(pairs, s, redactedValue)
+	}
+	return replaceStrings(v, strings.NewReplacer(pairs...))
+}
+
+// replaceStrings returns a copy of v with r applied to its strings, at any depth.
+func replaceStrings(v interface{}, r *strings.Replacer) interface{} {
+	switch v := v.(type) {
+	case string:
+		return r.Replace(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			out[key] = replaceStrings(value, r)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, value := range v {
+			out[i] = replaceStrings(value, r)
+		}
+		return out
+	default:
+		return v
+	}
+}
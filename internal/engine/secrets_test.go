@@ -0,0 +1,112 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+func TestSecretProviders_Vault(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("X-Vault-Token") != "s.test" || r.URL.Path != "/v1/secret/data/db" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"data":{"data":{"password":"s3cr3t-pw","user":"svc-reporting"},"metadata":{"version":3}}}`))
+	}))
+	defer srv.Close()
+	engine.RegisterSecretProvider("vault-test", &engine.VaultSecrets{Addr: srv.URL, Token: "s.test"})
+
+	w := engine.Workflow{
+		ID: "wf-secrets",
+		Nodes: map[string]engine.Node{
+			"dsn": {ID: "dsn", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{
+				"name":  "dsn",
+				"value": "postgres://{{ $credentials.vault-test:secret/data/db#user }}:{{ $credentials.vault-test:secret/data/db#password }}@db/app",
+			}},
+		},
+	}
+	store := createTestStorage(t)
+	ctx := context.Background()
+	ectx := engine.NewExecutionContext(w.ID)
+	runner := engine.NewWorkflowRunner(ectx, t.TempDir(), store, nil)
+	execID, _ := runner.CreateExecution(ctx, w.ID)
+	if err := runner.Run(ctx, w); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := ectx.GetVar("dsn"); got != "postgres://svc-reporting:s3cr3t-pw@db/app" {
+		t.Errorf("expected the secret in the node's config, got %v", got)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected the secret to be fetched once and cached, got %d requests", n)
+	}
+
+	// The stored input doesn't keep the secret's values
+	raw, err := store.GetNodeInput(ctx, execID, "dsn")
+	if err != nil {
+		t.Fatalf("GetNodeInput failed: %v", err)
+	}
+	if strings.Contains(string(raw), "s3cr3t-pw") || !strings.Contains(string(raw), "postgres://[REDACTED]:[REDACTED]@db/app") {
+		t.Errorf("expected the secrets to be redacted from the stored input, got %s", raw)
+	}
+
+	for _, tc := range []struct{ ref, want string }{
+		{"vault-test:secret/data/db#missing", "has no field missing"},
+		{"vault-test:secret/data/other", "unexpected status 403"},
+		{"nowhere:secret/data/db", `unknown secret provider "nowhere"`},
+		{"vault-test", "requires a provider and path"},
+	} {
+		w.Nodes["dsn"] = engine.Node{ID: "dsn", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "dsn", "value": "{{ $credentials." + tc.ref + " }}"}}
+		err := engine.NewWorkflowRunner(engine.NewExecutionContext(w.ID), t.TempDir(), store, nil).Run(ctx, w)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.ref, tc.want, err)
+		}
+	}
+}
+
+func TestAWSSecrets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct{ SecretId string }
+		json.Unmarshal(body, &req)
+		auth := r.Header.Get("Authorization")
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" ||
+			!strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDTEST/") ||
+			!strings.Contains(auth, "/eu-west-1/secretsmanager/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-security-token;x-amz-target, Signature=") ||
+			r.Header.Get("X-Amz-Security-Token") != "session" {
+			http.Error(w, "bad signature", http.StatusForbidden)
+			return
+		}
+		switch req.SecretId {
+		case "prod/stripe":
+			w.Write([]byte(`{"Name":"prod/stripe","SecretString":"{\"api_key\":\"sk_live_1\"}"}`))
+		case "prod/token":
+			w.Write([]byte(`{"Name":"prod/token","SecretString":"plain-token"}`))
+		default:
+			http.Error(w, `{"__type":"ResourceNotFoundException"}`, http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	p := &engine.AWSSecrets{Region: "eu-west-1", AccessKeyID: "AKIDTEST", SecretAccessKey: "secret", SessionToken: "session", Endpoint: srv.URL}
+	ctx := context.Background()
+
+	got, err := p.GetSecret(ctx, "prod/stripe")
+	if fields, _ := got.(map[string]interface{}); err != nil || fields["api_key"] != "sk_live_1" {
+		t.Errorf("expected the secret's fields, got %v (%v)", got, err)
+	}
+	if got, err := p.GetSecret(ctx, "prod/token"); err != nil || got != "plain-token" {
+		t.Errorf("expected the secret's string, got %v (%v)", got, err)
+	}
+	if _, err := p.GetSecret(ctx, "prod/missing"); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("expected a missing secret to fail, got %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// semver is a parsed semantic version. Build metadata is dropped.
+type semver struct {
+	major, minor, patch int
+	pre                 string
+}
+
+// parseSemver parses "1.2.3", "v1.2.3" or "1.2.3-beta.1+build".
+func parseSemver(s string) (semver, bool) {
+	s = strings.TrimPrefix(s, "v")
+	s, _, _ = strings.Cut(s, "+")
+	s, pre, _ := strings.Cut(s, "-")
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return semver{}, false
+	}
+	var nums [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || (len(p) > 1 && p[0] == '0') {
+			return semver{}, false
+		}
+		nums[i] = n
+	}
+	return semver{major: nums[0], minor: nums[1], patch: nums[2], pre: pre}, true
+}
+
+func (v semver) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.major, v.minor, v.patch)
+	if v.pre != "" {
+		s += "-" + v.pre
+	}
+	return s
+}
+
+// compare returns -1, 0 or 1. A pre-release sorts before its release;
+// pre-releases of the same version compare as strings.
+func (v semver) compare(o semver) int {
+	for _, d := range []int{v.major - o.major, v.minor - o.minor, v.patch - o.patch} {
+		if d != 0 {
+			if d < 0 {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case v.pre == o.pre:
+		return 0
+	case v.pre == "":
+		return 1
+	case o.pre == "":
+		return -1
+	}
+	return strings.Compare(v.pre, o.pre)
+}
+
+// versionClause is one comparison of a constraint, e.g. ">=1.2.0".
+type versionClause struct {
+	op string // "=", ">", ">=", "<", "<=", "^", "~"
+	v  semver
+}
+
+// VersionConstraint selects block versions, npm style: "1.2.3" (exact),
+// "^1.2.3" (same major), "~1.2.3" (same minor), "1" or "1.2.x" (prefix),
+// comparisons such as ">=1.2.0 <2.0.0" (all must hold), or "" and "*" (any).
+type VersionConstraint struct {
+	raw     string
+	clauses []versionClause
+}
+
+// ParseVersionConstraint parses a constraint as used to pin workflow nodes.
+func ParseVersionConstraint(s string) (VersionConstraint, error) {
+	c := VersionConstraint{raw: strings.TrimSpace(s)}
+	for _, field := range strings.Fields(s) {
+		if field == "*" || field == "latest" {
+			continue
+		}
+		op := ""
+		for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+			if strings.HasPrefix(field, prefix) {
+				op, field = prefix, field[len(prefix):]
+				break
+			}
+		}
+		if v, ok := parseSemver(field); ok {
+			if op == "" {
+				op = "="
+			}
+			c.clauses = append(c.clauses, versionClause{op: op, v: v})
+			continue
+		}
+		// A partial version ("1", "1.2", "1.2.x") is the range it prefixes
+		parts := strings.Split(strings.TrimPrefix(field, "v"), ".")
+		for len(parts) > 0 && (parts[len(parts)-1] == "x" || parts[len(parts)-1] == "*") {
+			parts = parts[:len(parts)-1]
+		}
+		if (op != "" && op != "^" && op != "~") || len(parts) == 0 || len(parts) > 2 {
+			return VersionConstraint{}, fmt.Errorf("invalid version constraint %q", s)
+		}
+		lower, ok := parseSemver(strings.Join(append(parts, "0", "0")[:3], "."))
+		if !ok {
+			return VersionConstraint{}, fmt.Errorf("invalid version constraint %q", s)
+		}
+		upper := semver{major: lower.major + 1}
+		if len(parts) == 2 && op != "^" {
+			upper = semver{major: lower.major, minor: lower.minor + 1}
+		}
+		c.clauses = append(c.clauses, versionClause{">=", lower}, versionClause{"<", upper})
+	}
+	return c, nil
+}
+
+func (c VersionConstraint) String() string {
+	return c.raw
+}
+
+// Any reports whether the constraint accepts every version.
+func (c VersionConstraint) Any() bool {
+	return len(c.clauses) == 0
+}
+
+// allowsPre reports whether a clause names a pre-release, which lets the
+// constraint match pre-release versions.
+func (c VersionConstraint) allowsPre() bool {
+	for _, cl := range c.clauses {
+		if cl.v.pre != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether v satisfies every clause.
+func (c VersionConstraint) matches(v semver) bool {
+	for _, cl := range c.clauses {
+		cmp := v.compare(cl.v)
+		var ok bool
+		switch cl.op {
+		case "=":
+			ok = cmp == 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case "^":
+			// Same major, or same minor below 1.0.0, as npm does
+			ok = cmp >= 0 && v.major == cl.v.major && (cl.v.major > 0 || v.minor == cl.v.minor)
+		case "~":
+			ok = cmp >= 0 && v.major == cl.v.major && v.minor == cl.v.minor
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,210 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// sftpConfig is the config of a std/sftp node. Like std/file it takes a path and
+// an operation, here on the remote server:
+//
+//	{"host": "files.partner.com", "user": "acme", "password": "{{ $globals.sftp_password }}",
+//	 "hostKey": "ssh-ed25519 AAAA...", "path": "/outbox/orders.csv",
+//	 "operation": {"type": "download", "encoding": "utf8"}}
+type sftpConfig struct {
+	sshConnection
+	Path      string        `json:"path"`
+	Operation sftpOperation `json:"operation"`
+}
+
+// sftpOperation is upload, download, list or delete. Uploads send Content (text,
+// base64 when Encoding is "base64", or an object as JSON) or the local file at
+// LocalPath; downloads return the content in Encoding ("utf8" by default, "base64"
+// for binary files) or save it to LocalPath.
+type sftpOperation struct {
+	Type      string      `json:"type"`
+	Content   interface{} `json:"content"`
+	Encoding  string      `json:"encoding"`
+	LocalPath string      `json:"localPath"`
+}
+
+// executeSFTP runs a std/sftp node: it connects over SSH and performs one file
+// operation on the server.
+func executeSFTP(ctx context.Context, input any) (any, error) {
+	payload, _ := input.(map[string]interface{})
+	var cfg sftpConfig
+	raw, _ := json.Marshal(payload["config"])
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("sftp: invalid config: %w", err)
+	}
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("sftp: config 'path' must be a non-empty string")
+	}
+	op := cfg.Operation
+	switch op.Type {
+	case "upload", "download", "list", "delete":
+	default:
+		return nil, fmt.Errorf("sftp: operation 'type' must be 'upload', 'download', 'list' or 'delete'")
+	}
+	switch op.Encoding {
+	case "", "utf8", "base64":
+	default:
+		return nil, fmt.Errorf("sftp: operation 'encoding' must be 'utf8' or 'base64'")
+	}
+
+	sshClient, err := cfg.dial(ctx, "sftp")
+	if err != nil {
+		return nil, err
+	}
+	defer sshClient.Close()
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		return nil, fmt.Errorf("sftp: failed to start session: %w", err)
+	}
+	defer client.Close()
+
+	// The sftp client is not context-aware; closing the connection aborts a transfer
+	stop := context.AfterFunc(ctx, func() { sshClient.Close() })
+	defer stop()
+
+	var data map[string]interface{}
+	switch op.Type {
+	case "upload":
+		data, err = sftpUpload(client, cfg.Path, op)
+	case "download":
+		data, err = sftpDownload(client, cfg.Path, op)
+	case "list":
+		data, err = sftpList(client, cfg.Path)
+	case "delete":
+		if err = client.Remove(cfg.Path); err == nil {
+			data = map[string]interface{}{"deleted": true}
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sftp: %s %s failed: %w", op.Type, cfg.Path, err)
+	}
+
+	data["operation"] = op.Type
+	data["path"] = cfg.Path
+	return map[string]interface{}{"data": data, "port": "default"}, nil
+}
+
+func sftpUpload(client *sftp.Client, path string, op sftpOperation) (map[string]interface{}, error) {
+	var src io.Reader
+	switch {
+	case op.LocalPath != "":
+		f, err := os.Open(op.LocalPath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		src = f
+	case op.Content == nil:
+		return nil, fmt.Errorf("operation 'content' or 'localPath' is required")
+	default:
+		content, err := sftpContent(op)
+		if err != nil {
+			return nil, err
+		}
+		src = content
+	}
+
+	dst, err := client.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	n, err := io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"bytesWritten": n}, nil
+}
+
+// sftpContent decodes the content of an upload.
+func sftpContent(op sftpOperation) (io.Reader, error) {
+	text, ok := op.Content.(string)
+	if !ok {
+		// Objects are uploaded as JSON, as std/file writes them
+		b, err := json.MarshalIndent(op.Content, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode content: %w", err)
+		}
+		text = string(b)
+	}
+	if op.Encoding == "base64" {
+		b, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return nil, fmt.Errorf("content is not valid base64: %w", err)
+		}
+		return bytes.NewReader(b), nil
+	}
+	return bytes.NewReader([]byte(text)), nil
+}
+
+func sftpDownload(client *sftp.Client, path string, op sftpOperation) (map[string]interface{}, error) {
+	src, err := client.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	if op.LocalPath != "" {
+		dst, err := os.Create(op.LocalPath)
+		if err != nil {
+			return nil, err
+		}
+		n, err := io.Copy(dst, src)
+		if closeErr := dst.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"size": n, "localPath": op.LocalPath}, nil
+	}
+
+	b, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	encoding := op.Encoding
+	content := string(b)
+	if encoding == "base64" {
+		content = base64.StdEncoding.EncodeToString(b)
+	} else {
+		encoding = "utf8"
+	}
+	return map[string]interface{}{"size": len(b), "content": content, "encoding": encoding}, nil
+}
+
+func sftpList(client *sftp.Client, path string) (map[string]interface{}, error) {
+	infos, err := client.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]map[string]interface{}, len(infos))
+	for i, info := range infos {
+		entries[i] = map[string]interface{}{
+			"name":    info.Name(),
+			"size":    info.Size(),
+			"isDir":   info.IsDir(),
+			"mode":    info.Mode().String(),
+			"modTime": info.ModTime().UTC().Format(time.RFC3339),
+		}
+	}
+	return map[string]interface{}{"entries": entries}, nil
+}
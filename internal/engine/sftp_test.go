@@ -0,0 +1,108 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// TestBunRunner_ExecuteNode_SFTP verifies std/sftp uploads, downloads, lists and
+// deletes files on the server, in text, base64 and via local files.
+func TestBunRunner_ExecuteNode_SFTP(t *testing.T) {
+	port, hostKey := startSSHServer(t, nil)
+	remote := t.TempDir()
+	local := t.TempDir()
+	runner := engine.NewBunRunner(t.TempDir())
+	runner.RuntimePath = "/nonexistent/bun" // any process start would fail
+	node := &engine.Node{ID: "transfer", Type: engine.NodeTypeSFTP}
+	run := func(path string, op map[string]interface{}) (map[string]interface{}, error) {
+		t.Helper()
+		config := map[string]interface{}{
+			"host":      "127.0.0.1",
+			"port":      float64(port),
+			"user":      "ops",
+			"password":  "secret",
+			"hostKey":   hostKey,
+			"path":      path,
+			"operation": op,
+		}
+		raw, err := runner.ExecuteNode(context.Background(), node, map[string]interface{}{"config": config})
+		if err != nil {
+			return nil, err
+		}
+		return raw.(map[string]interface{})["data"].(map[string]interface{}), nil
+	}
+
+	orders := filepath.Join(remote, "orders.csv")
+	data, err := run(orders, map[string]interface{}{"type": "upload", "content": "id,total\n1,9.99\n"})
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if data["bytesWritten"] != int64(16) {
+		t.Errorf("expected 16 bytes written, got %v", data["bytesWritten"])
+	}
+
+	binary := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+	logo := filepath.Join(remote, "logo.png")
+	if _, err := run(logo, map[string]interface{}{
+		"type":     "upload",
+		"content":  base64.StdEncoding.EncodeToString(binary),
+		"encoding": "base64",
+	}); err != nil {
+		t.Fatalf("base64 upload failed: %v", err)
+	}
+	if got, _ := os.ReadFile(logo); string(got) != string(binary) {
+		t.Errorf("expected binary content to round-trip, got %v", got)
+	}
+
+	data, err = run(orders, map[string]interface{}{"type": "download"})
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if data["content"] != "id,total\n1,9.99\n" || data["encoding"] != "utf8" {
+		t.Errorf("unexpected download result: %v", data)
+	}
+	data, err = run(logo, map[string]interface{}{"type": "download", "encoding": "base64"})
+	if err != nil {
+		t.Fatalf("base64 download failed: %v", err)
+	}
+	if data["content"] != base64.StdEncoding.EncodeToString(binary) {
+		t.Errorf("unexpected base64 download: %v", data["content"])
+	}
+
+	saved := filepath.Join(local, "orders.csv")
+	if _, err := run(orders, map[string]interface{}{"type": "download", "localPath": saved}); err != nil {
+		t.Fatalf("download to local file failed: %v", err)
+	}
+	if got, _ := os.ReadFile(saved); string(got) != "id,total\n1,9.99\n" {
+		t.Errorf("expected the download to be saved locally, got %q", got)
+	}
+	if _, err := run(filepath.Join(remote, "copy.csv"), map[string]interface{}{"type": "upload", "localPath": saved}); err != nil {
+		t.Fatalf("upload from local file failed: %v", err)
+	}
+
+	data, err = run(remote, map[string]interface{}{"type": "list"})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if entries := data["entries"].([]map[string]interface{}); len(entries) != 3 {
+		t.Errorf("expected 3 entries, got %v", entries)
+	}
+
+	if _, err := run(orders, map[string]interface{}{"type": "delete"}); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if _, err := os.Stat(orders); !os.IsNotExist(err) {
+		t.Errorf("expected the remote file to be deleted, stat error %v", err)
+	}
+	if _, err := run(orders, map[string]interface{}{"type": "download"}); err == nil {
+		t.Error("expected downloading a missing file to fail")
+	}
+	if _, err := run(orders, map[string]interface{}{"type": "rename"}); err == nil {
+		t.Error("expected an unknown operation to fail")
+	}
+}
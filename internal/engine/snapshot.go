@@ -0,0 +1,312 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// Snapshot is a blessed run of a workflow: the trigger data it ran with, how it
+// ended and what each of its nodes output. A snapshot test (RunSnapshotTest)
+// runs the workflow again with the same trigger data and fails if the outputs
+// diverge from the snapshot's beyond what its SnapshotOptions allow.
+type Snapshot struct {
+	WorkflowID  string                  `json:"workflow_id"`
+	ExecutionID string                  `json:"execution_id,omitempty"` // Execution it was recorded from
+	CreatedAt   time.Time               `json:"created_at"`
+	TriggerData map[string]interface{}  `json:"trigger_data,omitempty"`
+	Status      string                  `json:"status"`
+	Nodes       map[string]SnapshotNode `json:"nodes"`
+	SnapshotOptions
+}
+
+// SnapshotNode is what one node of a snapshot's run output.
+type SnapshotNode struct {
+	Output json.RawMessage `json:"output,omitempty"` // The stored result, port included
+	Error  string          `json:"error,omitempty"`
+}
+
+// SnapshotOptions configure how a snapshot test compares a run to its snapshot.
+// Field patterns address a field as "<node>.<path>", with the path of a
+// FieldChange: "fetch.data.headers.date", "fetch.data.items[0].price" or just
+// "fetch" for the whole node. A * matches any part of one path segment, e.g.
+// "*.data.timestamp"; a pattern matching a field also matches what it holds.
+type SnapshotOptions struct {
+	// Ignore lists the fields that aren't compared, e.g. timestamps and IDs
+	// that change with every run.
+	Ignore []string `json:"ignore,omitempty"`
+	// Tolerance is how far a number may drift from the snapshot's and still
+	// match; Tolerances sets it for the fields matching a pattern instead.
+	Tolerance  float64            `json:"tolerance,omitempty"`
+	Tolerances map[string]float64 `json:"tolerances,omitempty"`
+	// Mock lists the nodes that don't run in a snapshot test but output what
+	// they output in the snapshot, e.g. those calling external services.
+	Mock []string `json:"mock,omitempty"`
+	// Mocks run the snapshot tests in mock mode, answering their network calls
+	Mocks *Mocks `json:"mocks,omitempty"`
+}
+
+// Validate checks the options, returning a *FieldError for each problem found.
+func (o SnapshotOptions) Validate() error {
+	var errs []error
+	if o.Tolerance < 0 {
+		errs = append(errs, &FieldError{Field: "tolerance", Message: "must not be negative"})
+	}
+	for pattern, tolerance := range o.Tolerances {
+		if tolerance < 0 {
+			errs = append(errs, &FieldError{Field: "tolerances." + pattern, Message: "must not be negative"})
+		}
+	}
+	if o.Mocks != nil {
+		errs = append(errs, o.Mocks.validate("mocks."))
+	}
+	return errors.Join(errs...)
+}
+
+// SnapshotReport is the outcome of a snapshot test.
+type SnapshotReport struct {
+	WorkflowID  string `json:"workflow_id"`
+	ExecutionID string `json:"execution_id"` // The test run
+	// Passed is set when the run ended like the snapshot's and no node output
+	// diverged from it
+	Passed         bool   `json:"passed"`
+	Status         string `json:"status"`
+	ExpectedStatus string `json:"expected_status"`
+	// Nodes compares the nodes of either run, by ID; ignored nodes are left out
+	Nodes []SnapshotNodeDiff `json:"nodes"`
+}
+
+// SnapshotNodeDiff is how the output of one node in a snapshot test differs
+// from the snapshot's: DiffAdded for a node only the test ran, DiffRemoved for
+// one only the snapshot's run did, DiffChanged or DiffUnchanged for one both ran.
+type SnapshotNodeDiff struct {
+	NodeID  string        `json:"node_id"`
+	Status  string        `json:"status"`
+	Mocked  bool          `json:"mocked,omitempty"`
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+// errSnapshotNoData is returned by NewSnapshot for an execution whose node
+// results were not kept (see WorkflowSettings.SaveSuccessData).
+var errSnapshotNoData = errors.New("execution kept no node results to snapshot")
+
+// NewSnapshot records the finished execution executionID as a snapshot
+// compared with opts. Mocked nodes must have output in the execution.
+func NewSnapshot(ctx context.Context, store storage.Storage, executionID string, opts SnapshotOptions) (*Snapshot, error) {
+	exec, err := store.GetExecution(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+	if exec.Status == storage.ExecutionStatusRunning || exec.Status == storage.ExecutionStatusWaiting {
+		return nil, fmt.Errorf("execution %s is %s; only a finished one can be snapshotted", executionID, exec.Status)
+	}
+	snap := &Snapshot{
+		WorkflowID:      exec.WorkflowID,
+		ExecutionID:     executionID,
+		CreatedAt:       time.Now().UTC(),
+		Status:          string(exec.Status),
+		Nodes:           make(map[string]SnapshotNode),
+		SnapshotOptions: opts,
+	}
+	if len(exec.TriggerData) > 0 {
+		if err := json.Unmarshal(exec.TriggerData, &snap.TriggerData); err != nil {
+			return nil, fmt.Errorf("invalid trigger data: %w", err)
+		}
+	}
+
+	results, err := store.ListNodeResults(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+	for _, nr := range results {
+		snap.Nodes[nr.NodeID] = SnapshotNode{Output: nr.Result}
+	}
+	nodes, err := store.ListNodeExecutions(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+	for _, ne := range nodes {
+		switch ne.Status {
+		case storage.NodeStatusSuccess, storage.NodeStatusCached:
+			if _, ok := snap.Nodes[ne.NodeID]; !ok {
+				return nil, errSnapshotNoData
+			}
+		case storage.NodeStatusFailed:
+			node := snap.Nodes[ne.NodeID]
+			if ne.Error != nil {
+				node.Error = *ne.Error
+			}
+			snap.Nodes[ne.NodeID] = node
+		}
+	}
+	for _, nodeID := range opts.Mock {
+		if len(snap.Nodes[nodeID].Output) == 0 {
+			return nil, &FieldError{Field: "mock", Message: fmt.Sprintf("node %s has no output to replay", nodeID)}
+		}
+	}
+	return snap, nil
+}
+
+// RunSnapshotTest runs workflow in test mode with the trigger data of snap,
+// its mocked nodes replaying their snapshot outputs and in mock mode if snap
+// has Mocks, and compares the run to
+// snap. The run is kept in history like any test run; it starts no error
+// workflow. An error is returned only if the run could not be started.
+func RunSnapshotTest(ctx context.Context, store storage.Storage, blocksDir string, workflow Workflow, snap *Snapshot) (*SnapshotReport, error) {
+	ectx := NewExecutionContext(workflow.ID)
+	ectx.Test = true
+	ectx.Mocks = snap.Mocks
+	if snap.TriggerData != nil {
+		ectx.TriggerData = maps.Clone(snap.TriggerData)
+	}
+	for _, nodeID := range snap.Mock {
+		node, ok := snap.Nodes[nodeID]
+		if !ok || len(node.Output) == 0 {
+			return nil, fmt.Errorf("mocked node %s has no output in the snapshot", nodeID)
+		}
+		var output interface{}
+		if err := json.Unmarshal(node.Output, &output); err != nil {
+			return nil, fmt.Errorf("invalid snapshot output of node %s: %w", nodeID, err)
+		}
+		ectx.MockNode(nodeID, output)
+	}
+
+	// The run is read back from storage to be compared, so it must keep its data
+	if workflow.Settings != nil {
+		settings := *workflow.Settings
+		settings.SaveSuccessData = SaveDataAll
+		workflow.Settings = &settings
+	}
+	runner := NewWorkflowRunner(ectx, blocksDir, store, nil)
+	runner.errorRun = true
+	execID, err := runner.CreateExecution(ctx, workflow.ID)
+	if err != nil {
+		return nil, err
+	}
+	// A failed run is compared like any other; how it ended is read back below
+	runner.Run(ctx, workflow)
+
+	got, err := NewSnapshot(ctx, store, execID, SnapshotOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return snap.Compare(got), nil
+}
+
+// Compare reports how the run recorded in got differs from s, as allowed by
+// the options of s.
+func (s *Snapshot) Compare(got *Snapshot) *SnapshotReport {
+	report := &SnapshotReport{
+		WorkflowID:     s.WorkflowID,
+		ExecutionID:    got.ExecutionID,
+		Passed:         got.Status == s.Status,
+		Status:         got.Status,
+		ExpectedStatus: s.Status,
+		Nodes:          []SnapshotNodeDiff{},
+	}
+	ignore := compileFieldPatterns(s.Ignore)
+	tolerances := make(map[*regexp.Regexp]float64, len(s.Tolerances))
+	for pattern, tolerance := range s.Tolerances {
+		tolerances[compileFieldPattern(pattern)] = tolerance
+	}
+
+	ids := slices.Collect(maps.Keys(s.Nodes))
+	for id := range got.Nodes {
+		if _, ok := s.Nodes[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		if matchField(ignore, id) {
+			continue
+		}
+		diff := SnapshotNodeDiff{NodeID: id, Mocked: slices.Contains(s.Mock, id)}
+		want, inWant := s.Nodes[id]
+		have, inHave := got.Nodes[id]
+		switch {
+		case !inHave:
+			diff.Status = DiffRemoved
+		case !inWant:
+			diff.Status = DiffAdded
+		default:
+			changes := DiffJSON(want.Output, have.Output)
+			if want.Error != have.Error {
+				changes = append(changes, FieldChange{Path: "error", Change: DiffChanged, A: rawJSON(want.Error), B: rawJSON(have.Error)})
+			}
+			diff.Status = DiffUnchanged
+			for _, c := range changes {
+				field := joinPath(id, c.Path)
+				if matchField(ignore, field) || withinTolerance(c, s.tolerance(tolerances, field)) {
+					continue
+				}
+				diff.Changes = append(diff.Changes, c)
+				diff.Status = DiffChanged
+			}
+		}
+		if diff.Status != DiffUnchanged {
+			report.Passed = false
+		}
+		report.Nodes = append(report.Nodes, diff)
+	}
+	return report
+}
+
+// tolerance returns how far the number in field may drift: the largest
+// tolerance of the patterns matching it, else s.Tolerance.
+func (s *Snapshot) tolerance(patterns map[*regexp.Regexp]float64, field string) float64 {
+	tolerance, matched := s.Tolerance, false
+	for re, t := range patterns {
+		if matchField([]*regexp.Regexp{re}, field) && (!matched || t > tolerance) {
+			tolerance, matched = t, true
+		}
+	}
+	return tolerance
+}
+
+// withinTolerance reports whether c changes a number by at most tolerance.
+func withinTolerance(c FieldChange, tolerance float64) bool {
+	if c.Change != DiffChanged || tolerance <= 0 {
+		return false
+	}
+	var a, b float64
+	if json.Unmarshal(c.A, &a) != nil || json.Unmarshal(c.B, &b) != nil {
+		return false
+	}
+	return math.Abs(a-b) <= tolerance
+}
+
+// compileFieldPatterns compiles field patterns of SnapshotOptions.
+func compileFieldPatterns(patterns []string) []*regexp.Regexp {
+	res := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		res[i] = compileFieldPattern(p)
+	}
+	return res
+}
+
+// compileFieldPattern compiles a field pattern of SnapshotOptions into a
+// regexp matching the field and everything it holds.
+func compileFieldPattern(pattern string) *regexp.Regexp {
+	quoted := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, `[^.\[]*`)
+	return regexp.MustCompile(`^` + quoted + `($|[.\[])`)
+}
+
+// matchField reports whether any of patterns matches field.
+func matchField(patterns []*regexp.Regexp, field string) bool {
+	for _, re := range patterns {
+		if re.MatchString(field) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// snapshotWorkflow computes a total from its trigger data, after fetching a
+// rate from a node that needs the script runtime unless it is mocked.
+func snapshotWorkflow(total interface{}) engine.Workflow {
+	return engine.Workflow{
+		ID: "wf-snapshot",
+		Nodes: map[string]engine.Node{
+			"fetch": {ID: "fetch", Type: "std/http_request", Config: map[string]interface{}{"url": "https://rates.example.com"}},
+			"total": {ID: "total", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "total", "value": total}},
+		},
+		Edges: []engine.Edge{{ID: "e1", Source: "fetch", Target: "total"}},
+	}
+}
+
+func TestSnapshotTest(t *testing.T) {
+	ctx := context.Background()
+	store := createTestStorage(t)
+	blocksDir := t.TempDir()
+
+	// The blessed run: its fetch output stands in for a real response
+	blessed := &engine.Snapshot{
+		WorkflowID:  "wf-snapshot",
+		TriggerData: map[string]interface{}{"amount": 40},
+		Nodes: map[string]engine.SnapshotNode{
+			"fetch": {Output: []byte(`{"data":{"rate":1.5,"date":"2026-10-01"},"port":"default"}`)},
+		},
+		SnapshotOptions: engine.SnapshotOptions{Mock: []string{"fetch"}},
+	}
+	report, err := engine.RunSnapshotTest(ctx, store, blocksDir, snapshotWorkflow("{{ $trigger.amount }}"), blessed)
+	if err != nil {
+		t.Fatalf("failed to run: %v", err)
+	}
+	snap, err := engine.NewSnapshot(ctx, store, report.ExecutionID, blessed.SnapshotOptions)
+	if err != nil {
+		t.Fatalf("failed to record snapshot: %v", err)
+	}
+	if snap.Status != "completed" || snap.TriggerData["amount"] != float64(40) || len(snap.Nodes) != 2 {
+		t.Fatalf("unexpected snapshot: %+v", snap)
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		report, err := engine.RunSnapshotTest(ctx, store, blocksDir, snapshotWorkflow("{{ $trigger.amount }}"), snap)
+		if err != nil {
+			t.Fatalf("failed to run: %v", err)
+		}
+		if !report.Passed || len(report.Nodes) != 2 || !report.Nodes[0].Mocked {
+			t.Errorf("expected the run to match its snapshot, got %+v", report)
+		}
+	})
+
+	t.Run("diverged", func(t *testing.T) {
+		report, err := engine.RunSnapshotTest(ctx, store, blocksDir, snapshotWorkflow(41), snap)
+		if err != nil {
+			t.Fatalf("failed to run: %v", err)
+		}
+		if report.Passed {
+			t.Fatal("expected a changed output to fail the test")
+		}
+		total := report.Nodes[1]
+		if total.NodeID != "total" || total.Status != engine.DiffChanged || len(total.Changes) == 0 || total.Changes[0].Path != "data.value" {
+			t.Errorf("expected total.data.value to differ, got %+v", total)
+		}
+	})
+
+	t.Run("within tolerance", func(t *testing.T) {
+		tolerant := *snap
+		tolerant.Tolerances = map[string]float64{"total.data.value": 1.5}
+		report, err := engine.RunSnapshotTest(ctx, store, blocksDir, snapshotWorkflow(41), &tolerant)
+		if err != nil {
+			t.Fatalf("failed to run: %v", err)
+		}
+		if !report.Passed {
+			t.Errorf("expected a drift within tolerance to pass, got %+v", report)
+		}
+	})
+
+	t.Run("ignored", func(t *testing.T) {
+		ignoring := *snap
+		ignoring.Ignore = []string{"*.data.value"}
+		report, err := engine.RunSnapshotTest(ctx, store, blocksDir, snapshotWorkflow("changed"), &ignoring)
+		if err != nil {
+			t.Fatalf("failed to run: %v", err)
+		}
+		if !report.Passed {
+			t.Errorf("expected an ignored field to pass, got %+v", report)
+		}
+	})
+}
+
+func TestSnapshot_Compare(t *testing.T) {
+	want := &engine.Snapshot{
+		Status: "completed",
+		Nodes: map[string]engine.SnapshotNode{
+			"a": {Output: []byte(`{"items":[{"price":10.0,"at":"t1"}]}`)},
+			"b": {Output: []byte(`{"ok":true}`)},
+		},
+		SnapshotOptions: engine.SnapshotOptions{Ignore: []string{"a.items[*].at"}, Tolerance: 0.01},
+	}
+	got := &engine.Snapshot{
+		Status: "completed",
+		Nodes: map[string]engine.SnapshotNode{
+			"a": {Output: []byte(`{"items":[{"price":10.001,"at":"t2"}]}`)},
+			"c": {Error: "boom"},
+		},
+	}
+
+	report := want.Compare(got)
+	if report.Passed {
+		t.Fatal("expected a missing and an extra node to fail the test")
+	}
+	statuses := map[string]string{}
+	for _, n := range report.Nodes {
+		statuses[n.NodeID] = n.Status
+	}
+	if statuses["a"] != engine.DiffUnchanged || statuses["b"] != engine.DiffRemoved || statuses["c"] != engine.DiffAdded {
+		t.Errorf("unexpected node statuses: %v", statuses)
+	}
+
+	got.Status = "failed"
+	if report := want.Compare(got); report.Status != "failed" || report.ExpectedStatus != "completed" {
+		t.Errorf("expected the run's status in the report, got %+v", report)
+	}
+}
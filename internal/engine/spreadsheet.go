@@ -0,0 +1,330 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSheetsBaseURL  = "https://sheets.googleapis.com"
+	defaultGoogleTokenURI = "https://oauth2.googleapis.com/token"
+	sheetsScope           = "https://www.googleapis.com/auth/spreadsheets"
+)
+
+// spreadsheetConfig is the config of a std/spreadsheet node, which reads and
+// appends rows in Google Sheets as a service account:
+//
+//	{"credentials": "{{ $globals.google_service_account }}", "spreadsheetId": "1AbC...",
+//	 "range": "Orders!A:D", "operation": "append", "rows": "{{ $node.csv.data.items }}"}
+type spreadsheetConfig struct {
+	// Credentials is the service account key file, as an object or a JSON string.
+	Credentials   interface{} `json:"credentials"`
+	SpreadsheetID string      `json:"spreadsheetId"`
+	Range         string      `json:"range"`
+	Operation     string      `json:"operation"`
+	// Header, true by default, reads rows as objects keyed by the first row.
+	Header *bool `json:"header"`
+	// Rows to append: objects (written in Columns order, default sorted keys) or arrays.
+	Rows    []interface{} `json:"rows"`
+	Columns []string      `json:"columns"`
+	// BaseURL overrides the Sheets API endpoint (for proxies and emulators).
+	BaseURL string `json:"baseUrl"`
+}
+
+// serviceAccount holds the fields of a Google service account key used to sign in.
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// executeSpreadsheet runs a std/spreadsheet node. Reading outputs
+// {"items": [...], "count": n, "range": "..."}; appending outputs
+// {"updatedRange": "...", "updatedRows": n, "count": n}.
+func executeSpreadsheet(ctx context.Context, input any) (any, error) {
+	payload, _ := input.(map[string]interface{})
+	var cfg spreadsheetConfig
+	raw, _ := json.Marshal(payload["config"])
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("spreadsheet: invalid config: %w", err)
+	}
+	if cfg.SpreadsheetID == "" || cfg.Range == "" {
+		return nil, fmt.Errorf("spreadsheet: config 'spreadsheetId' and 'range' are required")
+	}
+	if cfg.Operation != "read" && cfg.Operation != "append" {
+		return nil, fmt.Errorf("spreadsheet: config 'operation' must be 'read' or 'append'")
+	}
+	account, err := parseServiceAccount(cfg.Credentials)
+	if err != nil {
+		return nil, fmt.Errorf("spreadsheet: %w", err)
+	}
+	token, err := googleAccessToken(ctx, account)
+	if err != nil {
+		return nil, fmt.Errorf("spreadsheet: %w", err)
+	}
+
+	base := strings.TrimSuffix(cfg.BaseURL, "/")
+	if base == "" {
+		base = defaultSheetsBaseURL
+	}
+	endpoint := fmt.Sprintf("%s/v4/spreadsheets/%s/values/%s", base, url.PathEscape(cfg.SpreadsheetID), url.PathEscape(cfg.Range))
+
+	var data map[string]interface{}
+	if cfg.Operation == "read" {
+		data, err = readSheet(ctx, endpoint, token, cfg.Header == nil || *cfg.Header)
+	} else {
+		data, err = appendSheet(ctx, endpoint, token, cfg.Rows, cfg.Columns)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("spreadsheet: %w", err)
+	}
+	return map[string]interface{}{"data": data, "port": "default"}, nil
+}
+
+func readSheet(ctx context.Context, endpoint, token string, header bool) (map[string]interface{}, error) {
+	var resp struct {
+		Range  string          `json:"range"`
+		Values [][]interface{} `json:"values"`
+	}
+	if err := sheetsRequest(ctx, http.MethodGet, endpoint, token, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	items := []interface{}{}
+	rows := resp.Values
+	if header && len(rows) > 0 {
+		columns := rows[0]
+		for _, row := range rows[1:] {
+			item := make(map[string]interface{}, len(columns))
+			for i, col := range columns {
+				// The API omits trailing empty cells
+				var value interface{} = ""
+				if i < len(row) {
+					value = row[i]
+				}
+				item[fmt.Sprint(col)] = value
+			}
+			items = append(items, item)
+		}
+	} else {
+		for _, row := range rows {
+			items = append(items, row)
+		}
+	}
+	return map[string]interface{}{"items": items, "count": len(items), "range": resp.Range}, nil
+}
+
+func appendSheet(ctx context.Context, endpoint, token string, rows []interface{}, columns []string) (map[string]interface{}, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("config 'rows' must not be empty")
+	}
+	if len(columns) == 0 {
+		columns = objectColumns(rows)
+	}
+	values := make([][]interface{}, len(rows))
+	for i, item := range rows {
+		switch row := item.(type) {
+		case map[string]interface{}:
+			values[i] = make([]interface{}, len(columns))
+			for j, col := range columns {
+				values[i][j] = sheetCell(row[col])
+			}
+		case []interface{}:
+			values[i] = make([]interface{}, len(row))
+			for j, v := range row {
+				values[i][j] = sheetCell(v)
+			}
+		default:
+			return nil, fmt.Errorf("row %d must be an object or an array, got %T", i, item)
+		}
+	}
+
+	var resp struct {
+		Updates struct {
+			UpdatedRange string `json:"updatedRange"`
+			UpdatedRows  int    `json:"updatedRows"`
+		} `json:"updates"`
+	}
+	body := map[string]interface{}{"values": values}
+	if err := sheetsRequest(ctx, http.MethodPost, endpoint+":append?valueInputOption=USER_ENTERED", token, body, &resp); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"updatedRange": resp.Updates.UpdatedRange,
+		"updatedRows":  resp.Updates.UpdatedRows,
+		"count":        len(rows),
+	}, nil
+}
+
+// sheetCell keeps scalars as they are and writes nested values as JSON.
+func sheetCell(v interface{}) interface{} {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return csvCell(v)
+	case nil:
+		return ""
+	default:
+		return v
+	}
+}
+
+func sheetsRequest(ctx context.Context, method, endpoint, token string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	usageFrom(ctx).addHTTPCall()
+	res, err := httpClient(ctx).Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	respBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("Sheets API returned %s: %s", res.Status, strings.TrimSpace(string(respBody)))
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+func parseServiceAccount(credentials interface{}) (*serviceAccount, error) {
+	raw, ok := credentials.(string)
+	if !ok {
+		b, _ := json.Marshal(credentials)
+		raw = string(b)
+	}
+	var account serviceAccount
+	if err := json.Unmarshal([]byte(raw), &account); err != nil || account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, fmt.Errorf("config 'credentials' must be a service account key with client_email and private_key")
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = defaultGoogleTokenURI
+	}
+	return &account, nil
+}
+
+// googleToken is a cached access token for one service account.
+type googleToken struct {
+	value   string
+	expires time.Time
+}
+
+var (
+	googleTokensMu sync.Mutex
+	googleTokens   = map[string]googleToken{}
+)
+
+// googleAccessToken exchanges a signed JWT for an OAuth access token (RFC 7523),
+// reusing a cached token until shortly before it expires. Runs in mock mode
+// neither use nor fill the cache, so mocked tokens stay in their run.
+func googleAccessToken(ctx context.Context, account *serviceAccount) (string, error) {
+	key := account.ClientEmail + " " + account.TokenURI
+	mocked := mocksFrom(ctx) != nil
+	googleTokensMu.Lock()
+	cached, ok := googleTokens[key]
+	googleTokensMu.Unlock()
+	if ok && !mocked && time.Until(cached.expires) > time.Minute {
+		return cached.value, nil
+	}
+
+	assertion, err := signServiceAccountJWT(account, time.Now())
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	usageFrom(ctx).addHTTPCall()
+	res, err := httpClient(ctx).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get access token: %w", err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get access token: %s: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil || token.AccessToken == "" {
+		return "", fmt.Errorf("failed to get access token: invalid response")
+	}
+
+	if !mocked {
+		googleTokensMu.Lock()
+		googleTokens[key] = googleToken{value: token.AccessToken, expires: time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)}
+		googleTokensMu.Unlock()
+	}
+	return token.AccessToken, nil
+}
+
+// signServiceAccountJWT builds the RS256-signed assertion for the token exchange.
+func signServiceAccountJWT(account *serviceAccount, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return "", fmt.Errorf("invalid service account private_key")
+	}
+	var key *rsa.PrivateKey
+	if parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		key, _ = parsed.(*rsa.PrivateKey)
+	} else if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+		return "", fmt.Errorf("invalid service account private_key: %w", err)
+	}
+	if key == nil {
+		return "", fmt.Errorf("service account private_key must be an RSA key")
+	}
+
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   account.ClientEmail,
+		"scope": sheetsScope,
+		"aud":   account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	signingInput := header + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token request: %w", err)
+	}
+	return signingInput + "." + enc.EncodeToString(sig), nil
+}
@@ -0,0 +1,148 @@
+package engine_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// TestBunRunner_ExecuteNode_Spreadsheet verifies that std/spreadsheet signs in
+// with a service account and reads and appends rows through the Sheets API.
+func TestBunRunner_ExecuteNode_Spreadsheet(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	privateKey := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+
+	var tokenRequests int
+	var appended [][]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		r.ParseForm()
+		parts := strings.Split(r.PostForm.Get("assertion"), ".")
+		if r.PostForm.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || len(parts) != 3 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		if !strings.Contains(string(claims), `"iss":"bot@example.iam.gserviceaccount.com"`) {
+			http.Error(w, "bad issuer", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "tok", "expires_in": 3600})
+	})
+	mux.HandleFunc("/v4/spreadsheets/{id}/values/{rest}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" || r.PathValue("id") != "sheet1" {
+			http.Error(w, `{"error":"forbidden"}`, http.StatusForbidden)
+			return
+		}
+		switch r.PathValue("rest") {
+		case "Orders!A:C":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"range":  "Orders!A1:C3",
+				"values": [][]interface{}{{"id", "name", "total"}, {"1", "Ada", "9.99"}, {"2", "Bob"}},
+			})
+		case "Orders!A:C:append":
+			if r.URL.Query().Get("valueInputOption") != "USER_ENTERED" {
+				http.Error(w, "missing valueInputOption", http.StatusBadRequest)
+				return
+			}
+			var body struct {
+				Values [][]interface{} `json:"values"`
+			}
+			b, _ := io.ReadAll(r.Body)
+			json.Unmarshal(b, &body)
+			appended = body.Values
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"updates": map[string]interface{}{"updatedRange": "Orders!A4:C5", "updatedRows": len(body.Values)},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	credentials, _ := json.Marshal(map[string]interface{}{
+		"type":         "service_account",
+		"client_email": "bot@example.iam.gserviceaccount.com",
+		"private_key":  privateKey,
+		"token_uri":    srv.URL + "/token",
+	})
+	runner := engine.NewBunRunner(t.TempDir())
+	runner.RuntimePath = "/nonexistent/bun" // any process start would fail
+	node := &engine.Node{ID: "sheet", Type: engine.NodeTypeSpreadsheet}
+	run := func(config map[string]interface{}) (map[string]interface{}, error) {
+		config["credentials"] = string(credentials)
+		config["spreadsheetId"] = "sheet1"
+		config["baseUrl"] = srv.URL
+		raw, err := runner.ExecuteNode(context.Background(), node, map[string]interface{}{"config": config})
+		if err != nil {
+			return nil, err
+		}
+		return raw.(map[string]interface{})["data"].(map[string]interface{}), nil
+	}
+
+	data, err := run(map[string]interface{}{"operation": "read", "range": "Orders!A:C"})
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	items, _ := json.Marshal(data["items"])
+	want := `[{"id":"1","name":"Ada","total":"9.99"},{"id":"2","name":"Bob","total":""}]`
+	if string(items) != want {
+		t.Errorf("expected items %s, got %s", want, items)
+	}
+
+	data, err = run(map[string]interface{}{
+		"operation": "append",
+		"range":     "Orders!A:C",
+		"columns":   []interface{}{"id", "name", "total"},
+		"rows": []interface{}{
+			map[string]interface{}{"id": 3.0, "name": "Cy", "total": 5.0},
+			[]interface{}{4.0, "Di", nil},
+		},
+	})
+	if err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+	if data["updatedRows"] != 2 || data["updatedRange"] != "Orders!A4:C5" {
+		t.Errorf("unexpected append result: %v", data)
+	}
+	if got, _ := json.Marshal(appended); string(got) != `[[3,"Cy",5],[4,"Di",""]]` {
+		t.Errorf("unexpected appended values %s", got)
+	}
+	if tokenRequests != 1 {
+		t.Errorf("expected the access token to be reused, got %d token requests", tokenRequests)
+	}
+
+	if _, err := run(map[string]interface{}{"operation": "read", "range": "Missing!A:A"}); err == nil {
+		t.Error("expected an API error to fail the node")
+	}
+	if _, err := runner.ExecuteNode(context.Background(), node, map[string]interface{}{"config": map[string]interface{}{
+		"operation": "read", "range": "A:A", "spreadsheetId": "x", "credentials": "{}",
+	}}); err == nil {
+		t.Error("expected invalid credentials to fail")
+	}
+}
@@ -0,0 +1,261 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	defaultSSHPort           = 22
+	defaultSSHConnectTimeout = 10 * time.Second
+	// maxSSHOutput bounds how much of each stream is kept in the node result;
+	// every line is still streamed as a NodeOutput event.
+	maxSSHOutput = 1 << 20
+)
+
+// sshConnection holds the connection settings shared by std/ssh and std/sftp.
+// Credentials are usually given via $globals so they stay out of the workflow
+// definition:
+//
+//	{"host": "db1", "user": "ops", "privateKey": "{{ $globals.deploy_key }}",
+//	 "hostKey": "ssh-ed25519 AAAA...", "command": "systemctl restart app"}
+type sshConnection struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+	User string `json:"user"`
+	// Password and/or PrivateKey (PEM or OpenSSH format, optionally encrypted
+	// with Passphrase) authenticate the connection.
+	Password   string `json:"password"`
+	PrivateKey string `json:"privateKey"`
+	Passphrase string `json:"passphrase"`
+	// HostKey is the server's public key in authorized_keys format. Connections to
+	// a server presenting another key are refused; InsecureIgnoreHostKey skips the check.
+	HostKey               string `json:"hostKey"`
+	InsecureIgnoreHostKey bool   `json:"insecureIgnoreHostKey"`
+	// ConnectTimeout in seconds (default 10).
+	ConnectTimeout int `json:"connectTimeout"`
+}
+
+// sshConfig is the config of a std/ssh node.
+type sshConfig struct {
+	sshConnection
+	// Command runs a single command; Script is piped to "sh -s" on the server.
+	Command string `json:"command"`
+	Script  string `json:"script"`
+}
+
+// executeSSH runs a std/ssh node: it connects to the server, runs the command or
+// script, streams its output line by line and routes a non-zero exit code to the
+// "error" port. Output: {"data": {"host", "command", "exitCode", "stdout", "stderr"},
+// "port": "success"|"error"}.
+func executeSSH(ctx context.Context, input any) (any, error) {
+	payload, _ := input.(map[string]interface{})
+	var cfg sshConfig
+	raw, _ := json.Marshal(payload["config"])
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("ssh: invalid config: %w", err)
+	}
+	if (cfg.Command == "") == (cfg.Script == "") {
+		return nil, fmt.Errorf("ssh: exactly one of config 'command' or 'script' is required")
+	}
+	command := cfg.Command
+	if cfg.Script != "" {
+		command = "sh -s"
+	}
+
+	client, err := cfg.dial(ctx, "ssh")
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("ssh: failed to open session: %w", err)
+	}
+	defer session.Close()
+	if cfg.Script != "" {
+		session.Stdin = strings.NewReader(cfg.Script)
+	}
+	stdoutPipe, err := session.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("ssh: %w", err)
+	}
+	stderrPipe, err := session.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("ssh: %w", err)
+	}
+
+	if err := session.Start(command); err != nil {
+		return nil, fmt.Errorf("ssh: failed to start command: %w", err)
+	}
+
+	var stdout, stderr cappedBuffer
+	var streams sync.WaitGroup
+	streams.Add(2)
+	go streamLines(ctx, "stdout", stdoutPipe, &stdout, &streams)
+	go streamLines(ctx, "stderr", stderrPipe, &stderr, &streams)
+
+	done := make(chan error, 1)
+	go func() {
+		streams.Wait()
+		done <- session.Wait()
+	}()
+
+	var waitErr error
+	select {
+	case waitErr = <-done:
+	case <-ctx.Done():
+		session.Signal(ssh.SIGKILL)
+		client.Close()
+		return nil, ctx.Err()
+	}
+
+	exitCode := 0
+	var exitErr *ssh.ExitError
+	switch {
+	case waitErr == nil:
+	case errors.As(waitErr, &exitErr):
+		exitCode = exitErr.ExitStatus()
+	default:
+		return nil, fmt.Errorf("ssh: command did not complete: %w", waitErr)
+	}
+
+	port := "success"
+	if exitCode != 0 {
+		port = "error"
+	}
+	return map[string]interface{}{
+		"data": map[string]interface{}{
+			"host":     cfg.Host,
+			"command":  command,
+			"exitCode": exitCode,
+			"stdout":   stdout.String(),
+			"stderr":   stderr.String(),
+		},
+		"port": port,
+	}, nil
+}
+
+// dial connects and authenticates to the server. prefix names the node in errors.
+func (c *sshConnection) dial(ctx context.Context, prefix string) (*ssh.Client, error) {
+	clientConfig, err := c.clientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", prefix, err)
+	}
+
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	dialer := net.Dialer{Timeout: clientConfig.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to connect to %s: %w", prefix, addr, err)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, clientConfig)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s: handshake with %s failed: %w", prefix, addr, err)
+	}
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// clientConfig validates the connection settings, fills in defaults and builds
+// the SSH client config.
+func (c *sshConnection) clientConfig() (*ssh.ClientConfig, error) {
+	if c.Host == "" {
+		return nil, fmt.Errorf("config 'host' is required")
+	}
+	if c.User == "" {
+		return nil, fmt.Errorf("config 'user' is required")
+	}
+	if c.Port == 0 {
+		c.Port = defaultSSHPort
+	}
+
+	var auth []ssh.AuthMethod
+	if c.PrivateKey != "" {
+		var signer ssh.Signer
+		var err error
+		if c.Passphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(c.PrivateKey), []byte(c.Passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(c.PrivateKey))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if c.Password != "" {
+		auth = append(auth, ssh.Password(c.Password))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("config 'password' or 'privateKey' is required")
+	}
+
+	var hostKeyCallback ssh.HostKeyCallback
+	switch {
+	case c.HostKey != "":
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(c.HostKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid host key: %w", err)
+		}
+		hostKeyCallback = ssh.FixedHostKey(key)
+	case c.InsecureIgnoreHostKey:
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	default:
+		return nil, fmt.Errorf("config 'hostKey' is required (or set 'insecureIgnoreHostKey')")
+	}
+
+	timeout := defaultSSHConnectTimeout
+	if c.ConnectTimeout > 0 {
+		timeout = time.Duration(c.ConnectTimeout) * time.Second
+	}
+	return &ssh.ClientConfig{
+		User:            c.User,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         timeout,
+	}, nil
+}
+
+// streamLines publishes each line read from r as node output and keeps it in buf.
+func streamLines(ctx context.Context, stream string, r io.Reader, buf *cappedBuffer, wg *sync.WaitGroup) {
+	defer wg.Done()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxSSHOutput)
+	for scanner.Scan() {
+		line := scanner.Text()
+		publishNodeOutput(ctx, stream, line)
+		buf.WriteLine(line)
+	}
+	// Drain whatever is left (e.g. an over-long line) so the command isn't blocked
+	io.Copy(io.Discard, r)
+}
+
+// cappedBuffer collects lines up to maxSSHOutput bytes, dropping the rest.
+type cappedBuffer struct {
+	b strings.Builder
+}
+
+func (c *cappedBuffer) WriteLine(line string) {
+	if c.b.Len()+len(line)+1 > maxSSHOutput {
+		return
+	}
+	c.b.WriteString(line)
+	c.b.WriteByte('\n')
+}
+
+func (c *cappedBuffer) String() string {
+	return c.b.String()
+}
@@ -0,0 +1,245 @@
+package engine_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// startSSHServer runs an in-process SSH server accepting user "ops" with password
+// "secret" or clientKey. It understands three commands: "uptime" prints a line,
+// "fail" prints to stderr and exits 3, and "sh -s" echoes the script it is sent.
+// The sftp subsystem serves the local filesystem.
+// Returns the server's port and its host key in authorized_keys format.
+func startSSHServer(t *testing.T, clientKey ssh.PublicKey) (int, string) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate host key: %v", err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("failed to create host signer: %v", err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if c.User() == "ops" && string(pass) == "secret" {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+		PublicKeyCallback: func(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if clientKey != nil && c.User() == "ops" && string(key.Marshal()) == string(clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	var wg sync.WaitGroup
+	t.Cleanup(func() {
+		ln.Close()
+		wg.Wait()
+	})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				serveSSHConn(conn, config)
+			}()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, string(ssh.MarshalAuthorizedKey(hostSigner.PublicKey()))
+}
+
+func serveSSHConn(conn net.Conn, config *ssh.ServerConfig) {
+	defer conn.Close()
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newCh := range chans {
+		if newCh.ChannelType() != "session" {
+			newCh.Reject(ssh.UnknownChannelType, "only sessions")
+			continue
+		}
+		ch, chReqs, err := newCh.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			defer ch.Close()
+			for req := range chReqs {
+				if req.Type == "subsystem" {
+					var subsystem struct{ Name string }
+					ssh.Unmarshal(req.Payload, &subsystem)
+					req.Reply(subsystem.Name == "sftp", nil)
+					if subsystem.Name == "sftp" {
+						if server, err := sftp.NewServer(ch); err == nil {
+							server.Serve()
+						}
+						return
+					}
+					continue
+				}
+				if req.Type != "exec" {
+					req.Reply(false, nil)
+					continue
+				}
+				var exec struct{ Command string }
+				ssh.Unmarshal(req.Payload, &exec)
+				req.Reply(true, nil)
+
+				status := uint32(0)
+				switch exec.Command {
+				case "uptime":
+					io.WriteString(ch, "up 3 days\nload 0.1\n")
+				case "fail":
+					io.WriteString(ch.Stderr(), "boom\n")
+					status = 3
+				case "sh -s":
+					io.Copy(ch, ch)
+				default:
+					status = 127
+				}
+				ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+				return
+			}
+		}()
+	}
+}
+
+// TestBunRunner_ExecuteNode_SSH verifies that std/ssh runs commands and scripts
+// over SSH, routes non-zero exit codes to the error port and checks the host key.
+func TestBunRunner_ExecuteNode_SSH(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate client key: %v", err)
+	}
+	clientPub, _ := ssh.NewPublicKey(pub)
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatalf("failed to marshal client key: %v", err)
+	}
+	privateKey := string(pem.EncodeToMemory(block))
+
+	port, hostKey := startSSHServer(t, clientPub)
+	runner := engine.NewBunRunner(t.TempDir())
+	runner.RuntimePath = "/nonexistent/bun" // any process start would fail
+	node := &engine.Node{ID: "remote", Type: engine.NodeTypeSSH}
+	run := func(config map[string]interface{}) (map[string]interface{}, error) {
+		config["host"] = "127.0.0.1"
+		config["port"] = float64(port)
+		config["user"] = "ops"
+		if _, ok := config["hostKey"]; !ok {
+			config["hostKey"] = hostKey
+		}
+		raw, err := runner.ExecuteNode(context.Background(), node, map[string]interface{}{"config": config})
+		if err != nil {
+			return nil, err
+		}
+		return raw.(map[string]interface{}), nil
+	}
+
+	result, err := run(map[string]interface{}{"password": "secret", "command": "uptime"})
+	if err != nil {
+		t.Fatalf("ExecuteNode failed: %v", err)
+	}
+	data := result["data"].(map[string]interface{})
+	if result["port"] != "success" || data["exitCode"] != 0 || data["stdout"] != "up 3 days\nload 0.1\n" {
+		t.Errorf("unexpected result for uptime: %v", result)
+	}
+
+	result, err = run(map[string]interface{}{"password": "secret", "command": "fail"})
+	if err != nil {
+		t.Fatalf("ExecuteNode failed: %v", err)
+	}
+	data = result["data"].(map[string]interface{})
+	if result["port"] != "error" || data["exitCode"] != 3 || data["stderr"] != "boom\n" {
+		t.Errorf("unexpected result for failing command: %v", result)
+	}
+
+	script := "cd /srv/app\n./deploy.sh\n"
+	result, err = run(map[string]interface{}{"privateKey": privateKey, "script": script})
+	if err != nil {
+		t.Fatalf("ExecuteNode with private key failed: %v", err)
+	}
+	if stdout := result["data"].(map[string]interface{})["stdout"]; stdout != script {
+		t.Errorf("expected the script to be sent on stdin, got %q", stdout)
+	}
+
+	_, otherHost := startSSHServer(t, nil)
+	if _, err := run(map[string]interface{}{"password": "secret", "command": "uptime", "hostKey": otherHost}); err == nil {
+		t.Error("expected a mismatched host key to be refused")
+	}
+	if _, err := run(map[string]interface{}{"password": "wrong", "command": "uptime"}); err == nil {
+		t.Error("expected a wrong password to fail")
+	}
+	if _, err := run(map[string]interface{}{"password": "secret", "command": "uptime", "hostKey": ""}); err == nil {
+		t.Error("expected a missing host key to be rejected")
+	}
+}
+
+// TestWorkflowRunner_SSHStreamsOutput verifies that std/ssh output lines are
+// published as NodeOutput events while the node runs.
+func TestWorkflowRunner_SSHStreamsOutput(t *testing.T) {
+	port, hostKey := startSSHServer(t, nil)
+	store := createTestStorage(t)
+	workflow := engine.Workflow{
+		ID:   "ssh-wf",
+		Name: "SSH",
+		Nodes: map[string]engine.Node{
+			"remote": {ID: "remote", Type: engine.NodeTypeSSH, Config: map[string]interface{}{
+				"host":     "127.0.0.1",
+				"port":     float64(port),
+				"user":     "ops",
+				"password": "secret",
+				"hostKey":  hostKey,
+				"command":  "uptime",
+			}},
+		},
+	}
+	bus := engine.NewEventBus()
+	var mu sync.Mutex
+	var lines []string
+	unsubscribe := bus.Subscribe(func(ev engine.Event) {
+		out := ev.(engine.NodeOutput)
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, out.NodeID+"/"+out.Stream+": "+out.Line)
+	}, engine.EventNodeOutput)
+
+	runner := engine.NewWorkflowRunner(engine.NewExecutionContext(workflow.ID), t.TempDir(), store, nil)
+	runner.SetEventBus(bus)
+	if err := runner.Run(context.Background(), workflow); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	unsubscribe()
+
+	want := []string{"remote/stdout: up 3 days", "remote/stdout: load 0.1"}
+	if len(lines) != len(want) || lines[0] != want[0] || lines[1] != want[1] {
+		t.Errorf("expected streamed lines %q, got %q", want, lines)
+	}
+}
@@ -15,12 +15,12 @@ func NewStateManager(ctx *ExecutionContext) *StateManager {
 
 // SetResult saves the output of a block.
 func (sm *StateManager) SetResult(blockID string, result interface{}) {
-	sm.ctx.Results[blockID] = result
+	sm.ctx.SetResult(blockID, result)
 }
 
 // GetResult retrieves the output of a block.
 func (sm *StateManager) GetResult(blockID string) interface{} {
-	return sm.ctx.Results[blockID]
+	return sm.ctx.GetResult(blockID)
 }
 
 // PrepareInput creates the input payload for a block, resolving any variables in the config.
@@ -35,6 +35,6 @@ func (sm *StateManager) PrepareInput(block Block) (map[string]interface{}, error
 	return map[string]interface{}{
 		"config": resolvedConfig,
 		// We also pass the full context if the block needs it (e.g. custom code)
-		// "context": sm.ctx.Results,
+		// "context": sm.ctx.Results(),
 	}, nil
 }
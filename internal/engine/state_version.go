@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// executionStateVersion is the version of the state saved with executions to
+// resume them (resumeState and suspendedState). Bump it whenever either changes
+// shape in a way older decoders can't read, and upgrade the previous version in
+// decodeResumeState or decodeSuspendedState, so executions in flight across an
+// upgrade still resume. Version 0 is state saved before versioning.
+const executionStateVersion = 1
+
+// stateVersion returns the version saved state was written with.
+func stateVersion(data []byte) (int, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return 0, err
+	}
+	if header.Version < 0 || header.Version > executionStateVersion {
+		return 0, fmt.Errorf("unsupported state version %d (this build reads up to %d)", header.Version, executionStateVersion)
+	}
+	return header.Version, nil
+}
+
+// decodeResumeState decodes the state a run saved, of any version.
+func decodeResumeState(data []byte) (resumeState, error) {
+	var state resumeState
+	if _, err := stateVersion(data); err != nil {
+		return state, err
+	}
+	// Version 0 has the same fields as version 1
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, err
+	}
+	state.Version = executionStateVersion
+	return state, nil
+}
+
+// decodeSuspendedState decodes the state of a suspended execution, of any version.
+func decodeSuspendedState(data []byte) (suspendedState, error) {
+	var state suspendedState
+	version, err := stateVersion(data)
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, err
+	}
+	if version == 0 && state.NodeType == "" {
+		// Saved before std/enqueue, when only delay nodes suspended
+		state.NodeType = NodeTypeDelay
+	}
+	state.Version = executionStateVersion
+	return state, nil
+}
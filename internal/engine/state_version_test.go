@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// States as saved by earlier builds. Executions holding them may still be in
+// flight after an upgrade, so every version must keep decoding; add the
+// previous shape here whenever executionStateVersion is bumped.
+var (
+	resumeStateV0    = `{"results":{"a":{"ok":true}},"variables":{"n":1},"current_node_id":"b","environment":"prod"}`
+	resumeStateV1    = `{"version":1,"results":{"a":{"ok":true}},"variables":{"n":1},"current_node_id":"b","environment":"prod"}`
+	suspendedStateV0 = `{"workflow":{"id":"wf","name":"","nodes":{"d":{"id":"d","type":"std/delay"}},"edges":null},` +
+		`"node_id":"d","unit":"s","started_at":"2025-01-01T00:00:00Z","suspended_at":"2025-01-01T00:00:01Z",` +
+		`"wake_at":"2025-01-01T00:01:01Z","trigger_data":null,"results":{},"variables":{}}`
+	suspendedStateV0Enqueue = `{"workflow":{"id":"wf","name":"","nodes":{},"edges":null},"node_id":"q","node_type":"std/enqueue",` +
+		`"unit":"s","started_at":"2025-01-01T00:00:00Z","suspended_at":"2025-01-01T00:00:01Z","wake_at":"0001-01-01T00:00:00Z",` +
+		`"signal":"go","trigger_data":null,"results":{},"variables":{},"pending":["x"]}`
+	suspendedStateV1 = `{"version":1,"workflow":{"id":"wf","name":"","nodes":{},"edges":null},"node_id":"q","node_type":"std/enqueue",` +
+		`"unit":"s","started_at":"2025-01-01T00:00:00Z","suspended_at":"2025-01-01T00:00:01Z","wake_at":"0001-01-01T00:00:00Z",` +
+		`"signal":"go","trigger_data":null,"results":{},"variables":{},"pending":["x"]}`
+)
+
+func TestDecodeResumeState(t *testing.T) {
+	want := resumeState{
+		Version:       executionStateVersion,
+		Results:       map[string]interface{}{"a": map[string]interface{}{"ok": true}},
+		Variables:     map[string]interface{}{"n": float64(1)},
+		CurrentNodeID: "b",
+		Environment:   "prod",
+	}
+	for name, data := range map[string]string{"v0": resumeStateV0, "v1": resumeStateV1} {
+		got, err := decodeResumeState([]byte(data))
+		if err != nil {
+			t.Errorf("%s: decode failed: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestDecodeSuspendedState(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		nodeType NodeType
+		signal   string
+		pending  []string
+	}{
+		{"v0 delay without node type", suspendedStateV0, NodeTypeDelay, "", nil},
+		{"v0 enqueue", suspendedStateV0Enqueue, NodeTypeEnqueue, "go", []string{"x"}},
+		{"v1", suspendedStateV1, NodeTypeEnqueue, "go", []string{"x"}},
+	}
+	for _, tt := range tests {
+		got, err := decodeSuspendedState([]byte(tt.data))
+		if err != nil {
+			t.Errorf("%s: decode failed: %v", tt.name, err)
+			continue
+		}
+		if got.Version != executionStateVersion || got.NodeType != tt.nodeType || got.Signal != tt.signal ||
+			!reflect.DeepEqual(got.Pending, tt.pending) || got.Workflow.ID != "wf" || got.Unit != "s" {
+			t.Errorf("%s: unexpected state %+v", tt.name, got)
+		}
+		if !got.SuspendedAt.Equal(time.Date(2025, 1, 1, 0, 0, 1, 0, time.UTC)) {
+			t.Errorf("%s: expected suspended_at to survive, got %v", tt.name, got.SuspendedAt)
+		}
+	}
+}
+
+func TestDecodeState_UnsupportedVersion(t *testing.T) {
+	for _, data := range []string{`{"version":2}`, `{"version":-1}`, `not json`} {
+		if _, err := decodeResumeState([]byte(data)); err == nil {
+			t.Errorf("expected decodeResumeState(%s) to fail", data)
+		}
+		if _, err := decodeSuspendedState([]byte(data)); err == nil {
+			t.Errorf("expected decodeSuspendedState(%s) to fail", data)
+		}
+	}
+}
+
+// TestStateShape fails when the saved state gains, loses or renames a field,
+// as a reminder to decide whether executionStateVersion needs a bump. Update
+// the expected keys once it has been.
+func TestStateShape(t *testing.T) {
+	keys := func(v any) []string {
+		data, _ := json.Marshal(v)
+		var m map[string]interface{}
+		json.Unmarshal(data, &m)
+		var ks []string
+		for k := range m {
+			ks = append(ks, k)
+		}
+		sort.Strings(ks)
+		return ks
+	}
+
+	resume := resumeState{Version: executionStateVersion, Environment: "e"}
+	if got, want := keys(resume), []string{"current_node_id", "environment", "results", "variables", "version"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("resumeState keys changed: got %v, want %v", got, want)
+	}
+
+	suspended := suspendedState{Version: executionStateVersion, NodeType: NodeTypeDelay, Signal: "s", Environment: "e",
+		Static: map[string]interface{}{"k": 1}, Pending: []string{"p"}}
+	want := []string{"environment", "node_id", "node_type", "pending", "results", "signal", "started_at", "static",
+		"suspended_at", "trigger_data", "unit", "variables", "version", "wake_at", "workflow"}
+	if got := keys(suspended); !reflect.DeepEqual(got, want) {
+		t.Errorf("suspendedState keys changed: got %v, want %v", got, want)
+	}
+}
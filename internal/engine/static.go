@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// loadStaticData seeds ectx with the static data persisted by the workflow's
+// previous runs ($workflowStatic). A workflow that never stored any starts empty.
+func loadStaticData(ctx context.Context, store storage.Storage, ectx *ExecutionContext) error {
+	raw, err := store.GetWorkflowStaticData(ctx, ectx.WorkflowID)
+	if err != nil {
+		return err
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("failed to parse workflow static data: %w", err)
+	}
+	ectx.LoadStatic(data)
+	return nil
+}
+
+// saveStaticData persists ectx's static data if a node changed it. Runners call
+// this only for completed executions, so a cursor never advances past a failed run.
+func saveStaticData(ctx context.Context, store storage.Storage, ectx *ExecutionContext) error {
+	data, changed := ectx.StaticData()
+	if !changed {
+		return nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode workflow static data: %w", err)
+	}
+	return store.SaveWorkflowStaticData(ctx, ectx.WorkflowID, raw)
+}
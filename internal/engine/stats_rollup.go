@@ -0,0 +1,70 @@
+package engine
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// defaultStatsRollupInterval is how often the daily workflow stats are rebuilt.
+const defaultStatsRollupInterval = 5 * time.Minute
+
+// StatsRollup keeps the daily workflow stats of storage up to date, so stats
+// are read from one row per workflow and day instead of the execution history.
+// Each rollup rebuilds today and yesterday, the latter to take in the runs
+// that finished just before midnight after its last rollup.
+type StatsRollup struct {
+	// PollInterval is how often the stats are rebuilt, and so how stale they may get.
+	PollInterval time.Duration
+
+	store storage.Storage
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewStatsRollup creates a rollup of the executions in store.
+func NewStatsRollup(store storage.Storage) *StatsRollup {
+	return &StatsRollup{
+		PollInterval: defaultStatsRollupInterval,
+		store:        store,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// Start begins rebuilding the stats in the background.
+func (r *StatsRollup) Start() {
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.PollInterval)
+		defer ticker.Stop()
+		for {
+			r.Rollup(context.Background(), time.Now())
+			select {
+			case <-ticker.C:
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops rebuilding the stats.
+func (r *StatsRollup) Stop() {
+	r.stopOnce.Do(func() { close(r.stop) })
+	<-r.done
+}
+
+// Rollup rebuilds the stats of the day of now and the day before.
+func (r *StatsRollup) Rollup(ctx context.Context, now time.Time) {
+	for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+		if err := r.store.RollupWorkflowStats(ctx, day); err != nil {
+			log.Printf("Stats rollup: failed to roll up %s: %v", day.UTC().Format(time.DateOnly), err)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func TestStatsRollup(t *testing.T) {
+	ctx := context.Background()
+	store := createTestStorage(t)
+
+	for _, status := range []storage.ExecutionStatus{storage.ExecutionStatusCompleted, storage.ExecutionStatusCompleted, storage.ExecutionStatusFailed} {
+		execID, _ := store.CreateExecution(ctx, "wf-stats")
+		store.UpdateExecutionStatus(ctx, execID, status, []byte(`{}`), nil)
+	}
+
+	rollup := engine.NewStatsRollup(store)
+	rollup.Rollup(ctx, time.Now())
+
+	stats, err := store.ListWorkflowStats(ctx, "wf-stats", time.Now().AddDate(0, 0, -1))
+	if err != nil {
+		t.Fatalf("failed to list stats: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Runs != 3 || stats[0].Failures != 1 {
+		t.Fatalf("expected today's 3 runs with 1 failure, got %+v", stats)
+	}
+
+	// Runs finished after a rollup are counted by the next one
+	execID, _ := store.CreateExecution(ctx, "wf-stats")
+	store.UpdateExecutionStatus(ctx, execID, storage.ExecutionStatusFailed, []byte(`{}`), nil)
+	rollup.Rollup(ctx, time.Now())
+	if stats, _ := store.ListWorkflowStats(ctx, "wf-stats", time.Now()); len(stats) != 1 || stats[0].Runs != 4 || stats[0].Failures != 2 {
+		t.Errorf("expected the new run to be counted, got %+v", stats)
+	}
+}
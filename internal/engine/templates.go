@@ -0,0 +1,226 @@
+package engine
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+//go:embed templates/*.json
+var templateFiles embed.FS
+
+// WorkflowTemplate is a starter workflow shipped with the server, e.g. a
+// webhook posting to Slack, with the triggers that run it. Its workflow and
+// trigger configs refer to the template's parameters as {{ $params.name }},
+// which Instantiate replaces with the values given.
+type WorkflowTemplate struct {
+	ID          string              `json:"id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Category    string              `json:"category,omitempty"`
+	Parameters  []TemplateParameter `json:"parameters,omitempty"`
+	Workflow    json.RawMessage     `json:"workflow"`
+	Triggers    []TemplateTrigger   `json:"triggers,omitempty"`
+}
+
+// TemplateParameter is a value asked for when a template is instantiated.
+type TemplateParameter struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	// Default is used when the parameter isn't given; optional parameters
+	// without one are replaced with an empty string.
+	Default interface{} `json:"default,omitempty"`
+}
+
+// TemplateTrigger is a trigger created with the workflow of a template.
+type TemplateTrigger struct {
+	Type    string                 `json:"type"`
+	Config  map[string]interface{} `json:"config,omitempty"`
+	Enabled bool                   `json:"enabled"`
+}
+
+// templateParamPattern matches the parameter references of templates.
+var templateParamPattern = regexp.MustCompile(`\{\{\s*\$params\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+var (
+	templatesOnce sync.Once
+	templates     []*WorkflowTemplate
+	templatesErr  error
+)
+
+// Templates returns the shipped workflow templates, sorted by ID.
+func Templates() ([]*WorkflowTemplate, error) {
+	templatesOnce.Do(func() {
+		templates, templatesErr = loadTemplates(templateFiles)
+	})
+	return templates, templatesErr
+}
+
+// TemplateByID returns the shipped template with the given ID.
+func TemplateByID(id string) (*WorkflowTemplate, bool) {
+	all, _ := Templates()
+	for _, t := range all {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func loadTemplates(fsys fs.FS) ([]*WorkflowTemplate, error) {
+	paths, err := fs.Glob(fsys, "templates/*.json")
+	if err != nil {
+		return nil, err
+	}
+	var list []*WorkflowTemplate
+	for _, path := range paths {
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return nil, err
+		}
+		var t WorkflowTemplate
+		if err := json.Unmarshal(data, &t); err != nil {
+			return nil, fmt.Errorf("invalid template %s: %w", path, err)
+		}
+		list = append(list, &t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// Instantiate returns the workflow and triggers of the template with its
+// parameters replaced by params, or their defaults. A reference making up a
+// whole string is replaced by the value as is, so parameters may be numbers,
+// lists or objects; within a longer string it is formatted into it. Missing
+// required parameters and unknown ones are reported as *FieldError of
+// "parameters.<name>", joined with errors.Join.
+func (t *WorkflowTemplate) Instantiate(params map[string]interface{}) (*Workflow, []TemplateTrigger, error) {
+	values := make(map[string]interface{}, len(t.Parameters))
+	var errs []error
+	for _, p := range t.Parameters {
+		value, ok := params[p.Name]
+		switch {
+		case ok && value != nil && value != "":
+			values[p.Name] = value
+		case p.Required:
+			errs = append(errs, fieldErrorf("parameters."+p.Name, "is required"))
+		case p.Default != nil:
+			values[p.Name] = p.Default
+		default:
+			values[p.Name] = ""
+		}
+	}
+	var unknown []string
+	for name := range params {
+		if !t.hasParameter(name) {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		errs = append(errs, fieldErrorf("parameters."+name, "is not a parameter of template %s", t.ID))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, nil, err
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(t.Workflow, &doc); err != nil {
+		return nil, nil, fmt.Errorf("invalid template %s: %w", t.ID, err)
+	}
+	doc, err := substituteParams(doc, values)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid template %s: %w", t.ID, err)
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	var wf Workflow
+	if err := json.Unmarshal(raw, &wf); err != nil {
+		return nil, nil, fmt.Errorf("invalid template %s: %w", t.ID, err)
+	}
+
+	triggers := make([]TemplateTrigger, len(t.Triggers))
+	for i, tr := range t.Triggers {
+		config, err := substituteParams(map[string]interface{}(tr.Config), values)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid template %s: %w", t.ID, err)
+		}
+		triggers[i] = TemplateTrigger{Type: tr.Type, Enabled: tr.Enabled}
+		if config != nil {
+			triggers[i].Config = config.(map[string]interface{})
+		}
+	}
+	return &wf, triggers, nil
+}
+
+func (t *WorkflowTemplate) hasParameter(name string) bool {
+	for _, p := range t.Parameters {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// substituteParams returns a copy of v, a decoded JSON value, with the
+// parameter references of its strings replaced by values.
+func substituteParams(v interface{}, values map[string]interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		if m := templateParamPattern.FindStringSubmatch(v); m != nil && m[0] == v {
+			value, ok := values[m[1]]
+			if !ok {
+				return nil, fmt.Errorf("unknown parameter %q", m[1])
+			}
+			return value, nil
+		}
+		var err error
+		s := templateParamPattern.ReplaceAllStringFunc(v, func(ref string) string {
+			name := templateParamPattern.FindStringSubmatch(ref)[1]
+			value, ok := values[name]
+			if !ok {
+				err = fmt.Errorf("unknown parameter %q", name)
+				return ref
+			}
+			if s, isString := value.(string); isString {
+				return s
+			}
+			encoded, _ := json.Marshal(value)
+			return string(encoded)
+		})
+		return s, err
+	case map[string]interface{}:
+		if v == nil {
+			return nil, nil
+		}
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			substituted, err := substituteParams(item, values)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = substituted
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			substituted, err := substituteParams(item, values)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = substituted
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,74 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+func TestTemplates(t *testing.T) {
+	templates, err := engine.Templates()
+	if err != nil {
+		t.Fatalf("failed to load templates: %v", err)
+	}
+	if len(templates) < 2 {
+		t.Fatalf("expected the shipped templates, got %d", len(templates))
+	}
+
+	// Every template instantiates into a valid workflow and triggers
+	for _, tmpl := range templates {
+		t.Run(tmpl.ID, func(t *testing.T) {
+			params := map[string]interface{}{}
+			for _, p := range tmpl.Parameters {
+				if p.Required {
+					params[p.Name] = "https://example.com/" + p.Name
+				}
+			}
+			wf, triggers, err := tmpl.Instantiate(params)
+			if err != nil {
+				t.Fatalf("failed to instantiate: %v", err)
+			}
+			if err := wf.Validate(); err != nil {
+				t.Errorf("invalid workflow: %v", err)
+			}
+			if err := wf.ValidateDefinition(engine.BuiltinConfigSchema); err != nil {
+				t.Errorf("invalid workflow: %v", err)
+			}
+			for _, tr := range triggers {
+				if err := engine.ValidateTriggerConfig(engine.TriggerType(tr.Type), tr.Config); err != nil {
+					t.Errorf("invalid %s trigger: %v", tr.Type, err)
+				}
+			}
+		})
+	}
+}
+
+func TestTemplateInstantiate(t *testing.T) {
+	tmpl, ok := engine.TemplateByID("cron-http-sync")
+	if !ok {
+		t.Fatal("expected the cron-http-sync template")
+	}
+
+	wf, triggers, err := tmpl.Instantiate(map[string]interface{}{
+		"source_url": "https://source.example.com/items",
+		"target_url": "https://target.example.com/import",
+	})
+	if err != nil {
+		t.Fatalf("failed to instantiate: %v", err)
+	}
+	if got := wf.Nodes["fetch"].Config["url"]; got != "https://source.example.com/items" {
+		t.Errorf("expected the source URL to be substituted, got %v", got)
+	}
+	// Run-time references are left for the execution
+	if got := wf.Nodes["push"].Config["body"]; got != "{{ $node.fetch.data.data }}" {
+		t.Errorf("expected the node reference to be kept, got %v", got)
+	}
+	if len(triggers) != 1 || triggers[0].Config["schedule"] != "@hourly" {
+		t.Errorf("expected a cron trigger with the default schedule, got %+v", triggers)
+	}
+
+	_, _, err = tmpl.Instantiate(map[string]interface{}{"source_url": "https://source.example.com", "colour": "blue"})
+	if got := fields(t, err); len(got) != 2 || got[0] != "parameters.colour" || got[1] != "parameters.target_url" {
+		t.Errorf("expected the unknown and missing parameters to be reported, got %v", got)
+	}
+}
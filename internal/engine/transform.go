@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	jsonata "github.com/blues/jsonata-go"
+	"github.com/jmespath/go-jmespath"
+)
+
+// executeTransform runs a std/transform node in-process. It applies the config's
+// operations in order to config.input (or the node input), like the Bun block:
+//
+//	{"type": "pick", "fields": ["id", "name"]}
+//	{"type": "rename", "mapping": {"id": "userId"}}
+//	{"type": "jmespath", "expression": "items[?active].name"}
+//	{"type": "jsonata", "expression": "$sum(items.price)"}
+//
+// The JavaScript "map" and "jsonpath" operations return errNeedsBun so the
+// whole node runs in the Bun block instead. The Bun block has no "jmespath" or
+// "jsonata" operation, so a node can't mix them with JavaScript ones.
+func executeTransform(input any) (any, error) {
+	payload, _ := input.(map[string]interface{})
+	if payload["config"] == nil {
+		return nil, fmt.Errorf("transform: missing required config")
+	}
+	// Configs built in Go hold typed slices ([]string, []map[string]interface{});
+	// decode them as JSON configs are
+	var config map[string]interface{}
+	raw, err := json.Marshal(payload["config"])
+	if err != nil {
+		return nil, fmt.Errorf("transform: invalid config: %w", err)
+	}
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return nil, fmt.Errorf("transform: config must be an object")
+	}
+	operations, ok := config["operations"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("transform: config 'operations' must be an array")
+	}
+	if len(operations) == 0 {
+		return nil, fmt.Errorf("transform: at least one operation is required")
+	}
+
+	// Check every operation up front so a JavaScript one falls back before any work is done
+	native, js := -1, -1 // First operations only this runner and only the Bun block have
+	for i, raw := range operations {
+		op, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("transform: operation %d must be an object", i)
+		}
+		switch op["type"] {
+		case "pick", "rename":
+		case "jmespath", "jsonata":
+			if native < 0 {
+				native = i
+			}
+		case "map", "jsonpath":
+			if js < 0 {
+				js = i
+			}
+		case nil:
+			return nil, fmt.Errorf("transform: operation %d must have a 'type' field", i)
+		default:
+			return nil, fmt.Errorf("transform: unknown operation type: %v", op["type"])
+		}
+	}
+	if native >= 0 && js >= 0 {
+		return nil, fmt.Errorf("transform: operation %d (%v) can't be combined with JavaScript operation %d (%v) in one node; split them into two transform nodes",
+			native, operations[native].(map[string]interface{})["type"], js, operations[js].(map[string]interface{})["type"])
+	}
+	if js >= 0 {
+		return nil, fmt.Errorf("%w: operation %d is %q", errNeedsBun, js, operations[js].(map[string]interface{})["type"])
+	}
+
+	data, hasInput := config["input"]
+	if !hasInput {
+		data = payload["input"]
+		if data == nil {
+			data = map[string]interface{}{}
+		}
+	}
+
+	for i, raw := range operations {
+		if data, err = applyTransformOperation(raw.(map[string]interface{}), data); err != nil {
+			return nil, fmt.Errorf("transform: operation %d (%v): %w", i, raw.(map[string]interface{})["type"], err)
+		}
+	}
+
+	return map[string]interface{}{
+		"data": map[string]interface{}{
+			"data":              data,
+			"operationsApplied": len(operations),
+		},
+		"port": "default",
+	}, nil
+}
+
+func applyTransformOperation(op map[string]interface{}, data interface{}) (interface{}, error) {
+	switch op["type"] {
+	case "pick":
+		fields, ok := op["fields"].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("'pick' operation requires 'fields' array")
+		}
+		obj, ok := data.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("'pick' operation requires an object")
+		}
+		result := make(map[string]interface{}, len(fields))
+		for _, f := range fields {
+			name, _ := f.(string)
+			if value, exists := obj[name]; exists {
+				result[name] = value
+			}
+		}
+		return result, nil
+
+	case "rename":
+		mapping, ok := op["mapping"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("'rename' operation requires 'mapping' object")
+		}
+		obj, ok := data.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("'rename' operation requires an object")
+		}
+		result := make(map[string]interface{}, len(obj))
+		for key, value := range obj {
+			if newKey, ok := mapping[key].(string); ok && newKey != "" {
+				key = newKey
+			}
+			result[key] = value
+		}
+		return result, nil
+
+	case "jmespath":
+		expression, ok := op["expression"].(string)
+		if !ok {
+			return nil, fmt.Errorf("'jmespath' operation requires 'expression' string")
+		}
+		return jmespath.Search(expression, data)
+
+	case "jsonata":
+		expression, ok := op["expression"].(string)
+		if !ok {
+			return nil, fmt.Errorf("'jsonata' operation requires 'expression' string")
+		}
+		compiled, err := jsonata.Compile(expression)
+		if err != nil {
+			return nil, err
+		}
+		result, err := compiled.Eval(data)
+		if errors.Is(err, jsonata.ErrUndefined) {
+			// No match is an empty result, not a failure
+			return nil, nil
+		}
+		return result, err
+	}
+	return nil, fmt.Errorf("unknown operation type: %v", op["type"])
+}
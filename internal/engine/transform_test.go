@@ -0,0 +1,180 @@
+package engine_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// TestBunRunner_ExecuteNode_NativeTransform verifies that std/transform runs
+// in-process for pick, rename, JMESPath and JSONata operations.
+func TestBunRunner_ExecuteNode_NativeTransform(t *testing.T) {
+	runner := engine.NewBunRunner(t.TempDir())
+	runner.RuntimePath = "/nonexistent/bun" // any process start would fail
+
+	order := map[string]interface{}{
+		"id":       "o-1",
+		"customer": "Ada",
+		"internal": true,
+		"items": []interface{}{
+			map[string]interface{}{"sku": "a", "price": 2.5, "active": true},
+			map[string]interface{}{"sku": "b", "price": 4.0, "active": false},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		operations []interface{}
+		want       interface{}
+	}{
+		{
+			name: "pick then rename",
+			operations: []interface{}{
+				map[string]interface{}{"type": "pick", "fields": []interface{}{"id", "customer"}},
+				map[string]interface{}{"type": "rename", "mapping": map[string]interface{}{"id": "orderId"}},
+			},
+			want: map[string]interface{}{"orderId": "o-1", "customer": "Ada"},
+		},
+		{
+			name: "jmespath filter",
+			operations: []interface{}{
+				map[string]interface{}{"type": "jmespath", "expression": "items[?active].sku"},
+			},
+			want: []interface{}{"a"},
+		},
+		{
+			name: "jsonata aggregate",
+			operations: []interface{}{
+				map[string]interface{}{"type": "jsonata", "expression": "$sum(items.price)"},
+			},
+			want: 6.5,
+		},
+		{
+			name: "jsonata no match",
+			operations: []interface{}{
+				map[string]interface{}{"type": "jsonata", "expression": "missing"},
+			},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &engine.Node{ID: "tf", Type: engine.NodeTypeTransform}
+			input := map[string]interface{}{
+				"config": map[string]interface{}{"input": order, "operations": tt.operations},
+			}
+			raw, err := runner.ExecuteNode(context.Background(), node, input)
+			if err != nil {
+				t.Fatalf("ExecuteNode failed: %v", err)
+			}
+			out := raw.(map[string]interface{})
+			data := out["data"].(map[string]interface{})
+			if !reflect.DeepEqual(data["data"], tt.want) {
+				t.Errorf("expected %#v, got %#v", tt.want, data["data"])
+			}
+			if data["operationsApplied"] != len(tt.operations) {
+				t.Errorf("expected %d operations applied, got %v", len(tt.operations), data["operationsApplied"])
+			}
+		})
+	}
+
+	for name, operations := range map[string]interface{}{
+		"no operations":     []interface{}{},
+		"unknown operation": []interface{}{map[string]interface{}{"type": "explode"}},
+		"invalid jmespath":  []interface{}{map[string]interface{}{"type": "jmespath", "expression": "items[?"}},
+		"pick on non-object": []interface{}{
+			map[string]interface{}{"type": "jmespath", "expression": "items"},
+			map[string]interface{}{"type": "pick", "fields": []interface{}{"sku"}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			node := &engine.Node{ID: "tf", Type: engine.NodeTypeTransform}
+			input := map[string]interface{}{
+				"config": map[string]interface{}{"input": order, "operations": operations},
+			}
+			if _, err := runner.ExecuteNode(context.Background(), node, input); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+// TestBunRunner_ExecuteNode_TransformGoConfig verifies that configs built in Go,
+// with typed slices rather than decoded JSON, run in-process too.
+func TestBunRunner_ExecuteNode_TransformGoConfig(t *testing.T) {
+	runner := engine.NewBunRunner(t.TempDir())
+	runner.RuntimePath = "/nonexistent/bun"
+
+	node := &engine.Node{ID: "tf", Type: engine.NodeTypeTransform}
+	input := map[string]interface{}{
+		"config": map[string]interface{}{
+			"input": map[string]interface{}{"id": "o-1", "internal": true},
+			"operations": []map[string]interface{}{
+				{"type": "pick", "fields": []string{"id"}},
+				{"type": "rename", "mapping": map[string]string{"id": "orderId"}},
+			},
+		},
+	}
+	raw, err := runner.ExecuteNode(context.Background(), node, input)
+	if err != nil {
+		t.Fatalf("ExecuteNode failed: %v", err)
+	}
+	data := raw.(map[string]interface{})["data"].(map[string]interface{})
+	if want := map[string]interface{}{"orderId": "o-1"}; !reflect.DeepEqual(data["data"], want) {
+		t.Errorf("expected %v, got %v", want, data["data"])
+	}
+}
+
+// TestBunRunner_ExecuteNode_TransformMixed verifies that a node mixing
+// operations only the native runner has with JavaScript ones is refused rather
+// than sent to the Bun block, which can't run it.
+func TestBunRunner_ExecuteNode_TransformMixed(t *testing.T) {
+	installFakeBun(t)
+	blocksDir := t.TempDir()
+	writeBlock(t, blocksDir, "std/transform.ts", `echo '{"data":{"data":"from bun","operationsApplied":2},"port":"default"}'`+"\n")
+
+	runner := engine.NewBunRunner(blocksDir)
+	node := &engine.Node{ID: "tf", Type: engine.NodeTypeTransform}
+	input := map[string]interface{}{
+		"config": map[string]interface{}{
+			"input": map[string]interface{}{"items": []interface{}{1, 2}},
+			"operations": []interface{}{
+				map[string]interface{}{"type": "jmespath", "expression": "items"},
+				map[string]interface{}{"type": "map", "expression": "data.length"},
+			},
+		},
+	}
+	_, err := runner.ExecuteNode(context.Background(), node, input)
+	if err == nil || !strings.Contains(err.Error(), "can't be combined with JavaScript operation 1") {
+		t.Errorf("expected the mix to be refused, got %v", err)
+	}
+}
+
+// TestBunRunner_ExecuteNode_TransformFallback verifies that JavaScript "map"
+// operations still run through the Bun block.
+func TestBunRunner_ExecuteNode_TransformFallback(t *testing.T) {
+	installFakeBun(t)
+	blocksDir := t.TempDir()
+	writeBlock(t, blocksDir, "std/transform.ts", `echo '{"data":{"data":"from bun","operationsApplied":1},"port":"default"}'`+"\n")
+
+	runner := engine.NewBunRunner(blocksDir)
+	node := &engine.Node{ID: "tf", Type: engine.NodeTypeTransform}
+	input := map[string]interface{}{
+		"config": map[string]interface{}{
+			"input":      map[string]interface{}{"n": 1},
+			"operations": []interface{}{map[string]interface{}{"type": "map", "expression": "data.n * 2"}},
+		},
+	}
+	raw, err := runner.ExecuteNode(context.Background(), node, input)
+	if err != nil {
+		t.Fatalf("ExecuteNode failed: %v", err)
+	}
+	data := raw.(map[string]interface{})["data"].(map[string]interface{})
+	if data["data"] != "from bun" {
+		t.Errorf("expected the Bun block's result, got %v", data)
+	}
+}
@@ -3,15 +3,22 @@ package engine
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os" // For os.Stat to check file existence
 	"os/exec" // For running Bun processes
 	"bufio" // For reading lines from stdout
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/conv3n/conv3n/internal/storage"
+	"github.com/conv3n/conv3n/internal/telemetry"
 	"github.com/robfig/cron/v3"
 )
 
@@ -19,10 +26,11 @@ import (
 type TriggerType string
 
 const (
-	TriggerTypeCron     TriggerType = "cron"
-	TriggerTypeInterval TriggerType = "interval"
-	TriggerTypeWebhook  TriggerType = "webhook"
-	TriggerTypeTS       TriggerType = "typescript" // New type for TypeScript-based triggers
+	TriggerTypeCron      TriggerType = "cron"
+	TriggerTypeInterval  TriggerType = "interval"
+	TriggerTypeWebhook   TriggerType = "webhook"
+	TriggerTypeTS        TriggerType = "typescript" // New type for TypeScript-based triggers
+	TriggerTypeWebSocket TriggerType = "websocket"  // Clients connect to GET /api/ws/{id}
 )
 
 // Trigger represents a workflow trigger configuration
@@ -48,10 +56,15 @@ type TriggerRunner interface {
 // ManagerForRunner defines the interface that runners use to interact with the trigger manager.
 // This is used to break the circular dependency for testing purposes.
 type ManagerForRunner interface {
-	Fire(ctx context.Context, triggerID string, payload map[string]interface{}) error
+	FireOnce(ctx context.Context, triggerID, key string, payload map[string]interface{}) (executionID string, duplicate bool, err error)
 	GetTrigger(triggerID string) (TriggerRunner, bool)
 	Register(trigger TriggerRunner) error
 	Unregister(triggerID string) error
+	Events() *EventBus
+	// ReportCrash is called by a runner whose trigger failed on its own
+	ReportCrash(triggerID string, reason string)
+	// ReportStopped is called by a runner whose trigger ended cleanly on its own
+	ReportStopped(triggerID string)
 }
 
 // TriggerManager manages all active triggers
@@ -60,8 +73,20 @@ type TriggerManager struct {
 	blocksDir  string
 	registry   *ExecutionRegistry
 	triggers   map[string]TriggerRunner
+	routes     map[string]string // Webhook path -> trigger ID, see addRoutes
 	workerPool *WorkerPool
+	events     *EventBus
+	delays     *DelayScheduler
+	quotas     *Quotas
 	mu         sync.RWMutex
+
+	// runs counts the runs of each sampled trigger (see sampledOut)
+	sampleMu sync.Mutex
+	runs     map[string]int
+
+	// maintenance is set while the server is in maintenance (see EnterMaintenance)
+	maintMu     sync.Mutex
+	maintenance *MaintenanceState
 }
 
 // NewTriggerManager creates a new trigger manager
@@ -71,10 +96,39 @@ func NewTriggerManager(store storage.Storage, blocksDir string, registry *Execut
 		blocksDir:  blocksDir,
 		registry:   registry,
 		triggers:   make(map[string]TriggerRunner),
+		routes:     make(map[string]string),
 		workerPool: workerPool,
+		runs:       make(map[string]int),
 	}
 }
 
+// SetEventBus makes the manager and the workflows it runs publish events to bus.
+func (tm *TriggerManager) SetEventBus(bus *EventBus) {
+	tm.events = bus
+}
+
+// SetDelayScheduler makes triggered workflows suspend in long delay nodes and be
+// resumed by s, instead of holding a worker for the whole delay.
+func (tm *TriggerManager) SetDelayScheduler(s *DelayScheduler) {
+	tm.delays = s
+}
+
+// SetQuotas makes the manager skip the runs of workflows over their quota in q;
+// they are recorded as skipped in the trigger's history.
+func (tm *TriggerManager) SetQuotas(q *Quotas) {
+	tm.quotas = q
+}
+
+// Quotas returns the quotas enforced by the manager (may be nil).
+func (tm *TriggerManager) Quotas() *Quotas {
+	return tm.quotas
+}
+
+// Events returns the event bus used by the manager (may be nil).
+func (tm *TriggerManager) Events() *EventBus {
+	return tm.events
+}
+
 // LoadTriggers loads enabled triggers from storage and starts them
 func (tm *TriggerManager) LoadTriggers(ctx context.Context) error {
 	triggers, err := tm.Store.ListAllTriggers(ctx)
@@ -88,17 +142,18 @@ func (tm *TriggerManager) LoadTriggers(ctx context.Context) error {
 		// Parse config
 		var config map[string]interface{}
 		if err := json.Unmarshal(t.Config, &config); err != nil {
-			log.Printf("Error parsing config for trigger %s: %v", t.ID, err)
+			tm.loadFailed(t.ID, fmt.Sprintf("failed to parse config: %v", err))
 			continue
 		}
 
 		var runner TriggerRunner
+		var catchUp func() // fires missed cron windows once the trigger is running
 
 		// Check if it's a TypeScript-based trigger
 		if TriggerType(t.Type) == TriggerTypeTS {
 			filePath, ok := config["file_path"].(string)
 			if !ok || filePath == "" {
-				log.Printf("Error: TypeScript trigger %s missing 'file_path' in config", t.ID)
+				tm.loadFailed(t.ID, "missing 'file_path' in config")
 				continue
 			}
 			runner = NewTSTriggerRunner(t.ID, t.WorkflowID, filePath, config, tm)
@@ -106,39 +161,62 @@ func (tm *TriggerManager) LoadTriggers(ctx context.Context) error {
 			// Existing Go-native triggers for backward compatibility
 			switch TriggerType(t.Type) {
 			case TriggerTypeCron:
-				schedule, ok := config["schedule"].(string)
-				if !ok {
-					log.Printf("Error: cron trigger %s missing schedule", t.ID)
+				schedule, err := CronSpec(config)
+				if err != nil {
+					tm.loadFailed(t.ID, err.Error())
 					continue
 				}
 				runner = NewCronTrigger(t.ID, t.WorkflowID, schedule, tm)
+				if maxCatchUp := cronCatchUpLimit(config); maxCatchUp > 0 {
+					catchUp = func() { tm.backfillCron(ctx, t, schedule, maxCatchUp) }
+				}
 
 			case TriggerTypeInterval:
 				intervalSec, ok := config["interval"].(float64)
 				if !ok {
-					log.Printf("Error: interval trigger %s missing interval", t.ID)
+					tm.loadFailed(t.ID, "missing interval")
 					continue
 				}
 				interval := time.Duration(intervalSec) * time.Second
 				runner = NewIntervalTrigger(t.ID, t.WorkflowID, interval, tm)
 
 			case TriggerTypeWebhook:
-				runner = NewWebhookTrigger(t.ID, t.WorkflowID, tm)
+				path, err := WebhookPath(config)
+				if err != nil {
+					tm.loadFailed(t.ID, err.Error())
+					continue
+				}
+				webhook := NewWebhookTrigger(t.ID, t.WorkflowID, tm)
+				webhook.SetPath(path)
+				runner = webhook
+
+			case TriggerTypeWebSocket:
+				runner = NewWebSocketTrigger(t.ID, t.WorkflowID, tm)
 
 			default:
-				log.Printf("Warning: unsupported trigger type %s for trigger %s", t.Type, t.ID)
+				tm.loadFailed(t.ID, fmt.Sprintf("unsupported trigger type %s", t.Type))
 				continue
 			}
 		}
 
 		if err := tm.Register(runner); err != nil {
 			log.Printf("Error registering trigger %s: %v", t.ID, err)
+			continue
+		}
+		if catchUp != nil {
+			catchUp()
 		}
 	}
 
 	return nil
 }
 
+// loadFailed logs a stored trigger that LoadTriggers can't start and marks it errored.
+func (tm *TriggerManager) loadFailed(triggerID, reason string) {
+	log.Printf("Error loading trigger %s: %s", triggerID, reason)
+	tm.setRuntimeStatus(triggerID, storage.TriggerRuntimeErrored, reason)
+}
+
 // TSTriggerRunner manages a TypeScript-based trigger executed by Bun.
 type TSTriggerRunner struct {
 	id             string
@@ -151,11 +229,16 @@ type TSTriggerRunner struct {
 	stdin          *bufio.Writer
 	stdoutScanner  *bufio.Scanner
 	stopChan       chan struct{}
-	readyChan      chan error
-	requests       sync.Map // Stores channels for pending requests (event replies)
-	isReady        bool
+	nextID         atomic.Int64
+	requests       sync.Map // Pending host requests: ID -> chan *RPCMessage for the response
+	writeMu        sync.Mutex // Serializes messages written to stdin
+	isReady        atomic.Bool
+	crashed        atomic.Bool // Set when the Bun process fails without being stopped
+	exited         chan struct{} // Closed once the Bun process is reaped and exitErr set
+	exitErr        error
 	mu             sync.Mutex // Protects write access to stdin and state changes
 	cancelContext  context.CancelFunc
+	stopping       atomic.Bool // Set by Stop so the read loop can tell a shutdown from a crash
 }
 
 // NewTSTriggerRunner creates a new TypeScript trigger runner.
@@ -168,7 +251,6 @@ func NewTSTriggerRunner(id, workflowID, filePath string, config map[string]inter
 		config:      config,
 		manager:     manager,
 		stopChan:    make(chan struct{}),
-		readyChan:   make(chan error, 1), // Buffered to prevent blocking if ready before read
 	}
 }
 
@@ -189,16 +271,26 @@ func (tr *TSTriggerRunner) Start(ctx context.Context) error {
 	if _, err := os.Stat(tr.filePath); os.IsNotExist(err) {
 		return fmt.Errorf("TypeScript trigger file not found: %s", tr.filePath)
 	}
+	process, err := parseTSProcessConfig(tr.config)
+	if err != nil {
+		return fmt.Errorf("TS trigger %s: %w", tr.id, err)
+	}
+	// The script path must not depend on the working directory
+	scriptPath, err := filepath.Abs(tr.filePath)
+	if err != nil {
+		return fmt.Errorf("TS trigger %s: %w", tr.id, err)
+	}
 
 	// Create a child context for the Bun process that can be cancelled
 	processCtx, cancel := context.WithCancel(context.Background())
 	tr.cancelContext = cancel
 
-	// Command to run the TypeScript trigger using Bun
-	// We assume 'bun' is in the PATH and the script expects to be run
-	// with 'bun run <script.ts>' which internally calls `runTrigger`.
-	tr.cmd = exec.CommandContext(processCtx, "bun", "run", tr.filePath)
-	tr.cmd.Dir = "." // Run from project root, or specific triggers dir
+	// Command to run the TypeScript trigger with the configured runtime, e.g.
+	// 'bun run <script.ts>'; the script internally calls `runTrigger`.
+	rt := CurrentRuntime()
+	tr.cmd = exec.CommandContext(processCtx, rt.Executable(), rt.Args(scriptPath, process.BunArgs, ResourceLimits{})...)
+	tr.cmd.Dir = process.Dir
+	tr.cmd.Env = process.Env
 
 	// Setup stdin pipe for sending messages to the Bun process
 	stdinPipe, err := tr.cmd.StdinPipe()
@@ -224,28 +316,35 @@ func (tr *TSTriggerRunner) Start(ctx context.Context) error {
 
 	log.Printf("TS trigger %s: Bun process started (PID: %d)", tr.id, tr.cmd.Process.Pid)
 
-	// Start a goroutine to read and process messages from the Bun process's stdout
-	go tr.readStdoutLoop(processCtx)
+	// Start a goroutine to read and process messages from the Bun process's stdout;
+	// it reaps the process once the output ends
+	tr.exited = make(chan struct{})
+	go tr.readStdoutLoop(processCtx, tr.cmd, tr.exited)
 
-	// Send the initial 'start' message with config to the TS trigger
-	startMsg := map[string]interface{}{
-		"type":   "start",
-		"config": tr.config,
-	}
-	if err := tr.sendToTS(startMsg); err != nil {
+	// Negotiate the protocol and hand over the config; the trigger answers once it has started
+	initialized, err := tr.call(RPCMethodInitialize, map[string]interface{}{
+		"protocolVersions": IPCProtocolVersions,
+		"config":           tr.config,
+	})
+	if err != nil {
 		cancel()
-		return fmt.Errorf("failed to send initial start message to TS trigger %s: %w", tr.id, err)
+		return fmt.Errorf("failed to initialize TS trigger %s: %w", tr.id, err)
 	}
 
 	// Wait for the TS trigger to signal that it's ready
 	select {
-	case err := <-tr.readyChan:
+	case resp := <-initialized:
+		if resp.Error != nil {
+			cancel()
+			return fmt.Errorf("TS trigger %s reported error during startup: %w", tr.id, resp.Error)
+		}
+		version, err := negotiateProtocol(resp.Result)
 		if err != nil {
 			cancel()
-			return fmt.Errorf("TS trigger %s reported error during startup: %w", tr.id, err)
+			return fmt.Errorf("TS trigger %s: %w", tr.id, err)
 		}
-		tr.isReady = true
-		log.Printf("TS trigger %s is ready.", tr.id)
+		tr.isReady.Store(true)
+		log.Printf("TS trigger %s is ready (protocol version %d).", tr.id, version)
 		return nil
 	case <-time.After(10 * time.Second): // Timeout for startup
 		cancel()
@@ -256,6 +355,73 @@ func (tr *TSTriggerRunner) Start(ctx context.Context) error {
 	}
 }
 
+// tsTriggerBaseEnv are the server environment variables every TS trigger
+// sees; others, which may hold server secrets, must be passed through explicitly.
+var tsTriggerBaseEnv = []string{"PATH", "HOME", "TMPDIR", "LANG", "TZ"}
+
+// tsProcessConfig is how the Bun process of a TS trigger is run.
+type tsProcessConfig struct {
+	Dir     string
+	Env     []string
+	BunArgs []string
+}
+
+// parseTSProcessConfig reads the process settings of a TS trigger config:
+//
+//	{"working_dir": "/srv/triggers", "env": {"API_URL": "https://..."},
+//	 "env_passthrough": ["AWS_REGION"], "bun_args": ["--smol"]}
+//
+// working_dir defaults to the server's; env is set on top of the base
+// environment and the env_passthrough variables; bun_args go to the runtime
+// (Bun unless configured otherwise) before the script.
+func parseTSProcessConfig(config map[string]interface{}) (*tsProcessConfig, error) {
+	var raw struct {
+		WorkingDir     string            `json:"working_dir"`
+		Env            map[string]string `json:"env"`
+		EnvPassthrough []string          `json:"env_passthrough"`
+		BunArgs        []string          `json:"bun_args"`
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid process config: %w", err)
+	}
+
+	process := &tsProcessConfig{Dir: ".", BunArgs: raw.BunArgs}
+	if raw.WorkingDir != "" {
+		info, err := os.Stat(raw.WorkingDir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid working_dir: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("invalid working_dir: %s is not a directory", raw.WorkingDir)
+		}
+		process.Dir = raw.WorkingDir
+	}
+
+	env := make(map[string]string)
+	for _, name := range append(slices.Clone(tsTriggerBaseEnv), raw.EnvPassthrough...) {
+		if value, ok := os.LookupEnv(name); ok {
+			env[name] = value
+		}
+	}
+	for name, value := range raw.Env {
+		if name == "" || strings.ContainsAny(name, "=\x00") {
+			return nil, fmt.Errorf("invalid env variable name %q", name)
+		}
+		env[name] = value
+	}
+	// Env must not be nil, which would inherit the whole server environment
+	process.Env = make([]string, 0, len(env))
+	for name, value := range env {
+		process.Env = append(process.Env, name+"="+value)
+	}
+	sort.Strings(process.Env)
+	return process, nil
+}
+
 // Stop sends a kill signal to the Bun process and waits for it to exit.
 func (tr *TSTriggerRunner) Stop() error {
 	tr.mu.Lock()
@@ -266,11 +432,11 @@ func (tr *TSTriggerRunner) Stop() error {
 	}
 
 	log.Printf("TS trigger %s: Stopping Bun process (PID: %d)", tr.id, tr.cmd.Process.Pid)
+	tr.stopping.Store(true)
 
-	// Send a 'kill' message for graceful shutdown in the TS script
-	killMsg := map[string]interface{}{"type": "kill"}
-	if err := tr.sendToTS(killMsg); err != nil {
-		log.Printf("TS trigger %s: failed to send kill message: %v. The process will be terminated.", tr.id, err)
+	// Ask the TS script to shut down gracefully
+	if err := tr.notify(RPCMethodShutdown, nil); err != nil {
+		log.Printf("TS trigger %s: failed to send shutdown notification: %v. The process will be terminated.", tr.id, err)
 	}
 
 	// Cancel the context, which sends a signal to the process
@@ -278,15 +444,10 @@ func (tr *TSTriggerRunner) Stop() error {
 		tr.cancelContext()
 	}
 
-	// Wait for the process to exit, but with a timeout to prevent deadlocks.
-	done := make(chan error, 1)
-	go func() {
-		done <- tr.cmd.Wait()
-	}()
-
+	// Wait for the read loop to reap the process, but with a timeout to prevent deadlocks.
 	select {
-	case err := <-done:
-		if err != nil {
+	case <-tr.exited:
+		if err := tr.exitErr; err != nil {
 			log.Printf("TS trigger %s: Bun process exited with error: %v", tr.id, err)
 		} else {
 			log.Printf("TS trigger %s: Bun process exited successfully.", tr.id)
@@ -303,27 +464,77 @@ func (tr *TSTriggerRunner) Stop() error {
 	// Clean up resources
 	close(tr.stopChan)
 	tr.cmd = nil
+	tr.writeMu.Lock()
 	tr.stdin = nil
+	tr.writeMu.Unlock()
 	tr.stdoutScanner = nil
-	tr.isReady = false
+	tr.isReady.Store(false)
 	tr.requests = sync.Map{} // Clear any pending requests
 	return nil
 }
 
-// Invoke sends an 'invoke' message to the running TypeScript trigger.
+// Healthy reports whether the Bun process is up and has signalled readiness.
+func (tr *TSTriggerRunner) Healthy() error {
+	if tr.crashed.Load() {
+		return fmt.Errorf("bun process exited unexpectedly")
+	}
+	if !tr.isReady.Load() {
+		return fmt.Errorf("not ready")
+	}
+	return nil
+}
+
+// Invoke sends an invoke notification to the running TypeScript trigger.
 func (tr *TSTriggerRunner) Invoke(ctx context.Context, payload map[string]interface{}) error {
+	return tr.InvokeWithKey(ctx, "", payload)
+}
+
+// InvokeWithKey sends an invoke notification like Invoke, passing key, the
+// idempotency key of the delivery, on for the trigger to fire with, so that a
+// redelivery runs nothing (see TriggerManager.FireOnce).
+func (tr *TSTriggerRunner) InvokeWithKey(ctx context.Context, key string, payload map[string]interface{}) error {
 	tr.mu.Lock()
 	defer tr.mu.Unlock()
 
-	if !tr.isReady {
+	if !tr.isReady.Load() {
 		return fmt.Errorf("TS trigger %s is not ready to receive invocations", tr.id)
 	}
+	// The labels ctx carries are passed on for the trigger to fire with
+	params := map[string]interface{}{"payload": payload}
+	if l := LabelsFromContext(ctx); l.CorrelationID != "" || len(l.Labels) > 0 {
+		params["correlationId"] = l.CorrelationID
+		params["labels"] = l.Labels
+	}
+	if key != "" {
+		params["idempotencyKey"] = key
+	}
+	return tr.notify(RPCMethodInvoke, params)
+}
 
-	invokeMsg := map[string]interface{}{
-		"type":    "invoke",
-		"payload": payload,
+// call sends a request to the TS trigger; its response is delivered on the
+// returned channel by the read loop.
+func (tr *TSTriggerRunner) call(method string, params interface{}) (<-chan *RPCMessage, error) {
+	id := tr.nextID.Add(1)
+	msg, err := newRPCRequest(&id, method, params)
+	if err != nil {
+		return nil, err
+	}
+	resp := make(chan *RPCMessage, 1)
+	tr.requests.Store(id, resp)
+	if err := tr.sendToTS(msg); err != nil {
+		tr.requests.Delete(id)
+		return nil, err
 	}
-	return tr.sendToTS(invokeMsg)
+	return resp, nil
+}
+
+// notify sends a notification to the TS trigger.
+func (tr *TSTriggerRunner) notify(method string, params interface{}) error {
+	msg, err := newRPCRequest(nil, method, params)
+	if err != nil {
+		return err
+	}
+	return tr.sendToTS(msg)
 }
 
 // sendToTS sends a JSON message to the Bun process's stdin.
@@ -332,6 +543,11 @@ func (tr *TSTriggerRunner) sendToTS(msg interface{}) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal message for TS trigger %s: %w", tr.id, err)
 	}
+	tr.writeMu.Lock()
+	defer tr.writeMu.Unlock()
+	if tr.stdin == nil {
+		return fmt.Errorf("TS trigger %s is not running", tr.id)
+	}
 	if _, err := tr.stdin.Write(jsonBytes); err != nil {
 		return fmt.Errorf("failed to write JSON to TS trigger %s stdin: %w", tr.id, err)
 	}
@@ -342,78 +558,83 @@ func (tr *TSTriggerRunner) sendToTS(msg interface{}) error {
 }
 
 // readStdoutLoop continuously reads and processes messages from the Bun process's stdout.
-func (tr *TSTriggerRunner) readStdoutLoop(ctx context.Context) {
+// Once the output ends it waits for cmd to exit and closes exited.
+func (tr *TSTriggerRunner) readStdoutLoop(ctx context.Context, cmd *exec.Cmd, exited chan struct{}) {
 	for tr.stdoutScanner.Scan() {
-		line := tr.stdoutScanner.Text()
-		if line == "" {
+		line := tr.stdoutScanner.Bytes()
+		if len(line) == 0 {
 			continue
 		}
 
-		var msg map[string]interface{}
-		if err := json.Unmarshal([]byte(line), &msg); err != nil {
-			log.Printf("TS trigger %s: failed to unmarshal stdout message '%s': %v", tr.id, line, err)
-			continue
-		}
-
-		msgType, ok := msg["type"].(string)
-		if !ok {
-			log.Printf("TS trigger %s: received message with missing or invalid 'type' field: %v", tr.id, msg)
+		msg, err := ParseRPCMessage(line)
+		if err != nil {
+			// Anything else on stdout, e.g. console.log from the script, is just output
+			log.Printf("TS trigger %s: %s", tr.id, line)
 			continue
 		}
 
-		switch msgType {
-		case "status":
-			status, sOk := msg["status"].(string)
-			if !sOk {
-				log.Printf("TS trigger %s: received status message with missing or invalid 'status' field: %v", tr.id, msg)
+		switch {
+		case msg.IsResponse():
+			var id int64
+			if err := json.Unmarshal(msg.ID, &id); err != nil {
+				log.Printf("TS trigger %s: response to unknown request %s", tr.id, msg.ID)
 				continue
 			}
-			if status == "ready" {
-				tr.readyChan <- nil // Signal that the trigger is ready
-			} else if status == "error" {
-				errMsg, _ := msg["message"].(string)
-				tr.readyChan <- fmt.Errorf("startup error: %s", errMsg)
+			if resp, ok := tr.requests.LoadAndDelete(id); ok {
+				resp.(chan *RPCMessage) <- msg
 			}
 
-		case "event":
+		case msg.Method == RPCMethodFire:
 			// A TS trigger wants to fire a workflow
-			requestId, rOk := msg["requestId"].(string)
-			payload, pOk := msg["payload"].(map[string]interface{})
-			if !rOk || !pOk {
-				log.Printf("TS trigger %s: received event message with missing or invalid 'requestId' or 'payload': %v", tr.id, msg)
+			var params struct {
+				Payload        map[string]interface{} `json:"payload"`
+				IdempotencyKey string                 `json:"idempotencyKey"`
+				CorrelationID  string                 `json:"correlationId"`
+				Labels         map[string]string      `json:"labels"`
+			}
+			if err := json.Unmarshal(msg.Params, &params); err != nil || params.Payload == nil {
+				tr.respond(msg, nil, &RPCError{Code: RPCInvalidParams, Message: "fire requires an object payload"})
+				continue
+			}
+			labels := ExecutionLabels{CorrelationID: params.CorrelationID, Labels: params.Labels}
+			if err := labels.Validate(); err != nil {
+				tr.respond(msg, nil, &RPCError{Code: RPCInvalidParams, Message: err.Error()})
 				continue
 			}
 
-			// Fire the workflow and capture the result/error
-			go func(reqID string, pld map[string]interface{}) {
+			// Fire the workflow and answer with the outcome
+			go func(req *RPCMessage, pld map[string]interface{}, key string) {
 				workflowCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute) // Workflow execution timeout
 				defer cancel()
+				workflowCtx = WithLabels(workflowCtx, labels)
 
-				err := tr.manager.Fire(workflowCtx, tr.id, pld)
-
-				// Send reply back to the TS trigger
-				replyMsg := map[string]interface{}{
-					"type":      "reply",
-					"requestId": reqID,
-				}
+				executionID, duplicate, err := tr.manager.FireOnce(workflowCtx, tr.id, key, pld)
 				if err != nil {
-					replyMsg["error"] = err.Error()
-				} else {
-					// Optionally, if Fire returned a result, include it here.
-					// For now, Fire only returns error.
+					tr.respond(req, nil, &RPCError{Code: RPCFireFailed, Message: err.Error()})
+					return
 				}
-				if sendErr := tr.sendToTS(replyMsg); sendErr != nil {
-					log.Printf("TS trigger %s: failed to send reply for request %s: %v", tr.id, reqID, sendErr)
+				if duplicate {
+					tr.respond(req, map[string]interface{}{"duplicate": true, "executionId": executionID}, nil)
+					return
 				}
-			}(requestId, payload)
-
-		case "error":
-			errMsg, _ := msg["message"].(string)
-			stack, _ := msg["stack"].(string)
-			log.Printf("TS trigger %s: Error from Bun process: %s\n%s", tr.id, errMsg, stack)
+				tr.respond(req, struct{}{}, nil)
+			}(msg, params.Payload, params.IdempotencyKey)
+
+		case msg.Method == RPCMethodLog:
+			var params struct {
+				Level   string `json:"level"`
+				Message string `json:"message"`
+				Stack   string `json:"stack"`
+			}
+			json.Unmarshal(msg.Params, &params)
+			if params.Stack != "" {
+				log.Printf("TS trigger %s [%s]: %s\n%s", tr.id, params.Level, params.Message, params.Stack)
+			} else {
+				log.Printf("TS trigger %s [%s]: %s", tr.id, params.Level, params.Message)
+			}
 
 		default:
-			log.Printf("TS trigger %s: received unknown message type '%s': %v", tr.id, msgType, msg)
+			tr.respond(msg, nil, &RPCError{Code: RPCMethodNotFound, Message: "method not found: " + msg.Method})
 		}
 	}
 
@@ -423,15 +644,79 @@ func (tr *TSTriggerRunner) readStdoutLoop(ctx context.Context) {
 	}
 
 	log.Printf("TS trigger %s: stdout read loop exited.", tr.id)
+	// Nothing will answer the requests still waiting
+	tr.requests.Range(func(id, resp interface{}) bool {
+		tr.requests.Delete(id)
+		resp.(chan *RPCMessage) <- newRPCResponse(nil, nil, &RPCError{Code: RPCInternalError, Message: "process exited"})
+		return true
+	})
+	// All output has been read, so the process can be waited for
+	tr.exitErr = cmd.Wait()
+	close(exited)
 
 	// Check if the process exited unexpectedly
 	select {
 	case <-ctx.Done(): // Context was cancelled (e.g., graceful shutdown)
 		log.Printf("TS trigger %s: stdout read loop exited due to context cancellation.", tr.id)
 	default: // Process exited without explicit cancellation
-		if tr.cmd != nil && tr.cmd.ProcessState != nil && !tr.cmd.ProcessState.Exited() {
-			log.Printf("TS trigger %s: Bun process exited unexpectedly, PID: %d", tr.id, tr.cmd.Process.Pid)
+		if tr.stopping.Load() {
+			return
+		}
+		tr.isReady.Store(false)
+		// Exit status 0 is a trigger that ended by itself; only a failure
+		// (a non-zero status or a signal) is a crash
+		if tr.exitErr == nil {
+			log.Printf("TS trigger %s: Bun process exited on its own, PID: %d", tr.id, cmd.Process.Pid)
+			tr.manager.ReportStopped(tr.id)
+			return
 		}
+		tr.crashed.Store(true)
+		log.Printf("TS trigger %s: Bun process exited unexpectedly (%v), PID: %d", tr.id, tr.exitErr, cmd.Process.Pid)
+		tr.manager.ReportCrash(tr.id, "bun process exited unexpectedly: "+tr.exitErr.Error())
+	}
+}
+
+// respond answers a request from the TS trigger; notifications get no answer.
+func (tr *TSTriggerRunner) respond(req *RPCMessage, result interface{}, rpcErr *RPCError) {
+	if req.IsNotification() {
+		if rpcErr != nil {
+			log.Printf("TS trigger %s: %s notification failed: %v", tr.id, req.Method, rpcErr)
+		}
+		return
+	}
+	if err := tr.sendToTS(newRPCResponse(req.ID, result, rpcErr)); err != nil {
+		log.Printf("TS trigger %s: failed to answer request %s: %v", tr.id, req.ID, err)
+	}
+}
+
+// ReportCrash records that a trigger failed on its own: its runtime status
+// becomes errored, though it stays registered and enabled, and TriggerCrashed
+// is published.
+func (tm *TriggerManager) ReportCrash(triggerID string, reason string) {
+	tm.setRuntimeStatus(triggerID, storage.TriggerRuntimeErrored, reason)
+	tm.Events().Publish(TriggerCrashed{
+		TriggerID: triggerID,
+		Error:     reason,
+		Time:      time.Now(),
+	})
+}
+
+// ReportStopped records that a trigger ended cleanly on its own: its runtime
+// status becomes stopped, though it stays registered and enabled. It is not a
+// crash, so no event is published.
+func (tm *TriggerManager) ReportStopped(triggerID string) {
+	tm.setRuntimeStatus(triggerID, storage.TriggerRuntimeStopped, "")
+}
+
+// setRuntimeStatus persists the runtime status of a trigger, with errMsg for
+// errored ones. Failures are only logged: runners may not be stored at all.
+func (tm *TriggerManager) setRuntimeStatus(triggerID string, status storage.TriggerRuntimeStatus, errMsg string) {
+	var msg *string
+	if errMsg != "" {
+		msg = &errMsg
+	}
+	if err := tm.Store.SetTriggerRuntimeStatus(context.Background(), triggerID, status, msg); err != nil {
+		log.Printf("Warning: failed to record runtime status of trigger %s: %v", triggerID, err)
 	}
 }
 
@@ -443,13 +728,40 @@ func (tm *TriggerManager) Register(trigger TriggerRunner) error {
 	if _, exists := tm.triggers[trigger.ID()]; exists {
 		return fmt.Errorf("trigger already registered: %s", trigger.ID())
 	}
+	return tm.start(trigger)
+}
+
+// Restart replaces the registered runner of a trigger, if any, with trigger
+// and starts it; the runtime status reads restarting until it has started.
+func (tm *TriggerManager) Restart(trigger TriggerRunner) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
 
-	// Start the trigger
+	tm.setRuntimeStatus(trigger.ID(), storage.TriggerRuntimeRestarting, "")
+	if old, exists := tm.triggers[trigger.ID()]; exists {
+		if err := old.Stop(); err != nil {
+			log.Printf("Warning: failed to stop trigger %s: %v", trigger.ID(), err)
+		}
+		delete(tm.triggers, trigger.ID())
+		tm.removeRoutes(trigger.ID())
+	}
+	return tm.start(trigger)
+}
+
+// start starts and adds a trigger, routing its webhook paths to it; tm.mu must be held.
+func (tm *TriggerManager) start(trigger TriggerRunner) error {
+	if err := tm.addRoutes(trigger); err != nil {
+		tm.setRuntimeStatus(trigger.ID(), storage.TriggerRuntimeErrored, err.Error())
+		return fmt.Errorf("failed to start trigger: %w", err)
+	}
 	if err := trigger.Start(context.Background()); err != nil {
+		tm.removeRoutes(trigger.ID())
+		tm.setRuntimeStatus(trigger.ID(), storage.TriggerRuntimeErrored, err.Error())
 		return fmt.Errorf("failed to start trigger: %w", err)
 	}
 
 	tm.triggers[trigger.ID()] = trigger
+	tm.setRuntimeStatus(trigger.ID(), storage.TriggerRuntimeRunning, "")
 	log.Printf("Registered trigger: %s (type: %s)", trigger.ID(), trigger.Type())
 	return nil
 }
@@ -470,6 +782,8 @@ func (tm *TriggerManager) Unregister(triggerID string) error {
 	}
 
 	delete(tm.triggers, triggerID)
+	tm.removeRoutes(triggerID)
+	tm.setRuntimeStatus(triggerID, storage.TriggerRuntimeStopped, "")
 	log.Printf("Unregistered trigger: %s", triggerID)
 	return nil
 }
@@ -494,6 +808,36 @@ func (tm *TriggerManager) ListTriggers() []TriggerRunner {
 	return triggers
 }
 
+// TriggerStatus describes the runtime state of a registered trigger.
+type TriggerStatus struct {
+	ID      string      `json:"id"`
+	Type    TriggerType `json:"type"`
+	Healthy bool        `json:"healthy"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// Statuses reports the runtime state of every registered trigger.
+// Runners that implement Healthy() error (e.g. TS triggers backed by a Bun process)
+// are asked directly; passive Go-native triggers are always healthy.
+func (tm *TriggerManager) Statuses() []TriggerStatus {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	statuses := make([]TriggerStatus, 0, len(tm.triggers))
+	for _, t := range tm.triggers {
+		status := TriggerStatus{ID: t.ID(), Type: t.Type(), Healthy: true}
+		if hc, ok := t.(interface{ Healthy() error }); ok {
+			if err := hc.Healthy(); err != nil {
+				status.Healthy = false
+				status.Error = err.Error()
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].ID < statuses[j].ID })
+	return statuses
+}
+
 // StopAll stops all triggers (for graceful shutdown)
 func (tm *TriggerManager) StopAll() {
 	tm.mu.Lock()
@@ -503,8 +847,10 @@ func (tm *TriggerManager) StopAll() {
 		if err := trigger.Stop(); err != nil {
 			log.Printf("Warning: failed to stop trigger %s: %v", id, err)
 		}
+		tm.setRuntimeStatus(id, storage.TriggerRuntimeStopped, "")
 	}
 	tm.triggers = make(map[string]TriggerRunner)
+	tm.routes = make(map[string]string)
 	log.Println("Stopped all triggers")
 }
 
@@ -515,92 +861,320 @@ func (tm *TriggerManager) ExecuteWorkflow(ctx context.Context, workflowID, trigg
 
 // Fire executes a workflow triggered by a trigger with optional payload
 func (tm *TriggerManager) Fire(ctx context.Context, triggerID string, payload map[string]interface{}) error {
+	return tm.fire(ctx, triggerID, payload, triggerRun{})
+}
+
+// FireOnce runs the workflows for triggerID like Fire, unless it was fired with
+// key before: a retried webhook delivery or queue message then maps to the
+// execution of the first one instead of running again. It reports whether the
+// call was such a duplicate and, if so, the ID of the trigger's own execution
+// for key, empty while that one is still starting. An empty key always fires.
+func (tm *TriggerManager) FireOnce(ctx context.Context, triggerID, key string, payload map[string]interface{}) (executionID string, duplicate bool, err error) {
+	if key == "" {
+		return "", false, tm.Fire(ctx, triggerID, payload)
+	}
+	executionID, claimed, err := tm.Store.ClaimIdempotencyKey(ctx, triggerID, key)
+	if err != nil {
+		return "", false, err
+	}
+	if !claimed {
+		log.Printf("Trigger %s: delivery %q already fired, not running it again", triggerID, key)
+		return executionID, true, nil
+	}
+	if err := tm.fire(ctx, triggerID, payload, triggerRun{idempotencyKey: key}); err != nil {
+		tm.releaseIdempotencyKey(ctx, triggerID, key)
+		return "", false, err
+	}
+	return "", false, nil
+}
+
+// releaseIdempotencyKey lets a delivery with key run again after the first one
+// failed before its execution was created.
+func (tm *TriggerManager) releaseIdempotencyKey(ctx context.Context, triggerID, key string) {
+	saveCtx, cancel := storage.Detach(ctx)
+	defer cancel()
+	if err := tm.Store.ReleaseIdempotencyKey(saveCtx, triggerID, key); err != nil {
+		log.Printf("Warning: failed to release idempotency key of trigger %s: %v", triggerID, err)
+	}
+}
+
+// fire runs the workflows for triggerID on the worker pool without waiting for
+// them; run holds the options of the trigger's own run, and its backfill flag
+// also tags the runs of bound workflows. While the server is in maintenance
+// the fire is held instead (see holdFire).
+func (tm *TriggerManager) fire(ctx context.Context, triggerID string, payload map[string]interface{}, run triggerRun) error {
+	if held, err := tm.holdFire(ctx, triggerID, payload, run, true); held {
+		return err
+	}
+	if err := tm.fireBindings(ctx, triggerID, payload, run.backfill, run.idempotencyKey); err != nil {
+		return err
+	}
 	// Use WorkerPool to limit concurrency
 	return tm.workerPool.Execute(ctx, func() error {
-		// Record trigger execution start
-		triggerExec := &storage.TriggerExecution{
-			ID:        fmt.Sprintf("texec_%d", time.Now().UnixNano()),
-			TriggerID: triggerID,
-			FiredAt:   time.Now(),
-			Status:    "running",
-			Payload:   nil, // Will be updated if payload exists
-		}
+		_, err := tm.runTriggered(ctx, triggerID, payload, run)
+		return err
+	})
+}
 
-		if payload != nil {
-			payloadBytes, _ := json.Marshal(payload)
-			triggerExec.Payload = payloadBytes
+// FireSync runs the workflows for triggerID like Fire but waits for the
+// trigger's own workflow to finish, returning the node results of its
+// execution, also when it failed partway.
+func (tm *TriggerManager) FireSync(ctx context.Context, triggerID string, payload map[string]interface{}) (map[string]interface{}, error) {
+	// No results to wait for from a queued fire, so it is always skipped
+	if held, err := tm.holdFire(ctx, triggerID, payload, triggerRun{}, false); held {
+		return nil, err
+	}
+	if err := tm.fireBindings(ctx, triggerID, payload, false, ""); err != nil {
+		return nil, err
+	}
+	var results map[string]interface{}
+	err := tm.workerPool.ExecuteSync(ctx, func() error {
+		execCtx, err := tm.runTriggered(ctx, triggerID, payload, triggerRun{})
+		if execCtx != nil {
+			results = execCtx.Results()
 		}
+		return err
+	})
+	return results, err
+}
 
-		// Get workflow definition
-		// First get the trigger to find the workflow ID
-		triggerRunner, exists := tm.GetTrigger(triggerID)
-		if !exists {
-			return fmt.Errorf("trigger not found: %s", triggerID)
-		}
-		// Use triggerRunner to avoid unused variable error (though we don't strictly need it if we fetch from DB)
-		_ = triggerRunner
-
-		// We need to access the workflow ID from the runner.
-		// Since TriggerRunner interface doesn't expose WorkflowID directly (it should),
-		// we might need to fetch the trigger from DB or cast the runner.
-		// For now, let's fetch from DB to be safe and get fresh config.
-		trigger, err := tm.Store.GetTrigger(ctx, triggerID)
-		if err != nil {
-			triggerExec.Status = "failed"
-			msg := err.Error()
-			triggerExec.Error = &msg
-			tm.Store.CreateTriggerExecution(ctx, triggerExec)
-			return fmt.Errorf("failed to get trigger: %w", err)
+// FireTest runs the trigger's own workflow in test mode and waits for it. The
+// execution is flagged as a test in history, no trigger execution is recorded
+// and the run's events go to events instead of the manager's bus. The trigger
+// doesn't need to be enabled. Returns the node results of the execution.
+func (tm *TriggerManager) FireTest(ctx context.Context, triggerID string, payload map[string]interface{}, events *EventBus) (map[string]interface{}, error) {
+	var results map[string]interface{}
+	err := tm.workerPool.ExecuteSync(ctx, func() error {
+		execCtx, err := tm.runTriggered(ctx, triggerID, payload, triggerRun{test: true, events: events})
+		if execCtx != nil {
+			results = execCtx.Results()
 		}
+		return err
+	})
+	return results, err
+}
 
-		workflow, err := tm.Store.GetWorkflow(ctx, trigger.WorkflowID)
-		if err != nil {
-			triggerExec.Status = "failed"
-			msg := err.Error()
-			triggerExec.Error = &msg
-			tm.Store.CreateTriggerExecution(ctx, triggerExec)
-			return fmt.Errorf("failed to get workflow: %w", err)
+// triggerRun holds the options of one workflow run for a trigger.
+type triggerRun struct {
+	workflowID string    // Bound workflow to run; empty runs the trigger's own
+	backfill   bool      // Tags the run in trigger execution history
+	test       bool      // Runs in test mode, see FireTest
+	events     *EventBus // Overrides the manager's bus when set
+
+	idempotencyKey string // Key claimed for the run, see FireOnce
+}
+
+// runTriggered runs a workflow for triggerID and records the trigger execution.
+// It returns the execution context once the run has started.
+func (tm *TriggerManager) runTriggered(ctx context.Context, triggerID string, payload map[string]interface{}, run triggerRun) (_ *ExecutionContext, err error) {
+	ctx, span := telemetry.Start(ctx, "trigger.fire", telemetry.SpanKindInternal)
+	span.SetAttr("trigger.id", triggerID)
+	started := false
+	defer func() {
+		if err != nil && !started && run.idempotencyKey != "" {
+			tm.releaseIdempotencyKey(ctx, triggerID, run.idempotencyKey)
 		}
+		span.RecordError(err)
+		span.End()
+	}()
 
-		// Parse workflow
-		var wf Workflow
-		if err := json.Unmarshal(workflow.Definition, &wf); err != nil {
-			triggerExec.Status = "failed"
-			msg := err.Error()
-			triggerExec.Error = &msg
-			tm.Store.CreateTriggerExecution(ctx, triggerExec)
-			return fmt.Errorf("failed to parse workflow: %w", err)
+	// Record trigger execution start
+	triggerExec := &storage.TriggerExecution{
+		ID:         storage.NewID(),
+		TriggerID:  triggerID,
+		WorkflowID: run.workflowID,
+		FiredAt:    time.Now(),
+		Status:     "running",
+		Payload:    nil, // Will be updated if payload exists
+		Backfill:   run.backfill,
+	}
+	// Test runs stay out of the trigger's execution history. The record is
+	// written after the run, which may have outlived ctx
+	record := func() {
+		if !run.test {
+			saveCtx, cancel := storage.Detach(ctx)
+			defer cancel()
+			if err := tm.Store.CreateTriggerExecution(saveCtx, triggerExec); err != nil {
+				log.Printf("Warning: failed to record trigger execution: %v", err)
+			}
 		}
+	}
+	events := tm.events
+	if run.events != nil {
+		events = run.events
+	}
+
+	if payload != nil {
+		payloadBytes, _ := json.Marshal(payload)
+		triggerExec.Payload = payloadBytes
+	}
+
+	// Get workflow definition
+	// First get the trigger to find the workflow ID
+	triggerRunner, exists := tm.GetTrigger(triggerID)
+	if !exists && !run.test {
+		return nil, fmt.Errorf("trigger not found: %s", triggerID)
+	}
+	// Use triggerRunner to avoid unused variable error (though we don't strictly need it if we fetch from DB)
+	_ = triggerRunner
+
+	// We need to access the workflow ID from the runner.
+	// Since TriggerRunner interface doesn't expose WorkflowID directly (it should),
+	// we might need to fetch the trigger from DB or cast the runner.
+	// For now, let's fetch from DB to be safe and get fresh config.
+	trigger, err := tm.Store.GetTrigger(ctx, triggerID)
+	if err != nil {
+		triggerExec.Status = "failed"
+		msg := err.Error()
+		triggerExec.Error = &msg
+		record()
+		return nil, fmt.Errorf("failed to get trigger: %w", err)
+	}
+
+	workflowID := run.workflowID
+	if workflowID == "" {
+		workflowID = trigger.WorkflowID
+		triggerExec.WorkflowID = workflowID
+	}
+
+	span.SetAttr("trigger.type", trigger.Type)
+	span.SetAttr("workflow.id", workflowID)
+	events.Publish(TriggerFired{TriggerID: triggerID, WorkflowID: workflowID, Time: time.Now()})
+
+	workflow, err := tm.Store.GetWorkflow(ctx, workflowID)
+	if err != nil {
+		triggerExec.Status = "failed"
+		msg := err.Error()
+		triggerExec.Error = &msg
+		record()
+		return nil, fmt.Errorf("failed to get workflow: %w", err)
+	}
 
-		// Create execution context
-		execCtx := NewExecutionContext(wf.ID)
-		// Inject trigger payload into context if available
-		if payload != nil {
-			execCtx.TriggerData = payload
+	// Parse workflow
+	var wf Workflow
+	if err := json.Unmarshal(workflow.Definition, &wf); err != nil {
+		triggerExec.Status = "failed"
+		msg := err.Error()
+		triggerExec.Error = &msg
+		record()
+		return nil, fmt.Errorf("failed to parse workflow: %w", err)
+	}
+	// A share of the fires runs the workflow's canary version, if it has one
+	canary := false
+	if !run.test {
+		if version, err := pickCanary(ctx, tm.Store, workflowID); err != nil {
+			log.Printf("Warning: failed to get canary of workflow %s: %v", workflowID, err)
+		} else if version != nil {
+			wf, canary = *version, true
 		}
+	}
 
-		runner := NewWorkflowRunner(execCtx, tm.blocksDir, tm.Store, tm.registry)
+	// Create execution context
+	execCtx := NewExecutionContext(wf.ID)
+	execCtx.Test = run.test
+	execCtx.Canary = canary
+	execCtx.TriggerID = triggerID
+	execCtx.IdempotencyKey = run.idempotencyKey
+	// Inject trigger payload into context if available
+	if payload != nil {
+		execCtx.TriggerData = payload
+	}
+	// A trigger can pin the environment its runs use: {"environment": "prod"}
+	var triggerConfig map[string]interface{}
+	if err := json.Unmarshal(trigger.Config, &triggerConfig); err == nil {
+		execCtx.Environment, _ = triggerConfig["environment"].(string)
+	}
+
+	runner := NewWorkflowRunner(execCtx, tm.blocksDir, tm.Store, tm.registry)
+	runner.SetEventBus(events)
+	runner.SetDelayScheduler(tm.delays)
+	runner.SetQuotas(tm.quotas)
 
-		// Execute workflow with timeout
-		execContext, cancel := context.WithTimeout(ctx, 5*time.Minute)
-		defer cancel()
+	// Execute workflow with timeout; the runner applies the workflow's own settings.timeout
+	execContext, cancel := context.WithCancel(ctx)
+	if timeout, _ := wf.Timeout(); timeout == 0 {
+		execContext, cancel = context.WithTimeout(ctx, 5*time.Minute)
+	}
+	defer cancel()
 
-		log.Printf("Executing workflow %s triggered by %s", trigger.WorkflowID, triggerID)
+	log.Printf("Executing workflow %s triggered by %s", workflowID, triggerID)
 
-		if err := runner.Run(execContext, wf); err != nil {
-			triggerExec.Status = "failed"
-			msg := err.Error()
-			triggerExec.Error = &msg
-			tm.Store.CreateTriggerExecution(ctx, triggerExec)
-			return fmt.Errorf("workflow execution failed: %w", err)
+	// Create the execution first so that it is recorded against the key
+	execID, err := runner.CreateExecution(execContext, wf.ID)
+	if err != nil {
+		triggerExec.Status = "failed"
+		// A workflow over its quota is blocked rather than failing
+		var quotaErr *QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			triggerExec.Status = "skipped"
 		}
+		msg := err.Error()
+		triggerExec.Error = &msg
+		record()
+		return nil, err
+	}
+	started = true
+	triggerExec.ExecutionID = &execID
+
+	if err := runner.Run(execContext, wf); err != nil {
+		triggerExec.Status = "failed"
+		msg := err.Error()
+		triggerExec.Error = &msg
+		record()
+		return execCtx, fmt.Errorf("workflow execution failed: %w", err)
+	}
 
-		triggerExec.Status = "success"
-		tm.Store.CreateTriggerExecution(ctx, triggerExec)
+	triggerExec.Status = "success"
+	if !run.test && run.idempotencyKey == "" && tm.sampledOut(triggerID, triggerConfig) && tm.dropSampledOut(ctx, triggerID, execID) {
+		// Only the fire itself is kept in the trigger's history
+		triggerExec.ExecutionID = nil
+		triggerExec.Payload = nil
+	}
+	record()
 
-		log.Printf("Workflow %s completed successfully", trigger.WorkflowID)
-		return nil
+	log.Printf("Workflow %s completed successfully", workflowID)
+	return execCtx, nil
+}
+
+// sampledOut reports whether a run of triggerID is left out of execution history
+// when it succeeds. A trigger with {"sample_every": N} keeps the full detail of
+// one run in N, starting with the first; the others are only counted (see
+// storage.TriggerRunCount). Failed runs are always kept.
+func (tm *TriggerManager) sampledOut(triggerID string, config map[string]interface{}) bool {
+	every, _ := config["sample_every"].(float64)
+	if every <= 1 {
+		return false
+	}
+	tm.sampleMu.Lock()
+	defer tm.sampleMu.Unlock()
+	n := tm.runs[triggerID]
+	tm.runs[triggerID] = n + 1
+	return n%int(every) != 0
+}
+
+// dropSampledOut deletes the sampled out execution executionID of triggerID
+// and counts it instead, unless it didn't complete (e.g. it waits in a delay).
+// Returns whether it was dropped.
+func (tm *TriggerManager) dropSampledOut(ctx context.Context, triggerID, executionID string) bool {
+	ctx, cancel := storage.Detach(ctx)
+	defer cancel()
+	dropped := false
+	err := storage.WithTx(ctx, tm.Store, func(tx storage.Tx) error {
+		exec, err := tx.GetExecution(ctx, executionID)
+		if err != nil || exec.Status != storage.ExecutionStatusCompleted {
+			return err
+		}
+		if err := tx.DeleteExecution(ctx, executionID); err != nil {
+			return err
+		}
+		dropped = true
+		return tx.CountSampledOutRun(ctx, triggerID, time.Now())
 	})
+	if err != nil {
+		log.Printf("Warning: failed to drop sampled out execution %s: %v", executionID, err)
+		return false
+	}
+	return dropped
 }
 
 // CronTrigger implements cron-based scheduling
@@ -740,6 +1314,7 @@ func (it *IntervalTrigger) Stop() error {
 type WebhookTrigger struct {
 	id         string
 	workflowID string
+	path       string // Custom path, see SetPath
 	manager    *TriggerManager
 }
 
@@ -767,7 +1342,9 @@ func (wt *WebhookTrigger) Invoke(ctx context.Context, payload map[string]interfa
 
 func (wt *WebhookTrigger) Start(ctx context.Context) error {
 	// Webhook trigger is passive, just log startup
-	log.Printf("Webhook trigger started: %s (waiting for POST /api/webhooks/%s)", wt.id, wt.id)
+	for _, path := range wt.WebhookPaths() {
+		log.Printf("Webhook trigger started: %s (waiting for POST %s%s)", wt.id, WebhookRoutePrefix, path)
+	}
 	return nil
 }
 
@@ -776,3 +1353,45 @@ func (wt *WebhookTrigger) Stop() error {
 	log.Printf("Webhook trigger stopped: %s", wt.id)
 	return nil
 }
+
+// WebSocketTrigger implements triggering from frames received on a server-hosted
+// WebSocket endpoint; the API server accepts the connections.
+type WebSocketTrigger struct {
+	id         string
+	workflowID string
+	manager    *TriggerManager
+}
+
+// NewWebSocketTrigger creates a new WebSocket trigger
+func NewWebSocketTrigger(id, workflowID string, manager *TriggerManager) *WebSocketTrigger {
+	return &WebSocketTrigger{
+		id:         id,
+		workflowID: workflowID,
+		manager:    manager,
+	}
+}
+
+func (wt *WebSocketTrigger) ID() string {
+	return wt.id
+}
+
+func (wt *WebSocketTrigger) Type() TriggerType {
+	return TriggerTypeWebSocket
+}
+
+// Invoke is not applicable for Go-native WebSocketTrigger.
+func (wt *WebSocketTrigger) Invoke(ctx context.Context, payload map[string]interface{}) error {
+	return fmt.Errorf("invoke not supported for WebSocketTrigger (use TS trigger instead)")
+}
+
+func (wt *WebSocketTrigger) Start(ctx context.Context) error {
+	// WebSocket trigger is passive, just log startup
+	log.Printf("WebSocket trigger started: %s (waiting for GET /api/ws/%s)", wt.id, wt.id)
+	return nil
+}
+
+func (wt *WebSocketTrigger) Stop() error {
+	// Connections are owned by the API server
+	log.Printf("WebSocket trigger stopped: %s", wt.id)
+	return nil
+}
@@ -0,0 +1,179 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/storage"
+	"github.com/expr-lang/expr"
+)
+
+// TriggerBinding routes a trigger's firings to another workflow, so that one
+// webhook endpoint or schedule can serve several workflows. A trigger lists
+// them in its config next to its own settings:
+//
+//	{"bindings": [{"workflow_id": "wf-orders", "filter": "payload.body.type == 'order'"}]}
+//
+// The trigger's own workflow always runs; each bound workflow runs when its
+// filter is empty or true. Filters are std/condition expressions over
+// {"payload": <trigger payload>}.
+type TriggerBinding struct {
+	WorkflowID string `json:"workflow_id"`
+	Filter     string `json:"filter,omitempty"`
+}
+
+// TriggerBindings reads and validates the bindings of a trigger config.
+func TriggerBindings(config map[string]interface{}) ([]TriggerBinding, error) {
+	raw, ok := config["bindings"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	var bindings []TriggerBinding
+	b, _ := json.Marshal(raw)
+	if err := json.Unmarshal(b, &bindings); err != nil {
+		return nil, fmt.Errorf("bindings must be a list of {\"workflow_id\", \"filter\"}: %w", err)
+	}
+	for i, binding := range bindings {
+		if binding.WorkflowID == "" {
+			return nil, fmt.Errorf("binding %d: workflow_id is required", i)
+		}
+		if strings.TrimSpace(binding.Filter) == "" {
+			continue
+		}
+		filter, _, _ := jsToExpr(binding.Filter)
+		if _, err := expr.Compile(filter, expr.Env(bindingEnv(nil))); err != nil {
+			return nil, fmt.Errorf("binding %d: invalid filter: %w", i, err)
+		}
+	}
+	return bindings, nil
+}
+
+// bindingEnv is the environment binding filters are evaluated in.
+func bindingEnv(payload map[string]interface{}) map[string]interface{} {
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	return map[string]interface{}{"payload": payload}
+}
+
+// Matches reports whether a firing with payload runs the bound workflow.
+func (b TriggerBinding) Matches(payload map[string]interface{}) (bool, error) {
+	if strings.TrimSpace(b.Filter) == "" {
+		return true, nil
+	}
+	env := bindingEnv(payload)
+	filter, _, _ := jsToExpr(b.Filter)
+	program, err := expr.Compile(filter, expr.Env(env))
+	if err != nil {
+		return false, fmt.Errorf("invalid filter: %w", err)
+	}
+	value, err := expr.Run(program, env)
+	if err != nil {
+		return false, fmt.Errorf("filter evaluation failed: %w", err)
+	}
+	return truthy(value), nil
+}
+
+// boundWorkflows returns the workflows bound to triggerID whose filters match
+// payload. Bindings that don't match, or whose filter fails, are recorded in
+// the trigger's execution history as skipped or failed.
+func (tm *TriggerManager) boundWorkflows(ctx context.Context, triggerID string, payload map[string]interface{}, backfill bool) []string {
+	trigger, err := tm.Store.GetTrigger(ctx, triggerID)
+	if err != nil {
+		return nil // the run of the trigger's own workflow records the error
+	}
+	var config map[string]interface{}
+	if err := json.Unmarshal(trigger.Config, &config); err != nil {
+		return nil
+	}
+	bindings, err := TriggerBindings(config)
+	if err != nil {
+		log.Printf("Error: trigger %s has invalid bindings: %v", triggerID, err)
+		return nil
+	}
+
+	var workflowIDs []string
+	var skipped []*storage.TriggerExecution
+	for _, binding := range bindings {
+		ok, err := binding.Matches(payload)
+		if ok {
+			workflowIDs = append(workflowIDs, binding.WorkflowID)
+			continue
+		}
+
+		triggerExec := &storage.TriggerExecution{
+			ID:         storage.NewID(),
+			TriggerID:  triggerID,
+			WorkflowID: binding.WorkflowID,
+			FiredAt:    time.Now(),
+			Status:     "skipped",
+			Backfill:   backfill,
+		}
+		if payload != nil {
+			triggerExec.Payload, _ = json.Marshal(payload)
+		}
+		if err != nil {
+			triggerExec.Status = "failed"
+			msg := err.Error()
+			triggerExec.Error = &msg
+		}
+		skipped = append(skipped, triggerExec)
+	}
+	if len(skipped) > 0 {
+		// The bindings one payload didn't match are recorded together
+		saveCtx, cancel := storage.Detach(ctx)
+		defer cancel()
+		err := storage.WithTx(saveCtx, tm.Store, func(tx storage.Tx) error {
+			for _, triggerExec := range skipped {
+				if err := tx.CreateTriggerExecution(saveCtx, triggerExec); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("Warning: failed to record skipped trigger bindings: %v", err)
+		}
+	}
+	return workflowIDs
+}
+
+// fireBindings runs the workflows bound to triggerID that match payload on the
+// worker pool without waiting for them. With the idempotency key of a delivery,
+// each bound run claims a key of its own (see boundIdempotencyKey): a retry
+// after the trigger's own run failed to start runs only what didn't run yet.
+func (tm *TriggerManager) fireBindings(ctx context.Context, triggerID string, payload map[string]interface{}, backfill bool, key string) error {
+	for _, workflowID := range tm.boundWorkflows(ctx, triggerID, payload, backfill) {
+		run := triggerRun{workflowID: workflowID, backfill: backfill}
+		if key != "" {
+			run.idempotencyKey = boundIdempotencyKey(workflowID, key)
+			_, claimed, err := tm.Store.ClaimIdempotencyKey(ctx, triggerID, run.idempotencyKey)
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				continue
+			}
+		}
+		if err := tm.workerPool.Execute(ctx, func() error {
+			_, err := tm.runTriggered(ctx, triggerID, payload, run)
+			return err
+		}); err != nil {
+			if run.idempotencyKey != "" {
+				tm.releaseIdempotencyKey(ctx, triggerID, run.idempotencyKey)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// boundIdempotencyKey is the key the run of workflowID, bound to a trigger,
+// claims for a delivery with key.
+func boundIdempotencyKey(workflowID, key string) string {
+	return "bound:" + workflowID + ":" + key
+}
@@ -0,0 +1,98 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// TestTriggerManager_Bindings verifies that firing a shared trigger runs its own
+// workflow plus each bound workflow whose filter matches the payload, and
+// records the others as skipped.
+func TestTriggerManager_Bindings(t *testing.T) {
+	ctx := context.Background()
+	store := createTestStorage(t)
+
+	for _, id := range []string{"wf-main", "wf-orders", "wf-refunds"} {
+		def, _ := json.Marshal(engine.Workflow{ID: id, Nodes: map[string]engine.Node{
+			"mark": {ID: "mark", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "seen", "value": "{{ $trigger.type }}"}},
+		}})
+		if err := store.CreateWorkflow(ctx, &storage.Workflow{ID: id, Name: id, Definition: def}); err != nil {
+			t.Fatalf("failed to create workflow: %v", err)
+		}
+	}
+	config := `{"bindings": [
+		{"workflow_id": "wf-orders", "filter": "payload.type === 'order'"},
+		{"workflow_id": "wf-refunds", "filter": "payload.type === 'refund'"}
+	]}`
+	if err := store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-shared", WorkflowID: "wf-main", Type: "webhook", Config: []byte(config), Enabled: true}); err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+
+	tm := engine.NewTriggerManager(store, t.TempDir(), nil, engine.NewWorkerPool(4))
+	defer tm.StopAll()
+	tm.Register(engine.NewWebhookTrigger("tr-shared", "wf-main", tm))
+	if err := tm.Fire(ctx, "tr-shared", map[string]interface{}{"type": "order"}); err != nil {
+		t.Fatalf("Fire failed: %v", err)
+	}
+
+	// Fire is asynchronous; wait for all three bindings to be recorded
+	statuses := map[string]string{}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) && len(statuses) < 3 {
+		execs, err := store.ListTriggerExecutions(ctx, "tr-shared", 10)
+		if err != nil {
+			t.Fatalf("failed to list trigger executions: %v", err)
+		}
+		for _, e := range execs {
+			statuses[e.WorkflowID] = e.Status
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	want := map[string]string{"wf-main": "success", "wf-orders": "success", "wf-refunds": "skipped"}
+	for id, status := range want {
+		if statuses[id] != status {
+			t.Errorf("expected %s to be %s, got %q", id, status, statuses[id])
+		}
+	}
+	for _, id := range []string{"wf-main", "wf-orders"} {
+		if execs, _ := store.ListExecutions(ctx, id, 1); len(execs) != 1 {
+			t.Errorf("expected %s to have run once, got %d executions", id, len(execs))
+		}
+	}
+	if execs, _ := store.ListExecutions(ctx, "wf-refunds", 1); len(execs) != 0 {
+		t.Errorf("expected wf-refunds not to run, got %d executions", len(execs))
+	}
+}
+
+func TestTriggerBindings(t *testing.T) {
+	bindings, err := engine.TriggerBindings(map[string]interface{}{"bindings": []interface{}{
+		map[string]interface{}{"workflow_id": "wf-1"},
+		map[string]interface{}{"workflow_id": "wf-2", "filter": "payload.body.amount > 100"},
+	}})
+	if err != nil || len(bindings) != 2 {
+		t.Fatalf("expected 2 bindings, got %v (%v)", bindings, err)
+	}
+	payload := map[string]interface{}{"body": map[string]interface{}{"amount": 250.0}}
+	if ok, err := bindings[0].Matches(payload); !ok || err != nil {
+		t.Errorf("expected a binding without filter to match, got %v (%v)", ok, err)
+	}
+	if ok, err := bindings[1].Matches(payload); !ok || err != nil {
+		t.Errorf("expected the filter to match, got %v (%v)", ok, err)
+	}
+
+	for name, raw := range map[string]interface{}{
+		"not a list":     "wf-1",
+		"no workflow_id": []interface{}{map[string]interface{}{"filter": "true"}},
+		"invalid filter": []interface{}{map[string]interface{}{"workflow_id": "wf-1", "filter": "payload.("}},
+	} {
+		if _, err := engine.TriggerBindings(map[string]interface{}{"bindings": raw}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// waitTriggerExecutions waits until n trigger executions of triggerID are recorded.
+func waitTriggerExecutions(t *testing.T, store storage.Storage, triggerID string, n int) []*storage.TriggerExecution {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		execs, err := store.ListTriggerExecutions(context.Background(), triggerID, 10)
+		if err != nil {
+			t.Fatalf("failed to list trigger executions: %v", err)
+		}
+		if len(execs) >= n || time.Now().After(deadline) {
+			if len(execs) != n {
+				t.Fatalf("expected %d trigger executions, got %d", n, len(execs))
+			}
+			return execs
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestTriggerManager_FireOnce(t *testing.T) {
+	ctx := context.Background()
+	store := createTestStorage(t)
+
+	def, _ := json.Marshal(engine.Workflow{ID: "wf-idem", Nodes: map[string]engine.Node{
+		"mark": {ID: "mark", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "seen", "value": true}},
+	}})
+	if err := store.CreateWorkflow(ctx, &storage.Workflow{ID: "wf-idem", Name: "wf-idem", Definition: def}); err != nil {
+		t.Fatalf("failed to create workflow: %v", err)
+	}
+	boundDef, _ := json.Marshal(engine.Workflow{ID: "wf-bound", Nodes: map[string]engine.Node{
+		"mark": {ID: "mark", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "seen", "value": true}},
+	}})
+	if err := store.CreateWorkflow(ctx, &storage.Workflow{ID: "wf-bound", Name: "wf-bound", Definition: boundDef}); err != nil {
+		t.Fatalf("failed to create workflow: %v", err)
+	}
+	for id, workflowID := range map[string]string{"tr-idem": "wf-idem", "tr-broken": "wf-missing"} {
+		if err := store.CreateTrigger(ctx, &storage.Trigger{ID: id, WorkflowID: workflowID, Type: "webhook", Config: []byte(`{}`), Enabled: true}); err != nil {
+			t.Fatalf("failed to create trigger: %v", err)
+		}
+	}
+	bound := []byte(`{"bindings": [{"workflow_id": "wf-bound"}]}`)
+	if err := store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-bound", WorkflowID: "wf-missing", Type: "webhook", Config: bound, Enabled: true}); err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+
+	tm := engine.NewTriggerManager(store, t.TempDir(), nil, engine.NewWorkerPool(2))
+	defer tm.StopAll()
+	tm.Register(engine.NewWebhookTrigger("tr-idem", "wf-idem", tm))
+	tm.Register(engine.NewWebhookTrigger("tr-broken", "wf-missing", tm))
+	tm.Register(engine.NewWebhookTrigger("tr-bound", "wf-missing", tm))
+
+	t.Run("RetryMapsToFirstExecution", func(t *testing.T) {
+		if _, duplicate, err := tm.FireOnce(ctx, "tr-idem", "delivery-1", nil); err != nil || duplicate {
+			t.Fatalf("expected the first delivery to fire, got duplicate=%v (%v)", duplicate, err)
+		}
+		first := waitTriggerExecutions(t, store, "tr-idem", 1)[0]
+		if first.Status != "success" || first.ExecutionID == nil {
+			t.Fatalf("expected a successful run with an execution, got %+v", first)
+		}
+
+		executionID, duplicate, err := tm.FireOnce(ctx, "tr-idem", "delivery-1", nil)
+		if err != nil || !duplicate || executionID != *first.ExecutionID {
+			t.Errorf("expected a duplicate of %s, got %q, %v (%v)", *first.ExecutionID, executionID, duplicate, err)
+		}
+		if _, duplicate, _ := tm.FireOnce(ctx, "tr-idem", "delivery-2", nil); duplicate {
+			t.Error("expected another key to fire")
+		}
+		waitTriggerExecutions(t, store, "tr-idem", 2)
+		if execs, _ := store.ListExecutions(ctx, "wf-idem", 10); len(execs) != 2 {
+			t.Errorf("expected 2 executions, got %d", len(execs))
+		}
+	})
+
+	t.Run("FailedStartReleasesKey", func(t *testing.T) {
+		if _, duplicate, err := tm.FireOnce(ctx, "tr-broken", "delivery-1", nil); err != nil || duplicate {
+			t.Fatalf("expected the first delivery to fire, got duplicate=%v (%v)", duplicate, err)
+		}
+		if failed := waitTriggerExecutions(t, store, "tr-broken", 1)[0]; failed.Status != "failed" {
+			t.Fatalf("expected the run to fail, got %+v", failed)
+		}
+		// The key is released after the failure is recorded
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			_, claimed, err := store.ClaimIdempotencyKey(ctx, "tr-broken", "delivery-1")
+			if err != nil {
+				t.Fatalf("failed to claim key: %v", err)
+			}
+			if claimed {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("expected the key of a run that failed to start to be released")
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	})
+	t.Run("RetryRunsBindingsOnce", func(t *testing.T) {
+		// The trigger's own run fails to start and releases its key; the
+		// retry it allows must not run the bound workflow a second time
+		for i := 1; i <= 2; i++ {
+			if _, duplicate, err := tm.FireOnce(ctx, "tr-bound", "delivery-1", nil); err != nil || duplicate {
+				t.Fatalf("expected delivery %d to fire, got duplicate=%v (%v)", i, duplicate, err)
+			}
+			waitTriggerExecutions(t, store, "tr-bound", i+1)
+		}
+		if execs, _ := store.ListExecutions(ctx, "wf-bound", 10); len(execs) != 1 {
+			t.Errorf("expected the bound workflow to run once, got %d executions", len(execs))
+		}
+	})
+}
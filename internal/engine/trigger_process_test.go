@@ -0,0 +1,132 @@
+package engine_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+// TestTSTriggerRunner_Process verifies that a TS trigger's Bun process runs in
+// the configured working directory with only the allowed environment.
+func TestTSTriggerRunner_Process(t *testing.T) {
+	out := t.TempDir()
+	// A bun that records how it was run, then acts as a trigger that is ready at once
+	bunDir := t.TempDir()
+	fakeBun := "#!/bin/sh\n" +
+		"echo \"$@\" > " + out + "/args\n" +
+		"pwd > " + out + "/cwd\n" +
+		"env > " + out + "/env\n" +
+		"read request\n" +
+		"echo '{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{\"protocolVersion\":1}}'\n" +
+		"while read line; do :; done\n"
+	if err := os.WriteFile(filepath.Join(bunDir, "bun"), []byte(fakeBun), 0755); err != nil {
+		t.Fatalf("failed to write fake bun: %v", err)
+	}
+	t.Setenv("PATH", bunDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("CONV3N_TEST_SECRET", "hunter2")
+	t.Setenv("CONV3N_TEST_SHARED", "shared")
+
+	script := filepath.Join(t.TempDir(), "trigger.ts")
+	if err := os.WriteFile(script, []byte("// trigger"), 0644); err != nil {
+		t.Fatalf("failed to write trigger: %v", err)
+	}
+	workDir, _ := filepath.EvalSymlinks(t.TempDir())
+	config := map[string]interface{}{
+		"working_dir":     workDir,
+		"env":             map[string]interface{}{"API_URL": "https://api.example.com"},
+		"env_passthrough": []interface{}{"CONV3N_TEST_SHARED"},
+		"bun_args":        []interface{}{"--smol"},
+	}
+	tm := engine.NewTriggerManager(createTestStorage(t), t.TempDir(), nil, engine.NewWorkerPool(1))
+	runner := engine.NewTSTriggerRunner("tr-ts", "wf-1", script, config, tm)
+	if err := runner.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	runner.Stop()
+
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatalf("fake bun did not record %s: %v", name, err)
+		}
+		return strings.TrimSpace(string(data))
+	}
+	if args := read("args"); args != "run --smol "+script {
+		t.Errorf("expected bun run --smol %s, got %q", script, args)
+	}
+	if cwd := read("cwd"); cwd != workDir {
+		t.Errorf("expected working directory %s, got %s", workDir, cwd)
+	}
+	env := read("env")
+	for _, want := range []string{"API_URL=https://api.example.com", "CONV3N_TEST_SHARED=shared", "PATH="} {
+		if !strings.Contains(env, want) {
+			t.Errorf("expected %s in the environment, got:\n%s", want, env)
+		}
+	}
+	if strings.Contains(env, "CONV3N_TEST_SECRET") {
+		t.Errorf("expected server variables not to be passed through, got:\n%s", env)
+	}
+
+	// A working directory that doesn't exist fails the start
+	config["working_dir"] = filepath.Join(workDir, "missing")
+	runner = engine.NewTSTriggerRunner("tr-ts", "wf-1", script, config, tm)
+	if err := runner.Start(context.Background()); err == nil || !strings.Contains(err.Error(), "working_dir") {
+		t.Errorf("expected a working_dir error, got %v", err)
+	}
+}
+
+// TestTSTriggerRunner_InvokeWithKey verifies that the idempotency key of a
+// delivery is passed on to the trigger with the invoke notification.
+func TestTSTriggerRunner_InvokeWithKey(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "lines")
+	// A bun that is ready at once and records the notifications it receives
+	bunDir := t.TempDir()
+	fakeBun := "#!/bin/sh\n" +
+		"read request\n" +
+		"echo '{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{\"protocolVersion\":1}}'\n" +
+		"while read line; do echo \"$line\" >> " + out + "; done\n"
+	if err := os.WriteFile(filepath.Join(bunDir, "bun"), []byte(fakeBun), 0755); err != nil {
+		t.Fatalf("failed to write fake bun: %v", err)
+	}
+	t.Setenv("PATH", bunDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	script := filepath.Join(t.TempDir(), "trigger.ts")
+	if err := os.WriteFile(script, []byte("// trigger"), 0644); err != nil {
+		t.Fatalf("failed to write trigger: %v", err)
+	}
+	tm := engine.NewTriggerManager(createTestStorage(t), t.TempDir(), nil, engine.NewWorkerPool(1))
+	runner := engine.NewTSTriggerRunner("tr-ts", "wf-1", script, map[string]interface{}{}, tm)
+	if err := runner.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer runner.Stop()
+
+	if err := runner.InvokeWithKey(context.Background(), "delivery-1", map[string]interface{}{"n": 1}); err != nil {
+		t.Fatalf("InvokeWithKey failed: %v", err)
+	}
+	if err := runner.Invoke(context.Background(), map[string]interface{}{"n": 2}); err != nil {
+		t.Fatalf("Invoke failed: %v", err)
+	}
+
+	var lines []string
+	deadline := time.Now().Add(5 * time.Second)
+	for len(lines) < 2 && time.Now().Before(deadline) {
+		data, _ := os.ReadFile(out)
+		lines = strings.Fields(string(data))
+		time.Sleep(20 * time.Millisecond)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 invoke notifications, got %q", lines)
+	}
+	if !strings.Contains(lines[0], `"idempotencyKey":"delivery-1"`) {
+		t.Errorf("expected the key in the first invoke, got %s", lines[0])
+	}
+	if strings.Contains(lines[1], "idempotencyKey") {
+		t.Errorf("expected no key in an invoke without one, got %s", lines[1])
+	}
+}
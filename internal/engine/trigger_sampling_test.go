@@ -0,0 +1,60 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func TestTriggerManager_Sampling(t *testing.T) {
+	ctx := context.Background()
+	store := createTestStorage(t)
+
+	def, _ := json.Marshal(engine.Workflow{ID: "wf-sampled", Nodes: map[string]engine.Node{
+		"mark": {ID: "mark", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "seen", "value": true}},
+	}})
+	if err := store.CreateWorkflow(ctx, &storage.Workflow{ID: "wf-sampled", Name: "wf-sampled", Definition: def}); err != nil {
+		t.Fatalf("failed to create workflow: %v", err)
+	}
+	if err := store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-sampled", WorkflowID: "wf-sampled", Type: "webhook", Config: []byte(`{"sample_every":3}`), Enabled: true}); err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+
+	tm := engine.NewTriggerManager(store, t.TempDir(), nil, engine.NewWorkerPool(1))
+	defer tm.StopAll()
+	tm.Register(engine.NewWebhookTrigger("tr-sampled", "wf-sampled", tm))
+
+	// Fired one after another, runs 1 and 4 are kept in full
+	for i := 1; i <= 4; i++ {
+		if _, _, err := tm.FireOnce(ctx, "tr-sampled", "", map[string]interface{}{"n": i}); err != nil {
+			t.Fatalf("failed to fire: %v", err)
+		}
+		waitTriggerExecutions(t, store, "tr-sampled", i)
+	}
+
+	execs, _ := store.ListExecutions(ctx, "wf-sampled", 10)
+	if len(execs) != 2 {
+		t.Fatalf("expected 2 executions kept in history, got %d", len(execs))
+	}
+	fires, _ := store.ListTriggerExecutions(ctx, "tr-sampled", 10)
+	kept := 0
+	for _, fire := range fires {
+		if fire.Status != "success" {
+			t.Errorf("expected every fire to succeed, got %+v", fire)
+		}
+		if fire.ExecutionID != nil {
+			kept++
+		}
+	}
+	if kept != 2 {
+		t.Errorf("expected 2 fires to reference their execution, got %d", kept)
+	}
+	counts, err := store.ListTriggerRunCounts(ctx, "tr-sampled", time.Now())
+	if err != nil || len(counts) != 1 || counts[0].SampledOut != 2 {
+		t.Errorf("expected 2 sampled out runs counted today, got %v (%v)", counts, err)
+	}
+}
@@ -0,0 +1,148 @@
+package engine_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// brokenTrigger is a runner whose Start always fails.
+type brokenTrigger struct{ id string }
+
+func (b brokenTrigger) Start(ctx context.Context) error { return errors.New("port in use") }
+func (b brokenTrigger) Stop() error                     { return nil }
+func (b brokenTrigger) ID() string                      { return b.id }
+func (b brokenTrigger) Type() engine.TriggerType        { return engine.TriggerTypeWebhook }
+func (b brokenTrigger) Invoke(ctx context.Context, payload map[string]interface{}) error {
+	return nil
+}
+
+// TestTriggerManager_RuntimeStatus verifies that the manager keeps the stored
+// runtime status of a trigger in line with what its runner is doing.
+func TestTriggerManager_RuntimeStatus(t *testing.T) {
+	ctx := context.Background()
+	store := createTestStorage(t)
+	if err := store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-1", WorkflowID: "wf-1", Type: "webhook", Config: []byte(`{}`), Enabled: true}); err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+	tm := engine.NewTriggerManager(store, t.TempDir(), nil, engine.NewWorkerPool(1))
+	defer tm.StopAll()
+
+	expect := func(step string, status storage.TriggerRuntimeStatus, errMsg string) {
+		t.Helper()
+		tr, err := store.GetTrigger(ctx, "tr-1")
+		if err != nil {
+			t.Fatalf("%s: failed to get trigger: %v", step, err)
+		}
+		gotErr := ""
+		if tr.RuntimeError != nil {
+			gotErr = *tr.RuntimeError
+		}
+		if tr.RuntimeStatus != status || gotErr != errMsg {
+			t.Errorf("%s: expected %s %q, got %s %q", step, status, errMsg, tr.RuntimeStatus, gotErr)
+		}
+	}
+
+	expect("created", storage.TriggerRuntimeStopped, "")
+
+	if err := tm.Register(engine.NewWebhookTrigger("tr-1", "wf-1", tm)); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	expect("registered", storage.TriggerRuntimeRunning, "")
+
+	tm.ReportCrash("tr-1", "bun process exited unexpectedly")
+	expect("crashed", storage.TriggerRuntimeErrored, "bun process exited unexpectedly")
+	if _, ok := tm.GetTrigger("tr-1"); !ok {
+		t.Error("expected a crashed trigger to stay registered")
+	}
+
+	if err := tm.Restart(engine.NewWebhookTrigger("tr-1", "wf-1", tm)); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	expect("restarted", storage.TriggerRuntimeRunning, "")
+
+	if err := tm.Restart(brokenTrigger{id: "tr-1"}); err == nil {
+		t.Fatal("expected Restart to fail")
+	}
+	expect("failed restart", storage.TriggerRuntimeErrored, "port in use")
+	if _, ok := tm.GetTrigger("tr-1"); ok {
+		t.Error("expected a trigger that failed to start not to be registered")
+	}
+
+	if err := tm.Register(engine.NewWebhookTrigger("tr-1", "wf-1", tm)); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := tm.Unregister("tr-1"); err != nil {
+		t.Fatalf("Unregister failed: %v", err)
+	}
+	expect("unregistered", storage.TriggerRuntimeStopped, "")
+}
+
+// TestTSTriggerRunner_Exit verifies that a TS trigger process that exits on its
+// own is recorded as stopped when it exits cleanly, and as a crash, with
+// TriggerCrashed published, when it fails.
+func TestTSTriggerRunner_Exit(t *testing.T) {
+	installFakeBun(t)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name    string
+		body    string
+		status  storage.TriggerRuntimeStatus
+		errMsg  string
+		crashed bool
+	}{
+		{"CleanExit", "exit 0\n", storage.TriggerRuntimeStopped, "", false},
+		{"FailedExit", "exit 3\n", storage.TriggerRuntimeErrored, "bun process exited unexpectedly: exit status 3", true},
+		{"Killed", "kill -9 $$\n", storage.TriggerRuntimeErrored, "bun process exited unexpectedly: signal: killed", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := createTestStorage(t)
+			if err := store.CreateTrigger(ctx, &storage.Trigger{ID: "tr-ts", WorkflowID: "wf-1", Type: "typescript", Config: []byte(`{}`), Enabled: true}); err != nil {
+				t.Fatalf("failed to create trigger: %v", err)
+			}
+			tm := engine.NewTriggerManager(store, t.TempDir(), nil, engine.NewWorkerPool(1))
+			defer tm.StopAll()
+			tm.SetEventBus(engine.NewEventBus())
+			crashes := make(chan engine.Event, 1)
+			defer tm.Events().Subscribe(func(ev engine.Event) { crashes <- ev }, engine.EventTriggerCrashed)()
+
+			// The trigger sleeps briefly so that it exits after it is registered
+			script, _ := writeTrigger(t, `{"protocolVersion":1}`, "sleep 0.2\n"+tc.body)
+			if err := tm.Register(engine.NewTSTriggerRunner("tr-ts", "wf-1", script, nil, tm)); err != nil {
+				t.Fatalf("Register failed: %v", err)
+			}
+
+			var tr *storage.Trigger
+			deadline := time.Now().Add(5 * time.Second)
+			for time.Now().Before(deadline) {
+				tr, _ = store.GetTrigger(ctx, "tr-ts")
+				if tr != nil && tr.RuntimeStatus != storage.TriggerRuntimeRunning {
+					break
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+			gotErr := ""
+			if tr.RuntimeError != nil {
+				gotErr = *tr.RuntimeError
+			}
+			if tr.RuntimeStatus != tc.status || gotErr != tc.errMsg {
+				t.Errorf("expected %s %q, got %s %q", tc.status, tc.errMsg, tr.RuntimeStatus, gotErr)
+			}
+			select {
+			case <-crashes:
+				if !tc.crashed {
+					t.Error("expected no TriggerCrashed for a clean exit")
+				}
+			case <-time.After(200 * time.Millisecond):
+				if tc.crashed {
+					t.Error("expected TriggerCrashed")
+				}
+			}
+		})
+	}
+}
@@ -80,18 +80,18 @@ stdin.on('data', (data) => {
   try {
     const msg = JSON.parse(data);
 
-    if (msg.type === 'start') {
-      // Received start, so send ready
-      sendMessage({ type: 'status', status: 'ready' });
-    } else if (msg.type === 'invoke') {
-      // Received invoke, send an event back
-      const requestId = Math.random().toString(36).substring(7);
+    if (msg.method === 'initialize') {
+      // Answering initialize tells the host the trigger is ready
+      sendMessage({ jsonrpc: '2.0', id: msg.id, result: { protocolVersion: 1 } });
+    } else if (msg.method === 'invoke') {
+      // Received invoke, fire a workflow in return
       sendMessage({
-        type: 'event',
-        requestId: requestId,
-        payload: { from: 'invoke', originalPayload: msg.payload }
+        jsonrpc: '2.0',
+        id: 'fire-1',
+        method: 'fire',
+        params: { payload: { from: 'invoke', originalPayload: msg.params.payload } }
       });
-    } else if (msg.type === 'kill') {
+    } else if (msg.method === 'shutdown') {
       process.exit(0);
     }
   } catch (e) {
@@ -179,19 +179,19 @@ function sendMessage(message) {
 stdin.on('data', (data) => {
   try {
     const msg = JSON.parse(data);
-    if (msg.type === 'start') {
+    if (msg.method === 'initialize') {
       // Send ready first
-      sendMessage({ type: 'status', status: 'ready' });
+      sendMessage({ jsonrpc: '2.0', id: msg.id, result: { protocolVersion: 1 } });
 
       // Then fire an event
-      const requestId = Math.random().toString(36).substring(7);
       sendMessage({
-        type: 'event',
-        requestId: requestId,
-        payload: { from: 'onStart' }
+        jsonrpc: '2.0',
+        id: 'fire-1',
+        method: 'fire',
+        params: { payload: { from: 'onStart' } }
       });
 
-    } else if (msg.type === 'kill') {
+    } else if (msg.method === 'shutdown') {
       process.exit(0);
     }
   } catch (e) {
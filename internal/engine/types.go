@@ -1,6 +1,10 @@
 package engine
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+	"time"
+)
 
 // =============================================================================
 // NODE TYPES
@@ -22,6 +26,16 @@ const (
 	NodeTypeWebhook     NodeType = "std/webhook"
 	NodeTypeSetVar      NodeType = "std/set_var"
 	NodeTypeGetVar      NodeType = "std/get_var"
+	NodeTypeSSH         NodeType = "std/ssh"
+	NodeTypeSFTP        NodeType = "std/sftp"
+	NodeTypeCSV         NodeType = "std/csv"
+	NodeTypeSpreadsheet NodeType = "std/spreadsheet"
+	NodeTypeArchive     NodeType = "std/archive"
+	NodeTypeDatetime    NodeType = "std/datetime"
+	NodeTypeXML         NodeType = "std/xml"
+	NodeTypeEnqueue     NodeType = "std/enqueue"
+	NodeTypeRateLimit   NodeType = "std/rate_limit"
+	NodeTypeDedupe      NodeType = "std/dedupe"
 
 	// Trigger nodes (long-running, emit events)
 	NodeTypeTriggerHTTP      NodeType = "trigger/http"
@@ -58,6 +72,9 @@ type Node struct {
 	Type     NodeType               `json:"type"`
 	Position Position               `json:"position"`
 	Config   map[string]interface{} `json:"config,omitempty"`
+	// Version pins the block that runs the node, e.g. "1.2.3" or "^1.2" (see
+	// VersionConstraint). Empty runs the newest installed version.
+	Version string `json:"version,omitempty"`
 	// Data is used for React Flow compatibility (label, etc.)
 	Data map[string]interface{} `json:"data,omitempty"`
 }
@@ -79,10 +96,125 @@ type Edge struct {
 // Workflow represents the entire workflow as a graph of nodes and edges.
 // This is the new graph-based structure replacing the linear []Block array.
 type Workflow struct {
-	ID    string          `json:"id"`
-	Name  string          `json:"name"`
-	Nodes map[string]Node `json:"nodes"` // Node ID -> Node
-	Edges []Edge          `json:"edges"`
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Nodes    map[string]Node   `json:"nodes"` // Node ID -> Node
+	Edges    []Edge            `json:"edges"`
+	Settings *WorkflowSettings `json:"settings,omitempty"`
+}
+
+// WorkflowSettings holds optional per-workflow execution settings.
+type WorkflowSettings struct {
+	// Timeout bounds a whole run, as a Go duration string (e.g. "5m"). Empty means no limit.
+	Timeout string `json:"timeout,omitempty"`
+	// EdgeMatching selects how edges are matched to output ports: "lenient"
+	// (the default) or "strict". See EdgeMatchingStrict.
+	EdgeMatching string `json:"edge_matching,omitempty"`
+	// Timezone (IANA name) the nodes work in unless they name one: it is passed
+	// to every node as the input's "timezone". Empty means UTC.
+	Timezone string `json:"timezone,omitempty"`
+	// NodeTimeout bounds each node that sets no config.timeout_ms, as a Go
+	// duration string. Empty means the runner's default.
+	NodeTimeout string `json:"node_timeout,omitempty"`
+	// ErrorWorkflow is the ID of a workflow started when a run fails, with the
+	// failure as its trigger data (see errorWorkflowPayload).
+	ErrorWorkflow string `json:"error_workflow,omitempty"`
+	// SaveSuccessData selects what is kept of a successful run: "all" (the
+	// default) or "none", which keeps only its summary and node timeline. Failed
+	// runs always keep their data.
+	SaveSuccessData string `json:"save_success_data,omitempty"`
+}
+
+const (
+	// SaveDataAll keeps the node results, inputs and state of an execution.
+	SaveDataAll = "all"
+	// SaveDataNone drops them once the execution completed, for high-volume
+	// workflows whose successful runs needn't be inspected.
+	SaveDataNone = "none"
+)
+
+const (
+	// EdgeMatchingLenient treats an edge without a sourceHandle as a wildcard
+	// that follows every port of its source node.
+	EdgeMatchingLenient = "lenient"
+	// EdgeMatchingStrict requires an exact port match for nodes that declare
+	// their ports: an edge without a sourceHandle is the "default" port. Nodes
+	// with undeclared ports are matched leniently.
+	EdgeMatchingStrict = "strict"
+)
+
+// Timeout returns the workflow-level run timeout, or 0 when none is set.
+func (w *Workflow) Timeout() (time.Duration, error) {
+	if w.Settings == nil || w.Settings.Timeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(w.Settings.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid settings.timeout %q: %w", w.Settings.Timeout, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid settings.timeout %q: must not be negative", w.Settings.Timeout)
+	}
+	return d, nil
+}
+
+// EdgeMatching returns the edge matching mode, EdgeMatchingLenient when none is set.
+func (w *Workflow) EdgeMatching() (string, error) {
+	if w.Settings == nil || w.Settings.EdgeMatching == "" {
+		return EdgeMatchingLenient, nil
+	}
+	switch w.Settings.EdgeMatching {
+	case EdgeMatchingLenient, EdgeMatchingStrict:
+		return w.Settings.EdgeMatching, nil
+	}
+	return "", fmt.Errorf("invalid settings.edge_matching %q: want %q or %q", w.Settings.EdgeMatching, EdgeMatchingLenient, EdgeMatchingStrict)
+}
+
+// Timezone returns the location the workflow's nodes work in, nil when none is set.
+func (w *Workflow) Timezone() (*time.Location, error) {
+	if w.Settings == nil || w.Settings.Timezone == "" {
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(w.Settings.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid settings.timezone %q: unknown timezone", w.Settings.Timezone)
+	}
+	return loc, nil
+}
+
+// NodeTimeout returns the default timeout of the workflow's nodes, or 0 when none is set.
+func (w *Workflow) NodeTimeout() (time.Duration, error) {
+	if w.Settings == nil || w.Settings.NodeTimeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(w.Settings.NodeTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid settings.node_timeout %q: %w", w.Settings.NodeTimeout, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid settings.node_timeout %q: must be positive", w.Settings.NodeTimeout)
+	}
+	return d, nil
+}
+
+// ErrorWorkflow returns the ID of the workflow to start when a run fails, if any.
+func (w *Workflow) ErrorWorkflow() string {
+	if w.Settings == nil {
+		return ""
+	}
+	return w.Settings.ErrorWorkflow
+}
+
+// SaveSuccessData returns what is kept of a successful run, SaveDataAll when none is set.
+func (w *Workflow) SaveSuccessData() (string, error) {
+	if w.Settings == nil || w.Settings.SaveSuccessData == "" {
+		return SaveDataAll, nil
+	}
+	switch w.Settings.SaveSuccessData {
+	case SaveDataAll, SaveDataNone:
+		return w.Settings.SaveSuccessData, nil
+	}
+	return "", fmt.Errorf("invalid settings.save_success_data %q: want %q or %q", w.Settings.SaveSuccessData, SaveDataAll, SaveDataNone)
 }
 
 // GetNode returns a node by ID, or nil if not found.
@@ -109,18 +241,44 @@ func (w *Workflow) FindStartNodes() []string {
 	return startNodes
 }
 
-// FindNextNode finds the next node ID by following an edge from the given node and port.
-// Returns empty string if no matching edge is found (end of execution path).
-func (w *Workflow) FindNextNode(nodeID, outputPort string) string {
+// FindNextNodes returns the targets of all edges leaving the given node through
+// the given port, in edge order, matching edges leniently. Several edges on one
+// port fan out to all of their targets. Returns nil if no edge matches (end of
+// execution path).
+func (w *Workflow) FindNextNodes(nodeID, outputPort string) []string {
+	return w.findNextNodes(nodeID, outputPort, false)
+}
+
+// findNextNodes returns the targets of the edges leaving nodeID that are taken
+// when it routes to outputPort (see Edge.follows).
+func (w *Workflow) findNextNodes(nodeID, outputPort string, strict bool) []string {
+	var targets []string
 	for _, edge := range w.Edges {
-		if edge.Source == nodeID {
-			// If outputPort is specified, match it; otherwise match any edge from this node
-			if outputPort == "" || edge.SourceHandle == "" || edge.SourceHandle == outputPort {
-				return edge.Target
-			}
+		if edge.Source == nodeID && edge.follows(outputPort, strict) {
+			targets = append(targets, edge.Target)
 		}
 	}
-	return ""
+	return targets
+}
+
+// follows reports whether the edge is taken when its source routes to port.
+// Leniently an edge without a sourceHandle matches any port; strictly it is the
+// default port. An empty port matches every edge.
+func (e Edge) follows(port string, strict bool) bool {
+	handle := e.SourceHandle
+	if handle == "" && strict {
+		handle = DefaultPort
+	}
+	return port == "" || handle == "" || handle == port
+}
+
+// pushNext adds the targets to the stack of nodes still to run so that they are
+// popped in edge order: each branch of a fan-out runs to its end before the next.
+func pushNext(pending, targets []string) []string {
+	for i := len(targets) - 1; i >= 0; i-- {
+		pending = append(pending, targets[i])
+	}
+	return pending
 }
 
 // FindOutgoingEdges returns all edges originating from the given node.
@@ -139,45 +297,216 @@ func (w *Workflow) FindOutgoingEdges(nodeID string) []Edge {
 // =============================================================================
 
 // ExecutionContext holds the state of a running workflow execution.
+// Results and variables are guarded by a mutex so nodes running concurrently
+// can read and write them safely; access them only through the methods below.
 type ExecutionContext struct {
 	WorkflowID  string
 	ExecutionID string
-	// Results stores the output of each node by Node ID
-	Results map[string]interface{}
-	// Variables stores user-defined variables (mutable state)
-	Variables map[string]interface{}
 	// TriggerData stores the payload from the trigger (e.g. webhook body)
 	TriggerData map[string]interface{}
+	// TriggerID is the trigger that started the run, if any
+	TriggerID string
+	// IdempotencyKey is the delivery key TriggerID was fired with, if any; the
+	// execution is recorded against it when created (see TriggerManager.FireOnce)
+	IdempotencyKey string
+	// Environment selects which named environment's global variables override
+	// the shared defaults ($globals). Empty means the defaults only.
+	Environment string
+	// Test marks a test-mode run (e.g. from a test webhook); its execution is
+	// flagged as such in history.
+	Test bool
+	// Canary marks a run of the workflow's canary version (see pickCanary); its
+	// execution is flagged as such in history.
+	Canary bool
+	// Mocks, if set, run the workflow in mock mode: network calls are answered
+	// by canned responses (see Mocks).
+	Mocks *Mocks
+
+	mu sync.RWMutex
+	// results stores the output of each node by Node ID
+	results map[string]interface{}
+	// variables stores user-defined variables (mutable state)
+	variables map[string]interface{}
+	// static is the workflow's persistent key/value data ($workflowStatic),
+	// loaded before the run and saved after it when staticChanged is set
+	static        map[string]interface{}
+	staticChanged bool
+	// globals holds the server-managed global variables ($globals), read-only during a run
+	globals map[string]interface{}
+	// mocks holds the outputs of nodes that don't run but replay them (see MockNode)
+	mocks map[string]interface{}
+	// secrets holds the values $credentials resolved to, redacted from saved node inputs
+	secrets map[string]bool
 }
 
 // NewExecutionContext creates a new context for a workflow execution.
 func NewExecutionContext(workflowID string) *ExecutionContext {
 	return &ExecutionContext{
 		WorkflowID:  workflowID,
-		Results:     make(map[string]interface{}),
-		Variables:   make(map[string]interface{}),
+		results:     make(map[string]interface{}),
+		variables:   make(map[string]interface{}),
+		static:      make(map[string]interface{}),
+		globals:     make(map[string]interface{}),
 		TriggerData: make(map[string]interface{}),
 	}
 }
 
 // SetResult saves the output of a node.
 func (ctx *ExecutionContext) SetResult(nodeID string, result interface{}) {
-	ctx.Results[nodeID] = result
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.results[nodeID] = result
 }
 
 // GetResult retrieves the output of a node.
 func (ctx *ExecutionContext) GetResult(nodeID string) interface{} {
-	return ctx.Results[nodeID]
+	result, _ := ctx.LookupResult(nodeID)
+	return result
+}
+
+// LookupResult retrieves the output of a node and reports whether it exists.
+func (ctx *ExecutionContext) LookupResult(nodeID string) (interface{}, bool) {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	result, ok := ctx.results[nodeID]
+	return result, ok
+}
+
+// Results returns a copy of all node results.
+func (ctx *ExecutionContext) Results() map[string]interface{} {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	return copyMap(ctx.results)
+}
+
+// MockNode makes the node nodeID output result instead of running, e.g. to
+// replay what it output in a snapshot (see RunSnapshotTest).
+func (ctx *ExecutionContext) MockNode(nodeID string, result interface{}) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	if ctx.mocks == nil {
+		ctx.mocks = make(map[string]interface{})
+	}
+	ctx.mocks[nodeID] = result
+}
+
+// mockResult returns the output the node nodeID was mocked with, if any,
+// through MockNode or Mocks.
+func (ctx *ExecutionContext) mockResult(nodeID string) (interface{}, bool) {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	if result, ok := ctx.mocks[nodeID]; ok {
+		return result, true
+	}
+	if ctx.Mocks == nil {
+		return nil, false
+	}
+	result, ok := ctx.Mocks.Nodes[nodeID]
+	return result, ok
 }
 
 // SetVar sets a user-defined variable.
 func (ctx *ExecutionContext) SetVar(name string, value interface{}) {
-	ctx.Variables[name] = value
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.variables[name] = value
 }
 
 // GetVar retrieves a user-defined variable.
 func (ctx *ExecutionContext) GetVar(name string) interface{} {
-	return ctx.Variables[name]
+	value, _ := ctx.LookupVar(name)
+	return value
+}
+
+// LookupVar retrieves a user-defined variable and reports whether it is set.
+func (ctx *ExecutionContext) LookupVar(name string) (interface{}, bool) {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	value, ok := ctx.variables[name]
+	return value, ok
+}
+
+// Variables returns a copy of all user-defined variables.
+func (ctx *ExecutionContext) Variables() map[string]interface{} {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	return copyMap(ctx.variables)
+}
+
+// Restore merges previously saved results and variables into the context,
+// e.g. when resuming an execution from its persisted state.
+func (ctx *ExecutionContext) Restore(results, variables map[string]interface{}) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	for k, v := range results {
+		ctx.results[k] = v
+	}
+	for k, v := range variables {
+		ctx.variables[k] = v
+	}
+}
+
+// SetStatic sets a key in the workflow's persistent static data.
+func (ctx *ExecutionContext) SetStatic(key string, value interface{}) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.static[key] = value
+	ctx.staticChanged = true
+}
+
+// LookupStatic retrieves a key from the workflow's static data and reports whether it is set.
+func (ctx *ExecutionContext) LookupStatic(key string) (interface{}, bool) {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	value, ok := ctx.static[key]
+	return value, ok
+}
+
+// StaticData returns a copy of the workflow's static data and whether it was
+// modified during this execution.
+func (ctx *ExecutionContext) StaticData() (map[string]interface{}, bool) {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	return copyMap(ctx.static), ctx.staticChanged
+}
+
+// LoadStatic replaces the static data with what was persisted by earlier runs.
+func (ctx *ExecutionContext) LoadStatic(data map[string]interface{}) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.static = copyMap(data)
+	ctx.staticChanged = false
+}
+
+// SetGlobals replaces the global variables visible to the execution.
+func (ctx *ExecutionContext) SetGlobals(globals map[string]interface{}) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.globals = copyMap(globals)
+}
+
+// LookupGlobal retrieves a global variable and reports whether it is set.
+func (ctx *ExecutionContext) LookupGlobal(name string) (interface{}, bool) {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	value, ok := ctx.globals[name]
+	return value, ok
+}
+
+// Globals returns a copy of all global variables.
+func (ctx *ExecutionContext) Globals() map[string]interface{} {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	return copyMap(ctx.globals)
+}
+
+// copyMap returns a shallow copy of m.
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
 }
 
 // =============================================================================
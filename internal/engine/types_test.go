@@ -2,6 +2,8 @@ package engine
 
 import (
 	"encoding/json"
+	"fmt"
+	"sync"
 	"testing"
 )
 
@@ -18,12 +20,8 @@ func TestNewExecutionContext(t *testing.T) {
 		t.Errorf("Expected WorkflowID %s, got %s", workflowID, ctx.WorkflowID)
 	}
 
-	if ctx.Results == nil {
-		t.Error("Results map not initialized")
-	}
-
-	if len(ctx.Results) != 0 {
-		t.Errorf("Expected empty Results map, got %d items", len(ctx.Results))
+	if results := ctx.Results(); len(results) != 0 {
+		t.Errorf("Expected empty Results map, got %d items", len(results))
 	}
 }
 
@@ -259,22 +257,22 @@ func TestExecutionContextResultsManipulation(t *testing.T) {
 	ctx := NewExecutionContext("test-workflow")
 
 	// Add results
-	ctx.Results["block1"] = map[string]interface{}{"value": 1}
-	ctx.Results["block2"] = map[string]interface{}{"value": 2}
+	ctx.SetResult("block1", map[string]interface{}{"value": 1})
+	ctx.SetResult("block2", map[string]interface{}{"value": 2})
 
-	if len(ctx.Results) != 2 {
-		t.Errorf("Expected 2 results, got %d", len(ctx.Results))
+	if len(ctx.Results()) != 2 {
+		t.Errorf("Expected 2 results, got %d", len(ctx.Results()))
 	}
 
 	// Verify retrieval
-	result1 := ctx.Results["block1"].(map[string]interface{})
+	result1 := ctx.GetResult("block1").(map[string]interface{})
 	if result1["value"] != 1 {
 		t.Errorf("Expected value 1, got %v", result1["value"])
 	}
 
 	// Update result
-	ctx.Results["block1"] = map[string]interface{}{"value": 100}
-	updatedResult := ctx.Results["block1"].(map[string]interface{})
+	ctx.SetResult("block1", map[string]interface{}{"value": 100})
+	updatedResult := ctx.GetResult("block1").(map[string]interface{})
 	if updatedResult["value"] != 100 {
 		t.Errorf("Expected updated value 100, got %v", updatedResult["value"])
 	}
@@ -346,3 +344,30 @@ func TestEmptyWorkflow(t *testing.T) {
 		t.Errorf("Expected 0 edges, got %d", len(decoded.Edges))
 	}
 }
+
+// TestExecutionContextConcurrentAccess verifies results and variables can be
+// read and written from parallel nodes (run with -race)
+func TestExecutionContextConcurrentAccess(t *testing.T) {
+	ctx := NewExecutionContext("test-workflow")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("block%d", i)
+			ctx.SetResult(id, map[string]interface{}{"value": i})
+			ctx.SetVar(id, i)
+			_ = ctx.Results()
+			_ = ctx.Variables()
+			if _, err := ResolveVariables("{{ $node."+id+".value }}", ctx); err != nil {
+				t.Errorf("resolve %s: %v", id, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(ctx.Results()) != 20 || len(ctx.Variables()) != 20 {
+		t.Errorf("Expected 20 results and variables, got %d and %d", len(ctx.Results()), len(ctx.Variables()))
+	}
+}
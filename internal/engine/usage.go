@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// usageMeter counts the resources an execution uses while it runs, so load can
+// be attributed to the workflows (and teams) causing it. The runner carries it
+// in the run's ctx; the code using a resource adds to it, and the runner saves
+// the totals with the execution's outcome. A nil meter counts nothing, so code
+// run outside an execution needs no checks.
+type usageMeter struct {
+	nodes       atomic.Int64
+	cpuTime     atomic.Int64 // nanoseconds
+	bytesStored atomic.Int64
+	httpCalls   atomic.Int64
+}
+
+type usageKey struct{}
+
+// withUsage returns a context under which resource usage is counted by u.
+func withUsage(ctx context.Context, u *usageMeter) context.Context {
+	return context.WithValue(ctx, usageKey{}, u)
+}
+
+// usageFrom returns the meter of the execution running under ctx, nil if none.
+func usageFrom(ctx context.Context) *usageMeter {
+	u, _ := ctx.Value(usageKey{}).(*usageMeter)
+	return u
+}
+
+// addNode counts a node that ran.
+func (u *usageMeter) addNode() {
+	if u != nil {
+		u.nodes.Add(1)
+	}
+}
+
+// addCPUTime counts the user and system CPU time of a Bun process.
+func (u *usageMeter) addCPUTime(d time.Duration) {
+	if u != nil {
+		u.cpuTime.Add(int64(d))
+	}
+}
+
+// addBytesStored counts node inputs and results written to storage.
+func (u *usageMeter) addBytesStored(n int) {
+	if u != nil {
+		u.bytesStored.Add(int64(n))
+	}
+}
+
+// addHTTPCall counts a request to an external service, every attempt and page included.
+func (u *usageMeter) addHTTPCall() {
+	if u != nil {
+		u.httpCalls.Add(1)
+	}
+}
+
+// total returns what the meter counted.
+func (u *usageMeter) total() storage.ExecutionUsage {
+	if u == nil {
+		return storage.ExecutionUsage{}
+	}
+	return storage.ExecutionUsage{
+		Nodes:       int(u.nodes.Load()),
+		CPUTimeMS:   time.Duration(u.cpuTime.Load()).Milliseconds(),
+		BytesStored: u.bytesStored.Load(),
+		HTTPCalls:   int(u.httpCalls.Load()),
+	}
+}
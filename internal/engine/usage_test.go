@@ -0,0 +1,49 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// TestWorkflowRunner_RecordsUsage verifies that a run saves the nodes it ran,
+// the bytes of their saved inputs and results and its external HTTP calls.
+func TestWorkflowRunner_RecordsUsage(t *testing.T) {
+	srv := paginatedAPI(t, 5)
+	workflow := engine.Workflow{
+		ID: "usage-wf",
+		Nodes: map[string]engine.Node{
+			"list": {ID: "list", Type: engine.NodeTypeHTTPRequest, Config: map[string]interface{}{
+				"url":        srv.URL + "/link",
+				"pagination": map[string]interface{}{"type": "link"},
+			}},
+			"set": {ID: "set", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "x", "value": 1.0}},
+		},
+		Edges: []engine.Edge{{ID: "e1", Source: "list", Target: "set"}},
+	}
+	store := createTestStorage(t)
+	ctx := context.Background()
+
+	runner := engine.NewWorkflowRunner(engine.NewExecutionContext(workflow.ID), t.TempDir(), store, nil)
+	execID, err := runner.CreateExecution(ctx, workflow.ID)
+	if err != nil {
+		t.Fatalf("CreateExecution failed: %v", err)
+	}
+	if err := runner.Run(ctx, workflow); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	exec, err := store.GetExecution(ctx, execID)
+	if err != nil {
+		t.Fatalf("failed to get execution: %v", err)
+	}
+	if exec.Status != storage.ExecutionStatusCompleted {
+		t.Fatalf("expected the execution to complete, got %s", exec.Status)
+	}
+	usage := exec.Usage
+	if usage.Nodes != 2 || usage.HTTPCalls != 3 || usage.BytesStored == 0 || usage.CPUTimeMS != 0 {
+		t.Errorf("expected 2 nodes, 3 HTTP calls, stored bytes and no Bun CPU time, got %+v", usage)
+	}
+}
@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Validate checks the workflow graph for structural problems that would make it
+// fail at run time: missing or mismatched node IDs, untyped nodes, edges pointing
+// at unknown nodes, graphs with no entry point and malformed settings.
+// Edge ports are checked by ValidatePorts.
+// All problems are reported together, joined with errors.Join.
+func (w *Workflow) Validate() error {
+	var errs []error
+
+	if len(w.Nodes) == 0 {
+		return errors.New("workflow has no nodes")
+	}
+
+	// Iterate in a stable order so the report is deterministic
+	ids := make([]string, 0, len(w.Nodes))
+	for id := range w.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		node := w.Nodes[id]
+		if node.ID != "" && node.ID != id {
+			errs = append(errs, fmt.Errorf("node %q: id field %q does not match its key", id, node.ID))
+		}
+		if node.Type == "" {
+			errs = append(errs, fmt.Errorf("node %q: missing type", id))
+		}
+		if _, err := ParseVersionConstraint(node.Version); err != nil {
+			errs = append(errs, fmt.Errorf("node %q: %w", id, err))
+		}
+	}
+
+	for i, edge := range w.Edges {
+		name := edge.ID
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		if _, ok := w.Nodes[edge.Source]; !ok {
+			errs = append(errs, fmt.Errorf("edge %s: unknown source node %q", name, edge.Source))
+		}
+		if _, ok := w.Nodes[edge.Target]; !ok {
+			errs = append(errs, fmt.Errorf("edge %s: unknown target node %q", name, edge.Target))
+		}
+	}
+
+	if _, err := w.Timeout(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := w.EdgeMatching(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := w.Timezone(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := w.NodeTimeout(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := w.SaveSuccessData(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(w.FindStartNodes()) == 0 {
+		errs = append(errs, errors.New("workflow has no start node (every node has an incoming edge)"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidatePorts checks that every edge leaving a node through a named port
+// (sourceHandle) uses a port the node's type declares. declared returns the
+// ports of a type, nil when it declares none (see BunRunner.DeclaredPorts).
+// With strict edge matching an edge without a sourceHandle is the default port
+// of a node that declares ports, so that port must exist. A node with
+// continue_on_fail also has ErrorPort. An edge that matches
+// every port of its node is ambiguous when other edges of the node name ports,
+// as those ports then also follow it. All problems are reported together,
+// joined with errors.Join.
+func (w *Workflow) ValidatePorts(declared func(NodeType) ([]string, error)) error {
+	var errs []error
+	mode, _ := w.EdgeMatching() // an invalid mode is reported by Validate
+
+	named := make(map[string][]string) // source node -> ports its edges name
+	for _, edge := range w.Edges {
+		if edge.SourceHandle != "" && !slices.Contains(named[edge.Source], edge.SourceHandle) {
+			named[edge.Source] = append(named[edge.Source], edge.SourceHandle)
+		}
+	}
+
+	for i, edge := range w.Edges {
+		node, ok := w.Nodes[edge.Source]
+		if !ok {
+			continue
+		}
+		name := edge.ID
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		ports, err := declared(node.Type)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("edge %s: %w", name, err))
+			continue
+		}
+		// A node that continues on failure may also route to the error port
+		if enabled, _ := node.Config["continue_on_fail"].(bool); enabled && ports != nil && !slices.Contains(ports, ErrorPort) {
+			ports = append(slices.Clip(ports), ErrorPort)
+		}
+		handle := edge.SourceHandle
+		if handle == "" && mode == EdgeMatchingStrict && ports != nil {
+			handle = DefaultPort
+		}
+		switch {
+		case handle == "" && len(named[edge.Source]) > 0:
+			errs = append(errs, fmt.Errorf("edge %s: ambiguous: without a sourceHandle it matches every port of node %q, including %s routed by other edges",
+				name, edge.Source, strings.Join(named[edge.Source], ", ")))
+		case handle != "" && ports != nil && !slices.Contains(ports, handle):
+			errs = append(errs, fmt.Errorf("edge %s: node %q (%s) has no output port %q (declared: %s)",
+				name, edge.Source, node.Type, handle, strings.Join(ports, ", ")))
+		}
+	}
+	return errors.Join(errs...)
+}
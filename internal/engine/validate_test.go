@@ -0,0 +1,165 @@
+package engine_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+func TestWorkflowValidate(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		wf := engine.Workflow{
+			Nodes: map[string]engine.Node{
+				"a": {ID: "a", Type: engine.NodeTypeHTTPRequest},
+				"b": {Type: engine.NodeTypeTransform},
+			},
+			Edges:    []engine.Edge{{ID: "e1", Source: "a", Target: "b"}},
+			Settings: &engine.WorkflowSettings{Timeout: "90s"},
+		}
+		if err := wf.Validate(); err != nil {
+			t.Errorf("expected valid workflow, got %v", err)
+		}
+		if d, _ := wf.Timeout(); d.Seconds() != 90 {
+			t.Errorf("expected 90s timeout, got %s", d)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		wf := engine.Workflow{}
+		if err := wf.Validate(); err == nil {
+			t.Error("expected error for workflow without nodes")
+		}
+	})
+
+	t.Run("ReportsAllProblems", func(t *testing.T) {
+		wf := engine.Workflow{
+			Nodes: map[string]engine.Node{
+				"a": {ID: "x", Type: engine.NodeTypeHTTPRequest},
+				"b": {ID: "b", Version: "soonish"},
+			},
+			Edges: []engine.Edge{
+				{ID: "e1", Source: "a", Target: "b"},
+				{ID: "e2", Source: "b", Target: "a"},
+				{ID: "e3", Source: "a", Target: "missing"},
+			},
+			Settings: &engine.WorkflowSettings{Timeout: "soon", Timezone: "Mars/Olympus", NodeTimeout: "0s", SaveSuccessData: "some"},
+		}
+		err := wf.Validate()
+		if err == nil {
+			t.Fatal("expected validation error")
+		}
+		for _, want := range []string{
+			`node "a": id field "x"`,
+			`node "b": missing type`,
+			`node "b": invalid version constraint "soonish"`,
+			`edge e3: unknown target node "missing"`,
+			`invalid settings.timeout "soon"`,
+			`invalid settings.timezone "Mars/Olympus"`,
+			`invalid settings.node_timeout "0s"`,
+			`invalid settings.save_success_data "some"`,
+			"no start node",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected error to mention %q, got:\n%v", want, err)
+			}
+		}
+	})
+}
+
+func TestWorkflowValidatePorts(t *testing.T) {
+	blocksDir := t.TempDir()
+	writeBlock(t, blocksDir, "acme/route.ts", "")
+	writeBlock(t, blocksDir, "acme/route.manifest.json", `{"id":"acme/route","ports":["low","high"]}`)
+	writeBlock(t, blocksDir, "acme/broken.ts", "")
+	writeBlock(t, blocksDir, "acme/broken.manifest.json", `{"ports":`)
+	writeBlock(t, blocksDir, "acme/open.ts", "")
+	runner := engine.NewBunRunner(blocksDir)
+
+	wf := engine.Workflow{
+		Nodes: map[string]engine.Node{
+			"cond":   {Type: engine.NodeTypeCondition},
+			"route":  {Type: "acme/route"},
+			"broken": {Type: "acme/broken"},
+			"open":   {Type: "acme/open"},
+			"code":   {Type: engine.NodeTypeCustomCode},
+			"end":    {Type: engine.NodeTypeTransform},
+		},
+		Edges: []engine.Edge{
+			{ID: "e1", Source: "cond", Target: "end", SourceHandle: "true"},
+			{ID: "e2", Source: "cond", Target: "end", SourceHandle: "maybe"},
+			{ID: "e3", Source: "route", Target: "end", SourceHandle: "high"},
+			{ID: "e4", Source: "route", Target: "end", SourceHandle: "medium"},
+			{ID: "e5", Source: "broken", Target: "end", SourceHandle: "x"},
+			{ID: "e6", Source: "open", Target: "end", SourceHandle: "anything"},
+			{ID: "e7", Source: "code", Target: "end", SourceHandle: "anything"},
+		},
+	}
+	err := wf.ValidatePorts(runner.DeclaredPorts)
+	if err == nil {
+		t.Fatal("expected port validation error")
+	}
+	for _, want := range []string{
+		`edge e2: node "cond" (std/condition) has no output port "maybe" (declared: true, false)`,
+		`edge e4: node "route" (acme/route) has no output port "medium" (declared: low, high)`,
+		"edge e5: invalid block manifest broken.manifest.json",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %q, got:\n%v", want, err)
+		}
+	}
+	for _, unwanted := range []string{"edge e1:", "edge e3:", "edge e6:", "edge e7:"} {
+		if strings.Contains(err.Error(), unwanted) {
+			t.Errorf("expected no error for %s, got:\n%v", unwanted, err)
+		}
+	}
+}
+
+func TestWorkflowValidatePorts_EdgeMatching(t *testing.T) {
+	runner := engine.NewBunRunner(t.TempDir())
+	wf := engine.Workflow{
+		Nodes: map[string]engine.Node{
+			"http": {Type: engine.NodeTypeHTTPRequest},
+			"cond": {Type: engine.NodeTypeCondition},
+			"end":  {Type: engine.NodeTypeTransform},
+		},
+		Edges: []engine.Edge{
+			{ID: "e1", Source: "http", Target: "end"},
+			{ID: "e2", Source: "http", Target: "end", SourceHandle: "error"},
+			{ID: "e3", Source: "cond", Target: "end"},
+			{ID: "e4", Source: "end", Target: "http"},
+		},
+	}
+
+	t.Run("Lenient", func(t *testing.T) {
+		err := wf.ValidatePorts(runner.DeclaredPorts)
+		if err == nil || !strings.Contains(err.Error(), `edge e1: ambiguous: without a sourceHandle it matches every port of node "http", including error`) {
+			t.Errorf("expected e1 to be reported as ambiguous, got %v", err)
+		}
+		if strings.Contains(err.Error(), "edge e3:") || strings.Contains(err.Error(), "edge e4:") {
+			t.Errorf("expected wildcard edges of other nodes to pass, got %v", err)
+		}
+	})
+
+	t.Run("Strict", func(t *testing.T) {
+		strict := wf
+		strict.Settings = &engine.WorkflowSettings{EdgeMatching: engine.EdgeMatchingStrict}
+		err := strict.ValidatePorts(runner.DeclaredPorts)
+		if err == nil || !strings.Contains(err.Error(), `edge e3: node "cond" (std/condition) has no output port "default"`) {
+			t.Errorf("expected e3 to need a port, got %v", err)
+		}
+		for _, unwanted := range []string{"edge e1:", "edge e2:", "edge e4:"} {
+			if strings.Contains(err.Error(), unwanted) {
+				t.Errorf("expected no error for %s, got:\n%v", unwanted, err)
+			}
+		}
+	})
+
+	t.Run("InvalidMode", func(t *testing.T) {
+		invalid := wf
+		invalid.Settings = &engine.WorkflowSettings{EdgeMatching: "fuzzy"}
+		if err := invalid.Validate(); err == nil || !strings.Contains(err.Error(), `invalid settings.edge_matching "fuzzy"`) {
+			t.Errorf("expected invalid edge_matching error, got %v", err)
+		}
+	})
+}
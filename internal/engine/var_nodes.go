@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// executeNode runs a node for a workflow runner. std/set_var and std/get_var act on
+// the execution context and std/rate_limit and std/dedupe on storage, so they run
+// in-process here; every other node goes to runner. A result routed to a port the
+// node type doesn't declare fails the node.
+func executeNode(ctx context.Context, runner *BunRunner, ectx *ExecutionContext, store storage.Storage, node *Node, input map[string]interface{}) (any, error) {
+	if result, ok := ectx.mockResult(node.ID); ok {
+		return result, nil
+	}
+	if ectx.Mocks != nil {
+		ctx = withMocks(ctx, ectx.Mocks)
+	}
+	var result any
+	var err error
+	switch node.Type {
+	case NodeTypeSetVar:
+		result, err = executeSetVar(ectx, input)
+	case NodeTypeGetVar:
+		result, err = executeGetVar(ectx, input)
+	case NodeTypeRateLimit:
+		result, err = executeRateLimit(ctx, store, input)
+	case NodeTypeDedupe:
+		result, err = executeDedupe(ctx, store, ectx, input)
+	default:
+		result, err = runner.ExecuteNode(ctx, node, input)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := runner.checkPort(node, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// executeSetVar runs a std/set_var node: {"config": {"name": ..., "value": ...}} sets
+// $vars.<name> for the rest of the execution and outputs {"name", "value"}.
+func executeSetVar(ectx *ExecutionContext, input map[string]interface{}) (any, error) {
+	config, _ := input["config"].(map[string]interface{})
+	name, _ := config["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("set_var: config 'name' is required and must be a string")
+	}
+	value, ok := config["value"]
+	if !ok {
+		return nil, fmt.Errorf("set_var: config 'value' is required")
+	}
+
+	ectx.SetVar(name, value)
+	return varResult(name, value), nil
+}
+
+// executeGetVar runs a std/get_var node: {"config": {"name": ..., "default": ...}}
+// outputs the current value of $vars.<name>. The optional default is used when the
+// variable is not set; without one a missing variable fails the node.
+func executeGetVar(ectx *ExecutionContext, input map[string]interface{}) (any, error) {
+	config, _ := input["config"].(map[string]interface{})
+	name, _ := config["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("get_var: config 'name' is required and must be a string")
+	}
+
+	value, ok := ectx.LookupVar(name)
+	if !ok {
+		if value, ok = config["default"]; !ok {
+			return nil, fmt.Errorf("get_var: variable not found: %s", name)
+		}
+	}
+	return varResult(name, value), nil
+}
+
+func varResult(name string, value interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"data": map[string]interface{}{
+			"name":  name,
+			"value": value,
+		},
+		"port": "default",
+	}
+}
@@ -0,0 +1,103 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+// varNodesWorkflow sets $vars.counter, reads it back with get_var and reads an
+// unset variable through its default.
+func varNodesWorkflow(id string) *engine.Workflow {
+	return &engine.Workflow{
+		ID:   id,
+		Name: "Variables",
+		Nodes: map[string]engine.Node{
+			"set": {ID: "set", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "counter", "value": 41.0}},
+			"get": {ID: "get", Type: engine.NodeTypeGetVar, Config: map[string]interface{}{"name": "counter"}},
+			"fallback": {ID: "fallback", Type: engine.NodeTypeGetVar, Config: map[string]interface{}{
+				"name":    "missing",
+				"default": "{{ $vars.counter }}",
+			}},
+		},
+		Edges: []engine.Edge{
+			{ID: "e1", Source: "set", Target: "get"},
+			{ID: "e2", Source: "get", Target: "fallback"},
+		},
+	}
+}
+
+// TestWorkflowRunner_VariableNodes verifies that std/set_var and std/get_var run
+// in-process: set_var updates $vars and get_var outputs the current value.
+func TestWorkflowRunner_VariableNodes(t *testing.T) {
+	store := createTestStorage(t)
+	ectx := engine.NewExecutionContext("vars-wf")
+	runner := engine.NewWorkflowRunner(ectx, t.TempDir(), store, nil)
+
+	if err := runner.Run(context.Background(), *varNodesWorkflow("vars-wf")); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := ectx.GetVar("counter"); got != 41.0 {
+		t.Errorf("expected $vars.counter 41, got %v", got)
+	}
+
+	execs, err := store.ListExecutions(context.Background(), "vars-wf", 1)
+	if err != nil || len(execs) != 1 {
+		t.Fatalf("expected one execution, got %v (%v)", execs, err)
+	}
+	for node, want := range map[string]string{
+		"get":      `"data":{"name":"counter","value":41}`,
+		"fallback": `"data":{"name":"missing","value":41}`,
+	} {
+		raw, err := store.GetNodeResult(context.Background(), execs[0].ID, node)
+		if err != nil {
+			t.Fatalf("expected a result for %s: %v", node, err)
+		}
+		if !strings.Contains(string(raw), want) {
+			t.Errorf("expected %s in %s result %s", want, node, raw)
+		}
+	}
+}
+
+// TestGraphRunner_VariableNodes verifies the same in the graph runner.
+func TestGraphRunner_VariableNodes(t *testing.T) {
+	store := createTestStorage(t)
+	wf := varNodesWorkflow("vars-graph")
+	wfBytes, _ := json.Marshal(wf)
+	store.CreateWorkflow(context.Background(), &storage.Workflow{ID: wf.ID, Name: wf.Name, Definition: wfBytes})
+
+	runner := engine.NewGraphRunner(wf, t.TempDir(), store)
+	if err := runner.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := runner.GetVariables()["counter"]; got != 41.0 {
+		t.Errorf("expected $vars.counter 41, got %v", got)
+	}
+	raw, _ := json.Marshal(runner.GetResults()["get"])
+	if !strings.Contains(string(raw), `"value":41`) {
+		t.Errorf("expected get_var to output 41, got %s", raw)
+	}
+}
+
+// TestWorkflowRunner_GetVarMissing verifies that reading an unset variable without
+// a default fails the node.
+func TestWorkflowRunner_GetVarMissing(t *testing.T) {
+	store := createTestStorage(t)
+	wf := engine.Workflow{
+		ID:   "vars-missing",
+		Name: "Missing variable",
+		Nodes: map[string]engine.Node{
+			"get": {ID: "get", Type: engine.NodeTypeGetVar, Config: map[string]interface{}{"name": "nope"}},
+		},
+	}
+
+	runner := engine.NewWorkflowRunner(engine.NewExecutionContext(wf.ID), t.TempDir(), store, nil)
+	err := runner.Run(context.Background(), wf)
+	if err == nil || !strings.Contains(err.Error(), "variable not found: nope") {
+		t.Fatalf("expected missing variable error, got %v", err)
+	}
+}
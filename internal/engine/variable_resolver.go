@@ -81,8 +81,17 @@ func replaceString(str string, ctx *ExecutionContext) (interface{}, error) {
 // Supports:
 // - $node.ID.data.field - access node results
 // - $vars.name - access user-defined variables
+// - $trigger.field - access the trigger payload (e.g. webhook body)
+// - $workflowStatic.key - access the workflow's data persisted across executions
+// - $globals.name - access server-managed global variables of the selected environment
+// - $credentials.provider:path#field - fetch a secret from a SecretProvider
 // - $error.message - access error info (in catch blocks)
 func getValueByPath(path string, ctx *ExecutionContext) (interface{}, error) {
+	// Secret paths contain dots of their own, so they aren't split like the others
+	if ref, ok := strings.CutPrefix(path, "$credentials."); ok {
+		return resolveCredential(ref, ctx)
+	}
+
 	parts := strings.Split(path, ".")
 	if len(parts) == 0 {
 		return nil, fmt.Errorf("empty path")
@@ -99,8 +108,13 @@ func getValueByPath(path string, ctx *ExecutionContext) (interface{}, error) {
 		if len(parts) < 2 {
 			return nil, fmt.Errorf("$node requires at least node ID: $node.ID")
 		}
-		current = ctx.Results
 		parts = parts[1:] // Skip $node prefix
+		result, exists := ctx.LookupResult(strings.Trim(parts[0], "[]\"'"))
+		if !exists {
+			return nil, fmt.Errorf("key not found: %s", parts[0])
+		}
+		current = nodeResultRoot(result, parts[1:])
+		parts = parts[1:]
 
 	case "$vars":
 		// Access user variables: $vars.counter
@@ -108,7 +122,7 @@ func getValueByPath(path string, ctx *ExecutionContext) (interface{}, error) {
 			return nil, fmt.Errorf("$vars requires variable name: $vars.name")
 		}
 		varName := parts[1]
-		val, exists := ctx.Variables[varName]
+		val, exists := ctx.LookupVar(varName)
 		if !exists {
 			return nil, fmt.Errorf("variable not found: %s", varName)
 		}
@@ -120,6 +134,45 @@ func getValueByPath(path string, ctx *ExecutionContext) (interface{}, error) {
 			return val, nil
 		}
 
+	case "$trigger":
+		// Access the trigger payload: $trigger.body.id (webhook body, CLI --input, etc.)
+		if len(parts) < 2 {
+			return ctx.TriggerData, nil
+		}
+		current = ctx.TriggerData
+		parts = parts[1:]
+
+	case "$workflowStatic":
+		// Access persistent workflow data: $workflowStatic.lastSyncedAt
+		if len(parts) < 2 {
+			data, _ := ctx.StaticData()
+			return data, nil
+		}
+		val, exists := ctx.LookupStatic(parts[1])
+		if !exists {
+			return nil, fmt.Errorf("static data key not found: %s", parts[1])
+		}
+		if len(parts) == 2 {
+			return val, nil
+		}
+		current = val
+		parts = parts[2:]
+
+	case "$globals":
+		// Access global variables: $globals.apiBaseUrl
+		if len(parts) < 2 {
+			return ctx.Globals(), nil
+		}
+		val, exists := ctx.LookupGlobal(parts[1])
+		if !exists {
+			return nil, fmt.Errorf("global variable not found: %s", parts[1])
+		}
+		if len(parts) == 2 {
+			return val, nil
+		}
+		current = val
+		parts = parts[2:]
+
 	case "$error":
 		// Access error info: $error.message (for catch blocks)
 		// TODO: Implement error context when adding try/catch
@@ -128,7 +181,12 @@ func getValueByPath(path string, ctx *ExecutionContext) (interface{}, error) {
 	default:
 		// Legacy support: direct node ID without $node prefix
 		// e.g., "block_1.data.field" -> same as "$node.block_1.data.field"
-		current = ctx.Results
+		result, exists := ctx.LookupResult(strings.Trim(root, "[]\"'"))
+		if !exists {
+			return nil, fmt.Errorf("key not found: %s", root)
+		}
+		current = nodeResultRoot(result, parts[1:])
+		parts = parts[1:]
 	}
 
 	// Traverse the path
@@ -150,3 +208,31 @@ func getValueByPath(path string, ctx *ExecutionContext) (interface{}, error) {
 
 	return current, nil
 }
+
+// nodeResultRoot returns what the path after a node's ID is looked up in: the
+// node's result, or the data of a block result {data, port} lacking the
+// path's first key, so that $node.X.field keeps reading $node.X.data.field as
+// it did when GraphRunner stored the data alone.
+func nodeResultRoot(result interface{}, rest []string) interface{} {
+	if len(rest) == 0 {
+		return result
+	}
+	if resMap, ok := result.(map[string]interface{}); ok {
+		if _, exists := resMap[strings.Trim(rest[0], "[]\"'")]; !exists {
+			if data, ok := blockData(result); ok {
+				return data
+			}
+		}
+	}
+	return result
+}
+
+// blockData returns the data of a block result {data, port}.
+func blockData(result interface{}) (interface{}, bool) {
+	resMap, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	data, ok := resMap["data"]
+	return data, ok
+}
@@ -8,7 +8,9 @@ import (
 // Helper function to create ExecutionContext from state map for testing
 func createTestContext(state map[string]interface{}) *ExecutionContext {
 	ctx := NewExecutionContext("test")
-	ctx.Results = state
+	for nodeID, result := range state {
+		ctx.SetResult(nodeID, result)
+	}
 	return ctx
 }
 
@@ -223,6 +225,18 @@ func TestGetValueByPath(t *testing.T) {
 			expected: 5,
 			wantErr:  false,
 		},
+		{
+			name:     "data field without data",
+			path:     "$node.block1.count",
+			expected: 5,
+			wantErr:  false,
+		},
+		{
+			name:     "data field without data or $node prefix",
+			path:     "block1.user.name",
+			expected: "Bob",
+			wantErr:  false,
+		},
 		{
 			name:     "non-existent key",
 			path:     "$node.block1.data.missing",
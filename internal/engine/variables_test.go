@@ -149,3 +149,79 @@ func TestVariableNotFound(t *testing.T) {
 
 	t.Logf("Correctly caught error: %v", err)
 }
+
+// TestTriggerDataResolution tests the {{ $trigger.* }} syntax in variable resolver.
+func TestTriggerDataResolution(t *testing.T) {
+	execCtx := NewExecutionContext("test")
+	execCtx.TriggerData = map[string]interface{}{
+		"body": map[string]interface{}{"user": "Zaraza"},
+	}
+
+	result, err := ResolveVariables("Hello, {{ $trigger.body.user }}!", execCtx)
+	if err != nil {
+		t.Fatalf("ResolveVariables failed: %v", err)
+	}
+	if result != "Hello, Zaraza!" {
+		t.Errorf("Expected %q, got %q", "Hello, Zaraza!", result)
+	}
+
+	if _, err := ResolveVariables("{{ $trigger.body.missing }}", execCtx); err == nil {
+		t.Error("Expected error for missing trigger field")
+	}
+}
+
+func TestWorkflowStaticResolution(t *testing.T) {
+	execCtx := NewExecutionContext("test")
+	execCtx.LoadStatic(map[string]interface{}{
+		"lastSyncedAt": "2024-01-01T00:00:00Z",
+		"cursor":       map[string]interface{}{"page": float64(3)},
+	})
+
+	result, err := ResolveVariables("since={{ $workflowStatic.lastSyncedAt }}", execCtx)
+	if err != nil {
+		t.Fatalf("ResolveVariables failed: %v", err)
+	}
+	if result != "since=2024-01-01T00:00:00Z" {
+		t.Errorf("Expected %q, got %q", "since=2024-01-01T00:00:00Z", result)
+	}
+
+	page, err := ResolveVariables("{{ $workflowStatic.cursor.page }}", execCtx)
+	if err != nil || page != float64(3) {
+		t.Errorf("Expected page 3, got %v (%v)", page, err)
+	}
+
+	if _, err := ResolveVariables("{{ $workflowStatic.missing }}", execCtx); err == nil {
+		t.Error("Expected error for missing static key")
+	}
+
+	// Loaded data is not a change; SetStatic is
+	if _, changed := execCtx.StaticData(); changed {
+		t.Error("Expected loaded static data to be unchanged")
+	}
+	execCtx.SetStatic("lastSyncedAt", "2024-02-01T00:00:00Z")
+	if data, changed := execCtx.StaticData(); !changed || data["lastSyncedAt"] != "2024-02-01T00:00:00Z" {
+		t.Errorf("Expected changed static data, got %v (changed=%v)", data, changed)
+	}
+}
+
+func TestGlobalsResolution(t *testing.T) {
+	execCtx := NewExecutionContext("test")
+	execCtx.SetGlobals(map[string]interface{}{
+		"apiBaseUrl": "https://staging.example.com",
+		"limits":     map[string]interface{}{"pageSize": float64(50)},
+	})
+
+	url, err := ResolveVariables("{{ $globals.apiBaseUrl }}/users", execCtx)
+	if err != nil || url != "https://staging.example.com/users" {
+		t.Errorf("Expected resolved URL, got %v (%v)", url, err)
+	}
+
+	size, err := ResolveVariables("{{ $globals.limits.pageSize }}", execCtx)
+	if err != nil || size != float64(50) {
+		t.Errorf("Expected page size 50, got %v (%v)", size, err)
+	}
+
+	if _, err := ResolveVariables("{{ $globals.missing }}", execCtx); err == nil {
+		t.Error("Expected error for missing global variable")
+	}
+}
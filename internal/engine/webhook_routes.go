@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WebhookRoutePrefix is the path webhook triggers are served under.
+const WebhookRoutePrefix = "/api/webhooks/"
+
+// maxWebhookPathLength bounds the custom paths of webhook triggers.
+const maxWebhookPathLength = 200
+
+// WebhookRunner is implemented by the trigger runners called over HTTP. While
+// one is registered, the manager routes WebhookRoutePrefix+<path> to it for
+// each of its WebhookPaths; they are released when it is unregistered.
+type WebhookRunner interface {
+	TriggerRunner
+	// WebhookPaths returns the paths the trigger is reached at, relative to
+	// WebhookRoutePrefix: its ID, and its custom path if it has one.
+	WebhookPaths() []string
+}
+
+// WebhookRoute is a path a registered trigger is served at.
+type WebhookRoute struct {
+	// Path is relative to WebhookRoutePrefix.
+	Path      string `json:"path"`
+	TriggerID string `json:"trigger_id"`
+}
+
+// WebhookPath returns the custom path of a webhook trigger config,
+// {"path": "orders/created"}, without surrounding slashes; empty when the
+// config sets none. Paths are made of segments of letters, digits and
+// "-._~", and are served under WebhookRoutePrefix next to the trigger IDs.
+func WebhookPath(config map[string]interface{}) (string, error) {
+	raw, ok := config["path"]
+	if !ok || raw == nil {
+		return "", nil
+	}
+	path, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("path must be a string")
+	}
+	path = strings.Trim(path, "/")
+	if len(path) > maxWebhookPathLength {
+		return "", fmt.Errorf("path must be at most %d characters", maxWebhookPathLength)
+	}
+	if path == "" {
+		return "", nil
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", fmt.Errorf("invalid path %q: empty, . and .. segments are not allowed", path)
+		}
+		for _, c := range segment {
+			if !isWebhookPathChar(c) {
+				return "", fmt.Errorf("invalid path %q: %q is not allowed", path, c)
+			}
+		}
+	}
+	return path, nil
+}
+
+func isWebhookPathChar(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-._~", c)
+}
+
+// SetPath makes the trigger also reachable at WebhookRoutePrefix+path (see WebhookPath).
+func (wt *WebhookTrigger) SetPath(path string) {
+	wt.path = path
+}
+
+// WebhookPaths returns the trigger's ID and custom path.
+func (wt *WebhookTrigger) WebhookPaths() []string {
+	if wt.path == "" {
+		return []string{wt.id}
+	}
+	return []string{wt.id, wt.path}
+}
+
+// WebhookPaths returns the trigger's ID and the custom path of its config.
+// TS triggers receive the calls to them through Invoke.
+func (tr *TSTriggerRunner) WebhookPaths() []string {
+	path, _ := WebhookPath(tr.config) // checked when the trigger is saved
+	if path == "" {
+		return []string{tr.id}
+	}
+	return []string{tr.id, path}
+}
+
+// addRoutes reserves the webhook paths of trigger, if it is a WebhookRunner.
+// Nothing is reserved when another trigger holds one of them. tm.mu must be held.
+func (tm *TriggerManager) addRoutes(trigger TriggerRunner) error {
+	runner, ok := trigger.(WebhookRunner)
+	if !ok {
+		return nil
+	}
+	paths := runner.WebhookPaths()
+	for _, path := range paths {
+		if owner, taken := tm.routes[path]; taken && owner != trigger.ID() {
+			return fmt.Errorf("webhook path %q is taken by trigger %s", path, owner)
+		}
+	}
+	if tm.routes == nil {
+		tm.routes = make(map[string]string)
+	}
+	for _, path := range paths {
+		tm.routes[path] = trigger.ID()
+	}
+	return nil
+}
+
+// removeRoutes releases the webhook paths of a trigger; tm.mu must be held.
+func (tm *TriggerManager) removeRoutes(triggerID string) {
+	for path, owner := range tm.routes {
+		if owner == triggerID {
+			delete(tm.routes, path)
+		}
+	}
+}
+
+// WebhookTarget returns the registered trigger served at path, relative to
+// WebhookRoutePrefix.
+func (tm *TriggerManager) WebhookTarget(path string) (TriggerRunner, bool) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	triggerID, ok := tm.routes[strings.Trim(path, "/")]
+	if !ok {
+		return nil, false
+	}
+	trigger, ok := tm.triggers[triggerID]
+	return trigger, ok
+}
+
+// WebhookRoutes returns the webhook routes of the registered triggers, sorted by path.
+func (tm *TriggerManager) WebhookRoutes() []WebhookRoute {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	routes := make([]WebhookRoute, 0, len(tm.routes))
+	for path, triggerID := range tm.routes {
+		routes = append(routes, WebhookRoute{Path: path, TriggerID: triggerID})
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Path < routes[j].Path })
+	return routes
+}
@@ -0,0 +1,88 @@
+package engine_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+func TestWebhookPath(t *testing.T) {
+	tests := []struct {
+		config map[string]interface{}
+		want   string
+		ok     bool
+	}{
+		{nil, "", true},
+		{map[string]interface{}{"path": "/orders/created/"}, "orders/created", true},
+		{map[string]interface{}{"path": "github~push_v2.json"}, "github~push_v2.json", true},
+		{map[string]interface{}{"path": "orders//created"}, "", false},
+		{map[string]interface{}{"path": "../admin"}, "", false},
+		{map[string]interface{}{"path": "orders?x=1"}, "", false},
+		{map[string]interface{}{"path": 42.0}, "", false},
+	}
+	for _, tt := range tests {
+		got, err := engine.WebhookPath(tt.config)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("WebhookPath(%v): expected %q (ok %v), got %q, %v", tt.config, tt.want, tt.ok, got, err)
+		}
+	}
+}
+
+// TestTriggerManager_WebhookRoutes verifies that webhook triggers are routed
+// while they are registered, at their ID and custom path.
+func TestTriggerManager_WebhookRoutes(t *testing.T) {
+	tm := engine.NewTriggerManager(createTestStorage(t), t.TempDir(), nil, engine.NewWorkerPool(1))
+	defer tm.StopAll()
+
+	orders := engine.NewWebhookTrigger("tr-orders", "wf-1", tm)
+	orders.SetPath("orders/created")
+	if err := tm.Register(orders); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := tm.Register(engine.NewIntervalTrigger("tr-interval", "wf-1", time.Hour, tm)); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	for _, path := range []string{"tr-orders", "orders/created", "/orders/created/"} {
+		if trigger, ok := tm.WebhookTarget(path); !ok || trigger.ID() != "tr-orders" {
+			t.Errorf("expected %q to reach tr-orders, got %v %v", path, trigger, ok)
+		}
+	}
+	if _, ok := tm.WebhookTarget("tr-interval"); ok {
+		t.Error("expected interval triggers not to be routed")
+	}
+	if routes := tm.WebhookRoutes(); len(routes) != 2 || routes[0].Path != "orders/created" || routes[1].Path != "tr-orders" {
+		t.Errorf("unexpected routes %+v", routes)
+	}
+
+	// A taken path keeps another trigger from starting
+	clash := engine.NewWebhookTrigger("tr-clash", "wf-1", tm)
+	clash.SetPath("orders/created")
+	if err := tm.Register(clash); err == nil {
+		t.Fatal("expected a clashing path to be refused")
+	}
+	if _, ok := tm.WebhookTarget("tr-clash"); ok {
+		t.Error("expected nothing of a refused trigger to be routed")
+	}
+
+	// Restarting moves the routes, unregistering releases them
+	moved := engine.NewWebhookTrigger("tr-orders", "wf-1", tm)
+	moved.SetPath("orders/new")
+	if err := tm.Restart(moved); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	if _, ok := tm.WebhookTarget("orders/created"); ok {
+		t.Error("expected the old path to be released on restart")
+	}
+	if _, ok := tm.WebhookTarget("orders/new"); !ok {
+		t.Error("expected the new path to be routed")
+	}
+	tm.Unregister("tr-orders")
+	if routes := tm.WebhookRoutes(); len(routes) != 0 {
+		t.Errorf("expected no routes after unregistering, got %+v", routes)
+	}
+	if err := tm.Register(clash); err != nil {
+		t.Errorf("expected the released path to be free, got %v", err)
+	}
+}
@@ -3,19 +3,71 @@ package engine
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"time"
 
 	"github.com/conv3n/conv3n/internal/storage"
+	"github.com/conv3n/conv3n/internal/telemetry"
 )
 
-// WorkflowRunner orchestrates the execution of a workflow.
-// Deprecated: Use GraphRunner for new graph-based workflows.
+// WorkflowRunner executes workflows. It is the engine's single execution path:
+// runs started through the API, triggers and the CLI, error workflows, runs
+// resumed after a delay or signal (DelayScheduler) and runs resumed from their
+// last checkpoint (ResumeExecution) all go through runGraph, and GraphRunner
+// wraps it. Features extend runs through these points rather than a runner of
+// their own, so that they apply to every run:
+//   - the runner's settings: SetEventBus, SetDelayScheduler, SetQuotas,
+//     SetHeartbeatInterval and SetCheckpointInterval
+//   - the ExecutionContext a run starts with: trigger data, environment,
+//     test mode, mocks and the like
+//   - node types: executeNode runs those implemented in the engine and
+//     BunRunner.ExecuteNode the others
+//   - the events published on the EventBus, to observe runs
+//   - NodeHooks registered with RegisterNodeHooks, run around every node
 type WorkflowRunner struct {
 	bunRunner    *BunRunner
 	stateManager *StateManager
 	storage      storage.Storage
 	registry     *ExecutionRegistry // Track active executions for cancellation
+	events       *EventBus          // Optional lifecycle event publisher
+	delays       *DelayScheduler    // Optional; long delay nodes suspend the execution
+	quotas       *Quotas            // Optional; limits the executions workflows start
+
+	heartbeatInterval  time.Duration
+	checkpointInterval time.Duration
+	lastCheckpoint     time.Time
+	// errorRun is set for a run of an error workflow, whose failure starts no
+	// error workflow in turn
+	errorRun bool
+	// defaultNodeTimeout bounds nodes that set no timeout of their own, nor
+	// their workflow one; zero leaves them unbounded
+	defaultNodeTimeout time.Duration
+
+	// Set by CreateExecution for the next Run; stopped is cancelled when the
+	// execution is stopped through the registry
+	executionID string
+	requestID   string
+	stopped     context.Context
+}
+
+// defaultCheckpointInterval is how often a running execution's state is saved
+// at most; nodes finishing in between are covered by the next checkpoint.
+const defaultCheckpointInterval = time.Second
+
+// resumeState is what a run saves to resume its execution from its last node
+// (see WorkflowRunner.ResumeExecution), and keeps once finished.
+type resumeState struct {
+	Version       int                    `json:"version"` // executionStateVersion
+	Results       map[string]interface{} `json:"results"`
+	Variables     map[string]interface{} `json:"variables"`
+	CurrentNodeID string                 `json:"current_node_id"`
+	// Environment is kept so a resumed execution sees the same $globals
+	Environment string `json:"environment,omitempty"`
+	// Pending holds the nodes of other fan-out branches still to run after CurrentNodeID
+	Pending []string `json:"pending,omitempty"`
 }
 
 // NewWorkflowRunner creates a new runner for a specific execution context.
@@ -25,58 +77,428 @@ func NewWorkflowRunner(ctx *ExecutionContext, blocksDir string, store storage.St
 		stateManager: NewStateManager(ctx),
 		storage:      store,
 		registry:     registry,
+
+		heartbeatInterval:  defaultHeartbeatInterval,
+		checkpointInterval: defaultCheckpointInterval,
 	}
 }
 
-// Run executes the workflow using the new graph-based engine.
-// Automatically detects workflow format and uses appropriate execution strategy.
+// SetEventBus makes the runner publish execution and node events to bus.
+func (wr *WorkflowRunner) SetEventBus(bus *EventBus) {
+	wr.events = bus
+}
+
+// SetDelayScheduler makes delay nodes of at least s.MinDuration suspend the
+// execution instead of sleeping: Run returns, releasing the worker, and s resumes
+// the execution once the delay is over. Without a scheduler delays sleep in-process.
+func (wr *WorkflowRunner) SetDelayScheduler(s *DelayScheduler) {
+	wr.delays = s
+}
+
+// SetQuotas makes CreateExecution refuse to start executions of workflows over
+// their quota in q, with a *QuotaExceededError. Test runs aren't limited.
+func (wr *WorkflowRunner) SetQuotas(q *Quotas) {
+	wr.quotas = q
+}
+
+// SetHeartbeatInterval sets how often the running execution records a heartbeat.
+func (wr *WorkflowRunner) SetHeartbeatInterval(d time.Duration) {
+	wr.heartbeatInterval = d
+}
+
+// SetCheckpointInterval sets how often the state of the running execution is
+// saved at most, for ResumeExecution after a crash. Zero saves it after every node.
+func (wr *WorkflowRunner) SetCheckpointInterval(d time.Duration) {
+	wr.checkpointInterval = d
+}
+
+// SetDefaultNodeTimeout bounds how long a node runs when neither its
+// config.timeout_ms nor the workflow's settings.node_timeout sets a limit.
+// Zero, the default, leaves such nodes unbounded.
+func (wr *WorkflowRunner) SetDefaultNodeTimeout(d time.Duration) {
+	wr.defaultNodeTimeout = d
+}
+
+// CreateExecution creates the execution record the next Run executes and
+// registers it for cancellation, so that a caller running the workflow in the
+// background can hand out its ID right away. Run creates one when it wasn't.
+func (wr *WorkflowRunner) CreateExecution(ctx context.Context, workflowID string) (string, error) {
+	if !wr.stateManager.ctx.Test {
+		if err := wr.quotas.Check(ctx, workflowID, time.Now()); err != nil {
+			return "", err
+		}
+	}
+	var execID string
+	err := storage.WithTx(ctx, wr.storage, func(tx storage.Tx) error {
+		var err error
+		if execID, err = tx.CreateExecution(ctx, workflowID); err != nil {
+			return err
+		}
+		if wr.stateManager.ctx.Test {
+			if err := tx.MarkTestExecution(ctx, execID); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+		if wr.stateManager.ctx.Canary {
+			if err := tx.MarkCanaryExecution(ctx, execID); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+		saveTriggerData(ctx, tx, execID, wr.stateManager.ctx.TriggerData)
+		wr.requestID = saveRequestID(ctx, tx, execID, workflowID)
+		saveLabels(ctx, tx, execID)
+		if key := wr.stateManager.ctx.IdempotencyKey; key != "" {
+			return tx.SetIdempotencyKeyExecution(ctx, wr.stateManager.ctx.TriggerID, key, execID)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create execution record: %w", err)
+	}
+	wr.executionID = execID
+	wr.register(execID, workflowID)
+	return execID, nil
+}
+
+// register makes the registry list execID and stop it by cancelling wr.stopped.
+func (wr *WorkflowRunner) register(execID, workflowID string) {
+	if wr.registry == nil {
+		wr.stopped = context.Background()
+		return
+	}
+	stopped, cancel := context.WithCancel(context.Background())
+	wr.stopped = stopped
+	wr.registry.RegisterActive(ActiveExecution{
+		ExecutionID: execID,
+		WorkflowID:  workflowID,
+		TriggerID:   wr.stateManager.ctx.TriggerID,
+		StartedAt:   time.Now(),
+	}, cancel)
+}
+
+// Run executes the workflow from its first start node, following edges by the
+// output port each node routes to.
 func (wr *WorkflowRunner) Run(ctx context.Context, workflow Workflow) error {
 	log.Printf("Starting workflow: %s (%s)", workflow.Name, workflow.ID)
 
 	// Use graph-based execution if workflow has nodes
 	if len(workflow.Nodes) > 0 {
-		return wr.runGraph(ctx, workflow)
+		return wr.runGraph(ctx, workflow, "", nil)
 	}
 
 	// No nodes found - workflow might be empty or invalid
-	return fmt.Errorf("workflow has no nodes to execute")
+	err := fmt.Errorf("workflow has no nodes to execute")
+	if wr.executionID != "" {
+		msg := err.Error()
+		saveCtx, cancel := storage.Detach(ctx)
+		wr.storage.TransitionExecutionStatus(saveCtx, wr.executionID, storage.ExecutionStatusRunning, storage.ExecutionStatusFailed, nil, &msg)
+		cancel()
+		if wr.registry != nil {
+			wr.registry.Unregister(wr.executionID)
+		}
+	}
+	return err
 }
 
-// runGraph executes the workflow using pointer-based graph traversal.
-func (wr *WorkflowRunner) runGraph(ctx context.Context, workflow Workflow) error {
-	// Create execution record
-	execID, err := wr.storage.CreateExecution(ctx, workflow.ID)
+// ResumeExecution continues the running execution executionID of workflow from
+// its last checkpoint, after the process running it was lost. Nodes whose
+// result was saved before are not run again: they keep their result and the
+// run follows the port it routed to.
+func (wr *WorkflowRunner) ResumeExecution(ctx context.Context, workflow Workflow, executionID string) error {
+	exec, err := wr.storage.GetExecution(ctx, executionID)
+	if err != nil {
+		return fmt.Errorf("failed to get execution %s: %w", executionID, err)
+	}
+	if exec.Status != storage.ExecutionStatusRunning {
+		return fmt.Errorf("execution %s is %s, not running", executionID, exec.Status)
+	}
+	if len(exec.State) == 0 {
+		return fmt.Errorf("execution %s has no saved state", executionID)
+	}
+	state, err := decodeResumeState(exec.State)
 	if err != nil {
-		return fmt.Errorf("failed to create execution record: %w", err)
+		return fmt.Errorf("failed to parse execution state for %s: %w", executionID, err)
+	}
+	if state.CurrentNodeID == "" {
+		return fmt.Errorf("execution %s has empty current_node_id", executionID)
+	}
+	if workflow.GetNode(state.CurrentNodeID) == nil {
+		return fmt.Errorf("node %s not found in workflow %s", state.CurrentNodeID, workflow.ID)
+	}
+
+	// A checkpoint holds no results, and nodes may have finished after it
+	if state.Results == nil {
+		state.Results = make(map[string]interface{})
+	}
+	saved, err := wr.storage.ListNodeResults(ctx, executionID)
+	if err != nil {
+		return fmt.Errorf("failed to load node results for %s: %w", executionID, err)
+	}
+	for _, nr := range saved {
+		if _, ok := state.Results[nr.NodeID]; ok {
+			continue
+		}
+		var data interface{}
+		if err := json.Unmarshal(nr.Result, &data); err != nil {
+			return fmt.Errorf("failed to parse result of node %s: %w", nr.NodeID, err)
+		}
+		state.Results[nr.NodeID] = data
+	}
+
+	nodes, err := wr.storage.ListNodeExecutions(ctx, executionID)
+	if err != nil {
+		return fmt.Errorf("failed to load node executions for %s: %w", executionID, err)
+	}
+	ports := make(map[string]string, len(nodes))
+	for _, ne := range nodes {
+		ports[ne.NodeID] = ne.Port
+	}
+
+	ectx := wr.stateManager.ctx
+	ectx.ExecutionID = executionID
+	ectx.Restore(state.Results, state.Variables)
+	ectx.Environment = state.Environment
+	if err := loadTriggerData(exec, ectx); err != nil {
+		return err
+	}
+	return wr.runGraph(ctx, workflow, executionID, &resumePoint{checkpoint: &state, startedAt: exec.StartedAt, ports: ports})
+}
+
+// resumePoint is where runGraph continues an execution started earlier: after
+// the delay or enqueue node it was suspended in, or from the last checkpoint of
+// a run that was interrupted.
+type resumePoint struct {
+	suspended  *suspendedState
+	checkpoint *resumeState
+	startedAt  time.Time
+	// ports holds the port each node of a checkpointed run routed to, as
+	// recorded when it finished; a node without one follows every edge
+	ports map[string]string
+}
+
+// runGraph executes the workflow using pointer-based graph traversal. With from
+// set it continues execution execID; otherwise it creates a new execution and
+// starts from the beginning.
+func (wr *WorkflowRunner) runGraph(ctx context.Context, workflow Workflow, execID string, from *resumePoint) error {
+	ctx, span := telemetry.Start(ctx, "workflow.execute", telemetry.SpanKindInternal)
+	defer span.End()
+	span.SetAttr("workflow.id", workflow.ID)
+	span.SetAttr("workflow.name", workflow.Name)
+
+	var resume *suspendedState
+	switch {
+	case from != nil:
+		resume = from.suspended
+		wr.register(execID, workflow.ID)
+	case wr.executionID == "":
+		if _, err := wr.CreateExecution(ctx, workflow.ID); err != nil {
+			span.RecordError(err)
+			return err
+		}
+		fallthrough
+	default:
+		execID = wr.executionID
+		wr.executionID = ""
+	}
+	span.SetAttr("execution.id", execID)
+
+	// Counts what this run uses; saved with its outcome, added to earlier runs'
+	usage := &usageMeter{}
+	ctx = withUsage(ctx, usage)
+
+	// The deferred status write below runs before the execution leaves the registry
+	defer func() {
+		if wr.registry != nil {
+			wr.registry.Unregister(execID)
+		}
+	}()
+
+	if err := loadStaticData(ctx, wr.storage, wr.stateManager.ctx); err != nil {
+		failExecution(ctx, wr.storage, execID, err)
+		span.RecordError(err)
+		return fmt.Errorf("failed to load workflow static data: %w", err)
+	}
+	if resume != nil {
+		// Reapply static data changed before the delay; it is saved when the run completes
+		for key, value := range resume.Static {
+			wr.stateManager.ctx.SetStatic(key, value)
+		}
+	}
+	if err := loadGlobals(ctx, wr.storage, wr.stateManager.ctx); err != nil {
+		failExecution(ctx, wr.storage, execID, err)
+		span.RecordError(err)
+		return fmt.Errorf("failed to load global variables: %w", err)
+	}
+
+	// settings.timeout bounds every run, also one resumed after a delay
+	ctx, cancelTimeout := withRunTimeout(ctx, &workflow)
+	defer cancelTimeout()
+
+	// Stopping the execution cancels ctx, also when it was stopped before Run
+	ctx, cancel := context.WithCancel(ctx)
+	stopWatch := context.AfterFunc(wr.stopped, cancel)
+	if wr.stopped.Err() != nil {
+		cancel() // AfterFunc calls it in a goroutine of its own
+	}
+	defer func() {
+		stopWatch()
+		cancel()
+	}()
+	defer startHeartbeat(ctx, wr.storage, execID, wr.heartbeatInterval)()
+
+	startedAt := time.Now()
+	if from == nil {
+		wr.events.Publish(ExecutionStarted{WorkflowID: workflow.ID, ExecutionID: execID, RequestID: wr.requestID, Time: startedAt})
+	} else {
+		startedAt = from.startedAt
 	}
 
 	var finalStatus = storage.ExecutionStatusCompleted
 	var finalError *string
+	// suspended is set when the run parks in a long delay node
+	var suspended *suspendedState
+	// lastNodeID is the node that finished last, where a resumed run continues
+	var lastNodeID string
 
 	defer func() {
-		stateBytes, _ := json.Marshal(wr.stateManager.ctx.Results)
-		if err := wr.storage.UpdateExecutionStatus(ctx, execID, finalStatus, stateBytes, finalError); err != nil {
+		span.SetAttr("execution.status", string(finalStatus))
+		// The run's ctx may already be cancelled or past its deadline; the outcome must still be saved
+		saveCtx, cancelSave := storage.Detach(ctx)
+		defer cancelSave()
+		if suspended != nil {
+			if err := wr.storage.AddExecutionUsage(saveCtx, execID, usage.total()); err != nil {
+				log.Printf("Failed to save execution usage: %v", err)
+			}
+			stateBytes, _ := json.Marshal(suspended)
+			var err error
+			if suspended.Signal != "" {
+				var wakeAt *time.Time
+				if !suspended.WakeAt.IsZero() {
+					wakeAt = &suspended.WakeAt
+				}
+				err = wr.storage.SuspendExecutionForSignal(saveCtx, execID, stateBytes, suspended.Signal, wakeAt)
+			} else {
+				err = wr.storage.SuspendExecution(saveCtx, execID, stateBytes, suspended.WakeAt)
+			}
+			if err != nil {
+				log.Printf("Failed to suspend execution: %v", err)
+			}
+			return
+		}
+		finished := ExecutionFinished{
+			WorkflowID:  workflow.ID,
+			ExecutionID: execID,
+			Status:      string(finalStatus),
+			Duration:    time.Since(startedAt),
+			Time:        time.Now(),
+		}
+		if finalError != nil {
+			span.RecordError(errors.New(*finalError))
+			finished.Error = *finalError
+		}
+		stateBytes, _ := json.Marshal(resumeState{
+			Version:       executionStateVersion,
+			Results:       wr.stateManager.ctx.Results(),
+			Variables:     wr.stateManager.ctx.Variables(),
+			CurrentNodeID: lastNodeID,
+			Environment:   wr.stateManager.ctx.Environment,
+		})
+		// transitioned is set when this run recorded the terminal status
+		var transitioned bool
+		err := storage.WithTx(saveCtx, wr.storage, func(tx storage.Tx) error {
+			recordSkippedNodes(saveCtx, tx, execID, workflow)
+			if err := tx.AddExecutionUsage(saveCtx, execID, usage.total()); err != nil {
+				return err
+			}
+			if finalStatus == storage.ExecutionStatusCompleted {
+				if err := saveStaticData(saveCtx, tx, wr.stateManager.ctx); err != nil {
+					log.Printf("Failed to save workflow static data: %v", err)
+				}
+			}
+			// The runner is the only writer of a running execution's terminal status;
+			// the conditional update keeps it from overwriting a stop recorded for an
+			// execution that was no longer registered
+			ok, err := tx.TransitionExecutionStatus(saveCtx, execID, storage.ExecutionStatusRunning, finalStatus, stateBytes, finalError)
+			if err == nil && !ok {
+				log.Printf("Execution %s was finished elsewhere; keeping its status", execID)
+			}
+			if err == nil && ok && finalStatus == storage.ExecutionStatusCompleted {
+				err = dropSuccessData(saveCtx, tx, &workflow, execID)
+			}
+			transitioned = err == nil && ok
+			return err
+		})
+		if err != nil {
 			log.Printf("Failed to update execution status: %v", err)
+		} else if transitioned {
+			// Subscribers read the execution they are told about, so only an
+			// outcome that is committed, and that is this run's, is published
+			wr.events.Publish(finished)
+		}
+		if finalStatus == storage.ExecutionStatusFailed && finalError != nil && !wr.errorRun {
+			startErrorWorkflow(wr.storage, wr.bunRunner.BlocksDir, wr.events, &workflow, execID, *finalError)
 		}
 	}()
 
-	// Find start nodes (nodes with no incoming edges)
-	startNodes := workflow.FindStartNodes()
-	if len(startNodes) == 0 {
-		return fmt.Errorf("no start nodes found in workflow")
+	// pending is a stack of nodes still to run; fan-outs push all their targets
+	var pending []string
+	// saved holds the nodes of a run resumed from its checkpoint that finished
+	// before, which aren't run again when reached
+	saved := make(map[string]bool)
+	switch {
+	case from == nil:
+		// Find start nodes (nodes with no incoming edges)
+		startNodes := workflow.FindStartNodes()
+		if len(startNodes) == 0 {
+			finalStatus = storage.ExecutionStatusFailed
+			msg := "no start nodes found in workflow"
+			finalError = &msg
+			return errors.New(msg)
+		}
+
+		// Execute from the first start node using pointer-based traversal
+		pending = []string{startNodes[0]}
+	case from.checkpoint != nil:
+		lastNodeID = from.checkpoint.CurrentNodeID
+		pending = append(slices.Clone(from.checkpoint.Pending), lastNodeID)
+		for nodeID := range from.checkpoint.Results {
+			saved[nodeID] = true
+		}
+	default:
+		// The wait is over: record its result and continue after it
+		nodeType, port := NodeTypeDelay, "default"
+		waited := delayResult(time.Since(resume.SuspendedAt), resume.Unit)
+		if resume.NodeType == NodeTypeEnqueue {
+			nodeType = NodeTypeEnqueue
+			waited = enqueueResult(time.Since(resume.SuspendedAt), resume.Unit, resume.Signal, resume.signaled, resume.signalPayload)
+			port = waited["port"].(string)
+		}
+		wr.stateManager.SetResult(resume.NodeID, waited)
+		recordNodeSuccess(ctx, wr.storage, execID, resume.NodeID, port, waited)
+		wr.events.Publish(NodeFinished{
+			WorkflowID:  workflow.ID,
+			ExecutionID: execID,
+			NodeID:      resume.NodeID,
+			NodeType:    nodeType,
+			Port:        port,
+			Output:      waited,
+			Duration:    time.Since(resume.SuspendedAt),
+			Time:        time.Now(),
+		})
+		lastNodeID = resume.NodeID
+		pending = pushNext(resume.Pending, wr.bunRunner.nextNodes(&workflow, &Node{ID: resume.NodeID, Type: nodeType}, port))
 	}
 
-	// Execute from the first start node using pointer-based traversal
-	startNodeID := startNodes[0]
-	currentNodeID := startNodeID
+	for len(pending) > 0 {
+		currentNodeID := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
 
-	for currentNodeID != "" {
 		// Check for context cancellation (kill switch)
 		select {
 		case <-ctx.Done():
-			log.Printf("Execution cancelled: %v", ctx.Err())
-			finalStatus = storage.ExecutionStatusCancelled
-			msg := "Execution stopped by user"
+			log.Printf("Execution cancelled: %v", context.Cause(ctx))
+			status, msg := interruptedStatus(ctx)
+			finalStatus = status
 			finalError = &msg
 			return ctx.Err()
 		default:
@@ -85,27 +507,150 @@ func (wr *WorkflowRunner) runGraph(ctx context.Context, workflow Workflow) error
 		// Get the current node
 		node := workflow.GetNode(currentNodeID)
 		if node == nil {
-			return fmt.Errorf("node not found: %s", currentNodeID)
+			finalStatus = storage.ExecutionStatusFailed
+			msg := fmt.Sprintf("node not found: %s", currentNodeID)
+			finalError = &msg
+			return errors.New(msg)
+		}
+
+		if saved[node.ID] {
+			delete(saved, node.ID)
+			port := savedPort(wr.stateManager.ctx.GetResult(node.ID))
+			if port == "" {
+				// Results saved without their port (by GraphRunner before it
+				// wrapped WorkflowRunner) route as their node's record says
+				port = from.ports[node.ID]
+			}
+			recordNodeFinish(ctx, wr.storage, execID, node.ID, storage.NodeStatusCached, port, nil)
+			log.Printf("Node %s skipped execution, using its saved result", node.ID)
+			lastNodeID = node.ID
+			wr.checkpoint(ctx, execID, node.ID, pending)
+			pending = pushNext(pending, wr.bunRunner.nextNodes(&workflow, node, port))
+			continue
 		}
 
 		log.Printf("Executing node: %s (%s)", node.ID, node.Type)
+		recordNodeStart(ctx, wr.storage, execID, node.ID)
+		usage.addNode()
 
 		// Prepare input by resolving variables
+		call := &NodeCall{WorkflowID: workflow.ID, ExecutionID: execID, Node: node, Test: wr.stateManager.ctx.Test}
 		resolvedConfig, err := ResolveVariables(node.Config, wr.stateManager.ctx)
 		if err != nil {
+			onNodeError(ctx, call, err)
+			if continueOnFail(ctx, node) {
+				next := wr.continueAfterFailure(ctx, &workflow, execID, node, err, time.Now())
+				lastNodeID = node.ID
+				wr.checkpoint(ctx, execID, node.ID, pending)
+				pending = pushNext(pending, next)
+				continue
+			}
+			recordNodeFinish(ctx, wr.storage, execID, node.ID, "", "", err)
 			finalStatus = storage.ExecutionStatusFailed
 			msg := err.Error()
 			finalError = &msg
 			return fmt.Errorf("failed to resolve variables for node %s: %w", node.ID, err)
 		}
 
-		input := map[string]interface{}{
-			"config": resolvedConfig,
+		// Hooks registered by integrators see the input first and may refuse the node
+		call.Input = nodeInput(&workflow, resolvedConfig)
+		if err := beforeNode(ctx, call); err != nil {
+			onNodeError(ctx, call, err)
+			if continueOnFail(ctx, node) {
+				next := wr.continueAfterFailure(ctx, &workflow, execID, node, err, time.Now())
+				lastNodeID = node.ID
+				wr.checkpoint(ctx, execID, node.ID, pending)
+				pending = pushNext(pending, next)
+				continue
+			}
+			recordNodeFinish(ctx, wr.storage, execID, node.ID, "", "", err)
+			finalStatus = storage.ExecutionStatusFailed
+			msg := err.Error()
+			finalError = &msg
+			return fmt.Errorf("node %s refused by a hook: %w", node.ID, err)
+		}
+		input := call.Input
+		saveNodeInput(ctx, wr.storage, execID, node.ID, wr.stateManager.ctx.redactResolvedSecrets(input))
+
+		// Long delays and enqueue nodes park the execution instead of holding the worker
+		if wr.delays != nil {
+			wait, err := wr.delays.parkNode(node, resolvedConfig)
+			if err != nil {
+				onNodeError(ctx, call, err)
+				recordNodeFinish(ctx, wr.storage, execID, node.ID, "", "", err)
+				finalStatus = storage.ExecutionStatusFailed
+				msg := err.Error()
+				finalError = &msg
+				return fmt.Errorf("failed to execute node %s: %w", node.ID, err)
+			}
+			if wait != nil {
+				static, changed := wr.stateManager.ctx.StaticData()
+				if !changed {
+					static = nil
+				}
+				wait.Workflow = workflow
+				wait.StartedAt = startedAt
+				wait.TriggerData = wr.stateManager.ctx.TriggerData
+				wait.Results = wr.stateManager.ctx.Results()
+				wait.Variables = wr.stateManager.ctx.Variables()
+				wait.Static = static
+				wait.Environment = wr.stateManager.ctx.Environment
+				wait.Pending = pending
+				suspended = wait
+				finalStatus = storage.ExecutionStatusWaiting
+				if wait.Signal != "" {
+					log.Printf("Execution %s waiting in node %s for signal %s", execID, node.ID, wait.Signal)
+				} else {
+					log.Printf("Execution %s waiting in node %s until %s", execID, node.ID, wait.WakeAt.Format(time.RFC3339))
+				}
+				return nil
+			}
 		}
 
 		// Execute node via BunRunner
-		rawResult, err := wr.bunRunner.ExecuteNode(ctx, node, input)
+		nodeCtx, nodeSpan := startNodeSpan(ctx, node)
+		nodeCtx = wr.events.withNodeOutput(nodeCtx, workflow.ID, execID, node.ID)
+		// Nodes run without a limit of their own unless the node or workflow sets one
+		timeout := wr.nodeTimeout(&workflow, node)
+		cancelNode := context.CancelFunc(func() {})
+		if timeout > 0 {
+			nodeCtx, cancelNode = context.WithTimeout(nodeCtx, timeout)
+		}
+		nodeStarted := time.Now()
+		rawResult, err := executeNode(nodeCtx, wr.bunRunner, wr.stateManager.ctx, wr.storage, node, input)
+		if err != nil && ctx.Err() == nil && errors.Is(nodeCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("node %s execution timed out after %s: %w", node.ID, timeout, err)
+		}
+		cancelNode()
 		if err != nil {
+			onNodeError(ctx, call, err)
+			nodeSpan.RecordError(err)
+			nodeSpan.End()
+			if continueOnFail(ctx, node) {
+				next := wr.continueAfterFailure(ctx, &workflow, execID, node, err, nodeStarted)
+				lastNodeID = node.ID
+				wr.checkpoint(ctx, execID, node.ID, pending)
+				pending = pushNext(pending, next)
+				continue
+			}
+			recordNodeFinish(ctx, wr.storage, execID, node.ID, "", "", err)
+			wr.events.Publish(NodeFinished{
+				WorkflowID:  workflow.ID,
+				ExecutionID: execID,
+				NodeID:      node.ID,
+				NodeType:    node.Type,
+				Error:       err.Error(),
+				Duration:    time.Since(nodeStarted),
+				Time:        time.Now(),
+			})
+			// A node killed because the run was stopped or timed out reports the
+			// interruption rather than the process's exit signal
+			if ctx.Err() != nil {
+				status, msg := interruptedStatus(ctx)
+				finalStatus = status
+				finalError = &msg
+				return fmt.Errorf("node %s interrupted: %s: %w", node.ID, msg, ctx.Err())
+			}
 			finalStatus = storage.ExecutionStatusFailed
 			msg := err.Error()
 			finalError = &msg
@@ -114,74 +659,214 @@ func (wr *WorkflowRunner) runGraph(ctx context.Context, workflow Workflow) error
 
 		// Parse result to extract data and output port
 		result := parseBlockResult(rawResult)
+		nodeSpan.SetAttr("node.port", result.Port)
+		nodeSpan.End()
+		wr.events.Publish(NodeFinished{
+			WorkflowID:  workflow.ID,
+			ExecutionID: execID,
+			NodeID:      node.ID,
+			NodeType:    node.Type,
+			Port:        result.Port,
+			Output:      result.Data,
+			Duration:    time.Since(nodeStarted),
+			Time:        time.Now(),
+		})
 
 		// Process special actions (set_var, get_var, etc.)
-		if err := wr.processNodeActions(node, result); err != nil {
+		if err := applyNodeAction(wr.stateManager.ctx, result.Data); err != nil {
 			log.Printf("Warning: failed to process node actions: %v", err)
 		}
 
 		// Save result to context and storage
 		wr.stateManager.SetResult(node.ID, result.Data)
-
-		resBytes, _ := json.Marshal(result.Data)
-		if err := wr.storage.SaveNodeResult(ctx, execID, node.ID, resBytes); err != nil {
-			log.Printf("Warning: failed to save node result: %v", err)
-		}
+		recordNodeSuccess(ctx, wr.storage, execID, node.ID, result.Port, result.Data)
+		afterNode(ctx, call, result)
+		lastNodeID = node.ID
+		wr.checkpoint(ctx, execID, node.ID, pending)
 
 		log.Printf("Node %s completed, output port: %s", node.ID, result.Port)
 
-		// Find the next node based on the output port
-		currentNodeID = workflow.FindNextNode(node.ID, result.Port)
+		// Queue the next nodes based on the output port
+		pending = pushNext(pending, wr.bunRunner.nextNodes(&workflow, node, result.Port))
 	}
 
 	log.Printf("Workflow %s completed", workflow.ID)
 	return nil
 }
 
+// continueAfterFailure records the failure of a continue_on_fail node that
+// started at started: its error becomes its result on ErrorPort, and the nodes
+// to run next are those following that port.
+func (wr *WorkflowRunner) continueAfterFailure(ctx context.Context, workflow *Workflow, execID string, node *Node, err error, started time.Time) []string {
+	result := failureResult(err)
+	wr.events.Publish(NodeFinished{
+		WorkflowID:  workflow.ID,
+		ExecutionID: execID,
+		NodeID:      node.ID,
+		NodeType:    node.Type,
+		Port:        result.Port,
+		Output:      result.Data,
+		Error:       err.Error(),
+		Duration:    time.Since(started),
+		Time:        time.Now(),
+	})
+	wr.stateManager.SetResult(node.ID, result.Data)
+	recordNodeContinued(ctx, wr.storage, execID, node.ID, err, result)
+
+	log.Printf("Node %s failed, continuing on its %s port: %v", node.ID, result.Port, err)
+	return wr.bunRunner.nextNodes(workflow, node, result.Port)
+}
+
+// withRunTimeout bounds ctx by the workflow's settings.timeout, if it sets one.
+func withRunTimeout(ctx context.Context, w *Workflow) (context.Context, context.CancelFunc) {
+	timeout, _ := w.Timeout()
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, timeout, fmt.Errorf("run exceeded its %s timeout (settings.timeout)", timeout))
+}
+
+// nodeTimeout returns the timeout set for node of w by its config.timeout_ms or
+// else the workflow's settings.node_timeout, or the runner's default when
+// neither is set.
+func (wr *WorkflowRunner) nodeTimeout(w *Workflow, node *Node) time.Duration {
+	if node != nil {
+		if v, ok := node.Config["timeout_ms"].(float64); ok && v > 0 {
+			return time.Duration(v) * time.Millisecond
+		}
+	}
+	if timeout, _ := w.NodeTimeout(); timeout > 0 {
+		return timeout
+	}
+	return wr.defaultNodeTimeout
+}
+
+// nodeInput is the input a node of w is called with: its resolved config and
+// the workflow's settings.timezone, if set.
+func nodeInput(w *Workflow, config interface{}) map[string]interface{} {
+	input := map[string]interface{}{
+		"config": config,
+	}
+	if w.Settings != nil && w.Settings.Timezone != "" {
+		input["timezone"] = w.Settings.Timezone
+	}
+	return input
+}
+
+// saveNodeInput stores the input a node is called with, secrets redacted, for
+// GET /api/executions/{id}/nodes/{nodeId}.
+func saveNodeInput(ctx context.Context, store storage.Storage, executionID, nodeID string, input interface{}) {
+	raw, err := json.Marshal(redactSecrets(input))
+	if err == nil {
+		usageFrom(ctx).addBytesStored(len(raw))
+		err = store.SaveNodeInput(ctx, executionID, nodeID, raw)
+	}
+	if err != nil {
+		log.Printf("Warning: failed to save node input: %v", err)
+	}
+}
+
+// saveTriggerData stores the payload that started an execution, so it can be
+// inspected afterwards and $trigger resolves the same when the run is replayed.
+func saveTriggerData(ctx context.Context, store storage.Storage, executionID string, data map[string]interface{}) {
+	if len(data) == 0 {
+		return
+	}
+	raw, err := json.Marshal(data)
+	if err == nil {
+		err = store.SaveExecutionTriggerData(ctx, executionID, raw)
+	}
+	if err != nil {
+		log.Printf("Warning: failed to save trigger data: %v", err)
+	}
+}
+
+// loadTriggerData restores the trigger payload stored for an execution into ectx.
+func loadTriggerData(exec *storage.Execution, ectx *ExecutionContext) error {
+	if len(exec.TriggerData) == 0 {
+		return nil
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(exec.TriggerData, &data); err != nil {
+		return fmt.Errorf("failed to parse trigger data of execution %s: %w", exec.ID, err)
+	}
+	ectx.TriggerData = data
+	return nil
+}
+
+// interruptedStatus maps a finished ctx to the execution's final status and message:
+// a deadline fails the execution as a timeout (including any cause attached with
+// context.WithTimeoutCause), anything else counts as a stop by the user.
+func interruptedStatus(ctx context.Context) (storage.ExecutionStatus, string) {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		msg := "Execution timed out"
+		if cause := context.Cause(ctx); cause != nil && cause != ctx.Err() {
+			msg += ": " + cause.Error()
+		}
+		return storage.ExecutionStatusFailed, msg
+	}
+	return storage.ExecutionStatusCancelled, "Execution stopped by user"
+}
+
+// startNodeSpan opens a tracing span for a single node execution.
+func startNodeSpan(ctx context.Context, node *Node) (context.Context, *telemetry.Span) {
+	ctx, span := telemetry.Start(ctx, "node.execute", telemetry.SpanKindInternal)
+	span.SetAttr("node.id", node.ID)
+	span.SetAttr("node.type", string(node.Type))
+	return ctx, span
+}
+
 // parseBlockResult converts raw Bun output to BlockResult with port routing.
 // IMPORTANT: We keep the full result structure (with "data" field) for variable resolution.
 // Variables like {{ $node.block_1.data.value }} expect the "data" field to exist.
 func parseBlockResult(raw interface{}) *BlockResult {
-	result := &BlockResult{
+	return &BlockResult{
 		Data: raw, // Keep full structure for variable resolution
-		Port: "default",
+		Port: resultPort(raw),
 	}
+}
 
-	resMap, ok := raw.(map[string]interface{})
-	if !ok {
-		return result
+// checkpoint saves the state needed to resume the execution from nodeID, which
+// just finished with pending still to run after it, unless the state was saved
+// less than checkpointInterval ago. Results are left out: each node's result is
+// saved as the node finishes, and ResumeExecution reads them back.
+func (wr *WorkflowRunner) checkpoint(ctx context.Context, execID, nodeID string, pending []string) {
+	if time.Since(wr.lastCheckpoint) < wr.checkpointInterval {
+		return
 	}
-
-	// Check if result has explicit port field
-	if port, hasPort := resMap["port"]; hasPort {
-		if portStr, ok := port.(string); ok {
-			result.Port = portStr
-		}
+	wr.lastCheckpoint = time.Now()
+	state, _ := json.Marshal(resumeState{
+		Version:       executionStateVersion,
+		Variables:     wr.stateManager.ctx.Variables(),
+		CurrentNodeID: nodeID,
+		Environment:   wr.stateManager.ctx.Environment,
+		Pending:       pending,
+	})
+	ctx, cancel := storage.Detach(ctx)
+	defer cancel()
+	if _, err := wr.storage.SaveExecutionState(ctx, execID, state); err != nil {
+		log.Printf("Warning: failed to checkpoint execution state: %v", err)
 	}
+}
 
-	// For condition blocks, check data.result to determine port
-	if data, hasData := resMap["data"]; hasData {
-		if dataMap, ok := data.(map[string]interface{}); ok {
-			if condResult, hasResult := dataMap["result"]; hasResult {
-				if boolResult, ok := condResult.(bool); ok {
-					if boolResult {
-						result.Port = "true"
-					} else {
-						result.Port = "false"
-					}
-				}
-			}
+// savedPort returns the port the saved result of a node routed to, or "" for a
+// result saved without one.
+func savedPort(result interface{}) string {
+	if resMap, ok := result.(map[string]interface{}); ok {
+		if port, ok := resMap["port"].(string); ok {
+			return port
 		}
 	}
-
-	return result
+	return ""
 }
 
-// processNodeActions handles special actions returned by blocks (e.g., set_var, get_var).
-// This allows blocks to trigger side effects in the execution context.
-func (wr *WorkflowRunner) processNodeActions(node *Node, result *BlockResult) error {
+// applyNodeAction applies the action in a block's raw output to ectx:
+//   - {"action": "set_var", "name": ..., "value": ...} sets $vars.name for this run
+//   - {"action": "set_static", "name": ..., "value": ...} sets $workflowStatic.name,
+//     which is persisted for later runs when the execution completes
+func applyNodeAction(ectx *ExecutionContext, raw interface{}) error {
 	// Check if result data contains an action field
-	dataMap, ok := result.Data.(map[string]interface{})
+	dataMap, ok := raw.(map[string]interface{})
 	if !ok {
 		return nil // No action to process
 	}
@@ -197,11 +882,11 @@ func (wr *WorkflowRunner) processNodeActions(node *Node, result *BlockResult) er
 	}
 
 	switch actionStr {
-	case "set_var":
+	case "set_var", "set_static":
 		// Extract variable name and value
 		name, hasName := dataMap["name"]
 		if !hasName {
-			return fmt.Errorf("set_var action requires 'name' field")
+			return fmt.Errorf("%s action requires 'name' field", actionStr)
 		}
 		nameStr, ok := name.(string)
 		if !ok {
@@ -210,11 +895,16 @@ func (wr *WorkflowRunner) processNodeActions(node *Node, result *BlockResult) er
 
 		value, hasValue := dataMap["value"]
 		if !hasValue {
-			return fmt.Errorf("set_var action requires 'value' field")
+			return fmt.Errorf("%s action requires 'value' field", actionStr)
 		}
 
+		if actionStr == "set_static" {
+			ectx.SetStatic(nameStr, value)
+			log.Printf("Set static data: %s = %v", nameStr, value)
+			break
+		}
 		// Set the variable in execution context
-		wr.stateManager.ctx.SetVar(nameStr, value)
+		ectx.SetVar(nameStr, value)
 		log.Printf("Set variable: %s = %v", nameStr, value)
 
 	case "get_var":
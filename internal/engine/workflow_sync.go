@@ -0,0 +1,285 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultWorkflowSyncInterval is how often the workflows directory is synced.
+const defaultWorkflowSyncInterval = 10 * time.Second
+
+// WorkflowSync keeps the workflows of storage in line with a directory of
+// workflow definitions, typically a git checkout, so automations are deployed
+// by pushing to the repository. Every .json, .yaml or .yml file under the
+// directory defines one workflow; its ID defaults to the file's path without
+// extension, with "/" replaced by "-". Synced workflows record their file as
+// their Source and are read-only through the API; they are deleted with their
+// file. Workflows created through the API are left alone.
+type WorkflowSync struct {
+	// PollInterval is how often the directory is scanned for changes.
+	PollInterval time.Duration
+	// ConfigSchema returns the config schema of a node type, which the nodes of
+	// synced workflows are checked against; the built-in schemas by default.
+	ConfigSchema func(NodeType) (ConfigSchema, error)
+
+	dir   string
+	store storage.Storage
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// SyncResult reports what a sync changed.
+type SyncResult struct {
+	// Revision is the git revision of the directory, empty if it isn't a git checkout.
+	Revision string      `json:"revision,omitempty"`
+	Created  []string    `json:"created,omitempty"`
+	Updated  []string    `json:"updated,omitempty"`
+	Deleted  []string    `json:"deleted,omitempty"`
+	Errors   []SyncError `json:"errors,omitempty"`
+}
+
+// SyncError is a workflow file that couldn't be synced. The workflow it defines
+// keeps its last synced definition.
+type SyncError struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+// NewWorkflowSync creates a sync of the workflow files under dir into store.
+func NewWorkflowSync(dir string, store storage.Storage) *WorkflowSync {
+	return &WorkflowSync{
+		PollInterval: defaultWorkflowSyncInterval,
+		ConfigSchema: BuiltinConfigSchema,
+		dir:          dir,
+		store:        store,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// Start begins syncing the directory in the background.
+func (s *WorkflowSync) Start() {
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.PollInterval)
+		defer ticker.Stop()
+		for {
+			if _, err := s.Sync(context.Background()); err != nil {
+				log.Printf("Workflow sync: %v", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops syncing the directory.
+func (s *WorkflowSync) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+}
+
+// Sync creates, updates and deletes the synced workflows of storage to match
+// the files of the directory. Invalid files are reported in the result and
+// logged; an error is returned only when the directory or storage fail.
+func (s *WorkflowSync) Sync(ctx context.Context) (*SyncResult, error) {
+	files, err := definitionFiles(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", s.dir, err)
+	}
+	stored, err := s.store.ListWorkflows(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflows: %w", err)
+	}
+	existing := make(map[string]*storage.Workflow, len(stored))
+	for _, w := range stored {
+		existing[w.ID] = w
+	}
+
+	result := &SyncResult{Revision: gitRevision(s.dir)}
+	fail := func(file string, err error) {
+		result.Errors = append(result.Errors, SyncError{File: file, Error: err.Error()})
+		log.Printf("Workflow sync: %s: %v", file, err)
+	}
+
+	synced := make(map[string]string) // workflow ID -> file
+	broken := make(map[string]bool)   // files that failed to load
+	for _, file := range files {
+		wf, err := s.load(file)
+		if err != nil {
+			fail(file, err)
+			broken[file] = true
+			continue
+		}
+		if other, dup := synced[wf.ID]; dup {
+			fail(file, fmt.Errorf("workflow %s is already defined by %s", wf.ID, other))
+			continue
+		}
+		synced[wf.ID] = file
+
+		definition, err := json.Marshal(wf)
+		if err != nil {
+			fail(file, err)
+			continue
+		}
+		current, ok := existing[wf.ID]
+		switch {
+		case !ok:
+			err = s.store.CreateWorkflow(ctx, &storage.Workflow{ID: wf.ID, Name: wf.Name, Definition: definition, Source: file, Revision: result.Revision})
+			if err == nil {
+				result.Created = append(result.Created, wf.ID)
+			}
+		case current.Source == "":
+			err = fmt.Errorf("workflow %s is managed through the API", wf.ID)
+		case current.Source != file || current.Name != wf.Name || !bytes.Equal(current.Definition, definition):
+			err = s.store.UpdateWorkflow(ctx, &storage.Workflow{ID: wf.ID, Name: wf.Name, Definition: definition, Source: file, Revision: result.Revision})
+			if err == nil {
+				result.Updated = append(result.Updated, wf.ID)
+			}
+		}
+		if err != nil {
+			fail(file, err)
+		}
+	}
+
+	for _, w := range stored {
+		if w.Source == "" {
+			continue
+		}
+		// The workflows of broken files keep their last synced definition
+		if _, ok := synced[w.ID]; ok || broken[w.Source] {
+			continue
+		}
+		if err := s.store.DeleteWorkflow(ctx, w.ID); err != nil {
+			fail(w.Source, err)
+			continue
+		}
+		result.Deleted = append(result.Deleted, w.ID)
+	}
+
+	if n := len(result.Created) + len(result.Updated) + len(result.Deleted); n > 0 {
+		log.Printf("Workflow sync: %d created, %d updated, %d deleted at revision %q",
+			len(result.Created), len(result.Updated), len(result.Deleted), result.Revision)
+	}
+	return result, nil
+}
+
+// definitionFiles returns the definition files under dir, relative to it,
+// sorted. Hidden files and directories, like .git, are skipped.
+func definitionFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if isDefinitionFile(path) {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// load reads and validates the workflow defined by file.
+func (s *WorkflowSync) load(file string) (*Workflow, error) {
+	return loadWorkflowFile(s.dir, file, s.ConfigSchema)
+}
+
+// isDefinitionFile reports whether path is a JSON or YAML definition file.
+func isDefinitionFile(path string) bool {
+	switch filepath.Ext(path) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// readDefinitionFile decodes the JSON or YAML file at path into v. YAML goes
+// through JSON so that it follows the JSON field names.
+func readDefinitionFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		var doc interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("invalid YAML: %w", err)
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return fmt.Errorf("invalid YAML: %w", err)
+		}
+	}
+	return json.Unmarshal(data, v)
+}
+
+// loadWorkflowFile reads and validates the workflow defined by file, relative
+// to dir. Its ID defaults to fileID(file) and its name to its ID.
+func loadWorkflowFile(dir, file string, schema func(NodeType) (ConfigSchema, error)) (*Workflow, error) {
+	var wf Workflow
+	if err := readDefinitionFile(filepath.Join(dir, filepath.FromSlash(file)), &wf); err != nil {
+		return nil, fmt.Errorf("invalid workflow: %w", err)
+	}
+	if wf.ID == "" {
+		wf.ID = fileID(file)
+	}
+	if wf.Name == "" {
+		wf.Name = wf.ID
+	}
+	if err := wf.Validate(); err != nil {
+		return nil, err
+	}
+	if err := wf.ValidateDefinition(schema); err != nil {
+		return nil, err
+	}
+	return &wf, nil
+}
+
+// fileID returns the default ID of what file defines: its path without
+// extension, with "/" replaced by "-".
+func fileID(file string) string {
+	return strings.ReplaceAll(strings.TrimSuffix(file, filepath.Ext(file)), "/", "-")
+}
+
+// gitRevision returns the commit checked out in dir, empty if dir isn't in a
+// git checkout or git isn't installed.
+func gitRevision(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
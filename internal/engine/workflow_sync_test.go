@@ -0,0 +1,137 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func writeWorkflowFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWorkflowSync(t *testing.T) {
+	ctx := context.Background()
+	store := createTestStorage(t)
+	dir := t.TempDir()
+
+	writeWorkflowFile(t, dir, "orders.json", `{"name":"Orders","nodes":{"wait":{"type":"std/delay","config":{"duration":1}}},"edges":[]}`)
+	writeWorkflowFile(t, dir, "billing/invoices.yaml", `
+name: Invoices
+nodes:
+  wait:
+    type: std/delay
+    config:
+      duration: 2
+`)
+	writeWorkflowFile(t, dir, "broken.json", `{"nodes":{"wait":{"type":"std/delay","config":{}}}}`)
+	writeWorkflowFile(t, dir, "README.md", "not a workflow")
+	store.CreateWorkflow(ctx, &storage.Workflow{ID: "manual", Name: "Manual", Definition: []byte(`{}`)})
+
+	sync := engine.NewWorkflowSync(dir, store)
+	result, err := sync.Sync(ctx)
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if len(result.Created) != 2 || len(result.Errors) != 1 || result.Errors[0].File != "broken.json" {
+		t.Fatalf("expected 2 workflows created and broken.json reported, got %+v", result)
+	}
+
+	invoices, err := store.GetWorkflow(ctx, "billing-invoices")
+	if err != nil {
+		t.Fatalf("expected the YAML workflow to be synced: %v", err)
+	}
+	if invoices.Source != "billing/invoices.yaml" || invoices.Name != "Invoices" {
+		t.Errorf("unexpected synced workflow %+v", invoices)
+	}
+	var wf engine.Workflow
+	json.Unmarshal(invoices.Definition, &wf)
+	if wf.ID != "billing-invoices" || wf.Nodes["wait"].Config["duration"] != float64(2) {
+		t.Errorf("unexpected definition %s", invoices.Definition)
+	}
+
+	// Unchanged files are left alone
+	if result, _ := sync.Sync(ctx); len(result.Created)+len(result.Updated)+len(result.Deleted) != 0 {
+		t.Errorf("expected no changes, got %+v", result)
+	}
+
+	// Edited files update their workflow, removed ones delete it
+	writeWorkflowFile(t, dir, "orders.json", `{"name":"Orders v2","nodes":{"wait":{"type":"std/delay","config":{"duration":1}}},"edges":[]}`)
+	os.Remove(filepath.Join(dir, "billing", "invoices.yaml"))
+	result, err = sync.Sync(ctx)
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if len(result.Updated) != 1 || result.Updated[0] != "orders" || len(result.Deleted) != 1 || result.Deleted[0] != "billing-invoices" {
+		t.Errorf("expected orders updated and billing-invoices deleted, got %+v", result)
+	}
+	if orders, _ := store.GetWorkflow(ctx, "orders"); orders == nil || orders.Name != "Orders v2" {
+		t.Errorf("expected orders to be renamed, got %+v", orders)
+	}
+	if _, err := store.GetWorkflow(ctx, "manual"); err != nil {
+		t.Errorf("expected the API-managed workflow to be kept: %v", err)
+	}
+
+	// A workflow whose file breaks keeps its last synced definition
+	writeWorkflowFile(t, dir, "orders.json", `{"nodes":`)
+	if result, _ := sync.Sync(ctx); len(result.Deleted) != 0 || len(result.Errors) != 2 {
+		t.Errorf("expected orders to be kept and reported, got %+v", result)
+	}
+
+	// Files may not take over API-managed workflows
+	writeWorkflowFile(t, dir, "manual.json", `{"nodes":{"wait":{"type":"std/delay","config":{"duration":1}}}}`)
+	sync.Sync(ctx)
+	if manual, _ := store.GetWorkflow(ctx, "manual"); manual.Source != "" || manual.Name != "Manual" {
+		t.Errorf("expected the API-managed workflow to be untouched, got %+v", manual)
+	}
+}
+
+func TestWorkflowSync_GitRevision(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	ctx := context.Background()
+	store := createTestStorage(t)
+	dir := t.TempDir()
+	writeWorkflowFile(t, dir, "orders.json", `{"nodes":{"wait":{"type":"std/delay","config":{"duration":1}}}}`)
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+		return string(out)
+	}
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "Add orders")
+	head := git("rev-parse", "HEAD")
+	head = head[:len(head)-1]
+
+	result, err := engine.NewWorkflowSync(dir, store).Sync(ctx)
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if result.Revision != head {
+		t.Errorf("expected revision %s, got %q", head, result.Revision)
+	}
+
+	// Executions record the revision their workflow was synced at
+	execID, _ := store.CreateExecution(ctx, "orders")
+	if e, _ := store.GetExecution(ctx, execID); e == nil || e.Revision != head {
+		t.Errorf("expected the execution to record revision %s, got %+v", head, e)
+	}
+}
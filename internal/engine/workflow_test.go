@@ -3,11 +3,13 @@ package engine_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,9 +18,17 @@ import (
 )
 
 func createTestStorage(t *testing.T) storage.Storage {
-	tmpDir := t.TempDir()
-	dbPath := filepath.Join(tmpDir, "test.db")
-	store, err := storage.NewSQLite(dbPath)
+	// Tests use the in-memory storage; the storage package holds it to the same
+	// behavior as SQLite. CONV3N_TEST_DB runs them against another driver, e.g.
+	// "sqlite://" for a fresh database file per test.
+	dsn := os.Getenv("CONV3N_TEST_DB")
+	switch dsn {
+	case "":
+		dsn = "memory://"
+	case "sqlite://":
+		dsn += filepath.Join(t.TempDir(), "test.db")
+	}
+	store, err := storage.Open(dsn)
 	if err != nil {
 		t.Fatalf("Failed to create test storage: %v", err)
 	}
@@ -63,6 +73,522 @@ func TestWorkflowRunner_Run_EmptyWorkflow(t *testing.T) {
 	}
 }
 
+// TestWorkflowRunner_CreateExecution verifies that an execution created before
+// Run is the one Run executes, and that stopping it before Run cancels it.
+func TestWorkflowRunner_CreateExecution(t *testing.T) {
+	workflow := engine.Workflow{
+		ID: "prepared-wf",
+		Nodes: map[string]engine.Node{
+			"set": {ID: "set", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "x", "value": 1.0}},
+		},
+	}
+	store := createTestStorage(t)
+	registry := engine.NewExecutionRegistry()
+	ctx := context.Background()
+
+	runner := engine.NewWorkflowRunner(engine.NewExecutionContext(workflow.ID), "/tmp", store, registry)
+	execID, err := runner.CreateExecution(ctx, workflow.ID)
+	if err != nil {
+		t.Fatalf("CreateExecution failed: %v", err)
+	}
+	if !registry.IsActive(execID) {
+		t.Error("expected the created execution to be registered")
+	}
+	if err := runner.Run(ctx, workflow); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if exec, _ := store.GetExecution(ctx, execID); exec.Status != storage.ExecutionStatusCompleted {
+		t.Errorf("expected the created execution to complete, got %s", exec.Status)
+	}
+	if registry.IsActive(execID) {
+		t.Error("expected the execution to leave the registry")
+	}
+
+	runner = engine.NewWorkflowRunner(engine.NewExecutionContext(workflow.ID), "/tmp", store, registry)
+	execID, _ = runner.CreateExecution(ctx, workflow.ID)
+	registry.Cancel(execID)
+	runner.Run(ctx, workflow)
+	if exec, _ := store.GetExecution(ctx, execID); exec.Status != storage.ExecutionStatusCancelled {
+		t.Errorf("expected an execution stopped before Run to be cancelled, got %s", exec.Status)
+	}
+}
+
+// TestWorkflowRunner_NodeInput verifies that the resolved input of each node is
+// stored with its secrets redacted.
+func TestWorkflowRunner_NodeInput(t *testing.T) {
+	workflow := engine.Workflow{
+		ID: "input-wf",
+		Nodes: map[string]engine.Node{
+			"set": {ID: "set", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{
+				"name":    "login",
+				"value":   map[string]interface{}{"user": "{{ $trigger.user }}", "password": "hunter2", "max_tokens": 100.0},
+				"headers": map[string]interface{}{"Authorization": "Bearer abc", "Accept": "application/json"},
+			}},
+		},
+	}
+	store := createTestStorage(t)
+	ctx := context.Background()
+	ectx := engine.NewExecutionContext(workflow.ID)
+	ectx.TriggerData = map[string]interface{}{"user": "ada"}
+
+	runner := engine.NewWorkflowRunner(ectx, "/tmp", store, nil)
+	execID, _ := runner.CreateExecution(ctx, workflow.ID)
+	if err := runner.Run(ctx, workflow); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	raw, err := store.GetNodeInput(ctx, execID, "set")
+	if err != nil {
+		t.Fatalf("GetNodeInput failed: %v", err)
+	}
+	var input struct {
+		Config struct {
+			Value   map[string]interface{} `json:"value"`
+			Headers map[string]interface{} `json:"headers"`
+		} `json:"config"`
+	}
+	json.Unmarshal(raw, &input)
+	value, headers := input.Config.Value, input.Config.Headers
+	if value["user"] != "ada" || value["password"] != "[REDACTED]" || value["max_tokens"] != 100.0 {
+		t.Errorf("unexpected value input %v", value)
+	}
+	if headers["Authorization"] != "[REDACTED]" || headers["Accept"] != "application/json" {
+		t.Errorf("unexpected headers input %v", headers)
+	}
+}
+
+// TestWorkflowRunner_NodeTimeline verifies the status and timing recorded for
+// each node, including nodes on a branch that wasn't taken.
+func TestWorkflowRunner_NodeTimeline(t *testing.T) {
+	workflow := engine.Workflow{
+		ID: "timeline-wf",
+		Nodes: map[string]engine.Node{
+			"first":  {ID: "first", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "a", "value": 1.0}},
+			"second": {ID: "second", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "b", "value": 2.0}},
+			"unused": {ID: "unused", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "c", "value": 3.0}},
+		},
+		Edges: []engine.Edge{
+			{ID: "e1", Source: "first", Target: "second"},
+			{ID: "e2", Source: "second", Target: "unused", SourceHandle: "never"},
+		},
+	}
+	store := createTestStorage(t)
+	ctx := context.Background()
+
+	runner := engine.NewWorkflowRunner(engine.NewExecutionContext(workflow.ID), "/tmp", store, nil)
+	execID, _ := runner.CreateExecution(ctx, workflow.ID)
+	if err := runner.Run(ctx, workflow); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	nodes, err := store.ListNodeExecutions(ctx, execID)
+	if err != nil || len(nodes) != 3 {
+		t.Fatalf("expected 3 node records, got %d (%v)", len(nodes), err)
+	}
+	for i, want := range []struct {
+		id     string
+		status storage.NodeStatus
+	}{{"first", storage.NodeStatusSuccess}, {"second", storage.NodeStatusSuccess}, {"unused", storage.NodeStatusSkipped}} {
+		if nodes[i].NodeID != want.id || nodes[i].Status != want.status {
+			t.Errorf("node %d: expected %s %s, got %s %s", i, want.id, want.status, nodes[i].NodeID, nodes[i].Status)
+		}
+	}
+	first := nodes[0]
+	if first.Attempts != 1 || first.Port != "default" || first.StartedAt == nil || first.FinishedAt == nil || first.FinishedAt.Before(*first.StartedAt) {
+		t.Errorf("unexpected record %+v", first)
+	}
+	if nodes[2].StartedAt != nil || nodes[2].Attempts != 0 {
+		t.Errorf("expected the skipped node never to start, got %+v", nodes[2])
+	}
+}
+
+// failingResultStore fails to save the result of one node.
+type failingResultStore struct {
+	storage.Storage
+	nodeID string
+}
+
+func (s failingResultStore) Begin(ctx context.Context) (storage.Tx, error) {
+	tx, err := s.Storage.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return failingResultTx{tx, s.nodeID}, nil
+}
+
+type failingResultTx struct {
+	storage.Tx
+	nodeID string
+}
+
+func (t failingResultTx) SaveNodeResult(ctx context.Context, executionID, nodeID string, result []byte) error {
+	if nodeID == t.nodeID {
+		return errors.New("disk full")
+	}
+	return t.Tx.SaveNodeResult(ctx, executionID, nodeID, result)
+}
+
+// TestWorkflowRunner_Heartbeat verifies that a running execution records
+// heartbeats while a node keeps it busy.
+func TestWorkflowRunner_Heartbeat(t *testing.T) {
+	workflow := engine.Workflow{
+		ID: "heartbeat-wf",
+		Nodes: map[string]engine.Node{
+			"wait": {ID: "wait", Type: engine.NodeTypeDelay, Config: map[string]interface{}{"duration": 150.0}},
+		},
+	}
+	store := createTestStorage(t)
+	ctx := context.Background()
+
+	runner := engine.NewWorkflowRunner(engine.NewExecutionContext(workflow.ID), t.TempDir(), store, nil)
+	runner.SetHeartbeatInterval(20 * time.Millisecond)
+	execID, _ := runner.CreateExecution(ctx, workflow.ID)
+	if err := runner.Run(ctx, workflow); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	exec, err := store.GetExecution(ctx, execID)
+	if err != nil {
+		t.Fatalf("failed to get execution: %v", err)
+	}
+	if exec.Status != storage.ExecutionStatusCompleted || exec.HeartbeatAt == nil {
+		t.Fatalf("expected a completed execution with a heartbeat, got %s (heartbeat %v)", exec.Status, exec.HeartbeatAt)
+	}
+	if exec.HeartbeatAt.Before(exec.StartedAt.Add(-time.Second)) {
+		t.Errorf("expected the heartbeat during the run, got %v (started %v)", exec.HeartbeatAt, exec.StartedAt)
+	}
+}
+
+// continueOnFailWorkflow has a failing node with continue_on_fail whose error
+// port leads to a fallback node.
+func continueOnFailWorkflow(id string) engine.Workflow {
+	return engine.Workflow{
+		ID:       id,
+		Settings: &engine.WorkflowSettings{EdgeMatching: engine.EdgeMatchingStrict},
+		Nodes: map[string]engine.Node{
+			"get":      {ID: "get", Type: engine.NodeTypeGetVar, Config: map[string]interface{}{"name": "nope", "continue_on_fail": true}},
+			"fallback": {ID: "fallback", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "fallback", "value": "{{ $node.get.error }}"}},
+			"next":     {ID: "next", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "next", "value": true}},
+		},
+		Edges: []engine.Edge{
+			{ID: "e1", Source: "get", Target: "fallback", SourceHandle: "error"},
+			{ID: "e2", Source: "get", Target: "next"},
+		},
+	}
+}
+
+// TestWorkflowRunner_ContinueOnFail verifies that a failed node with
+// continue_on_fail outputs its error on the error port and the run carries on.
+func TestWorkflowRunner_ContinueOnFail(t *testing.T) {
+	workflow := continueOnFailWorkflow("continue-wf")
+	if err := workflow.ValidatePorts(engine.NewBunRunner(t.TempDir()).DeclaredPorts); err != nil {
+		t.Fatalf("expected the error port of a continue_on_fail node to be valid, got %v", err)
+	}
+	store := createTestStorage(t)
+	ctx := context.Background()
+
+	runner := engine.NewWorkflowRunner(engine.NewExecutionContext(workflow.ID), t.TempDir(), store, nil)
+	execID, _ := runner.CreateExecution(ctx, workflow.ID)
+	if err := runner.Run(ctx, workflow); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if exec, _ := store.GetExecution(ctx, execID); exec.Status != storage.ExecutionStatusCompleted {
+		t.Fatalf("expected the execution to complete, got %s", exec.Status)
+	}
+
+	nodes, err := store.ListNodeExecutions(ctx, execID)
+	if err != nil {
+		t.Fatalf("failed to list node executions: %v", err)
+	}
+	statuses := make(map[string]storage.NodeStatus)
+	for _, ne := range nodes {
+		statuses[ne.NodeID] = ne.Status
+		if ne.NodeID == "get" && (ne.Port != "error" || ne.Error == nil || !strings.Contains(*ne.Error, "variable not found: nope")) {
+			t.Errorf("expected get to fail on the error port, got %+v", ne)
+		}
+	}
+	if statuses["get"] != storage.NodeStatusFailed || statuses["fallback"] != storage.NodeStatusSuccess {
+		t.Errorf("expected get to fail and fallback to run, got %v", statuses)
+	}
+	if status, ok := statuses["next"]; ok && status != storage.NodeStatusSkipped {
+		t.Errorf("expected the default port not to be followed, got next %s", status)
+	}
+	raw, err := store.GetNodeResult(ctx, execID, "get")
+	if err != nil || !strings.Contains(string(raw), `"error":"get_var: variable not found: nope"`) {
+		t.Errorf("expected the error as the result of get, got %s (%v)", raw, err)
+	}
+	raw, _ = store.GetNodeResult(ctx, execID, "fallback")
+	if !strings.Contains(string(raw), "variable not found: nope") {
+		t.Errorf("expected fallback to see the error, got %s", raw)
+	}
+}
+
+// TestGraphRunner_ContinueOnFail verifies the same in the graph runner.
+func TestGraphRunner_ContinueOnFail(t *testing.T) {
+	store := createTestStorage(t)
+	workflow := continueOnFailWorkflow("continue-graph")
+	wfBytes, _ := json.Marshal(workflow)
+	store.CreateWorkflow(context.Background(), &storage.Workflow{ID: workflow.ID, Name: workflow.ID, Definition: wfBytes})
+
+	runner := engine.NewGraphRunner(&workflow, t.TempDir(), store)
+	if err := runner.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	vars := runner.GetVariables()
+	if fallback, _ := vars["fallback"].(string); !strings.Contains(fallback, "variable not found: nope") {
+		t.Errorf("expected fallback to see the error, got %v", vars["fallback"])
+	}
+	if _, ok := vars["next"]; ok {
+		t.Error("expected the default port not to be followed")
+	}
+}
+
+// TestWorkflowRunner_Settings verifies that the runner honors the workflow's settings.
+func TestWorkflowRunner_Settings(t *testing.T) {
+	ctx := context.Background()
+	store := createTestStorage(t)
+
+	// run runs workflow and returns its execution
+	run := func(t *testing.T, workflow engine.Workflow) *storage.Execution {
+		t.Helper()
+		runner := engine.NewWorkflowRunner(engine.NewExecutionContext(workflow.ID), t.TempDir(), store, nil)
+		execID, _ := runner.CreateExecution(ctx, workflow.ID)
+		runner.Run(ctx, workflow)
+		exec, err := store.GetExecution(ctx, execID)
+		if err != nil {
+			t.Fatalf("failed to get execution: %v", err)
+		}
+		return exec
+	}
+	wait := map[string]engine.Node{
+		"wait": {ID: "wait", Type: engine.NodeTypeDelay, Config: map[string]interface{}{"duration": 2000.0}},
+	}
+
+	t.Run("Timezone", func(t *testing.T) {
+		workflow := engine.Workflow{
+			ID:       "settings-tz",
+			Settings: &engine.WorkflowSettings{Timezone: "Europe/Berlin"},
+			Nodes: map[string]engine.Node{
+				"date": {ID: "date", Type: engine.NodeTypeDatetime, Config: map[string]interface{}{"operation": "format", "value": "2026-01-15T12:00:00Z"}},
+			},
+		}
+		exec := run(t, workflow)
+		raw, _ := store.GetNodeResult(ctx, exec.ID, "date")
+		if !strings.Contains(string(raw), `"timezone":"Europe/Berlin"`) || !strings.Contains(string(raw), "2026-01-15T13:00:00+01:00") {
+			t.Errorf("expected the date in the workflow's timezone, got %s", raw)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		workflow := engine.Workflow{ID: "settings-timeout", Settings: &engine.WorkflowSettings{Timeout: "50ms"}, Nodes: wait}
+		exec := run(t, workflow)
+		if exec.Status != storage.ExecutionStatusFailed || exec.Error == nil || *exec.Error != "Execution timed out: run exceeded its 50ms timeout (settings.timeout)" {
+			t.Errorf("expected the run to time out, got %s (%v)", exec.Status, exec.Error)
+		}
+	})
+
+	t.Run("NodeTimeout", func(t *testing.T) {
+		workflow := engine.Workflow{ID: "settings-node-timeout", Settings: &engine.WorkflowSettings{NodeTimeout: "50ms"}, Nodes: wait}
+		exec := run(t, workflow)
+		if exec.Status != storage.ExecutionStatusFailed || exec.Error == nil || !strings.Contains(*exec.Error, "node wait execution timed out after 50ms") {
+			t.Errorf("expected the node to time out, got %s (%v)", exec.Status, exec.Error)
+		}
+	})
+
+	t.Run("SaveSuccessData", func(t *testing.T) {
+		workflow := engine.Workflow{
+			ID:       "settings-no-data",
+			Settings: &engine.WorkflowSettings{SaveSuccessData: engine.SaveDataNone},
+			Nodes: map[string]engine.Node{
+				"set": {ID: "set", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "x", "value": 1.0}},
+			},
+		}
+		exec := run(t, workflow)
+		if exec.Status != storage.ExecutionStatusCompleted || string(exec.State) != "{}" {
+			t.Errorf("expected a completed execution without state, got %s %s", exec.Status, exec.State)
+		}
+		if results, _ := store.ListNodeResults(ctx, exec.ID); len(results) != 0 {
+			t.Errorf("expected no node results to be kept, got %d", len(results))
+		}
+		if nodes, _ := store.ListNodeExecutions(ctx, exec.ID); len(nodes) != 1 || nodes[0].Status != storage.NodeStatusSuccess {
+			t.Errorf("expected the node timeline to be kept, got %v", nodes)
+		}
+
+		// A failed run keeps its data for inspection
+		workflow.Nodes["fail"] = engine.Node{ID: "fail", Type: engine.NodeTypeGetVar, Config: map[string]interface{}{"name": "nope"}}
+		workflow.Edges = []engine.Edge{{ID: "e1", Source: "set", Target: "fail"}}
+		exec = run(t, workflow)
+		if _, err := store.GetNodeResult(ctx, exec.ID, "set"); exec.Status != storage.ExecutionStatusFailed || err != nil {
+			t.Errorf("expected a failed execution to keep its results, got %s (%v)", exec.Status, err)
+		}
+	})
+
+	t.Run("ErrorWorkflow", func(t *testing.T) {
+		onError := engine.Workflow{ID: "settings-on-error", Nodes: map[string]engine.Node{
+			"report": {ID: "report", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{
+				"name":  "failure",
+				"value": "{{ $trigger.workflow.id }}: {{ $trigger.execution.error }}",
+			}},
+		}}
+		def, _ := json.Marshal(onError)
+		if err := store.CreateWorkflow(ctx, &storage.Workflow{ID: onError.ID, Name: onError.ID, Definition: def}); err != nil {
+			t.Fatalf("failed to create workflow: %v", err)
+		}
+		workflow := engine.Workflow{
+			ID:       "settings-failing",
+			Settings: &engine.WorkflowSettings{ErrorWorkflow: onError.ID},
+			Nodes: map[string]engine.Node{
+				"get": {ID: "get", Type: engine.NodeTypeGetVar, Config: map[string]interface{}{"name": "nope"}},
+			},
+		}
+		failed := run(t, workflow)
+
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			execs, _ := store.ListExecutions(ctx, onError.ID, 10)
+			if len(execs) == 1 && execs[0].Status == storage.ExecutionStatusCompleted {
+				raw, _ := store.GetNodeResult(ctx, execs[0].ID, "report")
+				if !strings.Contains(string(raw), "settings-failing: "+*failed.Error) {
+					t.Errorf("expected the error workflow to see the failure, got %s", raw)
+				}
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected the error workflow to run once, got %d executions", len(execs))
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	})
+}
+
+// TestWorkflowRunner_NodeResultWithStatus verifies that a node is only recorded
+// as successful together with its result.
+func TestWorkflowRunner_NodeResultWithStatus(t *testing.T) {
+	workflow := engine.Workflow{
+		ID: "atomic-wf",
+		Nodes: map[string]engine.Node{
+			"first":  {ID: "first", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "a", "value": 1.0}},
+			"second": {ID: "second", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": "b", "value": 2.0}},
+		},
+		Edges: []engine.Edge{{ID: "e1", Source: "first", Target: "second"}},
+	}
+	store := createTestStorage(t)
+	ctx := context.Background()
+
+	runner := engine.NewWorkflowRunner(engine.NewExecutionContext(workflow.ID), "/tmp", failingResultStore{store, "second"}, nil)
+	execID, _ := runner.CreateExecution(ctx, workflow.ID)
+	if err := runner.Run(ctx, workflow); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	nodes, err := store.ListNodeExecutions(ctx, execID)
+	if err != nil || len(nodes) != 2 {
+		t.Fatalf("expected 2 node records, got %d (%v)", len(nodes), err)
+	}
+	if nodes[0].Status != storage.NodeStatusSuccess {
+		t.Errorf("expected first to succeed, got %s", nodes[0].Status)
+	}
+	if nodes[1].Status == storage.NodeStatusSuccess {
+		t.Error("expected second not to be recorded as successful without its result")
+	}
+	if result, _ := store.GetNodeResult(ctx, execID, "second"); result != nil {
+		t.Errorf("expected no result for second, got %s", result)
+	}
+}
+
+// TestWorkflowRunner_FanOut verifies that several edges on one port run all of
+// their targets, each branch to its end before the next.
+func TestWorkflowRunner_FanOut(t *testing.T) {
+	setVar := func(name string) engine.Node {
+		return engine.Node{ID: name, Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": name, "value": true}}
+	}
+	workflow := engine.Workflow{
+		ID:    "fanout-wf",
+		Nodes: map[string]engine.Node{"start": setVar("start"), "a": setVar("a"), "b": setVar("b"), "c": setVar("c")},
+		Edges: []engine.Edge{
+			{ID: "e1", Source: "start", Target: "a", SourceHandle: "default"},
+			{ID: "e2", Source: "start", Target: "b", SourceHandle: "default"},
+			{ID: "e3", Source: "a", Target: "c"},
+		},
+	}
+	store := createTestStorage(t)
+	ctx := context.Background()
+
+	runner := engine.NewWorkflowRunner(engine.NewExecutionContext(workflow.ID), "/tmp", store, nil)
+	execID, _ := runner.CreateExecution(ctx, workflow.ID)
+	if err := runner.Run(ctx, workflow); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	nodes, err := store.ListNodeExecutions(ctx, execID)
+	if err != nil {
+		t.Fatalf("ListNodeExecutions failed: %v", err)
+	}
+	var order []string
+	for _, n := range nodes {
+		if n.Status != storage.NodeStatusSuccess {
+			t.Errorf("expected %s to succeed, got %s", n.NodeID, n.Status)
+		}
+		order = append(order, n.NodeID)
+	}
+	if got := strings.Join(order, ","); got != "start,a,c,b" {
+		t.Errorf("expected nodes to run in order start,a,c,b, got %s", got)
+	}
+}
+
+// TestWorkflowRunner_ResumeExecution verifies that a run resumed from its
+// checkpoint reuses the results saved before and runs the fan-out branches
+// still pending.
+func TestWorkflowRunner_ResumeExecution(t *testing.T) {
+	setVar := func(name string) engine.Node {
+		return engine.Node{ID: name, Type: engine.NodeTypeSetVar, Config: map[string]interface{}{"name": name, "value": true}}
+	}
+	workflow := engine.Workflow{
+		ID:    "resume-fanout-wf",
+		Nodes: map[string]engine.Node{"start": setVar("start"), "a": setVar("a"), "b": setVar("b")},
+		Edges: []engine.Edge{
+			{ID: "e1", Source: "start", Target: "a"},
+			{ID: "e2", Source: "start", Target: "b"},
+		},
+	}
+	store := createTestStorage(t)
+	ctx := context.Background()
+
+	// A run lost after a, with b still pending
+	execID, _ := store.CreateExecution(ctx, workflow.ID)
+	store.SaveExecutionState(ctx, execID, []byte(`{"version":1,"variables":{"start":true,"a":true},"current_node_id":"a","pending":["b"]}`))
+	for _, id := range []string{"start", "a"} {
+		store.StartNodeExecution(ctx, execID, id)
+		store.FinishNodeExecution(ctx, execID, id, storage.NodeStatusSuccess, "default", nil)
+		store.SaveNodeResult(ctx, execID, id, []byte(`{"data":{"name":"`+id+`","value":true},"port":"default"}`))
+	}
+
+	ectx := engine.NewExecutionContext(workflow.ID)
+	if err := engine.NewWorkflowRunner(ectx, t.TempDir(), store, nil).ResumeExecution(ctx, workflow, execID); err != nil {
+		t.Fatalf("ResumeExecution failed: %v", err)
+	}
+	exec, _ := store.GetExecution(ctx, execID)
+	if exec.Status != storage.ExecutionStatusCompleted || ectx.GetVar("b") != true {
+		t.Fatalf("expected the pending branch to run, got %s with variables %v", exec.Status, ectx.Variables())
+	}
+	nodes, _ := store.ListNodeExecutions(ctx, execID)
+	statuses := make(map[string]storage.NodeStatus)
+	for _, n := range nodes {
+		statuses[n.NodeID] = n.Status
+		if n.Attempts != 1 {
+			t.Errorf("expected %s to run once, got %d attempts", n.NodeID, n.Attempts)
+		}
+	}
+	if statuses["start"] != storage.NodeStatusSuccess || statuses["a"] != storage.NodeStatusCached || statuses["b"] != storage.NodeStatusSuccess {
+		t.Errorf("expected a to reuse its result and b to run, got %v", statuses)
+	}
+
+	err := engine.NewWorkflowRunner(engine.NewExecutionContext(workflow.ID), t.TempDir(), store, nil).ResumeExecution(ctx, workflow, execID)
+	if err == nil || !strings.Contains(err.Error(), "not running") {
+		t.Errorf("expected a finished execution not to resume, got %v", err)
+	}
+}
+
 // TestWorkflowRunner_Run_SingleBlock verifies single block execution
 func TestWorkflowRunner_Run_SingleBlock(t *testing.T) {
 	// Skip if bun is not available
@@ -113,7 +639,7 @@ func TestWorkflowRunner_Run_SingleBlock(t *testing.T) {
 	}
 
 	// Verify result
-	result := ctx.Results["http-block"]
+	result := ctx.GetResult("http-block")
 	if result == nil {
 		t.Fatal("Expected result for http-block, got nil")
 	}
@@ -183,12 +709,12 @@ func TestWorkflowRunner_Run_Chain(t *testing.T) {
 	}
 
 	// 4. Verify Results
-	res1 := ctx.Results["block_1"]
+	res1 := ctx.GetResult("block_1")
 	if res1 == nil {
 		t.Fatal("Block 1 result missing")
 	}
 
-	res2 := ctx.Results["block_2"]
+	res2 := ctx.GetResult("block_2")
 	if res2 == nil {
 		t.Fatal("Block 2 result missing")
 	}
@@ -237,6 +763,131 @@ func TestWorkflowRunner_Run_ErrorInBlock(t *testing.T) {
 	}
 }
 
+// TestWorkflowRunner_Run_Timeout verifies a run killed by its deadline is
+// recorded as a failed execution with the timeout cause.
+func TestWorkflowRunner_Run_Timeout(t *testing.T) {
+	installFakeBun(t)
+	blocksDir := t.TempDir()
+	writeBlock(t, blocksDir, "test/slow.ts", "sleep 5\n")
+
+	workflow := engine.Workflow{
+		ID:    "timeout-wf",
+		Name:  "Timeout Workflow",
+		Nodes: map[string]engine.Node{"slow": {ID: "slow", Type: "test/slow"}},
+	}
+
+	ctx := engine.NewExecutionContext(workflow.ID)
+	store := createTestStorage(t)
+	runner := engine.NewWorkflowRunner(ctx, blocksDir, store, nil)
+
+	execCtx, cancel := context.WithTimeoutCause(context.Background(), 200*time.Millisecond, errors.New("run exceeded its 200ms timeout"))
+	defer cancel()
+
+	err := runner.Run(execCtx, workflow)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+
+	execs, err := store.ListExecutions(context.Background(), workflow.ID, 1)
+	if err != nil || len(execs) != 1 {
+		t.Fatalf("expected one execution, got %v (%v)", execs, err)
+	}
+	if execs[0].Status != storage.ExecutionStatusFailed {
+		t.Errorf("expected status failed, got %s", execs[0].Status)
+	}
+	if execs[0].Error == nil || *execs[0].Error != "Execution timed out: run exceeded its 200ms timeout" {
+		t.Errorf("unexpected error message: %v", execs[0].Error)
+	}
+}
+
+// TestWorkflowRunner_Run_StaticData verifies $workflowStatic survives between runs
+// and is only persisted by runs that complete.
+// TestWorkflowRunner_DefaultNodeTimeout verifies that the runner's default
+// bounds nodes setting no timeout, and that timeout_ms overrides it.
+func TestWorkflowRunner_DefaultNodeTimeout(t *testing.T) {
+	store := createTestStorage(t)
+	run := func(config map[string]interface{}) error {
+		workflow := engine.Workflow{
+			ID:    "default-timeout-wf",
+			Nodes: map[string]engine.Node{"wait": {ID: "wait", Type: engine.NodeTypeDelay, Config: config}},
+		}
+		runner := engine.NewWorkflowRunner(engine.NewExecutionContext(workflow.ID), t.TempDir(), store, nil)
+		runner.SetDefaultNodeTimeout(50 * time.Millisecond)
+		return runner.Run(context.Background(), workflow)
+	}
+
+	if err := run(map[string]interface{}{"duration": 2000.0}); err == nil || !strings.Contains(err.Error(), "timed out after 50ms") {
+		t.Errorf("expected the default to stop the node, got %v", err)
+	}
+	if err := run(map[string]interface{}{"duration": 100.0, "timeout_ms": 1000.0}); err != nil {
+		t.Errorf("expected timeout_ms to override the default, got %v", err)
+	}
+}
+
+func TestWorkflowRunner_Run_StaticData(t *testing.T) {
+	installFakeBun(t)
+	blocksDir := t.TempDir()
+	// set_cursor stores the cursor given in its config; echo returns its input
+	writeBlock(t, blocksDir, "test/set_cursor.ts",
+		`sed -E 's/.*"cursor":"([^"]*)".*/{"action":"set_static","name":"cursor","value":"\1"}/'`+"\n")
+	writeBlock(t, blocksDir, "test/echo.ts", "cat\n")
+	writeBlock(t, blocksDir, "test/fail.ts", "exit 1\n")
+
+	store := createTestStorage(t)
+	run := func(nodes map[string]engine.Node, edges []engine.Edge) (*engine.ExecutionContext, error) {
+		workflow := engine.Workflow{ID: "static-wf", Name: "Static", Nodes: nodes, Edges: edges}
+		ctx := engine.NewExecutionContext(workflow.ID)
+		runner := engine.NewWorkflowRunner(ctx, blocksDir, store, nil)
+		execCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return ctx, runner.Run(execCtx, workflow)
+	}
+	storedCursor := func() string {
+		raw, err := store.GetWorkflowStaticData(context.Background(), "static-wf")
+		if err != nil {
+			t.Fatalf("failed to get static data: %v", err)
+		}
+		var data map[string]interface{}
+		json.Unmarshal(raw, &data)
+		cursor, _ := data["cursor"].(string)
+		return cursor
+	}
+
+	// First run stores the cursor
+	if _, err := run(map[string]engine.Node{
+		"set": {ID: "set", Type: "test/set_cursor", Config: map[string]interface{}{"cursor": "c1"}},
+	}, nil); err != nil {
+		t.Fatalf("first run failed: %v", err)
+	}
+	if got := storedCursor(); got != "c1" {
+		t.Fatalf("expected stored cursor c1, got %q", got)
+	}
+
+	// A later run reads it back through the resolver
+	ctx, err := run(map[string]engine.Node{
+		"read": {ID: "read", Type: "test/echo", Config: map[string]interface{}{"since": "{{ $workflowStatic.cursor }}"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("second run failed: %v", err)
+	}
+	echoed, _ := ctx.GetResult("read").(map[string]interface{})
+	config, _ := echoed["config"].(map[string]interface{})
+	if config["since"] != "c1" {
+		t.Errorf("expected resolved cursor c1, got %v", echoed)
+	}
+
+	// A failed run does not advance the cursor
+	if _, err := run(map[string]engine.Node{
+		"set":  {ID: "set", Type: "test/set_cursor", Config: map[string]interface{}{"cursor": "c2"}},
+		"fail": {ID: "fail", Type: "test/fail"},
+	}, []engine.Edge{{ID: "e1", Source: "set", Target: "fail"}}); err == nil {
+		t.Fatal("expected third run to fail")
+	}
+	if got := storedCursor(); got != "c1" {
+		t.Errorf("expected cursor to stay c1 after a failed run, got %q", got)
+	}
+}
+
 // TestWorkflowRunner_Run_SequentialExecution verifies graph traversal executes nodes in order
 func TestWorkflowRunner_Run_SequentialExecution(t *testing.T) {
 	// Skip if bun is not available
@@ -307,13 +958,13 @@ func TestWorkflowRunner_Run_SequentialExecution(t *testing.T) {
 	}
 
 	// Verify all 3 blocks executed
-	if len(ctx.Results) != 3 {
-		t.Errorf("Expected 3 results, got %d", len(ctx.Results))
+	if len(ctx.Results()) != 3 {
+		t.Errorf("Expected 3 results, got %d", len(ctx.Results()))
 	}
 
 	// Verify each block has a result
 	for _, blockID := range []string{"block-1", "block-2", "block-3"} {
-		if ctx.Results[blockID] == nil {
+		if ctx.GetResult(blockID) == nil {
 			t.Errorf("Missing result for %s", blockID)
 		}
 	}
@@ -522,3 +1173,157 @@ func TestGraphRunner_IdempotentNodeExecution(t *testing.T) {
 		t.Fatalf("expected HTTP handler to be called only once, got %d", hits)
 	}
 }
+
+// TestWorkflowRunner_Run_Environment verifies that $globals resolves to the shared
+// defaults overridden by the selected environment, and that an unknown environment
+// fails the execution instead of silently using the defaults.
+func TestWorkflowRunner_Run_Environment(t *testing.T) {
+	installFakeBun(t)
+	blocksDir := t.TempDir()
+	writeBlock(t, blocksDir, "test/echo.ts", "cat\n")
+
+	store := createTestStorage(t)
+	bg := context.Background()
+	if err := store.CreateEnvironment(bg, &storage.Environment{Name: "staging"}); err != nil {
+		t.Fatalf("failed to create environment: %v", err)
+	}
+	for _, v := range []*storage.GlobalVariable{
+		{Name: "apiBaseUrl", Value: []byte(`"https://api.example.com"`)},
+		{Name: "region", Value: []byte(`"eu"`)},
+		{Environment: "staging", Name: "apiBaseUrl", Value: []byte(`"https://staging.example.com"`)},
+	} {
+		if err := store.SetGlobalVariable(bg, v); err != nil {
+			t.Fatalf("failed to set global variable: %v", err)
+		}
+	}
+
+	workflow := engine.Workflow{ID: "env-wf", Name: "Env", Nodes: map[string]engine.Node{
+		"call": {ID: "call", Type: "test/echo", Config: map[string]interface{}{
+			"url":    "{{ $globals.apiBaseUrl }}",
+			"region": "{{ $globals.region }}",
+		}},
+	}}
+	run := func(env string) (map[string]interface{}, error) {
+		ctx := engine.NewExecutionContext(workflow.ID)
+		ctx.Environment = env
+		runner := engine.NewWorkflowRunner(ctx, blocksDir, store, nil)
+		execCtx, cancel := context.WithTimeout(bg, 5*time.Second)
+		defer cancel()
+		err := runner.Run(execCtx, workflow)
+		echoed, _ := ctx.GetResult("call").(map[string]interface{})
+		config, _ := echoed["config"].(map[string]interface{})
+		return config, err
+	}
+
+	config, err := run("")
+	if err != nil {
+		t.Fatalf("run without environment failed: %v", err)
+	}
+	if config["url"] != "https://api.example.com" || config["region"] != "eu" {
+		t.Errorf("expected default globals, got %v", config)
+	}
+
+	config, err = run("staging")
+	if err != nil {
+		t.Fatalf("run in staging failed: %v", err)
+	}
+	if config["url"] != "https://staging.example.com" || config["region"] != "eu" {
+		t.Errorf("expected staging override with default region, got %v", config)
+	}
+
+	if _, err := run("prod"); err == nil {
+		t.Error("expected run in unknown environment to fail")
+	}
+}
+
+// TestWorkflowRunner_Run_DeclaredPorts verifies that a block declaring its ports
+// in a manifest routes to whichever of them it returns, and that returning a port
+// it doesn't declare fails the node.
+func TestWorkflowRunner_Run_DeclaredPorts(t *testing.T) {
+	installFakeBun(t)
+	blocksDir := t.TempDir()
+	writeBlock(t, blocksDir, "test/echo.ts", "cat\n")
+	writeBlock(t, blocksDir, "acme/route.ts", `echo '{"data":{},"port":"b"}'`+"\n")
+	writeBlock(t, blocksDir, "acme/route.manifest.json", `{"id":"acme/route","ports":["a","b","c"]}`)
+	writeBlock(t, blocksDir, "acme/stray.ts", `echo '{"data":{},"port":"z"}'`+"\n")
+	writeBlock(t, blocksDir, "acme/stray.manifest.json", `{"id":"acme/stray","ports":["a","b","c"]}`)
+	store := createTestStorage(t)
+
+	run := func(router engine.NodeType) (*engine.ExecutionContext, error) {
+		workflow := engine.Workflow{
+			ID: "ports-wf",
+			Nodes: map[string]engine.Node{
+				"route": {ID: "route", Type: router},
+				"on_a":  {ID: "on_a", Type: "test/echo"},
+				"on_b":  {ID: "on_b", Type: "test/echo"},
+				"on_c":  {ID: "on_c", Type: "test/echo"},
+			},
+			Edges: []engine.Edge{
+				{Source: "route", Target: "on_a", SourceHandle: "a"},
+				{Source: "route", Target: "on_b", SourceHandle: "b"},
+				{Source: "route", Target: "on_c", SourceHandle: "c"},
+			},
+		}
+		ctx := engine.NewExecutionContext(workflow.ID)
+		runner := engine.NewWorkflowRunner(ctx, blocksDir, store, nil)
+		execCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return ctx, runner.Run(execCtx, workflow)
+	}
+
+	ctx, err := run("acme/route")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if ctx.GetResult("on_b") == nil || ctx.GetResult("on_a") != nil || ctx.GetResult("on_c") != nil {
+		t.Errorf("expected only on_b to run, got results %v", ctx.Results())
+	}
+
+	_, err = run("acme/stray")
+	if err == nil || !strings.Contains(err.Error(), `undeclared output port "z"`) {
+		t.Errorf("expected undeclared port error, got %v", err)
+	}
+}
+
+// TestWorkflowRunner_Run_StrictEdgeMatching verifies that with strict edge
+// matching an edge without a sourceHandle only follows the default port of a
+// node that declares ports, while lenient matching follows it for any port.
+func TestWorkflowRunner_Run_StrictEdgeMatching(t *testing.T) {
+	installFakeBun(t)
+	blocksDir := t.TempDir()
+	writeBlock(t, blocksDir, "test/echo.ts", "cat\n")
+	writeBlock(t, blocksDir, "acme/route.ts", `echo '{"data":{},"port":"b"}'`+"\n")
+	writeBlock(t, blocksDir, "acme/route.manifest.json", `{"id":"acme/route","ports":["default","b"]}`)
+	store := createTestStorage(t)
+
+	run := func(mode string) *engine.ExecutionContext {
+		workflow := engine.Workflow{
+			ID: "strict-wf",
+			Nodes: map[string]engine.Node{
+				"route": {ID: "route", Type: "acme/route"},
+				"plain": {ID: "plain", Type: "test/echo"},
+				"on_b":  {ID: "on_b", Type: "test/echo"},
+			},
+			Edges: []engine.Edge{
+				{Source: "route", Target: "plain"},
+				{Source: "route", Target: "on_b", SourceHandle: "b"},
+			},
+			Settings: &engine.WorkflowSettings{EdgeMatching: mode},
+		}
+		ctx := engine.NewExecutionContext(workflow.ID)
+		runner := engine.NewWorkflowRunner(ctx, blocksDir, store, nil)
+		execCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := runner.Run(execCtx, workflow); err != nil {
+			t.Fatalf("%s run failed: %v", mode, err)
+		}
+		return ctx
+	}
+
+	if ctx := run(engine.EdgeMatchingLenient); ctx.GetResult("plain") == nil || ctx.GetResult("on_b") == nil {
+		t.Errorf("expected lenient matching to run both targets, got %v", ctx.Results())
+	}
+	if ctx := run(engine.EdgeMatchingStrict); ctx.GetResult("plain") != nil || ctx.GetResult("on_b") == nil {
+		t.Errorf("expected strict matching to run only on_b, got %v", ctx.Results())
+	}
+}
@@ -0,0 +1,365 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Element values in the JSON item model: attributes are "@name" keys, text next
+// to attributes or children is "#text", repeated elements become arrays and an
+// element with only text is a plain string. Namespace prefixes are kept in the
+// names ("soap:Body", "@xmlns:soap") so documents round-trip.
+const (
+	xmlAttrPrefix = "@"
+	xmlTextKey    = "#text"
+)
+
+var soapVersions = map[string]struct{ namespace, contentType string }{
+	"1.1": {"http://schemas.xmlsoap.org/soap/envelope/", "text/xml; charset=utf-8"},
+	"1.2": {"http://www.w3.org/2003/05/soap-envelope", "application/soap+xml; charset=utf-8"},
+}
+
+// xmlConfig is the config of a std/xml node:
+//
+//	{"operation": "parse", "content": "{{ $node.download.data }}", "arrays": ["item"]}
+//	{"operation": "serialize", "data": {"order": {"@id": "42", "total": 9.99}}}
+//	{"operation": "soap", "url": "https://erp.example.com/ws", "action": "urn:GetOrder",
+//	 "body": "<GetOrder xmlns=\"urn:erp\"><Id>{{ $trigger.id }}</Id></GetOrder>"}
+type xmlConfig struct {
+	Operation string `json:"operation"`
+	// Content is the XML text to parse.
+	Content string `json:"content"`
+	// Arrays names elements that always parse to arrays, even when they occur once.
+	Arrays []string `json:"arrays"`
+	// Data is the document to serialize: an object with the root element as its only key.
+	Data map[string]interface{} `json:"data"`
+	// Declaration prepends <?xml version="1.0" encoding="UTF-8"?> when serializing.
+	Declaration bool `json:"declaration"`
+
+	// URL of the SOAP endpoint.
+	URL string `json:"url"`
+	// Action is sent as the SOAPAction header (1.1) or the action parameter (1.2).
+	Action string `json:"action"`
+	// Version is "1.1" (default) or "1.2".
+	Version string `json:"version"`
+	// Body and Header go inside soap:Body and soap:Header: an XML string, used
+	// verbatim, or an object serialized like Data. Object keys are written in
+	// sorted order, so use a string when the schema requires a sequence.
+	Body   interface{} `json:"body"`
+	Header interface{} `json:"header"`
+	// Headers are extra HTTP headers, e.g. Authorization.
+	Headers map[string]string `json:"headers"`
+}
+
+// executeXML runs a std/xml node. Parsing outputs {"root": name, "document":
+// {name: value}}; serializing outputs {"content": "..."}; a SOAP call outputs
+// {"status", "body"} with the parsed soap:Body contents, routing SOAP faults to
+// the "fault" port with {"status", "fault"}.
+func executeXML(ctx context.Context, input any) (any, error) {
+	payload, _ := input.(map[string]interface{})
+	var cfg xmlConfig
+	raw, _ := json.Marshal(payload["config"])
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("xml: invalid config: %w", err)
+	}
+
+	switch cfg.Operation {
+	case "parse":
+		root, doc, err := parseXML(cfg.Content, cfg.Arrays)
+		if err != nil {
+			return nil, fmt.Errorf("xml: %w", err)
+		}
+		return map[string]interface{}{"data": map[string]interface{}{"root": root, "document": doc}, "port": "default"}, nil
+	case "serialize":
+		if len(cfg.Data) != 1 {
+			return nil, fmt.Errorf("xml: config 'data' must have exactly one key, the root element")
+		}
+		var buf bytes.Buffer
+		if cfg.Declaration {
+			buf.WriteString(xml.Header)
+		}
+		if err := writeXMLElements(&buf, cfg.Data); err != nil {
+			return nil, fmt.Errorf("xml: %w", err)
+		}
+		return map[string]interface{}{"data": map[string]interface{}{"content": buf.String()}, "port": "default"}, nil
+	case "soap":
+		result, err := callSOAP(ctx, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("xml: soap: %w", err)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("xml: config 'operation' must be 'parse', 'serialize' or 'soap'")
+	}
+}
+
+type xmlFrame struct {
+	name     string
+	children map[string]interface{}
+	text     strings.Builder
+}
+
+// parseXML converts a document into the JSON item model.
+func parseXML(content string, arrays []string) (string, map[string]interface{}, error) {
+	forceArray := make(map[string]bool, len(arrays))
+	for _, name := range arrays {
+		forceArray[name] = true
+	}
+
+	// RawToken keeps prefixes as written instead of resolving them to namespace URLs
+	dec := xml.NewDecoder(strings.NewReader(content))
+	var stack []*xmlFrame
+	var root string
+	var doc map[string]interface{}
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid XML: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			frame := &xmlFrame{name: xmlName(t.Name), children: map[string]interface{}{}}
+			for _, attr := range t.Attr {
+				frame.children[xmlAttrPrefix+xmlName(attr.Name)] = attr.Value
+			}
+			stack = append(stack, frame)
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return "", nil, fmt.Errorf("invalid XML: unexpected </%s>", xmlName(t.Name))
+			}
+			frame := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			var value interface{} = frame.children
+			text := strings.TrimSpace(frame.text.String())
+			if len(frame.children) == 0 {
+				value = text
+			} else if text != "" {
+				frame.children[xmlTextKey] = text
+			}
+			if len(stack) == 0 {
+				root, doc = frame.name, map[string]interface{}{frame.name: value}
+				continue
+			}
+			parent := stack[len(stack)-1].children
+			switch existing := parent[frame.name].(type) {
+			case nil:
+				if forceArray[frame.name] {
+					parent[frame.name] = []interface{}{value}
+				} else {
+					parent[frame.name] = value
+				}
+			case []interface{}:
+				parent[frame.name] = append(existing, value)
+			default:
+				parent[frame.name] = []interface{}{existing, value}
+			}
+		}
+	}
+	if doc == nil {
+		return "", nil, fmt.Errorf("invalid XML: no root element")
+	}
+	return root, doc, nil
+}
+
+func xmlName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+// writeXMLElements writes each key of elements as an element, in sorted order.
+func writeXMLElements(buf *bytes.Buffer, elements map[string]interface{}) error {
+	names := make([]string, 0, len(elements))
+	for name := range elements {
+		if !strings.HasPrefix(name, xmlAttrPrefix) && name != xmlTextKey {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := writeXMLElement(buf, name, elements[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeXMLElement(buf *bytes.Buffer, name string, value interface{}) error {
+	if name == "" || strings.ContainsAny(name, " <>&\"'/=") {
+		return fmt.Errorf("invalid element name %q", name)
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if err := writeXMLElement(buf, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		buf.WriteString("<" + name)
+		attrs := make([]string, 0, len(v))
+		for key := range v {
+			if strings.HasPrefix(key, xmlAttrPrefix) {
+				attrs = append(attrs, key)
+			}
+		}
+		sort.Strings(attrs)
+		for _, key := range attrs {
+			buf.WriteString(" " + strings.TrimPrefix(key, xmlAttrPrefix) + `="`)
+			xml.EscapeText(buf, []byte(xmlScalar(v[key])))
+			buf.WriteString(`"`)
+		}
+		buf.WriteString(">")
+		if text, ok := v[xmlTextKey]; ok {
+			xml.EscapeText(buf, []byte(xmlScalar(text)))
+		}
+		if err := writeXMLElements(buf, v); err != nil {
+			return err
+		}
+		buf.WriteString("</" + name + ">")
+		return nil
+	case nil:
+		buf.WriteString("<" + name + "/>")
+		return nil
+	default:
+		buf.WriteString("<" + name + ">")
+		xml.EscapeText(buf, []byte(xmlScalar(v)))
+		buf.WriteString("</" + name + ">")
+		return nil
+	}
+}
+
+func xmlScalar(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// callSOAP wraps the configured body in a SOAP envelope, posts it and unwraps the response.
+func callSOAP(ctx context.Context, cfg xmlConfig) (map[string]interface{}, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("config 'url' is required")
+	}
+	if cfg.Version == "" {
+		cfg.Version = "1.1"
+	}
+	version, ok := soapVersions[cfg.Version]
+	if !ok {
+		return nil, fmt.Errorf("config 'version' must be '1.1' or '1.2'")
+	}
+
+	var envelope bytes.Buffer
+	envelope.WriteString(xml.Header)
+	envelope.WriteString(`<soap:Envelope xmlns:soap="` + version.namespace + `">`)
+	if cfg.Header != nil {
+		envelope.WriteString("<soap:Header>")
+		if err := writeSOAPPart(&envelope, cfg.Header); err != nil {
+			return nil, fmt.Errorf("config 'header': %w", err)
+		}
+		envelope.WriteString("</soap:Header>")
+	}
+	envelope.WriteString("<soap:Body>")
+	if err := writeSOAPPart(&envelope, cfg.Body); err != nil {
+		return nil, fmt.Errorf("config 'body': %w", err)
+	}
+	envelope.WriteString("</soap:Body></soap:Envelope>")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, &envelope)
+	if err != nil {
+		return nil, err
+	}
+	contentType := version.contentType
+	if cfg.Version == "1.1" {
+		req.Header.Set("SOAPAction", strconv.Quote(cfg.Action))
+	} else if cfg.Action != "" {
+		contentType += "; action=" + strconv.Quote(cfg.Action)
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	usageFrom(ctx).addHTTPCall()
+	res, err := httpClient(ctx).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	text, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	_, doc, err := parseXML(string(text), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: response is not XML: %w", res.Status, err)
+	}
+	body, ok := xmlChild(xmlChild(doc, "Envelope"), "Body").(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: response has no soap:Body", res.Status)
+	}
+	if fault := xmlChild(body, "Fault"); fault != nil {
+		return map[string]interface{}{
+			"data": map[string]interface{}{"status": res.StatusCode, "fault": fault},
+			"port": "fault",
+		}, nil
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, fmt.Errorf("endpoint returned %s", res.Status)
+	}
+	return map[string]interface{}{
+		"data": map[string]interface{}{"status": res.StatusCode, "body": body},
+		"port": "default",
+	}, nil
+}
+
+func writeSOAPPart(buf *bytes.Buffer, part interface{}) error {
+	switch p := part.(type) {
+	case nil:
+		return nil
+	case string:
+		buf.WriteString(p)
+		return nil
+	case map[string]interface{}:
+		return writeXMLElements(buf, p)
+	default:
+		return fmt.Errorf("must be an XML string or an object")
+	}
+}
+
+// xmlChild returns the child element with the given local name, whatever its prefix.
+func xmlChild(v interface{}, local string) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	for name, child := range m {
+		if name == local || strings.HasSuffix(name, ":"+local) {
+			return child
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/engine"
+)
+
+func runXML(t *testing.T, config map[string]interface{}) (map[string]interface{}, string, error) {
+	t.Helper()
+	runner := engine.NewBunRunner(t.TempDir())
+	runner.RuntimePath = "/nonexistent/bun" // any process start would fail
+	node := &engine.Node{ID: "xml", Type: engine.NodeTypeXML}
+	raw, err := runner.ExecuteNode(context.Background(), node, map[string]interface{}{"config": config})
+	if err != nil {
+		return nil, "", err
+	}
+	result := raw.(map[string]interface{})
+	return result["data"].(map[string]interface{}), result["port"].(string), nil
+}
+
+// TestBunRunner_ExecuteNode_XML verifies that std/xml parses XML into objects
+// and serializes them back.
+func TestBunRunner_ExecuteNode_XML(t *testing.T) {
+	data, _, err := runXML(t, map[string]interface{}{
+		"operation": "parse",
+		"content": `<?xml version="1.0"?>
+<orders xmlns:x="urn:x" count="2">
+  <order id="1"><name>Ada &amp; Co</name><x:total>9.99</x:total></order>
+  <order id="2"><name>Bob</name><note lang="en">rush</note></order>
+  <tag>single</tag>
+</orders>`,
+		"arrays": []interface{}{"tag"},
+	})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	doc, _ := json.Marshal(data["document"])
+	want := `{"orders":{"@count":"2","@xmlns:x":"urn:x","order":[{"@id":"1","name":"Ada \u0026 Co","x:total":"9.99"},{"@id":"2","name":"Bob","note":{"#text":"rush","@lang":"en"}}],"tag":["single"]}}`
+	if string(doc) != want || data["root"] != "orders" {
+		t.Errorf("expected %s, got %s (root %v)", want, doc, data["root"])
+	}
+
+	data, _, err = runXML(t, map[string]interface{}{
+		"operation": "serialize",
+		"data":      data["document"],
+	})
+	if err != nil {
+		t.Fatalf("serialize failed: %v", err)
+	}
+	wantXML := `<orders count="2" xmlns:x="urn:x"><order id="1"><name>Ada &amp; Co</name><x:total>9.99</x:total></order>` +
+		`<order id="2"><name>Bob</name><note lang="en">rush</note></order><tag>single</tag></orders>`
+	if data["content"] != wantXML {
+		t.Errorf("expected %s, got %s", wantXML, data["content"])
+	}
+
+	if _, _, err := runXML(t, map[string]interface{}{"operation": "parse", "content": "<a><b></a>"}); err == nil {
+		t.Error("expected malformed XML to fail")
+	}
+	if _, _, err := runXML(t, map[string]interface{}{"operation": "serialize", "data": map[string]interface{}{"a": 1.0, "b": 2.0}}); err == nil {
+		t.Error("expected several root elements to fail")
+	}
+}
+
+// TestBunRunner_ExecuteNode_SOAP verifies that std/xml wraps the body in a SOAP
+// envelope, unwraps the response body and routes faults to the "fault" port.
+func TestBunRunner_ExecuteNode_SOAP(t *testing.T) {
+	var gotAction, gotType, gotEnvelope string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAction, gotType = r.Header.Get("SOAPAction"), r.Header.Get("Content-Type")
+		b, _ := io.ReadAll(r.Body)
+		gotEnvelope = string(b)
+		w.Header().Set("Content-Type", "text/xml")
+		if strings.Contains(gotEnvelope, "<Id>0</Id>") {
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>`+
+				`<s:Fault><faultcode>s:Client</faultcode><faultstring>unknown order</faultstring></s:Fault></s:Body></s:Envelope>`)
+			return
+		}
+		io.WriteString(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>`+
+			`<GetOrderResponse xmlns="urn:erp"><Status>shipped</Status></GetOrderResponse></s:Body></s:Envelope>`)
+	}))
+	defer srv.Close()
+
+	data, port, err := runXML(t, map[string]interface{}{
+		"operation": "soap",
+		"url":       srv.URL,
+		"action":    "urn:GetOrder",
+		"body":      `<GetOrder xmlns="urn:erp"><Id>42</Id></GetOrder>`,
+		"header":    map[string]interface{}{"Auth": map[string]interface{}{"@xmlns": "urn:erp", "Token": "secret"}},
+	})
+	if err != nil {
+		t.Fatalf("soap call failed: %v", err)
+	}
+	if port != "default" {
+		t.Errorf("expected port default, got %q", port)
+	}
+	if gotAction != `"urn:GetOrder"` || !strings.HasPrefix(gotType, "text/xml") {
+		t.Errorf("unexpected SOAPAction %q or Content-Type %q", gotAction, gotType)
+	}
+	if !strings.Contains(gotEnvelope, `<soap:Header><Auth xmlns="urn:erp"><Token>secret</Token></Auth></soap:Header>`) ||
+		!strings.Contains(gotEnvelope, `<soap:Body><GetOrder xmlns="urn:erp"><Id>42</Id></GetOrder></soap:Body>`) {
+		t.Errorf("unexpected envelope %s", gotEnvelope)
+	}
+	body, _ := json.Marshal(data["body"])
+	if string(body) != `{"GetOrderResponse":{"@xmlns":"urn:erp","Status":"shipped"}}` {
+		t.Errorf("unexpected body %s", body)
+	}
+
+	data, port, err = runXML(t, map[string]interface{}{
+		"operation": "soap",
+		"url":       srv.URL,
+		"body":      `<GetOrder xmlns="urn:erp"><Id>0</Id></GetOrder>`,
+	})
+	if err != nil {
+		t.Fatalf("expected a fault to be routed, got error: %v", err)
+	}
+	fault, _ := data["fault"].(map[string]interface{})
+	if port != "fault" || data["status"] != 500 || fault["faultstring"] != "unknown order" {
+		t.Errorf("unexpected fault result on port %q: %v", port, data)
+	}
+}
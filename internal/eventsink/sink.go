@@ -0,0 +1,204 @@
+// Package eventsink publishes engine events to a message broker, so that other
+// systems can build monitoring on the stream of execution events.
+package eventsink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+)
+
+// Config selects the broker events are published to.
+type Config struct {
+	// Kind is "kafka" or "nats".
+	Kind string
+	// Servers are the Kafka brokers or NATS servers as host:port.
+	Servers []string
+	// Topic is the Kafka topic, or the NATS subject prefix: each event goes to
+	// <prefix>.<event type>, e.g. conv3n.events.execution.finished.
+	Topic string
+}
+
+// String returns the config in its URL form.
+func (c Config) String() string {
+	return c.Kind + "://" + strings.Join(c.Servers, ",") + "/" + c.Topic
+}
+
+// ParseURL parses a sink URL: kafka://broker1:9092,broker2:9092/topic or
+// nats://localhost:4222/subject.prefix.
+func ParseURL(raw string) (Config, error) {
+	kind, rest, ok := strings.Cut(raw, "://")
+	if !ok || (kind != "kafka" && kind != "nats") {
+		return Config{}, fmt.Errorf("event sink URL must start with kafka:// or nats://, got %q", raw)
+	}
+	servers, topic, _ := strings.Cut(rest, "/")
+	if servers == "" || topic == "" {
+		return Config{}, fmt.Errorf("event sink URL must name servers and a topic, got %q", raw)
+	}
+	return Config{Kind: kind, Servers: strings.Split(servers, ","), Topic: topic}, nil
+}
+
+// ConfigFromEnv reads the sink URL from CONV3N_EVENT_SINK. Returns ok=false when
+// it is unset, and logs and ignores an invalid URL.
+func ConfigFromEnv() (Config, bool) {
+	raw := os.Getenv("CONV3N_EVENT_SINK")
+	if raw == "" {
+		return Config{}, false
+	}
+	cfg, err := ParseURL(raw)
+	if err != nil {
+		log.Printf("Warning: invalid CONV3N_EVENT_SINK: %v", err)
+		return Config{}, false
+	}
+	return cfg, true
+}
+
+// publishedEvents are the event types a sink publishes; node output lines are
+// left out as they can be far more frequent than everything else.
+var publishedEvents = []engine.EventType{
+	engine.EventExecutionStarted,
+	engine.EventExecutionFinished,
+	engine.EventNodeFinished,
+	engine.EventTriggerFired,
+	engine.EventTriggerCrashed,
+	engine.EventWorkflowReport,
+}
+
+// publisher sends encoded events to a broker without waiting for them to be
+// acknowledged.
+type publisher interface {
+	publish(ev engine.Event, key string, value []byte) error
+	close() error
+}
+
+// Sink publishes the events of an EventBus to a broker.
+type Sink struct {
+	pub         publisher
+	unsubscribe func()
+}
+
+// Start connects to the broker in cfg and publishes the lifecycle events of bus
+// until Close. Publishing is asynchronous: failures are logged and the events
+// dropped, so a broker outage never blocks workflow execution.
+func Start(cfg Config, bus *engine.EventBus) (*Sink, error) {
+	var pub publisher
+	switch cfg.Kind {
+	case "kafka":
+		pub = newKafkaPublisher(cfg)
+	case "nats":
+		var err error
+		if pub, err = newNATSPublisher(cfg); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported event sink %q", cfg.Kind)
+	}
+
+	s := &Sink{pub: pub}
+	s.unsubscribe = bus.Subscribe(func(ev engine.Event) {
+		key, value, err := Encode(ev)
+		if err != nil {
+			log.Printf("Event sink: failed to encode %s event: %v", ev.EventType(), err)
+			return
+		}
+		if err := pub.publish(ev, key, value); err != nil {
+			log.Printf("Event sink: failed to publish %s event: %v", ev.EventType(), err)
+		}
+	}, publishedEvents...)
+	return s, nil
+}
+
+// Close stops publishing and flushes events still buffered for the broker.
+func (s *Sink) Close() error {
+	s.unsubscribe()
+	return s.pub.close()
+}
+
+// Encode returns the message for ev: its JSON fields plus "type", keyed by the
+// execution ID, the trigger ID for trigger events or the workflow ID for
+// reports, so that a Kafka partition keeps each execution's events in order.
+func Encode(ev engine.Event) (string, []byte, error) {
+	raw, err := json.Marshal(ev)
+	if err != nil {
+		return "", nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return "", nil, err
+	}
+	fields["type"] = ev.EventType()
+
+	key, _ := fields["execution_id"].(string)
+	if key == "" {
+		key, _ = fields["trigger_id"].(string)
+	}
+	if key == "" {
+		key, _ = fields["workflow_id"].(string)
+	}
+	value, err := json.Marshal(fields)
+	return key, value, err
+}
+
+// kafkaPublisher writes events to a Kafka topic.
+type kafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+func newKafkaPublisher(cfg Config) *kafkaPublisher {
+	return &kafkaPublisher{writer: &kafka.Writer{
+		Addr:         kafka.TCP(cfg.Servers...),
+		Topic:        cfg.Topic,
+		Balancer:     &kafka.Hash{},
+		BatchTimeout: 100 * time.Millisecond,
+		Async:        true,
+		Completion: func(messages []kafka.Message, err error) {
+			if err != nil {
+				log.Printf("Event sink: failed to write %d events to Kafka: %v", len(messages), err)
+			}
+		},
+	}}
+}
+
+func (p *kafkaPublisher) publish(_ engine.Event, key string, value []byte) error {
+	return p.writer.WriteMessages(context.Background(), kafka.Message{Key: []byte(key), Value: value})
+}
+
+func (p *kafkaPublisher) close() error {
+	return p.writer.Close()
+}
+
+// natsPublisher publishes events to NATS subjects under a prefix.
+type natsPublisher struct {
+	conn   *nats.Conn
+	prefix string
+}
+
+func newNATSPublisher(cfg Config) (*natsPublisher, error) {
+	urls := make([]string, len(cfg.Servers))
+	for i, server := range cfg.Servers {
+		urls[i] = "nats://" + server
+	}
+	conn, err := nats.Connect(strings.Join(urls, ","), nats.Name("conv3n"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	return &natsPublisher{conn: conn, prefix: cfg.Topic}, nil
+}
+
+func (p *natsPublisher) publish(ev engine.Event, _ string, value []byte) error {
+	return p.conn.Publish(p.prefix+"."+string(ev.EventType()), value)
+}
+
+func (p *natsPublisher) close() error {
+	err := p.conn.Flush()
+	p.conn.Close()
+	return err
+}
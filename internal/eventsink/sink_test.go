@@ -0,0 +1,131 @@
+package eventsink_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/eventsink"
+)
+
+func TestParseURL(t *testing.T) {
+	cfg, err := eventsink.ParseURL("kafka://broker1:9092,broker2:9092/conv3n-events")
+	if err != nil {
+		t.Fatalf("ParseURL failed: %v", err)
+	}
+	if cfg.Kind != "kafka" || len(cfg.Servers) != 2 || cfg.Servers[1] != "broker2:9092" || cfg.Topic != "conv3n-events" {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	if cfg.String() != "kafka://broker1:9092,broker2:9092/conv3n-events" {
+		t.Errorf("unexpected URL %s", cfg)
+	}
+
+	for _, raw := range []string{"amqp://localhost/events", "nats://localhost:4222", "nats:///events", "localhost:4222/events"} {
+		if _, err := eventsink.ParseURL(raw); err == nil {
+			t.Errorf("%s: expected an error", raw)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	key, value, err := eventsink.Encode(engine.ExecutionFinished{
+		WorkflowID:  "wf-1",
+		ExecutionID: "exec-1",
+		Status:      "completed",
+		Time:        time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	want := `{"duration":0,"execution_id":"exec-1","status":"completed","time":"2025-01-02T03:04:05Z","type":"execution.finished","workflow_id":"wf-1"}`
+	if key != "exec-1" || string(value) != want {
+		t.Errorf("expected key exec-1 and %s, got %q and %s", want, key, value)
+	}
+
+	if key, _, _ := eventsink.Encode(engine.TriggerFired{TriggerID: "tr-1", WorkflowID: "wf-1"}); key != "tr-1" {
+		t.Errorf("expected trigger events to be keyed by trigger ID, got %q", key)
+	}
+	if key, _, _ := eventsink.Encode(engine.WorkflowReport{WorkflowID: "wf-1"}); key != "wf-1" {
+		t.Errorf("expected reports to be keyed by workflow ID, got %q", key)
+	}
+}
+
+// fakeNATS accepts one NATS client connection and sends the subject and payload
+// of every PUB it receives to the returned channel.
+func fakeNATS(t *testing.T) (string, <-chan [2]string) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { lis.Close() })
+
+	published := make(chan [2]string, 16)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(conn, "INFO {\"server_id\":\"fake\",\"version\":\"2.10.0\",\"max_payload\":1048576}\r\n")
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch {
+			case len(fields) == 0:
+			case fields[0] == "PING":
+				fmt.Fprintf(conn, "PONG\r\n")
+			case fields[0] == "PUB" && len(fields) >= 3:
+				size, _ := strconv.Atoi(fields[len(fields)-1])
+				payload := make([]byte, size+2) // payload and \r\n
+				if _, err := io.ReadFull(r, payload); err != nil {
+					return
+				}
+				published <- [2]string{fields[1], string(payload[:size])}
+			}
+		}
+	}()
+	return lis.Addr().String(), published
+}
+
+func TestSink_NATS(t *testing.T) {
+	addr, published := fakeNATS(t)
+	bus := engine.NewEventBus()
+
+	sink, err := eventsink.Start(eventsink.Config{Kind: "nats", Servers: []string{addr}, Topic: "conv3n.events"}, bus)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	bus.Publish(engine.NodeOutput{WorkflowID: "wf-1", ExecutionID: "exec-1", NodeID: "n1", Line: "noise"})
+	bus.Publish(engine.ExecutionStarted{WorkflowID: "wf-1", ExecutionID: "exec-1"})
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	select {
+	case msg := <-published:
+		var fields map[string]interface{}
+		json.Unmarshal([]byte(msg[1]), &fields)
+		if msg[0] != "conv3n.events.execution.started" || fields["execution_id"] != "exec-1" {
+			t.Errorf("unexpected message on %s: %s", msg[0], msg[1])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event was published")
+	}
+	select {
+	case msg := <-published:
+		t.Errorf("expected node output not to be published, got %s on %s", msg[1], msg[0])
+	case <-time.After(50 * time.Millisecond):
+	}
+}
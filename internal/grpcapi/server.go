@@ -0,0 +1,335 @@
+// Package grpcapi serves the conv3n.v1.WorkflowService gRPC API (see
+// proto/conv3n/v1/conv3n.proto) next to the REST API, for services that
+// integrate over gRPC.
+package grpcapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/grpcapi/conv3nv1"
+	"github.com/conv3n/conv3n/internal/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server implements conv3nv1.WorkflowServiceServer on top of the same storage,
+// registry and event bus as the REST API.
+type Server struct {
+	conv3nv1.UnimplementedWorkflowServiceServer
+
+	Store     storage.Storage
+	BlocksDir string
+	Registry  *engine.ExecutionRegistry
+	Events    *engine.EventBus
+	Delays    *engine.DelayScheduler // Optional; long delay and enqueue nodes park runs
+	Quotas    *engine.Quotas         // Optional; runs count against the workflow's quota
+}
+
+// NewServer creates a new gRPC API server
+func NewServer(store storage.Storage, blocksDir string, registry *engine.ExecutionRegistry, events *engine.EventBus, delays *engine.DelayScheduler) *Server {
+	return &Server{
+		Store:     store,
+		BlocksDir: blocksDir,
+		Registry:  registry,
+		Events:    events,
+		Delays:    delays,
+	}
+}
+
+// NewGRPCServer returns a grpc.Server serving s. With a non-empty apiKey every
+// call must send it as "authorization: Bearer <key>" or "x-api-key: <key>"
+// metadata, like the REST API's headers.
+func NewGRPCServer(s *Server, apiKey string) *grpc.Server {
+	var opts []grpc.ServerOption
+	if apiKey != "" {
+		opts = append(opts,
+			grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				if err := checkAPIKey(ctx, apiKey); err != nil {
+					return nil, err
+				}
+				return handler(ctx, req)
+			}),
+			grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				if err := checkAPIKey(ss.Context(), apiKey); err != nil {
+					return err
+				}
+				return handler(srv, ss)
+			}),
+		)
+	}
+	gs := grpc.NewServer(opts...)
+	conv3nv1.RegisterWorkflowServiceServer(gs, s)
+	return gs
+}
+
+// checkAPIKey verifies the API key sent in the call's metadata.
+func checkAPIKey(ctx context.Context, key string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var provided string
+	if v := md.Get("x-api-key"); len(v) > 0 {
+		provided = v[0]
+	} else if v := md.Get("authorization"); len(v) > 0 && strings.HasPrefix(v[0], "Bearer ") {
+		provided = strings.TrimPrefix(v[0], "Bearer ")
+	}
+	if subtle.ConstantTimeCompare([]byte(provided), []byte(key)) != 1 {
+		return status.Error(codes.Unauthenticated, "invalid or missing API key")
+	}
+	return nil
+}
+
+// ListWorkflows lists the stored workflows.
+func (s *Server) ListWorkflows(ctx context.Context, _ *conv3nv1.ListWorkflowsRequest) (*conv3nv1.ListWorkflowsResponse, error) {
+	workflows, err := s.Store.ListWorkflows(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list workflows: %v", err)
+	}
+	resp := &conv3nv1.ListWorkflowsResponse{}
+	for _, wf := range workflows {
+		msg, err := toWorkflow(wf)
+		if err != nil {
+			return nil, err
+		}
+		resp.Workflows = append(resp.Workflows, msg)
+	}
+	return resp, nil
+}
+
+// GetWorkflow returns a stored workflow with its definition.
+func (s *Server) GetWorkflow(ctx context.Context, req *conv3nv1.GetWorkflowRequest) (*conv3nv1.Workflow, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	wf, err := s.Store.GetWorkflow(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "workflow not found: %v", err)
+	}
+	return toWorkflow(wf)
+}
+
+// ListExecutions lists a workflow's executions, newest first.
+func (s *Server) ListExecutions(ctx context.Context, req *conv3nv1.ListExecutionsRequest) (*conv3nv1.ListExecutionsResponse, error) {
+	if req.GetWorkflowId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "workflow_id is required")
+	}
+	limit := 20
+	if v := int(req.GetLimit()); v > 0 && v <= 100 {
+		limit = v
+	}
+
+	execs, err := s.Store.ListExecutions(ctx, req.GetWorkflowId(), limit)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list executions: %v", err)
+	}
+	resp := &conv3nv1.ListExecutionsResponse{}
+	for _, e := range execs {
+		resp.Executions = append(resp.Executions, toExecution(e))
+	}
+	return resp, nil
+}
+
+// GetExecution returns an execution with its saved state.
+func (s *Server) GetExecution(ctx context.Context, req *conv3nv1.GetExecutionRequest) (*conv3nv1.Execution, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	exec, err := s.Store.GetExecution(ctx, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "execution not found: %v", err)
+	}
+	msg := toExecution(exec)
+	if len(exec.State) > 0 {
+		var state map[string]interface{}
+		if err := json.Unmarshal(exec.State, &state); err == nil {
+			if msg.State, err = toStruct(state); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return msg, nil
+}
+
+// Run runs a stored or inline workflow and waits for it to finish, or to park
+// in a long delay or enqueue node. A failed run is reported in the response.
+func (s *Server) Run(ctx context.Context, req *conv3nv1.RunRequest) (*conv3nv1.RunResponse, error) {
+	var wf engine.Workflow
+	switch {
+	case req.GetWorkflowId() != "":
+		stored, err := s.Store.GetWorkflow(ctx, req.GetWorkflowId())
+		if err != nil {
+			return nil, status.Errorf(codes.NotFound, "workflow not found: %v", err)
+		}
+		if err := json.Unmarshal(stored.Definition, &wf); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to parse workflow: %v", err)
+		}
+	case req.GetDefinition() != nil:
+		raw, _ := json.Marshal(req.GetDefinition().AsMap())
+		if err := json.Unmarshal(raw, &wf); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid workflow definition: %v", err)
+		}
+	default:
+		return nil, status.Error(codes.InvalidArgument, "workflow_id or definition is required")
+	}
+
+	ectx := engine.NewExecutionContext(wf.ID)
+	ectx.Environment = req.GetEnvironment()
+	if req.GetTriggerData() != nil {
+		ectx.TriggerData = req.GetTriggerData().AsMap()
+	}
+	runner := engine.NewWorkflowRunner(ectx, s.BlocksDir, s.Store, s.Registry)
+	if s.Delays != nil {
+		runner.SetDelayScheduler(s.Delays)
+	}
+
+	runner.SetEventBus(s.Events)
+	runner.SetQuotas(s.Quotas)
+
+	execID, err := runner.CreateExecution(ctx, wf.ID)
+	var quotaErr *engine.QuotaExceededError
+	if errors.As(err, &quotaErr) {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	runErr := runner.Run(ctx, wf)
+
+	resp := &conv3nv1.RunResponse{ExecutionId: execID, Status: string(storage.ExecutionStatusFailed)}
+	if exec, err := s.Store.GetExecution(ctx, execID); err == nil {
+		resp.Status = string(exec.Status)
+	}
+	if runErr != nil {
+		resp.Error = runErr.Error()
+	}
+	results, err := toStruct(ectx.Results())
+	if err != nil {
+		return nil, err
+	}
+	resp.Results = results
+	return resp, nil
+}
+
+// streamedEvents are the event types Stream sends.
+var streamedEvents = []engine.EventType{
+	engine.EventExecutionStarted,
+	engine.EventExecutionFinished,
+	engine.EventNodeFinished,
+	engine.EventNodeOutput,
+}
+
+// Stream sends execution and node events matching the request's filters until
+// the client cancels the call. Like any event bus subscriber, a client that
+// falls too far behind misses events.
+func (s *Server) Stream(req *conv3nv1.StreamRequest, stream conv3nv1.WorkflowService_StreamServer) error {
+	ctx := stream.Context()
+	events := make(chan *conv3nv1.Event)
+	done := make(chan struct{})
+
+	unsubscribe := s.Events.Subscribe(func(ev engine.Event) {
+		msg := toEvent(ev)
+		if (req.GetWorkflowId() != "" && msg.WorkflowId != req.GetWorkflowId()) ||
+			(req.GetExecutionId() != "" && msg.ExecutionId != req.GetExecutionId()) {
+			return
+		}
+		select {
+		case events <- msg:
+		case <-done:
+		}
+	}, streamedEvents...)
+	defer unsubscribe()
+	defer close(done) // runs first, releasing a handler blocked on events
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-events:
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// toWorkflow converts a stored workflow to its message.
+func toWorkflow(wf *storage.Workflow) (*conv3nv1.Workflow, error) {
+	msg := &conv3nv1.Workflow{
+		Id:        wf.ID,
+		Name:      wf.Name,
+		CreatedAt: timestamppb.New(wf.CreatedAt),
+		UpdatedAt: timestamppb.New(wf.UpdatedAt),
+	}
+	var definition map[string]interface{}
+	if err := json.Unmarshal(wf.Definition, &definition); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to parse workflow %s: %v", wf.ID, err)
+	}
+	var err error
+	if msg.Definition, err = toStruct(definition); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// toExecution converts a stored execution to its message, without its state.
+func toExecution(e *storage.Execution) *conv3nv1.Execution {
+	msg := &conv3nv1.Execution{
+		Id:         e.ID,
+		WorkflowId: e.WorkflowID,
+		Status:     string(e.Status),
+		StartedAt:  timestamppb.New(e.StartedAt),
+	}
+	if e.CompletedAt != nil {
+		msg.CompletedAt = timestamppb.New(*e.CompletedAt)
+	}
+	if e.Error != nil {
+		msg.Error = *e.Error
+	}
+	return msg
+}
+
+// toEvent converts an engine event to its message.
+func toEvent(ev engine.Event) *conv3nv1.Event {
+	msg := &conv3nv1.Event{Type: string(ev.EventType()), Time: timestamppb.New(ev.EventTime())}
+	switch e := ev.(type) {
+	case engine.ExecutionStarted:
+		msg.WorkflowId, msg.ExecutionId = e.WorkflowID, e.ExecutionID
+	case engine.ExecutionFinished:
+		msg.WorkflowId, msg.ExecutionId = e.WorkflowID, e.ExecutionID
+		msg.Status, msg.Error = e.Status, e.Error
+		msg.DurationMs = e.Duration.Milliseconds()
+	case engine.NodeFinished:
+		msg.WorkflowId, msg.ExecutionId, msg.NodeId = e.WorkflowID, e.ExecutionID, e.NodeID
+		msg.Port, msg.Error = e.Port, e.Error
+		msg.DurationMs = e.Duration.Milliseconds()
+	case engine.NodeOutput:
+		msg.WorkflowId, msg.ExecutionId, msg.NodeId = e.WorkflowID, e.ExecutionID, e.NodeID
+		msg.Stream, msg.Line = e.Stream, e.Line
+	}
+	return msg
+}
+
+// toStruct converts a JSON-like value to a Struct, going through JSON so that
+// values structpb doesn't know (typed slices and maps, times) keep their JSON form.
+func toStruct(v map[string]interface{}) (*structpb.Struct, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode value: %v", err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode value: %v", err)
+	}
+	s, err := structpb.NewStruct(m)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode value: %v", err)
+	}
+	return s, nil
+}
@@ -0,0 +1,150 @@
+package grpcapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/engine"
+	"github.com/conv3n/conv3n/internal/grpcapi"
+	"github.com/conv3n/conv3n/internal/grpcapi/conv3nv1"
+	"github.com/conv3n/conv3n/internal/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// newTestClient serves a grpcapi.Server over an in-memory connection and
+// returns a client for it along with its storage.
+func newTestClient(t *testing.T, apiKey string) (conv3nv1.WorkflowServiceClient, storage.Storage) {
+	t.Helper()
+	store, err := storage.NewSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	server := grpcapi.NewServer(store, t.TempDir(), engine.NewExecutionRegistry(), engine.NewEventBus(), nil)
+	gs := grpcapi.NewGRPCServer(server, apiKey)
+	lis := bufconn.Listen(1 << 20)
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conv3nv1.NewWorkflowServiceClient(conn), store
+}
+
+// greetWorkflow stores a workflow whose only node sets a variable from the trigger data.
+func greetWorkflow(t *testing.T, store storage.Storage) {
+	t.Helper()
+	wf := engine.Workflow{
+		ID:   "wf-greet",
+		Name: "Greet",
+		Nodes: map[string]engine.Node{
+			"greet": {ID: "greet", Type: engine.NodeTypeSetVar, Config: map[string]interface{}{
+				"name": "greeting", "value": "hello {{ $trigger.name }}",
+			}},
+		},
+	}
+	def, _ := json.Marshal(wf)
+	if err := store.CreateWorkflow(context.Background(), &storage.Workflow{ID: wf.ID, Name: wf.Name, Definition: def}); err != nil {
+		t.Fatalf("failed to create workflow: %v", err)
+	}
+}
+
+func TestWorkflowService(t *testing.T) {
+	client, store := newTestClient(t, "")
+	greetWorkflow(t, store)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	list, err := client.ListWorkflows(ctx, &conv3nv1.ListWorkflowsRequest{})
+	if err != nil || len(list.Workflows) != 1 || list.Workflows[0].Id != "wf-greet" {
+		t.Fatalf("unexpected workflow list %v (%v)", list, err)
+	}
+	wf, err := client.GetWorkflow(ctx, &conv3nv1.GetWorkflowRequest{Id: "wf-greet"})
+	if err != nil || wf.Definition.Fields["nodes"].GetStructValue().Fields["greet"] == nil {
+		t.Fatalf("unexpected workflow %v (%v)", wf, err)
+	}
+	if _, err := client.GetWorkflow(ctx, &conv3nv1.GetWorkflowRequest{Id: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+
+	stream, err := client.Stream(ctx, &conv3nv1.StreamRequest{WorkflowId: "wf-greet"})
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	// Stream subscribes when the call reaches the server; wait until it has
+	time.Sleep(100 * time.Millisecond)
+
+	trigger, _ := structpb.NewStruct(map[string]interface{}{"name": "ada"})
+	run, err := client.Run(ctx, &conv3nv1.RunRequest{Workflow: &conv3nv1.RunRequest_WorkflowId{WorkflowId: "wf-greet"}, TriggerData: trigger})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if run.Status != string(storage.ExecutionStatusCompleted) || run.Error != "" {
+		t.Fatalf("unexpected run %v", run)
+	}
+	greet := run.Results.Fields["greet"].GetStructValue().Fields["data"].GetStructValue()
+	if greet.Fields["value"].GetStringValue() != "hello ada" {
+		t.Errorf("unexpected results %v", run.Results)
+	}
+
+	var types []string
+	for len(types) < 3 {
+		ev, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv failed after %v: %v", types, err)
+		}
+		if ev.ExecutionId != run.ExecutionId {
+			t.Errorf("unexpected event %v", ev)
+		}
+		types = append(types, ev.Type)
+	}
+	if types[0] != "execution.started" || types[1] != "node.finished" || types[2] != "execution.finished" {
+		t.Errorf("unexpected events %v", types)
+	}
+
+	execs, err := client.ListExecutions(ctx, &conv3nv1.ListExecutionsRequest{WorkflowId: "wf-greet"})
+	if err != nil || len(execs.Executions) != 1 || execs.Executions[0].Id != run.ExecutionId {
+		t.Fatalf("unexpected executions %v (%v)", execs, err)
+	}
+	exec, err := client.GetExecution(ctx, &conv3nv1.GetExecutionRequest{Id: run.ExecutionId})
+	if err != nil || exec.Status != string(storage.ExecutionStatusCompleted) {
+		t.Errorf("unexpected execution %v (%v)", exec, err)
+	}
+
+	if _, err := client.Run(ctx, &conv3nv1.RunRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument without a workflow, got %v", err)
+	}
+}
+
+func TestWorkflowService_APIKey(t *testing.T) {
+	client, _ := newTestClient(t, "secret")
+	ctx := context.Background()
+
+	if _, err := client.ListWorkflows(ctx, &conv3nv1.ListWorkflowsRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated without a key, got %v", err)
+	}
+	wrong := metadata.AppendToOutgoingContext(ctx, "x-api-key", "wrong")
+	if _, err := client.ListWorkflows(wrong, &conv3nv1.ListWorkflowsRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated with a wrong key, got %v", err)
+	}
+	authed := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+	if _, err := client.ListWorkflows(authed, &conv3nv1.ListWorkflowsRequest{}); err != nil {
+		t.Errorf("expected the key to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// DetachTimeout bounds the writes made with a context from Detach.
+const DetachTimeout = 10 * time.Second
+
+// Detach returns the context to persist with when the write must happen even
+// though ctx may be done: recording that a run was cancelled or timed out, or
+// applying a change a client asked for before disconnecting. The context
+// keeps ctx's values, such as the trace span, but not its cancellation or
+// deadline; DetachTimeout bounds it instead, so a stuck database can't hold
+// the caller forever. Call cancel once the writes are done.
+func Detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), DetachTimeout)
+}
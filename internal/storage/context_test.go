@@ -0,0 +1,43 @@
+package storage_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+type ctxKey struct{}
+
+func TestDetach(t *testing.T) {
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "span"))
+	cancel()
+
+	ctx, cancelDetached := storage.Detach(parent)
+	defer cancelDetached()
+	if ctx.Err() != nil {
+		t.Fatalf("expected the detached context to outlive its parent, got %v", ctx.Err())
+	}
+	if ctx.Value(ctxKey{}) != "span" {
+		t.Error("expected the detached context to keep the parent's values")
+	}
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > storage.DetachTimeout {
+		t.Errorf("expected a deadline within %s, got %v (%v)", storage.DetachTimeout, deadline, ok)
+	}
+
+	// A write made after the request was cancelled still lands
+	store, err := storage.NewSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+	execID, _ := store.CreateExecution(context.Background(), "wf")
+	if _, err := store.TransitionExecutionStatus(parent, execID, storage.ExecutionStatusRunning, storage.ExecutionStatusCancelled, nil, nil); err == nil {
+		t.Error("expected a write with the cancelled context to fail")
+	}
+	if ok, err := store.TransitionExecutionStatus(ctx, execID, storage.ExecutionStatusRunning, storage.ExecutionStatusCancelled, nil, nil); err != nil || !ok {
+		t.Errorf("expected the detached write to succeed, got %v (%v)", ok, err)
+	}
+}
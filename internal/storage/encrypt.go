@@ -0,0 +1,510 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/conv3n/conv3n/internal/awsauth"
+)
+
+// KeyProvider wraps the data keys EncryptedStorage encrypts payloads with under
+// a key encryption key kept outside the database, e.g. in a KMS.
+type KeyProvider interface {
+	WrapKey(ctx context.Context, key []byte) ([]byte, error)
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// LocalKey is a KeyProvider wrapping data keys with an AES-256 key held by the
+// server, for deployments without a KMS.
+type LocalKey struct {
+	aead cipher.AEAD
+}
+
+// NewLocalKey returns a LocalKey for key, which must be 32 bytes long.
+func NewLocalKey(key []byte) (*LocalKey, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalKey{aead: aead}, nil
+}
+
+// WrapKey implements KeyProvider.
+func (k *LocalKey) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	return seal(k.aead, key, nil)
+}
+
+// UnwrapKey implements KeyProvider.
+func (k *LocalKey) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	return open(k.aead, wrapped, nil)
+}
+
+// AWSKMS is a KeyProvider wrapping data keys with an AWS KMS key.
+type AWSKMS struct {
+	KeyID       string // Key ID, ARN or alias, e.g. "alias/conv3n"
+	Region      string
+	Credentials awsauth.Credentials
+	Endpoint    string       // https://kms.<region>.amazonaws.com if empty
+	Client      *http.Client // http.DefaultClient if nil
+}
+
+func (k *AWSKMS) client() *awsauth.Client {
+	return &awsauth.Client{Service: "kms", Region: k.Region, Credentials: k.Credentials, Endpoint: k.Endpoint, HTTPClient: k.Client}
+}
+
+// WrapKey implements KeyProvider.
+func (k *AWSKMS) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	var out struct{ CiphertextBlob []byte }
+	if err := k.client().Call(ctx, "TrentService.Encrypt", map[string]interface{}{"KeyId": k.KeyID, "Plaintext": key}, &out); err != nil {
+		return nil, fmt.Errorf("kms encrypt: %w", err)
+	}
+	return out.CiphertextBlob, nil
+}
+
+// UnwrapKey implements KeyProvider.
+func (k *AWSKMS) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var out struct{ Plaintext []byte }
+	if err := k.client().Call(ctx, "TrentService.Decrypt", map[string]interface{}{"KeyId": k.KeyID, "CiphertextBlob": wrapped}, &out); err != nil {
+		return nil, fmt.Errorf("kms decrypt: %w", err)
+	}
+	return out.Plaintext, nil
+}
+
+// encryptedMagic starts the payloads EncryptedStorage writes: it is followed by
+// the length of the wrapped data key (2 bytes), the wrapped key, and the
+// payload sealed with AES-256-GCM under the data key, with the identity of its
+// row (see payloadAAD) as additional data so that it can't be copied to another
+// row. JSON never starts with it, so payloads written before encryption was
+// turned on read as they are.
+var encryptedMagic = []byte("c3ne\x02")
+
+// encryptedMagicV1 starts the payloads written before they were bound to their
+// row; they are opened without additional data.
+var encryptedMagicV1 = []byte("c3ne\x01")
+
+// payloadAAD returns the additional data binding a payload to its row: the
+// kind of payload and the IDs of the row, such as an execution and node ID.
+func payloadAAD(kind string, ids ...string) []byte {
+	return []byte(kind + "\x00" + strings.Join(ids, "\x00"))
+}
+
+// dataKeyLifetime is how long EncryptedStorage encrypts with one data key
+// before generating another, so that the KMS is called once in a while rather
+// than for every write.
+const dataKeyLifetime = time.Hour
+
+// envelope seals and opens payloads with data keys wrapped by keys.
+type envelope struct {
+	keys KeyProvider
+
+	mu      sync.Mutex
+	key     cipher.AEAD // Current data key
+	wrapped []byte      // It, wrapped
+	expires time.Time
+	// opened caches the data keys already unwrapped, by wrapped key, so that
+	// reads don't call the KMS for every payload
+	opened map[string]cipher.AEAD
+}
+
+// current returns the data key to seal with and its wrapped form.
+func (e *envelope) current(ctx context.Context) (cipher.AEAD, []byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.key != nil && time.Now().Before(e.expires) {
+		return e.key, e.wrapped, nil
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := e.keys.WrapKey(ctx, raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	if len(wrapped) > 0xffff {
+		return nil, nil, fmt.Errorf("wrapped data key too long (%d bytes)", len(wrapped))
+	}
+	aead, err := newGCM(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	e.key, e.wrapped, e.expires = aead, wrapped, time.Now().Add(dataKeyLifetime)
+	e.opened[string(wrapped)] = aead
+	return aead, wrapped, nil
+}
+
+// seal encrypts payload bound to aad, leaving empty payloads as they are.
+func (e *envelope) seal(ctx context.Context, payload, aad []byte) ([]byte, error) {
+	if len(payload) == 0 {
+		return payload, nil
+	}
+	aead, wrapped, err := e.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := seal(aead, payload, aad)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(encryptedMagic)+2+len(wrapped)+len(sealed))
+	out = append(out, encryptedMagic...)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(wrapped)))
+	out = append(out, wrapped...)
+	return append(out, sealed...), nil
+}
+
+// open decrypts data sealed by seal under the same aad, returning other data
+// as it is.
+func (e *envelope) open(ctx context.Context, data, aad []byte) ([]byte, error) {
+	var rest []byte
+	switch {
+	case bytes.HasPrefix(data, encryptedMagic):
+		rest = data[len(encryptedMagic):]
+	case bytes.HasPrefix(data, encryptedMagicV1):
+		rest, aad = data[len(encryptedMagicV1):], nil
+	default:
+		return data, nil
+	}
+	if len(rest) < 2 || len(rest) < 2+int(binary.BigEndian.Uint16(rest)) {
+		return nil, errors.New("encrypted payload is truncated")
+	}
+	n := int(binary.BigEndian.Uint16(rest))
+	wrapped, sealed := rest[2:2+n], rest[2+n:]
+
+	e.mu.Lock()
+	aead, ok := e.opened[string(wrapped)]
+	e.mu.Unlock()
+	if !ok {
+		raw, err := e.keys.UnwrapKey(ctx, wrapped)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+		}
+		if aead, err = newGCM(raw); err != nil {
+			return nil, err
+		}
+		e.mu.Lock()
+		e.opened[string(wrapped)] = aead
+		e.mu.Unlock()
+	}
+	payload, err := open(aead, sealed, aad)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt payload: %w", err)
+	}
+	return payload, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext and authenticates additionalData with aead under a
+// random nonce, which it prepends.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts what seal returned.
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additionalData)
+}
+
+// EncryptedStorage is a Storage encrypting the payloads workflows process at
+// rest, for deployments handling personal data: execution state, trigger data,
+// node results and inputs, workflow static data, trigger payloads and
+// snapshots. It uses envelope encryption: payloads are sealed with AES-256-GCM
+// under data keys that keys wraps and that are stored, wrapped, with them, and
+// bound to their row. Payloads stored before encryption was turned on are read
+// as they are. Methods of Storage not wrapped here pass through unencrypted;
+// TestEncryptedStorage_CoversPayloads lists those that may.
+// SearchExecutions no longer finds words of encrypted node results.
+type EncryptedStorage struct {
+	Storage
+	env *envelope
+}
+
+// NewEncryptedStorage returns store with its payloads encrypted under keys.
+func NewEncryptedStorage(store Storage, keys KeyProvider) *EncryptedStorage {
+	return &EncryptedStorage{Storage: store, env: &envelope{keys: keys, opened: make(map[string]cipher.AEAD)}}
+}
+
+// encryptedTx is a Tx of an EncryptedStorage.
+type encryptedTx struct {
+	*EncryptedStorage
+	tx Tx
+}
+
+func (t *encryptedTx) Commit() error   { return t.tx.Commit() }
+func (t *encryptedTx) Rollback() error { return t.tx.Rollback() }
+
+func (s *EncryptedStorage) Begin(ctx context.Context) (Tx, error) {
+	tx, err := s.Storage.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedTx{EncryptedStorage: &EncryptedStorage{Storage: tx, env: s.env}, tx: tx}, nil
+}
+
+// openExecutions decrypts the state and trigger data of execs in place.
+func (s *EncryptedStorage) openExecutions(ctx context.Context, execs ...*Execution) error {
+	for _, e := range execs {
+		if e == nil {
+			continue
+		}
+		var err error
+		if e.State, err = s.env.open(ctx, e.State, payloadAAD("execution_state", e.ID)); err != nil {
+			return fmt.Errorf("execution %s state: %w", e.ID, err)
+		}
+		if e.TriggerData, err = s.env.open(ctx, e.TriggerData, payloadAAD("execution_trigger_data", e.ID)); err != nil {
+			return fmt.Errorf("execution %s trigger data: %w", e.ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *EncryptedStorage) SaveExecutionTriggerData(ctx context.Context, executionID string, data []byte) error {
+	sealed, err := s.env.seal(ctx, data, payloadAAD("execution_trigger_data", executionID))
+	if err != nil {
+		return err
+	}
+	return s.Storage.SaveExecutionTriggerData(ctx, executionID, sealed)
+}
+
+func (s *EncryptedStorage) UpdateExecutionStatus(ctx context.Context, executionID string, status ExecutionStatus, state []byte, errorMsg *string) error {
+	sealed, err := s.env.seal(ctx, state, payloadAAD("execution_state", executionID))
+	if err != nil {
+		return err
+	}
+	return s.Storage.UpdateExecutionStatus(ctx, executionID, status, sealed, errorMsg)
+}
+
+func (s *EncryptedStorage) TransitionExecutionStatus(ctx context.Context, executionID string, from, to ExecutionStatus, state []byte, errorMsg *string) (bool, error) {
+	sealed, err := s.env.seal(ctx, state, payloadAAD("execution_state", executionID))
+	if err != nil {
+		return false, err
+	}
+	return s.Storage.TransitionExecutionStatus(ctx, executionID, from, to, sealed, errorMsg)
+}
+
+func (s *EncryptedStorage) SaveExecutionState(ctx context.Context, executionID string, state []byte) (bool, error) {
+	sealed, err := s.env.seal(ctx, state, payloadAAD("execution_state", executionID))
+	if err != nil {
+		return false, err
+	}
+	return s.Storage.SaveExecutionState(ctx, executionID, sealed)
+}
+
+func (s *EncryptedStorage) SuspendExecution(ctx context.Context, executionID string, state []byte, wakeAt time.Time) error {
+	sealed, err := s.env.seal(ctx, state, payloadAAD("execution_state", executionID))
+	if err != nil {
+		return err
+	}
+	return s.Storage.SuspendExecution(ctx, executionID, sealed, wakeAt)
+}
+
+func (s *EncryptedStorage) SuspendExecutionForSignal(ctx context.Context, executionID string, state []byte, signal string, wakeAt *time.Time) error {
+	sealed, err := s.env.seal(ctx, state, payloadAAD("execution_state", executionID))
+	if err != nil {
+		return err
+	}
+	return s.Storage.SuspendExecutionForSignal(ctx, executionID, sealed, signal, wakeAt)
+}
+
+func (s *EncryptedStorage) GetExecution(ctx context.Context, executionID string) (*Execution, error) {
+	exec, err := s.Storage.GetExecution(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+	return exec, s.openExecutions(ctx, exec)
+}
+
+func (s *EncryptedStorage) ListExecutions(ctx context.Context, workflowID string, limit int) ([]*Execution, error) {
+	execs, err := s.Storage.ListExecutions(ctx, workflowID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return execs, s.openExecutions(ctx, execs...)
+}
+
+func (s *EncryptedStorage) FindExecutions(ctx context.Context, filter ExecutionFilter, limit int) ([]*Execution, error) {
+	execs, err := s.Storage.FindExecutions(ctx, filter, limit)
+	if err != nil {
+		return nil, err
+	}
+	return execs, s.openExecutions(ctx, execs...)
+}
+
+func (s *EncryptedStorage) ClaimDueExecutions(ctx context.Context, now time.Time, limit int) ([]*Execution, error) {
+	execs, err := s.Storage.ClaimDueExecutions(ctx, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return execs, s.openExecutions(ctx, execs...)
+}
+
+func (s *EncryptedStorage) ClaimSignaledExecutions(ctx context.Context, signal string) ([]*Execution, error) {
+	execs, err := s.Storage.ClaimSignaledExecutions(ctx, signal)
+	if err != nil {
+		return nil, err
+	}
+	return execs, s.openExecutions(ctx, execs...)
+}
+
+func (s *EncryptedStorage) ListStaleExecutions(ctx context.Context, before time.Time, limit int) ([]*Execution, error) {
+	execs, err := s.Storage.ListStaleExecutions(ctx, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	return execs, s.openExecutions(ctx, execs...)
+}
+
+func (s *EncryptedStorage) SaveNodeResult(ctx context.Context, executionID, nodeID string, result []byte) error {
+	sealed, err := s.env.seal(ctx, result, payloadAAD("node_result", executionID, nodeID))
+	if err != nil {
+		return err
+	}
+	return s.Storage.SaveNodeResult(ctx, executionID, nodeID, sealed)
+}
+
+func (s *EncryptedStorage) GetNodeResult(ctx context.Context, executionID, nodeID string) ([]byte, error) {
+	result, err := s.Storage.GetNodeResult(ctx, executionID, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	return s.env.open(ctx, result, payloadAAD("node_result", executionID, nodeID))
+}
+
+func (s *EncryptedStorage) ListNodeResults(ctx context.Context, executionID string) ([]*NodeResult, error) {
+	results, err := s.Storage.ListNodeResults(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		if r.Result, err = s.env.open(ctx, r.Result, payloadAAD("node_result", executionID, r.NodeID)); err != nil {
+			return nil, fmt.Errorf("node %s result: %w", r.NodeID, err)
+		}
+	}
+	return results, nil
+}
+
+func (s *EncryptedStorage) SaveNodeInput(ctx context.Context, executionID, nodeID string, input []byte) error {
+	sealed, err := s.env.seal(ctx, input, payloadAAD("node_input", executionID, nodeID))
+	if err != nil {
+		return err
+	}
+	return s.Storage.SaveNodeInput(ctx, executionID, nodeID, sealed)
+}
+
+func (s *EncryptedStorage) GetNodeInput(ctx context.Context, executionID, nodeID string) ([]byte, error) {
+	input, err := s.Storage.GetNodeInput(ctx, executionID, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	return s.env.open(ctx, input, payloadAAD("node_input", executionID, nodeID))
+}
+
+func (s *EncryptedStorage) GetWorkflowStaticData(ctx context.Context, workflowID string) ([]byte, error) {
+	data, err := s.Storage.GetWorkflowStaticData(ctx, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	return s.env.open(ctx, data, payloadAAD("workflow_static_data", workflowID))
+}
+
+func (s *EncryptedStorage) SaveWorkflowStaticData(ctx context.Context, workflowID string, data []byte) error {
+	sealed, err := s.env.seal(ctx, data, payloadAAD("workflow_static_data", workflowID))
+	if err != nil {
+		return err
+	}
+	return s.Storage.SaveWorkflowStaticData(ctx, workflowID, sealed)
+}
+
+func (s *EncryptedStorage) CreateTriggerExecution(ctx context.Context, triggerExec *TriggerExecution) error {
+	sealed, err := s.env.seal(ctx, triggerExec.Payload, payloadAAD("trigger_execution", triggerExec.ID))
+	if err != nil {
+		return err
+	}
+	te := *triggerExec
+	te.Payload = sealed
+	return s.Storage.CreateTriggerExecution(ctx, &te)
+}
+
+func (s *EncryptedStorage) ListTriggerExecutions(ctx context.Context, triggerID string, limit int) ([]*TriggerExecution, error) {
+	execs, err := s.Storage.ListTriggerExecutions(ctx, triggerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, te := range execs {
+		if te.Payload, err = s.env.open(ctx, te.Payload, payloadAAD("trigger_execution", te.ID)); err != nil {
+			return nil, fmt.Errorf("trigger execution %s payload: %w", te.ID, err)
+		}
+	}
+	return execs, nil
+}
+
+func (s *EncryptedStorage) QueueTriggerFire(ctx context.Context, fire *QueuedTriggerFire) error {
+	sealed, err := s.env.seal(ctx, fire.Payload, payloadAAD("queued_trigger_fire", fire.ID))
+	if err != nil {
+		return err
+	}
+	f := *fire
+	f.Payload = sealed
+	return s.Storage.QueueTriggerFire(ctx, &f)
+}
+
+func (s *EncryptedStorage) ListQueuedTriggerFires(ctx context.Context) ([]*QueuedTriggerFire, error) {
+	fires, err := s.Storage.ListQueuedTriggerFires(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fires {
+		if f.Payload, err = s.env.open(ctx, f.Payload, payloadAAD("queued_trigger_fire", f.ID)); err != nil {
+			return nil, fmt.Errorf("queued trigger fire %s payload: %w", f.ID, err)
+		}
+	}
+	return fires, nil
+}
+
+func (s *EncryptedStorage) GetWorkflowSnapshot(ctx context.Context, workflowID string) (*WorkflowSnapshot, error) {
+	snap, err := s.Storage.GetWorkflowSnapshot(ctx, workflowID)
+	if err != nil || snap == nil {
+		return snap, err
+	}
+	if snap.Data, err = s.env.open(ctx, snap.Data, payloadAAD("workflow_snapshot", workflowID)); err != nil {
+		return nil, fmt.Errorf("workflow %s snapshot: %w", workflowID, err)
+	}
+	return snap, nil
+}
+
+func (s *EncryptedStorage) SetWorkflowSnapshot(ctx context.Context, snapshot *WorkflowSnapshot) error {
+	sealed, err := s.env.seal(ctx, snapshot.Data, payloadAAD("workflow_snapshot", snapshot.WorkflowID))
+	if err != nil {
+		return err
+	}
+	snap := *snapshot
+	snap.Data = sealed
+	return s.Storage.SetWorkflowSnapshot(ctx, &snap)
+}
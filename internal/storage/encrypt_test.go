@@ -0,0 +1,241 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/awsauth"
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func TestEncryptedStorage(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, store storage.Storage) {
+		ctx := context.Background()
+		key, _ := storage.NewLocalKey(bytes.Repeat([]byte{7}, 32))
+		enc := storage.NewEncryptedStorage(store, key)
+
+		// Rows written before encryption was turned on still read
+		plainID, _ := store.CreateExecution(ctx, "wf-pii")
+		store.SaveNodeResult(ctx, plainID, "fetch", []byte(`{"email":"old@example.com"}`))
+
+		id, err := enc.CreateExecution(ctx, "wf-pii")
+		if err != nil {
+			t.Fatalf("failed to create execution: %v", err)
+		}
+		pii := `{"email":"ada@example.com"}`
+		if err := enc.SaveExecutionTriggerData(ctx, id, []byte(pii)); err != nil {
+			t.Fatalf("failed to save trigger data: %v", err)
+		}
+		if err := enc.SaveNodeResult(ctx, id, "fetch", []byte(pii)); err != nil {
+			t.Fatalf("failed to save node result: %v", err)
+		}
+		// Writes in a transaction are encrypted too
+		if err := storage.WithTx(ctx, enc, func(tx storage.Tx) error {
+			return tx.UpdateExecutionStatus(ctx, id, storage.ExecutionStatusCompleted, []byte(pii), nil)
+		}); err != nil {
+			t.Fatalf("failed to update execution: %v", err)
+		}
+
+		raw, _ := store.GetExecution(ctx, id)
+		rawResult, _ := store.GetNodeResult(ctx, id, "fetch")
+		for _, b := range [][]byte{raw.State, raw.TriggerData, rawResult} {
+			if len(b) == 0 || bytes.Contains(b, []byte("ada@example.com")) {
+				t.Errorf("expected the payload to be stored encrypted, got %q", b)
+			}
+		}
+
+		exec, err := enc.GetExecution(ctx, id)
+		if err != nil || string(exec.State) != pii || string(exec.TriggerData) != pii {
+			t.Errorf("expected decrypted state and trigger data, got %+v (%v)", exec, err)
+		}
+		if results, err := enc.ListNodeResults(ctx, id); err != nil || len(results) != 1 || string(results[0].Result) != pii {
+			t.Errorf("expected the decrypted node result, got %v (%v)", results, err)
+		}
+		if result, err := enc.GetNodeResult(ctx, plainID, "fetch"); err != nil || string(result) != `{"email":"old@example.com"}` {
+			t.Errorf("expected the unencrypted result as is, got %s (%v)", result, err)
+		}
+
+		// A payload copied to another row doesn't decrypt there
+		store.SaveNodeResult(ctx, id, "copy", rawResult)
+		if _, err := enc.GetNodeResult(ctx, id, "copy"); err == nil {
+			t.Error("expected a result copied to another node not to decrypt")
+		}
+
+		// Another key can't read them
+		other, _ := storage.NewLocalKey(bytes.Repeat([]byte{8}, 32))
+		if _, err := storage.NewEncryptedStorage(store, other).GetNodeResult(ctx, id, "fetch"); err == nil {
+			t.Error("expected decrypting with another key to fail")
+		}
+	})
+}
+
+// TestEncryptedStorage_UnboundPayloads verifies that payloads encrypted before
+// they were bound to their row still decrypt.
+func TestEncryptedStorage_UnboundPayloads(t *testing.T) {
+	ctx := context.Background()
+	key, _ := storage.NewLocalKey(bytes.Repeat([]byte{7}, 32))
+	dataKey := bytes.Repeat([]byte{9}, 32)
+	wrapped, _ := key.WrapKey(ctx, dataKey)
+	block, _ := aes.NewCipher(dataKey)
+	aead, _ := cipher.NewGCM(block)
+	nonce := make([]byte, aead.NonceSize())
+	payload := append([]byte("c3ne\x01"), byte(len(wrapped)>>8), byte(len(wrapped)))
+	payload = append(append(payload, wrapped...), aead.Seal(nonce, nonce, []byte(`{"v":1}`), nil)...)
+
+	store, _ := storage.Open("memory://")
+	defer store.Close()
+	id, _ := store.CreateExecution(ctx, "wf-v1")
+	store.SaveNodeResult(ctx, id, "fetch", payload)
+	if result, err := storage.NewEncryptedStorage(store, key).GetNodeResult(ctx, id, "fetch"); err != nil || string(result) != `{"v":1}` {
+		t.Errorf("expected the unbound payload to decrypt, got %s (%v)", result, err)
+	}
+}
+
+// payloadExempt lists the Storage methods carrying bytes that EncryptedStorage
+// leaves as they are, with why.
+var payloadExempt = map[string]string{
+	"CreateTrigger":       "trigger configuration",
+	"GetTrigger":          "trigger configuration",
+	"ListTriggers":        "trigger configuration",
+	"ListAllTriggers":     "trigger configuration",
+	"UpdateTrigger":       "trigger configuration",
+	"CreateWorkflow":      "workflow definition",
+	"GetWorkflow":         "workflow definition",
+	"ListWorkflows":       "workflow definition",
+	"UpdateWorkflow":      "workflow definition",
+	"GetWorkflowCanary":   "workflow definition",
+	"SetWorkflowCanary":   "workflow definition",
+	"ListGlobalVariables": "server configuration",
+	"SetGlobalVariable":   "server configuration",
+}
+
+// TestEncryptedStorage_CoversPayloads verifies that every Storage method
+// taking or returning bytes is either wrapped by EncryptedStorage or exempt,
+// so that a method added to Storage can't pass payloads through unencrypted.
+func TestEncryptedStorage_CoversPayloads(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "encrypt.go", nil, 0)
+	if err != nil {
+		t.Fatalf("failed to parse encrypt.go: %v", err)
+	}
+	wrapped := make(map[string]bool)
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil {
+			continue
+		}
+		if star, ok := fn.Recv.List[0].Type.(*ast.StarExpr); ok {
+			if ident, ok := star.X.(*ast.Ident); ok && ident.Name == "EncryptedStorage" {
+				wrapped[fn.Name.Name] = true
+			}
+		}
+	}
+
+	iface := reflect.TypeOf((*storage.Storage)(nil)).Elem()
+	for i := 0; i < iface.NumMethod(); i++ {
+		m := iface.Method(i)
+		carries := false
+		for j := 0; j < m.Type.NumIn(); j++ {
+			carries = carries || carriesBytes(m.Type.In(j), map[reflect.Type]bool{})
+		}
+		for j := 0; j < m.Type.NumOut(); j++ {
+			carries = carries || carriesBytes(m.Type.Out(j), map[reflect.Type]bool{})
+		}
+		if carries && !wrapped[m.Name] && payloadExempt[m.Name] == "" {
+			t.Errorf("Storage.%s carries bytes but EncryptedStorage neither wraps it nor exempts it", m.Name)
+		}
+	}
+}
+
+// carriesBytes reports whether values of t hold a []byte.
+func carriesBytes(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if t == reflect.TypeOf([]byte(nil)) {
+		return true
+	}
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return carriesBytes(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if carriesBytes(t.Field(i).Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestAWSKMS(t *testing.T) {
+	var encrypts, decrypts atomic.Int32
+	// A fake KMS "wrapping" keys by prefixing them
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in struct {
+			KeyId          string
+			Plaintext      []byte
+			CiphertextBlob []byte
+		}
+		json.NewDecoder(r.Body).Decode(&in)
+		if in.KeyId != "alias/conv3n" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"NotFoundException"}`))
+			return
+		}
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.Encrypt":
+			encrypts.Add(1)
+			json.NewEncoder(w).Encode(map[string][]byte{"CiphertextBlob": append([]byte("kms:"), in.Plaintext...)})
+		case "TrentService.Decrypt":
+			decrypts.Add(1)
+			json.NewEncoder(w).Encode(map[string][]byte{"Plaintext": bytes.TrimPrefix(in.CiphertextBlob, []byte("kms:"))})
+		}
+	}))
+	defer srv.Close()
+	kms := &storage.AWSKMS{KeyID: "alias/conv3n", Region: "us-east-1", Credentials: awsauth.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, Endpoint: srv.URL}
+
+	ctx := context.Background()
+	store, err := storage.Open("memory://")
+	if err != nil {
+		t.Fatalf("failed to open storage: %v", err)
+	}
+	defer store.Close()
+	id, _ := store.CreateExecution(ctx, "wf-kms")
+	enc := storage.NewEncryptedStorage(store, kms)
+	for _, node := range []string{"a", "b", "c"} {
+		if err := enc.SaveNodeResult(ctx, id, node, []byte(`{"ssn":"078-05-1120"}`)); err != nil {
+			t.Fatalf("failed to save node result: %v", err)
+		}
+	}
+	if n := encrypts.Load(); n != 1 {
+		t.Errorf("expected one data key for the writes, got %d KMS calls", n)
+	}
+
+	// A new server process unwraps the data key once
+	results, err := storage.NewEncryptedStorage(store, kms).ListNodeResults(ctx, id)
+	if err != nil || len(results) != 3 || string(results[2].Result) != `{"ssn":"078-05-1120"}` {
+		t.Fatalf("expected the decrypted results, got %v (%v)", results, err)
+	}
+	if n := decrypts.Load(); n != 1 {
+		t.Errorf("expected the data key to be unwrapped once, got %d KMS calls", n)
+	}
+
+	kms.KeyID = "alias/other"
+	err = storage.NewEncryptedStorage(store, kms).SaveExecutionTriggerData(ctx, id, []byte(`{}`))
+	if err == nil || !strings.Contains(err.Error(), "NotFoundException") {
+		t.Errorf("expected the KMS error, got %v", err)
+	}
+}
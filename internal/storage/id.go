@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+var (
+	idMu        sync.RWMutex
+	idGenerator = newUUIDv7
+)
+
+// NewID returns a new identifier for an execution, trigger, trigger execution
+// or workflow. IDs are UUIDv7: unique under concurrency and ordered by creation
+// time, without exposing more than millisecond timing.
+func NewID() string {
+	idMu.RLock()
+	defer idMu.RUnlock()
+	return idGenerator()
+}
+
+// SetIDGenerator replaces the generator behind NewID, e.g. with a counter to
+// make tests deterministic. Passing nil restores UUIDv7.
+func SetIDGenerator(gen func() string) {
+	idMu.Lock()
+	defer idMu.Unlock()
+	if gen == nil {
+		gen = newUUIDv7
+	}
+	idGenerator = gen
+}
+
+func newUUIDv7() string {
+	// NewV7 only fails when the system random source does, like uuid.New
+	return uuid.Must(uuid.NewV7()).String()
+}
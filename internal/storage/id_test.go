@@ -0,0 +1,44 @@
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/storage"
+	"github.com/google/uuid"
+)
+
+func TestNewID(t *testing.T) {
+	t.Run("UUIDv7", func(t *testing.T) {
+		prev := ""
+		for i := 0; i < 100; i++ {
+			id := storage.NewID()
+			parsed, err := uuid.Parse(id)
+			if err != nil || parsed.Version() != 7 {
+				t.Fatalf("expected a UUIDv7, got %q (%v)", id, err)
+			}
+			if id <= prev {
+				t.Fatalf("expected IDs ordered by creation, got %q after %q", id, prev)
+			}
+			prev = id
+		}
+	})
+
+	t.Run("SetIDGenerator", func(t *testing.T) {
+		n := 0
+		storage.SetIDGenerator(func() string {
+			n++
+			return fmt.Sprintf("id-%d", n)
+		})
+		defer storage.SetIDGenerator(nil)
+
+		id, err := storage.NewMemory().CreateExecution(context.Background(), "wf")
+		if err != nil {
+			t.Fatalf("failed to create execution: %v", err)
+		}
+		if id != "id-1" || storage.NewID() != "id-2" {
+			t.Errorf("expected the generator's IDs, got %q", id)
+		}
+	})
+}
@@ -0,0 +1,1629 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+func init() {
+	Register("memory", func(string) (Storage, error) {
+		return NewMemory(), nil
+	})
+}
+
+// MemoryStorage implements Storage in process memory. It behaves like
+// SQLiteStorage, including the atomic claims and conditional updates, but keeps
+// nothing across restarts: use it for tests and ephemeral embedded runs. Every
+// value is copied in and out, so callers never share state with the store.
+type MemoryStorage struct {
+	mu sync.Mutex
+	memData
+	closed bool
+}
+
+// memData is the contents of a MemoryStorage, kept apart so transactions can
+// share it and roll it back to a snapshot.
+type memData struct {
+	seq int64 // insertion order, the rowid of the SQLite tables
+
+	workflows    map[string]*memWorkflow
+	executions   map[string]*memExecution
+	nodeResults  map[nodeKey]*memNodeResult
+	nodeInputs   map[nodeKey][]byte
+	nodeExecs    map[nodeKey]*memNodeExecution
+	staticData   map[string][]byte
+	environments map[string]*Environment
+	globals      map[globalKey]*GlobalVariable
+	triggers     map[string]*memTrigger
+	triggerExecs map[string]*TriggerExecution
+	rateLimits   map[string]*memRateLimit
+	dedupeKeys   map[dedupeKey]*time.Time // expiry; nil keeps the key forever
+	idemKeys     map[idemKey]string       // execution ID; empty until it is created
+	runCounts    map[runCountKey]*TriggerRunCount
+	dailyStats   map[dayStatsKey]*WorkflowDayStats
+	quotas       map[string]*WorkflowQuota
+	canaries     map[string]*WorkflowCanary
+	snapshots    map[string]*WorkflowSnapshot
+	queuedFires  map[string]*memQueuedFire
+	purges       []*PurgeRecord // in insertion order
+}
+
+type nodeKey struct{ executionID, nodeID string }
+
+type globalKey struct{ environment, name string }
+
+type dedupeKey struct{ scope, key string }
+
+type idemKey struct{ triggerID, key string }
+
+type runCountKey struct{ triggerID, day string }
+
+type dayStatsKey struct{ workflowID, day string }
+
+type memQueuedFire struct {
+	QueuedTriggerFire
+	seq int64
+}
+
+type memWorkflow struct {
+	Workflow
+	seq int64
+}
+
+type memExecution struct {
+	Execution
+	wakeAt *time.Time
+	signal string
+	seq    int64
+}
+
+type memNodeResult struct {
+	NodeResult
+	seq int64
+}
+
+type memNodeExecution struct {
+	NodeExecution
+	seq int64
+}
+
+type memTrigger struct {
+	Trigger
+	seq int64
+}
+
+type memRateLimit struct {
+	windowStart int64 // Unix milliseconds
+	count       int
+}
+
+// triggerTypes and triggerExecutionStatuses mirror the CHECK constraints of the SQLite schema.
+var (
+	triggerTypes             = []string{"cron", "interval", "webhook", "typescript", "websocket"}
+	triggerExecutionStatuses = []string{"success", "failed", "skipped"}
+)
+
+// NewMemory creates an empty in-memory storage.
+func NewMemory() *MemoryStorage {
+	return &MemoryStorage{memData: newMemData()}
+}
+
+func newMemData() memData {
+	return memData{
+		workflows:    make(map[string]*memWorkflow),
+		executions:   make(map[string]*memExecution),
+		nodeResults:  make(map[nodeKey]*memNodeResult),
+		nodeInputs:   make(map[nodeKey][]byte),
+		nodeExecs:    make(map[nodeKey]*memNodeExecution),
+		staticData:   make(map[string][]byte),
+		environments: make(map[string]*Environment),
+		globals:      make(map[globalKey]*GlobalVariable),
+		triggers:     make(map[string]*memTrigger),
+		triggerExecs: make(map[string]*TriggerExecution),
+		rateLimits:   make(map[string]*memRateLimit),
+		dedupeKeys:   make(map[dedupeKey]*time.Time),
+		idemKeys:     make(map[idemKey]string),
+		runCounts:    make(map[runCountKey]*TriggerRunCount),
+		dailyStats:   make(map[dayStatsKey]*WorkflowDayStats),
+		quotas:       make(map[string]*WorkflowQuota),
+		canaries:     make(map[string]*WorkflowCanary),
+		snapshots:    make(map[string]*WorkflowSnapshot),
+		queuedFires:  make(map[string]*memQueuedFire),
+	}
+}
+
+// clone copies d deep enough that changes made through the store don't affect
+// the copy: records are updated in place, but byte slices are replaced, not written.
+func (d memData) clone() memData {
+	c := memData{seq: d.seq}
+	c.workflows = cloneRecords(d.workflows)
+	c.executions = cloneRecords(d.executions)
+	c.nodeResults = cloneRecords(d.nodeResults)
+	c.nodeInputs = maps.Clone(d.nodeInputs)
+	c.nodeExecs = cloneRecords(d.nodeExecs)
+	c.staticData = maps.Clone(d.staticData)
+	c.environments = cloneRecords(d.environments)
+	c.globals = cloneRecords(d.globals)
+	c.triggers = cloneRecords(d.triggers)
+	c.triggerExecs = cloneRecords(d.triggerExecs)
+	c.rateLimits = cloneRecords(d.rateLimits)
+	c.dedupeKeys = maps.Clone(d.dedupeKeys)
+	c.idemKeys = maps.Clone(d.idemKeys)
+	c.runCounts = cloneRecords(d.runCounts)
+	c.dailyStats = cloneRecords(d.dailyStats)
+	c.quotas = cloneRecords(d.quotas)
+	c.canaries = cloneRecords(d.canaries)
+	c.snapshots = cloneRecords(d.snapshots)
+	c.queuedFires = cloneRecords(d.queuedFires)
+	c.purges = slices.Clone(d.purges)
+	return c
+}
+
+func cloneRecords[K comparable, V any](m map[K]*V) map[K]*V {
+	c := make(map[K]*V, len(m))
+	for k, v := range m {
+		record := *v
+		c[k] = &record
+	}
+	return c
+}
+
+func (s *MemoryStorage) nextSeq() int64 {
+	s.seq++
+	return s.seq
+}
+
+// now returns the current time in UTC, as SQLite stores it.
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+func copyString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	return &c
+}
+
+// --- Workflow CRUD ---
+
+func (s *MemoryStorage) CreateWorkflow(ctx context.Context, w *Workflow) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.workflows[w.ID]; ok {
+		return fmt.Errorf("failed to create workflow: workflow %s already exists", w.ID)
+	}
+	t := now()
+	s.workflows[w.ID] = &memWorkflow{
+		Workflow: Workflow{ID: w.ID, Name: w.Name, Definition: bytes.Clone(w.Definition), CreatedAt: t, UpdatedAt: t, Source: w.Source, Revision: w.Revision},
+		seq:      s.nextSeq(),
+	}
+	return nil
+}
+
+func (s *MemoryStorage) GetWorkflow(ctx context.Context, id string) (*Workflow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, ok := s.workflows[id]
+	if !ok {
+		return nil, fmt.Errorf("workflow not found")
+	}
+	return w.copy(), nil
+}
+
+func (w *memWorkflow) copy() *Workflow {
+	c := w.Workflow
+	c.Definition = bytes.Clone(w.Definition)
+	return &c
+}
+
+func (s *MemoryStorage) UpdateWorkflow(ctx context.Context, w *Workflow) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.workflows[w.ID]
+	if !ok {
+		return fmt.Errorf("workflow not found")
+	}
+	stored.Name = w.Name
+	stored.Definition = bytes.Clone(w.Definition)
+	stored.Source = w.Source
+	stored.Revision = w.Revision
+	stored.UpdatedAt = now()
+	return nil
+}
+
+func (s *MemoryStorage) DeleteWorkflow(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.workflows, id)
+	return nil
+}
+
+func (s *MemoryStorage) ListWorkflows(ctx context.Context) ([]*Workflow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := make([]*memWorkflow, 0, len(s.workflows))
+	for _, w := range s.workflows {
+		stored = append(stored, w)
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		if !stored[i].UpdatedAt.Equal(stored[j].UpdatedAt) {
+			return stored[i].UpdatedAt.After(stored[j].UpdatedAt)
+		}
+		return stored[i].seq < stored[j].seq
+	})
+	var workflows []*Workflow
+	for _, w := range stored {
+		workflows = append(workflows, w.copy())
+	}
+	return workflows, nil
+}
+
+// --- Execution Management ---
+
+// CreateExecution creates a new workflow execution instance, recording the
+// workflow's current revision
+func (s *MemoryStorage) CreateExecution(ctx context.Context, workflowID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	executionID := NewID()
+	if _, ok := s.executions[executionID]; ok {
+		return "", fmt.Errorf("failed to create execution: execution %s already exists", executionID)
+	}
+	var revision string
+	if w, ok := s.workflows[workflowID]; ok {
+		revision = w.Revision
+	}
+	s.executions[executionID] = &memExecution{
+		Execution: Execution{
+			ID:         executionID,
+			WorkflowID: workflowID,
+			Status:     ExecutionStatusRunning,
+			State:      []byte("{}"),
+			StartedAt:  now(),
+			Revision:   revision,
+		},
+		seq: s.nextSeq(),
+	}
+	return executionID, nil
+}
+
+func (e *memExecution) copy() *Execution {
+	c := e.Execution
+	c.State = bytes.Clone(e.State)
+	c.CompletedAt = copyTime(e.CompletedAt)
+	c.Error = copyString(e.Error)
+	c.TriggerData = bytes.Clone(e.TriggerData)
+	c.HeartbeatAt = copyTime(e.HeartbeatAt)
+	c.Labels = maps.Clone(e.Labels)
+	return &c
+}
+
+// MarkTestExecution flags an execution as a test-mode run.
+func (s *MemoryStorage) MarkTestExecution(ctx context.Context, executionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.executions[executionID]; ok {
+		e.Test = true
+	}
+	return nil
+}
+
+// MarkCanaryExecution flags an execution as a run of the workflow's canary version.
+func (s *MemoryStorage) MarkCanaryExecution(ctx context.Context, executionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.executions[executionID]; ok {
+		e.Canary = true
+	}
+	return nil
+}
+
+// SetExecutionLabels records the correlation ID and labels an execution was started with.
+func (s *MemoryStorage) SetExecutionLabels(ctx context.Context, executionID, correlationID string, labels map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.executions[executionID]; ok {
+		e.CorrelationID = correlationID
+		e.Labels = nil
+		if len(labels) > 0 {
+			e.Labels = maps.Clone(labels)
+		}
+	}
+	return nil
+}
+
+// SetExecutionRequestID records the ID of the API request that started an execution.
+func (s *MemoryStorage) SetExecutionRequestID(ctx context.Context, executionID, requestID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.executions[executionID]; ok {
+		e.RequestID = requestID
+	}
+	return nil
+}
+
+// AddExecutionUsage adds usage to the usage recorded for an execution; each
+// run of an execution resumed after a wait adds its own.
+func (s *MemoryStorage) AddExecutionUsage(ctx context.Context, executionID string, usage ExecutionUsage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.executions[executionID]; ok {
+		e.Usage.Add(usage)
+	}
+	return nil
+}
+
+// SaveExecutionTriggerData stores the trigger payload an execution was started with.
+func (s *MemoryStorage) SaveExecutionTriggerData(ctx context.Context, executionID string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.executions[executionID]; ok {
+		e.TriggerData = bytes.Clone(data)
+	}
+	return nil
+}
+
+// UpdateExecutionStatus updates the status and state of an execution
+func (s *MemoryStorage) UpdateExecutionStatus(ctx context.Context, executionID string, status ExecutionStatus, state []byte, errorMsg *string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.executions[executionID]; ok {
+		e.finish(status, errorMsg)
+		e.State = bytes.Clone(state)
+	}
+	return nil
+}
+
+func (e *memExecution) finish(status ExecutionStatus, errorMsg *string) {
+	t := now()
+	e.Status = status
+	e.CompletedAt = &t
+	e.Error = copyString(errorMsg)
+}
+
+// TransitionExecutionStatus is UpdateExecutionStatus applied only while the
+// execution still has status from. A nil state keeps the stored one. Returns
+// false when the execution doesn't exist or has another status.
+func (s *MemoryStorage) TransitionExecutionStatus(ctx context.Context, executionID string, from, to ExecutionStatus, state []byte, errorMsg *string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.executions[executionID]
+	if !ok || e.Status != from {
+		return false, nil
+	}
+	e.finish(to, errorMsg)
+	if state != nil {
+		e.State = bytes.Clone(state)
+	}
+	return true, nil
+}
+
+// SaveExecutionState checkpoints the state of a running execution, leaving its
+// status alone. Returns false when the execution doesn't exist or isn't running.
+func (s *MemoryStorage) SaveExecutionState(ctx context.Context, executionID string, state []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.executions[executionID]
+	if !ok || e.Status != ExecutionStatusRunning {
+		return false, nil
+	}
+	e.State = bytes.Clone(state)
+	heartbeat := now()
+	e.HeartbeatAt = &heartbeat
+	return true, nil
+}
+
+// HeartbeatExecution records that a running execution is still alive.
+// Returns false when the execution doesn't exist or isn't running.
+func (s *MemoryStorage) HeartbeatExecution(ctx context.Context, executionID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.executions[executionID]
+	if !ok || e.Status != ExecutionStatusRunning {
+		return false, nil
+	}
+	heartbeat := now()
+	e.HeartbeatAt = &heartbeat
+	return true, nil
+}
+
+// SuspendExecution parks an execution as waiting until wakeAt.
+func (s *MemoryStorage) SuspendExecution(ctx context.Context, executionID string, state []byte, wakeAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.executions[executionID]; ok {
+		wake := wakeAt.UTC()
+		e.suspend(state, &wake)
+	}
+	return nil
+}
+
+func (e *memExecution) suspend(state []byte, wakeAt *time.Time) {
+	e.Status = ExecutionStatusWaiting
+	e.State = bytes.Clone(state)
+	e.wakeAt = wakeAt
+	e.CompletedAt = nil
+	e.Error = nil
+}
+
+// ClaimDueExecutions marks up to limit waiting executions whose wake-up time has
+// passed as running and returns them, earliest wake-up first.
+func (s *MemoryStorage) ClaimDueExecutions(ctx context.Context, now time.Time, limit int) ([]*Execution, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []*memExecution
+	for _, e := range s.executions {
+		if e.Status == ExecutionStatusWaiting && e.wakeAt != nil && !e.wakeAt.After(now) {
+			due = append(due, e)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].wakeAt.Equal(*due[j].wakeAt) {
+			return due[i].wakeAt.Before(*due[j].wakeAt)
+		}
+		return due[i].seq < due[j].seq
+	})
+	if limit >= 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return claim(due, now.UTC()), nil
+}
+
+// SuspendExecutionForSignal parks an execution as waiting until signal is sent
+// with ClaimSignaledExecutions or, if wakeAt is set, until wakeAt.
+func (s *MemoryStorage) SuspendExecutionForSignal(ctx context.Context, executionID string, state []byte, signal string, wakeAt *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.executions[executionID]; ok {
+		var wake *time.Time
+		if wakeAt != nil {
+			w := wakeAt.UTC()
+			wake = &w
+		}
+		e.suspend(state, wake)
+		e.signal = signal
+	}
+	return nil
+}
+
+// ClaimSignaledExecutions marks every execution waiting for signal as running and returns them.
+func (s *MemoryStorage) ClaimSignaledExecutions(ctx context.Context, signal string) ([]*Execution, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var waiting []*memExecution
+	for _, e := range s.executions {
+		if e.Status == ExecutionStatusWaiting && e.signal != "" && e.signal == signal {
+			waiting = append(waiting, e)
+		}
+	}
+	sort.Slice(waiting, func(i, j int) bool { return waiting[i].seq < waiting[j].seq })
+	return claim(waiting, now()), nil
+}
+
+// claim marks the executions as running with a heartbeat at now and returns
+// copies of them.
+func claim(executions []*memExecution, now time.Time) []*Execution {
+	var claimed []*Execution
+	for _, e := range executions {
+		e.Status = ExecutionStatusRunning
+		e.wakeAt = nil
+		e.signal = ""
+		e.HeartbeatAt = &now
+		claimed = append(claimed, e.copy())
+	}
+	return claimed
+}
+
+// ListStaleExecutions returns up to limit running executions whose last
+// heartbeat, or start when they have none, is before before. Oldest first.
+func (s *MemoryStorage) ListStaleExecutions(ctx context.Context, before time.Time, limit int) ([]*Execution, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var stale []*memExecution
+	for _, e := range s.executions {
+		if e.Status == ExecutionStatusRunning && e.lastSeen().Before(before) {
+			stale = append(stale, e)
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool {
+		if !stale[i].lastSeen().Equal(stale[j].lastSeen()) {
+			return stale[i].lastSeen().Before(stale[j].lastSeen())
+		}
+		return stale[i].seq < stale[j].seq
+	})
+	if limit >= 0 && len(stale) > limit {
+		stale = stale[:limit]
+	}
+	var executions []*Execution
+	for _, e := range stale {
+		executions = append(executions, e.copy())
+	}
+	return executions, nil
+}
+
+// lastSeen is the execution's last heartbeat, or its start when it has none.
+func (e *memExecution) lastSeen() time.Time {
+	if e.HeartbeatAt != nil {
+		return *e.HeartbeatAt
+	}
+	return e.StartedAt
+}
+
+// GetExecution retrieves a specific execution by ID
+func (s *MemoryStorage) GetExecution(ctx context.Context, executionID string) (*Execution, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.executions[executionID]
+	if !ok {
+		return nil, fmt.Errorf("failed to get execution: %w", sql.ErrNoRows)
+	}
+	return e.copy(), nil
+}
+
+// ListExecutions retrieves execution history for a workflow, most recent first
+func (s *MemoryStorage) ListExecutions(ctx context.Context, workflowID string, limit int) ([]*Execution, error) {
+	return s.listExecutions(func(e *memExecution) bool { return e.WorkflowID == workflowID }, limit), nil
+}
+
+// FindExecutions returns the executions matching filter, most recent first
+func (s *MemoryStorage) FindExecutions(ctx context.Context, filter ExecutionFilter, limit int) ([]*Execution, error) {
+	return s.listExecutions(func(e *memExecution) bool {
+		if filter.WorkflowID != "" && e.WorkflowID != filter.WorkflowID {
+			return false
+		}
+		if filter.CorrelationID != "" && e.CorrelationID != filter.CorrelationID {
+			return false
+		}
+		if filter.Test && !e.Test {
+			return false
+		}
+		for k, v := range filter.Labels {
+			if got, ok := e.Labels[k]; !ok || got != v {
+				return false
+			}
+		}
+		return true
+	}, limit), nil
+}
+
+// SearchExecutions returns the node results and errors containing every word
+// of query, those of the most recent executions first
+func (s *MemoryStorage) SearchExecutions(ctx context.Context, query string, limit int) ([]*ExecutionSearchHit, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var hits []*ExecutionSearchHit
+	add := func(executionID, nodeID, field, text string) {
+		e, ok := s.executions[executionID]
+		if !ok {
+			return
+		}
+		if snippet, ok := searchText(text, terms); ok {
+			hits = append(hits, &ExecutionSearchHit{ExecutionID: executionID, WorkflowID: e.WorkflowID, StartedAt: e.StartedAt, NodeID: nodeID, Field: field, Snippet: snippet})
+		}
+	}
+	for key, nr := range s.nodeResults {
+		add(key.executionID, key.nodeID, SearchFieldResult, string(nr.Result))
+	}
+	for key, ne := range s.nodeExecs {
+		if ne.Error != nil {
+			add(key.executionID, key.nodeID, SearchFieldError, *ne.Error)
+		}
+	}
+	for id, e := range s.executions {
+		if e.Error != nil {
+			add(id, "", SearchFieldError, *e.Error)
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		a, b := s.executions[hits[i].ExecutionID], s.executions[hits[j].ExecutionID]
+		if !a.StartedAt.Equal(b.StartedAt) {
+			return a.StartedAt.After(b.StartedAt)
+		}
+		if a.seq != b.seq {
+			return a.seq > b.seq
+		}
+		return hits[i].NodeID < hits[j].NodeID
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// listExecutions returns the executions matching keep, most recent first.
+func (s *MemoryStorage) listExecutions(keep func(*memExecution) bool, limit int) []*Execution {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var stored []*memExecution
+	for _, e := range s.executions {
+		if keep(e) {
+			stored = append(stored, e)
+		}
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		if !stored[i].StartedAt.Equal(stored[j].StartedAt) {
+			return stored[i].StartedAt.After(stored[j].StartedAt)
+		}
+		return stored[i].seq > stored[j].seq
+	})
+	if limit >= 0 && len(stored) > limit {
+		stored = stored[:limit]
+	}
+	var executions []*Execution
+	for _, e := range stored {
+		c := e.copy()
+		c.TriggerData = nil // like SQLite, only GetExecution loads it
+		executions = append(executions, c)
+	}
+	return executions
+}
+
+// --- Node Results, Inputs and Executions ---
+
+// SaveNodeResult persists the result of a single node execution
+func (s *MemoryStorage) SaveNodeResult(ctx context.Context, executionID, nodeID string, result []byte) error {
+	if result == nil {
+		return errors.New("failed to save node result: result is nil")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := nodeKey{executionID, nodeID}
+	nr, ok := s.nodeResults[key]
+	if !ok {
+		nr = &memNodeResult{NodeResult: NodeResult{ExecutionID: executionID, NodeID: nodeID}, seq: s.nextSeq()}
+		s.nodeResults[key] = nr
+	}
+	nr.Result = bytes.Clone(result)
+	nr.CreatedAt = now()
+	return nil
+}
+
+// GetNodeResult retrieves the result of a specific node execution
+func (s *MemoryStorage) GetNodeResult(ctx context.Context, executionID, nodeID string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	nr, ok := s.nodeResults[nodeKey{executionID, nodeID}]
+	if !ok {
+		return nil, fmt.Errorf("failed to get node result: %w", sql.ErrNoRows)
+	}
+	return bytes.Clone(nr.Result), nil
+}
+
+// ListNodeResults returns all node results of an execution in the order they were saved
+func (s *MemoryStorage) ListNodeResults(ctx context.Context, executionID string) ([]*NodeResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var stored []*memNodeResult
+	for key, nr := range s.nodeResults {
+		if key.executionID == executionID {
+			stored = append(stored, nr)
+		}
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		if !stored[i].CreatedAt.Equal(stored[j].CreatedAt) {
+			return stored[i].CreatedAt.Before(stored[j].CreatedAt)
+		}
+		return stored[i].seq < stored[j].seq
+	})
+	var results []*NodeResult
+	for _, nr := range stored {
+		c := nr.NodeResult
+		c.Result = bytes.Clone(nr.Result)
+		results = append(results, &c)
+	}
+	return results, nil
+}
+
+// SaveNodeInput persists the input a node of an execution was called with,
+// replacing the input of an earlier attempt
+func (s *MemoryStorage) SaveNodeInput(ctx context.Context, executionID, nodeID string, input []byte) error {
+	if input == nil {
+		return errors.New("failed to save node input: input is nil")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nodeInputs[nodeKey{executionID, nodeID}] = bytes.Clone(input)
+	return nil
+}
+
+// GetNodeInput retrieves the input of a node of an execution
+func (s *MemoryStorage) GetNodeInput(ctx context.Context, executionID, nodeID string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	input, ok := s.nodeInputs[nodeKey{executionID, nodeID}]
+	if !ok {
+		return nil, fmt.Errorf("failed to get node input: %w", sql.ErrNoRows)
+	}
+	return bytes.Clone(input), nil
+}
+
+// DeleteExecutionData drops the data an execution produced: the results and
+// inputs of its nodes, and its state, which is left empty. Its summary and node
+// timeline are kept.
+func (s *MemoryStorage) DeleteExecutionData(ctx context.Context, executionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.nodeResults {
+		if key.executionID == executionID {
+			delete(s.nodeResults, key)
+		}
+	}
+	for key := range s.nodeInputs {
+		if key.executionID == executionID {
+			delete(s.nodeInputs, key)
+		}
+	}
+	if e, ok := s.executions[executionID]; ok {
+		e.State = []byte("{}")
+	}
+	return nil
+}
+
+// DeleteExecution removes an execution together with its node results, inputs
+// and timeline
+func (s *MemoryStorage) DeleteExecution(ctx context.Context, executionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.nodeResults {
+		if key.executionID == executionID {
+			delete(s.nodeResults, key)
+		}
+	}
+	for key := range s.nodeInputs {
+		if key.executionID == executionID {
+			delete(s.nodeInputs, key)
+		}
+	}
+	for key := range s.nodeExecs {
+		if key.executionID == executionID {
+			delete(s.nodeExecs, key)
+		}
+	}
+	delete(s.executions, executionID)
+	return nil
+}
+
+// StartNodeExecution records that a node of an execution started running,
+// counting an attempt
+func (s *MemoryStorage) StartNodeExecution(ctx context.Context, executionID, nodeID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ne := s.nodeExecution(executionID, nodeID)
+	t := now()
+	ne.Status = NodeStatusRunning
+	ne.Port = ""
+	ne.Attempts++
+	ne.StartedAt = &t
+	ne.FinishedAt = nil
+	ne.Error = nil
+	return nil
+}
+
+// FinishNodeExecution records the outcome of a node of an execution. Nodes
+// that were never started (skipped or cached) get a record without a start time.
+func (s *MemoryStorage) FinishNodeExecution(ctx context.Context, executionID, nodeID string, status NodeStatus, port string, errorMsg *string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ne := s.nodeExecution(executionID, nodeID)
+	t := now()
+	ne.Status = status
+	ne.Port = port
+	ne.FinishedAt = &t
+	ne.Error = copyString(errorMsg)
+	return nil
+}
+
+// nodeExecution returns the record of a node, creating it if needed. Callers hold s.mu.
+func (s *MemoryStorage) nodeExecution(executionID, nodeID string) *memNodeExecution {
+	key := nodeKey{executionID, nodeID}
+	ne, ok := s.nodeExecs[key]
+	if !ok {
+		ne = &memNodeExecution{NodeExecution: NodeExecution{ExecutionID: executionID, NodeID: nodeID}, seq: s.nextSeq()}
+		s.nodeExecs[key] = ne
+	}
+	return ne
+}
+
+// ListNodeExecutions returns the node records of an execution in the order the
+// nodes started, followed by those that never started
+func (s *MemoryStorage) ListNodeExecutions(ctx context.Context, executionID string) ([]*NodeExecution, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var stored []*memNodeExecution
+	for key, ne := range s.nodeExecs {
+		if key.executionID == executionID {
+			stored = append(stored, ne)
+		}
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		a, b := stored[i], stored[j]
+		if (a.StartedAt == nil) != (b.StartedAt == nil) {
+			return a.StartedAt != nil
+		}
+		if a.StartedAt != nil && !a.StartedAt.Equal(*b.StartedAt) {
+			return a.StartedAt.Before(*b.StartedAt)
+		}
+		if (a.FinishedAt == nil) != (b.FinishedAt == nil) {
+			return a.FinishedAt == nil // NULL sorts first, as in SQLite
+		}
+		if a.FinishedAt != nil && !a.FinishedAt.Equal(*b.FinishedAt) {
+			return a.FinishedAt.Before(*b.FinishedAt)
+		}
+		return a.seq < b.seq
+	})
+	var nodes []*NodeExecution
+	for _, ne := range stored {
+		c := ne.NodeExecution
+		c.StartedAt = copyTime(ne.StartedAt)
+		c.FinishedAt = copyTime(ne.FinishedAt)
+		c.Error = copyString(ne.Error)
+		nodes = append(nodes, &c)
+	}
+	return nodes, nil
+}
+
+// --- Workflow Static Data ---
+
+// GetWorkflowStaticData returns the JSON static data of a workflow, or nil if it has none yet
+func (s *MemoryStorage) GetWorkflowStaticData(ctx context.Context, workflowID string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return bytes.Clone(s.staticData[workflowID]), nil
+}
+
+// SaveWorkflowStaticData replaces the JSON static data of a workflow
+func (s *MemoryStorage) SaveWorkflowStaticData(ctx context.Context, workflowID string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.staticData[workflowID] = bytes.Clone(data)
+	return nil
+}
+
+// --- Rate Limits ---
+
+// IncrementRateLimit counts one hit against key in the window starting at
+// windowStart and returns the hits counted in that window so far, this one
+// included. A hit in a new window starts the count over.
+func (s *MemoryStorage) IncrementRateLimit(ctx context.Context, key string, windowStart time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	start := windowStart.UnixMilli()
+	rl, ok := s.rateLimits[key]
+	if !ok || rl.windowStart != start {
+		rl = &memRateLimit{windowStart: start}
+		s.rateLimits[key] = rl
+	}
+	rl.count++
+	return rl.count, nil
+}
+
+// --- Dedupe Keys ---
+
+// MarkSeen adds key to the seen-set of scope until expiresAt (forever if nil)
+// and reports whether it was new: absent, or present but expired by now.
+// Expired keys of scope are pruned first.
+func (s *MemoryStorage) MarkSeen(ctx context.Context, scope, key string, now time.Time, expiresAt *time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	nowMs := now.UnixMilli()
+	for k, expires := range s.dedupeKeys {
+		if k.scope == scope && expires != nil && expires.UnixMilli() <= nowMs {
+			delete(s.dedupeKeys, k)
+		}
+	}
+	k := dedupeKey{scope, key}
+	if _, seen := s.dedupeKeys[k]; seen {
+		return false, nil
+	}
+	s.dedupeKeys[k] = copyTime(expiresAt)
+	return true, nil
+}
+
+// --- Trigger Idempotency Keys ---
+
+// ClaimIdempotencyKey records that triggerID was fired with key and reports
+// whether this call claimed it. If the key was claimed before, it returns the
+// execution recorded against it, empty while that one is still being created.
+func (s *MemoryStorage) ClaimIdempotencyKey(ctx context.Context, triggerID, key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := idemKey{triggerID, key}
+	if executionID, ok := s.idemKeys[k]; ok {
+		return executionID, false, nil
+	}
+	s.idemKeys[k] = ""
+	return "", true, nil
+}
+
+// SetIdempotencyKeyExecution records the execution started for a claimed key.
+func (s *MemoryStorage) SetIdempotencyKeyExecution(ctx context.Context, triggerID, key, executionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := idemKey{triggerID, key}
+	if _, ok := s.idemKeys[k]; !ok {
+		return fmt.Errorf("idempotency key %q of trigger %s not claimed", key, triggerID)
+	}
+	s.idemKeys[k] = executionID
+	return nil
+}
+
+// ReleaseIdempotencyKey forgets a claimed key, so that a later delivery with
+// it runs again.
+func (s *MemoryStorage) ReleaseIdempotencyKey(ctx context.Context, triggerID, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.idemKeys, idemKey{triggerID, key})
+	return nil
+}
+
+// --- Trigger Run Counts ---
+
+// CountSampledOutRun counts a successful run of a trigger at at that its
+// sampling left out of execution history
+func (s *MemoryStorage) CountSampledOutRun(ctx context.Context, triggerID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := runCountKey{triggerID, runCountDay(at)}
+	c, ok := s.runCounts[key]
+	if !ok {
+		c = &TriggerRunCount{TriggerID: triggerID, Day: key.day}
+		s.runCounts[key] = c
+	}
+	c.SampledOut++
+	return nil
+}
+
+// ListTriggerRunCounts returns the run counts of a trigger from the day of since
+// on, oldest day first; days without counted runs are left out
+func (s *MemoryStorage) ListTriggerRunCounts(ctx context.Context, triggerID string, since time.Time) ([]*TriggerRunCount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	from := runCountDay(since)
+	var counts []*TriggerRunCount
+	for key, c := range s.runCounts {
+		if key.triggerID == triggerID && key.day >= from {
+			cp := *c
+			counts = append(counts, &cp)
+		}
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Day < counts[j].Day })
+	return counts, nil
+}
+
+// --- Workflow Stats ---
+
+// RollupWorkflowStats rebuilds the stats of the day of day (in UTC) from the
+// executions that finished on it and the sampled out runs counted on it
+func (s *MemoryStorage) RollupWorkflowStats(ctx context.Context, day time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := runCountDay(day)
+	for key := range s.dailyStats {
+		if key.day == d {
+			delete(s.dailyStats, key)
+		}
+	}
+	stats := func(workflowID string) *WorkflowDayStats {
+		key := dayStatsKey{workflowID, d}
+		st, ok := s.dailyStats[key]
+		if !ok {
+			st = &WorkflowDayStats{WorkflowID: workflowID, Day: d}
+			s.dailyStats[key] = st
+		}
+		return st
+	}
+	for _, e := range s.executions {
+		if e.Test || e.CompletedAt == nil || runCountDay(*e.CompletedAt) != d {
+			continue
+		}
+		st := stats(e.WorkflowID)
+		st.Runs++
+		if e.Status == ExecutionStatusFailed {
+			st.Failures++
+		}
+		st.DurationMS += e.CompletedAt.Sub(e.StartedAt).Milliseconds()
+		st.Usage.Add(e.Usage)
+	}
+	for key, c := range s.runCounts {
+		if t, ok := s.triggers[key.triggerID]; ok && key.day == d {
+			stats(t.WorkflowID).SampledOut += c.SampledOut
+		}
+	}
+	return nil
+}
+
+// ListWorkflowStats returns the daily stats of a workflow, or of every workflow
+// when workflowID is empty, from the day of since on, ordered by day and workflow
+func (s *MemoryStorage) ListWorkflowStats(ctx context.Context, workflowID string, since time.Time) ([]*WorkflowDayStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	from := runCountDay(since)
+	var stats []*WorkflowDayStats
+	for key, st := range s.dailyStats {
+		if key.day >= from && (workflowID == "" || key.workflowID == workflowID) {
+			cp := *st
+			stats = append(stats, &cp)
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Day != stats[j].Day {
+			return stats[i].Day < stats[j].Day
+		}
+		return stats[i].WorkflowID < stats[j].WorkflowID
+	})
+	return stats, nil
+}
+
+// --- Workflow Quotas ---
+
+// GetWorkflowCanary returns the canary version of a workflow, nil if it has none
+func (s *MemoryStorage) GetWorkflowCanary(ctx context.Context, workflowID string) (*WorkflowCanary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.canaries[workflowID]
+	if !ok {
+		return nil, nil
+	}
+	cp := *c
+	cp.Definition = bytes.Clone(c.Definition)
+	return &cp, nil
+}
+
+// SetWorkflowCanary creates or replaces the canary version of a workflow
+func (s *MemoryStorage) SetWorkflowCanary(ctx context.Context, canary *WorkflowCanary) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *canary
+	c.Definition = bytes.Clone(canary.Definition)
+	c.CreatedAt = now()
+	if prev, ok := s.canaries[canary.WorkflowID]; ok && bytes.Equal(prev.Definition, canary.Definition) {
+		c.CreatedAt = prev.CreatedAt
+	}
+	s.canaries[canary.WorkflowID] = &c
+	return nil
+}
+
+// DeleteWorkflowCanary removes the canary version of a workflow
+func (s *MemoryStorage) DeleteWorkflowCanary(ctx context.Context, workflowID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.canaries, workflowID)
+	return nil
+}
+
+// GetWorkflowSnapshot returns the snapshot of a workflow, nil if it has none
+func (s *MemoryStorage) GetWorkflowSnapshot(ctx context.Context, workflowID string) (*WorkflowSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap, ok := s.snapshots[workflowID]
+	if !ok {
+		return nil, nil
+	}
+	cp := *snap
+	cp.Data = bytes.Clone(snap.Data)
+	return &cp, nil
+}
+
+// SetWorkflowSnapshot creates or replaces the snapshot of a workflow
+func (s *MemoryStorage) SetWorkflowSnapshot(ctx context.Context, snapshot *WorkflowSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap := *snapshot
+	snap.Data = bytes.Clone(snapshot.Data)
+	snap.CreatedAt = now()
+	s.snapshots[snapshot.WorkflowID] = &snap
+	return nil
+}
+
+// DeleteWorkflowSnapshot removes the snapshot of a workflow
+func (s *MemoryStorage) DeleteWorkflowSnapshot(ctx context.Context, workflowID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.snapshots, workflowID)
+	return nil
+}
+
+// WorkflowVersionStats counts the executions of a workflow started at or after
+// since, separately for its stable and canary versions
+func (s *MemoryStorage) WorkflowVersionStats(ctx context.Context, workflowID string, since time.Time) (stable, canary VersionStats, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.executions {
+		if e.WorkflowID != workflowID || e.Test || e.StartedAt.Before(since) {
+			continue
+		}
+		v := &stable
+		if e.Canary {
+			v = &canary
+		}
+		v.Runs++
+		switch e.Status {
+		case ExecutionStatusCompleted:
+			v.Completed++
+		case ExecutionStatusFailed:
+			v.Failed++
+		}
+		if e.CompletedAt != nil {
+			v.DurationMS += e.CompletedAt.Sub(e.StartedAt).Milliseconds()
+		}
+	}
+	return stable, canary, nil
+}
+
+// GetWorkflowQuota returns the quota set for a workflow, nil if none is
+func (s *MemoryStorage) GetWorkflowQuota(ctx context.Context, workflowID string) (*WorkflowQuota, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.quotas[workflowID]
+	if !ok {
+		return nil, nil
+	}
+	c := *q
+	c.OverrideUntil = copyTime(q.OverrideUntil)
+	return &c, nil
+}
+
+// SetWorkflowQuota creates or replaces the quota of a workflow
+func (s *MemoryStorage) SetWorkflowQuota(ctx context.Context, quota *WorkflowQuota) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *quota
+	c.OverrideUntil = copyTime(quota.OverrideUntil)
+	c.UpdatedAt = now()
+	s.quotas[quota.WorkflowID] = &c
+	return nil
+}
+
+// DeleteWorkflowQuota removes the quota of a workflow, which then has the defaults
+func (s *MemoryStorage) DeleteWorkflowQuota(ctx context.Context, workflowID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.quotas, workflowID)
+	return nil
+}
+
+// RecordPurge stores the audit record of a purge
+func (s *MemoryStorage) RecordPurge(ctx context.Context, p *PurgeRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *p
+	s.purges = append(s.purges, &c)
+	return nil
+}
+
+// ListPurges returns the audit records of purges, most recent first
+func (s *MemoryStorage) ListPurges(ctx context.Context, limit int) ([]*PurgeRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var purges []*PurgeRecord
+	for i := len(s.purges) - 1; i >= 0 && (limit < 0 || len(purges) < limit); i-- {
+		c := *s.purges[i]
+		purges = append(purges, &c)
+	}
+	return purges, nil
+}
+
+// WorkflowUsageSince counts the executions of a workflow started at or after
+// since, and sums their usage. Test-mode runs are left out.
+func (s *MemoryStorage) WorkflowUsageSince(ctx context.Context, workflowID string, since time.Time) (int, ExecutionUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var count int
+	var usage ExecutionUsage
+	for _, e := range s.executions {
+		if e.WorkflowID == workflowID && !e.Test && !e.StartedAt.Before(since) {
+			count++
+			usage.Add(e.Usage)
+		}
+	}
+	return count, usage, nil
+}
+
+// --- Global Variables and Environments ---
+
+func (s *MemoryStorage) CreateEnvironment(ctx context.Context, env *Environment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.environments[env.Name]; ok {
+		return fmt.Errorf("failed to create environment: environment %s already exists", env.Name)
+	}
+	s.environments[env.Name] = &Environment{Name: env.Name, Description: env.Description, CreatedAt: now()}
+	return nil
+}
+
+func (s *MemoryStorage) GetEnvironment(ctx context.Context, name string) (*Environment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	env, ok := s.environments[name]
+	if !ok {
+		return nil, fmt.Errorf("failed to get environment: %w", sql.ErrNoRows)
+	}
+	c := *env
+	return &c, nil
+}
+
+func (s *MemoryStorage) ListEnvironments(ctx context.Context) ([]*Environment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var envs []*Environment
+	for _, env := range s.environments {
+		c := *env
+		envs = append(envs, &c)
+	}
+	sort.Slice(envs, func(i, j int) bool { return envs[i].Name < envs[j].Name })
+	return envs, nil
+}
+
+// DeleteEnvironment removes an environment together with its variable overrides
+func (s *MemoryStorage) DeleteEnvironment(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.environments[name]; !ok {
+		return fmt.Errorf("environment not found: %s", name)
+	}
+	delete(s.environments, name)
+	for key := range s.globals {
+		if key.environment == name {
+			delete(s.globals, key)
+		}
+	}
+	return nil
+}
+
+// SetGlobalVariable creates or replaces a global variable
+func (s *MemoryStorage) SetGlobalVariable(ctx context.Context, v *GlobalVariable) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.globals[globalKey{v.Environment, v.Name}] = &GlobalVariable{
+		Environment: v.Environment,
+		Name:        v.Name,
+		Value:       bytes.Clone(v.Value),
+		UpdatedAt:   now(),
+	}
+	return nil
+}
+
+// ListGlobalVariables returns the variables defined directly in environment (empty for the shared defaults)
+func (s *MemoryStorage) ListGlobalVariables(ctx context.Context, environment string) ([]*GlobalVariable, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var vars []*GlobalVariable
+	for key, v := range s.globals {
+		if key.environment == environment {
+			c := *v
+			c.Value = bytes.Clone(v.Value)
+			vars = append(vars, &c)
+		}
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars, nil
+}
+
+func (s *MemoryStorage) DeleteGlobalVariable(ctx context.Context, environment, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := globalKey{environment, name}
+	if _, ok := s.globals[key]; !ok {
+		return fmt.Errorf("global variable not found: %s", name)
+	}
+	delete(s.globals, key)
+	return nil
+}
+
+// --- Trigger Management ---
+
+func (s *MemoryStorage) CreateTrigger(ctx context.Context, t *Trigger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.triggers[t.ID]; ok {
+		return fmt.Errorf("failed to create trigger: trigger %s already exists", t.ID)
+	}
+	if !slices.Contains(triggerTypes, t.Type) {
+		return fmt.Errorf("failed to create trigger: invalid type %q", t.Type)
+	}
+	created := now()
+	s.triggers[t.ID] = &memTrigger{
+		Trigger: Trigger{
+			ID:            t.ID,
+			WorkflowID:    t.WorkflowID,
+			Type:          t.Type,
+			Config:        bytes.Clone(t.Config),
+			Enabled:       t.Enabled,
+			CreatedAt:     created,
+			UpdatedAt:     created,
+			FilePath:      t.FilePath,
+			RuntimeStatus: TriggerRuntimeStopped,
+		},
+		seq: s.nextSeq(),
+	}
+	return nil
+}
+
+func (t *memTrigger) copy() *Trigger {
+	c := t.Trigger
+	c.Config = bytes.Clone(t.Config)
+	c.RuntimeError = copyString(t.RuntimeError)
+	return &c
+}
+
+func (s *MemoryStorage) GetTrigger(ctx context.Context, id string) (*Trigger, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.triggers[id]
+	if !ok {
+		return nil, fmt.Errorf("trigger not found")
+	}
+	return t.copy(), nil
+}
+
+func (s *MemoryStorage) UpdateTrigger(ctx context.Context, t *Trigger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.triggers[t.ID]
+	if !ok {
+		return fmt.Errorf("trigger not found")
+	}
+	if !slices.Contains(triggerTypes, t.Type) {
+		return fmt.Errorf("failed to update trigger: invalid type %q", t.Type)
+	}
+	stored.WorkflowID = t.WorkflowID
+	stored.Type = t.Type
+	stored.Config = bytes.Clone(t.Config)
+	stored.Enabled = t.Enabled
+	stored.UpdatedAt = now()
+	stored.FilePath = t.FilePath
+	return nil
+}
+
+func (s *MemoryStorage) DeleteTrigger(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.triggers, id)
+	return nil
+}
+
+func (s *MemoryStorage) ListTriggers(ctx context.Context, workflowID string) ([]*Trigger, error) {
+	return s.listTriggers(func(t *memTrigger) bool { return t.WorkflowID == workflowID }, true), nil
+}
+
+func (s *MemoryStorage) ListAllTriggers(ctx context.Context) ([]*Trigger, error) {
+	return s.listTriggers(func(t *memTrigger) bool { return t.Enabled }, false), nil
+}
+
+// listTriggers returns the triggers matching keep, newest first or in creation order.
+func (s *MemoryStorage) listTriggers(keep func(*memTrigger) bool, newestFirst bool) []*Trigger {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var stored []*memTrigger
+	for _, t := range s.triggers {
+		if keep(t) {
+			stored = append(stored, t)
+		}
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		if newestFirst {
+			return stored[i].seq > stored[j].seq
+		}
+		return stored[i].seq < stored[j].seq
+	})
+	var triggers []*Trigger
+	for _, t := range stored {
+		triggers = append(triggers, t.copy())
+	}
+	return triggers
+}
+
+// SetTriggerRuntimeStatus records the runtime status of a trigger; a nil errMsg
+// clears the stored error.
+func (s *MemoryStorage) SetTriggerRuntimeStatus(ctx context.Context, id string, status TriggerRuntimeStatus, errMsg *string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.triggers[id]
+	if !ok {
+		return fmt.Errorf("trigger not found")
+	}
+	t.RuntimeStatus = status
+	t.RuntimeError = copyString(errMsg)
+	return nil
+}
+
+// --- Queued Trigger Fires ---
+
+// QueueTriggerFire holds a fire of a trigger until ListQueuedTriggerFires picks it up
+func (s *MemoryStorage) QueueTriggerFire(ctx context.Context, fire *QueuedTriggerFire) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.queuedFires[fire.ID]; ok {
+		return fmt.Errorf("failed to queue trigger fire: fire %s already exists", fire.ID)
+	}
+	c := memQueuedFire{QueuedTriggerFire: *fire, seq: s.nextSeq()}
+	c.Payload = bytes.Clone(fire.Payload)
+	s.queuedFires[fire.ID] = &c
+	return nil
+}
+
+// ListQueuedTriggerFires returns the queued fires of all triggers, oldest first
+func (s *MemoryStorage) ListQueuedTriggerFires(ctx context.Context) ([]*QueuedTriggerFire, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := make([]*memQueuedFire, 0, len(s.queuedFires))
+	for _, f := range s.queuedFires {
+		stored = append(stored, f)
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		if !stored[i].QueuedAt.Equal(stored[j].QueuedAt) {
+			return stored[i].QueuedAt.Before(stored[j].QueuedAt)
+		}
+		return stored[i].seq < stored[j].seq
+	})
+	var fires []*QueuedTriggerFire
+	for _, f := range stored {
+		c := f.QueuedTriggerFire
+		c.Payload = bytes.Clone(f.Payload)
+		fires = append(fires, &c)
+	}
+	return fires, nil
+}
+
+// DeleteQueuedTriggerFire removes a queued fire and reports whether it was
+// still queued, so that only one server runs it
+func (s *MemoryStorage) DeleteQueuedTriggerFire(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.queuedFires[id]
+	delete(s.queuedFires, id)
+	return ok, nil
+}
+
+// --- Trigger Execution History ---
+
+func (s *MemoryStorage) CreateTriggerExecution(ctx context.Context, te *TriggerExecution) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.triggerExecs[te.ID]; ok {
+		return fmt.Errorf("failed to create trigger execution: trigger execution %s already exists", te.ID)
+	}
+	if !slices.Contains(triggerExecutionStatuses, te.Status) {
+		return fmt.Errorf("failed to create trigger execution: invalid status %q", te.Status)
+	}
+	c := *te
+	c.ExecutionID = copyString(te.ExecutionID)
+	c.Payload = bytes.Clone(te.Payload)
+	c.Error = copyString(te.Error)
+	s.triggerExecs[te.ID] = &c
+	return nil
+}
+
+func (s *MemoryStorage) ListTriggerExecutions(ctx context.Context, triggerID string, limit int) ([]*TriggerExecution, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var executions []*TriggerExecution
+	for _, te := range s.triggerExecs {
+		if te.TriggerID != triggerID {
+			continue
+		}
+		c := *te
+		c.ExecutionID = copyString(te.ExecutionID)
+		c.Payload = bytes.Clone(te.Payload)
+		if len(c.Payload) == 0 {
+			c.Payload = nil
+		}
+		c.Error = copyString(te.Error)
+		executions = append(executions, &c)
+	}
+	sort.Slice(executions, func(i, j int) bool { return executions[i].FiredAt.After(executions[j].FiredAt) })
+	if limit >= 0 && len(executions) > limit {
+		executions = executions[:limit]
+	}
+	return executions, nil
+}
+
+// DeleteTriggerExecution removes an entry of trigger history
+func (s *MemoryStorage) DeleteTriggerExecution(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.triggerExecs, id)
+	return nil
+}
+
+// Ping reports whether the storage is still open
+func (s *MemoryStorage) Ping(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errors.New("storage is closed")
+	}
+	return nil
+}
+
+func (s *MemoryStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// memoryTx is a transaction begun on a MemoryStorage. It holds the store's
+// lock until it ends, so other callers wait for it as writers do in SQLite.
+type memoryTx struct {
+	*MemoryStorage                // the transaction's view, sharing base's data
+	base           *MemoryStorage // locked until Commit or Rollback
+	snapshot       memData        // base's data at Begin, restored by Rollback
+	done           bool
+}
+
+// Begin starts a transaction. It copies the whole store to be able to roll
+// back, which is fine at the sizes an in-memory store is used for.
+func (s *MemoryStorage) Begin(ctx context.Context) (Tx, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, errors.New("storage is closed")
+	}
+	return &memoryTx{
+		MemoryStorage: &MemoryStorage{memData: s.memData},
+		base:          s,
+		snapshot:      s.memData.clone(),
+	}, nil
+}
+
+func (t *memoryTx) Begin(ctx context.Context) (Tx, error) {
+	return nil, errNestedTx
+}
+
+func (t *memoryTx) Commit() error {
+	return t.end(t.MemoryStorage.memData)
+}
+
+func (t *memoryTx) Rollback() error {
+	return t.end(t.snapshot)
+}
+
+// Close rolls the transaction back; the store stays open.
+func (t *memoryTx) Close() error {
+	return t.Rollback()
+}
+
+// end leaves base with data and releases it. The transaction's view is
+// detached from base, so a stray call after the end can't change it.
+func (t *memoryTx) end(data memData) error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	t.mu.Lock()
+	t.base.memData = data
+	t.MemoryStorage.memData = newMemData()
+	t.mu.Unlock()
+	t.base.mu.Unlock()
+	return nil
+}
+
+var (
+	_ Storage = (*MemoryStorage)(nil)
+	_ Tx      = (*memoryTx)(nil)
+)
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Factory opens a Storage for a DSN handled by its driver. It receives the DSN
+// as given to Open, scheme included.
+type Factory func(dsn string) (Storage, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]Factory)
+)
+
+// Register makes a storage driver available to Open under name, the DSN scheme
+// it handles (e.g. "postgres" for "postgres://..."). Drivers usually register
+// in an init function, so that importing their package is enough to use them.
+// Like database/sql.Register it panics if name is registered twice or factory is nil.
+func Register(name string, factory Factory) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if factory == nil {
+		panic("storage: Register factory is nil")
+	}
+	if _, dup := drivers[name]; dup {
+		panic("storage: Register called twice for driver " + name)
+	}
+	drivers[name] = factory
+}
+
+// Drivers returns the names of the registered drivers, sorted.
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open opens the storage a DSN names. The DSN's scheme selects the driver:
+// "sqlite://conv3n.db" uses SQLite, "postgres://..." a driver registered as
+// "postgres". A DSN without a scheme is a SQLite database path, so existing
+// --db values keep working.
+func Open(dsn string) (Storage, error) {
+	name := "sqlite"
+	if scheme, _, ok := strings.Cut(dsn, "://"); ok {
+		name = scheme
+	}
+
+	driversMu.RLock()
+	factory, ok := drivers[name]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown storage driver %q (registered: %s)", name, strings.Join(Drivers(), ", "))
+	}
+	return factory(dsn)
+}
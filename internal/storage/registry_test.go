@@ -0,0 +1,59 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("SQLite", func(t *testing.T) {
+		dir := t.TempDir()
+		for _, dsn := range []string{filepath.Join(dir, "plain.db"), "sqlite://" + filepath.Join(dir, "scheme.db")} {
+			store, err := storage.Open(dsn)
+			if err != nil {
+				t.Fatalf("Open(%q) failed: %v", dsn, err)
+			}
+			if err := store.Ping(ctx); err != nil {
+				t.Errorf("Ping failed for %q: %v", dsn, err)
+			}
+			store.Close()
+		}
+	})
+
+	t.Run("RegisteredDriver", func(t *testing.T) {
+		errFake := errors.New("fake driver")
+		var got string
+		storage.Register("fake", func(dsn string) (storage.Storage, error) {
+			got = dsn
+			return nil, errFake
+		})
+		if !slices.Contains(storage.Drivers(), "fake") {
+			t.Errorf("expected fake in drivers, got %v", storage.Drivers())
+		}
+		if _, err := storage.Open("fake://host/db"); !errors.Is(err, errFake) || got != "fake://host/db" {
+			t.Errorf("expected the fake factory to get the DSN, got %q (%v)", got, err)
+		}
+
+		defer func() {
+			if recover() == nil {
+				t.Error("expected registering a driver twice to panic")
+			}
+		}()
+		storage.Register("fake", func(string) (storage.Storage, error) { return nil, nil })
+	})
+
+	t.Run("UnknownDriver", func(t *testing.T) {
+		_, err := storage.Open("mystery://db")
+		if err == nil || !strings.Contains(err.Error(), `unknown storage driver "mystery"`) {
+			t.Errorf("expected unknown driver error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,317 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ScrubRule selects personal data ScrubbingStorage hashes or removes before it
+// is stored. Pattern matches text in the payloads' strings: "email", a
+// "card_number" (13 to 19 digits, optionally grouped, passing the Luhn check)
+// or "regex" for Regex. Fields restricts the rule to the values of fields with
+// these names, at any depth and ignoring case; a rule with fields and no
+// pattern applies to their whole values.
+type ScrubRule struct {
+	Pattern string   `json:"pattern,omitempty"`
+	Regex   string   `json:"regex,omitempty"`
+	Fields  []string `json:"fields,omitempty"`
+	// Action is ScrubHash (the default) or ScrubRemove
+	Action string `json:"action,omitempty"`
+}
+
+const (
+	// ScrubHash replaces the data by an HMAC of it under the storage's hash
+	// key, "hmac:" and 16 hex digits: the same value always hashes alike, so
+	// runs can still be correlated, but guessed values can't be checked
+	// against the hashes without the key.
+	ScrubHash = "hash"
+	// ScrubRemove replaces matched text by "[REMOVED]", and drops whole fields.
+	ScrubRemove = "remove"
+)
+
+// scrubRemoved replaces the text ScrubRemove rules match.
+const scrubRemoved = "[REMOVED]"
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	cardPattern  = regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`)
+)
+
+// scrubRule is a ScrubRule, compiled.
+type scrubRule struct {
+	re     *regexp.Regexp // Nil for whole fields
+	luhn   bool           // Matches must pass the Luhn check
+	fields map[string]bool
+	remove bool
+	key    []byte // Keys the hashes of ScrubHash rules
+}
+
+// compileScrubRules checks rules, returning the errors of those that are
+// invalid; hashKey keys the hashes of ScrubHash rules, which require one.
+func compileScrubRules(rules []ScrubRule, hashKey []byte) ([]scrubRule, error) {
+	compiled := make([]scrubRule, 0, len(rules))
+	var errs []error
+	for i, r := range rules {
+		c := scrubRule{key: hashKey}
+		switch r.Pattern {
+		case "":
+			if len(r.Fields) == 0 {
+				errs = append(errs, fmt.Errorf("scrub[%d]: a pattern or fields are required", i))
+			}
+		case "email":
+			c.re = emailPattern
+		case "card_number":
+			c.re, c.luhn = cardPattern, true
+		case "regex":
+			re, err := regexp.Compile(r.Regex)
+			if err != nil || r.Regex == "" {
+				errs = append(errs, fmt.Errorf("scrub[%d]: invalid regex %q", i, r.Regex))
+			}
+			c.re = re
+		default:
+			errs = append(errs, fmt.Errorf("scrub[%d]: unknown pattern %q (want email, card_number or regex)", i, r.Pattern))
+		}
+		switch r.Action {
+		case "", ScrubHash:
+			if len(hashKey) == 0 {
+				errs = append(errs, fmt.Errorf("scrub[%d]: the %s action requires a hash key", i, ScrubHash))
+			}
+		case ScrubRemove:
+			c.remove = true
+		default:
+			errs = append(errs, fmt.Errorf("scrub[%d]: unknown action %q (want %s or %s)", i, r.Action, ScrubHash, ScrubRemove))
+		}
+		if len(r.Fields) > 0 {
+			c.fields = make(map[string]bool, len(r.Fields))
+			for _, f := range r.Fields {
+				c.fields[strings.ToLower(f)] = true
+			}
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, errors.Join(errs...)
+}
+
+// hash returns the replacement of s by a ScrubHash rule.
+func (r *scrubRule) hash(s string) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(s))
+	return "hmac:" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// replace applies the rule's pattern to s.
+func (r *scrubRule) replace(s string) string {
+	return r.re.ReplaceAllStringFunc(s, func(match string) string {
+		if r.luhn && !luhnValid(match) {
+			return match
+		}
+		if r.remove {
+			return scrubRemoved
+		}
+		return r.hash(match)
+	})
+}
+
+// luhnValid reports whether the digits of s pass the Luhn check.
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// scrubValue returns v, a decoded JSON value, with rules applied. inField holds
+// the rules applying to v because of the field it is the value of.
+func scrubValue(v interface{}, rules []scrubRule, inField []*scrubRule) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			var matched []*scrubRule
+			removed := false
+			for i := range rules {
+				r := &rules[i]
+				if !r.fields[strings.ToLower(key)] {
+					continue
+				}
+				if r.re == nil {
+					if r.remove {
+						removed = true
+						break
+					}
+					raw, _ := json.Marshal(value)
+					value = r.hash(strings.Trim(string(raw), `"`))
+					continue
+				}
+				matched = append(matched, r)
+			}
+			if !removed {
+				out[key] = scrubValue(value, rules, append(inField[:len(inField):len(inField)], matched...))
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, value := range v {
+			out[i] = scrubValue(value, rules, inField)
+		}
+		return out
+	case string:
+		return scrubString(v, rules, inField)
+	case json.Number:
+		// Card numbers are often sent as numbers
+		if s := scrubString(v.String(), rules, inField); s != v.String() {
+			return s
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// scrubString applies to s the rules applying everywhere and those in inField.
+func scrubString(s string, rules []scrubRule, inField []*scrubRule) string {
+	for i := range rules {
+		if rules[i].re != nil && rules[i].fields == nil {
+			s = rules[i].replace(s)
+		}
+	}
+	for _, r := range inField {
+		s = r.replace(s)
+	}
+	return s
+}
+
+// scrub returns payload, JSON, with rules applied; other payloads are scrubbed
+// as text.
+func scrub(payload []byte, rules []scrubRule) []byte {
+	if len(payload) == 0 || len(rules) == 0 {
+		return payload
+	}
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return []byte(scrubString(string(payload), rules, nil))
+	}
+	out, err := json.Marshal(scrubValue(v, rules, nil))
+	if err != nil {
+		return payload
+	}
+	return out
+}
+
+// ScrubbingStorage is a Storage hashing or removing personal data matched by
+// its rules before it is stored, for data minimization: from node results and
+// inputs, trigger payloads and the state of finished executions. The state of
+// executions still running or waiting is kept as it is so that they can
+// resume, until they end: an execution ending without a new state, like one
+// the reaper fails, has its stored state scrubbed. A run resumed after a crash
+// gets the scrubbed results of the nodes it doesn't run again. Wrap a store in
+// it before NewEncryptedStorage, so that it sees the payloads before they are
+// encrypted.
+type ScrubbingStorage struct {
+	Storage
+	rules []scrubRule
+}
+
+// NewScrubbingStorage returns store with rules applied to what is stored.
+// hashKey, a server secret, keys the hashes of ScrubHash rules; it is
+// required when any rule hashes.
+func NewScrubbingStorage(store Storage, rules []ScrubRule, hashKey []byte) (*ScrubbingStorage, error) {
+	compiled, err := compileScrubRules(rules, hashKey)
+	if err != nil {
+		return nil, err
+	}
+	return &ScrubbingStorage{Storage: store, rules: compiled}, nil
+}
+
+// scrubbingTx is a Tx of a ScrubbingStorage.
+type scrubbingTx struct {
+	*ScrubbingStorage
+	tx Tx
+}
+
+func (t *scrubbingTx) Commit() error   { return t.tx.Commit() }
+func (t *scrubbingTx) Rollback() error { return t.tx.Rollback() }
+
+func (s *ScrubbingStorage) Begin(ctx context.Context) (Tx, error) {
+	tx, err := s.Storage.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &scrubbingTx{ScrubbingStorage: &ScrubbingStorage{Storage: tx, rules: s.rules}, tx: tx}, nil
+}
+
+// finalState returns state scrubbed if status ends the execution. Without a
+// new state, the one stored while the execution ran is scrubbed in its place.
+func (s *ScrubbingStorage) finalState(ctx context.Context, executionID string, status ExecutionStatus, state []byte) ([]byte, error) {
+	if status == ExecutionStatusRunning || status == ExecutionStatusWaiting {
+		return state, nil
+	}
+	if state == nil {
+		exec, err := s.Storage.GetExecution(ctx, executionID)
+		if err != nil {
+			return nil, err
+		}
+		if len(exec.State) == 0 {
+			return nil, nil
+		}
+		state = exec.State
+	}
+	return scrub(state, s.rules), nil
+}
+
+func (s *ScrubbingStorage) SaveExecutionTriggerData(ctx context.Context, executionID string, data []byte) error {
+	return s.Storage.SaveExecutionTriggerData(ctx, executionID, scrub(data, s.rules))
+}
+
+func (s *ScrubbingStorage) UpdateExecutionStatus(ctx context.Context, executionID string, status ExecutionStatus, state []byte, errorMsg *string) error {
+	state, err := s.finalState(ctx, executionID, status, state)
+	if err != nil {
+		return err
+	}
+	return s.Storage.UpdateExecutionStatus(ctx, executionID, status, state, errorMsg)
+}
+
+func (s *ScrubbingStorage) TransitionExecutionStatus(ctx context.Context, executionID string, from, to ExecutionStatus, state []byte, errorMsg *string) (bool, error) {
+	state, err := s.finalState(ctx, executionID, to, state)
+	if err != nil {
+		return false, err
+	}
+	return s.Storage.TransitionExecutionStatus(ctx, executionID, from, to, state, errorMsg)
+}
+
+func (s *ScrubbingStorage) SaveNodeResult(ctx context.Context, executionID, nodeID string, result []byte) error {
+	return s.Storage.SaveNodeResult(ctx, executionID, nodeID, scrub(result, s.rules))
+}
+
+func (s *ScrubbingStorage) SaveNodeInput(ctx context.Context, executionID, nodeID string, input []byte) error {
+	return s.Storage.SaveNodeInput(ctx, executionID, nodeID, scrub(input, s.rules))
+}
+
+func (s *ScrubbingStorage) CreateTriggerExecution(ctx context.Context, triggerExec *TriggerExecution) error {
+	te := *triggerExec
+	te.Payload = scrub(te.Payload, s.rules)
+	return s.Storage.CreateTriggerExecution(ctx, &te)
+}
@@ -0,0 +1,88 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/conv3n/conv3n/internal/storage"
+)
+
+func TestScrubbingStorage(t *testing.T) {
+	rules := []storage.ScrubRule{
+		{Pattern: "email"},
+		{Pattern: "card_number", Action: storage.ScrubRemove},
+		{Pattern: "regex", Regex: `\d{3}-\d{2}-\d{4}`, Fields: []string{"ssn"}},
+		{Fields: []string{"password"}, Action: storage.ScrubRemove},
+		{Fields: []string{"Phone"}},
+	}
+	forEachStorage(t, func(t *testing.T, store storage.Storage) {
+		ctx := context.Background()
+		scrubbing, err := storage.NewScrubbingStorage(store, rules, []byte("hash-key"))
+		if err != nil {
+			t.Fatalf("failed to create scrubbing storage: %v", err)
+		}
+		id, _ := scrubbing.CreateExecution(ctx, "wf-scrub")
+
+		payload := `{"user":{"email":"ada@example.com","note":"write to ada@example.com","ssn":"078-05-1120","password":"hunter2","phone":5551234},` +
+			`"cards":["4111 1111 1111 1111",4242424242424242,"1234 5678 9012 3456"],"ref":"078-05-1120"}`
+		if err := scrubbing.SaveNodeResult(ctx, id, "fetch", []byte(payload)); err != nil {
+			t.Fatalf("failed to save node result: %v", err)
+		}
+		got, _ := store.GetNodeResult(ctx, id, "fetch")
+		for _, pii := range []string{"ada@example.com", "hunter2", "password", "4111", "4242", "5551234", `"ssn":"078-05-1120"`} {
+			if strings.Contains(string(got), pii) {
+				t.Errorf("expected %s to be scrubbed, got %s", pii, got)
+			}
+		}
+		for _, kept := range []string{
+			`"email":"hmac:`, `"note":"write to hmac:`, `"ssn":"hmac:`, `"phone":"hmac:`,
+			`"[REMOVED]","[REMOVED]"`,
+			`"1234 5678 9012 3456"`, // Not a card number: fails the Luhn check
+			`"ref":"078-05-1120"`,   // The ssn rule only applies to ssn fields
+		} {
+			if !strings.Contains(string(got), kept) {
+				t.Errorf("expected %s in the stored result, got %s", kept, got)
+			}
+		}
+
+		// The state of a running execution is kept for resuming; a finished one's isn't
+		state := []byte(`{"results":{"fetch":{"email":"ada@example.com"}}}`)
+		scrubbing.SaveExecutionState(ctx, id, state)
+		if exec, _ := store.GetExecution(ctx, id); !bytes.Equal(exec.State, state) {
+			t.Errorf("expected the running state as is, got %s", exec.State)
+		}
+		storage.WithTx(ctx, scrubbing, func(tx storage.Tx) error {
+			_, err := tx.TransitionExecutionStatus(ctx, id, storage.ExecutionStatusRunning, storage.ExecutionStatusCompleted, state, nil)
+			return err
+		})
+		if exec, _ := store.GetExecution(ctx, id); strings.Contains(string(exec.State), "ada@example.com") || exec.Status != storage.ExecutionStatusCompleted {
+			t.Errorf("expected the final state to be scrubbed, got %s %s", exec.Status, exec.State)
+		}
+
+		// Failing a run without a new state, as the reaper does, scrubs the one it left
+		lost, _ := scrubbing.CreateExecution(ctx, "wf-scrub")
+		scrubbing.SaveExecutionState(ctx, lost, state)
+		scrubbing.TransitionExecutionStatus(ctx, lost, storage.ExecutionStatusRunning, storage.ExecutionStatusFailed, nil, nil)
+		if exec, _ := store.GetExecution(ctx, lost); strings.Contains(string(exec.State), "ada@example.com") || !strings.Contains(string(exec.State), "hmac:") {
+			t.Errorf("expected the state left by the lost run to be scrubbed, got %s", exec.State)
+		}
+	})
+}
+
+func TestScrubRules_Invalid(t *testing.T) {
+	_, err := storage.NewScrubbingStorage(nil, []storage.ScrubRule{
+		{Pattern: "phone", Action: storage.ScrubRemove},
+		{Pattern: "regex", Regex: "(", Action: storage.ScrubRemove},
+		{Pattern: "email", Action: "mask"},
+		{Action: storage.ScrubRemove},
+		{Pattern: "email"},
+	}, nil)
+	for _, want := range []string{`scrub[0]: unknown pattern "phone"`, `scrub[1]: invalid regex`, `scrub[2]: unknown action "mask"`,
+		"scrub[3]: a pattern or fields are required", "scrub[4]: the hash action requires a hash key"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// snippetTokens is how many words a search snippet holds, like the snippet()
+// calls of SQLiteStorage.SearchExecutions.
+const snippetTokens = 12
+
+// searchToken is a word of a searched text, at text[start:end].
+type searchToken struct {
+	start, end int
+	word       string // Lowercased
+}
+
+// tokenize splits text into words the way the FTS5 unicode61 tokenizer does:
+// runs of letters and digits, everything else separating them.
+func tokenize(text string) []searchToken {
+	var tokens []searchToken
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, searchToken{start, i, strings.ToLower(text[start:i])})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, searchToken{start, len(text), strings.ToLower(text[start:])})
+	}
+	return tokens
+}
+
+// searchTerms returns the distinct words of a search query, lowercased.
+func searchTerms(query string) []string {
+	var terms []string
+	for _, t := range tokenize(query) {
+		if !slices.Contains(terms, t.word) {
+			terms = append(terms, t.word)
+		}
+	}
+	return terms
+}
+
+// searchText reports whether text has every one of terms as a word and, if so,
+// returns a snippet of it from around the first one, marked up like those of
+// ExecutionSearchHit.
+func searchText(text string, terms []string) (string, bool) {
+	tokens := tokenize(text)
+	first := -1
+	var found []string
+	for i, t := range tokens {
+		if !slices.Contains(terms, t.word) {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		if !slices.Contains(found, t.word) {
+			found = append(found, t.word)
+		}
+	}
+	if len(terms) == 0 || len(found) < len(terms) {
+		return "", false
+	}
+
+	from := max(0, first-snippetTokens/4)
+	to := min(len(tokens), from+snippetTokens)
+	var b strings.Builder
+	pos := 0
+	if from > 0 {
+		b.WriteString("…")
+		pos = tokens[from].start
+	}
+	for _, t := range tokens[from:to] {
+		b.WriteString(text[pos:t.start])
+		if slices.Contains(terms, t.word) {
+			b.WriteString("<mark>" + text[t.start:t.end] + "</mark>")
+		} else {
+			b.WriteString(text[t.start:t.end])
+		}
+		pos = t.end
+	}
+	if to < len(tokens) {
+		b.WriteString("…")
+	} else {
+		b.WriteString(text[pos:])
+	}
+	return b.String(), true
+}
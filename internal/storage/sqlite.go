@@ -3,7 +3,10 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
@@ -18,6 +21,7 @@ const (
 	ExecutionStatusCompleted ExecutionStatus = "completed"
 	ExecutionStatusFailed    ExecutionStatus = "failed"
 	ExecutionStatusCancelled ExecutionStatus = "cancelled" // Execution stopped by user
+	ExecutionStatusWaiting   ExecutionStatus = "waiting"   // Suspended in a delay node until wake_at or a signal
 )
 
 // Workflow represents a stored workflow definition
@@ -27,6 +31,12 @@ type Workflow struct {
 	Definition []byte    `json:"definition"` // Stores the full JSON (engine.Workflow)
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
+	// Source is the file a workflow synced from a workflows directory was read
+	// from, relative to the directory; empty for workflows managed via the API.
+	Source string `json:"source,omitempty"`
+	// Revision is the git revision of the workflows directory the definition
+	// was synced at, empty if unknown.
+	Revision string `json:"revision,omitempty"`
 }
 
 // Execution represents a single workflow execution instance
@@ -39,8 +49,129 @@ type Execution struct {
 	StartedAt   time.Time
 	CompletedAt *time.Time
 	Error       *string
+	Test        bool   // Test-mode run, e.g. from a test webhook
+	TriggerData []byte // JSON payload of the event that started the run, nil if none
+	// HeartbeatAt is the last sign of life of the execution while it ran (see
+	// HeartbeatExecution), nil before its first
+	HeartbeatAt *time.Time
+	// RequestID is the X-Request-ID of the API call that started the run, empty
+	// for runs not started through the API
+	RequestID string
+	// Revision is the Revision of the workflow when the run started
+	Revision string
+	// Usage is the resources the execution used, across all its runs
+	Usage ExecutionUsage
+	// Canary is set for a run of the workflow's canary version (see WorkflowCanary)
+	Canary bool
+	// CorrelationID ties the execution to a business transaction traced across
+	// systems, empty if the caller gave none
+	CorrelationID string
+	// Labels are free-form key/value tags attached by the caller, nil if none
+	Labels map[string]string
 }
 
+// ExecutionFilter selects executions for FindExecutions; empty fields match
+// every execution
+type ExecutionFilter struct {
+	WorkflowID    string
+	CorrelationID string
+	Labels        map[string]string // Executions having all of these labels
+	Test          bool              // Only test-mode runs
+}
+
+// ExecutionSearchHit is a node result or error of an execution that matched a
+// SearchExecutions query
+type ExecutionSearchHit struct {
+	ExecutionID string
+	WorkflowID  string
+	StartedAt   time.Time
+	NodeID      string // Empty for a match in the execution's own error
+	Field       string // SearchFieldResult or SearchFieldError
+	// Snippet is the matching text around the terms, which are wrapped in
+	// <mark> and </mark>
+	Snippet string
+}
+
+// Fields of an execution searched by SearchExecutions
+const (
+	SearchFieldResult = "result" // A node result
+	SearchFieldError  = "error"  // The error of a node, or of the execution
+)
+
+// ExecutionUsage is the resources used by an execution, for attributing load
+type ExecutionUsage struct {
+	Nodes       int   // Nodes run
+	CPUTimeMS   int64 // User and system CPU time of its Bun processes
+	BytesStored int64 // Size of the node inputs and results saved
+	HTTPCalls   int   // Requests to external services, retries and pages included
+}
+
+// Add adds o to u.
+func (u *ExecutionUsage) Add(o ExecutionUsage) {
+	u.Nodes += o.Nodes
+	u.CPUTimeMS += o.CPUTimeMS
+	u.BytesStored += o.BytesStored
+	u.HTTPCalls += o.HTTPCalls
+}
+
+// NodeResult is the stored output of one node in an execution
+type NodeResult struct {
+	ExecutionID string
+	NodeID      string
+	Result      []byte
+	CreatedAt   time.Time
+}
+
+// NodeStatus is the status of one node in an execution
+type NodeStatus string
+
+const (
+	NodeStatusRunning NodeStatus = "running"
+	NodeStatusSuccess NodeStatus = "success"
+	NodeStatusFailed  NodeStatus = "failed"
+	NodeStatusSkipped NodeStatus = "skipped" // Not reached by the execution (e.g. on a branch not taken)
+	NodeStatusCached  NodeStatus = "cached"  // Result reused from an earlier attempt of the execution
+)
+
+// NodeExecution records the status and timing of one node in an execution
+type NodeExecution struct {
+	ExecutionID string
+	NodeID      string
+	Status      NodeStatus
+	Port        string // Output port taken, once finished
+	Attempts    int    // Times the node was started within the execution
+	StartedAt   *time.Time
+	FinishedAt  *time.Time
+	Error       *string
+}
+
+// Environment is a named set of global variable overrides (e.g. dev, staging, prod)
+type Environment struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// GlobalVariable is a server-managed value exposed to workflows as $globals.<name>.
+// Variables with an empty Environment are shared defaults; a named environment overrides them.
+type GlobalVariable struct {
+	Environment string    `json:"environment"`
+	Name        string    `json:"name"`
+	Value       []byte    `json:"value"` // JSON-encoded value
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TriggerRuntimeStatus is what a trigger is actually doing, as maintained by the
+// trigger manager; an enabled trigger may still be stopped or errored.
+type TriggerRuntimeStatus string
+
+const (
+	TriggerRuntimeRunning    TriggerRuntimeStatus = "running"
+	TriggerRuntimeStopped    TriggerRuntimeStatus = "stopped"
+	TriggerRuntimeErrored    TriggerRuntimeStatus = "errored"    // Failed to start or crashed; see RuntimeError
+	TriggerRuntimeRestarting TriggerRuntimeStatus = "restarting" // Being stopped and started again, e.g. after an update
+)
+
 // Trigger represents a workflow trigger configuration
 type Trigger struct {
 	ID         string
@@ -51,17 +182,113 @@ type Trigger struct {
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 	FilePath   string // New: Path to the TypeScript trigger file, if Type is 'typescript'
+	// RuntimeStatus and RuntimeError are set by SetTriggerRuntimeStatus only;
+	// CreateTrigger and UpdateTrigger leave them alone
+	RuntimeStatus TriggerRuntimeStatus
+	RuntimeError  *string
 }
 
 // TriggerExecution represents a single trigger firing event
 type TriggerExecution struct {
 	ID          string
 	TriggerID   string
+	WorkflowID  string  // Workflow run; a bound workflow for triggers shared across workflows
 	ExecutionID *string // NULL if workflow execution failed to start
 	FiredAt     time.Time
 	Status      string // success, failed, skipped
 	Payload     []byte // JSON-encoded trigger payload (e.g. webhook body)
 	Error       *string
+	Backfill    bool // Catch-up run for a schedule window missed while the server was down
+}
+
+// TriggerRunCount counts the runs of a trigger on one day that were left out
+// of execution history
+type TriggerRunCount struct {
+	TriggerID  string
+	Day        string // YYYY-MM-DD, in UTC
+	SampledOut int    // Successful runs dropped by the trigger's sampling
+}
+
+// QueuedTriggerFire is a fire of a trigger held while the server was in
+// maintenance, to be run once it ends or by the next server to start
+type QueuedTriggerFire struct {
+	ID        string
+	TriggerID string
+	Payload   []byte // JSON-encoded trigger payload
+	Backfill  bool   // Catch-up run for a missed schedule window
+	QueuedAt  time.Time
+}
+
+// WorkflowDayStats aggregates the executions of a workflow that finished on
+// one day, as of the last RollupWorkflowStats of that day. Test-mode runs are left out.
+type WorkflowDayStats struct {
+	WorkflowID string
+	Day        string // YYYY-MM-DD, in UTC
+	Runs       int    // Executions that completed, failed or were cancelled
+	Failures   int
+	DurationMS int64          // Total run time of those executions
+	SampledOut int            // Successful runs of its triggers left out of history by sampling
+	Usage      ExecutionUsage // Total usage of those executions
+}
+
+// WorkflowCanary is a new version of a workflow that runs for a share of its
+// trigger fires, while the others run the stable version, until it is promoted
+type WorkflowCanary struct {
+	WorkflowID string
+	Definition []byte // JSON-encoded definition of the new version
+	Percent    int    // Share of the fires running it, 1 to 100
+	// CreatedAt is when the definition was set; changing only Percent keeps it
+	CreatedAt time.Time
+}
+
+// WorkflowSnapshot is the blessed run of a workflow that snapshot tests compare
+// new runs against (see engine.Snapshot)
+type WorkflowSnapshot struct {
+	WorkflowID  string
+	ExecutionID string // Execution it was recorded from
+	Data        []byte // JSON-encoded engine.Snapshot
+	CreatedAt   time.Time
+}
+
+// VersionStats counts the executions of one version of a workflow.
+// Test-mode runs are left out.
+type VersionStats struct {
+	Runs       int   // Executions started
+	Completed  int   // Executions that completed
+	Failed     int   // Executions that failed
+	DurationMS int64 // Total run time of the finished executions
+}
+
+// WorkflowQuota is the quota an admin set for a workflow, replacing the
+// server's default limits that it sets (see engine.Quotas)
+type WorkflowQuota struct {
+	WorkflowID       string
+	ExecutionsPerDay int   // Executions started per day (UTC); 0 keeps the default
+	StorageBytes     int64 // Bytes stored by its executions in history; 0 keeps the default
+	// OverrideUntil lifts the workflow's limits until then, nil if not lifted
+	OverrideUntil *time.Time
+	UpdatedAt     time.Time
+}
+
+// PurgeRecord is the audit record of a purge of the data stored about a data
+// subject (see engine.PurgeSubject). It keeps a hash of the subject's
+// identifier, not the identifier, so that the audit trail holds none of the
+// data it records the removal of.
+type PurgeRecord struct {
+	ID          string
+	SubjectHash string // HMAC-SHA256 of the subject identifier under the server's hash key, in hex
+	// MatchedBy is "content" for data containing the identifier, or
+	// "label:<key>" for executions labelled <key>=identifier
+	MatchedBy         string
+	Reason            string
+	Executions        int // Executions deleted with their node results, inputs and timeline
+	TriggerExecutions int // Entries of trigger history deleted with their payloads
+	QueuedFires       int // Fires queued during maintenance deleted before they ran
+	Snapshots         int // Workflow snapshots deleted because they were blessed from a purged run
+	// SkippedRunning counts matching executions left in place because they
+	// were still running; purge again once they finish
+	SkippedRunning int
+	PurgedAt       time.Time
 }
 
 // Storage defines the interface for workflow persistence
@@ -77,13 +304,56 @@ type Storage interface {
 
 	// Execution Management - track history of all workflow runs
 	CreateExecution(ctx context.Context, workflowID string) (executionID string, err error)
+	MarkTestExecution(ctx context.Context, executionID string) error
+	MarkCanaryExecution(ctx context.Context, executionID string) error
+	SaveExecutionTriggerData(ctx context.Context, executionID string, data []byte) error
+	SetExecutionRequestID(ctx context.Context, executionID, requestID string) error
+	SetExecutionLabels(ctx context.Context, executionID, correlationID string, labels map[string]string) error
+	AddExecutionUsage(ctx context.Context, executionID string, usage ExecutionUsage) error
 	UpdateExecutionStatus(ctx context.Context, executionID string, status ExecutionStatus, state []byte, errorMsg *string) error
+	TransitionExecutionStatus(ctx context.Context, executionID string, from, to ExecutionStatus, state []byte, errorMsg *string) (bool, error)
+	SaveExecutionState(ctx context.Context, executionID string, state []byte) (bool, error)
+	HeartbeatExecution(ctx context.Context, executionID string) (bool, error)
 	GetExecution(ctx context.Context, executionID string) (*Execution, error)
 	ListExecutions(ctx context.Context, workflowID string, limit int) ([]*Execution, error)
+	FindExecutions(ctx context.Context, filter ExecutionFilter, limit int) ([]*Execution, error)
+	SearchExecutions(ctx context.Context, query string, limit int) ([]*ExecutionSearchHit, error)
+	SuspendExecution(ctx context.Context, executionID string, state []byte, wakeAt time.Time) error
+	ClaimDueExecutions(ctx context.Context, now time.Time, limit int) ([]*Execution, error)
+	SuspendExecutionForSignal(ctx context.Context, executionID string, state []byte, signal string, wakeAt *time.Time) error
+	ClaimSignaledExecutions(ctx context.Context, signal string) ([]*Execution, error)
+	ListStaleExecutions(ctx context.Context, before time.Time, limit int) ([]*Execution, error)
+
+	// Rate Limits - shared counters for std/rate_limit nodes
+	IncrementRateLimit(ctx context.Context, key string, windowStart time.Time) (int, error)
+
+	// Dedupe Keys - seen-sets for std/dedupe nodes
+	MarkSeen(ctx context.Context, scope, key string, now time.Time, expiresAt *time.Time) (bool, error)
 
 	// Node Results - now tied to execution_id instead of workflow_id
 	SaveNodeResult(ctx context.Context, executionID, nodeID string, result []byte) error
 	GetNodeResult(ctx context.Context, executionID, nodeID string) ([]byte, error)
+	ListNodeResults(ctx context.Context, executionID string) ([]*NodeResult, error)
+	SaveNodeInput(ctx context.Context, executionID, nodeID string, input []byte) error
+	StartNodeExecution(ctx context.Context, executionID, nodeID string) error
+	FinishNodeExecution(ctx context.Context, executionID, nodeID string, status NodeStatus, port string, errorMsg *string) error
+	ListNodeExecutions(ctx context.Context, executionID string) ([]*NodeExecution, error)
+	GetNodeInput(ctx context.Context, executionID, nodeID string) ([]byte, error)
+	DeleteExecutionData(ctx context.Context, executionID string) error
+	DeleteExecution(ctx context.Context, executionID string) error
+
+	// Workflow Static Data - key/value state a workflow keeps across executions
+	GetWorkflowStaticData(ctx context.Context, workflowID string) ([]byte, error)
+	SaveWorkflowStaticData(ctx context.Context, workflowID string, data []byte) error
+
+	// Global Variables and Environments
+	CreateEnvironment(ctx context.Context, env *Environment) error
+	GetEnvironment(ctx context.Context, name string) (*Environment, error)
+	ListEnvironments(ctx context.Context) ([]*Environment, error)
+	DeleteEnvironment(ctx context.Context, name string) error
+	SetGlobalVariable(ctx context.Context, v *GlobalVariable) error
+	ListGlobalVariables(ctx context.Context, environment string) ([]*GlobalVariable, error)
+	DeleteGlobalVariable(ctx context.Context, environment, name string) error
 
 	// Trigger Management
 	CreateTrigger(ctx context.Context, trigger *Trigger) error
@@ -92,10 +362,57 @@ type Storage interface {
 	DeleteTrigger(ctx context.Context, id string) error
 	ListTriggers(ctx context.Context, workflowID string) ([]*Trigger, error)
 	ListAllTriggers(ctx context.Context) ([]*Trigger, error)
+	SetTriggerRuntimeStatus(ctx context.Context, id string, status TriggerRuntimeStatus, errMsg *string) error
 
 	// Trigger Execution History
 	CreateTriggerExecution(ctx context.Context, triggerExec *TriggerExecution) error
 	ListTriggerExecutions(ctx context.Context, triggerID string, limit int) ([]*TriggerExecution, error)
+	DeleteTriggerExecution(ctx context.Context, id string) error
+
+	// Trigger Idempotency Keys - dedupe retried deliveries (see TriggerManager.FireOnce)
+	ClaimIdempotencyKey(ctx context.Context, triggerID, key string) (executionID string, claimed bool, err error)
+	SetIdempotencyKeyExecution(ctx context.Context, triggerID, key, executionID string) error
+	ReleaseIdempotencyKey(ctx context.Context, triggerID, key string) error
+
+	// Trigger Run Counts - daily counters of runs not kept in execution history
+	CountSampledOutRun(ctx context.Context, triggerID string, at time.Time) error
+	ListTriggerRunCounts(ctx context.Context, triggerID string, since time.Time) ([]*TriggerRunCount, error)
+
+	// Queued Trigger Fires - fires held during maintenance (see QueuedTriggerFire)
+	QueueTriggerFire(ctx context.Context, fire *QueuedTriggerFire) error
+	ListQueuedTriggerFires(ctx context.Context) ([]*QueuedTriggerFire, error)
+	DeleteQueuedTriggerFire(ctx context.Context, id string) (bool, error)
+
+	// Workflow Stats - daily aggregates of execution history (see WorkflowDayStats)
+	RollupWorkflowStats(ctx context.Context, day time.Time) error
+	ListWorkflowStats(ctx context.Context, workflowID string, since time.Time) ([]*WorkflowDayStats, error)
+
+	// Workflow Canaries - new versions run for a share of trigger fires (see WorkflowCanary)
+	GetWorkflowCanary(ctx context.Context, workflowID string) (*WorkflowCanary, error)
+	SetWorkflowCanary(ctx context.Context, canary *WorkflowCanary) error
+	DeleteWorkflowCanary(ctx context.Context, workflowID string) error
+	WorkflowVersionStats(ctx context.Context, workflowID string, since time.Time) (stable, canary VersionStats, err error)
+
+	// Workflow Snapshots - blessed runs snapshot tests compare against (see WorkflowSnapshot)
+	GetWorkflowSnapshot(ctx context.Context, workflowID string) (*WorkflowSnapshot, error)
+	SetWorkflowSnapshot(ctx context.Context, snapshot *WorkflowSnapshot) error
+	DeleteWorkflowSnapshot(ctx context.Context, workflowID string) error
+
+	// Workflow Quotas - limits on executions and storage per workflow (see WorkflowQuota)
+	GetWorkflowQuota(ctx context.Context, workflowID string) (*WorkflowQuota, error)
+	SetWorkflowQuota(ctx context.Context, quota *WorkflowQuota) error
+	DeleteWorkflowQuota(ctx context.Context, workflowID string) error
+	WorkflowUsageSince(ctx context.Context, workflowID string, since time.Time) (executions int, usage ExecutionUsage, err error)
+
+	// Purges - audit trail of the data deleted for data subjects (see PurgeRecord)
+	RecordPurge(ctx context.Context, purge *PurgeRecord) error
+	ListPurges(ctx context.Context, limit int) ([]*PurgeRecord, error)
+
+	// Transactions - apply multi-row state changes together (see Tx and WithTx)
+	Begin(ctx context.Context) (Tx, error)
+
+	// Ping verifies the database answers a query (used by readiness checks)
+	Ping(ctx context.Context) error
 
 	Close() error
 }
@@ -103,12 +420,35 @@ type Storage interface {
 // SQLiteStorage implements Storage using modernc.org/sqlite (Pure Go)
 type SQLiteStorage struct {
 	db *sql.DB
+	q  sqlQuerier // db, or tx in a storage returned by Begin
+	tx *sql.Tx
+}
+
+// sqlQuerier is what *sql.DB and *sql.Tx have in common.
+type sqlQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func init() {
+	Register("sqlite", func(dsn string) (Storage, error) {
+		return NewSQLite(strings.TrimPrefix(dsn, "sqlite://"))
+	})
 }
 
 // NewSQLite creates a new SQLite-backed storage
 // Uses modernc.org/sqlite for cross-platform builds without CGO
 func NewSQLite(dbPath string) (*SQLiteStorage, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	// Concurrent runs read and write the same file; wait for a lock instead of
+	// failing immediately with SQLITE_BUSY. Set per DSN so every pooled connection gets it.
+	// Transactions take the write lock up front: one upgrading from a read lock
+	// can fail with SQLITE_BUSY without waiting.
+	sep := "?"
+	if strings.Contains(dbPath, "?") {
+		sep = "&"
+	}
+	db, err := sql.Open("sqlite", dbPath+sep+"_pragma=busy_timeout(5000)&_txlock=immediate")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -119,9 +459,52 @@ func NewSQLite(dbPath string) (*SQLiteStorage, error) {
 		return nil, fmt.Errorf("failed to initialize schema: %w", err)
 	}
 
-	return &SQLiteStorage{db: db}, nil
+	return &SQLiteStorage{db: db, q: db}, nil
 }
 
+// workflowExecutionsColumns defines workflow_executions; shared with
+// migrateExecutionStatuses, which rebuilds the table when the status CHECK changes.
+const workflowExecutionsColumns = `
+		execution_id TEXT PRIMARY KEY,
+		workflow_id TEXT NOT NULL,
+		status TEXT NOT NULL CHECK(status IN ('running', 'completed', 'failed', 'cancelled', 'waiting')),
+		state BLOB NOT NULL,
+		started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		completed_at DATETIME,
+		error TEXT,
+		wake_at DATETIME,
+		signal TEXT,
+		test BOOLEAN NOT NULL DEFAULT 0,
+		trigger_data BLOB,
+		heartbeat_at DATETIME, -- last sign of life of a running execution; NULL until its first
+		request_id TEXT NOT NULL DEFAULT '', -- X-Request-ID of the API call that started it
+		revision TEXT NOT NULL DEFAULT '', -- git revision of the workflow when it started
+		usage_nodes INTEGER NOT NULL DEFAULT 0, -- resources used, summed over its runs
+		usage_cpu_time_ms INTEGER NOT NULL DEFAULT 0,
+		usage_bytes_stored INTEGER NOT NULL DEFAULT 0,
+		usage_http_calls INTEGER NOT NULL DEFAULT 0,
+		canary BOOLEAN NOT NULL DEFAULT 0, -- ran the workflow's canary version
+		correlation_id TEXT NOT NULL DEFAULT '', -- business transaction the run belongs to
+		labels TEXT, -- JSON object of the caller's labels
+		FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+	`
+
+// triggersColumns defines triggers; shared with migrateTriggerTypes, which
+// rebuilds the table when the type CHECK changes.
+const triggersColumns = `
+		id TEXT PRIMARY KEY,
+		workflow_id TEXT NOT NULL,
+		type TEXT NOT NULL CHECK(type IN ('cron', 'interval', 'webhook', 'typescript', 'websocket')),
+		config BLOB NOT NULL,
+		enabled BOOLEAN NOT NULL DEFAULT 1,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		file_path TEXT NOT NULL DEFAULT '', -- New: Stores path to TS file for typescript triggers
+		runtime_status TEXT NOT NULL DEFAULT 'stopped',
+		runtime_error TEXT,
+		FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+	`
+
 // initSchema creates necessary tables for execution history tracking
 // Migration from single-state model to full execution history
 func initSchema(db *sql.DB) error {
@@ -132,21 +515,14 @@ func initSchema(db *sql.DB) error {
 		name TEXT NOT NULL,
 		definition BLOB NOT NULL,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		source TEXT NOT NULL DEFAULT '', -- file of a workflow synced from a workflows directory
+		revision TEXT NOT NULL DEFAULT ''
 	);
 
 	-- Execution History: track all workflow runs (not just latest state)
 	-- Each workflow run gets a unique execution_id (UUID)
-	CREATE TABLE IF NOT EXISTS workflow_executions (
-		execution_id TEXT PRIMARY KEY,
-		workflow_id TEXT NOT NULL,
-		status TEXT NOT NULL CHECK(status IN ('running', 'completed', 'failed', 'cancelled')),
-		state BLOB NOT NULL,
-		started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		completed_at DATETIME,
-		error TEXT,
-		FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
-	);
+	CREATE TABLE IF NOT EXISTS workflow_executions (` + workflowExecutionsColumns + `);
 
 	-- Index for querying execution history by workflow (most recent first)
 	CREATE INDEX IF NOT EXISTS idx_executions_workflow 
@@ -156,6 +532,10 @@ func initSchema(db *sql.DB) error {
 	CREATE INDEX IF NOT EXISTS idx_executions_status
 		ON workflow_executions(status, started_at DESC);
 
+	-- Index for rolling up the executions that finished on a day (workflow_daily_stats)
+	CREATE INDEX IF NOT EXISTS idx_executions_completed
+		ON workflow_executions(completed_at);
+
 	-- Node Results: now tied to execution_id instead of workflow_id
 	-- This allows tracking node outputs for each specific execution
 	CREATE TABLE IF NOT EXISTS node_results (
@@ -167,19 +547,57 @@ func initSchema(db *sql.DB) error {
 		FOREIGN KEY (execution_id) REFERENCES workflow_executions(execution_id) ON DELETE CASCADE
 	);
 
-	-- Triggers: store trigger configurations
-	CREATE TABLE IF NOT EXISTS triggers (
-		id TEXT PRIMARY KEY,
-		workflow_id TEXT NOT NULL,
-		type TEXT NOT NULL CHECK(type IN ('cron', 'interval', 'webhook', 'typescript')),
-		config BLOB NOT NULL,
-		enabled BOOLEAN NOT NULL DEFAULT 1,
+	-- Node Inputs: the resolved input each node was called with, secrets redacted
+	CREATE TABLE IF NOT EXISTS node_inputs (
+		execution_id TEXT NOT NULL,
+		node_id TEXT NOT NULL,
+		input BLOB NOT NULL,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (execution_id, node_id),
+		FOREIGN KEY (execution_id) REFERENCES workflow_executions(execution_id) ON DELETE CASCADE
+	);
+
+	-- Node Executions: status and timing of each node in an execution
+	CREATE TABLE IF NOT EXISTS node_executions (
+		execution_id TEXT NOT NULL,
+		node_id TEXT NOT NULL,
+		status TEXT NOT NULL,
+		port TEXT NOT NULL DEFAULT '',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		started_at DATETIME,
+		finished_at DATETIME,
+		error TEXT,
+		PRIMARY KEY (execution_id, node_id),
+		FOREIGN KEY (execution_id) REFERENCES workflow_executions(execution_id) ON DELETE CASCADE
+	);
+
+	-- Workflow Static Data: JSON object persisted across executions ($workflowStatic)
+	CREATE TABLE IF NOT EXISTS workflow_static_data (
+		workflow_id TEXT PRIMARY KEY,
+		data BLOB NOT NULL,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		file_path TEXT NOT NULL DEFAULT '', -- New: Stores path to TS file for typescript triggers
 		FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
 	);
 
+	-- Environments: named sets of global variable overrides
+	CREATE TABLE IF NOT EXISTS environments (
+		name TEXT PRIMARY KEY,
+		description TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Global Variables: $globals.*; environment '' holds the shared defaults
+	CREATE TABLE IF NOT EXISTS global_variables (
+		environment TEXT NOT NULL DEFAULT '',
+		name TEXT NOT NULL,
+		value BLOB NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (environment, name)
+	);
+
+	-- Triggers: store trigger configurations
+	CREATE TABLE IF NOT EXISTS triggers (` + triggersColumns + `);
+
 	-- Index for querying triggers by workflow
 	CREATE INDEX IF NOT EXISTS idx_triggers_workflow
 		ON triggers(workflow_id);
@@ -204,21 +622,302 @@ func initSchema(db *sql.DB) error {
 	-- Index for querying trigger execution history
 	CREATE INDEX IF NOT EXISTS idx_trigger_executions_trigger
 		ON trigger_executions(trigger_id, fired_at DESC);
+
+	-- Index for counting trigger executions by outcome
+	CREATE INDEX IF NOT EXISTS idx_trigger_executions_status
+		ON trigger_executions(status);
+
+	-- Rate Limits: per-key execution counters for std/rate_limit, one fixed window per key
+	CREATE TABLE IF NOT EXISTS rate_limits (
+		key TEXT PRIMARY KEY,
+		window_start INTEGER NOT NULL, -- Unix milliseconds
+		count INTEGER NOT NULL
+	);
+
+	-- Dedupe Keys: seen-sets of std/dedupe nodes; expires_at NULL keeps a key forever
+	CREATE TABLE IF NOT EXISTS dedupe_keys (
+		scope TEXT NOT NULL,
+		key TEXT NOT NULL,
+		seen_at INTEGER NOT NULL, -- Unix milliseconds
+		expires_at INTEGER, -- Unix milliseconds
+		PRIMARY KEY (scope, key)
+	);
+
+	-- Index for pruning expired dedupe keys
+	CREATE INDEX IF NOT EXISTS idx_dedupe_keys_expires
+		ON dedupe_keys(scope, expires_at);
+
+	-- Trigger Idempotency Keys: deliveries a trigger was fired with, so retries map to the first execution
+	CREATE TABLE IF NOT EXISTS trigger_idempotency_keys (
+		trigger_id TEXT NOT NULL,
+		key TEXT NOT NULL,
+		execution_id TEXT, -- NULL until the execution of the first delivery is created
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (trigger_id, key),
+		FOREIGN KEY (trigger_id) REFERENCES triggers(id) ON DELETE CASCADE,
+		FOREIGN KEY (execution_id) REFERENCES workflow_executions(execution_id) ON DELETE SET NULL
+	);
+
+	-- Trigger Run Counts: daily counters of runs left out of execution history
+	CREATE TABLE IF NOT EXISTS trigger_run_counts (
+		trigger_id TEXT NOT NULL,
+		day TEXT NOT NULL, -- YYYY-MM-DD, UTC
+		sampled_out INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (trigger_id, day),
+		FOREIGN KEY (trigger_id) REFERENCES triggers(id) ON DELETE CASCADE
+	);
+
+	-- Workflow Daily Stats: per-workflow aggregates of the executions finished on
+	-- a day, rebuilt by RollupWorkflowStats so stats don't scan execution history
+	CREATE TABLE IF NOT EXISTS workflow_daily_stats (
+		workflow_id TEXT NOT NULL,
+		day TEXT NOT NULL, -- YYYY-MM-DD, UTC
+		runs INTEGER NOT NULL DEFAULT 0,
+		failures INTEGER NOT NULL DEFAULT 0,
+		duration_ms INTEGER NOT NULL DEFAULT 0,
+		sampled_out INTEGER NOT NULL DEFAULT 0,
+		nodes INTEGER NOT NULL DEFAULT 0,
+		cpu_time_ms INTEGER NOT NULL DEFAULT 0,
+		bytes_stored INTEGER NOT NULL DEFAULT 0,
+		http_calls INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (day, workflow_id)
+	);
+
+	-- Workflow Canaries: new versions run for a share of trigger fires
+	CREATE TABLE IF NOT EXISTS workflow_canaries (
+		workflow_id TEXT PRIMARY KEY,
+		definition BLOB NOT NULL,
+		percent INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+	);
+
+	-- Workflow Snapshots: blessed runs snapshot tests compare against
+	CREATE TABLE IF NOT EXISTS workflow_snapshots (
+		workflow_id TEXT PRIMARY KEY,
+		execution_id TEXT NOT NULL,
+		data BLOB NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+	);
+
+	-- Workflow Quotas: limits set by admins, replacing the server's defaults
+	CREATE TABLE IF NOT EXISTS workflow_quotas (
+		workflow_id TEXT PRIMARY KEY,
+		executions_per_day INTEGER NOT NULL DEFAULT 0,
+		storage_bytes INTEGER NOT NULL DEFAULT 0,
+		override_until DATETIME, -- limits lifted until then
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+	);
+
+	-- Queued Trigger Fires: fires held while a server was in maintenance
+	CREATE TABLE IF NOT EXISTS queued_trigger_fires (
+		id TEXT PRIMARY KEY,
+		trigger_id TEXT NOT NULL,
+		payload BLOB,
+		backfill BOOLEAN NOT NULL DEFAULT 0,
+		queued_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (trigger_id) REFERENCES triggers(id) ON DELETE CASCADE
+	);
+
+	-- Purges: audit trail of the data deleted for data subjects, which outlives it
+	CREATE TABLE IF NOT EXISTS purges (
+		id TEXT PRIMARY KEY,
+		subject_hash TEXT NOT NULL,
+		matched_by TEXT NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		executions INTEGER NOT NULL DEFAULT 0,
+		trigger_executions INTEGER NOT NULL DEFAULT 0,
+		queued_fires INTEGER NOT NULL DEFAULT 0,
+		snapshots INTEGER NOT NULL DEFAULT 0,
+		skipped_running INTEGER NOT NULL DEFAULT 0,
+		purged_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
 	`
 
+	if err := migrateExecutionStatuses(db); err != nil {
+		return err
+	}
+	if err := migrateTriggerTypes(db); err != nil {
+		return err
+	}
+
 	_, err := db.Exec(schema)
 	if err != nil {
 		return fmt.Errorf("failed to execute schema: %w", err)
 	}
 
-	// Idempotent migration to add 'file_path' to triggers table if it doesn't exist.
-	rows, err := db.Query("PRAGMA table_info(triggers)")
+	// Idempotent migrations for columns added after the initial schema
+	migrations := []struct{ table, column, definition string }{
+		{"triggers", "file_path", "TEXT NOT NULL DEFAULT ''"},
+		{"triggers", "runtime_status", "TEXT NOT NULL DEFAULT 'stopped'"},
+		{"triggers", "runtime_error", "TEXT"},
+		{"trigger_executions", "backfill", "BOOLEAN NOT NULL DEFAULT 0"},
+		{"trigger_executions", "workflow_id", "TEXT NOT NULL DEFAULT ''"},
+		{"workflow_executions", "signal", "TEXT"},
+		{"workflow_executions", "test", "BOOLEAN NOT NULL DEFAULT 0"},
+		{"workflow_executions", "trigger_data", "BLOB"},
+		{"workflow_executions", "heartbeat_at", "DATETIME"},
+		{"workflow_executions", "request_id", "TEXT NOT NULL DEFAULT ''"},
+		{"workflow_executions", "revision", "TEXT NOT NULL DEFAULT ''"},
+		{"workflow_executions", "usage_nodes", "INTEGER NOT NULL DEFAULT 0"},
+		{"workflow_executions", "usage_cpu_time_ms", "INTEGER NOT NULL DEFAULT 0"},
+		{"workflow_executions", "usage_bytes_stored", "INTEGER NOT NULL DEFAULT 0"},
+		{"workflow_executions", "usage_http_calls", "INTEGER NOT NULL DEFAULT 0"},
+		{"workflow_executions", "canary", "BOOLEAN NOT NULL DEFAULT 0"},
+		{"workflow_executions", "correlation_id", "TEXT NOT NULL DEFAULT ''"},
+		{"workflow_executions", "labels", "TEXT"},
+		{"workflows", "source", "TEXT NOT NULL DEFAULT ''"},
+		{"workflows", "revision", "TEXT NOT NULL DEFAULT ''"},
+		{"workflow_daily_stats", "nodes", "INTEGER NOT NULL DEFAULT 0"},
+		{"workflow_daily_stats", "cpu_time_ms", "INTEGER NOT NULL DEFAULT 0"},
+		{"workflow_daily_stats", "bytes_stored", "INTEGER NOT NULL DEFAULT 0"},
+		{"workflow_daily_stats", "http_calls", "INTEGER NOT NULL DEFAULT 0"},
+	}
+	for _, m := range migrations {
+		if err := addColumnIfMissing(db, m.table, m.column, m.definition); err != nil {
+			return err
+		}
+	}
+
+	// Indexes on migrated columns, created once they exist
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_executions_correlation ON workflow_executions(correlation_id) WHERE correlation_id != ''`); err != nil {
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+
+	// Full-text indexes of what SearchExecutions searches
+	for _, idx := range searchIndexes {
+		if err := createSearchIndex(db, idx.fts, idx.table, idx.column); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// searchIndexes are the FTS5 tables indexing the columns SearchExecutions
+// searches. They are external-content tables, reading the text from the
+// indexed table by rowid, so they don't store a second copy of it.
+var searchIndexes = []struct{ fts, table, column string }{
+	{"node_results_fts", "node_results", "result"},
+	{"node_errors_fts", "node_executions", "error"},
+	{"execution_errors_fts", "workflow_executions", "error"},
+}
+
+// createSearchIndex creates the FTS5 table fts indexing column of table, with
+// the triggers keeping it in sync. A new index is filled from the rows table
+// already has.
+func createSearchIndex(db *sql.DB, fts, table, column string) error {
+	var exists bool
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)`, fts).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to inspect %s: %w", fts, err)
+	}
+	statements := []string{
+		fmt.Sprintf(`CREATE VIRTUAL TABLE IF NOT EXISTS %[1]s USING fts5(%[3]s, content='%[2]s', content_rowid='rowid')`, fts, table, column),
+		fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_insert AFTER INSERT ON %[2]s WHEN new.%[3]s IS NOT NULL BEGIN
+			INSERT INTO %[1]s (rowid, %[3]s) VALUES (new.rowid, new.%[3]s);
+		END`, fts, table, column),
+		fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_delete AFTER DELETE ON %[2]s WHEN old.%[3]s IS NOT NULL BEGIN
+			INSERT INTO %[1]s (%[1]s, rowid, %[3]s) VALUES ('delete', old.rowid, old.%[3]s);
+		END`, fts, table, column),
+		fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_update AFTER UPDATE OF %[3]s ON %[2]s WHEN old.%[3]s IS NOT new.%[3]s BEGIN
+			INSERT INTO %[1]s (%[1]s, rowid, %[3]s) SELECT 'delete', old.rowid, old.%[3]s WHERE old.%[3]s IS NOT NULL;
+			INSERT INTO %[1]s (rowid, %[3]s) SELECT new.rowid, new.%[3]s WHERE new.%[3]s IS NOT NULL;
+		END`, fts, table, column),
+	}
+	if !exists {
+		statements = append(statements, fmt.Sprintf(`INSERT INTO %[1]s (%[1]s) VALUES ('rebuild')`, fts))
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to create search index %s: %w", fts, err)
+		}
+	}
+	return nil
+}
+
+// migrateExecutionStatuses rebuilds workflow_executions from databases created
+// before the 'waiting' status existed: SQLite cannot alter a CHECK constraint in
+// place. Indexes dropped with the old table are recreated by the schema afterwards.
+func migrateExecutionStatuses(db *sql.DB) error {
+	var ddl string
+	err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'workflow_executions'`).Scan(&ddl)
+	if err == sql.ErrNoRows || (err == nil && strings.Contains(ddl, "'waiting'")) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to inspect workflow_executions: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin migration: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Create, copy, drop, then rename the new table, so foreign keys in other
+	// tables keep referring to workflow_executions
+	statements := []string{
+		`CREATE TABLE workflow_executions_new (` + workflowExecutionsColumns + `)`,
+		`INSERT INTO workflow_executions_new (execution_id, workflow_id, status, state, started_at, completed_at, error)
+			SELECT execution_id, workflow_id, status, state, started_at, completed_at, error FROM workflow_executions`,
+		`DROP TABLE workflow_executions`,
+		`ALTER TABLE workflow_executions_new RENAME TO workflow_executions`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to migrate workflow_executions: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// migrateTriggerTypes rebuilds triggers from databases created before the
+// 'websocket' type existed, like migrateExecutionStatuses does for executions.
+func migrateTriggerTypes(db *sql.DB) error {
+	var ddl string
+	err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'triggers'`).Scan(&ddl)
+	if err == sql.ErrNoRows || (err == nil && strings.Contains(ddl, "'websocket'")) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to inspect triggers: %w", err)
+	}
+	// Databases old enough may also predate file_path, which the copy below needs
+	if err := addColumnIfMissing(db, "triggers", "file_path", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin migration: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		`CREATE TABLE triggers_new (` + triggersColumns + `)`,
+		`INSERT INTO triggers_new (id, workflow_id, type, config, enabled, created_at, updated_at, file_path)
+			SELECT id, workflow_id, type, config, enabled, created_at, updated_at, file_path FROM triggers`,
+		`DROP TABLE triggers`,
+		`ALTER TABLE triggers_new RENAME TO triggers`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to migrate triggers: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// addColumnIfMissing adds column to table unless it already exists.
+func addColumnIfMissing(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query("PRAGMA table_info(" + table + ")")
 	if err != nil {
-		return fmt.Errorf("failed to get triggers table info: %w", err)
+		return fmt.Errorf("failed to get %s table info: %w", table, err)
 	}
 	defer rows.Close()
 
-	var columnExists bool
 	for rows.Next() {
 		var (
 			cid        int
@@ -231,19 +930,17 @@ func initSchema(db *sql.DB) error {
 		if err := rows.Scan(&cid, &name, &ctype, &notnull, &dflt_value, &pk); err != nil {
 			return fmt.Errorf("failed to scan table info row: %w", err)
 		}
-		if name == "file_path" {
-			columnExists = true
-			break
+		if name == column {
+			return nil
 		}
 	}
-
-	if !columnExists {
-		_, err = db.Exec("ALTER TABLE triggers ADD COLUMN file_path TEXT NOT NULL DEFAULT ''")
-		if err != nil {
-			return fmt.Errorf("failed to add file_path column to triggers table: %w", err)
-		}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read %s table info: %w", table, err)
 	}
 
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add %s column to %s table: %w", column, table, err)
+	}
 	return nil
 }
 
@@ -256,10 +953,10 @@ func isDuplicateColumnError(err error) bool {
 
 func (s *SQLiteStorage) CreateWorkflow(ctx context.Context, w *Workflow) error {
 	query := `
-		INSERT INTO workflows (id, name, definition, created_at, updated_at)
-		VALUES (?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		INSERT INTO workflows (id, name, definition, source, revision, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 	`
-	_, err := s.db.ExecContext(ctx, query, w.ID, w.Name, w.Definition)
+	_, err := s.q.ExecContext(ctx, query, w.ID, w.Name, w.Definition, w.Source, w.Revision)
 	if err != nil {
 		return fmt.Errorf("failed to create workflow: %w", err)
 	}
@@ -267,9 +964,9 @@ func (s *SQLiteStorage) CreateWorkflow(ctx context.Context, w *Workflow) error {
 }
 
 func (s *SQLiteStorage) GetWorkflow(ctx context.Context, id string) (*Workflow, error) {
-	query := `SELECT id, name, definition, created_at, updated_at FROM workflows WHERE id = ?`
+	query := `SELECT id, name, definition, created_at, updated_at, source, revision FROM workflows WHERE id = ?`
 	var w Workflow
-	err := s.db.QueryRowContext(ctx, query, id).Scan(&w.ID, &w.Name, &w.Definition, &w.CreatedAt, &w.UpdatedAt)
+	err := s.q.QueryRowContext(ctx, query, id).Scan(&w.ID, &w.Name, &w.Definition, &w.CreatedAt, &w.UpdatedAt, &w.Source, &w.Revision)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("workflow not found")
@@ -282,10 +979,10 @@ func (s *SQLiteStorage) GetWorkflow(ctx context.Context, id string) (*Workflow,
 func (s *SQLiteStorage) UpdateWorkflow(ctx context.Context, w *Workflow) error {
 	query := `
 		UPDATE workflows 
-		SET name = ?, definition = ?, updated_at = CURRENT_TIMESTAMP 
+		SET name = ?, definition = ?, source = ?, revision = ?, updated_at = CURRENT_TIMESTAMP 
 		WHERE id = ?
 	`
-	res, err := s.db.ExecContext(ctx, query, w.Name, w.Definition, w.ID)
+	res, err := s.q.ExecContext(ctx, query, w.Name, w.Definition, w.Source, w.Revision, w.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update workflow: %w", err)
 	}
@@ -301,7 +998,7 @@ func (s *SQLiteStorage) UpdateWorkflow(ctx context.Context, w *Workflow) error {
 
 func (s *SQLiteStorage) DeleteWorkflow(ctx context.Context, id string) error {
 	query := `DELETE FROM workflows WHERE id = ?`
-	_, err := s.db.ExecContext(ctx, query, id)
+	_, err := s.q.ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete workflow: %w", err)
 	}
@@ -309,8 +1006,8 @@ func (s *SQLiteStorage) DeleteWorkflow(ctx context.Context, id string) error {
 }
 
 func (s *SQLiteStorage) ListWorkflows(ctx context.Context) ([]*Workflow, error) {
-	query := `SELECT id, name, definition, created_at, updated_at FROM workflows ORDER BY updated_at DESC`
-	rows, err := s.db.QueryContext(ctx, query)
+	query := `SELECT id, name, definition, created_at, updated_at, source, revision FROM workflows ORDER BY updated_at DESC`
+	rows, err := s.q.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list workflows: %w", err)
 	}
@@ -319,7 +1016,7 @@ func (s *SQLiteStorage) ListWorkflows(ctx context.Context) ([]*Workflow, error)
 	var workflows []*Workflow
 	for rows.Next() {
 		var w Workflow
-		if err := rows.Scan(&w.ID, &w.Name, &w.Definition, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		if err := rows.Scan(&w.ID, &w.Name, &w.Definition, &w.CreatedAt, &w.UpdatedAt, &w.Source, &w.Revision); err != nil {
 			return nil, err
 		}
 		workflows = append(workflows, &w)
@@ -330,23 +1027,91 @@ func (s *SQLiteStorage) ListWorkflows(ctx context.Context) ([]*Workflow, error)
 // --- Execution Management ---
 
 // CreateExecution creates a new workflow execution instance
-// Returns a unique execution_id (UUID) for tracking this specific run
+// Returns a unique execution_id (UUID) for tracking this specific run; the
+// execution records the workflow's current revision
 func (s *SQLiteStorage) CreateExecution(ctx context.Context, workflowID string) (string, error) {
-	// Generate UUID for execution (using timestamp-based approach for simplicity)
-	// In production, consider using github.com/google/uuid
-	executionID := fmt.Sprintf("%s-%d", workflowID, time.Now().UnixNano())
+	executionID := NewID()
 
 	query := `
-		INSERT INTO workflow_executions (execution_id, workflow_id, status, state, started_at)
-		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		INSERT INTO workflow_executions (execution_id, workflow_id, status, state, started_at, revision)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, COALESCE((SELECT revision FROM workflows WHERE id = ?), ''))
 	`
-	_, err := s.db.ExecContext(ctx, query, executionID, workflowID, ExecutionStatusRunning, []byte("{}"))
+	_, err := s.q.ExecContext(ctx, query, executionID, workflowID, ExecutionStatusRunning, []byte("{}"), workflowID)
 	if err != nil {
 		return "", fmt.Errorf("failed to create execution: %w", err)
 	}
 	return executionID, nil
 }
 
+// MarkTestExecution flags an execution as a test-mode run.
+func (s *SQLiteStorage) MarkTestExecution(ctx context.Context, executionID string) error {
+	_, err := s.q.ExecContext(ctx, `UPDATE workflow_executions SET test = 1 WHERE execution_id = ?`, executionID)
+	if err != nil {
+		return fmt.Errorf("failed to mark test execution: %w", err)
+	}
+	return nil
+}
+
+// MarkCanaryExecution flags an execution as a run of the workflow's canary version.
+func (s *SQLiteStorage) MarkCanaryExecution(ctx context.Context, executionID string) error {
+	_, err := s.q.ExecContext(ctx, `UPDATE workflow_executions SET canary = 1 WHERE execution_id = ?`, executionID)
+	if err != nil {
+		return fmt.Errorf("failed to mark canary execution: %w", err)
+	}
+	return nil
+}
+
+// SetExecutionLabels records the correlation ID and labels an execution was started with.
+func (s *SQLiteStorage) SetExecutionLabels(ctx context.Context, executionID, correlationID string, labels map[string]string) error {
+	var raw any
+	if len(labels) > 0 {
+		b, err := json.Marshal(labels)
+		if err != nil {
+			return fmt.Errorf("failed to encode execution labels: %w", err)
+		}
+		raw = string(b)
+	}
+	_, err := s.q.ExecContext(ctx, `UPDATE workflow_executions SET correlation_id = ?, labels = ? WHERE execution_id = ?`, correlationID, raw, executionID)
+	if err != nil {
+		return fmt.Errorf("failed to set execution labels: %w", err)
+	}
+	return nil
+}
+
+// SetExecutionRequestID records the ID of the API request that started an execution.
+func (s *SQLiteStorage) SetExecutionRequestID(ctx context.Context, executionID, requestID string) error {
+	_, err := s.q.ExecContext(ctx, `UPDATE workflow_executions SET request_id = ? WHERE execution_id = ?`, requestID, executionID)
+	if err != nil {
+		return fmt.Errorf("failed to set execution request ID: %w", err)
+	}
+	return nil
+}
+
+// AddExecutionUsage adds usage to the usage recorded for an execution; each
+// run of an execution resumed after a wait adds its own.
+func (s *SQLiteStorage) AddExecutionUsage(ctx context.Context, executionID string, usage ExecutionUsage) error {
+	query := `
+		UPDATE workflow_executions
+		SET usage_nodes = usage_nodes + ?, usage_cpu_time_ms = usage_cpu_time_ms + ?,
+			usage_bytes_stored = usage_bytes_stored + ?, usage_http_calls = usage_http_calls + ?
+		WHERE execution_id = ?
+	`
+	_, err := s.q.ExecContext(ctx, query, usage.Nodes, usage.CPUTimeMS, usage.BytesStored, usage.HTTPCalls, executionID)
+	if err != nil {
+		return fmt.Errorf("failed to add execution usage: %w", err)
+	}
+	return nil
+}
+
+// SaveExecutionTriggerData stores the trigger payload an execution was started with.
+func (s *SQLiteStorage) SaveExecutionTriggerData(ctx context.Context, executionID string, data []byte) error {
+	_, err := s.q.ExecContext(ctx, `UPDATE workflow_executions SET trigger_data = ? WHERE execution_id = ?`, data, executionID)
+	if err != nil {
+		return fmt.Errorf("failed to save execution trigger data: %w", err)
+	}
+	return nil
+}
+
 // UpdateExecutionStatus updates the status and state of an execution
 // Used to mark execution as completed or failed, and store final state
 func (s *SQLiteStorage) UpdateExecutionStatus(ctx context.Context, executionID string, status ExecutionStatus, state []byte, errorMsg *string) error {
@@ -355,26 +1120,184 @@ func (s *SQLiteStorage) UpdateExecutionStatus(ctx context.Context, executionID s
 		SET status = ?, state = ?, completed_at = CURRENT_TIMESTAMP, error = ?
 		WHERE execution_id = ?
 	`
-	_, err := s.db.ExecContext(ctx, query, status, state, errorMsg, executionID)
+	_, err := s.q.ExecContext(ctx, query, status, state, errorMsg, executionID)
 	if err != nil {
 		return fmt.Errorf("failed to update execution status: %w", err)
 	}
 	return nil
 }
 
-// GetExecution retrieves a specific execution by ID
+// TransitionExecutionStatus is UpdateExecutionStatus applied only while the
+// execution still has status from, in one statement, so that concurrent writers
+// can't overwrite each other's terminal status. A nil state keeps the stored
+// one. Returns false when the execution doesn't exist or has another status.
+func (s *SQLiteStorage) TransitionExecutionStatus(ctx context.Context, executionID string, from, to ExecutionStatus, state []byte, errorMsg *string) (bool, error) {
+	query := `
+		UPDATE workflow_executions
+		SET status = ?, state = COALESCE(?, state), completed_at = CURRENT_TIMESTAMP, error = ?
+		WHERE execution_id = ? AND status = ?
+	`
+	res, err := s.q.ExecContext(ctx, query, to, state, errorMsg, executionID, from)
+	if err != nil {
+		return false, fmt.Errorf("failed to update execution status: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update execution status: %w", err)
+	}
+	return n > 0, nil
+}
+
+// SaveExecutionState checkpoints the state of a running execution, leaving its
+// status alone, and counts as a heartbeat. Returns false when the execution
+// doesn't exist or isn't running.
+func (s *SQLiteStorage) SaveExecutionState(ctx context.Context, executionID string, state []byte) (bool, error) {
+	query := `UPDATE workflow_executions SET state = ?, heartbeat_at = ? WHERE execution_id = ? AND status = ?`
+	res, err := s.q.ExecContext(ctx, query, state, time.Now().UTC(), executionID, ExecutionStatusRunning)
+	if err != nil {
+		return false, fmt.Errorf("failed to save execution state: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to save execution state: %w", err)
+	}
+	return n > 0, nil
+}
+
+// HeartbeatExecution records that a running execution is still alive, so that
+// it isn't taken for lost (see ListStaleExecutions). Returns false when the
+// execution doesn't exist or isn't running.
+func (s *SQLiteStorage) HeartbeatExecution(ctx context.Context, executionID string) (bool, error) {
+	query := `UPDATE workflow_executions SET heartbeat_at = ? WHERE execution_id = ? AND status = ?`
+	res, err := s.q.ExecContext(ctx, query, time.Now().UTC(), executionID, ExecutionStatusRunning)
+	if err != nil {
+		return false, fmt.Errorf("failed to record execution heartbeat: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record execution heartbeat: %w", err)
+	}
+	return n > 0, nil
+}
+
+// SuspendExecution parks an execution as waiting until wakeAt, storing the
+// state needed to resume it. The worker running it can then be released.
+func (s *SQLiteStorage) SuspendExecution(ctx context.Context, executionID string, state []byte, wakeAt time.Time) error {
+	query := `
+		UPDATE workflow_executions
+		SET status = ?, state = ?, wake_at = ?, completed_at = NULL, error = NULL
+		WHERE execution_id = ?
+	`
+	if _, err := s.q.ExecContext(ctx, query, ExecutionStatusWaiting, state, wakeAt.UTC(), executionID); err != nil {
+		return fmt.Errorf("failed to suspend execution: %w", err)
+	}
+	return nil
+}
+
+// ClaimDueExecutions marks up to limit waiting executions whose wake-up time has
+// passed as running, with a heartbeat at now, and returns them. The update is a
+// single statement, so an execution is claimed at most once even with several
+// schedulers polling.
+func (s *SQLiteStorage) ClaimDueExecutions(ctx context.Context, now time.Time, limit int) ([]*Execution, error) {
+	query := `
+		UPDATE workflow_executions
+		SET status = ?, wake_at = NULL, signal = NULL, heartbeat_at = ?
+		WHERE execution_id IN (
+			SELECT execution_id FROM workflow_executions
+			WHERE status = ? AND wake_at <= ?
+			ORDER BY wake_at
+			LIMIT ?
+		)
+		RETURNING execution_id, workflow_id, status, state, started_at
+	`
+	rows, err := s.q.QueryContext(ctx, query, ExecutionStatusRunning, now.UTC(), ExecutionStatusWaiting, now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due executions: %w", err)
+	}
+	return scanClaimedExecutions(rows)
+}
+
+// SuspendExecutionForSignal parks an execution as waiting until signal is sent
+// with ClaimSignaledExecutions or, if wakeAt is set, until wakeAt, whichever
+// comes first.
+func (s *SQLiteStorage) SuspendExecutionForSignal(ctx context.Context, executionID string, state []byte, signal string, wakeAt *time.Time) error {
+	var wake interface{}
+	if wakeAt != nil {
+		wake = wakeAt.UTC()
+	}
+	query := `
+		UPDATE workflow_executions
+		SET status = ?, state = ?, wake_at = ?, signal = ?, completed_at = NULL, error = NULL
+		WHERE execution_id = ?
+	`
+	if _, err := s.q.ExecContext(ctx, query, ExecutionStatusWaiting, state, wake, signal, executionID); err != nil {
+		return fmt.Errorf("failed to suspend execution: %w", err)
+	}
+	return nil
+}
+
+// ClaimSignaledExecutions marks every execution waiting for signal as running and
+// returns them. Like ClaimDueExecutions it is a single statement, so an execution
+// waiting for both a signal and a wake-up time is claimed by only one of them.
+func (s *SQLiteStorage) ClaimSignaledExecutions(ctx context.Context, signal string) ([]*Execution, error) {
+	query := `
+		UPDATE workflow_executions
+		SET status = ?, wake_at = NULL, signal = NULL, heartbeat_at = ?
+		WHERE status = ? AND signal = ?
+		RETURNING execution_id, workflow_id, status, state, started_at
+	`
+	rows, err := s.q.QueryContext(ctx, query, ExecutionStatusRunning, time.Now().UTC(), ExecutionStatusWaiting, signal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim signaled executions: %w", err)
+	}
+	return scanClaimedExecutions(rows)
+}
+
+// ListStaleExecutions returns up to limit running executions without a sign of
+// life since before: their last heartbeat, or their start when they have none,
+// is older. Oldest first.
+func (s *SQLiteStorage) ListStaleExecutions(ctx context.Context, before time.Time, limit int) ([]*Execution, error) {
+	query := `
+		SELECT execution_id, workflow_id, status, state, started_at
+		FROM workflow_executions
+		WHERE status = ? AND COALESCE(heartbeat_at, started_at) < ?
+		ORDER BY COALESCE(heartbeat_at, started_at)
+		LIMIT ?
+	`
+	rows, err := s.q.QueryContext(ctx, query, ExecutionStatusRunning, before.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stale executions: %w", err)
+	}
+	return scanClaimedExecutions(rows)
+}
+
+func scanClaimedExecutions(rows *sql.Rows) ([]*Execution, error) {
+	defer rows.Close()
+	var executions []*Execution
+	for rows.Next() {
+		var exec Execution
+		if err := rows.Scan(&exec.ID, &exec.WorkflowID, &exec.Status, &exec.State, &exec.StartedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan execution: %w", err)
+		}
+		executions = append(executions, &exec)
+	}
+	return executions, rows.Err()
+}
+
+// GetExecution retrieves a specific execution by ID
 func (s *SQLiteStorage) GetExecution(ctx context.Context, executionID string) (*Execution, error) {
 	query := `
-		SELECT execution_id, workflow_id, status, state, started_at, completed_at, error
+		SELECT execution_id, workflow_id, status, state, started_at, completed_at, error, test, trigger_data, heartbeat_at, request_id, revision,
+			usage_nodes, usage_cpu_time_ms, usage_bytes_stored, usage_http_calls, canary, correlation_id, labels
 		FROM workflow_executions
 		WHERE execution_id = ?
 	`
 
 	var exec Execution
-	var completedAt sql.NullTime
-	var errorMsg sql.NullString
+	var completedAt, heartbeatAt sql.NullTime
+	var errorMsg, labels sql.NullString
 
-	err := s.db.QueryRowContext(ctx, query, executionID).Scan(
+	err := s.q.QueryRowContext(ctx, query, executionID).Scan(
 		&exec.ID,
 		&exec.WorkflowID,
 		&exec.Status,
@@ -382,6 +1305,18 @@ func (s *SQLiteStorage) GetExecution(ctx context.Context, executionID string) (*
 		&exec.StartedAt,
 		&completedAt,
 		&errorMsg,
+		&exec.Test,
+		&exec.TriggerData,
+		&heartbeatAt,
+		&exec.RequestID,
+		&exec.Revision,
+		&exec.Usage.Nodes,
+		&exec.Usage.CPUTimeMS,
+		&exec.Usage.BytesStored,
+		&exec.Usage.HTTPCalls,
+		&exec.Canary,
+		&exec.CorrelationID,
+		&labels,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get execution: %w", err)
@@ -390,35 +1325,133 @@ func (s *SQLiteStorage) GetExecution(ctx context.Context, executionID string) (*
 	if completedAt.Valid {
 		exec.CompletedAt = &completedAt.Time
 	}
+	if heartbeatAt.Valid {
+		exec.HeartbeatAt = &heartbeatAt.Time
+	}
 	if errorMsg.Valid {
 		exec.Error = &errorMsg.String
 	}
+	if labels.Valid {
+		json.Unmarshal([]byte(labels.String), &exec.Labels)
+	}
 
 	return &exec, nil
 }
 
+// executionListColumns are the columns of the executions listed by
+// ListExecutions and FindExecutions (see scanExecutions)
+const executionListColumns = `execution_id, workflow_id, status, state, started_at, completed_at, error, test, heartbeat_at, request_id, revision,
+			usage_nodes, usage_cpu_time_ms, usage_bytes_stored, usage_http_calls, canary, correlation_id, labels`
+
 // ListExecutions retrieves execution history for a workflow
 // Returns most recent executions first, limited by the limit parameter
 func (s *SQLiteStorage) ListExecutions(ctx context.Context, workflowID string, limit int) ([]*Execution, error) {
 	query := `
-		SELECT execution_id, workflow_id, status, state, started_at, completed_at, error
+		SELECT ` + executionListColumns + `
 		FROM workflow_executions
 		WHERE workflow_id = ?
 		ORDER BY started_at DESC
 		LIMIT ?
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, workflowID, limit)
+	rows, err := s.q.QueryContext(ctx, query, workflowID, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list executions: %w", err)
 	}
+	return scanExecutions(rows)
+}
+
+// FindExecutions returns the executions matching filter, most recent first,
+// limited by the limit parameter
+func (s *SQLiteStorage) FindExecutions(ctx context.Context, filter ExecutionFilter, limit int) ([]*Execution, error) {
+	where := []string{"1 = 1"}
+	var args []any
+	if filter.WorkflowID != "" {
+		where = append(where, "workflow_id = ?")
+		args = append(args, filter.WorkflowID)
+	}
+	if filter.CorrelationID != "" {
+		where = append(where, "correlation_id = ?")
+		args = append(args, filter.CorrelationID)
+	}
+	if filter.Test {
+		where = append(where, "test = 1")
+	}
+	for _, key := range slices.Sorted(maps.Keys(filter.Labels)) {
+		where = append(where, "EXISTS (SELECT 1 FROM json_each(labels) WHERE key = ? AND value = ?)")
+		args = append(args, key, filter.Labels[key])
+	}
+	query := `
+		SELECT ` + executionListColumns + `
+		FROM workflow_executions
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY started_at DESC
+		LIMIT ?
+	`
+
+	rows, err := s.q.QueryContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find executions: %w", err)
+	}
+	return scanExecutions(rows)
+}
+
+// SearchExecutions returns the node results and errors containing every word
+// of query, those of the most recent executions first, limited by the limit
+// parameter. Words match whole words of the text, regardless of case.
+func (s *SQLiteStorage) SearchExecutions(ctx context.Context, query string, limit int) ([]*ExecutionSearchHit, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	// Quoted, the terms are matched as words rather than read as FTS5 syntax
+	match := `"` + strings.Join(terms, `" "`) + `"`
+	search := `
+		SELECT h.execution_id, e.workflow_id, e.started_at, h.node_id, h.field, h.snippet
+		FROM (
+			SELECT r.execution_id, r.node_id, 'result' AS field,
+				snippet(node_results_fts, 0, '<mark>', '</mark>', '…', 12) AS snippet
+			FROM node_results_fts JOIN node_results r ON r.rowid = node_results_fts.rowid
+			WHERE node_results_fts MATCH ?
+			UNION ALL
+			SELECT n.execution_id, n.node_id, 'error', snippet(node_errors_fts, 0, '<mark>', '</mark>', '…', 12)
+			FROM node_errors_fts JOIN node_executions n ON n.rowid = node_errors_fts.rowid
+			WHERE node_errors_fts MATCH ?
+			UNION ALL
+			SELECT x.execution_id, '', 'error', snippet(execution_errors_fts, 0, '<mark>', '</mark>', '…', 12)
+			FROM execution_errors_fts JOIN workflow_executions x ON x.rowid = execution_errors_fts.rowid
+			WHERE execution_errors_fts MATCH ?
+		) h
+		JOIN workflow_executions e ON e.execution_id = h.execution_id
+		ORDER BY e.started_at DESC, e.rowid DESC, h.node_id
+		LIMIT ?
+	`
+	rows, err := s.q.QueryContext(ctx, search, match, match, match, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search executions: %w", err)
+	}
+	defer rows.Close()
+
+	var hits []*ExecutionSearchHit
+	for rows.Next() {
+		var h ExecutionSearchHit
+		if err := rows.Scan(&h.ExecutionID, &h.WorkflowID, &h.StartedAt, &h.NodeID, &h.Field, &h.Snippet); err != nil {
+			return nil, fmt.Errorf("failed to scan search hit: %w", err)
+		}
+		hits = append(hits, &h)
+	}
+	return hits, rows.Err()
+}
+
+// scanExecutions reads the executions selected with executionListColumns, and closes rows
+func scanExecutions(rows *sql.Rows) ([]*Execution, error) {
 	defer rows.Close()
 
 	var executions []*Execution
 	for rows.Next() {
 		var exec Execution
-		var completedAt sql.NullTime
-		var errorMsg sql.NullString
+		var completedAt, heartbeatAt sql.NullTime
+		var errorMsg, labels sql.NullString
 
 		err := rows.Scan(
 			&exec.ID,
@@ -428,6 +1461,17 @@ func (s *SQLiteStorage) ListExecutions(ctx context.Context, workflowID string, l
 			&exec.StartedAt,
 			&completedAt,
 			&errorMsg,
+			&exec.Test,
+			&heartbeatAt,
+			&exec.RequestID,
+			&exec.Revision,
+			&exec.Usage.Nodes,
+			&exec.Usage.CPUTimeMS,
+			&exec.Usage.BytesStored,
+			&exec.Usage.HTTPCalls,
+			&exec.Canary,
+			&exec.CorrelationID,
+			&labels,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan execution: %w", err)
@@ -436,9 +1480,15 @@ func (s *SQLiteStorage) ListExecutions(ctx context.Context, workflowID string, l
 		if completedAt.Valid {
 			exec.CompletedAt = &completedAt.Time
 		}
+		if heartbeatAt.Valid {
+			exec.HeartbeatAt = &heartbeatAt.Time
+		}
 		if errorMsg.Valid {
 			exec.Error = &errorMsg.String
 		}
+		if labels.Valid {
+			json.Unmarshal([]byte(labels.String), &exec.Labels)
+		}
 
 		executions = append(executions, &exec)
 	}
@@ -456,7 +1506,7 @@ func (s *SQLiteStorage) SaveNodeResult(ctx context.Context, executionID, nodeID
 			result = excluded.result,
 			created_at = CURRENT_TIMESTAMP
 	`
-	_, err := s.db.ExecContext(ctx, query, executionID, nodeID, result)
+	_, err := s.q.ExecContext(ctx, query, executionID, nodeID, result)
 	if err != nil {
 		return fmt.Errorf("failed to save node result: %w", err)
 	}
@@ -467,13 +1517,766 @@ func (s *SQLiteStorage) SaveNodeResult(ctx context.Context, executionID, nodeID
 func (s *SQLiteStorage) GetNodeResult(ctx context.Context, executionID, nodeID string) ([]byte, error) {
 	var result []byte
 	query := `SELECT result FROM node_results WHERE execution_id = ? AND node_id = ?`
-	err := s.db.QueryRowContext(ctx, query, executionID, nodeID).Scan(&result)
+	err := s.q.QueryRowContext(ctx, query, executionID, nodeID).Scan(&result)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get node result: %w", err)
 	}
 	return result, nil
 }
 
+// ListNodeResults returns all node results of an execution in the order they were saved
+func (s *SQLiteStorage) ListNodeResults(ctx context.Context, executionID string) ([]*NodeResult, error) {
+	query := `
+		SELECT execution_id, node_id, result, created_at
+		FROM node_results
+		WHERE execution_id = ?
+		ORDER BY created_at ASC, rowid ASC
+	`
+	rows, err := s.q.QueryContext(ctx, query, executionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list node results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*NodeResult
+	for rows.Next() {
+		var nr NodeResult
+		if err := rows.Scan(&nr.ExecutionID, &nr.NodeID, &nr.Result, &nr.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan node result: %w", err)
+		}
+		results = append(results, &nr)
+	}
+	return results, rows.Err()
+}
+
+// SaveNodeInput persists the input a node of an execution was called with,
+// replacing the input of an earlier attempt
+func (s *SQLiteStorage) SaveNodeInput(ctx context.Context, executionID, nodeID string, input []byte) error {
+	query := `
+		INSERT INTO node_inputs (execution_id, node_id, input, created_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(execution_id, node_id) DO UPDATE SET
+			input = excluded.input,
+			created_at = CURRENT_TIMESTAMP
+	`
+	if _, err := s.q.ExecContext(ctx, query, executionID, nodeID, input); err != nil {
+		return fmt.Errorf("failed to save node input: %w", err)
+	}
+	return nil
+}
+
+// GetNodeInput retrieves the input of a node of an execution
+func (s *SQLiteStorage) GetNodeInput(ctx context.Context, executionID, nodeID string) ([]byte, error) {
+	var input []byte
+	query := `SELECT input FROM node_inputs WHERE execution_id = ? AND node_id = ?`
+	if err := s.q.QueryRowContext(ctx, query, executionID, nodeID).Scan(&input); err != nil {
+		return nil, fmt.Errorf("failed to get node input: %w", err)
+	}
+	return input, nil
+}
+
+// DeleteExecutionData drops the data an execution produced: the results and
+// inputs of its nodes, and its state, which is left empty. Its summary row and
+// node timeline are kept.
+func (s *SQLiteStorage) DeleteExecutionData(ctx context.Context, executionID string) error {
+	for _, query := range []string{
+		`DELETE FROM node_results WHERE execution_id = ?`,
+		`DELETE FROM node_inputs WHERE execution_id = ?`,
+		`UPDATE workflow_executions SET state = '{}' WHERE execution_id = ?`,
+	} {
+		if _, err := s.q.ExecContext(ctx, query, executionID); err != nil {
+			return fmt.Errorf("failed to delete execution data: %w", err)
+		}
+	}
+	return nil
+}
+
+// DeleteExecution removes an execution together with its node results, inputs
+// and timeline
+func (s *SQLiteStorage) DeleteExecution(ctx context.Context, executionID string) error {
+	for _, query := range []string{
+		`DELETE FROM node_results WHERE execution_id = ?`,
+		`DELETE FROM node_inputs WHERE execution_id = ?`,
+		`DELETE FROM node_executions WHERE execution_id = ?`,
+		`DELETE FROM workflow_executions WHERE execution_id = ?`,
+	} {
+		if _, err := s.q.ExecContext(ctx, query, executionID); err != nil {
+			return fmt.Errorf("failed to delete execution: %w", err)
+		}
+	}
+	return nil
+}
+
+// StartNodeExecution records that a node of an execution started running,
+// counting an attempt
+func (s *SQLiteStorage) StartNodeExecution(ctx context.Context, executionID, nodeID string) error {
+	query := `
+		INSERT INTO node_executions (execution_id, node_id, status, attempts, started_at)
+		VALUES (?, ?, ?, 1, ?)
+		ON CONFLICT(execution_id, node_id) DO UPDATE SET
+			status = excluded.status,
+			port = '',
+			attempts = attempts + 1,
+			started_at = excluded.started_at,
+			finished_at = NULL,
+			error = NULL
+	`
+	if _, err := s.q.ExecContext(ctx, query, executionID, nodeID, NodeStatusRunning, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to start node execution: %w", err)
+	}
+	return nil
+}
+
+// FinishNodeExecution records the outcome of a node of an execution. Nodes
+// that were never started (skipped or cached) get a record without a start time.
+func (s *SQLiteStorage) FinishNodeExecution(ctx context.Context, executionID, nodeID string, status NodeStatus, port string, errorMsg *string) error {
+	query := `
+		INSERT INTO node_executions (execution_id, node_id, status, port, finished_at, error)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(execution_id, node_id) DO UPDATE SET
+			status = excluded.status,
+			port = excluded.port,
+			finished_at = excluded.finished_at,
+			error = excluded.error
+	`
+	if _, err := s.q.ExecContext(ctx, query, executionID, nodeID, status, port, time.Now().UTC(), errorMsg); err != nil {
+		return fmt.Errorf("failed to finish node execution: %w", err)
+	}
+	return nil
+}
+
+// ListNodeExecutions returns the node records of an execution in the order the
+// nodes started, followed by those that never started
+func (s *SQLiteStorage) ListNodeExecutions(ctx context.Context, executionID string) ([]*NodeExecution, error) {
+	query := `
+		SELECT execution_id, node_id, status, port, attempts, started_at, finished_at, error
+		FROM node_executions
+		WHERE execution_id = ?
+		ORDER BY started_at IS NULL, started_at ASC, finished_at ASC, rowid ASC
+	`
+	rows, err := s.q.QueryContext(ctx, query, executionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list node executions: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []*NodeExecution
+	for rows.Next() {
+		var ne NodeExecution
+		var startedAt, finishedAt sql.NullTime
+		var errorMsg sql.NullString
+		if err := rows.Scan(&ne.ExecutionID, &ne.NodeID, &ne.Status, &ne.Port, &ne.Attempts, &startedAt, &finishedAt, &errorMsg); err != nil {
+			return nil, fmt.Errorf("failed to scan node execution: %w", err)
+		}
+		if startedAt.Valid {
+			ne.StartedAt = &startedAt.Time
+		}
+		if finishedAt.Valid {
+			ne.FinishedAt = &finishedAt.Time
+		}
+		if errorMsg.Valid {
+			ne.Error = &errorMsg.String
+		}
+		nodes = append(nodes, &ne)
+	}
+	return nodes, rows.Err()
+}
+
+// --- Workflow Static Data ---
+
+// GetWorkflowStaticData returns the JSON static data of a workflow, or nil if it has none yet
+func (s *SQLiteStorage) GetWorkflowStaticData(ctx context.Context, workflowID string) ([]byte, error) {
+	var data []byte
+	query := `SELECT data FROM workflow_static_data WHERE workflow_id = ?`
+	err := s.q.QueryRowContext(ctx, query, workflowID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow static data: %w", err)
+	}
+	return data, nil
+}
+
+// SaveWorkflowStaticData replaces the JSON static data of a workflow
+func (s *SQLiteStorage) SaveWorkflowStaticData(ctx context.Context, workflowID string, data []byte) error {
+	query := `
+		INSERT INTO workflow_static_data (workflow_id, data, updated_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(workflow_id) DO UPDATE SET
+			data = excluded.data,
+			updated_at = CURRENT_TIMESTAMP
+	`
+	if _, err := s.q.ExecContext(ctx, query, workflowID, data); err != nil {
+		return fmt.Errorf("failed to save workflow static data: %w", err)
+	}
+	return nil
+}
+
+// --- Rate Limits ---
+
+// IncrementRateLimit counts one hit against key in the window starting at
+// windowStart and returns the hits counted in that window so far, this one
+// included. A hit in a new window starts the count over. The read and write are
+// one statement, so concurrent executions never share a count.
+func (s *SQLiteStorage) IncrementRateLimit(ctx context.Context, key string, windowStart time.Time) (int, error) {
+	query := `
+		INSERT INTO rate_limits (key, window_start, count)
+		VALUES (?, ?, 1)
+		ON CONFLICT(key) DO UPDATE SET
+			count = CASE WHEN window_start = excluded.window_start THEN count + 1 ELSE 1 END,
+			window_start = excluded.window_start
+		RETURNING count
+	`
+	var count int
+	if err := s.q.QueryRowContext(ctx, query, key, windowStart.UnixMilli()).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to increment rate limit: %w", err)
+	}
+	return count, nil
+}
+
+// --- Dedupe Keys ---
+
+// MarkSeen adds key to the seen-set of scope until expiresAt (forever if nil)
+// and reports whether it was new: absent, or present but expired by now. The
+// check and the insert are one statement, so of several executions marking the
+// same key only one sees it as new. Expired keys of scope are pruned first.
+func (s *SQLiteStorage) MarkSeen(ctx context.Context, scope, key string, now time.Time, expiresAt *time.Time) (bool, error) {
+	prune := `DELETE FROM dedupe_keys WHERE scope = ? AND expires_at <= ?`
+	if _, err := s.q.ExecContext(ctx, prune, scope, now.UnixMilli()); err != nil {
+		return false, fmt.Errorf("failed to prune dedupe keys: %w", err)
+	}
+
+	var expires interface{}
+	if expiresAt != nil {
+		expires = expiresAt.UnixMilli()
+	}
+	query := `
+		INSERT INTO dedupe_keys (scope, key, seen_at, expires_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(scope, key) DO UPDATE SET
+			seen_at = excluded.seen_at,
+			expires_at = excluded.expires_at
+		WHERE dedupe_keys.expires_at IS NOT NULL AND dedupe_keys.expires_at <= excluded.seen_at
+	`
+	res, err := s.q.ExecContext(ctx, query, scope, key, now.UnixMilli(), expires)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark dedupe key: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark dedupe key: %w", err)
+	}
+	return n > 0, nil
+}
+
+// --- Trigger Idempotency Keys ---
+
+// ClaimIdempotencyKey records that triggerID was fired with key and reports
+// whether this call claimed it. If the key was claimed before, it returns the
+// execution recorded against it, empty while that one is still being created.
+// The check and the insert are one statement, so of several deliveries with
+// the same key only one claims it.
+func (s *SQLiteStorage) ClaimIdempotencyKey(ctx context.Context, triggerID, key string) (string, bool, error) {
+	query := `
+		INSERT INTO trigger_idempotency_keys (trigger_id, key, created_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(trigger_id, key) DO NOTHING
+	`
+	res, err := s.q.ExecContext(ctx, query, triggerID, key)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+	if n > 0 {
+		return "", true, nil
+	}
+
+	var executionID sql.NullString
+	query = `SELECT execution_id FROM trigger_idempotency_keys WHERE trigger_id = ? AND key = ?`
+	if err := s.q.QueryRowContext(ctx, query, triggerID, key).Scan(&executionID); err != nil {
+		return "", false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	return executionID.String, false, nil
+}
+
+// SetIdempotencyKeyExecution records the execution started for a claimed key.
+func (s *SQLiteStorage) SetIdempotencyKeyExecution(ctx context.Context, triggerID, key, executionID string) error {
+	query := `UPDATE trigger_idempotency_keys SET execution_id = ? WHERE trigger_id = ? AND key = ?`
+	res, err := s.q.ExecContext(ctx, query, executionID, triggerID, key)
+	if err != nil {
+		return fmt.Errorf("failed to set idempotency key execution: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("idempotency key %q of trigger %s not claimed", key, triggerID)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey forgets a claimed key, so that a later delivery with
+// it runs again; used when the first one failed before its execution started.
+func (s *SQLiteStorage) ReleaseIdempotencyKey(ctx context.Context, triggerID, key string) error {
+	query := `DELETE FROM trigger_idempotency_keys WHERE trigger_id = ? AND key = ?`
+	if _, err := s.q.ExecContext(ctx, query, triggerID, key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// --- Trigger Run Counts ---
+
+// runCountDay is the day a run at t is counted on.
+func runCountDay(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// currentTimestamp formats t like CURRENT_TIMESTAMP, for comparing it with the
+// columns set to that; time.Time arguments are stored in another format, which
+// doesn't sort with it
+func currentTimestamp(t time.Time) string {
+	return t.UTC().Format(time.DateTime)
+}
+
+// CountSampledOutRun counts a successful run of a trigger at at that its
+// sampling left out of execution history
+func (s *SQLiteStorage) CountSampledOutRun(ctx context.Context, triggerID string, at time.Time) error {
+	query := `
+		INSERT INTO trigger_run_counts (trigger_id, day, sampled_out)
+		VALUES (?, ?, 1)
+		ON CONFLICT(trigger_id, day) DO UPDATE SET sampled_out = sampled_out + 1
+	`
+	if _, err := s.q.ExecContext(ctx, query, triggerID, runCountDay(at)); err != nil {
+		return fmt.Errorf("failed to count sampled out run: %w", err)
+	}
+	return nil
+}
+
+// ListTriggerRunCounts returns the run counts of a trigger from the day of since
+// on, oldest day first; days without counted runs are left out
+func (s *SQLiteStorage) ListTriggerRunCounts(ctx context.Context, triggerID string, since time.Time) ([]*TriggerRunCount, error) {
+	query := `
+		SELECT trigger_id, day, sampled_out
+		FROM trigger_run_counts
+		WHERE trigger_id = ? AND day >= ?
+		ORDER BY day ASC
+	`
+	rows, err := s.q.QueryContext(ctx, query, triggerID, runCountDay(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trigger run counts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []*TriggerRunCount
+	for rows.Next() {
+		var c TriggerRunCount
+		if err := rows.Scan(&c.TriggerID, &c.Day, &c.SampledOut); err != nil {
+			return nil, fmt.Errorf("failed to scan trigger run count: %w", err)
+		}
+		counts = append(counts, &c)
+	}
+	return counts, rows.Err()
+}
+
+// --- Workflow Stats ---
+
+// RollupWorkflowStats rebuilds the stats of the day of day (in UTC) from the
+// executions that finished on it and the sampled out runs counted on it
+func (s *SQLiteStorage) RollupWorkflowStats(ctx context.Context, day time.Time) error {
+	from := runCountDay(day)
+	to := runCountDay(day.UTC().AddDate(0, 0, 1))
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{`DELETE FROM workflow_daily_stats WHERE day = ?`, []any{from}},
+		{`
+			INSERT INTO workflow_daily_stats (workflow_id, day, runs, failures, duration_ms,
+				nodes, cpu_time_ms, bytes_stored, http_calls)
+			SELECT workflow_id, ?, COUNT(*), SUM(status = 'failed'),
+				CAST(COALESCE(SUM((julianday(completed_at) - julianday(started_at)) * 86400000), 0) AS INTEGER),
+				SUM(usage_nodes), SUM(usage_cpu_time_ms), SUM(usage_bytes_stored), SUM(usage_http_calls)
+			FROM workflow_executions
+			WHERE completed_at >= ? AND completed_at < ? AND NOT test
+			GROUP BY workflow_id
+		`, []any{from, from, to}},
+		{`
+			INSERT INTO workflow_daily_stats (workflow_id, day, sampled_out)
+			SELECT t.workflow_id, c.day, SUM(c.sampled_out)
+			FROM trigger_run_counts c JOIN triggers t ON t.id = c.trigger_id
+			WHERE c.day = ?
+			GROUP BY t.workflow_id
+			ON CONFLICT(day, workflow_id) DO UPDATE SET sampled_out = excluded.sampled_out
+		`, []any{from}},
+	}
+	for _, stmt := range statements {
+		if _, err := s.q.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+			return fmt.Errorf("failed to roll up workflow stats: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListWorkflowStats returns the daily stats of a workflow, or of every workflow
+// when workflowID is empty, from the day of since on, ordered by day and workflow
+func (s *SQLiteStorage) ListWorkflowStats(ctx context.Context, workflowID string, since time.Time) ([]*WorkflowDayStats, error) {
+	query := `
+		SELECT workflow_id, day, runs, failures, duration_ms, sampled_out,
+			nodes, cpu_time_ms, bytes_stored, http_calls
+		FROM workflow_daily_stats
+		WHERE day >= ? AND (? = '' OR workflow_id = ?)
+		ORDER BY day ASC, workflow_id ASC
+	`
+	rows, err := s.q.QueryContext(ctx, query, runCountDay(since), workflowID, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflow stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []*WorkflowDayStats
+	for rows.Next() {
+		var st WorkflowDayStats
+		if err := rows.Scan(&st.WorkflowID, &st.Day, &st.Runs, &st.Failures, &st.DurationMS, &st.SampledOut,
+			&st.Usage.Nodes, &st.Usage.CPUTimeMS, &st.Usage.BytesStored, &st.Usage.HTTPCalls); err != nil {
+			return nil, fmt.Errorf("failed to scan workflow stats: %w", err)
+		}
+		stats = append(stats, &st)
+	}
+	return stats, rows.Err()
+}
+
+// --- Workflow Quotas ---
+
+// GetWorkflowCanary returns the canary version of a workflow, nil if it has none
+func (s *SQLiteStorage) GetWorkflowCanary(ctx context.Context, workflowID string) (*WorkflowCanary, error) {
+	query := `SELECT workflow_id, definition, percent, created_at FROM workflow_canaries WHERE workflow_id = ?`
+	var c WorkflowCanary
+	err := s.q.QueryRowContext(ctx, query, workflowID).Scan(&c.WorkflowID, &c.Definition, &c.Percent, &c.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow canary: %w", err)
+	}
+	return &c, nil
+}
+
+// SetWorkflowCanary creates or replaces the canary version of a workflow
+func (s *SQLiteStorage) SetWorkflowCanary(ctx context.Context, canary *WorkflowCanary) error {
+	query := `
+		INSERT INTO workflow_canaries (workflow_id, definition, percent, created_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(workflow_id) DO UPDATE SET
+			created_at = CASE WHEN definition = excluded.definition THEN created_at ELSE excluded.created_at END,
+			definition = excluded.definition,
+			percent = excluded.percent
+	`
+	if _, err := s.q.ExecContext(ctx, query, canary.WorkflowID, canary.Definition, canary.Percent); err != nil {
+		return fmt.Errorf("failed to set workflow canary: %w", err)
+	}
+	return nil
+}
+
+// DeleteWorkflowCanary removes the canary version of a workflow
+func (s *SQLiteStorage) DeleteWorkflowCanary(ctx context.Context, workflowID string) error {
+	if _, err := s.q.ExecContext(ctx, `DELETE FROM workflow_canaries WHERE workflow_id = ?`, workflowID); err != nil {
+		return fmt.Errorf("failed to delete workflow canary: %w", err)
+	}
+	return nil
+}
+
+// --- Workflow Snapshots ---
+
+// GetWorkflowSnapshot returns the snapshot of a workflow, nil if it has none
+func (s *SQLiteStorage) GetWorkflowSnapshot(ctx context.Context, workflowID string) (*WorkflowSnapshot, error) {
+	query := `SELECT workflow_id, execution_id, data, created_at FROM workflow_snapshots WHERE workflow_id = ?`
+	var snap WorkflowSnapshot
+	err := s.q.QueryRowContext(ctx, query, workflowID).Scan(&snap.WorkflowID, &snap.ExecutionID, &snap.Data, &snap.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow snapshot: %w", err)
+	}
+	return &snap, nil
+}
+
+// SetWorkflowSnapshot creates or replaces the snapshot of a workflow
+func (s *SQLiteStorage) SetWorkflowSnapshot(ctx context.Context, snapshot *WorkflowSnapshot) error {
+	query := `
+		INSERT INTO workflow_snapshots (workflow_id, execution_id, data, created_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(workflow_id) DO UPDATE SET
+			execution_id = excluded.execution_id,
+			data = excluded.data,
+			created_at = excluded.created_at
+	`
+	if _, err := s.q.ExecContext(ctx, query, snapshot.WorkflowID, snapshot.ExecutionID, snapshot.Data); err != nil {
+		return fmt.Errorf("failed to set workflow snapshot: %w", err)
+	}
+	return nil
+}
+
+// DeleteWorkflowSnapshot removes the snapshot of a workflow
+func (s *SQLiteStorage) DeleteWorkflowSnapshot(ctx context.Context, workflowID string) error {
+	if _, err := s.q.ExecContext(ctx, `DELETE FROM workflow_snapshots WHERE workflow_id = ?`, workflowID); err != nil {
+		return fmt.Errorf("failed to delete workflow snapshot: %w", err)
+	}
+	return nil
+}
+
+// WorkflowVersionStats counts the executions of a workflow started at or after
+// since, separately for its stable and canary versions
+func (s *SQLiteStorage) WorkflowVersionStats(ctx context.Context, workflowID string, since time.Time) (stable, canary VersionStats, err error) {
+	query := `
+		SELECT canary, COUNT(*), SUM(status = 'completed'), SUM(status = 'failed'),
+			CAST(COALESCE(SUM((julianday(completed_at) - julianday(started_at)) * 86400000), 0) AS INTEGER)
+		FROM workflow_executions
+		WHERE workflow_id = ? AND started_at >= ? AND NOT test
+		GROUP BY canary
+	`
+	rows, err := s.q.QueryContext(ctx, query, workflowID, currentTimestamp(since))
+	if err != nil {
+		return stable, canary, fmt.Errorf("failed to count workflow versions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var isCanary bool
+		var v VersionStats
+		if err := rows.Scan(&isCanary, &v.Runs, &v.Completed, &v.Failed, &v.DurationMS); err != nil {
+			return stable, canary, fmt.Errorf("failed to scan workflow version stats: %w", err)
+		}
+		if isCanary {
+			canary = v
+		} else {
+			stable = v
+		}
+	}
+	return stable, canary, rows.Err()
+}
+
+// GetWorkflowQuota returns the quota set for a workflow, nil if none is
+func (s *SQLiteStorage) GetWorkflowQuota(ctx context.Context, workflowID string) (*WorkflowQuota, error) {
+	query := `
+		SELECT workflow_id, executions_per_day, storage_bytes, override_until, updated_at
+		FROM workflow_quotas
+		WHERE workflow_id = ?
+	`
+	var q WorkflowQuota
+	var overrideUntil sql.NullTime
+	err := s.q.QueryRowContext(ctx, query, workflowID).Scan(&q.WorkflowID, &q.ExecutionsPerDay, &q.StorageBytes, &overrideUntil, &q.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow quota: %w", err)
+	}
+	if overrideUntil.Valid {
+		q.OverrideUntil = &overrideUntil.Time
+	}
+	return &q, nil
+}
+
+// SetWorkflowQuota creates or replaces the quota of a workflow
+func (s *SQLiteStorage) SetWorkflowQuota(ctx context.Context, quota *WorkflowQuota) error {
+	var overrideUntil any
+	if quota.OverrideUntil != nil {
+		overrideUntil = quota.OverrideUntil.UTC()
+	}
+	query := `
+		INSERT INTO workflow_quotas (workflow_id, executions_per_day, storage_bytes, override_until, updated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(workflow_id) DO UPDATE SET
+			executions_per_day = excluded.executions_per_day,
+			storage_bytes = excluded.storage_bytes,
+			override_until = excluded.override_until,
+			updated_at = CURRENT_TIMESTAMP
+	`
+	if _, err := s.q.ExecContext(ctx, query, quota.WorkflowID, quota.ExecutionsPerDay, quota.StorageBytes, overrideUntil); err != nil {
+		return fmt.Errorf("failed to set workflow quota: %w", err)
+	}
+	return nil
+}
+
+// DeleteWorkflowQuota removes the quota of a workflow, which then has the defaults
+func (s *SQLiteStorage) DeleteWorkflowQuota(ctx context.Context, workflowID string) error {
+	if _, err := s.q.ExecContext(ctx, `DELETE FROM workflow_quotas WHERE workflow_id = ?`, workflowID); err != nil {
+		return fmt.Errorf("failed to delete workflow quota: %w", err)
+	}
+	return nil
+}
+
+// RecordPurge stores the audit record of a purge
+func (s *SQLiteStorage) RecordPurge(ctx context.Context, p *PurgeRecord) error {
+	query := `
+		INSERT INTO purges (id, subject_hash, matched_by, reason, executions, trigger_executions, queued_fires, snapshots, skipped_running, purged_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := s.q.ExecContext(ctx, query, p.ID, p.SubjectHash, p.MatchedBy, p.Reason, p.Executions, p.TriggerExecutions,
+		p.QueuedFires, p.Snapshots, p.SkippedRunning, p.PurgedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record purge: %w", err)
+	}
+	return nil
+}
+
+// ListPurges returns the audit records of purges, most recent first, limited
+// by the limit parameter
+func (s *SQLiteStorage) ListPurges(ctx context.Context, limit int) ([]*PurgeRecord, error) {
+	query := `
+		SELECT id, subject_hash, matched_by, reason, executions, trigger_executions, queued_fires, snapshots, skipped_running, purged_at
+		FROM purges
+		ORDER BY purged_at DESC, rowid DESC
+		LIMIT ?
+	`
+	rows, err := s.q.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purges: %w", err)
+	}
+	defer rows.Close()
+
+	var purges []*PurgeRecord
+	for rows.Next() {
+		var p PurgeRecord
+		if err := rows.Scan(&p.ID, &p.SubjectHash, &p.MatchedBy, &p.Reason, &p.Executions, &p.TriggerExecutions,
+			&p.QueuedFires, &p.Snapshots, &p.SkippedRunning, &p.PurgedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan purge: %w", err)
+		}
+		purges = append(purges, &p)
+	}
+	return purges, rows.Err()
+}
+
+// WorkflowUsageSince counts the executions of a workflow started at or after
+// since, and sums their usage. Test-mode runs are left out.
+func (s *SQLiteStorage) WorkflowUsageSince(ctx context.Context, workflowID string, since time.Time) (int, ExecutionUsage, error) {
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(usage_nodes), 0), COALESCE(SUM(usage_cpu_time_ms), 0),
+			COALESCE(SUM(usage_bytes_stored), 0), COALESCE(SUM(usage_http_calls), 0)
+		FROM workflow_executions
+		WHERE workflow_id = ? AND started_at >= ? AND NOT test
+	`
+	var count int
+	var u ExecutionUsage
+	err := s.q.QueryRowContext(ctx, query, workflowID, currentTimestamp(since)).Scan(&count, &u.Nodes, &u.CPUTimeMS, &u.BytesStored, &u.HTTPCalls)
+	if err != nil {
+		return 0, ExecutionUsage{}, fmt.Errorf("failed to sum workflow usage: %w", err)
+	}
+	return count, u, nil
+}
+
+// --- Global Variables and Environments ---
+
+func (s *SQLiteStorage) CreateEnvironment(ctx context.Context, env *Environment) error {
+	query := `INSERT INTO environments (name, description, created_at) VALUES (?, ?, CURRENT_TIMESTAMP)`
+	if _, err := s.q.ExecContext(ctx, query, env.Name, env.Description); err != nil {
+		return fmt.Errorf("failed to create environment: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) GetEnvironment(ctx context.Context, name string) (*Environment, error) {
+	var env Environment
+	query := `SELECT name, description, created_at FROM environments WHERE name = ?`
+	if err := s.q.QueryRowContext(ctx, query, name).Scan(&env.Name, &env.Description, &env.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to get environment: %w", err)
+	}
+	return &env, nil
+}
+
+func (s *SQLiteStorage) ListEnvironments(ctx context.Context) ([]*Environment, error) {
+	rows, err := s.q.QueryContext(ctx, `SELECT name, description, created_at FROM environments ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list environments: %w", err)
+	}
+	defer rows.Close()
+
+	var envs []*Environment
+	for rows.Next() {
+		var env Environment
+		if err := rows.Scan(&env.Name, &env.Description, &env.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan environment: %w", err)
+		}
+		envs = append(envs, &env)
+	}
+	return envs, rows.Err()
+}
+
+// DeleteEnvironment removes an environment together with its variable overrides
+func (s *SQLiteStorage) DeleteEnvironment(ctx context.Context, name string) error {
+	return WithTx(ctx, s.txStorage(), func(tx Tx) error {
+		q := tx.(*sqliteTx).q
+		res, err := q.ExecContext(ctx, `DELETE FROM environments WHERE name = ?`, name)
+		if err != nil {
+			return fmt.Errorf("failed to delete environment: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return fmt.Errorf("environment not found: %s", name)
+		}
+		if _, err := q.ExecContext(ctx, `DELETE FROM global_variables WHERE environment = ?`, name); err != nil {
+			return fmt.Errorf("failed to delete environment variables: %w", err)
+		}
+		return nil
+	})
+}
+
+// SetGlobalVariable creates or replaces a global variable
+func (s *SQLiteStorage) SetGlobalVariable(ctx context.Context, v *GlobalVariable) error {
+	query := `
+		INSERT INTO global_variables (environment, name, value, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(environment, name) DO UPDATE SET
+			value = excluded.value,
+			updated_at = CURRENT_TIMESTAMP
+	`
+	if _, err := s.q.ExecContext(ctx, query, v.Environment, v.Name, v.Value); err != nil {
+		return fmt.Errorf("failed to set global variable: %w", err)
+	}
+	return nil
+}
+
+// ListGlobalVariables returns the variables defined directly in environment (empty for the shared defaults)
+func (s *SQLiteStorage) ListGlobalVariables(ctx context.Context, environment string) ([]*GlobalVariable, error) {
+	query := `
+		SELECT environment, name, value, updated_at
+		FROM global_variables
+		WHERE environment = ?
+		ORDER BY name
+	`
+	rows, err := s.q.QueryContext(ctx, query, environment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list global variables: %w", err)
+	}
+	defer rows.Close()
+
+	var vars []*GlobalVariable
+	for rows.Next() {
+		var v GlobalVariable
+		if err := rows.Scan(&v.Environment, &v.Name, &v.Value, &v.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan global variable: %w", err)
+		}
+		vars = append(vars, &v)
+	}
+	return vars, rows.Err()
+}
+
+func (s *SQLiteStorage) DeleteGlobalVariable(ctx context.Context, environment, name string) error {
+	res, err := s.q.ExecContext(ctx, `DELETE FROM global_variables WHERE environment = ? AND name = ?`, environment, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete global variable: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("global variable not found: %s", name)
+	}
+	return nil
+}
+
 // --- Trigger Management ---
 
 func (s *SQLiteStorage) CreateTrigger(ctx context.Context, t *Trigger) error {
@@ -481,7 +2284,7 @@ func (s *SQLiteStorage) CreateTrigger(ctx context.Context, t *Trigger) error {
 		INSERT INTO triggers (id, workflow_id, type, config, enabled, created_at, updated_at, file_path)
 		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, ?)
 	`
-	_, err := s.db.ExecContext(ctx, query, t.ID, t.WorkflowID, t.Type, t.Config, t.Enabled, t.FilePath)
+	_, err := s.q.ExecContext(ctx, query, t.ID, t.WorkflowID, t.Type, t.Config, t.Enabled, t.FilePath)
 	if err != nil {
 		return fmt.Errorf("failed to create trigger: %w", err)
 	}
@@ -489,9 +2292,9 @@ func (s *SQLiteStorage) CreateTrigger(ctx context.Context, t *Trigger) error {
 }
 
 func (s *SQLiteStorage) GetTrigger(ctx context.Context, id string) (*Trigger, error) {
-	query := `SELECT id, workflow_id, type, config, enabled, created_at, updated_at, file_path FROM triggers WHERE id = ?`
+	query := `SELECT id, workflow_id, type, config, enabled, created_at, updated_at, file_path, runtime_status, runtime_error FROM triggers WHERE id = ?`
 	var t Trigger
-	err := s.db.QueryRowContext(ctx, query, id).Scan(&t.ID, &t.WorkflowID, &t.Type, &t.Config, &t.Enabled, &t.CreatedAt, &t.UpdatedAt, &t.FilePath)
+	err := s.q.QueryRowContext(ctx, query, id).Scan(&t.ID, &t.WorkflowID, &t.Type, &t.Config, &t.Enabled, &t.CreatedAt, &t.UpdatedAt, &t.FilePath, &t.RuntimeStatus, &t.RuntimeError)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("trigger not found")
@@ -507,7 +2310,7 @@ func (s *SQLiteStorage) UpdateTrigger(ctx context.Context, t *Trigger) error {
 		SET workflow_id = ?, type = ?, config = ?, enabled = ?, updated_at = CURRENT_TIMESTAMP, file_path = ?
 		WHERE id = ?
 	`
-	res, err := s.db.ExecContext(ctx, query, t.WorkflowID, t.Type, t.Config, t.Enabled, t.FilePath, t.ID)
+	res, err := s.q.ExecContext(ctx, query, t.WorkflowID, t.Type, t.Config, t.Enabled, t.FilePath, t.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update trigger: %w", err)
 	}
@@ -523,7 +2326,7 @@ func (s *SQLiteStorage) UpdateTrigger(ctx context.Context, t *Trigger) error {
 
 func (s *SQLiteStorage) DeleteTrigger(ctx context.Context, id string) error {
 	query := `DELETE FROM triggers WHERE id = ?`
-	_, err := s.db.ExecContext(ctx, query, id)
+	_, err := s.q.ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete trigger: %w", err)
 	}
@@ -531,8 +2334,8 @@ func (s *SQLiteStorage) DeleteTrigger(ctx context.Context, id string) error {
 }
 
 func (s *SQLiteStorage) ListTriggers(ctx context.Context, workflowID string) ([]*Trigger, error) {
-	query := `SELECT id, workflow_id, type, config, enabled, created_at, updated_at, file_path FROM triggers WHERE workflow_id = ? ORDER BY created_at DESC`
-	rows, err := s.db.QueryContext(ctx, query, workflowID)
+	query := `SELECT id, workflow_id, type, config, enabled, created_at, updated_at, file_path, runtime_status, runtime_error FROM triggers WHERE workflow_id = ? ORDER BY created_at DESC`
+	rows, err := s.q.QueryContext(ctx, query, workflowID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list triggers: %w", err)
 	}
@@ -541,7 +2344,7 @@ func (s *SQLiteStorage) ListTriggers(ctx context.Context, workflowID string) ([]
 	var triggers []*Trigger
 	for rows.Next() {
 		var t Trigger
-		if err := rows.Scan(&t.ID, &t.WorkflowID, &t.Type, &t.Config, &t.Enabled, &t.CreatedAt, &t.UpdatedAt, &t.FilePath); err != nil {
+		if err := rows.Scan(&t.ID, &t.WorkflowID, &t.Type, &t.Config, &t.Enabled, &t.CreatedAt, &t.UpdatedAt, &t.FilePath, &t.RuntimeStatus, &t.RuntimeError); err != nil {
 			return nil, err
 		}
 		triggers = append(triggers, &t)
@@ -550,8 +2353,8 @@ func (s *SQLiteStorage) ListTriggers(ctx context.Context, workflowID string) ([]
 }
 
 func (s *SQLiteStorage) ListAllTriggers(ctx context.Context) ([]*Trigger, error) {
-	query := `SELECT id, workflow_id, type, config, enabled, created_at, updated_at, file_path FROM triggers WHERE enabled = 1`
-	rows, err := s.db.QueryContext(ctx, query)
+	query := `SELECT id, workflow_id, type, config, enabled, created_at, updated_at, file_path, runtime_status, runtime_error FROM triggers WHERE enabled = 1`
+	rows, err := s.q.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list all triggers: %w", err)
 	}
@@ -560,7 +2363,7 @@ func (s *SQLiteStorage) ListAllTriggers(ctx context.Context) ([]*Trigger, error)
 	var triggers []*Trigger
 	for rows.Next() {
 		var t Trigger
-		if err := rows.Scan(&t.ID, &t.WorkflowID, &t.Type, &t.Config, &t.Enabled, &t.CreatedAt, &t.UpdatedAt, &t.FilePath); err != nil {
+		if err := rows.Scan(&t.ID, &t.WorkflowID, &t.Type, &t.Config, &t.Enabled, &t.CreatedAt, &t.UpdatedAt, &t.FilePath, &t.RuntimeStatus, &t.RuntimeError); err != nil {
 			return nil, err
 		}
 		triggers = append(triggers, &t)
@@ -568,14 +2371,78 @@ func (s *SQLiteStorage) ListAllTriggers(ctx context.Context) ([]*Trigger, error)
 	return triggers, nil
 }
 
+// SetTriggerRuntimeStatus records the runtime status of a trigger. errMsg is
+// stored as given, so nil clears the error of a trigger that recovered.
+func (s *SQLiteStorage) SetTriggerRuntimeStatus(ctx context.Context, id string, status TriggerRuntimeStatus, errMsg *string) error {
+	query := `UPDATE triggers SET runtime_status = ?, runtime_error = ? WHERE id = ?`
+	res, err := s.q.ExecContext(ctx, query, status, errMsg, id)
+	if err != nil {
+		return fmt.Errorf("failed to set trigger runtime status: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("trigger not found")
+	}
+	return nil
+}
+
+// --- Queued Trigger Fires ---
+
+// QueueTriggerFire holds a fire of a trigger until ListQueuedTriggerFires picks it up
+func (s *SQLiteStorage) QueueTriggerFire(ctx context.Context, fire *QueuedTriggerFire) error {
+	query := `INSERT INTO queued_trigger_fires (id, trigger_id, payload, backfill, queued_at) VALUES (?, ?, ?, ?, ?)`
+	if _, err := s.q.ExecContext(ctx, query, fire.ID, fire.TriggerID, fire.Payload, fire.Backfill, fire.QueuedAt.UTC()); err != nil {
+		return fmt.Errorf("failed to queue trigger fire: %w", err)
+	}
+	return nil
+}
+
+// ListQueuedTriggerFires returns the queued fires of all triggers, oldest first
+func (s *SQLiteStorage) ListQueuedTriggerFires(ctx context.Context) ([]*QueuedTriggerFire, error) {
+	query := `
+		SELECT id, trigger_id, payload, backfill, queued_at
+		FROM queued_trigger_fires
+		ORDER BY queued_at, rowid
+	`
+	rows, err := s.q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queued trigger fires: %w", err)
+	}
+	defer rows.Close()
+
+	var fires []*QueuedTriggerFire
+	for rows.Next() {
+		var f QueuedTriggerFire
+		if err := rows.Scan(&f.ID, &f.TriggerID, &f.Payload, &f.Backfill, &f.QueuedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan queued trigger fire: %w", err)
+		}
+		fires = append(fires, &f)
+	}
+	return fires, rows.Err()
+}
+
+// DeleteQueuedTriggerFire removes a queued fire and reports whether it was
+// still queued, so that only one server runs it
+func (s *SQLiteStorage) DeleteQueuedTriggerFire(ctx context.Context, id string) (bool, error) {
+	res, err := s.q.ExecContext(ctx, `DELETE FROM queued_trigger_fires WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete queued trigger fire: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
 // --- Trigger Execution History ---
 
 func (s *SQLiteStorage) CreateTriggerExecution(ctx context.Context, te *TriggerExecution) error {
 	query := `
-		INSERT INTO trigger_executions (id, trigger_id, execution_id, fired_at, status, payload, error)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO trigger_executions (id, trigger_id, workflow_id, execution_id, fired_at, status, payload, error, backfill)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	_, err := s.db.ExecContext(ctx, query, te.ID, te.TriggerID, te.ExecutionID, te.FiredAt, te.Status, te.Payload, te.Error)
+	_, err := s.q.ExecContext(ctx, query, te.ID, te.TriggerID, te.WorkflowID, te.ExecutionID, te.FiredAt, te.Status, te.Payload, te.Error, te.Backfill)
 	if err != nil {
 		return fmt.Errorf("failed to create trigger execution: %w", err)
 	}
@@ -584,13 +2451,13 @@ func (s *SQLiteStorage) CreateTriggerExecution(ctx context.Context, te *TriggerE
 
 func (s *SQLiteStorage) ListTriggerExecutions(ctx context.Context, triggerID string, limit int) ([]*TriggerExecution, error) {
 	query := `
-		SELECT id, trigger_id, execution_id, fired_at, status, payload, error
+		SELECT id, trigger_id, workflow_id, execution_id, fired_at, status, payload, error, backfill
 		FROM trigger_executions
 		WHERE trigger_id = ?
 		ORDER BY fired_at DESC
 		LIMIT ?
 	`
-	rows, err := s.db.QueryContext(ctx, query, triggerID, limit)
+	rows, err := s.q.QueryContext(ctx, query, triggerID, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list trigger executions: %w", err)
 	}
@@ -606,11 +2473,13 @@ func (s *SQLiteStorage) ListTriggerExecutions(ctx context.Context, triggerID str
 		err := rows.Scan(
 			&te.ID,
 			&te.TriggerID,
+			&te.WorkflowID,
 			&executionID,
 			&te.FiredAt,
 			&te.Status,
 			&payload,
 			&errorMsg,
+			&te.Backfill,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan trigger execution: %w", err)
@@ -631,7 +2500,65 @@ func (s *SQLiteStorage) ListTriggerExecutions(ctx context.Context, triggerID str
 	return executions, nil
 }
 
+// DeleteTriggerExecution removes an entry of trigger history
+func (s *SQLiteStorage) DeleteTriggerExecution(ctx context.Context, id string) error {
+	if _, err := s.q.ExecContext(ctx, `DELETE FROM trigger_executions WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete trigger execution: %w", err)
+	}
+	return nil
+}
+
 // Close releases database resources
+
+// sqliteTx is a transaction begun on a SQLiteStorage.
+type sqliteTx struct {
+	*SQLiteStorage
+}
+
+// Begin starts a transaction. Its context bounds the whole transaction: the
+// transaction is rolled back if the context is cancelled before Commit.
+func (s *SQLiteStorage) Begin(ctx context.Context) (Tx, error) {
+	if s.tx != nil {
+		return nil, errNestedTx
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	return &sqliteTx{&SQLiteStorage{db: s.db, q: tx, tx: tx}}, nil
+}
+
+// txStorage returns s as a Tx if it is a transaction, so that methods running
+// several statements join it instead of beginning their own.
+func (s *SQLiteStorage) txStorage() Storage {
+	if s.tx != nil {
+		return &sqliteTx{s}
+	}
+	return s
+}
+
+func (t *sqliteTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *sqliteTx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// Close rolls the transaction back; the database stays open.
+func (t *sqliteTx) Close() error {
+	return t.Rollback()
+}
+
+// Ping runs a trivial query to verify the database is reachable and responsive
+func (s *SQLiteStorage) Ping(ctx context.Context) error {
+	var one int
+	if err := s.q.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		return fmt.Errorf("database query failed: %w", err)
+	}
+	return nil
+}
+
 func (s *SQLiteStorage) Close() error {
 	return s.db.Close()
 }
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"errors"
+)
+
+// Tx is a Storage whose writes are applied together by Commit, or not at all
+// after Rollback or a crash. Until then they are seen only through the Tx, and
+// other writers may wait for it: use only the Tx, from one goroutine, until it
+// ends, and keep it short. Close on a Tx rolls it back.
+type Tx interface {
+	Storage
+	Commit() error
+	Rollback() error
+}
+
+// errNestedTx is returned by Begin on a Tx; use WithTx to join a transaction.
+var errNestedTx = errors.New("storage: transactions cannot be nested")
+
+// WithTx runs fn in a transaction begun on store, committing it if fn returns
+// nil and rolling it back otherwise. If store is itself a Tx, fn runs in it and
+// its owner decides whether the writes are committed.
+func WithTx(ctx context.Context, store Storage, fn func(tx Tx) error) error {
+	if tx, ok := store.(Tx); ok {
+		return fn(tx)
+	}
+	tx, err := store.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
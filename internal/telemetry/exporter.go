@@ -0,0 +1,90 @@
+package telemetry
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer spans are created with.
+const instrumentationName = "github.com/conv3n/conv3n"
+
+// ExporterConfig configures the OTLP/HTTP span exporter.
+type ExporterConfig struct {
+	// Endpoint is the collector base URL, e.g. http://localhost:4318.
+	// Spans are POSTed to Endpoint + "/v1/traces".
+	Endpoint string
+	// ServiceName is reported as the service.name resource attribute.
+	ServiceName string
+	// Headers are added to every export request (e.g. auth tokens).
+	Headers map[string]string
+}
+
+// ConfigFromEnv builds an exporter config from the standard OpenTelemetry
+// environment variables (OTEL_EXPORTER_OTLP_ENDPOINT, OTEL_EXPORTER_OTLP_HEADERS,
+// OTEL_SERVICE_NAME). Returns ok=false when no endpoint is configured.
+func ConfigFromEnv() (ExporterConfig, bool) {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if endpoint == "" {
+		return ExporterConfig{}, false
+	}
+
+	cfg := ExporterConfig{
+		Endpoint:    strings.TrimSuffix(strings.TrimSuffix(endpoint, "/"), "/v1/traces"),
+		ServiceName: os.Getenv("OTEL_SERVICE_NAME"),
+		Headers:     make(map[string]string),
+	}
+	for _, pair := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		if k, v, found := strings.Cut(pair, "="); found {
+			cfg.Headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return cfg, true
+}
+
+// NewExporter creates an OTLP/HTTP exporter for the given config. It doesn't
+// connect until spans are exported.
+func NewExporter(ctx context.Context, cfg ExporterConfig) (sdktrace.SpanExporter, error) {
+	return otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(cfg.Endpoint+"/v1/traces"),
+		otlptracehttp.WithHeaders(cfg.Headers),
+	)
+}
+
+// Tracer creates spans and batches finished ones to an exporter.
+type Tracer struct {
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+}
+
+// NewTracer creates a tracer exporting through exporter, reporting spans as
+// those of serviceName (default "conv3n").
+func NewTracer(exporter sdktrace.SpanExporter, serviceName string) *Tracer {
+	if serviceName == "" {
+		serviceName = "conv3n"
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	return &Tracer{provider: provider, tracer: provider.Tracer(instrumentationName)}
+}
+
+// Flush exports all finished spans and waits for the export to finish.
+func (t *Tracer) Flush(ctx context.Context) error {
+	return t.provider.ForceFlush(ctx)
+}
+
+// Shutdown flushes remaining spans and stops the exporter.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	return t.provider.Shutdown(ctx)
+}
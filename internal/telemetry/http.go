@@ -0,0 +1,64 @@
+package telemetry
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// statusRecorder captures the response status code for span attributes.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Flush forwards streaming flushes to the underlying writer when supported.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// hijack websocket connections or set deadlines.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Middleware wraps an HTTP handler with a server span per request.
+// An incoming W3C traceparent header is honored so conv3n joins the caller's trace.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if currentTracer() == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := propagation.TraceContext{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		ctx, span := Start(ctx, r.Method+" "+r.URL.Path, SpanKindServer)
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		req := r.WithContext(ctx)
+		next.ServeHTTP(rec, req)
+
+		// The mux fills in the matched pattern on the request it was given
+		if req.Pattern != "" {
+			span.SetName(req.Pattern)
+			span.SetAttr("http.route", req.Pattern)
+		}
+		span.SetAttr("http.method", r.Method)
+		span.SetAttr("http.target", r.URL.Path)
+		span.SetAttr("http.status_code", rec.status)
+		if rec.status >= http.StatusInternalServerError {
+			span.RecordError(fmt.Errorf("HTTP %d", rec.status))
+		}
+	})
+}
@@ -0,0 +1,151 @@
+// Package telemetry provides distributed tracing for the engine on top of the
+// OpenTelemetry SDK. Spans are exported to any OpenTelemetry collector via
+// OTLP/HTTP; tracing stays off, at no cost, until a tracer is installed.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanKind is the OpenTelemetry span kind.
+type SpanKind = trace.SpanKind
+
+const (
+	SpanKindInternal = trace.SpanKindInternal
+	SpanKindServer   = trace.SpanKindServer
+	SpanKindClient   = trace.SpanKindClient
+)
+
+// TraceID is a 16-byte W3C trace identifier.
+type TraceID = trace.TraceID
+
+// SpanID is an 8-byte W3C span identifier.
+type SpanID = trace.SpanID
+
+// Span is a single timed operation. All methods are safe to call on a nil Span,
+// which is what callers get when tracing is disabled.
+type Span struct {
+	span trace.Span
+}
+
+// SetAttr records a key/value attribute on the span.
+// Supported value types: string, bool, int, int64, float64, time.Duration (recorded in ms).
+func (s *Span) SetAttr(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.span.SetAttributes(attributeOf(key, value))
+}
+
+// SetName renames the span, e.g. once the matched route is known.
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.span.SetName(name)
+}
+
+// RecordError marks the span as failed with the error message.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End finishes the span and hands it to the exporter. Subsequent calls are no-ops.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.span.End()
+}
+
+// TraceID returns the trace this span belongs to (zero when tracing is disabled).
+func (s *Span) TraceID() TraceID {
+	if s == nil {
+		return TraceID{}
+	}
+	return s.span.SpanContext().TraceID()
+}
+
+// SpanID returns the span's own identifier (zero when tracing is disabled).
+func (s *Span) SpanID() SpanID {
+	if s == nil {
+		return SpanID{}
+	}
+	return s.span.SpanContext().SpanID()
+}
+
+var (
+	globalMu     sync.RWMutex
+	globalTracer *Tracer
+)
+
+// SetTracer installs the process-wide tracer. Passing nil disables tracing.
+func SetTracer(t *Tracer) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	globalTracer = t
+}
+
+func currentTracer() *Tracer {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	return globalTracer
+}
+
+// Start begins a span as a child of whatever span is in ctx, which may be a
+// remote one extracted from an incoming request.
+// When no tracer is installed it returns ctx unchanged and a nil span.
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	tracer := currentTracer()
+	if tracer == nil {
+		return ctx, nil
+	}
+	ctx, span := tracer.tracer.Start(ctx, name, trace.WithSpanKind(kind))
+	return ctx, &Span{span: span}
+}
+
+// SpanFromContext returns the active span in ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return nil
+	}
+	return &Span{span: span}
+}
+
+// Detach returns a fresh background context that keeps the active span of ctx,
+// so work outliving a request (async executions) stays in the same trace.
+func Detach(ctx context.Context) context.Context {
+	return trace.ContextWithSpan(context.Background(), trace.SpanFromContext(ctx))
+}
+
+// attributeOf converts a SetAttr value into an OpenTelemetry attribute.
+func attributeOf(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	case time.Duration:
+		return attribute.Float64(key, float64(v)/float64(time.Millisecond))
+	default:
+		return attribute.String(key, fmt.Sprintf("%v", v))
+	}
+}
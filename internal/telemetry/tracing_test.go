@@ -0,0 +1,156 @@
+package telemetry_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/conv3n/conv3n/internal/telemetry"
+)
+
+// setupTracer installs a tracer whose spans are kept in memory.
+func setupTracer(t *testing.T) (*tracetest.InMemoryExporter, *telemetry.Tracer) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tracer := telemetry.NewTracer(exporter, "")
+	telemetry.SetTracer(tracer)
+	t.Cleanup(func() {
+		telemetry.SetTracer(nil)
+		tracer.Shutdown(context.Background())
+	})
+	return exporter, tracer
+}
+
+func byName(spans tracetest.SpanStubs, name string) *tracetest.SpanStub {
+	for i := range spans {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+	return nil
+}
+
+func TestStart_DisabledReturnsNilSpan(t *testing.T) {
+	telemetry.SetTracer(nil)
+	ctx, span := telemetry.Start(context.Background(), "noop", telemetry.SpanKindInternal)
+	if span != nil {
+		t.Fatal("expected nil span when tracing is disabled")
+	}
+	// Methods must be safe on a nil span
+	span.SetAttr("key", "value")
+	span.RecordError(errors.New("boom"))
+	span.End()
+	if telemetry.SpanFromContext(ctx) != nil {
+		t.Error("expected no span in context")
+	}
+}
+
+func TestExporter_ParentChildSpans(t *testing.T) {
+	exporter, tracer := setupTracer(t)
+
+	ctx, parent := telemetry.Start(context.Background(), "workflow.execute", telemetry.SpanKindInternal)
+	parent.SetAttr("workflow.id", "wf-1")
+	_, child := telemetry.Start(ctx, "node.execute", telemetry.SpanKindInternal)
+	child.SetAttr("node.type", "std/http_request")
+	child.RecordError(errors.New("node failed"))
+	child.End()
+	parent.End()
+
+	tracer.Flush(context.Background())
+
+	spans := exporter.GetSpans()
+	p := byName(spans, "workflow.execute")
+	ch := byName(spans, "node.execute")
+	if p == nil || ch == nil {
+		t.Fatalf("expected both spans to be exported, got %v", spans)
+	}
+	if ch.SpanContext.TraceID() != p.SpanContext.TraceID() {
+		t.Errorf("child trace %v != parent trace %v", ch.SpanContext.TraceID(), p.SpanContext.TraceID())
+	}
+	if ch.Parent.SpanID() != p.SpanContext.SpanID() {
+		t.Errorf("child parent %v != parent span %v", ch.Parent.SpanID(), p.SpanContext.SpanID())
+	}
+	if ch.Status.Code != codes.Error || ch.Status.Description != "node failed" {
+		t.Errorf("expected error status on child, got %v", ch.Status)
+	}
+	if service, _ := p.Resource.Set().Value("service.name"); service.AsString() != "conv3n" {
+		t.Errorf("expected service name conv3n, got %q", service.AsString())
+	}
+}
+
+func TestMiddleware_HonorsTraceparent(t *testing.T) {
+	exporter, tracer := setupTracer(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/workflows/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if telemetry.SpanFromContext(r.Context()) == nil {
+			t.Error("expected span in handler context")
+		}
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/workflows/wf-1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	telemetry.Middleware(mux).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusTeapot {
+		t.Fatalf("expected handler status to pass through, got %d", rec.Code)
+	}
+
+	tracer.Flush(context.Background())
+
+	span := byName(exporter.GetSpans(), "GET /api/workflows/{id}")
+	if span == nil {
+		t.Fatalf("expected span named after route pattern, got %v", exporter.GetSpans())
+	}
+	if id := span.SpanContext.TraceID().String(); id != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected remote trace id, got %v", id)
+	}
+	if id := span.Parent.SpanID().String(); id != "00f067aa0ba902b7" {
+		t.Errorf("expected remote parent span id, got %v", id)
+	}
+}
+
+// TestNewExporter verifies that spans are sent to the collector's
+// /v1/traces with the configured headers.
+func TestNewExporter(t *testing.T) {
+	var mu sync.Mutex
+	var paths, auths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+		auths = append(auths, r.Header.Get("Authorization"))
+	}))
+	defer srv.Close()
+
+	exporter, err := telemetry.NewExporter(context.Background(), telemetry.ExporterConfig{
+		Endpoint: srv.URL,
+		Headers:  map[string]string{"Authorization": "Bearer token"},
+	})
+	if err != nil {
+		t.Fatalf("NewExporter failed: %v", err)
+	}
+	tracer := telemetry.NewTracer(exporter, "")
+	telemetry.SetTracer(tracer)
+	defer telemetry.SetTracer(nil)
+
+	_, span := telemetry.Start(context.Background(), "workflow.execute", telemetry.SpanKindInternal)
+	span.End()
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paths) != 1 || paths[0] != "/v1/traces" || auths[0] != "Bearer token" {
+		t.Errorf("expected one export to /v1/traces with the header, got %v %v", paths, auths)
+	}
+}
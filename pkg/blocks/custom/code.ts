@@ -1,6 +1,10 @@
 // pkg/blocks/custom/code.ts
 // Custom Code Block: Execute user-provided TypeScript/JavaScript
 // Allows users to write arbitrary code executed in the Bun runtime.
+// The engine passes codeModule, a cache path keyed by the code's hash: the code
+// is compiled and written there on the first run and imported from there after.
+
+import { renameSync } from "node:fs";
 
 // Define type-safe input/output interfaces
 interface CustomCodeInput {
@@ -9,6 +13,7 @@ interface CustomCodeInput {
         input?: unknown;     // Optional input data resolved from variables
     };
     input?: unknown;         // Optional input data from previous blocks
+    codeModule?: string;     // Cache path of the compiled module, set by the engine
 }
 
 interface CustomCodeOutput {
@@ -27,6 +32,26 @@ interface BlockResult {
     port: string;
 }
 
+// Wrap bare statements in a default export so every snippet is a module
+function toModule(userCode: string): string {
+    return userCode.includes("export default")
+        ? userCode
+        : `export default async (input) => { ${userCode} }`;
+}
+
+// Write the compiled module to the cache; concurrent runs may race, so write
+// to a private file and rename it into place. Returns false if caching failed.
+async function writeModule(path: string, compiled: string): Promise<boolean> {
+    const tmp = `${path}.${process.pid}.tmp`;
+    try {
+        await Bun.write(tmp, compiled);
+        renameSync(tmp, path);
+        return true;
+    } catch {
+        return false;
+    }
+}
+
 // Helper to create error result
 function createErrorResult(
     message: string,
@@ -64,10 +89,16 @@ async function main(): Promise<void> {
             ? input.config.input
             : (input.input ?? {});
 
-        // 2. Validate syntax by attempting to transpile
-        try {
+        // 2. Compile the code, unless an earlier run cached the module
+        const codeModule = input.codeModule;
+        let cached = codeModule !== undefined && await Bun.file(codeModule).exists();
+        let compiled = "";
+        if (!cached) try {
             const transpiler = new Bun.Transpiler({ loader: "ts" });
-            transpiler.transformSync(userCode);
+            compiled = transpiler.transformSync(toModule(userCode));
+            if (codeModule !== undefined) {
+                cached = await writeModule(codeModule, compiled);
+            }
         } catch (syntaxError) {
             const endTime = performance.now();
             const err = syntaxError instanceof Error ? syntaxError : new Error(String(syntaxError));
@@ -88,14 +119,10 @@ async function main(): Promise<void> {
         let userFunction: (input: unknown) => Promise<unknown>;
 
         try {
-            // Create a temporary module from the user code
-            const moduleCode = userCode.includes("export default")
-                ? userCode
-                : `export default async (input) => { ${userCode} }`;
-
-            // Use dynamic import with data URL to execute the code
-            const dataUrl = `data:text/typescript;base64,${btoa(moduleCode)}`;
-            const module = await import(dataUrl);
+            // Import the cached module, or the compiled code via a data URL if it couldn't be cached
+            const module = cached
+                ? await import(codeModule!)
+                : await import(`data:text/javascript;base64,${Buffer.from(compiled).toString("base64")}`);
             userFunction = module.default;
 
             if (typeof userFunction !== "function") {
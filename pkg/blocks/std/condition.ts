@@ -1,8 +1,10 @@
 // pkg/blocks/std/condition.ts
 // Standard Block: Conditional Branching
 // Evaluates JavaScript expressions and routes to "true" or "false" output ports.
+// The Go engine evaluates most conditions natively; this script only runs for
+// expressions that need JavaScript (e.g. typeof, string/array methods).
 
-import { Block, BlockHelpers } from "../../bunock/sdk/sdk.ts";
+import { Block, BlockHelpers, isMain } from "../../bunock/sdk/sdk.ts";
 
 // Type definitions for input/output
 export interface ConditionConfig {
@@ -76,6 +78,6 @@ export class ConditionBlock extends Block<ConditionConfig, ConditionOutput> {
 }
 
 // Only run if this is the entry point
-if (import.meta.main) {
+if (isMain(import.meta)) {
     new ConditionBlock().run();
 }
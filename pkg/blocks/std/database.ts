@@ -1,6 +1,7 @@
 // pkg/blocks/std/database.ts
 // Standard Block: Database Operations
-// Provides SQLite operations via native bun:sqlite module
+// Provides SQLite operations via native bun:sqlite module, so unlike the other
+// std blocks it only runs under Bun
 
 import { stdin, stdout } from "bun";
 import { Database } from "bun:sqlite";
@@ -1,6 +1,10 @@
 // pkg/blocks/std/delay.ts
 // Standard Block: Delay/Sleep
 // Introduces time delays in workflow execution.
+// The Go engine implements std/delay natively (long delays suspend the execution
+// and are resumed by the server's scheduler); this script is kept for standalone use.
+
+import { isMain, readStdinJSON, sleep, writeStdoutJSON } from "../../bunock/sdk/io.ts";
 
 // Type definitions for input/output
 export interface DelayConfig {
@@ -53,9 +57,9 @@ export async function executeDelay(config: DelayConfig): Promise<DelayOutput> {
     const unit = config.unit ?? "ms";
     const durationMs = convertToMilliseconds(config.duration, unit);
 
-    // Perform the actual delay using Bun's native sleep
+    // Perform the actual delay
     const startTime = Date.now();
-    await Bun.sleep(durationMs);
+    await sleep(durationMs);
     const actualDelay = Date.now() - startTime;
 
     return {
@@ -69,7 +73,7 @@ export async function executeDelay(config: DelayConfig): Promise<DelayOutput> {
 export async function main(): Promise<void> {
     try {
         // 1. Read input
-        const input: DelayInput = await Bun.stdin.json();
+        const input: DelayInput = await readStdinJSON();
         const { config } = input;
 
         // 2. Validate config
@@ -85,7 +89,7 @@ export async function main(): Promise<void> {
         };
 
         // 5. Write output
-        await Bun.write(Bun.stdout, JSON.stringify(output));
+        await writeStdoutJSON(output);
 
     } catch (error) {
         const message = error instanceof Error ? error.message : String(error);
@@ -95,6 +99,6 @@ export async function main(): Promise<void> {
 }
 
 // Only run main if this is the entry point
-if (import.meta.main) {
+if (isMain(import.meta)) {
     main();
 }
@@ -2,7 +2,9 @@
 // Standard Block: File Operations
 // Provides file system operations: read, write, delete, exists
 
-import { stdin, stdout } from "bun";
+import { mkdir, readFile, stat, unlink, writeFile } from "node:fs/promises";
+import { dirname } from "node:path";
+import { isMain, readStdinJSON, writeStdoutJSON } from "../../bunock/sdk/io.ts";
 
 
 
@@ -66,31 +68,38 @@ export function validateConfig(config: any): void {
     }
 }
 
+// Report whether path is an existing file
+async function fileExists(path: string): Promise<boolean> {
+    try {
+        return (await stat(path)).isFile();
+    } catch {
+        return false;
+    }
+}
+
 // Execute read operation
 export async function executeRead(path: string, format: 'text' | 'json' | 'bytes' = 'text'): Promise<any> {
-    const file = Bun.file(path);
-
     // Check if file exists
-    const exists = await file.exists();
+    const exists = await fileExists(path);
     if (!exists) {
         throw new Error(`File not found: ${path}`);
     }
 
-    // Get file size for output
-    const size = file.size;
-
     // Read file based on format
     let data: any;
+    let size: number;
     try {
+        const bytes = await readFile(path);
+        size = bytes.byteLength;
         switch (format) {
             case 'text':
-                data = await file.text();
+                data = bytes.toString('utf8');
                 break;
             case 'json':
-                data = await file.json();
+                data = JSON.parse(bytes.toString('utf8'));
                 break;
             case 'bytes':
-                data = await file.bytes();
+                data = new Uint8Array(bytes.buffer, bytes.byteOffset, bytes.byteLength);
                 break;
         }
     } catch (error: any) {
@@ -111,8 +120,11 @@ export async function executeWrite(path: string, content: string | object): Prom
             ? JSON.stringify(content, null, 2)
             : content;
 
-        // Write file using Bun.write
-        const bytesWritten = await Bun.write(path, writeContent);
+        // Create missing parent directories, then write the file
+        const bytes = new TextEncoder().encode(writeContent);
+        await mkdir(dirname(path), { recursive: true });
+        await writeFile(path, bytes);
+        const bytesWritten = bytes.byteLength;
 
         return { path, bytesWritten };
     } catch (error: any) {
@@ -123,16 +135,14 @@ export async function executeWrite(path: string, content: string | object): Prom
 // Execute delete operation
 export async function executeDelete(path: string): Promise<{ path: string; deleted: boolean }> {
     try {
-        const file = Bun.file(path);
-
         // Check if file exists before attempting delete
-        const exists = await file.exists();
+        const exists = await fileExists(path);
         if (!exists) {
             throw new Error(`File not found: ${path}`);
         }
 
         // Delete the file
-        await file.delete();
+        await unlink(path);
 
         return { path, deleted: true };
     } catch (error: any) {
@@ -146,8 +156,7 @@ export async function executeDelete(path: string): Promise<{ path: string; delet
 // Execute exists operation
 export async function executeExists(path: string): Promise<{ path: string; exists: boolean }> {
     try {
-        const file = Bun.file(path);
-        const exists = await file.exists();
+        const exists = await fileExists(path);
 
         return { path, exists };
     } catch (error: any) {
@@ -159,7 +168,7 @@ export async function executeExists(path: string): Promise<{ path: string; exist
 export async function main() {
     try {
         // 1. Read input
-        const input: FileInput = await Bun.stdin.json();
+        const input: FileInput = await readStdinJSON();
         const { config } = input;
 
         // 2. Validate config
@@ -196,7 +205,7 @@ export async function main() {
         }
 
         // 4. Write output
-        await Bun.write(Bun.stdout, JSON.stringify(result));
+        await writeStdoutJSON(result);
 
     } catch (error: any) {
         console.error(`File Block Failed: ${error.message}`);
@@ -205,6 +214,6 @@ export async function main() {
 }
 
 // Only run main if this is the entry point
-if (import.meta.main) {
+if (isMain(import.meta)) {
     main();
 }
@@ -1,6 +1,7 @@
 /**
  * GetVar block - Retrieves a user-defined variable from the execution context.
  * Variables are accessed via {{ $vars.name }} syntax in config.
+ * The Go engine runs this node natively; the script is kept for standalone use.
  */
 
 export default async function getVar(input: any): Promise<any> {
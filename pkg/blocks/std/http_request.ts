@@ -1,6 +1,12 @@
 // pkg/blocks/std/http_request.ts
 // Standard Block: HTTP Request
 // Executes HTTP requests and returns response with routing port.
+// Requests with a "pagination" config (page, offset, cursor or link) are looped
+// over by the Go engine, which outputs the items of all pages; they never reach this script.
+// A "retry" config (maxAttempts, initialDelay, maxDelay, statuses) makes the engine
+// re-run requests answered with 429/5xx, honouring Retry-After.
+
+import { isMain, readStdinJSON, writeStdoutJSON } from "../../bunock/sdk/io.ts";
 
 // Type definitions for better type safety
 export interface HttpRequestConfig {
@@ -83,7 +89,7 @@ export function getOutputPort(status: number): string {
 export async function main(): Promise<void> {
     try {
         // 1. Read Input (Config + Context)
-        const input: HttpRequestInput = await Bun.stdin.json();
+        const input: HttpRequestInput = await readStdinJSON();
         const { config } = input;
 
         // 2. Validate config
@@ -99,7 +105,7 @@ export async function main(): Promise<void> {
         };
 
         // 5. Write Output
-        await Bun.write(Bun.stdout, JSON.stringify(output));
+        await writeStdoutJSON(output);
 
     } catch (error) {
         // Write error result with error port
@@ -112,12 +118,12 @@ export async function main(): Promise<void> {
             },
             port: "error",
         };
-        await Bun.write(Bun.stdout, JSON.stringify(errorOutput));
+        await writeStdoutJSON(errorOutput);
         process.exit(1);
     }
 }
 
 // Only run main if this is the entry point
-if (import.meta.main) {
+if (isMain(import.meta)) {
     main();
 }
@@ -2,6 +2,8 @@
 // Standard Block: Loop/Iteration
 // Iterates over arrays and applies transformations.
 
+import { isMain, readStdinJSON, writeStdoutJSON } from "../../bunock/sdk/io.ts";
+
 // Type definitions for input/output
 export interface LoopConfig {
     items: unknown[];        // Array to iterate over (from {{ $node.X.data }})
@@ -108,7 +110,7 @@ export function applyFilter(items: any[], expression: string): any[] {
 export async function main(): Promise<void> {
     try {
         // 1. Read input
-        const input: LoopInput = await Bun.stdin.json();
+        const input: LoopInput = await readStdinJSON();
         const { config } = input;
 
         // 2. Validate config
@@ -141,7 +143,7 @@ export async function main(): Promise<void> {
         };
 
         // 7. Write output
-        await Bun.write(Bun.stdout, JSON.stringify(output));
+        await writeStdoutJSON(output);
 
     } catch (error) {
         const message = error instanceof Error ? error.message : String(error);
@@ -151,6 +153,6 @@ export async function main(): Promise<void> {
 }
 
 // Only run main if this is the entry point
-if (import.meta.main) {
+if (isMain(import.meta)) {
     main();
 }
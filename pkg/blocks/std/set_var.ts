@@ -1,6 +1,7 @@
 /**
  * SetVar block - Sets a user-defined variable in the execution context.
  * Variables persist across nodes and can be accessed via {{ $vars.name }} syntax.
+ * The Go engine runs this node natively; the script is kept for standalone use.
  */
 
 export default async function setVar(input: any): Promise<any> {
@@ -1,8 +1,11 @@
 // pkg/blocks/std/transform.ts
 // Standard Block: Data Transformation
 // Provides JSONPath queries, field mapping, renaming, and type conversion.
+// The Go engine runs pick/rename (and its own jmespath/jsonata operations) natively;
+// this script only runs for transforms that use "map" or "jsonpath".
 
 import { query as jsonpathQuery } from "jsonpath-rfc9535";
+import { isMain, readStdinJSON, writeStdoutJSON } from "../../bunock/sdk/io.ts";
 
 // Type definitions for transformation operations
 export type TransformOperation =
@@ -141,7 +144,7 @@ export function applyJSONPath(data: any, queryString: string): any {
 export async function main(): Promise<void> {
     try {
         // 1. Read input
-        const input: TransformInput = await Bun.stdin.json();
+        const input: TransformInput = await readStdinJSON();
         const { config } = input;
 
         // 2. Validate config
@@ -181,7 +184,7 @@ export async function main(): Promise<void> {
         };
 
         // 6. Write output
-        await Bun.write(Bun.stdout, JSON.stringify(output));
+        await writeStdoutJSON(output);
 
     } catch (error) {
         const message = error instanceof Error ? error.message : String(error);
@@ -191,6 +194,6 @@ export async function main(): Promise<void> {
 }
 
 // Only run main if this is the entry point
-if (import.meta.main) {
+if (isMain(import.meta)) {
     main();
 }
@@ -2,7 +2,7 @@
 // Standard Block: Webhook Operations
 // Provides outgoing HTTP requests for external API integration
 
-import { stdin, stdout } from "bun";
+import { isMain, readStdinJSON, writeStdoutJSON } from "../../bunock/sdk/io.ts";
 
 // Maximum timeout to prevent hanging requests
 const MAX_TIMEOUT_MS = 30000; // 30 seconds
@@ -159,7 +159,7 @@ export async function executeWebhook(config: WebhookConfig): Promise<WebhookOutp
 export async function main() {
     try {
         // 1. Read input
-        const input: WebhookInput = await Bun.stdin.json();
+        const input: WebhookInput = await readStdinJSON();
         const { config } = input;
 
         // 2. Validate config
@@ -169,7 +169,7 @@ export async function main() {
         const result = await executeWebhook(config);
 
         // 4. Write output
-        await Bun.write(Bun.stdout, JSON.stringify(result));
+        await writeStdoutJSON(result);
 
     } catch (error: any) {
         console.error(`Webhook Block Failed: ${error.message}`);
@@ -178,6 +178,6 @@ export async function main() {
 }
 
 // Only run main if this is the entry point
-if (import.meta.main) {
+if (isMain(import.meta)) {
     main();
 }
@@ -0,0 +1,76 @@
+// pkg/bunock/sdk/io.ts
+// Runtime-neutral block I/O: reading the JSON input from stdin, writing the
+// JSON output to stdout and detecting the entry point. Under Bun they use
+// Bun's own APIs, elsewhere node: built-ins, so that blocks built on them run
+// under Bun, Node (22.6+) and Deno.
+
+import process from "node:process";
+import { realpathSync } from "node:fs";
+import { fileURLToPath } from "node:url";
+
+/**
+ * Read stdin to its end and parse it as JSON
+ */
+export async function readStdinJSON<T = unknown>(): Promise<T> {
+    const bun = (globalThis as any).Bun;
+    if (bun) {
+        return await bun.stdin.json();
+    }
+    const decoder = new TextDecoder();
+    let text = "";
+    for await (const chunk of process.stdin) {
+        text += typeof chunk === "string" ? chunk : decoder.decode(chunk, { stream: true });
+    }
+    text += decoder.decode();
+    return JSON.parse(text);
+}
+
+/**
+ * Write value to stdout as JSON, resolving once it is flushed so that the
+ * caller may exit right after
+ */
+export async function writeStdoutJSON(value: unknown): Promise<void> {
+    const bun = (globalThis as any).Bun;
+    if (bun) {
+        await bun.write(bun.stdout, JSON.stringify(value));
+        return;
+    }
+    return new Promise((resolve, reject) => {
+        process.stdout.write(JSON.stringify(value), (err) => (err ? reject(err) : resolve()));
+    });
+}
+
+/**
+ * Report whether the module of meta is the script the runtime was started
+ * with. Bun and Deno set import.meta.main; Node only does from v24.2, so
+ * older ones are answered by comparing the module with process.argv[1].
+ *
+ * @example
+ * ```typescript
+ * if (isMain(import.meta)) {
+ *   new MyBlock().run();
+ * }
+ * ```
+ */
+export function isMain(meta: ImportMeta): boolean {
+    const main = (meta as { main?: boolean }).main;
+    if (typeof main === "boolean") {
+        return main;
+    }
+    const entry = process.argv[1];
+    if (!entry || !meta.url.startsWith("file:")) {
+        return false;
+    }
+    try {
+        return realpathSync(entry) === realpathSync(fileURLToPath(meta.url));
+    } catch {
+        return false;
+    }
+}
+
+/**
+ * Resolve after ms milliseconds
+ */
+export function sleep(ms: number): Promise<void> {
+    return new Promise((resolve) => setTimeout(resolve, ms));
+}
@@ -2,6 +2,10 @@
 // SDK for building type-safe blocks in the CONV3N workflow engine
 // Provides base classes, utilities, and helpers to simplify block development
 
+import { isMain, readStdinJSON, writeStdoutJSON } from "./io.ts";
+
+export { isMain };
+
 /**
  * Standard input structure for all blocks
  * @template TConfig - Type of the block configuration
@@ -48,7 +52,7 @@ export interface ErrorDetails {
  *   }
  * }
  * 
- * if (import.meta.main) {
+ * if (isMain(import.meta)) {
  *   new MyBlock().run();
  * }
  * ```
@@ -104,7 +108,7 @@ export abstract class Block<TConfig, TOutput> {
      * Override this method if you need custom input parsing
      */
     protected async readInput(): Promise<BlockInput<TConfig>> {
-        return await Bun.stdin.json();
+        return await readStdinJSON<BlockInput<TConfig>>();
     }
 
     /**
@@ -114,7 +118,7 @@ export abstract class Block<TConfig, TOutput> {
      */
     protected async writeOutput(data: TOutput, port: string): Promise<void> {
         const output: BlockOutput<TOutput> = { data, port };
-        await Bun.write(Bun.stdout, JSON.stringify(output));
+        await writeStdoutJSON(output);
     }
 
     /**
@@ -128,7 +132,7 @@ export abstract class Block<TConfig, TOutput> {
             data: { error: errorDetails },
             port,
         };
-        await Bun.write(Bun.stdout, JSON.stringify(output));
+        await writeStdoutJSON(output);
         process.exit(1);
     }
 
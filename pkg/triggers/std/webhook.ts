@@ -22,17 +22,20 @@ export default createTrigger({
   },
 
   // The onMessage method handles custom messages sent from the Go orchestrator.
-  // For a webhook, the orchestrator will send an "invoke" message with the HTTP request payload
+  // For a webhook, the orchestrator will send an "invoke" notification with the HTTP request payload
   // when a corresponding HTTP endpoint is hit.
   async onMessage(message: OrchestratorMessage, ctx) {
-    if (message.type === "invoke") {
+    if (message.method === "invoke") {
       console.log(`Webhook trigger '${this.id}' received invocation.`);
       // Fire the workflow with the payload received from the orchestrator.
-      // The payload typically contains details of the incoming HTTP request.
-      await ctx.fire(message.payload);
+      // The payload typically contains details of the incoming HTTP request,
+      // the correlation ID and labels those of its X-Correlation-ID and X-Execution-Labels headers,
+      // and the idempotency key its Idempotency-Key header, so that a redelivery runs nothing.
+      const { payload, idempotencyKey, correlationId, labels } = message.params;
+      await ctx.fire(payload, { idempotencyKey, correlationId, labels });
     } else {
       console.warn(
-        `Webhook trigger '${this.id}' received unknown message type: ${message.type}`
+        `Webhook trigger '${this.id}' received unknown method: ${message.method}`
       );
     }
   },
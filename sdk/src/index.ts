@@ -48,10 +48,15 @@ export { createTrigger, runTrigger, onShutdown } from "./trigger";
 
 // IPC utilities (for advanced use cases)
 export {
+  PROTOCOL_VERSION,
+  ErrorCodes,
   sendMessage,
   sendResult,
   sendError,
-  sendReady,
+  sendLog,
+  respond,
+  respondError,
+  request,
   readInput,
   emitEventAndWait,
   generateRequestId,
@@ -68,9 +73,14 @@ export type {
   // Trigger types
   TriggerContext,
   TriggerDefinition,
+  FireOptions,
   // IPC types
   OrchestratorMessage,
   WorkerMessage,
+  JsonRpcId,
+  JsonRpcRequest,
+  JsonRpcResponse,
+  JsonRpcError,
   // Utility types
   VariableStore,
 } from "./types";
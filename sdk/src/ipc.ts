@@ -3,7 +3,31 @@
  * Handles stdin/stdout JSON streaming between Go orchestrator and Bun workers.
  */
 
-import type { OrchestratorMessage, WorkerMessage, BlockResult } from "./types";
+import type {
+  OrchestratorMessage,
+  WorkerMessage,
+  JsonRpcId,
+  JsonRpcError,
+  JsonRpcResponse,
+  FireOptions,
+} from "./types";
+
+/**
+ * Version of the conv3n method set this SDK speaks over JSON-RPC 2.0.
+ */
+export const PROTOCOL_VERSION = 1;
+
+/**
+ * JSON-RPC error codes; -32000 to -32099 are reserved for the application.
+ */
+export const ErrorCodes = {
+  PARSE_ERROR: -32700,
+  INVALID_REQUEST: -32600,
+  METHOD_NOT_FOUND: -32601,
+  INVALID_PARAMS: -32602,
+  INTERNAL_ERROR: -32603,
+  FIRE_FAILED: -32000,
+} as const;
 
 // =============================================================================
 // OUTPUT (Bun -> Go)
@@ -18,32 +42,47 @@ export function sendMessage(message: WorkerMessage): void {
 }
 
 /**
- * Send block execution result to orchestrator.
+ * Answer a request from the orchestrator.
  */
-export function sendResult<T>(data: T, port: string = "default"): void {
-  sendMessage({ type: "result", data, port });
+export function respond(id: JsonRpcId, result: unknown): void {
+  sendMessage({ jsonrpc: "2.0", id, result: result ?? {} });
 }
 
 /**
- * Send an event from a trigger to orchestrator.
- * Returns a promise that resolves when the orchestrator sends a reply.
+ * Answer a request from the orchestrator with an error.
  */
-export function sendEvent(requestId: string, payload: unknown): void {
-  sendMessage({ type: "event", requestId, payload });
+export function respondError(id: JsonRpcId, error: JsonRpcError): void {
+  sendMessage({ jsonrpc: "2.0", id, error });
 }
 
 /**
- * Signal that the trigger is ready to receive events.
+ * Send block execution result to orchestrator.
+ * A block's input is an implicit request without id, so the response has a null id.
  */
-export function sendReady(): void {
-  sendMessage({ type: "status", status: "ready" });
+export function sendResult<T>(data: T, port: string = "default"): void {
+  respond(null, { data, port });
 }
 
 /**
- * Send an error message to orchestrator.
+ * Send a block execution error to orchestrator.
  */
 export function sendError(message: string, stack?: string): void {
-  sendMessage({ type: "error", message, stack });
+  respondError(null, {
+    code: ErrorCodes.INTERNAL_ERROR,
+    message,
+    data: stack ? { stack } : undefined,
+  });
+}
+
+/**
+ * Send a log line from a trigger to orchestrator.
+ */
+export function sendLog(
+  level: "debug" | "info" | "warn" | "error",
+  message: string,
+  stack?: string
+): void {
+  sendMessage({ jsonrpc: "2.0", method: "log", params: { level, message, stack } });
 }
 
 // =============================================================================
@@ -90,14 +129,16 @@ export async function readInput<T = unknown>(): Promise<T> {
 // =============================================================================
 
 /**
- * Pending requests waiting for replies from orchestrator.
- * Maps requestId -> resolve function.
+ * Requests sent to the orchestrator that wait for its response.
  */
-const pendingRequests = new Map<string, (data: unknown) => void>();
+const pendingRequests = new Map<
+  JsonRpcId,
+  { resolve: (result: unknown) => void; reject: (error: Error) => void }
+>();
 
 /**
  * Start listening for messages from orchestrator.
- * Used by triggers to receive replies for emitted events.
+ * Responses settle pending requests; requests and notifications go to onMessage.
  */
 export async function startMessageLoop(
   onMessage?: (msg: OrchestratorMessage) => void
@@ -120,57 +161,56 @@ export async function startMessageLoop(
 
       if (!line.trim()) continue;
 
+      let msg: OrchestratorMessage | JsonRpcResponse;
       try {
-        const msg = JSON.parse(line) as OrchestratorMessage;
-        handleOrchestratorMessage(msg, onMessage);
+        msg = JSON.parse(line);
       } catch (err) {
-        sendError(`Failed to parse message: ${err}`);
+        respondError(null, { code: ErrorCodes.PARSE_ERROR, message: `Failed to parse message: ${err}` });
+        continue;
+      }
+      if (!("method" in msg)) {
+        handleResponse(msg);
+      } else {
+        onMessage?.(msg);
       }
     }
   }
 }
 
 /**
- * Handle incoming message from orchestrator.
+ * Settle the pending request a response answers.
  */
-function handleOrchestratorMessage(
-  msg: OrchestratorMessage,
-  onMessage?: (msg: OrchestratorMessage) => void
-): void {
-  switch (msg.type) {
-    case "reply":
-      // Resolve pending request
-      const resolver = pendingRequests.get(msg.requestId);
-      if (resolver) {
-        resolver(msg.data);
-        pendingRequests.delete(msg.requestId);
-      }
-      break;
-
-    case "kill":
-      // Graceful shutdown
-      process.exit(0);
-      break;
-
-    default:
-      // Pass to custom handler
-      onMessage?.(msg);
+function handleResponse(msg: JsonRpcResponse): void {
+  const pending = pendingRequests.get(msg.id);
+  if (!pending) return;
+  pendingRequests.delete(msg.id);
+  if (msg.error) {
+    pending.reject(new Error(`${msg.error.message} (code ${msg.error.code})`));
+  } else {
+    pending.resolve(msg.result);
   }
 }
 
+/**
+ * Send a request to the orchestrator and wait for its response.
+ */
+export function request<TResult>(method: string, params?: unknown): Promise<TResult> {
+  const id = generateRequestId();
+  return new Promise<TResult>((resolve, reject) => {
+    pendingRequests.set(id, { resolve: resolve as (result: unknown) => void, reject });
+    sendMessage({ jsonrpc: "2.0", id, method, params });
+  });
+}
+
 /**
  * Emit an event and wait for reply from orchestrator.
- * Used by triggers to send events and receive workflow results.
+ * Used by triggers to fire their workflow; rejects if it could not be started.
  */
 export function emitEventAndWait<TPayload, TResult>(
-  payload: TPayload
+  payload: TPayload,
+  options: FireOptions = {}
 ): Promise<TResult> {
-  const requestId = crypto.randomUUID();
-
-  return new Promise<TResult>((resolve) => {
-    pendingRequests.set(requestId, resolve as (data: unknown) => void);
-    sendEvent(requestId, payload);
-  });
+  return request<TResult>("fire", { payload, ...options });
 }
 
 /**
@@ -4,11 +4,14 @@
  */
 import {
   startMessageLoop,
-  sendReady,
-  sendError,
+  respond,
+  respondError,
+  sendLog,
   emitEventAndWait,
+  ErrorCodes,
+  PROTOCOL_VERSION,
 } from "./ipc";
-import type { OrchestratorMessage } from "./types";
+import type { FireOptions, OrchestratorMessage } from "./types";
 
 // =============================================================================
 // TYPE DEFINITIONS
@@ -26,9 +29,13 @@ export interface TriggerContext<TConfig = Record<string, unknown>> {
    * Fires the trigger, causing the associated workflow to execute.
    * The provided payload will be available to the workflow via `ctx.trigger`.
    * @param payload - The data payload to send with the event.
+   * @param options.idempotencyKey - Identifies the delivery, e.g. a queue message ID;
+   *   firing again with the same key doesn't run the workflow again.
+   * @param options.correlationId - Correlation ID recorded on the execution.
+   * @param options.labels - Labels recorded on the execution.
    * @returns A promise that resolves with the result of the workflow execution.
    */
-  fire: <TPayload, TResult>(payload: TPayload) => Promise<TResult>;
+  fire: <TPayload, TResult>(payload: TPayload, options?: FireOptions) => Promise<TResult>;
 }
 
 /**
@@ -116,19 +123,32 @@ export async function runTrigger<TConfig = Record<string, unknown>>(
   let context: TriggerContext<TConfig> | null = null;
 
   try {
-    // The first message from the orchestrator will be the config
+    // The first message from the orchestrator is the initialize request with the config
     await startMessageLoop(async (msg) => {
-      if (msg.type === "start") {
+      if (msg.method === "initialize") {
+        if (!msg.params.protocolVersions.includes(PROTOCOL_VERSION)) {
+          respondError(msg.id, {
+            code: ErrorCodes.INVALID_PARAMS,
+            message: `unsupported protocol versions ${msg.params.protocolVersions}, the SDK speaks ${PROTOCOL_VERSION}`,
+          });
+          return;
+        }
         // Initialize context and run onStart
         context = {
-          config: msg.config as TConfig,
-          fire: (payload) => emitEventAndWait(payload),
+          config: msg.params.config as TConfig,
+          fire: (payload, options) => emitEventAndWait(payload, options),
         };
-        await definition.onStart(context);
+        try {
+          await definition.onStart(context);
+        } catch (err) {
+          const error = err instanceof Error ? err : new Error(String(err));
+          respondError(msg.id, { code: ErrorCodes.INTERNAL_ERROR, message: error.message, data: { stack: error.stack } });
+          return;
+        }
 
-        // Signal to the orchestrator that the trigger is initialized and ready
-        sendReady();
-      } else if (msg.type === "kill") {
+        // Answering initialize tells the orchestrator the trigger is ready
+        respond(msg.id, { protocolVersion: PROTOCOL_VERSION });
+      } else if (msg.method === "shutdown") {
         // Run onStop and exit
         if (context) {
           await definition.onStop(context);
@@ -143,7 +163,7 @@ export async function runTrigger<TConfig = Record<string, unknown>>(
     });
   } catch (err) {
     const error = err instanceof Error ? err : new Error(String(err));
-    sendError(error.message, error.stack);
+    sendLog("error", error.message, error.stack);
     process.exit(1);
   }
 }
\ No newline at end of file
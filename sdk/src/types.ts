@@ -68,9 +68,25 @@ export interface TriggerContext<TConfig = Record<string, unknown>> {
    * Fires the trigger, causing the associated workflow to execute.
    * The provided payload will be available to the workflow via `ctx.trigger`.
    * @param payload - The data payload to send with the event.
+   * @param options.idempotencyKey - Identifies the delivery, e.g. a queue message ID;
+   *   firing again with the same key doesn't run the workflow again.
+   * @param options.correlationId - Correlation ID recorded on the execution.
+   * @param options.labels - Labels recorded on the execution.
    * @returns A promise that resolves with the result of the workflow execution.
    */
-  fire: <TPayload, TResult>(payload: TPayload) => Promise<TResult>;
+  fire: <TPayload, TResult>(payload: TPayload, options?: FireOptions) => Promise<TResult>;
+}
+
+/**
+ * Options of a single `fire` call.
+ */
+export interface FireOptions {
+  /** Key of the delivery; a redelivery with the same key maps to the first one's execution. */
+  idempotencyKey?: string;
+  /** ID shared by the systems taking part in a business transaction, recorded on the execution. */
+  correlationId?: string;
+  /** Key/value labels recorded on the execution, e.g. `{ order_id: "A-17" }`; searchable in the executions API. */
+  labels?: Record<string, string>;
 }
 
 /**
@@ -113,27 +129,65 @@ export interface TriggerDefinition<TConfig = Record<string, unknown>> {
 // IPC PROTOCOL
 // =============================================================================
 
+// All IPC is newline-delimited JSON-RPC 2.0; see PROTOCOL.md.
+
+export type JsonRpcId = string | number | null;
+
+/**
+ * A JSON-RPC request, or a notification when it has no id.
+ */
+export interface JsonRpcRequest<TParams = unknown> {
+  jsonrpc: "2.0";
+  id?: JsonRpcId;
+  method: string;
+  params?: TParams;
+}
+
+/**
+ * The error object of a failed JSON-RPC response.
+ */
+export interface JsonRpcError {
+  code: number;
+  message: string;
+  data?: unknown;
+}
+
+/**
+ * A JSON-RPC response: exactly one of result and error is set.
+ */
+export interface JsonRpcResponse<TResult = unknown> {
+  jsonrpc: "2.0";
+  id: JsonRpcId;
+  result?: TResult;
+  error?: JsonRpcError;
+}
+
 /**
- * Messages sent from Go orchestrator to Bun worker (via stdin).
+ * Requests and notifications sent from Go orchestrator to a trigger (via stdin).
  */
 export type OrchestratorMessage =
-  // Block-related
-  | { type: "execute"; input: BlockInput; requestId?: string }
-  // Trigger-related
-  | { type: "start"; config: Record<string, unknown> }
-  | { type: "reply"; requestId: string; data: unknown }
-  | { type: "kill" }
-  // General
-  | { type: "invoke"; payload: unknown }; // For triggers like webhooks
+  | {
+      jsonrpc: "2.0";
+      id: JsonRpcId;
+      method: "initialize";
+      params: { protocolVersions: number[]; config: Record<string, unknown> };
+    }
+  | {
+      jsonrpc: "2.0";
+      method: "invoke";
+      params: {
+        payload: unknown;
+        idempotencyKey?: string;
+        correlationId?: string;
+        labels?: Record<string, string>;
+      };
+    } // For triggers like webhooks
+  | { jsonrpc: "2.0"; method: "shutdown" };
 
 /**
  * Messages sent from Bun worker to Go orchestrator (via stdout).
  */
-export type WorkerMessage =
-  | { type: "result"; data: unknown; port: string }
-  | { type: "event"; requestId: string; payload: unknown }
-  | { type: "status"; status: "ready" | "error"; message?: string }
-  | { type: "error"; message: string; stack?: string };
+export type WorkerMessage = JsonRpcRequest | JsonRpcResponse;
 
 // =============================================================================
 // VARIABLE STORE
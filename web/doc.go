@@ -0,0 +1,4 @@
+// Package web holds the editor UI. Built with the embedui tag, the binary
+// embeds its production build (web/dist, from `make build-ui`) and the server
+// hosts it at /.
+package web
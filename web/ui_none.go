@@ -0,0 +1,11 @@
+//go:build !embedui
+
+package web
+
+import "io/fs"
+
+// FS returns nil: this binary was built without the embedui tag, so it embeds
+// no UI; the server can still host a build from --ui-dir.
+func FS() fs.FS {
+	return nil
+}